@@ -2,44 +2,49 @@ package graph
 
 import (
 	"context"
-	"fmt"
+	"strings"
 
-	jwt "github.com/golang-jwt/jwt/v5"
-	"github.com/riyadennis/identity-server/app/gql/graph/model"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/riyadennis/identity-server/business/authz"
 	"github.com/riyadennis/identity-server/business/store"
 	"github.com/riyadennis/identity-server/foundation/middleware"
-	"github.com/sirupsen/logrus"
 )
 
 // callerID extracts the authenticated caller's user ID from the request context.
 func callerID(ctx context.Context) (string, error) {
-	claims, ok := ctx.Value(middleware.UserClaimsKey).(*jwt.RegisteredClaims)
+	claims, ok := ctx.Value(middleware.UserClaimsKey).(*store.Claims)
 	if !ok || claims == nil || claims.Subject == "" {
-		return "", fmt.Errorf("unauthorized: missing or invalid token claims")
+		return "", authz.ErrUnauthenticated
 	}
 	return claims.Subject, nil
 }
 
-// callerIsAdmin looks up the caller's role from the store and returns an error
-// if they are not an ADMIN. Use this at the top of any resolver that requires
-// elevated permissions.
-func callerIsAdmin(ctx context.Context, s store.Store, logger *logrus.Logger) error {
-	userID, err := callerID(ctx)
-	if err != nil {
-		return err
+// requireAuthentication fails the root fields that are not @public when the
+// caller has no valid token. It goes by the fields of the parsed document, so
+// it holds for every transport and no operation name makes a field public.
+// Fields that need more than a token check it with @hasRole and @hasPermission.
+func requireAuthentication(ctx context.Context, next graphql.RootResolver) graphql.Marshaler {
+	field := graphql.GetRootFieldContext(ctx).Field
+	if publicField(field.Definition) {
+		return next(ctx)
 	}
 
-	user, err := s.Retrieve(ctx, userID)
-	if err != nil {
-		return fmt.Errorf("failed to verify caller permissions: %w", err)
-	}
-	if user == nil {
-		return fmt.Errorf("unauthorized: caller not found")
-	}
-	logger.Infof("got user %v", model.Role(user.Role))
-	if model.Role(user.Role) != model.RoleAdmin {
-		return fmt.Errorf("forbidden: admin role required")
+	if _, err := callerID(ctx); err != nil {
+		graphql.AddError(ctx, gqlerror.WrapPath(ast.Path{ast.PathName(field.Alias)}, err))
+		return graphql.Null
 	}
 
-	return nil
+	return next(ctx)
+}
+
+// publicField reports whether the root field f can be used without a token.
+func publicField(f *ast.FieldDefinition) bool {
+	// introspection and the federation SDL describe the schema, not its data.
+	if strings.HasPrefix(f.Name, "__") || f.Name == "_service" {
+		return true
+	}
+	return f.Directives.ForName("public") != nil
 }
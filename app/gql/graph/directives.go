@@ -0,0 +1,58 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/riyadennis/identity-server/app/gql/graph/generated"
+	"github.com/riyadennis/identity-server/app/gql/graph/model"
+	"github.com/riyadennis/identity-server/business/authz"
+)
+
+// NewExecutableSchema wires the resolvers and the authorization directives
+// declared in schema.graphqls into an executable schema, fields are scored
+// for the complexity limit with their @cost.
+func NewExecutableSchema(r *Resolver) graphql.ExecutableSchema {
+	return costSchema{generated.NewExecutableSchema(generated.Config{
+		Resolvers: r,
+		Directives: generated.DirectiveRoot{
+			HasRole:       r.hasRole,
+			HasPermission: r.hasPermission,
+		},
+	})}
+}
+
+// hasRole implements @hasRole, the field resolver only runs when the caller has the role.
+func (r *Resolver) hasRole(ctx context.Context, _ any, next graphql.Resolver, role model.Role) (any, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := r.Authorizer.HasRole(ctx, authz.Subject{UserID: userID}, roleName(role))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		r.Logger.Infof("user %s denied %s, missing role %s", userID, graphql.GetFieldContext(ctx).Field.Name, role)
+		return nil, authz.ErrForbidden
+	}
+
+	return next(ctx)
+}
+
+// hasPermission implements @hasPermission, the field resolver only runs when
+// one of the caller's roles grants the permission.
+func (r *Resolver) hasPermission(ctx context.Context, _ any, next graphql.Resolver, name string) (any, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.Authorizer.Authorize(ctx, authz.Subject{UserID: userID}, name, authz.Resource{})
+	if err != nil {
+		return nil, err
+	}
+
+	return next(ctx)
+}
@@ -0,0 +1,202 @@
+package graph
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/riyadennis/identity-server/app/gql/graph/model"
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// publicFields are reachable without a directive, they are either public or
+// only ever act on the caller's own account.
+var publicFields = map[string]bool{
+	"Login":              true,
+	"Register":           true,
+	"me":                 true,
+	"organizations":      true,
+	"createOrganization": true,
+	"switchOrganization": true,
+	"acceptInvitation":   true,
+	"updateProfile":      true,
+	"changeEmail":        true,
+	"confirmEmailChange": true,
+	// loginHistory checks users:read itself when asked for another user.
+	"loginHistory": true,
+}
+
+func TestSchema_EveryFieldIsProtected(t *testing.T) {
+	schema := NewExecutableSchema(newResolver(&mocks.Store{}, &mocks.Authenticator{}, tokenConfig())).Schema()
+	for _, root := range []string{"Query", "Mutation", "Subscription"} {
+		for _, field := range schema.Types[root].Fields {
+			if publicFields[field.Name] || strings.HasPrefix(field.Name, "_") {
+				continue
+			}
+			protected := field.Directives.ForName("hasRole") != nil ||
+				field.Directives.ForName("hasPermission") != nil
+			assert.Truef(t, protected, "%s.%s has no @hasRole or @hasPermission directive", root, field.Name)
+		}
+	}
+}
+
+func newTestClient(ctx context.Context, st store.Store) *client.Client {
+	srv := handler.New(NewExecutableSchema(newResolver(st, &mocks.Authenticator{}, tokenConfig())))
+	srv.AddTransport(transport.POST{})
+	return client.New(srv, func(bd *client.Request) {
+		bd.HTTP = bd.HTTP.WithContext(ctx)
+	})
+}
+
+func TestHasPermission(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		ctx           context.Context
+		store         *mocks.Store
+		expectedError string
+	}{
+		{
+			name:          "unauthenticated",
+			ctx:           context.Background(),
+			store:         &mocks.Store{},
+			expectedError: "unauthorized",
+		},
+		{
+			name:          "missing permission",
+			ctx:           withCaller("1"),
+			store:         &mocks.Store{Permissions: []string{authz.RolesRead}},
+			expectedError: authz.ErrForbidden.Error(),
+		},
+		{
+			name: "granted",
+			ctx:  withCaller("1"),
+			store: &mocks.Store{
+				User:        &store.User{ID: "2", Email: testEmail, Roles: []string{authz.RoleUser}},
+				Permissions: []string{authz.UsersRead},
+			},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			var resp struct {
+				ListUsers []struct {
+					ID    string
+					Email string
+				}
+			}
+			err := newTestClient(sc.ctx, sc.store).Post(`query { listUsers { id email } }`, &resp)
+			if sc.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), sc.expectedError)
+				assert.Empty(t, resp.ListUsers)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, resp.ListUsers, 1)
+			assert.Equal(t, testEmail, resp.ListUsers[0].Email)
+		})
+	}
+}
+
+func TestHasRole(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		roles         []string
+		expectedError error
+	}{
+		{
+			name:          "not an admin",
+			roles:         []string{authz.RoleUser},
+			expectedError: authz.ErrForbidden,
+		},
+		{
+			name:  "admin",
+			roles: []string{authz.RoleAdmin},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			res := newResolver(&mocks.Store{User: &store.User{ID: "1", Roles: sc.roles}}, &mocks.Authenticator{}, tokenConfig())
+			called := false
+			next := func(context.Context) (any, error) {
+				called = true
+				return nil, nil
+			}
+			ctx := graphql.WithFieldContext(withCaller("1"), &graphql.FieldContext{Field: graphql.CollectedField{Field: &ast.Field{Name: "field"}}})
+			_, err := res.hasRole(ctx, nil, next, model.RoleAdmin)
+			if sc.expectedError != nil {
+				assert.ErrorIs(t, err, sc.expectedError)
+				assert.False(t, called)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, called)
+		})
+	}
+}
+
+func TestAssignRole_Permission(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		store         *mocks.Store
+		expectedError string
+	}{
+		{
+			name:          "admin without roles:write",
+			store:         &mocks.Store{User: &store.User{ID: "1", Roles: []string{authz.RoleAdmin}}, Permissions: []string{authz.RolesRead}},
+			expectedError: authz.ErrForbidden.Error(),
+		},
+		{
+			name:  "superadmin",
+			store: &mocks.Store{User: &store.User{ID: "1", Roles: []string{authz.RoleSuperAdmin}}, Permissions: []string{authz.RolesWrite}},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			var resp struct {
+				AssignRole struct {
+					UserID string
+					Role   string
+				}
+			}
+			err := newTestClient(withCaller("1"), sc.store).Post(
+				`mutation { assignRole(userId: "2", role: ADMIN) { userId role } }`, &resp)
+			if sc.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), sc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "2", resp.AssignRole.UserID)
+			assert.Equal(t, "ADMIN", resp.AssignRole.Role)
+		})
+	}
+}
+
+func TestHasPermission_ResolverNotCalled(t *testing.T) {
+	st := &countingStore{Store: mocks.Store{Permissions: []string{}}}
+	var resp map[string]any
+	err := newTestClient(withCaller("1"), st).Post(`mutation { deleteRole(name: "support") }`, &resp)
+	require.Error(t, err)
+	assert.Equal(t, 0, st.deleted)
+}
+
+// countingStore records whether the resolver reached the store.
+type countingStore struct {
+	mocks.Store
+	deleted int
+}
+
+func (s *countingStore) DeleteRole(_ context.Context, _ string) error {
+	s.deleted++
+	return nil
+}
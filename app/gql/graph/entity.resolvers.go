@@ -0,0 +1,66 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.90
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/riyadennis/identity-server/app/gql/graph/generated"
+	"github.com/riyadennis/identity-server/app/gql/graph/model"
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/store"
+)
+
+// FindManyUserByIDs is the resolver for the findManyUserByIDs field.
+func (r *entityResolver) FindManyUserByIDs(ctx context.Context, reps []*model.UserByIDsInput) ([]*model.User, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// _entities skips the directives of the query fields, the caller needs
+	// users:read as with users unless every representation is their own account.
+	ids := make([]string, 0, len(reps))
+	resource := authz.User(userID)
+	for _, rep := range reps {
+		ids = append(ids, rep.ID)
+		if rep.ID != userID {
+			resource = authz.Resource{}
+		}
+	}
+	err = r.Authorizer.Authorize(ctx, authz.Subject{UserID: userID}, authz.UsersRead, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := r.Store.RetrieveMany(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve users: %w", err)
+	}
+	byID := make(map[string]*store.User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	// users outside the tenant are left null, with an error for each of them.
+	entities := make([]*model.User, len(reps))
+	for i, rep := range reps {
+		user, ok := byID[rep.ID]
+		if !ok {
+			graphql.AddError(ctx, userNotFound(rep.ID))
+			continue
+		}
+		entities[i] = toUser(user)
+	}
+
+	return entities, nil
+}
+
+// Entity returns generated.EntityResolver implementation.
+func (r *Resolver) Entity() generated.EntityResolver { return &entityResolver{r} }
+
+type entityResolver struct{ *Resolver }
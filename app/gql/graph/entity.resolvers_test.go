@@ -0,0 +1,157 @@
+package graph
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/store"
+	customMiddleware "github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+const entitiesQuery = `query($representations: [_Any!]!) {
+	_entities(representations: $representations) { ... on User { id email role } }
+}`
+
+func TestEntities_User(t *testing.T) {
+	representations := func(ids ...string) []map[string]any {
+		reps := make([]map[string]any, 0, len(ids))
+		for _, id := range ids {
+			reps = append(reps, map[string]any{"__typename": "User", "id": id})
+		}
+		return reps
+	}
+	scenarios := []struct {
+		name            string
+		ids             []string
+		token           bool
+		user            *store.User
+		permissions     []string
+		expectedData    string
+		expectedError   string
+		expectedFetches [][]string
+	}{
+		{
+			name:          "without a token",
+			ids:           []string{"2"},
+			user:          &store.User{Email: testEmail},
+			expectedError: "unauthorized",
+		},
+		{
+			name:            "own account",
+			ids:             []string{"1"},
+			token:           true,
+			user:            &store.User{Email: testEmail, Roles: []string{authz.RoleAdmin}},
+			expectedData:    `{"_entities": [{"id": "1", "email": "john@example.com", "role": "ADMIN"}]}`,
+			expectedFetches: [][]string{{"1"}},
+		},
+		{
+			name:            "superadmin",
+			ids:             []string{"1"},
+			token:           true,
+			user:            &store.User{Email: testEmail, Roles: []string{authz.RoleAdmin, authz.RoleSuperAdmin}},
+			expectedData:    `{"_entities": [{"id": "1", "email": "john@example.com", "role": "SUPERADMIN"}]}`,
+			expectedFetches: [][]string{{"1"}},
+		},
+		{
+			name:          "other user without users:read",
+			ids:           []string{"2"},
+			token:         true,
+			user:          &store.User{Email: testEmail},
+			expectedData:  `{"_entities": [null]}`,
+			expectedError: authz.ErrForbidden.Error(),
+		},
+		{
+			name:          "own account with another user without users:read",
+			ids:           []string{"1", "2"},
+			token:         true,
+			user:          &store.User{Email: testEmail},
+			expectedData:  `{"_entities": [null, null]}`,
+			expectedError: authz.ErrForbidden.Error(),
+		},
+		{
+			name:        "users fetched in one batch",
+			ids:         []string{"2", "3", "2"},
+			token:       true,
+			user:        &store.User{Email: testEmail},
+			permissions: []string{authz.UsersRead},
+			expectedData: `{"_entities": [
+				{"id": "2", "email": "john@example.com", "role": "USER"},
+				{"id": "3", "email": "john@example.com", "role": "USER"},
+				{"id": "2", "email": "john@example.com", "role": "USER"}
+			]}`,
+			expectedFetches: [][]string{{"2", "3", "2"}},
+		},
+		{
+			name:            "user of another organization",
+			ids:             []string{"2"},
+			token:           true,
+			permissions:     []string{authz.UsersRead},
+			expectedData:    `{"_entities": [null]}`,
+			expectedError:   "user 2 not found",
+			expectedFetches: [][]string{{"2"}},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			st := &mocks.Store{
+				User:          sc.user,
+				Permissions:   sc.permissions,
+				Organizations: []*store.Organization{{ID: "org-1"}},
+			}
+			r := newResolver(st, &mocks.Authenticator{}, tokenConfig())
+			ac := &customMiddleware.AuthConfig{TokenConfig: tokenConfig(), Authorizer: r.Authorizer, Logger: r.Logger}
+
+			req := postRequest(t, map[string]any{
+				"query":     entitiesQuery,
+				"variables": map[string]any{"representations": representations(sc.ids...)},
+			})
+			if sc.token {
+				req.Header.Set("Authorization", testToken(t, "1"))
+			}
+			w := httptest.NewRecorder()
+			newRouter(ac, st, newHandler(r, ac, DefaultLimits(), nil), DefaultLimits(), nil).ServeHTTP(w, req)
+
+			var resp struct {
+				Data   json.RawMessage
+				Errors []struct{ Message string }
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+			if sc.expectedError != "" {
+				require.NotEmpty(t, resp.Errors)
+				assert.Contains(t, resp.Errors[0].Message, sc.expectedError)
+			} else {
+				assert.Empty(t, resp.Errors)
+			}
+			if sc.expectedData != "" {
+				assert.JSONEq(t, sc.expectedData, string(resp.Data))
+			}
+			assert.Equal(t, sc.expectedFetches, st.RetrievedMany)
+		})
+	}
+}
+
+func TestEntities_Service(t *testing.T) {
+	r := newResolver(&mocks.Store{}, &mocks.Authenticator{}, tokenConfig())
+	ac := &customMiddleware.AuthConfig{TokenConfig: tokenConfig(), Authorizer: r.Authorizer, Logger: r.Logger}
+
+	w := httptest.NewRecorder()
+	newRouter(ac, r.Store, newHandler(r, ac, DefaultLimits(), nil), DefaultLimits(), nil).
+		ServeHTTP(w, postRequest(t, map[string]any{"query": `{ _service { sdl } }`}))
+
+	var resp struct {
+		Data struct {
+			Service struct{ SDL string } `json:"_service"`
+		}
+		Errors []struct{ Message string }
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Empty(t, resp.Errors)
+	assert.Contains(t, resp.Data.Service.SDL, `type User @key(fields: "id")`)
+	assert.Contains(t, resp.Data.Service.SDL, `role: Role!`)
+}
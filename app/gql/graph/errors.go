@@ -0,0 +1,62 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/sirupsen/logrus"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/riyadennis/identity-server/business"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+// internalMessage replaces the message of errors that are failures of the service.
+const internalMessage = "internal error"
+
+// presentError gives every error the code and HTTP status of its category in
+// its extensions, so clients do not have to match messages. Failures of the
+// service are logged and shown with only a correlation id to find the log by.
+func presentError(logger *logrus.Logger) graphql.ErrorPresenterFunc {
+	return func(ctx context.Context, err error) *gqlerror.Error {
+		gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+		if gqlErr.Extensions == nil {
+			gqlErr.Extensions = make(map[string]any)
+		}
+
+		// errors about the document itself, such as parsing, validation and
+		// the limits, come with a code of their own.
+		if gqlErr.Err == nil {
+			if _, ok := gqlErr.Extensions["code"]; !ok {
+				gqlErr.Extensions["code"] = foundation.InvalidRequest
+			}
+			gqlErr.Extensions["status"] = http.StatusBadRequest
+			return gqlErr
+		}
+
+		ce := business.Classify(gqlErr.Err)
+		if ce.Category == foundation.CategoryInternal {
+			id := business.CorrelationID(ctx)
+			logger.WithField("correlation_id", id).Errorf("graphql request failed at %s: %v", gqlErr.Path, err)
+			gqlErr.Message = internalMessage
+			gqlErr.Extensions["correlationId"] = id
+		}
+		if _, ok := gqlErr.Extensions["code"]; !ok {
+			gqlErr.Extensions["code"] = ce.Code
+		}
+		gqlErr.Extensions["status"] = ce.Category.Status()
+		return gqlErr
+	}
+}
+
+// invalidArgument reports err as a problem with the arguments of a field.
+func invalidArgument(err error) error {
+	return foundation.NewCustomError(foundation.CategoryInvalid, foundation.InvalidRequest, err)
+}
+
+// userNotFound is the error for a user id that does not exist or is not visible to the caller.
+func userNotFound(id string) error {
+	return foundation.NewCustomError(foundation.CategoryNotFound, foundation.UserDoNotExist, fmt.Errorf("user %s not found", id))
+}
@@ -0,0 +1,100 @@
+package graph
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+	customMiddleware "github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+func TestPresentError(t *testing.T) {
+	scenarios := []struct {
+		name               string
+		query              string
+		store              *mocks.Store
+		withoutToken       bool
+		expectedMessage    string
+		expectedCode       string
+		expectedStatus     float64
+		expectedCorrelated bool
+	}{
+		{
+			name:            "unauthenticated",
+			query:           `{ me { id } }`,
+			store:           &mocks.Store{User: &store.User{ID: "1"}},
+			withoutToken:    true,
+			expectedMessage: "unauthorized: missing or invalid token claims",
+			expectedCode:    foundation.UnAuthorised,
+			expectedStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:            "not found",
+			query:           `{ me { id } }`,
+			store:           &mocks.Store{Organizations: []*store.Organization{{ID: "org-1"}}},
+			expectedMessage: "user 1 not found",
+			expectedCode:    foundation.UserDoNotExist,
+			expectedStatus:  http.StatusNotFound,
+		},
+		{
+			name:            "invalid document",
+			query:           `{ me { id }`,
+			store:           &mocks.Store{Organizations: []*store.Organization{{ID: "org-1"}}},
+			expectedMessage: "Expected Name, found <EOF>",
+			expectedCode:    "GRAPHQL_PARSE_FAILED",
+			expectedStatus:  http.StatusBadRequest,
+		},
+		{
+			name:               "internal",
+			query:              `mutation { Register(input: {firstName: "John", lastName: "Doe", email: "john@example.com", password: "secret", terms: true}) { id } }`,
+			store:              &mocks.Store{Error: errors.New("connection refused")},
+			withoutToken:       true,
+			expectedMessage:    internalMessage,
+			expectedCode:       foundation.InternalError,
+			expectedStatus:     http.StatusInternalServerError,
+			expectedCorrelated: true,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			res := newResolver(sc.store, &mocks.Authenticator{}, tokenConfig())
+			ac := &customMiddleware.AuthConfig{TokenConfig: tokenConfig(), Authorizer: res.Authorizer, Logger: res.Logger}
+			router := newRouter(ac, res.Store, newHandler(res, ac, DefaultLimits(), nil), DefaultLimits(), nil)
+
+			r := postRequest(t, map[string]any{"query": sc.query})
+			r.Header.Set(middleware.RequestIDHeader, "req-1")
+			if !sc.withoutToken {
+				r.Header.Set("Authorization", testToken(t, "1"))
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			var resp struct {
+				Errors []struct {
+					Message    string
+					Extensions map[string]any
+				}
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+			require.Len(t, resp.Errors, 1)
+			assert.Contains(t, resp.Errors[0].Message, sc.expectedMessage)
+			assert.Equal(t, sc.expectedCode, resp.Errors[0].Extensions["code"])
+			assert.Equal(t, sc.expectedStatus, resp.Errors[0].Extensions["status"])
+			if sc.expectedCorrelated {
+				assert.Equal(t, "req-1", resp.Errors[0].Extensions["correlationId"])
+				assert.NotContains(t, w.Body.String(), "connection refused")
+			} else {
+				assert.NotContains(t, resp.Errors[0].Extensions, "correlationId")
+			}
+		})
+	}
+}
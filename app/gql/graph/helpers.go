@@ -3,11 +3,20 @@ package graph
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/riyadennis/identity-server/app/gql/graph/loaders"
 	"github.com/riyadennis/identity-server/app/gql/graph/model"
 	"github.com/riyadennis/identity-server/business"
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/events"
 	"github.com/riyadennis/identity-server/business/store"
-	"github.com/riyadennis/identity-server/business/validation"
+	"github.com/riyadennis/identity-server/foundation"
+	"github.com/vektah/gqlparser/v2/gqlerror"
 )
 
 func (r *mutationResolver) insertUser(ctx context.Context, input model.RegisterInput, createdBy string) (*model.RegisterResponse, error) {
@@ -17,7 +26,6 @@ func (r *mutationResolver) insertUser(ctx context.Context, input model.RegisterI
 		Email:     input.Email,
 		Password:  input.Password,
 		Terms:     input.Terms,
-		CreatedBy: createdBy,
 		Active:    true,
 	}
 	if input.Company != nil {
@@ -27,28 +35,66 @@ func (r *mutationResolver) insertUser(ctx context.Context, input model.RegisterI
 		u.PostCode = *input.PostCode
 	}
 
-	if err := validation.ValidateUser(u); err != nil {
-		return nil, err
-	}
-
-	existing, err := r.Store.Read(ctx, u.Email)
+	created, err := r.Users.Create(ctx, u, createdBy)
 	if err != nil {
-		return nil, err
-	}
-	if existing.Email == u.Email {
-		return nil, errors.New("email already exists")
+		return nil, emailExistsError(err)
 	}
+	r.Events.Publish(events.UserRegistered{UserID: created.ID, Email: created.Email, Roles: created.Roles})
 
-	u.Password, err = business.EncryptPassword(u.Password)
-	if err != nil {
-		return nil, err
+	return toRegisterResponse(created), nil
+}
+
+// retrieveUser reads a user through the loaders of the request, so the users
+// looked up while resolving a document are fetched together and only once.
+func (r *Resolver) retrieveUser(ctx context.Context, id string) (*store.User, error) {
+	if l := loaders.For(ctx); l != nil {
+		return l.Users.Load(ctx, id)
 	}
+	return r.Store.Retrieve(ctx, id)
+}
 
-	created, err := r.Store.Insert(ctx, u)
-	if err != nil {
-		return nil, err
+// subscribe sends the events of types about users the subscriber can see,
+// converted by value, until the subscription ends. Users are read with the
+// subscriber's context, so the same tenant rules apply as to the queries.
+func subscribe[T any](ctx context.Context, r *Resolver, value func(events.Event, *store.User) *T, types ...string) <-chan *T {
+	in := r.Events.Subscribe(ctx, types...)
+	out := make(chan *T, 1)
+	go func() {
+		defer close(out)
+		for e := range in {
+			user, err := r.Store.Retrieve(ctx, e.AggregateID())
+			if err != nil {
+				r.Logger.Errorf("failed to retrieve user %s for a subscription: %v", e.AggregateID(), err)
+				continue
+			}
+			if user == nil {
+				continue
+			}
+
+			select {
+			case out <- value(e, user):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return out
+}
+
+// emailExistsError gives errors about an email that is already taken the
+// EmailAlreadyExists code, other errors are returned unchanged.
+func emailExistsError(err error) error {
+	if !errors.Is(err, store.ErrDuplicateEmail) && !errors.Is(err, business.ErrEmailAlreadyExists) {
+		return err
 	}
+	gqlErr := gqlerror.Wrap(err)
+	gqlErr.Extensions = map[string]any{"code": foundation.EmailAlreadyExists}
+	return gqlErr
+}
 
+// toRegisterResponse converts a new user into its graphql representation.
+func toRegisterResponse(created *store.User) *model.RegisterResponse {
 	return &model.RegisterResponse{
 		ID:        &created.ID,
 		FirstName: &created.FirstName,
@@ -58,5 +104,448 @@ func (r *mutationResolver) insertUser(ctx context.Context, input model.RegisterI
 		PostCode:  &created.PostCode,
 		Terms:     &created.Terms,
 		CreatedAt: &created.CreatedAt,
+	}
+}
+
+// toLoginResponse converts an issued token into its graphql representation.
+func toLoginResponse(token *store.Token) (*model.LoginResponse, error) {
+	status := http.StatusOK
+	ttl, err := strconv.ParseInt(token.TokenTTL, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	intTTL := int(ttl)
+	return &model.LoginResponse{
+		Status:      &status,
+		AccessToken: &token.AccessToken,
+		Expiry:      &token.Expiry,
+		TokenType:   &token.TokenType,
+		LastRefresh: &token.LastRefresh,
+		TokenTTL:    &intTTL,
 	}, nil
 }
+
+// toOrganization converts a stored organization into its graphql representation.
+func toOrganization(o *store.Organization) *model.Organization {
+	return &model.Organization{
+		ID:        o.ID,
+		Name:      o.Name,
+		CreatedAt: &o.CreatedAt,
+	}
+}
+
+// toUser converts a stored user into its graphql representation.
+func toUser(u *store.User) *model.User {
+	fullName := u.FirstName + " " + u.LastName
+	return &model.User{
+		ID:            u.ID,
+		Email:         u.Email,
+		Name:          &fullName,
+		FirstName:     &u.FirstName,
+		LastName:      &u.LastName,
+		Company:       &u.Company,
+		PostCode:      &u.PostCode,
+		Locale:        &u.Locale,
+		Timezone:      &u.Timezone,
+		Picture:       &u.PictureURL,
+		EmailVerified: false,
+		Roles:         u.Roles,
+		Role:          builtInRole(u.Roles),
+		Active:        &u.Active,
+		CreatedAt:     &u.CreatedAt,
+	}
+}
+
+var errNegativeFirst = invalidArgument(errors.New("first can not be negative"))
+
+// userSorts maps the graphql sort fields to the fields the store orders users by.
+var userSorts = map[model.UserSortField]store.UserSort{
+	model.UserSortFieldCreatedAt: store.SortCreatedAt,
+	model.UserSortFieldEmail:     store.SortEmail,
+	model.UserSortFieldLastName:  store.SortLastName,
+}
+
+// listOptions converts the arguments of the users query into store.ListOptions.
+func listOptions(first *int, after *string, filter *model.UserFilter, orderBy *model.UserOrder) (store.ListOptions, error) {
+	opts := store.ListOptions{Sort: store.SortCreatedAt}
+	if first != nil {
+		if *first < 0 {
+			return opts, errNegativeFirst
+		}
+		opts.First = *first
+	}
+	if after != nil {
+		opts.After = *after
+	}
+	if orderBy != nil {
+		opts.Sort = userSorts[orderBy.Field]
+		opts.Descending = orderBy.Direction == model.SortDirectionDesc
+	}
+	if filter == nil {
+		return opts, nil
+	}
+
+	opts.Active = filter.Active
+	if filter.Role != nil {
+		opts.Role = *filter.Role
+	}
+	if filter.Company != nil {
+		opts.Company = *filter.Company
+	}
+	if filter.EmailPrefix != nil {
+		opts.EmailPrefix = *filter.EmailPrefix
+	}
+	var err error
+	if filter.CreatedAfter != nil {
+		if opts.CreatedAfter, err = time.Parse(time.RFC3339, *filter.CreatedAfter); err != nil {
+			return opts, invalidArgument(fmt.Errorf("invalid createdAfter: %w", err))
+		}
+	}
+	if filter.CreatedBefore != nil {
+		if opts.CreatedBefore, err = time.Parse(time.RFC3339, *filter.CreatedBefore); err != nil {
+			return opts, invalidArgument(fmt.Errorf("invalid createdBefore: %w", err))
+		}
+	}
+
+	return opts, nil
+}
+
+// toUsersConnection converts a page of users into a relay connection.
+func toUsersConnection(page *store.UserPage, opts store.ListOptions) *model.UsersConnection {
+	conn := &model.UsersConnection{
+		Edges: make([]*model.UserEdge, 0, len(page.Users)),
+		PageInfo: &model.PageInfo{
+			HasNextPage:     page.HasNextPage,
+			HasPreviousPage: opts.After != "",
+		},
+	}
+	for _, u := range page.Users {
+		conn.Edges = append(conn.Edges, &model.UserEdge{
+			Cursor: store.UserCursor(u, opts.Sort),
+			Node:   toUser(u),
+		})
+	}
+	if len(conn.Edges) > 0 {
+		conn.PageInfo.StartCursor = &conn.Edges[0].Cursor
+		conn.PageInfo.EndCursor = &conn.Edges[len(conn.Edges)-1].Cursor
+	}
+
+	return conn
+}
+
+// searchOptions converts the arguments of the searchUsers query into store.SearchOptions.
+func searchOptions(query string, first *int, after *string) (store.SearchOptions, error) {
+	opts := store.SearchOptions{Query: query}
+	if first != nil {
+		if *first < 0 {
+			return opts, errNegativeFirst
+		}
+		opts.First = *first
+	}
+	if after != nil {
+		opts.After = *after
+	}
+	return opts, nil
+}
+
+// toSearchConnection converts a page of search results into a connection,
+// the cursor of each edge is its position in the results.
+func toSearchConnection(page *store.UserPage, opts store.SearchOptions) *model.UsersConnection {
+	// the store already rejected a malformed cursor.
+	offset, _ := store.SearchOffset(opts.After)
+	conn := &model.UsersConnection{
+		Edges: make([]*model.UserEdge, 0, len(page.Users)),
+		PageInfo: &model.PageInfo{
+			HasNextPage:     page.HasNextPage,
+			HasPreviousPage: offset > 0,
+		},
+	}
+	for i, u := range page.Users {
+		conn.Edges = append(conn.Edges, &model.UserEdge{
+			Cursor: store.SearchCursor(offset + i + 1),
+			Node:   toUser(u),
+		})
+	}
+	if len(conn.Edges) > 0 {
+		conn.PageInfo.StartCursor = &conn.Edges[0].Cursor
+		conn.PageInfo.EndCursor = &conn.Edges[len(conn.Edges)-1].Cursor
+	}
+
+	return conn
+}
+
+// auditOptions converts the arguments of the auditEvents query into store.AuditOptions.
+func auditOptions(first *int, after *string, filter *model.AuditEventFilter) (store.AuditOptions, error) {
+	var opts store.AuditOptions
+	if first != nil {
+		if *first < 0 {
+			return opts, errNegativeFirst
+		}
+		opts.First = *first
+	}
+	if after != nil {
+		opts.After = *after
+	}
+	if filter == nil {
+		return opts, nil
+	}
+
+	if filter.ActorID != nil {
+		opts.ActorID = *filter.ActorID
+	}
+	if filter.TargetID != nil {
+		opts.TargetID = *filter.TargetID
+	}
+	if filter.Action != nil {
+		opts.Action = *filter.Action
+	}
+	var err error
+	if filter.Since != nil {
+		if opts.Since, err = time.Parse(time.RFC3339, *filter.Since); err != nil {
+			return opts, invalidArgument(fmt.Errorf("invalid since: %w", err))
+		}
+	}
+	if filter.Until != nil {
+		if opts.Until, err = time.Parse(time.RFC3339, *filter.Until); err != nil {
+			return opts, invalidArgument(fmt.Errorf("invalid until: %w", err))
+		}
+	}
+
+	return opts, nil
+}
+
+// toAuditEventsConnection converts a page of audit events into a relay connection.
+func toAuditEventsConnection(page *store.AuditPage, opts store.AuditOptions) *model.AuditEventsConnection {
+	conn := &model.AuditEventsConnection{
+		Edges: make([]*model.AuditEventEdge, 0, len(page.Events)),
+		PageInfo: &model.PageInfo{
+			HasNextPage:     page.HasNextPage,
+			HasPreviousPage: opts.After != "",
+		},
+	}
+	for _, e := range page.Events {
+		conn.Edges = append(conn.Edges, &model.AuditEventEdge{
+			Cursor: store.AuditCursor(e),
+			Node:   toAuditEvent(e),
+		})
+	}
+	if len(conn.Edges) > 0 {
+		conn.PageInfo.StartCursor = &conn.Edges[0].Cursor
+		conn.PageInfo.EndCursor = &conn.Edges[len(conn.Edges)-1].Cursor
+	}
+
+	return conn
+}
+
+func toAuditEvent(e *store.AuditEvent) *model.AuditEvent {
+	event := &model.AuditEvent{
+		ID:             e.ID,
+		OrganizationID: e.OrganizationID,
+		ActorID:        e.ActorID,
+		Action:         e.Action,
+		TargetType:     e.TargetType,
+		TargetID:       e.TargetID,
+		IP:             e.IP,
+		UserAgent:      e.UserAgent,
+		RequestID:      e.RequestID,
+		CreatedAt:      e.CreatedAt.Format(time.RFC3339Nano),
+	}
+	if len(e.Before) > 0 {
+		before := string(e.Before)
+		event.Before = &before
+	}
+	if len(e.After) > 0 {
+		after := string(e.After)
+		event.After = &after
+	}
+	return event
+}
+
+// loginHistoryOptions converts the arguments of the loginHistory query into store.LoginHistoryOptions.
+func loginHistoryOptions(first *int, after *string) (store.LoginHistoryOptions, error) {
+	var opts store.LoginHistoryOptions
+	if first != nil {
+		if *first < 0 {
+			return opts, errNegativeFirst
+		}
+		opts.First = *first
+	}
+	if after != nil {
+		opts.After = *after
+	}
+	return opts, nil
+}
+
+// toLoginAttemptsConnection converts a page of login attempts into a relay connection.
+func toLoginAttemptsConnection(page *store.LoginAttemptPage, opts store.LoginHistoryOptions) *model.LoginAttemptsConnection {
+	conn := &model.LoginAttemptsConnection{
+		Edges: make([]*model.LoginAttemptEdge, 0, len(page.Attempts)),
+		PageInfo: &model.PageInfo{
+			HasNextPage:     page.HasNextPage,
+			HasPreviousPage: opts.After != "",
+		},
+	}
+	for _, a := range page.Attempts {
+		attempt := &model.LoginAttempt{
+			ID:        a.ID,
+			UserID:    a.UserID,
+			IP:        a.IP,
+			UserAgent: a.UserAgent,
+			Success:   a.Success,
+			CreatedAt: a.CreatedAt.Format(time.RFC3339Nano),
+		}
+		if a.FailureReason != "" {
+			attempt.FailureReason = &a.FailureReason
+		}
+		conn.Edges = append(conn.Edges, &model.LoginAttemptEdge{
+			Cursor: store.LoginAttemptCursor(a),
+			Node:   attempt,
+		})
+	}
+	if len(conn.Edges) > 0 {
+		conn.PageInfo.StartCursor = &conn.Edges[0].Cursor
+		conn.PageInfo.EndCursor = &conn.Edges[len(conn.Edges)-1].Cursor
+	}
+
+	return conn
+}
+
+func toUsers(users []*store.User) []*model.User {
+	result := make([]*model.User, 0, len(users))
+	for _, u := range users {
+		result = append(result, toUser(u))
+	}
+	return result
+}
+
+// toRoleDefinition converts a stored role into its graphql representation.
+func toRoleDefinition(r *store.Role) *model.RoleDefinition {
+	return &model.RoleDefinition{
+		Name:        r.Name,
+		Description: &r.Description,
+		BuiltIn:     r.BuiltIn,
+		Permissions: r.Permissions,
+	}
+}
+
+// roleName maps a built-in graphql role to the name it is stored under.
+func roleName(role model.Role) string {
+	return strings.ToLower(role.String())
+}
+
+// builtInRole picks the most privileged built-in role out of the roles assigned to a user.
+func builtInRole(roles []string) model.Role {
+	switch business.BuiltInRole(roles) {
+	case authz.RoleSuperAdmin:
+		return model.RoleSuperadmin
+	case authz.RoleAdmin:
+		return model.RoleAdmin
+	default:
+		return model.RoleUser
+	}
+}
+
+func (r *Resolver) userRoles(ctx context.Context, userID string) (*model.UserRolesResponse, error) {
+	roles, err := r.Store.UserRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.UserRolesResponse{
+		UserID: userID,
+		Roles:  roles,
+	}, nil
+}
+
+// toInvitation converts a store invitation into its graphql representation.
+func toInvitation(inv *store.Invitation) *model.Invitation {
+	return &model.Invitation{
+		ID:             inv.ID,
+		Email:          inv.Email,
+		Role:           inv.Role,
+		OrganizationID: inv.OrganizationID,
+		InvitedBy:      &inv.InvitedBy,
+		ExpiresAt:      inv.ExpiresAt.UTC().Format(time.RFC3339),
+		Expired:        inv.Expired(),
+		CreatedAt:      &inv.CreatedAt,
+	}
+}
+
+func webhookDeliveryOptions(first *int, after *string) (store.WebhookDeliveryOptions, error) {
+	var opts store.WebhookDeliveryOptions
+	if first != nil {
+		if *first < 0 {
+			return opts, errNegativeFirst
+		}
+		opts.First = *first
+	}
+	if after != nil {
+		opts.After = *after
+	}
+	return opts, nil
+}
+
+func toWebhook(w *store.Webhook) *model.Webhook {
+	webhook := &model.Webhook{
+		ID:             w.ID,
+		OrganizationID: w.OrganizationID,
+		URL:            w.URL,
+		EventTypes:     w.EventTypes,
+		CreatedBy:      w.CreatedBy,
+		CreatedAt:      w.CreatedAt.Format(time.RFC3339Nano),
+	}
+	if w.Secret != "" {
+		webhook.Secret = &w.Secret
+	}
+	return webhook
+}
+
+func toWebhookDelivery(d *store.WebhookDelivery) *model.WebhookDelivery {
+	delivery := &model.WebhookDelivery{
+		ID:           d.ID,
+		WebhookID:    d.WebhookID,
+		EventID:      d.EventID,
+		EventType:    d.EventType,
+		UserID:       d.UserID,
+		Payload:      string(d.Payload),
+		Status:       d.Status,
+		Attempts:     d.Attempts,
+		ResponseCode: d.ResponseCode,
+		CreatedAt:    d.CreatedAt.Format(time.RFC3339Nano),
+	}
+	if d.LastError != "" {
+		delivery.LastError = &d.LastError
+	}
+	if d.NextAttemptAt != nil {
+		next := d.NextAttemptAt.Format(time.RFC3339Nano)
+		delivery.NextAttemptAt = &next
+	}
+	if d.DeliveredAt != nil {
+		delivered := d.DeliveredAt.Format(time.RFC3339Nano)
+		delivery.DeliveredAt = &delivered
+	}
+	return delivery
+}
+
+// toWebhookDeliveriesConnection converts a page of webhook deliveries into a relay connection.
+func toWebhookDeliveriesConnection(page *store.WebhookDeliveryPage, opts store.WebhookDeliveryOptions) *model.WebhookDeliveriesConnection {
+	conn := &model.WebhookDeliveriesConnection{
+		Edges: make([]*model.WebhookDeliveryEdge, 0, len(page.Deliveries)),
+		PageInfo: &model.PageInfo{
+			HasNextPage:     page.HasNextPage,
+			HasPreviousPage: opts.After != "",
+		},
+	}
+	for _, d := range page.Deliveries {
+		conn.Edges = append(conn.Edges, &model.WebhookDeliveryEdge{
+			Cursor: store.WebhookDeliveryCursor(d),
+			Node:   toWebhookDelivery(d),
+		})
+	}
+	if len(conn.Edges) > 0 {
+		conn.PageInfo.StartCursor = &conn.Edges[0].Cursor
+		conn.PageInfo.EndCursor = &conn.Edges[len(conn.Edges)-1].Cursor
+	}
+
+	return conn
+}
@@ -0,0 +1,305 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/sirupsen/logrus"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/riyadennis/identity-server/business/store"
+)
+
+const (
+	// DefaultMaxDepth is how deep the selections of an operation can nest,
+	// the introspection query of GraphiQL nests 12 fields deep.
+	DefaultMaxDepth = 12
+	// DefaultMaxComplexity is the most an operation can cost, see @cost in schema.graphqls.
+	DefaultMaxComplexity = 5000
+	// DefaultMaxAliases is how many aliased fields an operation can have.
+	DefaultMaxAliases = 15
+	// DefaultMaxBodyBytes is the largest request body the server reads.
+	DefaultMaxBodyBytes = 1 << 20
+)
+
+// Codes in the extensions of the errors for rejected requests.
+const (
+	errDepthLimit      = "DEPTH_LIMIT_EXCEEDED"
+	errAliasLimit      = "ALIAS_LIMIT_EXCEEDED"
+	errRequestTooLarge = "REQUEST_TOO_LARGE"
+
+	errIntrospectionDisabled = "INTROSPECTION_DISABLED"
+)
+
+// Limits bounds the work a single request can make the server do. A limit
+// of 0 turns it off.
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+	MaxAliases    int
+	MaxBodyBytes  int64
+	// Introspection serves the schema to introspection queries and the
+	// playground at /, it should be off in production.
+	Introspection bool
+}
+
+// DefaultLimits are the limits used when none are configured.
+func DefaultLimits() Limits {
+	return Limits{
+		MaxDepth:      DefaultMaxDepth,
+		MaxComplexity: DefaultMaxComplexity,
+		MaxAliases:    DefaultMaxAliases,
+		MaxBodyBytes:  DefaultMaxBodyBytes,
+		Introspection: true,
+	}
+}
+
+// NewENVLimits reads the limits from GRAPHQL_MAX_DEPTH, GRAPHQL_MAX_COMPLEXITY,
+// GRAPHQL_MAX_ALIASES and GRAPHQL_MAX_BODY_BYTES, the defaults are used for
+// the ones that are empty or invalid. Introspection is off when ENV is
+// production unless GRAPHQL_INTROSPECTION says otherwise.
+func NewENVLimits(logger *logrus.Logger) Limits {
+	l := DefaultLimits()
+	l.MaxDepth = envLimit(logger, "GRAPHQL_MAX_DEPTH", l.MaxDepth)
+	l.MaxComplexity = envLimit(logger, "GRAPHQL_MAX_COMPLEXITY", l.MaxComplexity)
+	l.MaxAliases = envLimit(logger, "GRAPHQL_MAX_ALIASES", l.MaxAliases)
+	l.MaxBodyBytes = int64(envLimit(logger, "GRAPHQL_MAX_BODY_BYTES", int(l.MaxBodyBytes)))
+
+	l.Introspection = os.Getenv("ENV") != "production"
+	if v := os.Getenv("GRAPHQL_INTROSPECTION"); v != "" {
+		introspection, err := strconv.ParseBool(v)
+		if err != nil {
+			logger.Errorf("invalid GRAPHQL_INTROSPECTION %q, introspection is %t", v, l.Introspection)
+		} else {
+			l.Introspection = introspection
+		}
+	}
+
+	return l
+}
+
+func envLimit(logger *logrus.Logger, name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit < 0 {
+		logger.Errorf("invalid %s %q, using %d", name, v, def)
+		return def
+	}
+	return limit
+}
+
+// queryLimits rejects operations that nest deeper than maxDepth or have more
+// than maxAliases aliased fields before any resolver runs. Introspection
+// fields are counted like any other, only __typename, which selects nothing,
+// is left out.
+type queryLimits struct {
+	maxDepth   int
+	maxAliases int
+}
+
+var _ interface {
+	graphql.OperationContextMutator
+	graphql.HandlerExtension
+} = queryLimits{}
+
+func (queryLimits) ExtensionName() string {
+	return "QueryLimits"
+}
+
+func (queryLimits) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// rejectIntrospection fails the introspection fields as a mistake of the
+// client when introspection is disabled, rather than as a server error.
+func rejectIntrospection(ctx context.Context, next graphql.RootResolver) graphql.Marshaler {
+	field := graphql.GetRootFieldContext(ctx).Field
+	if field.Name != "__schema" && field.Name != "__type" {
+		return next(ctx)
+	}
+
+	err := gqlerror.ErrorPathf(ast.Path{ast.PathName(field.Alias)}, "introspection disabled")
+	errcode.Set(err, errIntrospectionDisabled)
+	graphql.AddError(ctx, err)
+	return graphql.Null
+}
+
+func (q queryLimits) MutateOperationContext(_ context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	op := opCtx.Doc.Operations.ForName(opCtx.OperationName)
+	if op == nil {
+		return nil
+	}
+
+	if depth := selectionDepth(op.SelectionSet, map[string]int{}); q.maxDepth > 0 && depth > q.maxDepth {
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", depth, q.maxDepth)
+		errcode.Set(err, errDepthLimit)
+		return err
+	}
+	if aliases := aliasCount(op.SelectionSet, map[string]int{}); q.maxAliases > 0 && aliases > q.maxAliases {
+		err := gqlerror.Errorf("operation has %d aliases, which exceeds the limit of %d", aliases, q.maxAliases)
+		errcode.Set(err, errAliasLimit)
+		return err
+	}
+
+	return nil
+}
+
+// selectionDepth is how many fields deep the selection set nests, fragments
+// count as the fields they select. The depth of every fragment is kept in
+// fragments, so fragments spread many times are only walked once.
+func selectionDepth(set ast.SelectionSet, fragments map[string]int) int {
+	depth := 0
+	for _, selection := range set {
+		var d int
+		switch s := selection.(type) {
+		case *ast.Field:
+			if s.Name == "__typename" {
+				continue
+			}
+			d = 1 + selectionDepth(s.SelectionSet, fragments)
+		case *ast.FragmentSpread:
+			fd, ok := fragments[s.Name]
+			if !ok {
+				fd = selectionDepth(s.Definition.SelectionSet, fragments)
+				fragments[s.Name] = fd
+			}
+			d = fd
+		case *ast.InlineFragment:
+			d = selectionDepth(s.SelectionSet, fragments)
+		}
+		depth = max(depth, d)
+	}
+	return depth
+}
+
+// aliasCount is the number of fields in the selection set selected under
+// another name, fragments count every time they are spread. The count of
+// every fragment is kept in fragments, so fragments spread many times are
+// only walked once, and it stops growing at math.MaxInt.
+func aliasCount(set ast.SelectionSet, fragments map[string]int) int {
+	count := 0
+	for _, selection := range set {
+		var c int
+		switch s := selection.(type) {
+		case *ast.Field:
+			if s.Alias != "" && s.Alias != s.Name {
+				c = 1
+			}
+			c = addCount(c, aliasCount(s.SelectionSet, fragments))
+		case *ast.FragmentSpread:
+			fc, ok := fragments[s.Name]
+			if !ok {
+				fc = aliasCount(s.Definition.SelectionSet, fragments)
+				fragments[s.Name] = fc
+			}
+			c = fc
+		case *ast.InlineFragment:
+			c = aliasCount(s.SelectionSet, fragments)
+		}
+		count = addCount(count, c)
+	}
+	return count
+}
+
+// addCount adds two counts without going over math.MaxInt.
+func addCount(a, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+// costSchema scores fields for the complexity limit with the @cost directive
+// of their definition. A field costs its weight plus the cost of its
+// selections once for every item it returns: the first argument, at most
+// store.MaxPageSize, or else the listSize of @cost. Fields with neither cost
+// 1 plus their selections.
+type costSchema struct {
+	graphql.ExecutableSchema
+}
+
+func (s costSchema) Complexity(ctx context.Context, typeName, field string, childComplexity int, args map[string]any) (int, bool) {
+	var def *ast.FieldDefinition
+	if t := s.Schema().Types[typeName]; t != nil {
+		def = t.Fields.ForName(field)
+	}
+	if def == nil {
+		return s.ExecutableSchema.Complexity(ctx, typeName, field, childComplexity, args)
+	}
+
+	weight, items := 1, 1
+	cost := def.Directives.ForName("cost")
+	if cost != nil {
+		costArgs := cost.ArgumentMap(nil)
+		weight = intValue(costArgs["weight"], weight)
+		items = intValue(costArgs["listSize"], items)
+	}
+	first := intValue(args["first"], 0)
+	if first > 0 {
+		items = min(first, store.MaxPageSize)
+	}
+	if cost == nil && first == 0 {
+		return s.ExecutableSchema.Complexity(ctx, typeName, field, childComplexity, args)
+	}
+
+	if childComplexity > 0 && items > (math.MaxInt-weight)/childComplexity {
+		return math.MaxInt, true
+	}
+	return weight + items*childComplexity, true
+}
+
+// intValue converts the value of an Int argument, def is returned when it is missing.
+func intValue(v any, def int) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int64:
+		return int(n)
+	case *int:
+		if n != nil {
+			return *n
+		}
+	}
+	return def
+}
+
+// limitBody answers requests with a body over limit bytes with a 413 in the
+// format of GraphQL errors, bodies sent without a length stop being read at limit.
+func limitBody(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limit <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r.ContentLength > limit {
+				err := gqlerror.Errorf("request body has %d bytes, which exceeds the limit of %d", r.ContentLength, limit)
+				errcode.Set(err, errRequestTooLarge)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusRequestEntityTooLarge)
+				_ = json.NewEncoder(w).Encode(graphql.Response{Errors: gqlerror.List{err}})
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// String describes the limits for the startup log.
+func (l Limits) String() string {
+	return fmt.Sprintf("depth %d, complexity %d, aliases %d, body %d bytes, introspection %t",
+		l.MaxDepth, l.MaxComplexity, l.MaxAliases, l.MaxBodyBytes, l.Introspection)
+}
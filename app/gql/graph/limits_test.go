@@ -0,0 +1,341 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/store"
+	customMiddleware "github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+type limitsResponse struct {
+	Data   map[string]any
+	Errors []struct {
+		Message    string
+		Extensions struct{ Code string }
+	}
+}
+
+// serveWithLimits sends r with a token of user 1, who can read users, to a
+// router with limits.
+func serveWithLimits(t *testing.T, limits Limits, r *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	st := &mocks.Store{
+		User:          &store.User{ID: "1", Email: testEmail},
+		Permissions:   []string{authz.UsersRead},
+		Organizations: []*store.Organization{{ID: "org-1"}},
+	}
+	res := newResolver(st, &mocks.Authenticator{}, tokenConfig())
+	ac := &customMiddleware.AuthConfig{TokenConfig: tokenConfig(), Authorizer: res.Authorizer, Logger: res.Logger}
+
+	r.Header.Set("Authorization", testToken(t, "1"))
+	w := httptest.NewRecorder()
+	newRouter(ac, res.Store, newHandler(res, ac, limits, nil), limits, nil).ServeHTTP(w, r)
+	return w
+}
+
+func TestLimits_Operations(t *testing.T) {
+	limits := DefaultLimits()
+	limits.MaxDepth = 3
+	limits.MaxAliases = 2
+	limits.MaxComplexity = 100
+
+	scenarios := []struct {
+		name         string
+		query        string
+		expectedCode string
+	}{
+		{
+			name:  "within the limits",
+			query: `{ users(first: 5) { pageInfo { hasNextPage } } }`,
+		},
+		{
+			name:         "too deep",
+			query:        `{ users { edges { node { id } } } }`,
+			expectedCode: errDepthLimit,
+		},
+		{
+			name:         "too deep through a fragment",
+			query:        `query { users { ...edges } } fragment edges on UsersConnection { edges { node { id } } }`,
+			expectedCode: errDepthLimit,
+		},
+		{
+			name:         "introspection is counted",
+			query:        `{ __schema { types { fields { type { name } } } } }`,
+			expectedCode: errDepthLimit,
+		},
+		{
+			name:  "__typename is not counted",
+			query: `{ users { edges { node { __typename } } } }`,
+		},
+		{
+			name:         "aliased __typename",
+			query:        `{ a: __typename b: __typename c: __typename }`,
+			expectedCode: errAliasLimit,
+		},
+		{
+			name:         "too many aliases",
+			query:        `{ a: me { id } b: me { id } c: me { id } }`,
+			expectedCode: errAliasLimit,
+		},
+		{
+			name:  "aliases within the limit",
+			query: `{ a: me { id } b: me { id } }`,
+		},
+		{
+			name:         "first over the complexity limit",
+			query:        `{ users(first: 50) { pageInfo { hasNextPage } } }`,
+			expectedCode: "COMPLEXITY_LIMIT_EXCEEDED",
+		},
+		{
+			name:  "listSize within the complexity limit",
+			query: `{ users { pageInfo { hasNextPage } } }`,
+		},
+		{
+			name:         "weight over the complexity limit",
+			query:        `{ searchUsers(query: "jane", first: 46) { pageInfo { hasNextPage } } }`,
+			expectedCode: "COMPLEXITY_LIMIT_EXCEEDED",
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			w := serveWithLimits(t, limits, postRequest(t, map[string]any{"query": sc.query}))
+
+			var resp limitsResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+			if sc.expectedCode != "" {
+				require.NotEmpty(t, resp.Errors)
+				assert.Equal(t, sc.expectedCode, resp.Errors[0].Extensions.Code)
+				assert.Nil(t, resp.Data)
+				return
+			}
+			assert.Empty(t, resp.Errors)
+		})
+	}
+}
+
+func TestLimits_FragmentChain(t *testing.T) {
+	// every fragment spreads the next one twice, walking each spread would
+	// visit the last fragment 2^40 times
+	var query strings.Builder
+	query.WriteString(`{ ...f0 }`)
+	for i := range 40 {
+		fmt.Fprintf(&query, ` fragment f%d on Query { ...f%d ...f%d }`, i, i+1, i+1)
+	}
+	query.WriteString(` fragment f40 on Query { a: me { id } }`)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- serveWithLimits(t, DefaultLimits(), postRequest(t, map[string]any{"query": query.String()}))
+	}()
+
+	select {
+	case w := <-done:
+		var resp limitsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+		require.NotEmpty(t, resp.Errors)
+		assert.Equal(t, errAliasLimit, resp.Errors[0].Extensions.Code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("limits are not checked in time")
+	}
+}
+
+func TestLimits_IntrospectionQuery(t *testing.T) {
+	// the type references of the introspection query of GraphiQL
+	typeRef := strings.Repeat("ofType { kind name ", 7) + strings.Repeat("} ", 7)
+	query := `{ __schema { types { name fields { name type { kind name ` + typeRef + `} } } } }`
+
+	w := serveWithLimits(t, DefaultLimits(), postRequest(t, map[string]any{"query": query}))
+
+	var resp limitsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+	assert.Empty(t, resp.Errors)
+	assert.NotNil(t, resp.Data)
+}
+
+func TestLimits_Body(t *testing.T) {
+	limits := DefaultLimits()
+	limits.MaxBodyBytes = 64
+
+	t.Run("content length over the limit", func(t *testing.T) {
+		w := serveWithLimits(t, limits, postRequest(t, map[string]any{"query": `{ me { id } }`, "variables": map[string]any{"padding": strings.Repeat("x", 64)}}))
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+		var resp limitsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.Len(t, resp.Errors, 1)
+		assert.Equal(t, errRequestTooLarge, resp.Errors[0].Extensions.Code)
+	})
+
+	t.Run("unknown length over the limit", func(t *testing.T) {
+		r := postRequest(t, map[string]any{"query": `{ me { id } }`, "variables": map[string]any{"padding": strings.Repeat("x", 64)}})
+		r.ContentLength = -1
+		r.Body = io.NopCloser(io.MultiReader(r.Body))
+		w := serveWithLimits(t, limits, r)
+
+		var resp limitsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		require.NotEmpty(t, resp.Errors)
+		assert.Nil(t, resp.Data)
+	})
+
+	t.Run("within the limit", func(t *testing.T) {
+		w := serveWithLimits(t, limits, postRequest(t, map[string]any{"query": `{ me { id } }`}))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"data":{"me":{"id":"1"}}}`, w.Body.String())
+	})
+}
+
+func TestLimits_Introspection(t *testing.T) {
+	const query = `{ __schema { queryType { name } } }`
+	scenarios := []struct {
+		name          string
+		introspection bool
+		expectedError string
+		expectedCode  int
+	}{
+		{
+			name:          "enabled",
+			introspection: true,
+			expectedCode:  http.StatusOK,
+		},
+		{
+			name:          "disabled",
+			expectedError: "introspection disabled",
+			expectedCode:  http.StatusNotFound,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			limits := DefaultLimits()
+			limits.Introspection = sc.introspection
+
+			w := serveWithLimits(t, limits, postRequest(t, map[string]any{"query": query}))
+			var resp limitsResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			if sc.expectedError != "" {
+				require.NotEmpty(t, resp.Errors)
+				assert.Contains(t, resp.Errors[0].Message, sc.expectedError)
+				assert.Equal(t, errIntrospectionDisabled, resp.Errors[0].Extensions.Code)
+			} else {
+				assert.Empty(t, resp.Errors)
+			}
+
+			w = serveWithLimits(t, limits, httptest.NewRequest(http.MethodGet, "/", nil))
+			assert.Equal(t, sc.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestCostSchema_Complexity(t *testing.T) {
+	schema := NewExecutableSchema(newResolver(&mocks.Store{}, &mocks.Authenticator{}, tokenConfig()))
+	scenarios := []struct {
+		name     string
+		field    string
+		args     map[string]any
+		expected int
+	}{
+		{
+			name:  "without a cost",
+			field: "me",
+		},
+		{
+			name:     "list size",
+			field:    "listUsers",
+			expected: 1 + 100*3,
+		},
+		{
+			name:     "first replaces the list size",
+			field:    "users",
+			args:     map[string]any{"first": int64(5)},
+			expected: 1 + 5*3,
+		},
+		{
+			name:     "first is capped at the page size",
+			field:    "users",
+			args:     map[string]any{"first": int64(1000)},
+			expected: 1 + store.MaxPageSize*3,
+		},
+		{
+			name:     "weight",
+			field:    "searchUsers",
+			expected: 10 + 20*3,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			complexity, ok := schema.Complexity(context.Background(), "Query", sc.field, 3, sc.args)
+			if sc.expected == 0 {
+				// gqlgen scores the field 1 plus its selections.
+				assert.False(t, ok)
+				return
+			}
+			assert.True(t, ok)
+			assert.Equal(t, sc.expected, complexity)
+		})
+	}
+}
+
+func TestNewENVLimits(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		env      map[string]string
+		expected Limits
+	}{
+		{
+			name:     "defaults",
+			expected: DefaultLimits(),
+		},
+		{
+			name: "configured",
+			env: map[string]string{
+				"GRAPHQL_MAX_DEPTH":      "5",
+				"GRAPHQL_MAX_COMPLEXITY": "0",
+				"GRAPHQL_MAX_ALIASES":    "3",
+				"GRAPHQL_MAX_BODY_BYTES": "1024",
+			},
+			expected: Limits{MaxDepth: 5, MaxAliases: 3, MaxBodyBytes: 1024, Introspection: true},
+		},
+		{
+			name:     "invalid values",
+			env:      map[string]string{"GRAPHQL_MAX_DEPTH": "deep", "GRAPHQL_MAX_ALIASES": "-1"},
+			expected: DefaultLimits(),
+		},
+		{
+			name: "production",
+			env:  map[string]string{"ENV": "production"},
+			expected: func() Limits {
+				l := DefaultLimits()
+				l.Introspection = false
+				return l
+			}(),
+		},
+		{
+			name:     "introspection enabled in production",
+			env:      map[string]string{"ENV": "production", "GRAPHQL_INTROSPECTION": "true"},
+			expected: DefaultLimits(),
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			for _, name := range []string{"ENV", "GRAPHQL_MAX_DEPTH", "GRAPHQL_MAX_COMPLEXITY", "GRAPHQL_MAX_ALIASES", "GRAPHQL_MAX_BODY_BYTES", "GRAPHQL_INTROSPECTION"} {
+				t.Setenv(name, sc.env[name])
+			}
+			assert.Equal(t, sc.expected, NewENVLimits(logrus.New()))
+		})
+	}
+}
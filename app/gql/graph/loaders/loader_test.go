@@ -0,0 +1,155 @@
+package loaders
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// upper records the keys of each fetch and loads keys as their upper case,
+// keys starting with "missing" are not found.
+type upper struct {
+	mu      sync.Mutex
+	batches [][]string
+	err     error
+}
+
+func (u *upper) fetch(_ context.Context, keys []string) (map[string]string, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.batches = append(u.batches, keys)
+	if u.err != nil {
+		return nil, u.err
+	}
+
+	values := make(map[string]string)
+	for _, k := range keys {
+		if !strings.HasPrefix(k, "missing") {
+			values[k] = strings.ToUpper(k)
+		}
+	}
+	return values, nil
+}
+
+func loadConcurrently(t *testing.T, l *Loader[string, string], keys ...string) []string {
+	t.Helper()
+	values := make([]string, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := l.Load(context.Background(), key)
+			assert.NoError(t, err)
+			values[i] = v
+		}()
+	}
+	wg.Wait()
+	return values
+}
+
+func TestLoader_Load(t *testing.T) {
+	scenarios := []struct {
+		name            string
+		maxBatch        int
+		keys            []string
+		expectedValues  []string
+		expectedBatches int
+	}{
+		{
+			name:            "batched",
+			maxBatch:        10,
+			keys:            []string{"a", "b", "c"},
+			expectedValues:  []string{"A", "B", "C"},
+			expectedBatches: 1,
+		},
+		{
+			name:            "same key loaded once",
+			maxBatch:        10,
+			keys:            []string{"a", "a", "a"},
+			expectedValues:  []string{"A", "A", "A"},
+			expectedBatches: 1,
+		},
+		{
+			name:            "split in full batches",
+			maxBatch:        2,
+			keys:            []string{"a", "b", "c", "d"},
+			expectedValues:  []string{"A", "B", "C", "D"},
+			expectedBatches: 2,
+		},
+		{
+			name:            "missing key",
+			maxBatch:        10,
+			keys:            []string{"a", "missing"},
+			expectedValues:  []string{"A", ""},
+			expectedBatches: 1,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			u := &upper{}
+			l := NewLoader(u.fetch, 50*time.Millisecond, sc.maxBatch)
+
+			assert.Equal(t, sc.expectedValues, loadConcurrently(t, l, sc.keys...))
+			assert.Len(t, u.batches, sc.expectedBatches)
+			for _, b := range u.batches {
+				assert.LessOrEqual(t, len(b), sc.maxBatch)
+			}
+		})
+	}
+}
+
+func TestLoader_Cache(t *testing.T) {
+	u := &upper{}
+	l := NewLoader(u.fetch, time.Millisecond, 10)
+
+	loadConcurrently(t, l, "a")
+	loadConcurrently(t, l, "a")
+	assert.Equal(t, [][]string{{"a"}}, u.batches)
+
+	l.Clear("a")
+	loadConcurrently(t, l, "a")
+	assert.Equal(t, [][]string{{"a"}, {"a"}}, u.batches)
+}
+
+func TestLoader_Error(t *testing.T) {
+	u := &upper{err: errors.New("database down")}
+	l := NewLoader(u.fetch, time.Millisecond, 10)
+
+	_, err := l.Load(context.Background(), "a")
+	assert.Equal(t, u.err, err)
+
+	u.mu.Lock()
+	u.err = nil
+	u.mu.Unlock()
+	v, err := l.Load(context.Background(), "a")
+	require.NoError(t, err)
+	assert.Equal(t, "A", v, "failed loads are not cached")
+	assert.Len(t, u.batches, 2)
+}
+
+func TestLoader_LoadAll(t *testing.T) {
+	u := &upper{}
+	l := NewLoader(u.fetch, time.Millisecond, 10)
+
+	values, err := l.LoadAll(context.Background(), []string{"c", "a", "missing", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"C", "A", "", "B"}, values)
+	assert.Len(t, u.batches, 1)
+}
+
+func TestLoader_Cancelled(t *testing.T) {
+	u := &upper{}
+	l := NewLoader(u.fetch, time.Hour, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := l.Load(ctx, "a")
+	assert.ErrorIs(t, err, context.Canceled)
+}
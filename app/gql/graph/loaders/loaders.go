@@ -0,0 +1,68 @@
+package loaders
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/riyadennis/identity-server/business/store"
+)
+
+type loadersKey struct{}
+
+const (
+	// wait is how long loads are collected before a batch is fetched.
+	wait = 2 * time.Millisecond
+	// maxBatch keeps the IN lists of the batched queries short.
+	maxBatch = store.MaxPageSize
+)
+
+// Loaders batch and cache the lookups of a single request.
+type Loaders struct {
+	Users *Loader[string, *store.User]
+}
+
+// NewLoaders returns loaders fetching from st, the users are scoped to the
+// tenant of the context they are loaded with.
+func NewLoaders(st store.Store) *Loaders {
+	return &Loaders{
+		Users: NewLoader(func(ctx context.Context, ids []string) (map[string]*store.User, error) {
+			users, err := st.RetrieveMany(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[string]*store.User, len(users))
+			for _, u := range users {
+				byID[u.ID] = u
+			}
+			return byID, nil
+		}, wait, maxBatch),
+	}
+}
+
+// Middleware gives every request its own loaders, nothing is cached between
+// requests. Websocket connections get none, they would cache users for as
+// long as the connection is open.
+func Middleware(st store.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+				next.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithLoaders(r.Context(), NewLoaders(st))))
+		})
+	}
+}
+
+// WithLoaders returns a copy of ctx carrying l.
+func WithLoaders(ctx context.Context, l *Loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+// For returns the loaders of the request, nil when Middleware was not used.
+func For(ctx context.Context) *Loaders {
+	l, _ := ctx.Value(loadersKey{}).(*Loaders)
+	return l
+}
@@ -0,0 +1,72 @@
+package loaders
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+)
+
+func TestNewLoaders_Users(t *testing.T) {
+	st := &mocks.Store{User: &store.User{Email: "jane.doe@test.com"}}
+	l := NewLoaders(st)
+
+	users, err := l.Users.LoadAll(t.Context(), []string{"1", "2"})
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "1", users[0].ID)
+	assert.Equal(t, "2", users[1].ID)
+	assert.Equal(t, [][]string{{"1", "2"}}, st.RetrievedMany)
+
+	st.User = nil
+	user, err := l.Users.Load(t.Context(), "3")
+	require.NoError(t, err)
+	assert.Nil(t, user, "users that are not found load nil")
+}
+
+func TestMiddleware(t *testing.T) {
+	scenarios := []struct {
+		name            string
+		upgrade         string
+		expectedLoaders bool
+	}{
+		{
+			name:            "http request",
+			expectedLoaders: true,
+		},
+		{
+			name:    "websocket",
+			upgrade: "websocket",
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			var seen []*Loaders
+			handler := Middleware(&mocks.Store{})(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				seen = append(seen, For(r.Context()))
+			}))
+
+			for range 2 {
+				r := httptest.NewRequest(http.MethodGet, "/graphql", nil)
+				if sc.upgrade != "" {
+					r.Header.Set("Upgrade", sc.upgrade)
+				}
+				handler.ServeHTTP(httptest.NewRecorder(), r)
+			}
+
+			require.Len(t, seen, 2)
+			if !sc.expectedLoaders {
+				assert.Nil(t, seen[0])
+				assert.Nil(t, seen[1])
+				return
+			}
+			assert.NotNil(t, seen[0])
+			assert.NotSame(t, seen[0], seen[1], "every request has its own loaders")
+		})
+	}
+}
@@ -9,11 +9,106 @@ import (
 	"strconv"
 )
 
+// The details an invited user fills in, token comes from the invitation link.
+type AcceptInvitationInput struct {
+	Token     string  `json:"token"`
+	FirstName string  `json:"firstName"`
+	LastName  string  `json:"lastName"`
+	Password  string  `json:"password"`
+	Company   *string `json:"company,omitempty"`
+	PostCode  *string `json:"postCode,omitempty"`
+	Terms     bool    `json:"terms"`
+}
+
 type ActivationResponse struct {
 	UserID string `json:"userId"`
 	Active bool   `json:"active"`
 }
 
+// An entry of the audit log, before and after are JSON objects with the values that changed.
+type AuditEvent struct {
+	ID             string `json:"id"`
+	OrganizationID string `json:"organizationId"`
+	// User that made the change, empty for changes made by the service or by users that are not logged in.
+	ActorID string `json:"actorId"`
+	Action  string `json:"action"`
+	// Kind of the target, user or role.
+	TargetType string  `json:"targetType"`
+	TargetID   string  `json:"targetId"`
+	Before     *string `json:"before,omitempty"`
+	After      *string `json:"after,omitempty"`
+	IP         string  `json:"ip"`
+	UserAgent  string  `json:"userAgent"`
+	RequestID  string  `json:"requestId"`
+	CreatedAt  string  `json:"createdAt"`
+}
+
+type AuditEventEdge struct {
+	Cursor string      `json:"cursor"`
+	Node   *AuditEvent `json:"node"`
+}
+
+// Narrows down the audit events listed, filters left out match every event.
+type AuditEventFilter struct {
+	ActorID  *string `json:"actorId,omitempty"`
+	TargetID *string `json:"targetId,omitempty"`
+	// Action such as role.assigned or user.deleted.
+	Action *string `json:"action,omitempty"`
+	// RFC 3339 time the events were recorded at or after.
+	Since *string `json:"since,omitempty"`
+	// RFC 3339 time the events were recorded before.
+	Until *string `json:"until,omitempty"`
+}
+
+// A page of audit events, newest first, pass pageInfo.endCursor as after to read the next one.
+type AuditEventsConnection struct {
+	Edges    []*AuditEventEdge `json:"edges"`
+	PageInfo *PageInfo         `json:"pageInfo"`
+}
+
+type CreateWebhookInput struct {
+	// Absolute http or https URL the events are posted to.
+	URL        string   `json:"url"`
+	EventTypes []string `json:"eventTypes"`
+	// At least 16 characters, one is generated when it is left out.
+	Secret *string `json:"secret,omitempty"`
+}
+
+// A pending invitation to join an organization, expired invitations can be resent.
+type Invitation struct {
+	ID             string  `json:"id"`
+	Email          string  `json:"email"`
+	Role           string  `json:"role"`
+	OrganizationID string  `json:"organizationId"`
+	InvitedBy      *string `json:"invitedBy,omitempty"`
+	ExpiresAt      string  `json:"expiresAt"`
+	Expired        bool    `json:"expired"`
+	CreatedAt      *string `json:"createdAt,omitempty"`
+}
+
+// A login with a password, failureReason is unknown_email or invalid_password when it failed.
+type LoginAttempt struct {
+	ID string `json:"id"`
+	// Empty when nobody has the email that was used.
+	UserID        string  `json:"userId"`
+	IP            string  `json:"ip"`
+	UserAgent     string  `json:"userAgent"`
+	Success       bool    `json:"success"`
+	FailureReason *string `json:"failureReason,omitempty"`
+	CreatedAt     string  `json:"createdAt"`
+}
+
+type LoginAttemptEdge struct {
+	Cursor string        `json:"cursor"`
+	Node   *LoginAttempt `json:"node"`
+}
+
+// A page of login attempts, newest first, pass pageInfo.endCursor as after to read the next one.
+type LoginAttemptsConnection struct {
+	Edges    []*LoginAttemptEdge `json:"edges"`
+	PageInfo *PageInfo           `json:"pageInfo"`
+}
+
 type LoginInput struct {
 	Email    *string `json:"email,omitempty"`
 	Password *string `json:"password,omitempty"`
@@ -31,6 +126,25 @@ type LoginResponse struct {
 type Mutation struct {
 }
 
+// A tenant, users only see and manage the members of the organization their token was issued for.
+type Organization struct {
+	ID        string  `json:"id"`
+	Name      string  `json:"name"`
+	CreatedAt *string `json:"createdAt,omitempty"`
+}
+
+type PageInfo struct {
+	HasNextPage     bool    `json:"hasNextPage"`
+	HasPreviousPage bool    `json:"hasPreviousPage"`
+	StartCursor     *string `json:"startCursor,omitempty"`
+	EndCursor       *string `json:"endCursor,omitempty"`
+}
+
+type PermissionDefinition struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+}
+
 type Query struct {
 }
 
@@ -55,34 +169,173 @@ type RegisterResponse struct {
 	CreatedAt *string `json:"createdAt,omitempty"`
 }
 
+// A role given to a user.
+type RoleAssignment struct {
+	UserID string `json:"userId"`
+	Role   string `json:"role"`
+}
+
+type RoleDefinition struct {
+	Name        string   `json:"name"`
+	Description *string  `json:"description,omitempty"`
+	BuiltIn     bool     `json:"builtIn"`
+	Permissions []string `json:"permissions"`
+}
+
+type RoleInput struct {
+	Name        string   `json:"name"`
+	Description *string  `json:"description,omitempty"`
+	Permissions []string `json:"permissions"`
+}
+
 type RoleResponse struct {
 	UserID string `json:"userId"`
 	Role   Role   `json:"role"`
 }
 
+// Live updates over the websocket transport, the connection_init payload carries the token as Authorization.
+// Subscribers only get the changes made to users of their organization while they are subscribed.
+type Subscription struct {
+}
+
+// Profile details to change, fields left out are not updated and an empty value
+// clears locale, timezone and picture.
+type UpdateProfileInput struct {
+	FirstName *string `json:"firstName,omitempty"`
+	LastName  *string `json:"lastName,omitempty"`
+	Company   *string `json:"company,omitempty"`
+	PostCode  *string `json:"postCode,omitempty"`
+	Locale    *string `json:"locale,omitempty"`
+	Timezone  *string `json:"timezone,omitempty"`
+	Picture   *string `json:"picture,omitempty"`
+}
+
+// Users can be referenced by id from the other subgraphs of the supergraph.
 type User struct {
-	ID            string  `json:"id"`
-	Email         string  `json:"email"`
-	Name          *string `json:"name,omitempty"`
-	Picture       *string `json:"picture,omitempty"`
-	EmailVerified bool    `json:"emailVerified"`
+	ID        string  `json:"id"`
+	Email     string  `json:"email"`
+	Name      *string `json:"name,omitempty"`
+	FirstName *string `json:"firstName,omitempty"`
+	LastName  *string `json:"lastName,omitempty"`
+	Company   *string `json:"company,omitempty"`
+	PostCode  *string `json:"postCode,omitempty"`
+	// BCP 47 language tag such as en-GB.
+	Locale *string `json:"locale,omitempty"`
+	// IANA time zone name such as Europe/London.
+	Timezone      *string  `json:"timezone,omitempty"`
+	Picture       *string  `json:"picture,omitempty"`
+	EmailVerified bool     `json:"emailVerified"`
+	Roles         []string `json:"roles"`
+	// ADMIN when the user has the admin role, USER otherwise.
+	Role      Role    `json:"role"`
+	Active    *bool   `json:"active,omitempty"`
+	CreatedAt *string `json:"createdAt,omitempty"`
+}
+
+func (User) IsEntity() {}
+
+type UserByIDsInput struct {
+	ID string `json:"ID"`
+}
+
+type UserEdge struct {
+	Cursor string `json:"cursor"`
+	Node   *User  `json:"node"`
 }
 
+// Narrows down the users listed, filters left out match every user.
+type UserFilter struct {
+	// Name of a role the users have been assigned.
+	Role   *string `json:"role,omitempty"`
+	Active *bool   `json:"active,omitempty"`
+	// Company of the users, it has to match exactly.
+	Company *string `json:"company,omitempty"`
+	// RFC 3339 time the users registered at or after.
+	CreatedAfter *string `json:"createdAfter,omitempty"`
+	// RFC 3339 time the users registered before.
+	CreatedBefore *string `json:"createdBefore,omitempty"`
+	EmailPrefix   *string `json:"emailPrefix,omitempty"`
+}
+
+type UserOrder struct {
+	Field     UserSortField `json:"field"`
+	Direction SortDirection `json:"direction"`
+}
+
+type UserRolesResponse struct {
+	UserID string   `json:"userId"`
+	Roles  []string `json:"roles"`
+}
+
+// A page of users, pass pageInfo.endCursor as after to read the next one.
+type UsersConnection struct {
+	Edges    []*UserEdge `json:"edges"`
+	PageInfo *PageInfo   `json:"pageInfo"`
+}
+
+// An endpoint the events it subscribes to are posted to, signed with its secret.
+type Webhook struct {
+	ID             string `json:"id"`
+	OrganizationID string `json:"organizationId"`
+	URL            string `json:"url"`
+	// Events posted to the webhook, such as user.registered.
+	EventTypes []string `json:"eventTypes"`
+	// Signs the deliveries, it is only returned by createWebhook.
+	Secret    *string `json:"secret,omitempty"`
+	CreatedBy string  `json:"createdBy"`
+	CreatedAt string  `json:"createdAt"`
+}
+
+// A page of webhook deliveries, newest first, pass pageInfo.endCursor as after to read the next one.
+type WebhookDeliveriesConnection struct {
+	Edges    []*WebhookDeliveryEdge `json:"edges"`
+	PageInfo *PageInfo              `json:"pageInfo"`
+}
+
+// An event posted, or to be posted, to a webhook. status is pending, succeeded or failed.
+type WebhookDelivery struct {
+	ID        string `json:"id"`
+	WebhookID string `json:"webhookId"`
+	EventID   string `json:"eventId"`
+	EventType string `json:"eventType"`
+	// User the event is about.
+	UserID string `json:"userId"`
+	// The event as a JSON object.
+	Payload  string `json:"payload"`
+	Status   string `json:"status"`
+	Attempts int    `json:"attempts"`
+	// HTTP status of the last attempt, 0 when it got no response.
+	ResponseCode int     `json:"responseCode"`
+	LastError    *string `json:"lastError,omitempty"`
+	// When a pending delivery is posted next.
+	NextAttemptAt *string `json:"nextAttemptAt,omitempty"`
+	DeliveredAt   *string `json:"deliveredAt,omitempty"`
+	CreatedAt     string  `json:"createdAt"`
+}
+
+type WebhookDeliveryEdge struct {
+	Cursor string           `json:"cursor"`
+	Node   *WebhookDelivery `json:"node"`
+}
+
+// Built-in roles, custom roles are managed through RoleDefinition.
 type Role string
 
 const (
-	RoleAdmin Role = "ADMIN"
-	RoleUser  Role = "USER"
+	RoleSuperadmin Role = "SUPERADMIN"
+	RoleAdmin      Role = "ADMIN"
+	RoleUser       Role = "USER"
 )
 
 var AllRole = []Role{
+	RoleSuperadmin,
 	RoleAdmin,
 	RoleUser,
 }
 
 func (e Role) IsValid() bool {
 	switch e {
-	case RoleAdmin, RoleUser:
+	case RoleSuperadmin, RoleAdmin, RoleUser:
 		return true
 	}
 	return false
@@ -122,3 +375,115 @@ func (e Role) MarshalJSON() ([]byte, error) {
 	e.MarshalGQL(&buf)
 	return buf.Bytes(), nil
 }
+
+type SortDirection string
+
+const (
+	SortDirectionAsc  SortDirection = "ASC"
+	SortDirectionDesc SortDirection = "DESC"
+)
+
+var AllSortDirection = []SortDirection{
+	SortDirectionAsc,
+	SortDirectionDesc,
+}
+
+func (e SortDirection) IsValid() bool {
+	switch e {
+	case SortDirectionAsc, SortDirectionDesc:
+		return true
+	}
+	return false
+}
+
+func (e SortDirection) String() string {
+	return string(e)
+}
+
+func (e *SortDirection) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SortDirection(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SortDirection", str)
+	}
+	return nil
+}
+
+func (e SortDirection) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *SortDirection) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e SortDirection) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
+
+type UserSortField string
+
+const (
+	UserSortFieldCreatedAt UserSortField = "CREATED_AT"
+	UserSortFieldEmail     UserSortField = "EMAIL"
+	UserSortFieldLastName  UserSortField = "LAST_NAME"
+)
+
+var AllUserSortField = []UserSortField{
+	UserSortFieldCreatedAt,
+	UserSortFieldEmail,
+	UserSortFieldLastName,
+}
+
+func (e UserSortField) IsValid() bool {
+	switch e {
+	case UserSortFieldCreatedAt, UserSortFieldEmail, UserSortFieldLastName:
+		return true
+	}
+	return false
+}
+
+func (e UserSortField) String() string {
+	return string(e)
+}
+
+func (e *UserSortField) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = UserSortField(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid UserSortField", str)
+	}
+	return nil
+}
+
+func (e UserSortField) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *UserSortField) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e UserSortField) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
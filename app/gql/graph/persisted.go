@@ -0,0 +1,200 @@
+package graph
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/sirupsen/logrus"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+	customMiddleware "github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+// Codes in the extensions of the errors for operations that are not persisted.
+const (
+	errPersistedQueryNotFound   = "PERSISTED_QUERY_NOT_FOUND"
+	errPersistedQueryNotAllowed = "PERSISTED_QUERY_NOT_ALLOWED"
+)
+
+const apolloManifestFormat = "apollo-persisted-query-manifest"
+
+var errEmptyManifest = errors.New("persisted query manifest has no operations")
+
+// PersistedQueries is the allowlist of operations the server runs, read from
+// a manifest. Every document can be requested by the id it has in the
+// manifest or by its SHA-256 hash, as automatic persisted query clients do.
+type PersistedQueries struct {
+	path string
+
+	mu        sync.RWMutex
+	documents map[string]string
+}
+
+// LoadPersistedQueries reads the manifest at path, which is either an Apollo
+// persisted query manifest or a JSON object of ids to documents. Nothing is
+// loaded for an empty path and every operation is allowed.
+func LoadPersistedQueries(path string) (*PersistedQueries, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	pq := &PersistedQueries{path: path}
+	if _, err := pq.Reload(); err != nil {
+		return nil, err
+	}
+	return pq, nil
+}
+
+// Reload reads the manifest again and returns how many operations it has,
+// the operations loaded before are kept when it can not be read.
+func (pq *PersistedQueries) Reload() (int, error) {
+	data, err := os.ReadFile(pq.path)
+	if err != nil {
+		return 0, err
+	}
+	operations, err := parseManifest(data)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", pq.path, err)
+	}
+
+	documents := make(map[string]string, 2*len(operations))
+	for id, document := range operations {
+		documents[id] = document
+		documents[queryHash(document)] = document
+	}
+
+	pq.mu.Lock()
+	pq.documents = documents
+	pq.mu.Unlock()
+	return len(operations), nil
+}
+
+// parseManifest returns the documents of the manifest by their id.
+func parseManifest(data []byte) (map[string]string, error) {
+	var apollo struct {
+		Format     string `json:"format"`
+		Version    int    `json:"version"`
+		Operations []struct {
+			ID   string `json:"id"`
+			Body string `json:"body"`
+		} `json:"operations"`
+	}
+	if err := json.Unmarshal(data, &apollo); err != nil {
+		return nil, err
+	}
+
+	operations := make(map[string]string)
+	if apollo.Format == apolloManifestFormat {
+		if apollo.Version != 1 {
+			return nil, fmt.Errorf("unsupported manifest version %d", apollo.Version)
+		}
+		for _, op := range apollo.Operations {
+			if op.ID == "" || op.Body == "" {
+				return nil, errors.New("manifest operation without an id or body")
+			}
+			operations[op.ID] = op.Body
+		}
+	} else if err := json.Unmarshal(data, &operations); err != nil {
+		return nil, fmt.Errorf("manifest is neither an %s nor an object of documents: %w", apolloManifestFormat, err)
+	}
+	if len(operations) == 0 {
+		return nil, errEmptyManifest
+	}
+
+	return operations, nil
+}
+
+func (pq *PersistedQueries) document(id string) (string, bool) {
+	pq.mu.RLock()
+	defer pq.mu.RUnlock()
+	document, ok := pq.documents[id]
+	return document, ok
+}
+
+var _ interface {
+	graphql.OperationParameterMutator
+	graphql.HandlerExtension
+} = (*PersistedQueries)(nil)
+
+func (*PersistedQueries) ExtensionName() string {
+	return "PersistedQueries"
+}
+
+func (*PersistedQueries) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// MutateOperationParameters replaces the id in the persistedQuery extension
+// of the request with its document, the sha256Hash of automatic persisted
+// queries is looked up as an id too. Requests with a query and no id only
+// run when the query is in the manifest.
+func (pq *PersistedQueries) MutateOperationParameters(_ context.Context, params *graphql.RawParams) *gqlerror.Error {
+	if params.Extensions["persistedQuery"] == nil {
+		if _, ok := pq.document(queryHash(params.Query)); !ok {
+			err := gqlerror.Errorf("operation is not in the persisted query manifest")
+			errcode.Set(err, errPersistedQueryNotAllowed)
+			return err
+		}
+		return nil
+	}
+
+	extension, ok := params.Extensions["persistedQuery"].(map[string]any)
+	if !ok {
+		return gqlerror.Errorf("invalid persistedQuery extension")
+	}
+	id, _ := extension["id"].(string)
+	if id == "" {
+		id, _ = extension["sha256Hash"].(string)
+	}
+
+	document, ok := pq.document(id)
+	if !ok {
+		err := gqlerror.Errorf("persisted query %q not found", id)
+		errcode.Set(err, errPersistedQueryNotFound)
+		return err
+	}
+	if params.Query != "" && params.Query != document {
+		err := gqlerror.Errorf("query does not match persisted query %q", id)
+		errcode.Set(err, errPersistedQueryNotAllowed)
+		return err
+	}
+
+	params.Query = document
+	return nil
+}
+
+// reloadPersistedQueries is the admin endpoint that reads the manifest again
+// after a deploy of the frontends added operations to it.
+func reloadPersistedQueries(pq *PersistedQueries, logger *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		operations, err := pq.Reload()
+		if err != nil {
+			logger.Errorf("failed to reload persisted queries: %v", err)
+			foundation.ErrorResponse(w, r, http.StatusInternalServerError, err, foundation.InvalidManifest)
+			return
+		}
+
+		if claims, ok := r.Context().Value(customMiddleware.UserClaimsKey).(*store.Claims); ok {
+			logger.Infof("user %s reloaded %d persisted queries", claims.Subject, operations)
+		}
+		_ = foundation.Resource(w, http.StatusOK, struct {
+			Operations int `json:"operations"`
+		}{Operations: operations})
+	}
+}
+
+func queryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,232 @@
+package graph
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/store"
+	customMiddleware "github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+const (
+	meDocument = `query Me { me { id } }`
+	meEmail    = `query MeEmail { me { email } }`
+)
+
+func writeManifest(t *testing.T, path, manifest string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(manifest), 0o600))
+}
+
+// persistedRouter serves the operations of the manifest at path to user 1,
+// who holds permissions.
+func persistedRouter(t *testing.T, path string, permissions ...string) (http.Handler, *PersistedQueries) {
+	t.Helper()
+	pq, err := LoadPersistedQueries(path)
+	require.NoError(t, err)
+
+	st := &mocks.Store{
+		User:          &store.User{ID: "1", Email: testEmail},
+		Permissions:   permissions,
+		Organizations: []*store.Organization{{ID: "org-1"}},
+	}
+	r := newResolver(st, &mocks.Authenticator{}, tokenConfig())
+	ac := &customMiddleware.AuthConfig{TokenConfig: tokenConfig(), Authorizer: r.Authorizer, Logger: r.Logger}
+	return newRouter(ac, r.Store, newHandler(r, ac, DefaultLimits(), pq), DefaultLimits(), pq), pq
+}
+
+func TestLoadPersistedQueries(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		manifest      string
+		expectedIDs   []string
+		expectedError string
+	}{
+		{
+			name:        "apollo manifest",
+			manifest:    `{"format": "apollo-persisted-query-manifest", "version": 1, "operations": [{"id": "me", "name": "Me", "type": "query", "body": "query Me { me { id } }"}]}`,
+			expectedIDs: []string{"me", queryHash(meDocument)},
+		},
+		{
+			name:        "object of documents",
+			manifest:    `{"me": "query Me { me { id } }"}`,
+			expectedIDs: []string{"me", queryHash(meDocument)},
+		},
+		{
+			name:          "unsupported version",
+			manifest:      `{"format": "apollo-persisted-query-manifest", "version": 2, "operations": []}`,
+			expectedError: "unsupported manifest version 2",
+		},
+		{
+			name:          "operation without a body",
+			manifest:      `{"format": "apollo-persisted-query-manifest", "version": 1, "operations": [{"id": "me"}]}`,
+			expectedError: "without an id or body",
+		},
+		{
+			name:          "no operations",
+			manifest:      `{}`,
+			expectedError: errEmptyManifest.Error(),
+		},
+		{
+			name:          "invalid json",
+			manifest:      `[`,
+			expectedError: "unexpected end of JSON input",
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "manifest.json")
+			writeManifest(t, path, sc.manifest)
+
+			pq, err := LoadPersistedQueries(path)
+			if sc.expectedError != "" {
+				require.ErrorContains(t, err, sc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			for _, id := range sc.expectedIDs {
+				document, ok := pq.document(id)
+				assert.True(t, ok, id)
+				assert.Equal(t, meDocument, document)
+			}
+		})
+	}
+
+	t.Run("empty path", func(t *testing.T) {
+		pq, err := LoadPersistedQueries("")
+		require.NoError(t, err)
+		assert.Nil(t, pq)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadPersistedQueries(filepath.Join(t.TempDir(), "missing.json"))
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestPersistedQueries_Operations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	writeManifest(t, path, `{"me": "query Me { me { id } }"}`)
+	router, _ := persistedRouter(t, path)
+
+	scenarios := []struct {
+		name         string
+		body         map[string]any
+		expectedCode string
+	}{
+		{
+			name: "named id",
+			body: map[string]any{"extensions": map[string]any{"persistedQuery": map[string]any{"version": 1, "id": "me"}}},
+		},
+		{
+			name: "automatic persisted query hash",
+			body: map[string]any{"extensions": map[string]any{"persistedQuery": map[string]any{"version": 1, "sha256Hash": queryHash(meDocument)}}},
+		},
+		{
+			name: "query in the manifest",
+			body: map[string]any{"query": meDocument},
+		},
+		{
+			name:         "query not in the manifest",
+			body:         map[string]any{"query": meEmail},
+			expectedCode: errPersistedQueryNotAllowed,
+		},
+		{
+			name:         "unknown id",
+			body:         map[string]any{"extensions": map[string]any{"persistedQuery": map[string]any{"version": 1, "id": "users"}}},
+			expectedCode: errPersistedQueryNotFound,
+		},
+		{
+			name: "query registered under another id",
+			body: map[string]any{
+				"query":      meEmail,
+				"extensions": map[string]any{"persistedQuery": map[string]any{"version": 1, "sha256Hash": queryHash(meDocument)}},
+			},
+			expectedCode: errPersistedQueryNotAllowed,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			r := postRequest(t, sc.body)
+			r.Header.Set("Authorization", testToken(t, "1"))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			var resp limitsResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+			if sc.expectedCode != "" {
+				require.NotEmpty(t, resp.Errors)
+				assert.Equal(t, sc.expectedCode, resp.Errors[0].Extensions.Code)
+				assert.Nil(t, resp.Data)
+				return
+			}
+			assert.Empty(t, resp.Errors)
+			assert.Equal(t, map[string]any{"me": map[string]any{"id": "1"}}, resp.Data)
+		})
+	}
+}
+
+func TestPersistedQueries_Reload(t *testing.T) {
+	reload := func(router http.Handler, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/admin/persisted-queries/reload", nil)
+		if token != "" {
+			r.Header.Set("Authorization", token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	t.Run("without a token", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "manifest.json")
+		writeManifest(t, path, `{"me": "query Me { me { id } }"}`)
+		router, _ := persistedRouter(t, path, authz.QueriesManage)
+
+		assert.Equal(t, http.StatusUnauthorized, reload(router, "").Code)
+	})
+
+	t.Run("missing permission", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "manifest.json")
+		writeManifest(t, path, `{"me": "query Me { me { id } }"}`)
+		router, _ := persistedRouter(t, path, authz.UsersRead)
+
+		assert.Equal(t, http.StatusForbidden, reload(router, testToken(t, "1")).Code)
+	})
+
+	t.Run("new manifest", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "manifest.json")
+		writeManifest(t, path, `{"me": "query Me { me { id } }"}`)
+		router, pq := persistedRouter(t, path, authz.QueriesManage)
+
+		writeManifest(t, path, `{"me": "query Me { me { id } }", "meEmail": "query MeEmail { me { email } }"}`)
+		w := reload(router, testToken(t, "1"))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"operations": 2}`, w.Body.String())
+		document, ok := pq.document("meEmail")
+		assert.True(t, ok)
+		assert.Equal(t, meEmail, document)
+	})
+
+	t.Run("invalid manifest", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "manifest.json")
+		writeManifest(t, path, `{"me": "query Me { me { id } }"}`)
+		router, pq := persistedRouter(t, path, authz.QueriesManage)
+
+		writeManifest(t, path, `{}`)
+		w := reload(router, testToken(t, "1"))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		_, ok := pq.document("me")
+		assert.True(t, ok, "the previous manifest is kept")
+	})
+}
@@ -1,7 +1,13 @@
 package graph
 
 import (
+	"os"
+
+	"github.com/riyadennis/identity-server/business"
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/events"
 	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
 	"github.com/sirupsen/logrus"
 )
 
@@ -15,13 +21,31 @@ type Resolver struct {
 	tokenConfig   *store.TokenConfig
 	Store         store.Store
 	Authenticator store.Authenticator
+	Authorizer    *authz.Authorizer
+	Inviter       *business.Inviter
+	EmailChanger  *business.EmailChanger
+	Deleter       *business.Deleter
+	Webhooks      *business.Webhooks
+	Users         *business.Users
+	Helper        *business.Helper
+	// Events carries the changes the mutations make to subscriptions.
+	Events *events.Bus
 }
 
 func NewResolver(l *logrus.Logger, tc *store.TokenConfig, st store.Store, au store.Authenticator) *Resolver {
+	mailer := foundation.NewENVMailer(l)
 	return &Resolver{
 		Logger:        l,
 		tokenConfig:   tc,
 		Store:         st,
 		Authenticator: au,
+		Authorizer:    authz.NewAuthorizer(st, l),
+		Inviter:       business.NewInviter(st, mailer, l, os.Getenv("INVITATION_URL")),
+		EmailChanger:  business.NewEmailChanger(st, au, mailer, l, os.Getenv("EMAIL_CHANGE_URL")),
+		Deleter:       business.NewDeleter(st, l, os.Getenv("DELETED_USER_RETENTION")),
+		Webhooks:      business.NewWebhooks(st, l),
+		Users:         business.NewUsers(st, l),
+		Helper:        business.NewHelper(st, au, mailer, l),
+		Events:        events.NewBus(),
 	}
 }
@@ -8,13 +8,13 @@ package graph
 import (
 	"context"
 	"fmt"
-	"net/http"
-	"strconv"
 
-	jwt "github.com/golang-jwt/jwt/v5"
 	"github.com/riyadennis/identity-server/app/gql/graph/generated"
 	"github.com/riyadennis/identity-server/app/gql/graph/model"
-	"github.com/riyadennis/identity-server/business"
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/events"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/business/validation"
 	"github.com/riyadennis/identity-server/foundation/middleware"
 )
 
@@ -22,26 +22,12 @@ import (
 func (r *mutationResolver) Login(ctx context.Context, input model.LoginInput) (*model.LoginResponse, error) {
 	r.Logger.Info("processing graphql request to login")
 
-	helper := business.NewHelper(r.Store, r.Authenticator, r.Logger)
-	token, err := helper.Login(ctx, r.tokenConfig, *input.Email, *input.Password)
+	token, err := r.Helper.Login(ctx, r.tokenConfig, *input.Email, *input.Password)
 	if err != nil {
 		return nil, err
 	}
 
-	status := http.StatusOK
-	ttl, err := strconv.ParseInt(token.TokenTTL, 10, 32)
-	if err != nil {
-		return nil, err
-	}
-	intTTL := int(ttl)
-	return &model.LoginResponse{
-		Status:      &status,
-		AccessToken: &token.AccessToken,
-		Expiry:      &token.Expiry,
-		TokenType:   &token.TokenType,
-		LastRefresh: &token.LastRefresh,
-		TokenTTL:    &intTTL,
-	}, nil
+	return toLoginResponse(token)
 }
 
 // Register is the resolver for the Register field.
@@ -57,25 +43,18 @@ func (r *mutationResolver) CreateUser(ctx context.Context, input model.RegisterI
 		return nil, err
 	}
 
-	if err := callerIsAdmin(ctx, r.Store, r.Logger); err != nil {
-		return nil, err
-	}
-
 	r.Logger.Infof("admin %s creating user %s", adminID, input.Email)
 	return r.insertUser(ctx, input, adminID)
 }
 
 // AssignRole is the resolver for the assignRole field.
 func (r *mutationResolver) AssignRole(ctx context.Context, userID string, role model.Role) (*model.RoleResponse, error) {
-	if err := callerIsAdmin(ctx, r.Store, r.Logger); err != nil {
-		return nil, err
-	}
-
 	r.Logger.Infof("assigning role %s to user %s", role, userID)
 
-	if err := r.Store.UpdateRole(ctx, userID, role.String()); err != nil {
-		return nil, fmt.Errorf("failed to assign role: %w", err)
+	if err := r.Users.AssignRole(ctx, userID, roleName(role)); err != nil {
+		return nil, err
 	}
+	r.Events.Publish(events.RoleAssigned{UserID: userID, Role: roleName(role)})
 
 	return &model.RoleResponse{
 		UserID: userID,
@@ -85,16 +64,17 @@ func (r *mutationResolver) AssignRole(ctx context.Context, userID string, role m
 
 // UserActivation is the resolver for the userActivation field.
 func (r *mutationResolver) UserActivation(ctx context.Context, userID string) (*model.ActivationResponse, error) {
-	if err := callerIsAdmin(ctx, r.Store, r.Logger); err != nil {
-		return nil, err
-	}
-
 	r.Logger.Infof("toggling active status for user %s", userID)
 
 	active, err := r.Store.ToggleActive(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to toggle user activation: %w", err)
 	}
+	if active {
+		r.Events.Publish(events.UserActivated{UserID: userID})
+	} else {
+		r.Events.Publish(events.UserDeactivated{UserID: userID})
+	}
 
 	return &model.ActivationResponse{
 		UserID: userID,
@@ -102,111 +82,581 @@ func (r *mutationResolver) UserActivation(ctx context.Context, userID string) (*
 	}, nil
 }
 
+// DeleteUser is the resolver for the deleteUser field.
+func (r *mutationResolver) DeleteUser(ctx context.Context, userID string) (bool, error) {
+	r.Logger.Infof("deleting user %s", userID)
+
+	if err := r.Deleter.Delete(ctx, userID); err != nil {
+		return false, fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	return true, nil
+}
+
+// RestoreUser is the resolver for the restoreUser field.
+func (r *mutationResolver) RestoreUser(ctx context.Context, userID string) (*model.User, error) {
+	r.Logger.Infof("restoring user %s", userID)
+
+	user, err := r.Deleter.Restore(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	return toUser(user), nil
+}
+
+// GrantRole is the resolver for the grantRole field.
+func (r *mutationResolver) GrantRole(ctx context.Context, userID string, role string) (*model.UserRolesResponse, error) {
+	r.Logger.Infof("granting role %s to user %s", role, userID)
+
+	if err := r.Store.AssignRole(ctx, userID, role); err != nil {
+		return nil, fmt.Errorf("failed to grant role: %w", err)
+	}
+	r.Events.Publish(events.RoleAssigned{UserID: userID, Role: role})
+
+	return r.userRoles(ctx, userID)
+}
+
+// RevokeRole is the resolver for the revokeRole field.
+func (r *mutationResolver) RevokeRole(ctx context.Context, userID string, role string) (*model.UserRolesResponse, error) {
+	r.Logger.Infof("revoking role %s from user %s", role, userID)
+
+	if err := r.Store.RevokeRole(ctx, userID, role); err != nil {
+		return nil, fmt.Errorf("failed to revoke role: %w", err)
+	}
+
+	return r.userRoles(ctx, userID)
+}
+
+// CreateRole is the resolver for the createRole field.
+func (r *mutationResolver) CreateRole(ctx context.Context, input model.RoleInput) (*model.RoleDefinition, error) {
+	r.Logger.Infof("creating role %s", input.Name)
+
+	role := &store.Role{
+		Name:        input.Name,
+		Permissions: input.Permissions,
+	}
+	if input.Description != nil {
+		role.Description = *input.Description
+	}
+
+	created, err := r.Store.CreateRole(ctx, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create role: %w", err)
+	}
+
+	return toRoleDefinition(created), nil
+}
+
+// UpdateRolePermissions is the resolver for the updateRolePermissions field.
+func (r *mutationResolver) UpdateRolePermissions(ctx context.Context, name string, permissions []string) (*model.RoleDefinition, error) {
+	r.Logger.Infof("updating permissions of role %s", name)
+
+	if err := r.Store.SetRolePermissions(ctx, name, permissions); err != nil {
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+
+	role, err := r.Store.RetrieveRole(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve role: %w", err)
+	}
+
+	return toRoleDefinition(role), nil
+}
+
+// DeleteRole is the resolver for the deleteRole field.
+func (r *mutationResolver) DeleteRole(ctx context.Context, name string) (bool, error) {
+	r.Logger.Infof("deleting role %s", name)
+
+	if err := r.Store.DeleteRole(ctx, name); err != nil {
+		return false, fmt.Errorf("failed to delete role: %w", err)
+	}
+
+	return true, nil
+}
+
+// CreateOrganization is the resolver for the createOrganization field.
+func (r *mutationResolver) CreateOrganization(ctx context.Context, name string) (*model.Organization, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Logger.Infof("user %s creating organization %s", userID, name)
+	org, err := r.Store.CreateOrganization(ctx, name, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	return toOrganization(org), nil
+}
+
+// SwitchOrganization is the resolver for the switchOrganization field.
+func (r *mutationResolver) SwitchOrganization(ctx context.Context, organizationID string) (*model.LoginResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Logger.Infof("user %s switching to organization %s", userID, organizationID)
+	token, err := r.Helper.SwitchOrganization(ctx, r.tokenConfig, userID, organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to switch organization: %w", err)
+	}
+
+	return toLoginResponse(token)
+}
+
+// RemoveOrganizationMember is the resolver for the removeOrganizationMember field.
+func (r *mutationResolver) RemoveOrganizationMember(ctx context.Context, userID string) (bool, error) {
+	tenant, ok := store.TenantFromContext(ctx)
+	if !ok || tenant.OrganizationID == "" {
+		return false, authz.ErrForbidden
+	}
+
+	r.Logger.Infof("removing user %s from organization %s", userID, tenant.OrganizationID)
+	if err := r.Store.RemoveMember(ctx, tenant.OrganizationID, userID); err != nil {
+		return false, fmt.Errorf("failed to remove organization member: %w", err)
+	}
+
+	return true, nil
+}
+
+// InviteUser is the resolver for the inviteUser field.
+func (r *mutationResolver) InviteUser(ctx context.Context, email string, role *string) (*model.Invitation, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Logger.Infof("user %s inviting %s", userID, email)
+	var roleName string
+	if role != nil {
+		roleName = *role
+	}
+	inv, err := r.Inviter.Invite(ctx, userID, email, roleName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to invite user: %w", err)
+	}
+
+	return toInvitation(inv), nil
+}
+
+// ResendInvitation is the resolver for the resendInvitation field.
+func (r *mutationResolver) ResendInvitation(ctx context.Context, id string) (*model.Invitation, error) {
+	r.Logger.Infof("resending invitation %s", id)
+
+	inv, err := r.Inviter.Resend(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resend invitation: %w", err)
+	}
+
+	return toInvitation(inv), nil
+}
+
+// RevokeInvitation is the resolver for the revokeInvitation field.
+func (r *mutationResolver) RevokeInvitation(ctx context.Context, id string) (bool, error) {
+	r.Logger.Infof("revoking invitation %s", id)
+
+	if err := r.Store.RevokeInvitation(ctx, id); err != nil {
+		return false, fmt.Errorf("failed to revoke invitation: %w", err)
+	}
+
+	return true, nil
+}
+
+// AcceptInvitation is the resolver for the acceptInvitation field.
+func (r *mutationResolver) AcceptInvitation(ctx context.Context, input model.AcceptInvitationInput) (*model.RegisterResponse, error) {
+	r.Logger.Info("processing graphql request to accept an invitation")
+
+	u := &store.User{
+		FirstName: input.FirstName,
+		LastName:  input.LastName,
+		Password:  input.Password,
+		Terms:     input.Terms,
+	}
+	if input.Company != nil {
+		u.Company = *input.Company
+	}
+	if input.PostCode != nil {
+		u.PostCode = *input.PostCode
+	}
+
+	created, err := r.Inviter.Accept(ctx, input.Token, u)
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept invitation: %w", emailExistsError(err))
+	}
+	r.Events.Publish(events.UserRegistered{UserID: created.ID, Email: created.Email, Roles: created.Roles})
+
+	return toRegisterResponse(created), nil
+}
+
+// UpdateProfile is the resolver for the updateProfile field.
+func (r *mutationResolver) UpdateProfile(ctx context.Context, input model.UpdateProfileInput) (*model.User, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	update := &store.ProfileUpdate{
+		FirstName:  input.FirstName,
+		LastName:   input.LastName,
+		Company:    input.Company,
+		PostCode:   input.PostCode,
+		Locale:     input.Locale,
+		Timezone:   input.Timezone,
+		PictureURL: input.Picture,
+	}
+	if err := validation.ValidateProfile(update); err != nil {
+		return nil, err
+	}
+
+	user, err := r.Store.UpdateProfile(ctx, userID, update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+
+	return toUser(user), nil
+}
+
+// ChangeEmail is the resolver for the changeEmail field.
+func (r *mutationResolver) ChangeEmail(ctx context.Context, password string, newEmail string) (bool, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	r.Logger.Infof("user %s requested an email change", userID)
+	if err := r.EmailChanger.Request(ctx, userID, password, newEmail); err != nil {
+		return false, fmt.Errorf("failed to change email: %w", emailExistsError(err))
+	}
+
+	return true, nil
+}
+
+// ConfirmEmailChange is the resolver for the confirmEmailChange field.
+func (r *mutationResolver) ConfirmEmailChange(ctx context.Context, token string) (*model.User, error) {
+	user, err := r.EmailChanger.Confirm(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm email change: %w", emailExistsError(err))
+	}
+
+	return toUser(user), nil
+}
+
+// CreateWebhook is the resolver for the createWebhook field.
+func (r *mutationResolver) CreateWebhook(ctx context.Context, input model.CreateWebhookInput) (*model.Webhook, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Logger.Infof("user %s creating a webhook for %s", userID, input.URL)
+	w := &store.Webhook{URL: input.URL, EventTypes: input.EventTypes}
+	if input.Secret != nil {
+		w.Secret = *input.Secret
+	}
+	webhook, err := r.Webhooks.Create(ctx, userID, w)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	return toWebhook(webhook), nil
+}
+
+// DeleteWebhook is the resolver for the deleteWebhook field.
+func (r *mutationResolver) DeleteWebhook(ctx context.Context, id string) (bool, error) {
+	r.Logger.Infof("deleting webhook %s", id)
+
+	if err := r.Store.DeleteWebhook(ctx, id); err != nil {
+		return false, fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	return true, nil
+}
+
+// ReplayWebhookDelivery is the resolver for the replayWebhookDelivery field.
+func (r *mutationResolver) ReplayWebhookDelivery(ctx context.Context, id string) (*model.WebhookDelivery, error) {
+	r.Logger.Infof("replaying webhook delivery %s", id)
+
+	d, err := r.Webhooks.Replay(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay webhook delivery: %w", err)
+	}
+
+	return toWebhookDelivery(d), nil
+}
+
 // Me is the resolver for the me query.
 func (r *queryResolver) Me(ctx context.Context) (*model.User, error) {
 	accessToken, ok := ctx.Value(middleware.AccessTokenKey).(string)
 	if !ok || accessToken == "" {
-		return nil, fmt.Errorf("unauthorized: no access token provided")
+		return nil, authz.ErrUnauthenticated
+	}
+	claims, ok := ctx.Value(middleware.UserClaimsKey).(*store.Claims)
+	if !ok || claims == nil {
+		return nil, authz.ErrUnauthenticated
 	}
-	claims, ok := ctx.Value(middleware.UserClaimsKey).(*jwt.RegisteredClaims)
-	user, err := r.Store.Retrieve(ctx, claims.Subject)
+	user, err := r.retrieveUser(ctx, claims.Subject)
 	if err != nil {
 		return nil, err
 	}
 
 	if user == nil {
-		return nil, fmt.Errorf("failed to find user for ID %s", claims.Subject)
+		return nil, userNotFound(claims.Subject)
 	}
 
-	fullName := user.FirstName + " " + user.LastName
-	return &model.User{
-		ID:            claims.Subject,
-		Email:         user.Email,
-		Name:          &fullName,
-		Picture:       nil,
-		EmailVerified: false,
-	}, nil
+	return toUser(user), nil
 }
 
 // GetUserRole is the resolver for the getUserRole field.
 func (r *queryResolver) GetUserRole(ctx context.Context, userID string) (*model.RoleResponse, error) {
 	r.Logger.Infof("fetching role for user %s", userID)
 
-	user, err := r.Store.Retrieve(ctx, userID)
+	user, err := r.retrieveUser(ctx, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve user: %w", err)
 	}
 	if user == nil {
-		return nil, fmt.Errorf("user %s not found", userID)
-	}
-
-	role := model.Role(user.Role)
-	if !role.IsValid() {
-		return nil, fmt.Errorf("invalid role %q for user %s", user.Role, userID)
+		return nil, userNotFound(userID)
 	}
 
 	return &model.RoleResponse{
 		UserID: userID,
-		Role:   role,
+		Role:   builtInRole(user.Roles),
 	}, nil
 }
 
+// GetUserRoles is the resolver for the getUserRoles field.
+func (r *queryResolver) GetUserRoles(ctx context.Context, userID string) (*model.UserRolesResponse, error) {
+	return r.userRoles(ctx, userID)
+}
+
 // ListUsersByRole is the resolver for the listUsersByRole field.
 func (r *queryResolver) ListUsersByRole(ctx context.Context, role model.Role) ([]*model.User, error) {
 	r.Logger.Infof("listing users with role %s", role)
-	if err := callerIsAdmin(ctx, r.Store, r.Logger); err != nil {
+	page, err := r.Store.ListUsers(ctx, store.ListOptions{Role: roleName(role), First: store.MaxPageSize})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users by role: %w", err)
+	}
+
+	return toUsers(page.Users), nil
+}
+
+// ListUsers is the resolver for the listUsers field.
+func (r *queryResolver) ListUsers(ctx context.Context) ([]*model.User, error) {
+	r.Logger.Info("listing all registered users")
+
+	page, err := r.Store.ListUsers(ctx, store.ListOptions{First: store.MaxPageSize})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	return toUsers(page.Users), nil
+}
+
+// Users is the resolver for the users field.
+func (r *queryResolver) Users(ctx context.Context, first *int, after *string, filter *model.UserFilter, orderBy *model.UserOrder) (*model.UsersConnection, error) {
+	opts, err := listOptions(first, after, filter, orderBy)
+	if err != nil {
 		return nil, err
 	}
 
-	users, err := r.Store.ListByRole(ctx, role.String())
+	page, err := r.Store.ListUsers(ctx, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list users by role: %w", err)
+		return nil, fmt.Errorf("failed to list users: %w", err)
 	}
 
-	result := make([]*model.User, 0, len(users))
-	for _, u := range users {
-		fullName := u.FirstName + " " + u.LastName
-		result = append(result, &model.User{
-			ID:            u.ID,
-			Email:         u.Email,
-			Name:          &fullName,
-			EmailVerified: false,
+	return toUsersConnection(page, opts), nil
+}
+
+// SearchUsers is the resolver for the searchUsers field.
+func (r *queryResolver) SearchUsers(ctx context.Context, query string, first *int, after *string) (*model.UsersConnection, error) {
+	opts, err := searchOptions(query, first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := r.Store.SearchUsers(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	return toSearchConnection(page, opts), nil
+}
+
+// Roles is the resolver for the roles field.
+func (r *queryResolver) Roles(ctx context.Context) ([]*model.RoleDefinition, error) {
+	roles, err := r.Store.ListRoles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+
+	result := make([]*model.RoleDefinition, 0, len(roles))
+	for _, role := range roles {
+		result = append(result, toRoleDefinition(role))
+	}
+	return result, nil
+}
+
+// Permissions is the resolver for the permissions field.
+func (r *queryResolver) Permissions(ctx context.Context) ([]*model.PermissionDefinition, error) {
+	permissions, err := r.Store.ListPermissions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list permissions: %w", err)
+	}
+
+	result := make([]*model.PermissionDefinition, 0, len(permissions))
+	for _, p := range permissions {
+		result = append(result, &model.PermissionDefinition{
+			Name:        p.Name,
+			Description: &p.Description,
 		})
 	}
 	return result, nil
 }
 
-// ListUsers is the resolver for the listUsers field.
-func (r *queryResolver) ListUsers(ctx context.Context) ([]*model.User, error) {
-	if err := callerIsAdmin(ctx, r.Store, r.Logger); err != nil {
+// Organizations is the resolver for the organizations field.
+func (r *queryResolver) Organizations(ctx context.Context) ([]*model.Organization, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
 		return nil, err
 	}
 
-	r.Logger.Info("listing all registered users")
+	organizations, err := r.Store.ListOrganizations(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+
+	result := make([]*model.Organization, 0, len(organizations))
+	for _, org := range organizations {
+		result = append(result, toOrganization(org))
+	}
+	return result, nil
+}
 
-	users, err := r.Store.ListAll(ctx)
+// Invitations is the resolver for the invitations field.
+func (r *queryResolver) Invitations(ctx context.Context) ([]*model.Invitation, error) {
+	invitations, err := r.Store.ListInvitations(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list users: %w", err)
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
 	}
 
-	result := make([]*model.User, 0, len(users))
-	for _, u := range users {
-		fullName := u.FirstName + " " + u.LastName
-		result = append(result, &model.User{
-			ID:            u.ID,
-			Email:         u.Email,
-			Name:          &fullName,
-			EmailVerified: false,
-		})
+	result := make([]*model.Invitation, 0, len(invitations))
+	for _, inv := range invitations {
+		result = append(result, toInvitation(inv))
+	}
+	return result, nil
+}
+
+// AuditEvents is the resolver for the auditEvents field.
+func (r *queryResolver) AuditEvents(ctx context.Context, first *int, after *string, filter *model.AuditEventFilter) (*model.AuditEventsConnection, error) {
+	opts, err := auditOptions(first, after, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := r.Store.ListAuditEvents(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+
+	return toAuditEventsConnection(page, opts), nil
+}
+
+// LoginHistory is the resolver for the loginHistory field.
+func (r *queryResolver) LoginHistory(ctx context.Context, userID *string, first *int, after *string) (*model.LoginAttemptsConnection, error) {
+	caller, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id := caller
+	if userID != nil && *userID != "" {
+		id = *userID
+	}
+	err = r.Authorizer.Authorize(ctx, authz.Subject{UserID: caller}, authz.UsersRead, authz.User(id))
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := loginHistoryOptions(first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := r.Store.ListLoginAttempts(ctx, id, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list login attempts: %w", err)
+	}
+
+	return toLoginAttemptsConnection(page, opts), nil
+}
+
+// Webhooks is the resolver for the webhooks field.
+func (r *queryResolver) Webhooks(ctx context.Context) ([]*model.Webhook, error) {
+	webhooks, err := r.Store.ListWebhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	result := make([]*model.Webhook, 0, len(webhooks))
+	for _, w := range webhooks {
+		result = append(result, toWebhook(w))
 	}
 	return result, nil
 }
 
+// WebhookDeliveries is the resolver for the webhookDeliveries field.
+func (r *queryResolver) WebhookDeliveries(ctx context.Context, webhookID string, first *int, after *string) (*model.WebhookDeliveriesConnection, error) {
+	opts, err := webhookDeliveryOptions(first, after)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := r.Store.ListWebhookDeliveries(ctx, webhookID, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+
+	return toWebhookDeliveriesConnection(page, opts), nil
+}
+
+// UserRegistered is the resolver for the userRegistered field.
+func (r *subscriptionResolver) UserRegistered(ctx context.Context) (<-chan *model.User, error) {
+	return subscribe(ctx, r.Resolver, func(_ events.Event, u *store.User) *model.User {
+		return toUser(u)
+	}, events.TypeUserRegistered), nil
+}
+
+// UserActivationChanged is the resolver for the userActivationChanged field.
+func (r *subscriptionResolver) UserActivationChanged(ctx context.Context) (<-chan *model.ActivationResponse, error) {
+	return subscribe(ctx, r.Resolver, func(e events.Event, u *store.User) *model.ActivationResponse {
+		return &model.ActivationResponse{
+			UserID: u.ID,
+			Active: e.EventType() == events.TypeUserActivated,
+		}
+	}, events.TypeUserActivated, events.TypeUserDeactivated), nil
+}
+
+// RoleAssigned is the resolver for the roleAssigned field.
+func (r *subscriptionResolver) RoleAssigned(ctx context.Context) (<-chan *model.RoleAssignment, error) {
+	return subscribe(ctx, r.Resolver, func(e events.Event, u *store.User) *model.RoleAssignment {
+		return &model.RoleAssignment{
+			UserID: u.ID,
+			Role:   e.(events.RoleAssigned).Role,
+		}
+	}, events.TypeRoleAssigned), nil
+}
+
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
 // Query returns generated.QueryResolver implementation.
 func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 
+// Subscription returns generated.SubscriptionResolver implementation.
+func (r *Resolver) Subscription() generated.SubscriptionResolver { return &subscriptionResolver{r} }
+
 type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
@@ -4,13 +4,20 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
+	jwt "github.com/golang-jwt/jwt/v5"
 	"github.com/riyadennis/identity-server/app/gql/graph/model"
 	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business"
+	"github.com/riyadennis/identity-server/business/authz"
 	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+	"github.com/riyadennis/identity-server/foundation/middleware"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vektah/gqlparser/v2/gqlerror"
 )
 
 const (
@@ -34,7 +41,7 @@ func tokenConfig() *store.TokenConfig {
 // --- Login ---
 
 func TestLogin_InvalidEmail(t *testing.T) {
-	r := &mutationResolver{newResolver(nil, nil, tokenConfig())}
+	r := &mutationResolver{newResolver(&mocks.Store{}, &mocks.Authenticator{}, tokenConfig())}
 	email := "not-an-email"
 	password := testPassword
 	_, err := r.Login(context.Background(), model.LoginInput{Email: &email, Password: &password})
@@ -94,9 +101,8 @@ func TestRegister_ValidationError(t *testing.T) {
 }
 
 func TestRegister_EmailAlreadyExists(t *testing.T) {
-	existing := &store.User{Email: testEmail}
 	r := &mutationResolver{newResolver(
-		&mocks.Store{User: existing},
+		&mocks.Store{Error: store.ErrDuplicateEmail},
 		&mocks.Authenticator{},
 		tokenConfig(),
 	)}
@@ -107,11 +113,13 @@ func TestRegister_EmailAlreadyExists(t *testing.T) {
 		Password:  testPassword,
 		Terms:     true,
 	})
-	require.Error(t, err)
-	assert.Contains(t, err.Error(), "email already exists")
+	require.ErrorIs(t, err, store.ErrDuplicateEmail)
+	var gqlErr *gqlerror.Error
+	require.ErrorAs(t, err, &gqlErr)
+	assert.Equal(t, foundation.EmailAlreadyExists, gqlErr.Extensions["code"])
 }
 
-func TestRegister_StoreReadError(t *testing.T) {
+func TestRegister_StoreInsertError(t *testing.T) {
 	r := &mutationResolver{newResolver(
 		&mocks.Store{Error: errors.New("db error")},
 		&mocks.Authenticator{},
@@ -140,7 +148,6 @@ func TestRegister_Success(t *testing.T) {
 		Terms:     true,
 		CreatedAt: "2026-01-01 00:00:00",
 	}
-	// Read returns empty user (email doesn't exist yet), Insert returns created user
 	st := &insertMockStore{created: created}
 	r := &mutationResolver{newResolver(st, &mocks.Authenticator{}, tokenConfig())}
 
@@ -159,8 +166,427 @@ func TestRegister_Success(t *testing.T) {
 	assert.Equal(t, testEmail, *resp.Email)
 }
 
+// --- Roles ---
+
+func withCaller(userID string) context.Context {
+	return context.WithValue(context.Background(), middleware.UserClaimsKey,
+		&store.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: userID}, OrgID: "org-1"})
+}
+
+func TestListUsers_Success(t *testing.T) {
+	st := &mocks.Store{
+		User:        &store.User{ID: "1", Email: testEmail, Roles: []string{authz.RoleAdmin}},
+		Permissions: []string{authz.UsersRead},
+	}
+	r := &queryResolver{newResolver(st, &mocks.Authenticator{}, tokenConfig())}
+	users, err := r.ListUsers(withCaller("1"))
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, []string{authz.RoleAdmin}, users[0].Roles)
+}
+
+func TestUsers(t *testing.T) {
+	first := 10
+	role := "support"
+	invalidDate := "01/01/2024"
+	after := store.UserCursor(&store.User{ID: "0", Email: "a@test.com"}, store.SortEmail)
+	createdAfter := "2024-01-01T00:00:00Z"
+	active := true
+	scenarios := []struct {
+		name         string
+		filter       *model.UserFilter
+		expectedOpts *store.ListOptions
+		expectedErr  string
+	}{
+		{
+			name:        "invalid created after",
+			filter:      &model.UserFilter{CreatedAfter: &invalidDate},
+			expectedErr: "invalid createdAfter",
+		},
+		{
+			name:   "listed",
+			filter: &model.UserFilter{Role: &role, Active: &active, CreatedAfter: &createdAfter},
+			expectedOpts: &store.ListOptions{
+				Role:         role,
+				Active:       &active,
+				CreatedAfter: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				Sort:         store.SortEmail,
+				Descending:   true,
+				First:        first,
+				After:        after,
+			},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			st := &mocks.Store{User: &store.User{ID: "1", Email: testEmail}}
+			r := &queryResolver{newResolver(st, &mocks.Authenticator{}, tokenConfig())}
+
+			conn, err := r.Users(withCaller("1"), &first, &after, sc.filter,
+				&model.UserOrder{Field: model.UserSortFieldEmail, Direction: model.SortDirectionDesc})
+			if sc.expectedErr != "" {
+				require.ErrorContains(t, err, sc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, sc.expectedOpts, st.ListedWith)
+			require.Len(t, conn.Edges, 1)
+			assert.Equal(t, testEmail, conn.Edges[0].Node.Email)
+			assert.Equal(t, store.UserCursor(st.User, store.SortEmail), conn.Edges[0].Cursor)
+			assert.Equal(t, &conn.Edges[0].Cursor, conn.PageInfo.EndCursor)
+			assert.False(t, conn.PageInfo.HasNextPage)
+			assert.True(t, conn.PageInfo.HasPreviousPage)
+		})
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	first := 10
+	negative := -1
+	after := store.SearchCursor(10)
+	scenarios := []struct {
+		name        string
+		first       *int
+		storeErr    error
+		expectedErr string
+	}{
+		{
+			name:        "negative first",
+			first:       &negative,
+			expectedErr: errNegativeFirst.Error(),
+		},
+		{
+			name:        "no words",
+			first:       &first,
+			storeErr:    store.ErrEmptySearch,
+			expectedErr: store.ErrEmptySearch.Error(),
+		},
+		{
+			name:  "found",
+			first: &first,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			st := &mocks.Store{User: &store.User{ID: "1", Email: testEmail}, Error: sc.storeErr}
+			r := &queryResolver{newResolver(st, &mocks.Authenticator{}, tokenConfig())}
+
+			conn, err := r.SearchUsers(withCaller("1"), "john", sc.first, &after)
+			if sc.expectedErr != "" {
+				require.ErrorContains(t, err, sc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, &store.SearchOptions{Query: "john", First: first, After: after}, st.SearchedWith)
+			require.Len(t, conn.Edges, 1)
+			assert.Equal(t, testEmail, conn.Edges[0].Node.Email)
+			assert.Equal(t, store.SearchCursor(11), conn.Edges[0].Cursor)
+			assert.False(t, conn.PageInfo.HasNextPage)
+			assert.True(t, conn.PageInfo.HasPreviousPage)
+		})
+	}
+}
+
+func TestAuditEvents(t *testing.T) {
+	first := 10
+	negative := -1
+	since := "2024-01-01T00:00:00Z"
+	invalid := "yesterday"
+	action := store.AuditRoleAssigned
+	created := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	event := &store.AuditEvent{
+		ID:         "event-1",
+		ActorID:    "1",
+		Action:     store.AuditRoleAssigned,
+		TargetType: store.AuditTargetUser,
+		TargetID:   "2",
+		After:      []byte(`{"role":"support"}`),
+		CreatedAt:  created,
+	}
+	scenarios := []struct {
+		name        string
+		first       *int
+		filter      *model.AuditEventFilter
+		expectedErr string
+	}{
+		{
+			name:        "negative first",
+			first:       &negative,
+			expectedErr: errNegativeFirst.Error(),
+		},
+		{
+			name:        "invalid since",
+			filter:      &model.AuditEventFilter{Since: &invalid},
+			expectedErr: "invalid since",
+		},
+		{
+			name:   "listed",
+			first:  &first,
+			filter: &model.AuditEventFilter{Action: &action, Since: &since},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			st := &mocks.Store{AuditEvents: []*store.AuditEvent{event}}
+			r := &queryResolver{newResolver(st, &mocks.Authenticator{}, tokenConfig())}
+
+			conn, err := r.AuditEvents(withCaller("1"), sc.first, nil, sc.filter)
+			if sc.expectedErr != "" {
+				require.ErrorContains(t, err, sc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, &store.AuditOptions{
+				Action: store.AuditRoleAssigned,
+				Since:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				First:  first,
+			}, st.AuditListedWith)
+			require.Len(t, conn.Edges, 1)
+			node := conn.Edges[0].Node
+			assert.Equal(t, "event-1", node.ID)
+			assert.Equal(t, `{"role":"support"}`, *node.After)
+			assert.Nil(t, node.Before)
+			assert.Equal(t, "2024-01-02T10:00:00Z", node.CreatedAt)
+			assert.Equal(t, store.AuditCursor(event), conn.Edges[0].Cursor)
+			assert.False(t, conn.PageInfo.HasPreviousPage)
+		})
+	}
+}
+
+func TestGetUserRole_BuiltInRole(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		roles        []string
+		expectedRole model.Role
+	}{
+		{name: "admin", roles: []string{"admin", "support"}, expectedRole: model.RoleAdmin},
+		{name: "superadmin", roles: []string{"admin", "superadmin"}, expectedRole: model.RoleSuperadmin},
+		{name: "user", roles: []string{"support"}, expectedRole: model.RoleUser},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			st := &mocks.Store{User: &store.User{ID: "1", Roles: sc.roles}}
+			r := &queryResolver{newResolver(st, &mocks.Authenticator{}, tokenConfig())}
+			resp, err := r.GetUserRole(withCaller("1"), "1")
+			require.NoError(t, err)
+			assert.Equal(t, sc.expectedRole, resp.Role)
+		})
+	}
+}
+
+func TestGrantRole(t *testing.T) {
+	st := &mocks.Store{User: &store.User{ID: "2", Roles: []string{"support", "user"}}}
+	r := &mutationResolver{newResolver(st, &mocks.Authenticator{}, tokenConfig())}
+	resp, err := r.GrantRole(withCaller("1"), "2", "support")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"support", "user"}, resp.Roles)
+}
+
+func TestGrantRole_StoreError(t *testing.T) {
+	st := &mocks.Store{Error: store.ErrRoleNotFound}
+	r := &mutationResolver{newResolver(st, &mocks.Authenticator{}, tokenConfig())}
+	_, err := r.GrantRole(withCaller("1"), "2", "support")
+	require.ErrorIs(t, err, store.ErrRoleNotFound)
+}
+
+func TestCreateRole(t *testing.T) {
+	st := &mocks.Store{Permissions: []string{authz.RolesWrite}}
+	r := &mutationResolver{newResolver(st, &mocks.Authenticator{}, tokenConfig())}
+	description := "helpdesk"
+	role, err := r.CreateRole(withCaller("1"), model.RoleInput{
+		Name:        "support",
+		Description: &description,
+		Permissions: []string{authz.UsersRead},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "support", role.Name)
+	assert.False(t, role.BuiltIn)
+	assert.Equal(t, []string{authz.UsersRead}, role.Permissions)
+}
+
+// --- Organizations ---
+
+func TestOrganizations(t *testing.T) {
+	st := &mocks.Store{Organizations: []*store.Organization{{ID: "org-1", Name: "Acme"}}}
+	r := &queryResolver{newResolver(st, &mocks.Authenticator{}, tokenConfig())}
+	organizations, err := r.Organizations(withCaller("1"))
+	require.NoError(t, err)
+	require.Len(t, organizations, 1)
+	assert.Equal(t, "Acme", organizations[0].Name)
+}
+
+func TestCreateOrganization(t *testing.T) {
+	r := &mutationResolver{newResolver(&mocks.Store{}, &mocks.Authenticator{}, tokenConfig())}
+	org, err := r.CreateOrganization(withCaller("1"), "Acme")
+	require.NoError(t, err)
+	assert.Equal(t, "Acme", org.Name)
+
+	_, err = r.CreateOrganization(context.Background(), "Acme")
+	require.Error(t, err)
+}
+
+func TestRemoveOrganizationMember(t *testing.T) {
+	r := &mutationResolver{newResolver(&mocks.Store{}, &mocks.Authenticator{}, tokenConfig())}
+
+	_, err := r.RemoveOrganizationMember(withCaller("1"), "2")
+	require.ErrorIs(t, err, authz.ErrForbidden)
+
+	ctx := store.WithTenant(withCaller("1"), store.Tenant{OrganizationID: "org-1"})
+	removed, err := r.RemoveOrganizationMember(ctx, "2")
+	require.NoError(t, err)
+	assert.True(t, removed)
+}
+
+// --- Invitations ---
+
+func TestInviteUser(t *testing.T) {
+	st := &mocks.Store{Organizations: []*store.Organization{{ID: "org-1", Name: "Acme"}}}
+	r := &mutationResolver{newResolver(st, &mocks.Authenticator{}, tokenConfig())}
+	mailer := &mocks.Mailer{}
+	r.Inviter.Mailer = mailer
+
+	role := "support"
+	ctx := store.WithTenant(withCaller("1"), store.Tenant{OrganizationID: "org-1"})
+	inv, err := r.InviteUser(ctx, "jane@example.com", &role)
+	require.NoError(t, err)
+	assert.Equal(t, "support", inv.Role)
+	assert.Equal(t, "org-1", inv.OrganizationID)
+	assert.False(t, inv.Expired)
+	require.Len(t, mailer.Sent, 1)
+	assert.Equal(t, "jane@example.com", mailer.Sent[0].To)
+
+	_, err = r.InviteUser(context.Background(), "jane@example.com", nil)
+	require.Error(t, err)
+}
+
+func TestRevokeInvitation(t *testing.T) {
+	st := &mocks.Store{Invitations: []*store.Invitation{{ID: "invitation-1"}}}
+	r := &mutationResolver{newResolver(st, &mocks.Authenticator{}, tokenConfig())}
+
+	revoked, err := r.RevokeInvitation(withCaller("1"), "invitation-1")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	_, err = r.RevokeInvitation(withCaller("1"), "invitation-2")
+	require.ErrorIs(t, err, store.ErrInvitationNotFound)
+}
+
+func TestDeleteUser(t *testing.T) {
+	r := &mutationResolver{newResolver(&mocks.Store{}, &mocks.Authenticator{}, tokenConfig())}
+	_, err := r.DeleteUser(withCaller("1"), "2")
+	require.ErrorIs(t, err, store.ErrUserNotFound)
+
+	r = &mutationResolver{newResolver(&mocks.Store{User: &store.User{ID: "2"}}, &mocks.Authenticator{}, tokenConfig())}
+	deleted, err := r.DeleteUser(withCaller("1"), "2")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+}
+
+func TestRestoreUser(t *testing.T) {
+	r := &mutationResolver{newResolver(&mocks.Store{}, &mocks.Authenticator{}, tokenConfig())}
+	_, err := r.RestoreUser(withCaller("1"), "2")
+	require.ErrorIs(t, err, store.ErrUserNotFound)
+
+	r = &mutationResolver{newResolver(&mocks.Store{User: &store.User{ID: "2", Email: testEmail}}, &mocks.Authenticator{}, tokenConfig())}
+	user, err := r.RestoreUser(withCaller("1"), "2")
+	require.NoError(t, err)
+	assert.Equal(t, testEmail, user.Email)
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	st := &mocks.Store{Invitations: []*store.Invitation{{
+		Email:     testEmail,
+		Role:      "support",
+		TokenHash: "3c469e9d6c5875d37a43f353d4f88e61fcf812c66eee3457465a40b0da4153e0", // sha256 of "token"
+	}}}
+	r := &mutationResolver{newResolver(st, &mocks.Authenticator{}, tokenConfig())}
+	input := model.AcceptInvitationInput{
+		Token:     "unknown",
+		FirstName: "John",
+		LastName:  "Doe",
+		Password:  testPassword,
+		Terms:     true,
+	}
+
+	_, err := r.AcceptInvitation(context.Background(), input)
+	require.ErrorIs(t, err, store.ErrInvitationNotFound)
+
+	input.Token = "token"
+	resp, err := r.AcceptInvitation(context.Background(), input)
+	require.NoError(t, err)
+	assert.Equal(t, testEmail, *resp.Email)
+}
+
+func TestInvitations(t *testing.T) {
+	st := &mocks.Store{Invitations: []*store.Invitation{{ID: "invitation-1", Email: testEmail}}}
+	r := &queryResolver{newResolver(st, &mocks.Authenticator{}, tokenConfig())}
+	invitations, err := r.Invitations(withCaller("1"))
+	require.NoError(t, err)
+	require.Len(t, invitations, 1)
+	assert.Equal(t, testEmail, invitations[0].Email)
+	assert.True(t, invitations[0].Expired)
+}
+
+// --- Profile ---
+
+func TestUpdateProfile(t *testing.T) {
+	st := &mocks.Store{User: &store.User{ID: "1", FirstName: "John", LastName: "Doe"}}
+	r := &mutationResolver{newResolver(st, &mocks.Authenticator{}, tokenConfig())}
+	locale, timezone, invalid := "en-GB", "Europe/London", "Mars/Olympus"
+
+	_, err := r.UpdateProfile(context.Background(), model.UpdateProfileInput{Locale: &locale})
+	require.Error(t, err)
+
+	_, err = r.UpdateProfile(withCaller("1"), model.UpdateProfileInput{Timezone: &invalid})
+	require.Error(t, err)
+
+	user, err := r.UpdateProfile(withCaller("1"), model.UpdateProfileInput{Locale: &locale, Timezone: &timezone})
+	require.NoError(t, err)
+	assert.Equal(t, "en-GB", *user.Locale)
+	assert.Equal(t, "Europe/London", *user.Timezone)
+	assert.Equal(t, "John", *user.FirstName)
+}
+
+// --- Email change ---
+
+func TestChangeEmail(t *testing.T) {
+	st := &mocks.Store{User: &store.User{ID: "1", Email: testEmail}}
+	r := &mutationResolver{newResolver(st, &mocks.Authenticator{ReturnVal: true}, tokenConfig())}
+	mailer := &mocks.Mailer{}
+	r.EmailChanger.Mailer = mailer
+
+	_, err := r.ChangeEmail(context.Background(), testPassword, "john@new.example.com")
+	require.Error(t, err)
+
+	_, err = r.ChangeEmail(withCaller("1"), testPassword, testEmail)
+	require.ErrorIs(t, err, business.ErrInvalidDetails)
+
+	changed, err := r.ChangeEmail(withCaller("1"), testPassword, "john@new.example.com")
+	require.NoError(t, err)
+	assert.True(t, changed)
+	require.Len(t, mailer.Sent, 2)
+	assert.Equal(t, "john@new.example.com", mailer.Sent[0].To)
+}
+
+func TestConfirmEmailChange(t *testing.T) {
+	st := &mocks.Store{EmailChanges: []*store.EmailChange{{
+		UserID:    "1",
+		NewEmail:  "john@new.example.com",
+		TokenHash: "3c469e9d6c5875d37a43f353d4f88e61fcf812c66eee3457465a40b0da4153e0", // sha256 of "token"
+	}}}
+	r := &mutationResolver{newResolver(st, &mocks.Authenticator{}, tokenConfig())}
+
+	_, err := r.ConfirmEmailChange(context.Background(), "unknown")
+	require.ErrorIs(t, err, store.ErrEmailChangeNotFound)
+
+	user, err := r.ConfirmEmailChange(context.Background(), "token")
+	require.NoError(t, err)
+	assert.Equal(t, "john@new.example.com", user.Email)
+}
+
 // insertMockStore returns empty user on Read (not found) and created on Insert.
 type insertMockStore struct {
+	mocks.Store
 	created *store.User
 }
 
@@ -175,20 +601,154 @@ func (s *insertMockStore) Read(_ context.Context, _ string) (*store.User, error)
 func (s *insertMockStore) Retrieve(_ context.Context, _ string) (*store.User, error) {
 	return s.created, nil
 }
-func (s *insertMockStore) Delete(_ string) (int64, error) { return 0, nil }
-func (s *insertMockStore) Ping() error                    { return nil }
-func (s *insertMockStore) UpdateRole(_ context.Context, _ string, _ string) error {
-	return nil
-}
 
-func (s *insertMockStore) ListByRole(_ context.Context, _ string) ([]*store.User, error) {
-	return nil, nil
+func TestLoginHistory(t *testing.T) {
+	other := "2"
+	negative := -1
+	attempt := &store.LoginAttempt{
+		ID:            "attempt-1",
+		UserID:        "1",
+		IP:            "203.0.113.7",
+		FailureReason: store.LoginFailureInvalidPassword,
+		CreatedAt:     time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC),
+	}
+	scenarios := []struct {
+		name        string
+		userID      *string
+		first       *int
+		store       *mocks.Store
+		expectedErr string
+	}{
+		{
+			name:        "negative first",
+			first:       &negative,
+			store:       &mocks.Store{User: &store.User{ID: "1"}},
+			expectedErr: errNegativeFirst.Error(),
+		},
+		{
+			name:        "another user without permission",
+			userID:      &other,
+			store:       &mocks.Store{User: &store.User{ID: "2"}},
+			expectedErr: authz.ErrForbidden.Error(),
+		},
+		{
+			name:        "user not found",
+			userID:      &other,
+			store:       &mocks.Store{Permissions: []string{authz.UsersRead}},
+			expectedErr: store.ErrUserNotFound.Error(),
+		},
+		{
+			name:  "own logins",
+			store: &mocks.Store{User: &store.User{ID: "1"}},
+		},
+		{
+			name:   "another user",
+			userID: &other,
+			store:  &mocks.Store{User: &store.User{ID: "2"}, Permissions: []string{authz.UsersRead}},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			sc.store.LoginAttempts = []*store.LoginAttempt{attempt}
+			r := &queryResolver{newResolver(sc.store, &mocks.Authenticator{}, tokenConfig())}
+
+			conn, err := r.LoginHistory(withCaller("1"), sc.userID, sc.first, nil)
+			if sc.expectedErr != "" {
+				require.ErrorContains(t, err, sc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, conn.Edges, 1)
+			node := conn.Edges[0].Node
+			assert.Equal(t, "attempt-1", node.ID)
+			assert.False(t, node.Success)
+			assert.Equal(t, store.LoginFailureInvalidPassword, *node.FailureReason)
+			assert.Equal(t, "2024-01-02T10:00:00Z", node.CreatedAt)
+			assert.Equal(t, store.LoginAttemptCursor(attempt), conn.Edges[0].Cursor)
+		})
+	}
 }
 
-func (s *insertMockStore) ListAll(_ context.Context) ([]*store.User, error) {
-	return nil, nil
+func TestCreateWebhook(t *testing.T) {
+	secret := "0123456789abcdef"
+	short := "short"
+	scenarios := []struct {
+		name        string
+		ctx         context.Context
+		input       model.CreateWebhookInput
+		expectedErr string
+	}{
+		{
+			name:        "no caller",
+			ctx:         context.Background(),
+			input:       model.CreateWebhookInput{URL: "https://example.com/hook", EventTypes: []string{"user.registered"}},
+			expectedErr: authz.ErrUnauthenticated.Error(),
+		},
+		{
+			name:        "short secret",
+			ctx:         withCaller("1"),
+			input:       model.CreateWebhookInput{URL: "https://example.com/hook", EventTypes: []string{"user.registered"}, Secret: &short},
+			expectedErr: "at least 16 characters",
+		},
+		{
+			name:  "created",
+			ctx:   withCaller("1"),
+			input: model.CreateWebhookInput{URL: "https://example.com/hook", EventTypes: []string{"user.registered"}, Secret: &secret},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			r := &mutationResolver{newResolver(&mocks.Store{}, &mocks.Authenticator{}, tokenConfig())}
+
+			webhook, err := r.CreateWebhook(sc.ctx, sc.input)
+			if sc.expectedErr != "" {
+				require.ErrorContains(t, err, sc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "webhook-1", webhook.ID)
+			assert.Equal(t, "1", webhook.CreatedBy)
+			assert.Equal(t, &secret, webhook.Secret)
+		})
+	}
 }
 
-func (s *insertMockStore) ToggleActive(_ context.Context, _ string) (bool, error) {
-	return false, nil
+func TestWebhooks(t *testing.T) {
+	st := &mocks.Store{
+		Webhooks: []*store.Webhook{{ID: "webhook-1", URL: "https://example.com/hook", EventTypes: []string{"user.registered"}}},
+		Deliveries: []*store.WebhookDelivery{{
+			ID:        "delivery-1",
+			WebhookID: "webhook-1",
+			Payload:   []byte(`{"user_id":"2"}`),
+			Status:    store.DeliveryFailed,
+			Attempts:  10,
+		}},
+	}
+	q := &queryResolver{newResolver(st, &mocks.Authenticator{}, tokenConfig())}
+
+	webhooks, err := q.Webhooks(withCaller("1"))
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	assert.Nil(t, webhooks[0].Secret)
+
+	_, err = q.WebhookDeliveries(withCaller("1"), "webhook-2", nil, nil)
+	require.ErrorIs(t, err, store.ErrWebhookNotFound)
+
+	conn, err := q.WebhookDeliveries(withCaller("1"), "webhook-1", nil, nil)
+	require.NoError(t, err)
+	require.Len(t, conn.Edges, 1)
+	assert.Equal(t, `{"user_id":"2"}`, conn.Edges[0].Node.Payload)
+	assert.Nil(t, conn.Edges[0].Node.DeliveredAt)
+
+	m := &mutationResolver{newResolver(st, &mocks.Authenticator{}, tokenConfig())}
+	delivery, err := m.ReplayWebhookDelivery(withCaller("1"), "delivery-1")
+	require.NoError(t, err)
+	assert.Equal(t, store.DeliveryPending, delivery.Status)
+	assert.Zero(t, delivery.Attempts)
+
+	deleted, err := m.DeleteWebhook(withCaller("1"), "webhook-1")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	_, err = m.DeleteWebhook(withCaller("1"), "webhook-1")
+	require.ErrorIs(t, err, store.ErrWebhookNotFound)
 }
@@ -2,14 +2,11 @@ package graph
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net"
 	"net/http"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
@@ -20,7 +17,9 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
-	"github.com/riyadennis/identity-server/app/gql/graph/generated"
+	"github.com/riyadennis/identity-server/app/gql/graph/loaders"
+	"github.com/riyadennis/identity-server/business"
+	"github.com/riyadennis/identity-server/business/authz"
 	"github.com/riyadennis/identity-server/business/store"
 	customMiddleware "github.com/riyadennis/identity-server/foundation/middleware"
 	"github.com/sirupsen/logrus"
@@ -49,20 +48,47 @@ type Server struct {
 	Authenticator store.Authenticator
 	Logger        *logrus.Logger
 	TokenConfig   *store.TokenConfig
+	Logins        *business.LoginRecorder
 	ShutDown      chan os.Signal
 }
 
+// NewServer serves the schema on port, only the operations of pq run when
+// it is not nil.
 func NewServer(logger *logrus.Logger, port string, store store.Store,
-	auth store.Authenticator, tc *store.TokenConfig,
+	auth store.Authenticator, tc *store.TokenConfig, pq *PersistedQueries,
 ) *Server {
 	resolver := NewResolver(logger, tc, store, auth)
-	srv := handler.New(generated.NewExecutableSchema(
-		generated.Config{
-			Resolvers: resolver,
+	ac := &customMiddleware.AuthConfig{
+		TokenConfig: tc,
+		Authorizer:  resolver.Authorizer,
+		Logger:      logger,
+	}
+	limits := NewENVLimits(logger)
+	logger.Infof("graphql limits: %s", limits)
+	srv := newHandler(resolver, ac, limits, pq)
+	addr := fmt.Sprintf(":%s", port)
+	return &Server{
+		Server: &http.Server{
+			Addr:    addr,
+			Handler: newRouter(ac, store, srv, limits, pq),
 		},
-	))
+		Store:         store,
+		Authenticator: auth,
+		Logger:        logger,
+		TokenConfig:   tc,
+		Logins:        resolver.Helper.Logins,
+		ShutDown:      make(chan os.Signal, 1),
+	}
+}
+
+// newHandler serves the schema over every transport, websocket connections
+// are authenticated with ac. Operations over the limits are rejected before
+// they are executed, as are the ones missing from pq when it is not nil.
+func newHandler(resolver *Resolver, ac *customMiddleware.AuthConfig, limits Limits, pq *PersistedQueries) *handler.Server {
+	srv := handler.New(NewExecutableSchema(resolver))
 	srv.AddTransport(transport.Websocket{
 		KeepAlivePingInterval: 10 * time.Second,
+		InitFunc:              websocketInit(ac),
 	})
 	srv.AddTransport(transport.Options{})
 	srv.AddTransport(transport.GET{})
@@ -70,23 +96,26 @@ func NewServer(logger *logrus.Logger, port string, store store.Store,
 	srv.AddTransport(transport.MultipartForm{})
 
 	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	srv.SetErrorPresenter(presentError(resolver.Logger))
+	srv.AroundRootFields(requireAuthentication)
 
-	srv.Use(extension.Introspection{})
-	srv.Use(extension.AutomaticPersistedQuery{
-		Cache: lru.New[string](100),
-	})
-	addr := fmt.Sprintf(":%s", port)
-	return &Server{
-		Server: &http.Server{
-			Addr:    addr,
-			Handler: newRouter(logger, tc, srv),
-		},
-		Store:         store,
-		Authenticator: auth,
-		Logger:        logger,
-		TokenConfig:   tc,
-		ShutDown:      make(chan os.Signal, 1),
+	if limits.Introspection {
+		srv.Use(extension.Introspection{})
+	} else {
+		srv.AroundRootFields(rejectIntrospection)
+	}
+	srv.Use(queryLimits{maxDepth: limits.MaxDepth, maxAliases: limits.MaxAliases})
+	if limits.MaxComplexity > 0 {
+		srv.Use(extension.FixedComplexityLimit(limits.MaxComplexity))
 	}
+	if pq != nil {
+		srv.Use(pq)
+	} else {
+		srv.Use(extension.AutomaticPersistedQuery{
+			Cache: lru.New[string](100),
+		})
+	}
+	return srv
 }
 
 func (s *Server) Start(port string) error {
@@ -113,71 +142,28 @@ func (s *Server) Start(port string) error {
 	return sErr
 }
 
-// QLRequest is used to distinguish public and private graphql operations.
-type QLRequest struct {
-	OperationName string `json:"operationName"`
-	Query         string `json:"query"`
-}
-
-// Public operations that don't require authentication.
-var publicOperations = map[string]bool{
-	"Login":        true,
-	"Register":     true,
-	"refreshToken": true,
-}
-
-// operationName returns the operation name from the request, falling back
-// to parsing the query string when the operationName JSON field is absent
-// (as is the case with clients like Bruno).
-func operationName(req QLRequest) string {
-	if req.OperationName != "" {
-		return req.OperationName
-	}
-	query := strings.TrimSpace(req.Query)
-	for _, prefix := range []string{"mutation ", "query ", "subscription "} {
-		if strings.HasPrefix(query, prefix) {
-			rest := strings.TrimSpace(query[len(prefix):])
-			if end := strings.IndexAny(rest, "({ "); end > 0 {
-				return strings.TrimSpace(rest[:end])
-			}
+// websocketInit authenticates websocket connections, which carry no
+// Authorization header, with the token in the Authorization field of their
+// connection_init payload. As over HTTP the token is optional, fields that
+// need one fail when it is missing.
+func websocketInit(ac *customMiddleware.AuthConfig) transport.WebsocketInitFunc {
+	return func(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		ctx, err := ac.AuthenticateOptional(ctx, payload.Authorization())
+		if err != nil {
+			return nil, nil, err
 		}
-	}
-	return ""
-}
-
-func NeedsAuthMiddleWare(ac customMiddleware.AuthConfig) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			body, err := io.ReadAll(r.Body)
-			if err != nil {
-				http.Error(w, "Failed to read request", http.StatusBadRequest)
-				return
-			}
 
-			// Parse GraphQL request
-			var gqlReq QLRequest
-			if err := json.Unmarshal(body, &gqlReq); err != nil {
-				http.Error(w, "Invalid GraphQL request", http.StatusBadRequest)
-				return
-			}
-
-			// Restore body for next handler
-			r.Body = io.NopCloser(strings.NewReader(string(body)))
-
-			// Check if operation is public
-			if publicOperations[operationName(gqlReq)] {
-				next.ServeHTTP(w, r)
-			} else {
-				ac.Auth(next).ServeHTTP(w, r)
-			}
-		})
+		return ctx, &payload, nil
 	}
 }
 
-func newRouter(logger *logrus.Logger, tc *store.TokenConfig, srv *handler.Server) http.Handler {
+// newRouter serves srv with the loaders of st installed for each request.
+func newRouter(ac *customMiddleware.AuthConfig, st store.Store, srv *handler.Server, limits Limits, pq *PersistedQueries) http.Handler {
 	chiRouter := chi.NewRouter()
 
 	chiRouter.Use(middleware.RequestID)
+	chiRouter.Use(customMiddleware.RealIP)
+	chiRouter.Use(customMiddleware.Actor)
 	chiRouter.Use(middleware.Recoverer)
 
 	chiRouter.Use(cors.Handler(cors.Options{
@@ -188,15 +174,31 @@ func newRouter(logger *logrus.Logger, tc *store.TokenConfig, srv *handler.Server
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
-	ac := customMiddleware.AuthConfig{
-		TokenConfig: tc,
-		Logger:      logger,
+	chiRouter.Use(limitBody(limits.MaxBodyBytes))
+	chiRouter.Use(ac.OptionalAuth)
+	chiRouter.Use(loaders.Middleware(st))
+	if limits.Introspection {
+		chiRouter.Handle("/", otelhttp.NewHandler(
+			playground.Handler("GraphQL playground", "/graphql"),
+			"graphql"))
 	}
-	chiRouter.Use(NeedsAuthMiddleWare(ac))
-	chiRouter.Handle("/", otelhttp.NewHandler(
-		playground.Handler("GraphQL playground", "/graphql"),
-		"graphql"))
 
 	chiRouter.Handle("/graphql", srv)
+	if pq != nil {
+		chiRouter.With(ac.Auth, ac.RequirePermission(authz.QueriesManage)).
+			Post("/admin/persisted-queries/reload", reloadPersistedQueries(pq, ac.Logger))
+	}
 	return chiRouter
 }
+
+// Stop shuts the server down once the requests in flight have finished and
+// waits for the new login notices being sent, giving up when ctx is done.
+func (s *Server) Stop(ctx context.Context) error {
+	err := s.Server.Shutdown(ctx)
+	if s.Logins != nil {
+		if err := s.Logins.Wait(ctx); err != nil {
+			s.Logger.Errorf("login notices not sent before shutdown: %v", err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,229 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/store"
+	customMiddleware "github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+const meQuery = `query Me { me { id } }`
+
+func postRequest(t *testing.T, body map[string]any) *http.Request {
+	t.Helper()
+	b, err := json.Marshal(body)
+	require.NoError(t, err)
+	r := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(b))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+func multipartRequest(t *testing.T, query string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	operations, err := json.Marshal(map[string]any{"query": query})
+	require.NoError(t, err)
+	require.NoError(t, w.WriteField("operations", string(operations)))
+	require.NoError(t, w.WriteField("map", "{}"))
+	require.NoError(t, w.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/graphql", &body)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+	return r
+}
+
+func TestServer_Authentication(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		request       func(t *testing.T) *http.Request
+		token         string
+		expectedError string
+		expectedData  string
+	}{
+		{
+			name:          "post without a token",
+			request:       func(t *testing.T) *http.Request { return postRequest(t, map[string]any{"query": meQuery}) },
+			expectedError: "unauthorized",
+		},
+		{
+			name:         "post with a token",
+			request:      func(t *testing.T) *http.Request { return postRequest(t, map[string]any{"query": meQuery}) },
+			token:        "valid",
+			expectedData: `{"me":{"id":"1"}}`,
+		},
+		{
+			name:          "invalid token",
+			request:       func(t *testing.T) *http.Request { return postRequest(t, map[string]any{"query": meQuery}) },
+			token:         "Bearer not-a-real-token",
+			expectedError: "unauthorized",
+		},
+		{
+			name: "public operation name",
+			request: func(t *testing.T) *http.Request {
+				return postRequest(t, map[string]any{"operationName": "Login", "query": `query Login { me { id } }`})
+			},
+			expectedError: "unauthorized",
+		},
+		{
+			name: "public field",
+			request: func(t *testing.T) *http.Request {
+				return postRequest(t, map[string]any{"query": `mutation { Login(input: {email: "not-an-email", password: "x"}) { status } }`})
+			},
+			expectedError: "invalid password",
+		},
+		{
+			name: "get without a token",
+			request: func(*testing.T) *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(meQuery), nil)
+			},
+			expectedError: "unauthorized",
+		},
+		{
+			name: "get with a token",
+			request: func(*testing.T) *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(meQuery), nil)
+			},
+			token:        "valid",
+			expectedData: `{"me":{"id":"1"}}`,
+		},
+		{
+			name:         "multipart with a token",
+			request:      func(t *testing.T) *http.Request { return multipartRequest(t, meQuery) },
+			token:        "valid",
+			expectedData: `{"me":{"id":"1"}}`,
+		},
+		{
+			name:          "multipart without a token",
+			request:       func(t *testing.T) *http.Request { return multipartRequest(t, meQuery) },
+			expectedError: "unauthorized",
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			st := &mocks.Store{
+				User:          &store.User{ID: "1", Email: testEmail},
+				Organizations: []*store.Organization{{ID: "org-1"}},
+			}
+			r := newResolver(st, &mocks.Authenticator{}, tokenConfig())
+			ac := &customMiddleware.AuthConfig{TokenConfig: tokenConfig(), Authorizer: r.Authorizer, Logger: r.Logger}
+			router := newRouter(ac, r.Store, newHandler(r, ac, DefaultLimits(), nil), DefaultLimits(), nil)
+
+			req := sc.request(t)
+			if sc.token == "valid" {
+				req.Header.Set("Authorization", testToken(t, "1"))
+			} else if sc.token != "" {
+				req.Header.Set("Authorization", sc.token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			var resp struct {
+				Data   json.RawMessage
+				Errors []struct{ Message string }
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			if sc.expectedError != "" {
+				require.NotEmpty(t, resp.Errors)
+				assert.True(t, strings.Contains(resp.Errors[0].Message, sc.expectedError), resp.Errors[0].Message)
+				return
+			}
+			assert.Empty(t, resp.Errors)
+			assert.JSONEq(t, sc.expectedData, string(resp.Data))
+		})
+	}
+}
+
+func TestServer_MixedDocument(t *testing.T) {
+	r := newResolver(&mocks.Store{}, &mocks.Authenticator{}, tokenConfig())
+	ac := &customMiddleware.AuthConfig{TokenConfig: tokenConfig(), Authorizer: r.Authorizer, Logger: r.Logger}
+
+	w := httptest.NewRecorder()
+	newRouter(ac, r.Store, newHandler(r, ac, DefaultLimits(), nil), DefaultLimits(), nil).ServeHTTP(w, postRequest(t, map[string]any{
+		"operationName": "Register",
+		"query": `mutation Register {
+			Login(input: {email: "not-an-email", password: "x"}) { status }
+			createOrganization(name: "acme") { id }
+		}`,
+	}))
+
+	var resp struct {
+		Errors []struct {
+			Message string
+			Path    []string
+		}
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	var unauthorized []string
+	for _, e := range resp.Errors {
+		if strings.Contains(e.Message, "unauthorized") {
+			unauthorized = append(unauthorized, e.Path...)
+		}
+	}
+	assert.Equal(t, []string{"createOrganization"}, unauthorized)
+}
+
+func TestServer_Loaders(t *testing.T) {
+	st := &mocks.Store{
+		User:          &store.User{ID: "1", Email: testEmail, Roles: []string{"user"}},
+		Permissions:   []string{authz.RolesRead},
+		Organizations: []*store.Organization{{ID: "org-1"}},
+	}
+	r := newResolver(st, &mocks.Authenticator{}, tokenConfig())
+	ac := &customMiddleware.AuthConfig{TokenConfig: tokenConfig(), Authorizer: r.Authorizer, Logger: r.Logger}
+
+	req := postRequest(t, map[string]any{"query": `{
+		me { id }
+		a: getUserRole(userId: "2") { userId }
+		b: getUserRole(userId: "2") { userId }
+		c: getUserRole(userId: "1") { userId }
+	}`})
+	req.Header.Set("Authorization", testToken(t, "1"))
+	w := httptest.NewRecorder()
+	newRouter(ac, st, newHandler(r, ac, DefaultLimits(), nil), DefaultLimits(), nil).ServeHTTP(w, req)
+
+	assert.JSONEq(t, `{"data": {"me": {"id": "1"}, "a": {"userId": "2"}, "b": {"userId": "2"}, "c": {"userId": "1"}}}`, w.Body.String())
+	var fetched []string
+	for _, ids := range st.RetrievedMany {
+		fetched = append(fetched, ids...)
+	}
+	assert.ElementsMatch(t, []string{"1", "2"}, fetched, "every user is fetched once")
+}
+
+// stoppedServer records that it was shut down.
+type stoppedServer struct {
+	shutdown bool
+}
+
+func (s *stoppedServer) Shutdown(context.Context) error {
+	s.shutdown = true
+	return nil
+}
+
+func (s *stoppedServer) Serve(net.Listener) error { return nil }
+
+func TestServer_Stop(t *testing.T) {
+	s := NewServer(logrus.New(), "0", &mocks.Store{}, &mocks.Authenticator{}, tokenConfig(), nil)
+	require.NotNil(t, s.Logins)
+	httpServer := &stoppedServer{}
+	s.Server = httpServer
+
+	require.NoError(t, s.Stop(context.Background()))
+	assert.True(t, httpServer.shutdown)
+}
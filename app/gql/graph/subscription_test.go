@@ -0,0 +1,174 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business"
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/events"
+	"github.com/riyadennis/identity-server/business/store"
+	customMiddleware "github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+// newWebsocketClient serves r through the router, as NewServer does.
+func newWebsocketClient(r *Resolver) *client.Client {
+	ac := &customMiddleware.AuthConfig{TokenConfig: tokenConfig(), Authorizer: r.Authorizer, Logger: r.Logger}
+	return client.New(newRouter(ac, r.Store, newHandler(r, ac, DefaultLimits(), nil), DefaultLimits(), nil), client.Path("/graphql"))
+}
+
+func testToken(t *testing.T, userID string) string {
+	t.Helper()
+	token, err := business.NewHelper(&mocks.Store{}, &mocks.Authenticator{}, &mocks.Mailer{}, logrus.New()).
+		ManageToken(context.Background(), tokenConfig(), userID, "org-1")
+	require.NoError(t, err)
+	return "Bearer " + token.AccessToken
+}
+
+// publishUntil publishes e until done is closed, a subscription only gets
+// the events published once the server has started it.
+func publishUntil(bus *events.Bus, e events.Event, done <-chan struct{}) {
+	for {
+		bus.Publish(e)
+		select {
+		case <-done:
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestSubscription_Websocket(t *testing.T) {
+	const query = `subscription { userActivationChanged { userId active } }`
+	scenarios := []struct {
+		name        string
+		payload     func(t *testing.T) map[string]any
+		permissions []string
+		expectedErr string
+	}{
+		{
+			name:        "no token",
+			payload:     func(*testing.T) map[string]any { return nil },
+			expectedErr: "unauthorized",
+		},
+		{
+			name: "invalid token",
+			payload: func(*testing.T) map[string]any {
+				return map[string]any{"Authorization": "Bearer not-a-real-token"}
+			},
+			expectedErr: "unauthorized",
+		},
+		{
+			name: "missing permission",
+			payload: func(t *testing.T) map[string]any {
+				return map[string]any{"Authorization": testToken(t, "1")}
+			},
+			permissions: []string{authz.RolesRead},
+			expectedErr: authz.ErrForbidden.Error(),
+		},
+		{
+			name: "subscribed",
+			payload: func(t *testing.T) map[string]any {
+				return map[string]any{"Authorization": testToken(t, "1")}
+			},
+			permissions: []string{authz.UsersRead},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			st := &mocks.Store{
+				User:          &store.User{ID: "2"},
+				Permissions:   sc.permissions,
+				Organizations: []*store.Organization{{ID: "org-1"}},
+			}
+			r := newResolver(st, &mocks.Authenticator{}, tokenConfig())
+
+			sub := newWebsocketClient(r).WebsocketWithPayload(query, sc.payload(t))
+			defer func() { _ = sub.Close() }()
+
+			done := make(chan struct{})
+			defer close(done)
+			go publishUntil(r.Events, events.UserDeactivated{UserID: "2"}, done)
+
+			var resp struct {
+				UserActivationChanged struct {
+					UserID string
+					Active bool
+				}
+			}
+			err := sub.Next(&resp)
+			if sc.expectedErr != "" {
+				require.ErrorContains(t, err, sc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "2", resp.UserActivationChanged.UserID)
+			assert.False(t, resp.UserActivationChanged.Active)
+		})
+	}
+}
+
+func TestSubscription_Mutations(t *testing.T) {
+	ctx, cancel := context.WithCancel(withCaller("1"))
+	defer cancel()
+
+	st := &mocks.Store{User: &store.User{ID: "2", Email: testEmail, Active: true}}
+	r := newResolver(st, &mocks.Authenticator{}, tokenConfig())
+	sub := &subscriptionResolver{r}
+	activations, err := sub.UserActivationChanged(ctx)
+	require.NoError(t, err)
+	roles, err := sub.RoleAssigned(ctx)
+	require.NoError(t, err)
+
+	m := &mutationResolver{r}
+	_, err = m.UserActivation(withCaller("1"), "2")
+	require.NoError(t, err)
+	_, err = m.GrantRole(withCaller("1"), "2", "support")
+	require.NoError(t, err)
+
+	select {
+	case a := <-activations:
+		assert.Equal(t, "2", a.UserID)
+		assert.True(t, a.Active)
+	case <-time.After(time.Second):
+		t.Fatal("no activation received")
+	}
+	select {
+	case a := <-roles:
+		assert.Equal(t, "2", a.UserID)
+		assert.Equal(t, "support", a.Role)
+	case <-time.After(time.Second):
+		t.Fatal("no role assignment received")
+	}
+}
+
+func TestSubscription_OtherOrganization(t *testing.T) {
+	ctx, cancel := context.WithCancel(withCaller("1"))
+
+	// the store does not find users outside of the subscriber's organization.
+	r := newResolver(&mocks.Store{}, &mocks.Authenticator{}, tokenConfig())
+	registered, err := (&subscriptionResolver{r}).UserRegistered(ctx)
+	require.NoError(t, err)
+
+	r.Events.Publish(events.UserRegistered{UserID: "2"})
+	select {
+	case u := <-registered:
+		t.Fatalf("received user %s of another organization", u.ID)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case _, ok := <-registered:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("subscription not closed")
+	}
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	// initialise migration settings.
 	_ "github.com/go-sql-driver/mysql"
@@ -11,9 +13,14 @@ import (
 
 	"github.com/riyadennis/identity-server/app/gql/graph"
 	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/business/validation"
 	"github.com/riyadennis/identity-server/foundation"
 )
 
+// shutdownTimeout is how long the requests in flight and the notices being
+// sent have to finish when the server is stopped.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	logger := foundation.NewLogger()
 	err := foundation.ValidatePort(os.Getenv("GRAPHQL_PORT"))
@@ -21,11 +28,19 @@ func main() {
 		logger.Fatalf("invalid port: %v", os.Getenv("GRAPHQL_PORT"))
 	}
 	cfg := store.NewENVConfig()
+	err = validation.LoadDisposableDomains(os.Getenv("DISPOSABLE_EMAIL_DOMAINS"))
+	if err != nil {
+		logger.Fatalf("failed to load disposable email domains: %v", err)
+	}
+	pq, err := graph.LoadPersistedQueries(os.Getenv("GRAPHQL_PERSISTED_QUERIES"))
+	if err != nil {
+		logger.Fatalf("failed to load persisted queries: %v", err)
+	}
 	st, auth, err := store.SetUpMYSQL(logger)
 	if err != nil {
 		logger.Fatalf("database setUp failed %v", err)
 	}
-	s := graph.NewServer(logger, os.Getenv("GRAPHQL_PORT"), st, auth, cfg.Token)
+	s := graph.NewServer(logger, os.Getenv("GRAPHQL_PORT"), st, auth, cfg.Token, pq)
 	signal.Notify(s.ShutDown, os.Interrupt, syscall.SIGTERM)
 
 	err = s.Start(os.Getenv("GRAPHQL_PORT"))
@@ -34,4 +49,10 @@ func main() {
 	}
 
 	<-s.ShutDown
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		logger.Errorf("failed to shut down graphQL server: %v", err)
+	}
 }
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"syscall"
@@ -10,7 +11,9 @@ import (
 	_ "github.com/golang-migrate/migrate/source/file"
 	"github.com/riyadennis/identity-server/app/server"
 
+	"github.com/riyadennis/identity-server/business"
 	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/business/validation"
 	"github.com/riyadennis/identity-server/foundation"
 )
 
@@ -18,6 +21,10 @@ func main() {
 	logger := foundation.NewLogger()
 	cfg := store.NewENVConfig()
 
+	err := validation.LoadDisposableDomains(os.Getenv("DISPOSABLE_EMAIL_DOMAINS"))
+	if err != nil {
+		logger.Fatalf("failed to load disposable email domains: %v", err)
+	}
 	st, auth, err := store.SetUpMYSQL(logger)
 	if err != nil {
 		logger.Fatalf("database setUp failed %v", err)
@@ -36,6 +43,23 @@ func main() {
 
 	newServer.RESTHandler(cfg.Token, st, auth)
 
+	// deleted users are purged and the outbox is relayed by the REST server
+	// only, the other servers share its database.
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	go business.NewDeleter(st, logger, os.Getenv("DELETED_USER_RETENTION")).RunPurger(workerCtx, business.PurgeInterval)
+
+	publisher, err := foundation.NewENVPublisher(logger)
+	if err != nil {
+		logger.Fatalf("event publisher initialisation failed: %v", err)
+	}
+	if p, ok := publisher.(*foundation.NATSPublisher); ok {
+		defer func() { _ = p.Close() }()
+	}
+	webhooks := business.NewWebhooks(st, logger)
+	go business.NewRelay(st, foundation.Publishers{publisher, webhooks}, logger).Run(workerCtx, business.RelayInterval)
+	go webhooks.Run(workerCtx, business.WebhookInterval)
+
 	err = newServer.Run()
 	if err != nil {
 		logger.Fatalf("error running server: %v", err)
@@ -2,12 +2,55 @@ package mocks
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
 
 	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
 )
 
 type Store struct {
-	Error error
+	Error         error
+	Permissions   []string
+	RoleList      []*store.Role
+	Organizations []*store.Organization
+	Invitations   []*store.Invitation
+	EmailChanges  []*store.EmailChange
+	// ReservedEmails are the addresses EmailReserved reports as reserved.
+	ReservedEmails []string
+	// ListedWith are the options ListUsers was last called with.
+	ListedWith *store.ListOptions
+	// SearchedWith are the options SearchUsers was last called with.
+	SearchedWith *store.SearchOptions
+	// RestoredSince is the deletion time Restore was last called with.
+	RestoredSince time.Time
+	// PurgeBatches are the numbers of users each call to PurgeDeleted purges.
+	PurgeBatches []int64
+	// AuditEvents are the events recorded with RecordAudit and listed by ListAuditEvents.
+	AuditEvents []*store.AuditEvent
+	// AuditListedWith are the options ListAuditEvents was last called with.
+	AuditListedWith *store.AuditOptions
+	// LoginAttempts are the attempts recorded with RecordLoginAttempt and listed by ListLoginAttempts.
+	LoginAttempts []*store.LoginAttempt
+	// Known is what KnownLogins returns, a user that never logged in when nil.
+	Known *store.KnownLogins
+	// LoginHistoryWith are the options ListLoginAttempts was last called with.
+	LoginHistoryWith *store.LoginHistoryOptions
+	// Outbox are the events waiting to be published by RelayEvents.
+	Outbox []*store.OutboxEvent
+	// Webhooks and Deliveries are the webhooks and their deliveries.
+	Webhooks   []*store.Webhook
+	Deliveries []*store.WebhookDelivery
+	// Enqueued are the events passed to EnqueueWebhookDeliveries and
+	// EnqueuedFor the organizations they were enqueued for.
+	Enqueued    []*store.OutboxEvent
+	EnqueuedFor []string
+	// Replaced is the role ReplaceRole last took away.
+	Replaced string
+	// RetrievedMany are the ids of each call to RetrieveMany.
+	RetrievedMany [][]string
 	*store.User
 }
 
@@ -23,30 +66,77 @@ func (s *Store) Retrieve(_ context.Context, _ string) (*store.User, error) {
 	return s.User, s.Error
 }
 
-func (s *Store) Delete(_ string) (int64, error) {
-	return 0, s.Error
+// RetrieveMany records ids in RetrievedMany and returns a copy of User for
+// each of them, with the id set.
+func (s *Store) RetrieveMany(_ context.Context, ids []string) ([]*store.User, error) {
+	s.RetrievedMany = append(s.RetrievedMany, ids)
+	if s.User == nil || s.Error != nil {
+		return nil, s.Error
+	}
+	users := make([]*store.User, 0, len(ids))
+	for _, id := range ids {
+		u := *s.User
+		u.ID = id
+		users = append(users, &u)
+	}
+	return users, nil
 }
 
-func (s *Store) Ping() error {
-	return s.Error
+// Delete reports User as deleted when it is set.
+func (s *Store) Delete(_ context.Context, _ string) (int64, error) {
+	if s.User == nil {
+		return 0, s.Error
+	}
+	return 1, s.Error
 }
 
-func (s *Store) UpdateRole(_ context.Context, _ string, _ string) error {
+// Restore reports User as restored when it is set.
+func (s *Store) Restore(_ context.Context, _ string, deletedSince time.Time) (int64, error) {
+	s.RestoredSince = deletedSince
+	if s.User == nil {
+		return 0, s.Error
+	}
+	return 1, s.Error
+}
+
+// PurgeDeleted returns the first of PurgeBatches and removes it.
+func (s *Store) PurgeDeleted(_ context.Context, _ time.Time, _ int) (int64, error) {
+	if s.Error != nil || len(s.PurgeBatches) == 0 {
+		return 0, s.Error
+	}
+	purged := s.PurgeBatches[0]
+	s.PurgeBatches = s.PurgeBatches[1:]
+	return purged, nil
+}
+
+func (s *Store) Ping() error {
 	return s.Error
 }
 
-func (s *Store) ListByRole(_ context.Context, _ string) ([]*store.User, error) {
-	if s.User == nil {
+// ListUsers records opts in ListedWith and returns a page with User.
+func (s *Store) ListUsers(_ context.Context, opts store.ListOptions) (*store.UserPage, error) {
+	s.ListedWith = &opts
+	if s.Error != nil {
 		return nil, s.Error
 	}
-	return []*store.User{s.User}, s.Error
+	page := &store.UserPage{Users: []*store.User{}}
+	if s.User != nil {
+		page.Users = append(page.Users, s.User)
+	}
+	return page, nil
 }
 
-func (s *Store) ListAll(_ context.Context) ([]*store.User, error) {
-	if s.User == nil {
+// SearchUsers records opts in SearchedWith and returns a page with User.
+func (s *Store) SearchUsers(_ context.Context, opts store.SearchOptions) (*store.UserPage, error) {
+	s.SearchedWith = &opts
+	if s.Error != nil {
 		return nil, s.Error
 	}
-	return []*store.User{s.User}, s.Error
+	page := &store.UserPage{Users: []*store.User{}}
+	if s.User != nil {
+		page.Users = append(page.Users, s.User)
+	}
+	return page, nil
 }
 
 func (s *Store) ToggleActive(_ context.Context, _ string) (bool, error) {
@@ -56,6 +146,464 @@ func (s *Store) ToggleActive(_ context.Context, _ string) (bool, error) {
 	return false, s.Error
 }
 
+// SetActive reports User as changed when it is not in the active state already.
+func (s *Store) SetActive(_ context.Context, _ string, active bool) (bool, error) {
+	if s.Error != nil {
+		return false, s.Error
+	}
+	if s.User == nil {
+		return false, store.ErrUserNotFound
+	}
+	return s.User.Active != active, nil
+}
+
+// UpdateProfile applies the update to the mock user.
+func (s *Store) UpdateProfile(_ context.Context, _ string, p *store.ProfileUpdate) (*store.User, error) {
+	if s.Error != nil {
+		return nil, s.Error
+	}
+	if s.User == nil {
+		return nil, store.ErrUserNotFound
+	}
+	for field, value := range map[*string]*string{
+		&s.User.FirstName:  p.FirstName,
+		&s.User.LastName:   p.LastName,
+		&s.User.Company:    p.Company,
+		&s.User.PostCode:   p.PostCode,
+		&s.User.Locale:     p.Locale,
+		&s.User.Timezone:   p.Timezone,
+		&s.User.PictureURL: p.PictureURL,
+	} {
+		if value != nil {
+			*field = *value
+		}
+	}
+	return s.User, nil
+}
+
+func (s *Store) UserRoles(_ context.Context, _ string) ([]string, error) {
+	if s.User == nil {
+		return nil, s.Error
+	}
+	return s.User.Roles, s.Error
+}
+
+func (s *Store) UserPermissions(_ context.Context, _ string) ([]string, error) {
+	return s.Permissions, s.Error
+}
+
+func (s *Store) AssignRole(_ context.Context, _, _ string) error {
+	return s.Error
+}
+
+func (s *Store) RevokeRole(_ context.Context, _, _ string) error {
+	return s.Error
+}
+
+func (s *Store) ReplaceRole(_ context.Context, _, _, replaced string) error {
+	s.Replaced = replaced
+	return s.Error
+}
+
+func (s *Store) ListRoles(_ context.Context) ([]*store.Role, error) {
+	return s.RoleList, s.Error
+}
+
+func (s *Store) RetrieveRole(_ context.Context, name string) (*store.Role, error) {
+	for _, r := range s.RoleList {
+		if r.Name == name {
+			return r, s.Error
+		}
+	}
+	if s.Error != nil {
+		return nil, s.Error
+	}
+	return nil, store.ErrRoleNotFound
+}
+
+func (s *Store) CreateRole(_ context.Context, r *store.Role) (*store.Role, error) {
+	return r, s.Error
+}
+
+func (s *Store) SetRolePermissions(_ context.Context, _ string, _ []string) error {
+	return s.Error
+}
+
+func (s *Store) DeleteRole(_ context.Context, _ string) error {
+	return s.Error
+}
+
+func (s *Store) ListPermissions(_ context.Context) ([]*store.Permission, error) {
+	permissions := make([]*store.Permission, 0, len(s.Permissions))
+	for _, p := range s.Permissions {
+		permissions = append(permissions, &store.Permission{Name: p})
+	}
+	return permissions, s.Error
+}
+
+func (s *Store) CreateOrganization(_ context.Context, name, ownerID string) (*store.Organization, error) {
+	return &store.Organization{ID: name, Name: name, CreatedBy: ownerID}, s.Error
+}
+
+func (s *Store) RetrieveOrganization(_ context.Context, id string) (*store.Organization, error) {
+	for _, o := range s.Organizations {
+		if o.ID == id {
+			return o, s.Error
+		}
+	}
+	if s.Error != nil {
+		return nil, s.Error
+	}
+	return nil, store.ErrOrganizationNotFound
+}
+
+func (s *Store) ListOrganizations(_ context.Context, _ string) ([]*store.Organization, error) {
+	return s.Organizations, s.Error
+}
+
+// IsMember treats the user as a member of every organization in Organizations.
+func (s *Store) IsMember(_ context.Context, orgID, _ string) (bool, error) {
+	for _, o := range s.Organizations {
+		if o.ID == orgID {
+			return true, s.Error
+		}
+	}
+	return false, s.Error
+}
+
+func (s *Store) AddMember(_ context.Context, _, _ string, _ []string) error {
+	return s.Error
+}
+
+func (s *Store) RemoveMember(_ context.Context, _, _ string) error {
+	return s.Error
+}
+
+func (s *Store) CreateInvitation(ctx context.Context, inv *store.Invitation) (*store.Invitation, error) {
+	if s.Error != nil {
+		return nil, s.Error
+	}
+	if t, ok := store.TenantFromContext(ctx); ok {
+		inv.OrganizationID = t.OrganizationID
+	}
+	inv.ID = "invitation-1"
+	return inv, nil
+}
+
+func (s *Store) RetrieveInvitation(_ context.Context, id string) (*store.Invitation, error) {
+	return s.invitation(func(inv *store.Invitation) bool { return inv.ID == id })
+}
+
+func (s *Store) PendingInvitation(_ context.Context, tokenHash string) (*store.Invitation, error) {
+	return s.invitation(func(inv *store.Invitation) bool { return inv.TokenHash == tokenHash })
+}
+
+func (s *Store) invitation(match func(*store.Invitation) bool) (*store.Invitation, error) {
+	if s.Error != nil {
+		return nil, s.Error
+	}
+	for _, inv := range s.Invitations {
+		if match(inv) {
+			return inv, nil
+		}
+	}
+	return nil, store.ErrInvitationNotFound
+}
+
+func (s *Store) ListInvitations(_ context.Context) ([]*store.Invitation, error) {
+	return s.Invitations, s.Error
+}
+
+func (s *Store) RenewInvitation(ctx context.Context, id, tokenHash string, expiresAt time.Time) error {
+	inv, err := s.RetrieveInvitation(ctx, id)
+	if err != nil {
+		return err
+	}
+	inv.TokenHash = tokenHash
+	inv.ExpiresAt = expiresAt
+	return nil
+}
+
+func (s *Store) RevokeInvitation(ctx context.Context, id string) error {
+	_, err := s.RetrieveInvitation(ctx, id)
+	return err
+}
+
+// AcceptInvitation returns User with the details of the matching invitation,
+// or ErrDuplicateEmail if User already has the invited email.
+func (s *Store) AcceptInvitation(ctx context.Context, tokenHash string, u *store.User) (*store.User, error) {
+	inv, err := s.PendingInvitation(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	if s.User != nil && s.User.Email == inv.Email {
+		return nil, store.ErrDuplicateEmail
+	}
+	u.Email = inv.Email
+	u.Roles = []string{inv.Role}
+	return u, nil
+}
+
+func (s *Store) CreateEmailChange(_ context.Context, c *store.EmailChange) (*store.EmailChange, error) {
+	if s.Error != nil {
+		return nil, s.Error
+	}
+	c.ID = "email-change-1"
+	s.EmailChanges = append(s.EmailChanges, c)
+	return c, nil
+}
+
+// ConfirmEmailChange sets the email of User to the new address of the matching change.
+func (s *Store) ConfirmEmailChange(_ context.Context, tokenHash string, _ time.Time) (*store.User, error) {
+	if s.Error != nil {
+		return nil, s.Error
+	}
+	for _, c := range s.EmailChanges {
+		if c.TokenHash != tokenHash {
+			continue
+		}
+		if slices.Contains(s.ReservedEmails, c.NewEmail) {
+			return nil, store.ErrDuplicateEmail
+		}
+		if s.User == nil {
+			s.User = &store.User{ID: c.UserID}
+		}
+		s.User.Email = c.NewEmail
+		return s.User, nil
+	}
+	return nil, store.ErrEmailChangeNotFound
+}
+
+func (s *Store) EmailReserved(_ context.Context, email, _ string) (bool, error) {
+	return slices.Contains(s.ReservedEmails, email), s.Error
+}
+
+// ExportUser returns an export of User, or ErrUserNotFound when it is not set.
+func (s *Store) ExportUser(_ context.Context, _ string) (*store.UserExport, error) {
+	if s.Error != nil {
+		return nil, s.Error
+	}
+	if s.User == nil {
+		return nil, store.ErrUserNotFound
+	}
+	return &store.UserExport{
+		ExportedAt:    time.Now().UTC(),
+		Profile:       s.User,
+		Organizations: s.Organizations,
+		Roles:         []*store.RoleGrant{},
+		Sessions:      []*store.Session{},
+		EmailChanges:  s.EmailChanges,
+		AuditEvents:   s.AuditEvents,
+		LoginAttempts: s.LoginAttempts,
+	}, nil
+}
+
+// EraseUser records the erasure of User, or returns ErrUserNotFound when it is not set.
+func (s *Store) EraseUser(_ context.Context, id, erasedBy string) (*store.Erasure, error) {
+	if s.Error != nil {
+		return nil, s.Error
+	}
+	if s.User == nil {
+		return nil, store.ErrUserNotFound
+	}
+	return &store.Erasure{ID: "erasure-1", UserID: id, ErasedBy: erasedBy, ErasedAt: time.Now().UTC()}, nil
+}
+
+// RecordAudit appends e to AuditEvents.
+func (s *Store) RecordAudit(_ context.Context, e *store.AuditEvent) error {
+	if s.Error != nil {
+		return s.Error
+	}
+	s.AuditEvents = append(s.AuditEvents, e)
+	return nil
+}
+
+// ListAuditEvents records opts in AuditListedWith and returns a page with AuditEvents.
+func (s *Store) ListAuditEvents(_ context.Context, opts store.AuditOptions) (*store.AuditPage, error) {
+	s.AuditListedWith = &opts
+	if s.Error != nil {
+		return nil, s.Error
+	}
+	events := s.AuditEvents
+	if events == nil {
+		events = []*store.AuditEvent{}
+	}
+	return &store.AuditPage{Events: events}, nil
+}
+
+// RecordLoginAttempt appends a to LoginAttempts.
+func (s *Store) RecordLoginAttempt(_ context.Context, a *store.LoginAttempt) error {
+	if s.Error != nil {
+		return s.Error
+	}
+	s.LoginAttempts = append(s.LoginAttempts, a)
+	return nil
+}
+
+// KnownLogins returns Known.
+func (s *Store) KnownLogins(_ context.Context, _, _, _ string) (*store.KnownLogins, error) {
+	if s.Error != nil {
+		return nil, s.Error
+	}
+	if s.Known == nil {
+		return &store.KnownLogins{}, nil
+	}
+	return s.Known, nil
+}
+
+// ListLoginAttempts records opts in LoginHistoryWith and returns a page with
+// LoginAttempts, or ErrUserNotFound when User is not set.
+func (s *Store) ListLoginAttempts(_ context.Context, _ string, opts store.LoginHistoryOptions) (*store.LoginAttemptPage, error) {
+	s.LoginHistoryWith = &opts
+	if s.Error != nil {
+		return nil, s.Error
+	}
+	if s.User == nil {
+		return nil, store.ErrUserNotFound
+	}
+	attempts := s.LoginAttempts
+	if attempts == nil {
+		attempts = []*store.LoginAttempt{}
+	}
+	return &store.LoginAttemptPage{Attempts: attempts}, nil
+}
+
+// RelayEvents passes up to limit events of Outbox to publish and removes the
+// ones published. The ordering of the events of a user is left to the store.
+func (s *Store) RelayEvents(_ context.Context, limit int, publish func(*store.OutboxEvent) error) (int, error) {
+	if s.Error != nil {
+		return 0, s.Error
+	}
+	published := 0
+	var pending []*store.OutboxEvent
+	for i, e := range s.Outbox {
+		if i >= limit {
+			pending = append(pending, e)
+			continue
+		}
+		if err := publish(e); err != nil {
+			e.Attempts++
+			pending = append(pending, e)
+			continue
+		}
+		published++
+	}
+	s.Outbox = pending
+	return published, nil
+}
+
+// CreateWebhook adds w to Webhooks.
+func (s *Store) CreateWebhook(_ context.Context, w *store.Webhook) (*store.Webhook, error) {
+	if s.Error != nil {
+		return nil, s.Error
+	}
+	w.ID = fmt.Sprintf("webhook-%d", len(s.Webhooks)+1)
+	w.CreatedAt = time.Now().UTC()
+	s.Webhooks = append(s.Webhooks, w)
+	return w, nil
+}
+
+// RetrieveWebhook returns the webhook of Webhooks with id.
+func (s *Store) RetrieveWebhook(_ context.Context, id string) (*store.Webhook, error) {
+	if s.Error != nil {
+		return nil, s.Error
+	}
+	for _, w := range s.Webhooks {
+		if w.ID == id {
+			return w, nil
+		}
+	}
+	return nil, store.ErrWebhookNotFound
+}
+
+// ListWebhooks returns Webhooks.
+func (s *Store) ListWebhooks(_ context.Context) ([]*store.Webhook, error) {
+	if s.Error != nil {
+		return nil, s.Error
+	}
+	if s.Webhooks == nil {
+		return []*store.Webhook{}, nil
+	}
+	return s.Webhooks, nil
+}
+
+// DeleteWebhook removes the webhook with id from Webhooks.
+func (s *Store) DeleteWebhook(_ context.Context, id string) error {
+	if s.Error != nil {
+		return s.Error
+	}
+	for i, w := range s.Webhooks {
+		if w.ID == id {
+			s.Webhooks = slices.Delete(s.Webhooks, i, i+1)
+			return nil
+		}
+	}
+	return store.ErrWebhookNotFound
+}
+
+// EnqueueWebhookDeliveries records e in Enqueued and organizationID in EnqueuedFor.
+func (s *Store) EnqueueWebhookDeliveries(_ context.Context, e *store.OutboxEvent, organizationID string) (int64, error) {
+	if s.Error != nil {
+		return 0, s.Error
+	}
+	s.Enqueued = append(s.Enqueued, e)
+	s.EnqueuedFor = append(s.EnqueuedFor, organizationID)
+	return 1, nil
+}
+
+// DueWebhookDeliveries returns up to limit pending Deliveries that are due.
+func (s *Store) DueWebhookDeliveries(_ context.Context, limit int) ([]*store.WebhookDelivery, error) {
+	if s.Error != nil {
+		return nil, s.Error
+	}
+	due := []*store.WebhookDelivery{}
+	for _, d := range s.Deliveries {
+		if len(due) == limit {
+			break
+		}
+		if d.Status == store.DeliveryPending && (d.NextAttemptAt == nil || !d.NextAttemptAt.After(time.Now())) {
+			due = append(due, d)
+		}
+	}
+	return due, nil
+}
+
+// UpdateWebhookDelivery returns Error, the deliveries returned are the ones in Deliveries.
+func (s *Store) UpdateWebhookDelivery(_ context.Context, _ *store.WebhookDelivery) error {
+	return s.Error
+}
+
+// ListWebhookDeliveries returns a page with the Deliveries of the webhook.
+func (s *Store) ListWebhookDeliveries(ctx context.Context, webhookID string, _ store.WebhookDeliveryOptions) (*store.WebhookDeliveryPage, error) {
+	if _, err := s.RetrieveWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+	deliveries := []*store.WebhookDelivery{}
+	for _, d := range s.Deliveries {
+		if d.WebhookID == webhookID {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return &store.WebhookDeliveryPage{Deliveries: deliveries}, nil
+}
+
+// ReplayWebhookDelivery makes the delivery of Deliveries with id pending again.
+func (s *Store) ReplayWebhookDelivery(_ context.Context, id string) (*store.WebhookDelivery, error) {
+	if s.Error != nil {
+		return nil, s.Error
+	}
+	for _, d := range s.Deliveries {
+		if d.ID == id {
+			now := time.Now()
+			d.Status = store.DeliveryPending
+			d.Attempts = 0
+			d.NextAttemptAt = &now
+			return d, nil
+		}
+	}
+	return nil, store.ErrDeliveryNotFound
+}
+
 type Authenticator struct {
 	ReturnVal bool
 	Error     error
@@ -66,10 +614,39 @@ func (ma *Authenticator) Authenticate(_, _ string) (bool, error) {
 	return ma.ReturnVal, ma.Error
 }
 
-func (ma *Authenticator) FetchLoginToken(_ string) (*store.TokenRecord, error) {
+func (ma *Authenticator) FetchLoginToken(_, _ string) (*store.TokenRecord, error) {
 	return ma.Token, nil
 }
 
 func (ma *Authenticator) SaveLoginToken(_ context.Context, _ *store.TokenRecord) error {
 	return nil
 }
+
+// Mailer records the mails it is asked to send.
+type Mailer struct {
+	Error error
+	Sent  []foundation.Mail
+}
+
+func (m *Mailer) Send(_ context.Context, mail foundation.Mail) error {
+	if m.Error != nil {
+		return m.Error
+	}
+	m.Sent = append(m.Sent, mail)
+	return nil
+}
+
+// Publisher records the events it is asked to publish, Fail are the IDs of
+// the events it fails to publish.
+type Publisher struct {
+	Fail      []string
+	Published []foundation.Message
+}
+
+func (p *Publisher) Publish(_ context.Context, m foundation.Message) error {
+	if slices.Contains(p.Fail, m.ID) {
+		return errors.New("publish failed")
+	}
+	p.Published = append(p.Published, m)
+	return nil
+}
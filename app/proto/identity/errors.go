@@ -0,0 +1,77 @@
+package identity
+
+import (
+	"context"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+
+	"github.com/riyadennis/identity-server/business"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+// errorDomain identifies this service in the details of errors it returns.
+const errorDomain = "identity-server"
+
+// internalMessage replaces the message of errors that are failures of the service.
+const internalMessage = "internal error"
+
+// categoryCodes are the status codes of the error categories.
+var categoryCodes = map[foundation.Category]codes.Code{
+	foundation.CategoryInternal:        codes.Internal,
+	foundation.CategoryInvalid:         codes.InvalidArgument,
+	foundation.CategoryUnauthenticated: codes.Unauthenticated,
+	foundation.CategoryForbidden:       codes.PermissionDenied,
+	foundation.CategoryNotFound:        codes.NotFound,
+	foundation.CategoryConflict:        codes.AlreadyExists,
+}
+
+// toStatus converts err into the status of its category, the code of the
+// error is sent as the reason of its error details. Errors that already are
+// a status are returned unchanged.
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	ce := business.Classify(err)
+	return withDetails(status.New(categoryCodes[ce.Category], err.Error()), &errdetails.ErrorInfo{
+		Reason: ce.Code,
+		Domain: errorDomain,
+	})
+}
+
+// withDetails returns the error of st with details, or without them when
+// they can not be added.
+func withDetails(st *status.Status, details ...protoadapt.MessageV1) error {
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// errorInterceptor converts the errors of every call into a status. Failures
+// of the service are logged and sent with only the id of the request, so the
+// log can be found without their details reaching the caller.
+func (s *Server) errorInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err == nil {
+		return resp, nil
+	}
+
+	err = toStatus(err)
+	if code := status.Code(err); code != codes.Internal && code != codes.Unknown {
+		return resp, err
+	}
+
+	id := business.CorrelationID(ctx)
+	s.Logger.WithField("correlation_id", id).Errorf("%s failed: %v", info.FullMethod, err)
+	return resp, withDetails(status.New(codes.Internal, internalMessage),
+		&errdetails.ErrorInfo{Reason: foundation.InternalError, Domain: errorDomain},
+		&errdetails.RequestInfo{RequestId: id},
+	)
+}
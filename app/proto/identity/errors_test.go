@@ -0,0 +1,151 @@
+package identity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business"
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+func TestErrorInterceptor(t *testing.T) {
+	scenarios := []struct {
+		name              string
+		err               error
+		expectedCode      codes.Code
+		expectedMessage   string
+		expectedReason    string
+		expectedRequestID string
+	}{
+		{
+			name:            "not found",
+			err:             fmt.Errorf("failed to delete user: %w", store.ErrUserNotFound),
+			expectedCode:    codes.NotFound,
+			expectedMessage: "failed to delete user: user not found",
+			expectedReason:  foundation.UserDoNotExist,
+		},
+		{
+			name:            "forbidden",
+			err:             authz.ErrForbidden,
+			expectedCode:    codes.PermissionDenied,
+			expectedMessage: authz.ErrForbidden.Error(),
+			expectedReason:  foundation.Forbidden,
+		},
+		{
+			name:            "invalid password",
+			err:             business.ErrInvalidPassword,
+			expectedCode:    codes.PermissionDenied,
+			expectedMessage: business.ErrInvalidPassword.Error(),
+			expectedReason:  foundation.InvalidPassword,
+		},
+		{
+			name:            "status",
+			err:             status.Error(codes.Unauthenticated, "missing authorization header"),
+			expectedCode:    codes.Unauthenticated,
+			expectedMessage: "missing authorization header",
+		},
+		{
+			name:              "internal",
+			err:               errors.New("connection refused"),
+			expectedCode:      codes.Internal,
+			expectedMessage:   internalMessage,
+			expectedReason:    foundation.InternalError,
+			expectedRequestID: "req-1",
+		},
+		{
+			name:              "internal status",
+			err:               status.Error(codes.Internal, "connection refused"),
+			expectedCode:      codes.Internal,
+			expectedMessage:   internalMessage,
+			expectedReason:    foundation.InternalError,
+			expectedRequestID: "req-1",
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			server := &Server{Logger: logrus.New()}
+			ctx := store.WithActor(context.Background(), store.Actor{RequestID: "req-1"})
+
+			_, err := server.errorInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/identity.Identity/DeleteUser"},
+				func(context.Context, any) (any, error) {
+					return nil, sc.err
+				})
+
+			st := status.Convert(err)
+			assert.Equal(t, sc.expectedCode, st.Code())
+			assert.Equal(t, sc.expectedMessage, st.Message())
+			var reason, requestID string
+			for _, d := range st.Details() {
+				switch d := d.(type) {
+				case *errdetails.ErrorInfo:
+					reason = d.GetReason()
+					assert.Equal(t, errorDomain, d.GetDomain())
+				case *errdetails.RequestInfo:
+					requestID = d.GetRequestId()
+				}
+			}
+			assert.Equal(t, sc.expectedReason, reason)
+			assert.Equal(t, sc.expectedRequestID, requestID)
+		})
+	}
+
+	t.Run("success", func(t *testing.T) {
+		server := &Server{Logger: logrus.New()}
+		resp, err := server.errorInterceptor(context.Background(), nil, &grpc.UnaryServerInfo{},
+			func(context.Context, any) (any, error) {
+				return "ok", nil
+			})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp)
+	})
+}
+
+func TestAuthenticate_Errors(t *testing.T) {
+	scenarios := []struct {
+		name            string
+		store           *mocks.Store
+		expectedCode    codes.Code
+		expectedMessage string
+	}{
+		{
+			name:            "not a member",
+			store:           &mocks.Store{User: &store.User{ID: testUserID}},
+			expectedCode:    codes.PermissionDenied,
+			expectedMessage: authz.ErrForbidden.Error(),
+		},
+		{
+			name:            "store error",
+			store:           &mocks.Store{Error: errors.New("db down")},
+			expectedCode:    codes.Internal,
+			expectedMessage: internalMessage,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			server, ctx := profileServer(t, sc.store)
+
+			_, err := server.errorInterceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/identity.Identity/DeleteUser"},
+				func(ctx context.Context, _ any) (any, error) {
+					return server.DeleteUser(ctx, &UserIDRequest{UserId: proto.String("user-2")})
+				})
+
+			st := status.Convert(err)
+			assert.Equal(t, sc.expectedCode, st.Code())
+			assert.Equal(t, sc.expectedMessage, st.Message())
+		})
+	}
+}
@@ -1,6 +1,6 @@
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.36.10
+// 	protoc-gen-go v1.36.11
 // 	protoc        v4.25.3
 // source: app/proto/identity/identity.proto
 
@@ -264,6 +264,586 @@ func (x *UserResponse) GetEmailVerified() bool {
 	return false
 }
 
+// The profile of the authenticated user.
+type Profile struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ID            *string                `protobuf:"bytes,1,req,name=ID" json:"ID,omitempty"`
+	Email         *string                `protobuf:"bytes,2,req,name=email" json:"email,omitempty"`
+	FirstName     *string                `protobuf:"bytes,3,opt,name=first_name,json=firstName" json:"first_name,omitempty"`
+	LastName      *string                `protobuf:"bytes,4,opt,name=last_name,json=lastName" json:"last_name,omitempty"`
+	Company       *string                `protobuf:"bytes,5,opt,name=company" json:"company,omitempty"`
+	PostCode      *string                `protobuf:"bytes,6,opt,name=post_code,json=postCode" json:"post_code,omitempty"`
+	Locale        *string                `protobuf:"bytes,7,opt,name=locale" json:"locale,omitempty"`
+	Timezone      *string                `protobuf:"bytes,8,opt,name=timezone" json:"timezone,omitempty"`
+	PictureUrl    *string                `protobuf:"bytes,9,opt,name=picture_url,json=pictureUrl" json:"picture_url,omitempty"`
+	Roles         []string               `protobuf:"bytes,10,rep,name=roles" json:"roles,omitempty"`
+	Active        *bool                  `protobuf:"varint,11,opt,name=active" json:"active,omitempty"`
+	CreatedAt     *string                `protobuf:"bytes,12,opt,name=created_at,json=createdAt" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Profile) Reset() {
+	*x = Profile{}
+	mi := &file_app_proto_identity_identity_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Profile) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Profile) ProtoMessage() {}
+
+func (x *Profile) ProtoReflect() protoreflect.Message {
+	mi := &file_app_proto_identity_identity_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Profile.ProtoReflect.Descriptor instead.
+func (*Profile) Descriptor() ([]byte, []int) {
+	return file_app_proto_identity_identity_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Profile) GetID() string {
+	if x != nil && x.ID != nil {
+		return *x.ID
+	}
+	return ""
+}
+
+func (x *Profile) GetEmail() string {
+	if x != nil && x.Email != nil {
+		return *x.Email
+	}
+	return ""
+}
+
+func (x *Profile) GetFirstName() string {
+	if x != nil && x.FirstName != nil {
+		return *x.FirstName
+	}
+	return ""
+}
+
+func (x *Profile) GetLastName() string {
+	if x != nil && x.LastName != nil {
+		return *x.LastName
+	}
+	return ""
+}
+
+func (x *Profile) GetCompany() string {
+	if x != nil && x.Company != nil {
+		return *x.Company
+	}
+	return ""
+}
+
+func (x *Profile) GetPostCode() string {
+	if x != nil && x.PostCode != nil {
+		return *x.PostCode
+	}
+	return ""
+}
+
+func (x *Profile) GetLocale() string {
+	if x != nil && x.Locale != nil {
+		return *x.Locale
+	}
+	return ""
+}
+
+func (x *Profile) GetTimezone() string {
+	if x != nil && x.Timezone != nil {
+		return *x.Timezone
+	}
+	return ""
+}
+
+func (x *Profile) GetPictureUrl() string {
+	if x != nil && x.PictureUrl != nil {
+		return *x.PictureUrl
+	}
+	return ""
+}
+
+func (x *Profile) GetRoles() []string {
+	if x != nil {
+		return x.Roles
+	}
+	return nil
+}
+
+func (x *Profile) GetActive() bool {
+	if x != nil && x.Active != nil {
+		return *x.Active
+	}
+	return false
+}
+
+func (x *Profile) GetCreatedAt() string {
+	if x != nil && x.CreatedAt != nil {
+		return *x.CreatedAt
+	}
+	return ""
+}
+
+// Profile details to change, fields that are not set are left unchanged and
+// an empty value clears locale, timezone and picture_url.
+type UpdateProfileRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FirstName     *string                `protobuf:"bytes,1,opt,name=first_name,json=firstName" json:"first_name,omitempty"`
+	LastName      *string                `protobuf:"bytes,2,opt,name=last_name,json=lastName" json:"last_name,omitempty"`
+	Company       *string                `protobuf:"bytes,3,opt,name=company" json:"company,omitempty"`
+	PostCode      *string                `protobuf:"bytes,4,opt,name=post_code,json=postCode" json:"post_code,omitempty"`
+	Locale        *string                `protobuf:"bytes,5,opt,name=locale" json:"locale,omitempty"`
+	Timezone      *string                `protobuf:"bytes,6,opt,name=timezone" json:"timezone,omitempty"`
+	PictureUrl    *string                `protobuf:"bytes,7,opt,name=picture_url,json=pictureUrl" json:"picture_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateProfileRequest) Reset() {
+	*x = UpdateProfileRequest{}
+	mi := &file_app_proto_identity_identity_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateProfileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateProfileRequest) ProtoMessage() {}
+
+func (x *UpdateProfileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_app_proto_identity_identity_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateProfileRequest.ProtoReflect.Descriptor instead.
+func (*UpdateProfileRequest) Descriptor() ([]byte, []int) {
+	return file_app_proto_identity_identity_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UpdateProfileRequest) GetFirstName() string {
+	if x != nil && x.FirstName != nil {
+		return *x.FirstName
+	}
+	return ""
+}
+
+func (x *UpdateProfileRequest) GetLastName() string {
+	if x != nil && x.LastName != nil {
+		return *x.LastName
+	}
+	return ""
+}
+
+func (x *UpdateProfileRequest) GetCompany() string {
+	if x != nil && x.Company != nil {
+		return *x.Company
+	}
+	return ""
+}
+
+func (x *UpdateProfileRequest) GetPostCode() string {
+	if x != nil && x.PostCode != nil {
+		return *x.PostCode
+	}
+	return ""
+}
+
+func (x *UpdateProfileRequest) GetLocale() string {
+	if x != nil && x.Locale != nil {
+		return *x.Locale
+	}
+	return ""
+}
+
+func (x *UpdateProfileRequest) GetTimezone() string {
+	if x != nil && x.Timezone != nil {
+		return *x.Timezone
+	}
+	return ""
+}
+
+func (x *UpdateProfileRequest) GetPictureUrl() string {
+	if x != nil && x.PictureUrl != nil {
+		return *x.PictureUrl
+	}
+	return ""
+}
+
+// The details of a user registering, the email is stored in lower case.
+type RegisterRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FirstName     *string                `protobuf:"bytes,1,req,name=first_name,json=firstName" json:"first_name,omitempty"`
+	LastName      *string                `protobuf:"bytes,2,req,name=last_name,json=lastName" json:"last_name,omitempty"`
+	Email         *string                `protobuf:"bytes,3,req,name=email" json:"email,omitempty"`
+	Password      *string                `protobuf:"bytes,4,req,name=password" json:"password,omitempty"`
+	Terms         *bool                  `protobuf:"varint,5,req,name=terms" json:"terms,omitempty"`
+	Company       *string                `protobuf:"bytes,6,opt,name=company" json:"company,omitempty"`
+	PostCode      *string                `protobuf:"bytes,7,opt,name=post_code,json=postCode" json:"post_code,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RegisterRequest) Reset() {
+	*x = RegisterRequest{}
+	mi := &file_app_proto_identity_identity_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RegisterRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RegisterRequest) ProtoMessage() {}
+
+func (x *RegisterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_app_proto_identity_identity_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RegisterRequest.ProtoReflect.Descriptor instead.
+func (*RegisterRequest) Descriptor() ([]byte, []int) {
+	return file_app_proto_identity_identity_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RegisterRequest) GetFirstName() string {
+	if x != nil && x.FirstName != nil {
+		return *x.FirstName
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetLastName() string {
+	if x != nil && x.LastName != nil {
+		return *x.LastName
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetEmail() string {
+	if x != nil && x.Email != nil {
+		return *x.Email
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetPassword() string {
+	if x != nil && x.Password != nil {
+		return *x.Password
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetTerms() bool {
+	if x != nil && x.Terms != nil {
+		return *x.Terms
+	}
+	return false
+}
+
+func (x *RegisterRequest) GetCompany() string {
+	if x != nil && x.Company != nil {
+		return *x.Company
+	}
+	return ""
+}
+
+func (x *RegisterRequest) GetPostCode() string {
+	if x != nil && x.PostCode != nil {
+		return *x.PostCode
+	}
+	return ""
+}
+
+// Filters, order and page of a user listing, filters that are not set match
+// every user. Times are in RFC 3339 and sort is created_at, email or last_name.
+type ListUsersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PageSize      *int32                 `protobuf:"varint,1,opt,name=page_size,json=pageSize" json:"page_size,omitempty"`
+	PageToken     *string                `protobuf:"bytes,2,opt,name=page_token,json=pageToken" json:"page_token,omitempty"`
+	Role          *string                `protobuf:"bytes,3,opt,name=role" json:"role,omitempty"`
+	Active        *bool                  `protobuf:"varint,4,opt,name=active" json:"active,omitempty"`
+	Company       *string                `protobuf:"bytes,5,opt,name=company" json:"company,omitempty"`
+	CreatedAfter  *string                `protobuf:"bytes,6,opt,name=created_after,json=createdAfter" json:"created_after,omitempty"`
+	CreatedBefore *string                `protobuf:"bytes,7,opt,name=created_before,json=createdBefore" json:"created_before,omitempty"`
+	EmailPrefix   *string                `protobuf:"bytes,8,opt,name=email_prefix,json=emailPrefix" json:"email_prefix,omitempty"`
+	Sort          *string                `protobuf:"bytes,9,opt,name=sort" json:"sort,omitempty"`
+	Descending    *bool                  `protobuf:"varint,10,opt,name=descending" json:"descending,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUsersRequest) Reset() {
+	*x = ListUsersRequest{}
+	mi := &file_app_proto_identity_identity_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUsersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUsersRequest) ProtoMessage() {}
+
+func (x *ListUsersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_app_proto_identity_identity_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUsersRequest.ProtoReflect.Descriptor instead.
+func (*ListUsersRequest) Descriptor() ([]byte, []int) {
+	return file_app_proto_identity_identity_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ListUsersRequest) GetPageSize() int32 {
+	if x != nil && x.PageSize != nil {
+		return *x.PageSize
+	}
+	return 0
+}
+
+func (x *ListUsersRequest) GetPageToken() string {
+	if x != nil && x.PageToken != nil {
+		return *x.PageToken
+	}
+	return ""
+}
+
+func (x *ListUsersRequest) GetRole() string {
+	if x != nil && x.Role != nil {
+		return *x.Role
+	}
+	return ""
+}
+
+func (x *ListUsersRequest) GetActive() bool {
+	if x != nil && x.Active != nil {
+		return *x.Active
+	}
+	return false
+}
+
+func (x *ListUsersRequest) GetCompany() string {
+	if x != nil && x.Company != nil {
+		return *x.Company
+	}
+	return ""
+}
+
+func (x *ListUsersRequest) GetCreatedAfter() string {
+	if x != nil && x.CreatedAfter != nil {
+		return *x.CreatedAfter
+	}
+	return ""
+}
+
+func (x *ListUsersRequest) GetCreatedBefore() string {
+	if x != nil && x.CreatedBefore != nil {
+		return *x.CreatedBefore
+	}
+	return ""
+}
+
+func (x *ListUsersRequest) GetEmailPrefix() string {
+	if x != nil && x.EmailPrefix != nil {
+		return *x.EmailPrefix
+	}
+	return ""
+}
+
+func (x *ListUsersRequest) GetSort() string {
+	if x != nil && x.Sort != nil {
+		return *x.Sort
+	}
+	return ""
+}
+
+func (x *ListUsersRequest) GetDescending() bool {
+	if x != nil && x.Descending != nil {
+		return *x.Descending
+	}
+	return false
+}
+
+// A page of users, next_page_token is not set on the last page.
+type ListUsersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Users         []*Profile             `protobuf:"bytes,1,rep,name=users" json:"users,omitempty"`
+	NextPageToken *string                `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListUsersResponse) Reset() {
+	*x = ListUsersResponse{}
+	mi := &file_app_proto_identity_identity_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListUsersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListUsersResponse) ProtoMessage() {}
+
+func (x *ListUsersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_app_proto_identity_identity_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListUsersResponse.ProtoReflect.Descriptor instead.
+func (*ListUsersResponse) Descriptor() ([]byte, []int) {
+	return file_app_proto_identity_identity_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListUsersResponse) GetUsers() []*Profile {
+	if x != nil {
+		return x.Users
+	}
+	return nil
+}
+
+func (x *ListUsersResponse) GetNextPageToken() string {
+	if x != nil && x.NextPageToken != nil {
+		return *x.NextPageToken
+	}
+	return ""
+}
+
+// The user of the caller's organization to delete or restore.
+type UserIDRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        *string                `protobuf:"bytes,1,req,name=user_id,json=userId" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserIDRequest) Reset() {
+	*x = UserIDRequest{}
+	mi := &file_app_proto_identity_identity_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserIDRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserIDRequest) ProtoMessage() {}
+
+func (x *UserIDRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_app_proto_identity_identity_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserIDRequest.ProtoReflect.Descriptor instead.
+func (*UserIDRequest) Descriptor() ([]byte, []int) {
+	return file_app_proto_identity_identity_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *UserIDRequest) GetUserId() string {
+	if x != nil && x.UserId != nil {
+		return *x.UserId
+	}
+	return ""
+}
+
+type DeleteUserResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Deleted       *bool                  `protobuf:"varint,1,req,name=deleted" json:"deleted,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteUserResponse) Reset() {
+	*x = DeleteUserResponse{}
+	mi := &file_app_proto_identity_identity_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteUserResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteUserResponse) ProtoMessage() {}
+
+func (x *DeleteUserResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_app_proto_identity_identity_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteUserResponse.ProtoReflect.Descriptor instead.
+func (*DeleteUserResponse) Descriptor() ([]byte, []int) {
+	return file_app_proto_identity_identity_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DeleteUserResponse) GetDeleted() bool {
+	if x != nil && x.Deleted != nil {
+		return *x.Deleted
+	}
+	return false
+}
+
 var File_app_proto_identity_identity_proto protoreflect.FileDescriptor
 
 const file_app_proto_identity_identity_proto_rawDesc = "" +
@@ -285,10 +865,76 @@ const file_app_proto_identity_identity_proto_rawDesc = "" +
 	"\x02ID\x18\x01 \x02(\tR\x02ID\x12\x14\n" +
 	"\x05email\x18\x02 \x02(\tR\x05email\x12\x12\n" +
 	"\x04name\x18\x03 \x02(\tR\x04name\x12$\n" +
-	"\remailVerified\x18\x04 \x01(\bR\remailVerified2U\n" +
+	"\remailVerified\x18\x04 \x01(\bR\remailVerified\"\xc4\x02\n" +
+	"\aProfile\x12\x0e\n" +
+	"\x02ID\x18\x01 \x02(\tR\x02ID\x12\x14\n" +
+	"\x05email\x18\x02 \x02(\tR\x05email\x12\x1d\n" +
+	"\n" +
+	"first_name\x18\x03 \x01(\tR\tfirstName\x12\x1b\n" +
+	"\tlast_name\x18\x04 \x01(\tR\blastName\x12\x18\n" +
+	"\acompany\x18\x05 \x01(\tR\acompany\x12\x1b\n" +
+	"\tpost_code\x18\x06 \x01(\tR\bpostCode\x12\x16\n" +
+	"\x06locale\x18\a \x01(\tR\x06locale\x12\x1a\n" +
+	"\btimezone\x18\b \x01(\tR\btimezone\x12\x1f\n" +
+	"\vpicture_url\x18\t \x01(\tR\n" +
+	"pictureUrl\x12\x14\n" +
+	"\x05roles\x18\n" +
+	" \x03(\tR\x05roles\x12\x16\n" +
+	"\x06active\x18\v \x01(\bR\x06active\x12\x1d\n" +
+	"\n" +
+	"created_at\x18\f \x01(\tR\tcreatedAt\"\xde\x01\n" +
+	"\x14UpdateProfileRequest\x12\x1d\n" +
+	"\n" +
+	"first_name\x18\x01 \x01(\tR\tfirstName\x12\x1b\n" +
+	"\tlast_name\x18\x02 \x01(\tR\blastName\x12\x18\n" +
+	"\acompany\x18\x03 \x01(\tR\acompany\x12\x1b\n" +
+	"\tpost_code\x18\x04 \x01(\tR\bpostCode\x12\x16\n" +
+	"\x06locale\x18\x05 \x01(\tR\x06locale\x12\x1a\n" +
+	"\btimezone\x18\x06 \x01(\tR\btimezone\x12\x1f\n" +
+	"\vpicture_url\x18\a \x01(\tR\n" +
+	"pictureUrl\"\xcc\x01\n" +
+	"\x0fRegisterRequest\x12\x1d\n" +
+	"\n" +
+	"first_name\x18\x01 \x02(\tR\tfirstName\x12\x1b\n" +
+	"\tlast_name\x18\x02 \x02(\tR\blastName\x12\x14\n" +
+	"\x05email\x18\x03 \x02(\tR\x05email\x12\x1a\n" +
+	"\bpassword\x18\x04 \x02(\tR\bpassword\x12\x14\n" +
+	"\x05terms\x18\x05 \x02(\bR\x05terms\x12\x18\n" +
+	"\acompany\x18\x06 \x01(\tR\acompany\x12\x1b\n" +
+	"\tpost_code\x18\a \x01(\tR\bpostCode\"\xb7\x02\n" +
+	"\x10ListUsersRequest\x12\x1b\n" +
+	"\tpage_size\x18\x01 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x02 \x01(\tR\tpageToken\x12\x12\n" +
+	"\x04role\x18\x03 \x01(\tR\x04role\x12\x16\n" +
+	"\x06active\x18\x04 \x01(\bR\x06active\x12\x18\n" +
+	"\acompany\x18\x05 \x01(\tR\acompany\x12#\n" +
+	"\rcreated_after\x18\x06 \x01(\tR\fcreatedAfter\x12%\n" +
+	"\x0ecreated_before\x18\a \x01(\tR\rcreatedBefore\x12!\n" +
+	"\femail_prefix\x18\b \x01(\tR\vemailPrefix\x12\x12\n" +
+	"\x04sort\x18\t \x01(\tR\x04sort\x12\x1e\n" +
+	"\n" +
+	"descending\x18\n" +
+	" \x01(\bR\n" +
+	"descending\"[\n" +
+	"\x11ListUsersResponse\x12\x1e\n" +
+	"\x05users\x18\x01 \x03(\v2\b.ProfileR\x05users\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"(\n" +
+	"\rUserIDRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x02(\tR\x06userId\".\n" +
+	"\x12DeleteUserResponse\x12\x18\n" +
+	"\adeleted\x18\x01 \x02(\bR\adeleted2\xe5\x02\n" +
 	"\bIdentity\x12&\n" +
+	"\bRegister\x12\x10.RegisterRequest\x1a\b.Profile\x12&\n" +
 	"\x05Login\x12\r.LoginRequest\x1a\x0e.LoginResponse\x12!\n" +
-	"\x02Me\x12\f.UserRequest\x1a\r.UserResponseB\rZ\v../identity"
+	"\x02Me\x12\f.UserRequest\x1a\r.UserResponse\x12$\n" +
+	"\n" +
+	"GetProfile\x12\f.UserRequest\x1a\b.Profile\x120\n" +
+	"\rUpdateProfile\x12\x15.UpdateProfileRequest\x1a\b.Profile\x122\n" +
+	"\tListUsers\x12\x11.ListUsersRequest\x1a\x12.ListUsersResponse\x121\n" +
+	"\n" +
+	"DeleteUser\x12\x0e.UserIDRequest\x1a\x13.DeleteUserResponse\x12'\n" +
+	"\vRestoreUser\x12\x0e.UserIDRequest\x1a\b.ProfileB\rZ\v../identity"
 
 var (
 	file_app_proto_identity_identity_proto_rawDescOnce sync.Once
@@ -302,23 +948,43 @@ func file_app_proto_identity_identity_proto_rawDescGZIP() []byte {
 	return file_app_proto_identity_identity_proto_rawDescData
 }
 
-var file_app_proto_identity_identity_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_app_proto_identity_identity_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
 var file_app_proto_identity_identity_proto_goTypes = []any{
-	(*LoginRequest)(nil),  // 0: LoginRequest
-	(*LoginResponse)(nil), // 1: LoginResponse
-	(*UserRequest)(nil),   // 2: UserRequest
-	(*UserResponse)(nil),  // 3: UserResponse
+	(*LoginRequest)(nil),         // 0: LoginRequest
+	(*LoginResponse)(nil),        // 1: LoginResponse
+	(*UserRequest)(nil),          // 2: UserRequest
+	(*UserResponse)(nil),         // 3: UserResponse
+	(*Profile)(nil),              // 4: Profile
+	(*UpdateProfileRequest)(nil), // 5: UpdateProfileRequest
+	(*RegisterRequest)(nil),      // 6: RegisterRequest
+	(*ListUsersRequest)(nil),     // 7: ListUsersRequest
+	(*ListUsersResponse)(nil),    // 8: ListUsersResponse
+	(*UserIDRequest)(nil),        // 9: UserIDRequest
+	(*DeleteUserResponse)(nil),   // 10: DeleteUserResponse
 }
 var file_app_proto_identity_identity_proto_depIdxs = []int32{
-	0, // 0: Identity.Login:input_type -> LoginRequest
-	2, // 1: Identity.Me:input_type -> UserRequest
-	1, // 2: Identity.Login:output_type -> LoginResponse
-	3, // 3: Identity.Me:output_type -> UserResponse
-	2, // [2:4] is the sub-list for method output_type
-	0, // [0:2] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	4,  // 0: ListUsersResponse.users:type_name -> Profile
+	6,  // 1: Identity.Register:input_type -> RegisterRequest
+	0,  // 2: Identity.Login:input_type -> LoginRequest
+	2,  // 3: Identity.Me:input_type -> UserRequest
+	2,  // 4: Identity.GetProfile:input_type -> UserRequest
+	5,  // 5: Identity.UpdateProfile:input_type -> UpdateProfileRequest
+	7,  // 6: Identity.ListUsers:input_type -> ListUsersRequest
+	9,  // 7: Identity.DeleteUser:input_type -> UserIDRequest
+	9,  // 8: Identity.RestoreUser:input_type -> UserIDRequest
+	4,  // 9: Identity.Register:output_type -> Profile
+	1,  // 10: Identity.Login:output_type -> LoginResponse
+	3,  // 11: Identity.Me:output_type -> UserResponse
+	4,  // 12: Identity.GetProfile:output_type -> Profile
+	4,  // 13: Identity.UpdateProfile:output_type -> Profile
+	8,  // 14: Identity.ListUsers:output_type -> ListUsersResponse
+	10, // 15: Identity.DeleteUser:output_type -> DeleteUserResponse
+	4,  // 16: Identity.RestoreUser:output_type -> Profile
+	9,  // [9:17] is the sub-list for method output_type
+	1,  // [1:9] is the sub-list for method input_type
+	1,  // [1:1] is the sub-list for extension type_name
+	1,  // [1:1] is the sub-list for extension extendee
+	0,  // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_app_proto_identity_identity_proto_init() }
@@ -332,7 +998,7 @@ func file_app_proto_identity_identity_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_app_proto_identity_identity_proto_rawDesc), len(file_app_proto_identity_identity_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   4,
+			NumMessages:   11,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
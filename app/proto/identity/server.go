@@ -7,15 +7,20 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/riyadennis/identity-server/business"
+	"github.com/riyadennis/identity-server/business/authz"
 	"github.com/riyadennis/identity-server/business/store"
 	"github.com/riyadennis/identity-server/business/validation"
+	"github.com/riyadennis/identity-server/foundation"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/runtime/protoimpl"
 )
 
@@ -24,24 +29,31 @@ type Server struct {
 	Server              *grpc.Server
 	Store               store.Store
 	Authenticator       store.Authenticator
+	Authorizer          *authz.Authorizer
 	Logger              *logrus.Logger
 	TokenConfig         *store.TokenConfig
+	Deleter             *business.Deleter
+	Users               *business.Users
+	Helper              *business.Helper
 	ServerError         chan error
 	ShutDown            chan os.Signal
 }
 
 func NewServer(logger *logrus.Logger, tc *store.TokenConfig, st store.Store, auth store.Authenticator) *Server {
-	gs := grpc.NewServer()
 	s := &Server{
 		unImplementedServer: UnimplementedIdentityServer{},
-		Server:              gs,
 		Store:               st,
 		Authenticator:       auth,
+		Authorizer:          authz.NewAuthorizer(st, logger),
 		Logger:              logger,
 		TokenConfig:         tc,
+		Deleter:             business.NewDeleter(st, logger, os.Getenv("DELETED_USER_RETENTION")),
+		Users:               business.NewUsers(st, logger),
+		Helper:              business.NewHelper(st, auth, foundation.NewENVMailer(logger), logger),
 		ShutDown:            make(chan os.Signal, 1),
 	}
-	RegisterIdentityServer(gs, s)
+	s.Server = grpc.NewServer(grpc.ChainUnaryInterceptor(actorInterceptor, s.errorInterceptor))
+	RegisterIdentityServer(s.Server, s)
 	return s
 }
 
@@ -59,10 +71,40 @@ func (s *Server) Run(port string) error {
 	return nil
 }
 
+// Stop stops the server once the calls in flight have finished and waits for
+// the new login notices being sent, giving up on them when ctx is done.
+func (s *Server) Stop(ctx context.Context) {
+	s.Server.GracefulStop()
+	if err := s.Helper.Logins.Wait(ctx); err != nil {
+		s.Logger.Errorf("login notices not sent before shutdown: %v", err)
+	}
+}
+
+// Register creates a user with the details in the request, an email that is
+// already taken is rejected with codes.AlreadyExists.
+func (s *Server) Register(ctx context.Context, request *RegisterRequest) (*Profile, error) {
+	s.Logger.Info("processing gRPC request to register")
+	u := &store.User{
+		FirstName: request.GetFirstName(),
+		LastName:  request.GetLastName(),
+		Email:     request.GetEmail(),
+		Password:  request.GetPassword(),
+		Terms:     request.GetTerms(),
+		Company:   request.GetCompany(),
+		PostCode:  request.GetPostCode(),
+		Active:    true,
+	}
+	user, err := s.Users.Create(ctx, u, "")
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProfile(user), nil
+}
+
 func (s *Server) Login(ctx context.Context, request *LoginRequest) (*LoginResponse, error) {
 	s.Logger.Info("processing gRPC request to login")
-	helper := business.NewHelper(s.Store, s.Authenticator, s.Logger)
-	token, err := helper.Login(ctx, s.TokenConfig, *request.Email, *request.Password)
+	token, err := s.Helper.Login(ctx, s.TokenConfig, *request.Email, *request.Password)
 	if err != nil {
 		return nil, err
 	}
@@ -85,25 +127,17 @@ func (s *Server) Login(ctx context.Context, request *LoginRequest) (*LoginRespon
 }
 
 func (s *Server) Me(ctx context.Context, _ *UserRequest) (*UserResponse, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unauthenticated, "missing metadata")
-	}
-	authHeaders := md.Get("authorization")
-	if len(authHeaders) == 0 {
-		return nil, status.Error(codes.Unauthenticated, "missing authorization header")
-	}
-	claims, err := validation.ValidateToken(authHeaders[0], s.TokenConfig)
+	ctx, claims, err := s.authenticate(ctx, authz.UsersRead)
 	if err != nil {
-		return nil, status.Error(codes.InvalidArgument, "token failed validation")
+		return nil, err
 	}
 	user, err := s.Store.Retrieve(ctx, claims.Subject)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return nil, toStatus(err)
 	}
 
 	if user == nil {
-		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to find user for ID %s", claims.Subject))
+		return nil, toStatus(store.ErrUserNotFound)
 	}
 	fullName := user.FirstName + " " + user.LastName
 	return &UserResponse{
@@ -115,4 +149,242 @@ func (s *Server) Me(ctx context.Context, _ *UserRequest) (*UserResponse, error)
 	}, nil
 }
 
+// GetProfile returns the profile of the authenticated user.
+func (s *Server) GetProfile(ctx context.Context, _ *UserRequest) (*Profile, error) {
+	ctx, claims, err := s.authenticate(ctx, authz.UsersRead)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.Store.Retrieve(ctx, claims.Subject)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if user == nil {
+		return nil, toStatus(store.ErrUserNotFound)
+	}
+
+	return toProfile(user), nil
+}
+
+// UpdateProfile changes the profile details set in the request for the authenticated user.
+func (s *Server) UpdateProfile(ctx context.Context, request *UpdateProfileRequest) (*Profile, error) {
+	ctx, claims, err := s.authenticate(ctx, authz.UsersWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	update := &store.ProfileUpdate{
+		FirstName:  request.FirstName,
+		LastName:   request.LastName,
+		Company:    request.Company,
+		PostCode:   request.PostCode,
+		Locale:     request.Locale,
+		Timezone:   request.Timezone,
+		PictureURL: request.PictureUrl,
+	}
+	if err := validation.ValidateProfile(update); err != nil {
+		return nil, toStatus(err)
+	}
+
+	user, err := s.Store.UpdateProfile(ctx, claims.Subject, update)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProfile(user), nil
+}
+
+// ListUsers returns a page of the users of the caller's organization, it
+// needs the permission across the organization rather than on the caller's own account.
+func (s *Server) ListUsers(ctx context.Context, request *ListUsersRequest) (*ListUsersResponse, error) {
+	ctx, err := s.authenticateFor(ctx, authz.UsersRead, authz.Resource{})
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := listOptions(request)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	page, err := s.Store.ListUsers(ctx, opts)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	response := &ListUsersResponse{Users: make([]*Profile, 0, len(page.Users))}
+	for _, u := range page.Users {
+		response.Users = append(response.Users, toProfile(u))
+	}
+	if page.HasNextPage {
+		response.NextPageToken = &page.NextCursor
+	}
+	return response, nil
+}
+
+// DeleteUser deletes a user of the caller's organization, the user can be
+// restored with RestoreUser until the retention has passed.
+func (s *Server) DeleteUser(ctx context.Context, request *UserIDRequest) (*DeleteUserResponse, error) {
+	ctx, err := s.authenticateFor(ctx, authz.UsersDelete, authz.User(request.GetUserId()))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Deleter.Delete(ctx, request.GetUserId()); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &DeleteUserResponse{Deleted: proto.Bool(true)}, nil
+}
+
+// RestoreUser brings back a user of the caller's organization deleted within the retention.
+func (s *Server) RestoreUser(ctx context.Context, request *UserIDRequest) (*Profile, error) {
+	ctx, err := s.authenticateFor(ctx, authz.UsersDelete, authz.User(request.GetUserId()))
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.Deleter.Restore(ctx, request.GetUserId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProfile(user), nil
+}
+
+// listOptions converts a ListUsersRequest into store.ListOptions.
+func listOptions(request *ListUsersRequest) (store.ListOptions, error) {
+	opts := store.ListOptions{
+		Role:        request.GetRole(),
+		Active:      request.Active,
+		Company:     request.GetCompany(),
+		EmailPrefix: request.GetEmailPrefix(),
+		Descending:  request.GetDescending(),
+		First:       int(request.GetPageSize()),
+		After:       request.GetPageToken(),
+	}
+	if opts.First < 0 {
+		return opts, fmt.Errorf("invalid page_size: %d", opts.First)
+	}
+
+	sort, ok := store.ParseUserSort(request.GetSort())
+	if !ok {
+		return opts, store.ErrInvalidSort
+	}
+	opts.Sort = sort
+
+	var err error
+	if request.CreatedAfter != nil {
+		if opts.CreatedAfter, err = time.Parse(time.RFC3339, request.GetCreatedAfter()); err != nil {
+			return opts, fmt.Errorf("invalid created_after: %w", err)
+		}
+	}
+	if request.CreatedBefore != nil {
+		if opts.CreatedBefore, err = time.Parse(time.RFC3339, request.GetCreatedBefore()); err != nil {
+			return opts, fmt.Errorf("invalid created_before: %w", err)
+		}
+	}
+
+	return opts, nil
+}
+
+// authenticate validates the token in the request metadata and checks the
+// user has permission on their own account. The returned context is scoped
+// to the tenant of the token.
+func (s *Server) authenticate(ctx context.Context, permission string) (context.Context, *store.Claims, error) {
+	ctx, claims, err := s.tenant(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = s.Authorizer.Authorize(ctx, authz.Subject{UserID: claims.Subject}, permission, authz.User(claims.Subject))
+	if err != nil {
+		return nil, nil, toStatus(err)
+	}
+
+	return ctx, claims, nil
+}
+
+// authenticateFor validates the token in the request metadata and checks the
+// user has permission on resource. The returned context is scoped to the
+// tenant of the token.
+func (s *Server) authenticateFor(ctx context.Context, permission string, resource authz.Resource) (context.Context, error) {
+	ctx, claims, err := s.tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = s.Authorizer.Authorize(ctx, authz.Subject{UserID: claims.Subject}, permission, resource)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return ctx, nil
+}
+
+// tenant validates the token in the request metadata and returns a context
+// scoped to the tenant of the token.
+func (s *Server) tenant(ctx context.Context) (context.Context, *store.Claims, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, nil, status.Error(codes.Unauthenticated, "missing metadata")
+	}
+	authHeaders := md.Get("authorization")
+	if len(authHeaders) == 0 {
+		return nil, nil, status.Error(codes.Unauthenticated, "missing authorization header")
+	}
+	claims, err := validation.ValidateToken(authHeaders[0], s.TokenConfig)
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, "token failed validation")
+	}
+	subject := authz.Subject{UserID: claims.Subject}
+	tenant, err := s.Authorizer.Tenant(ctx, subject, claims.OrgID)
+	if err != nil {
+		return nil, nil, toStatus(err)
+	}
+
+	actor := store.ActorFromContext(ctx)
+	actor.UserID = claims.Subject
+	ctx = store.WithActor(ctx, actor)
+
+	return store.WithTenant(ctx, tenant), claims, nil
+}
+
+// actorInterceptor adds the address and user agent of the caller and the
+// x-request-id metadata to the context of every call so the audit events of
+// the changes it makes can be traced back to it.
+func actorInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	var actor store.Actor
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		actor.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(actor.IP); err == nil {
+			actor.IP = host
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ua := md.Get("user-agent"); len(ua) > 0 {
+			actor.UserAgent = ua[0]
+		}
+		if id := md.Get("x-request-id"); len(id) > 0 {
+			actor.RequestID = id[0]
+		}
+	}
+
+	return handler(store.WithActor(ctx, actor), req)
+}
+
+func toProfile(u *store.User) *Profile {
+	return &Profile{
+		ID:         &u.ID,
+		Email:      &u.Email,
+		FirstName:  &u.FirstName,
+		LastName:   &u.LastName,
+		Company:    &u.Company,
+		PostCode:   &u.PostCode,
+		Locale:     &u.Locale,
+		Timezone:   &u.Timezone,
+		PictureUrl: &u.PictureURL,
+		Roles:      u.Roles,
+		Active:     &u.Active,
+		CreatedAt:  &u.CreatedAt,
+	}
+}
+
 func (s *Server) mustEmbedUnimplementedIdentityServer() {}
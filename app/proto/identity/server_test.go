@@ -3,15 +3,29 @@ package identity
 import (
 	"context"
 	"errors"
+	"net"
 	"net/http"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business"
+	"github.com/riyadennis/identity-server/business/authz"
 	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 const (
@@ -67,7 +81,8 @@ func TestLogin(t *testing.T) {
 					Password: &password,
 				}
 			}(),
-			expectedError: errors.New("invalid email"),
+			mockStore:     &mocks.Store{},
+			expectedError: errors.New("email not found"),
 		},
 		{
 			name: "user not found",
@@ -142,6 +157,7 @@ func TestLogin(t *testing.T) {
 				Logger:        logrus.New(),
 				Store:         sc.mockStore,
 				Authenticator: sc.mockAuth,
+				Helper:        business.NewHelper(sc.mockStore, sc.mockAuth, &mocks.Mailer{}, logrus.New()),
 				TokenConfig: &store.TokenConfig{
 					Issuer:         "test-issuer",
 					KeyPath:        "/tmp/",
@@ -190,6 +206,7 @@ func TestLogin_InvalidTokenTTL(t *testing.T) {
 	server := &Server{
 		Store:         mockStore,
 		Authenticator: mockAuth,
+		Helper:        business.NewHelper(mockStore, mockAuth, &mocks.Mailer{}, logrus.New()),
 		Logger:        logrus.New(),
 		TokenConfig: &store.TokenConfig{
 			Issuer:         "test-issuer",
@@ -212,6 +229,299 @@ func TestLogin_InvalidTokenTTL(t *testing.T) {
 	assert.Nil(t, resp)
 }
 
+// profileServer returns a server for st and a context carrying a token for
+// testUserID in organization org-1.
+func profileServer(t *testing.T, st *mocks.Store) (*Server, context.Context) {
+	t.Helper()
+	tc := &store.TokenConfig{
+		Issuer:         "test-issuer",
+		KeyPath:        "../../../business/validation/testdata/",
+		PrivateKeyName: "test_private.pem",
+		PublicKeyName:  "test_public.pem",
+	}
+	key, err := os.ReadFile(tc.KeyPath + tc.PrivateKeyName)
+	require.NoError(t, err)
+	token, err := store.GenerateToken(logrus.New(), key, &store.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   testUserID,
+			Issuer:    tc.Issuer,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		OrgID: "org-1",
+	})
+	require.NoError(t, err)
+
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs("authorization", "Bearer "+token.AccessToken))
+	return NewServer(logrus.New(), tc, st, &mocks.Authenticator{}), ctx
+}
+
+func TestGetProfile(t *testing.T) {
+	st := &mocks.Store{
+		User:          &store.User{ID: testUserID, Email: testEmail, Locale: "en-GB"},
+		Organizations: []*store.Organization{{ID: "org-1"}},
+	}
+	server, ctx := profileServer(t, st)
+
+	_, err := server.GetProfile(context.Background(), &UserRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	profile, err := server.GetProfile(ctx, &UserRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, testEmail, profile.GetEmail())
+	assert.Equal(t, "en-GB", profile.GetLocale())
+}
+
+func TestUpdateProfile(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		request      *UpdateProfileRequest
+		user         *store.User
+		expectedCode codes.Code
+	}{
+		{
+			name:         "invalid timezone",
+			request:      &UpdateProfileRequest{Timezone: proto.String("Mars/Olympus")},
+			user:         &store.User{ID: testUserID},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "user not found",
+			request:      &UpdateProfileRequest{Locale: proto.String("en-GB")},
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:         "updated",
+			request:      &UpdateProfileRequest{Locale: proto.String("en-GB"), Timezone: proto.String("Europe/London")},
+			user:         &store.User{ID: testUserID, FirstName: "John"},
+			expectedCode: codes.OK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			st := &mocks.Store{User: sc.user, Organizations: []*store.Organization{{ID: "org-1"}}}
+			server, ctx := profileServer(t, st)
+
+			profile, err := server.UpdateProfile(ctx, sc.request)
+			assert.Equal(t, sc.expectedCode, status.Code(err))
+			if sc.expectedCode != codes.OK {
+				return
+			}
+			assert.Equal(t, "John", profile.GetFirstName())
+			assert.Equal(t, "en-GB", profile.GetLocale())
+			assert.Equal(t, "Europe/London", profile.GetTimezone())
+		})
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		permissions  []string
+		request      *ListUsersRequest
+		expectedCode codes.Code
+	}{
+		{
+			name:         "forbidden",
+			request:      &ListUsersRequest{},
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "invalid sort",
+			permissions:  []string{authz.UsersRead},
+			request:      &ListUsersRequest{Sort: proto.String("password")},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "invalid created after",
+			permissions:  []string{authz.UsersRead},
+			request:      &ListUsersRequest{CreatedAfter: proto.String("yesterday")},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:        "listed",
+			permissions: []string{authz.UsersRead},
+			request: &ListUsersRequest{
+				PageSize:   proto.Int32(10),
+				Role:       proto.String("support"),
+				Active:     proto.Bool(true),
+				Sort:       proto.String("email"),
+				Descending: proto.Bool(true),
+			},
+			expectedCode: codes.OK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			st := &mocks.Store{
+				User:          &store.User{ID: testUserID, Email: testEmail, Roles: []string{"support"}, Active: true},
+				Organizations: []*store.Organization{{ID: "org-1"}},
+				Permissions:   sc.permissions,
+			}
+			server, ctx := profileServer(t, st)
+
+			_, err := server.ListUsers(context.Background(), sc.request)
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+			response, err := server.ListUsers(ctx, sc.request)
+			assert.Equal(t, sc.expectedCode, status.Code(err))
+			if sc.expectedCode != codes.OK {
+				return
+			}
+			require.Len(t, response.GetUsers(), 1)
+			assert.Equal(t, testEmail, response.GetUsers()[0].GetEmail())
+			assert.Equal(t, []string{"support"}, response.GetUsers()[0].GetRoles())
+			assert.True(t, response.GetUsers()[0].GetActive())
+			assert.Empty(t, response.GetNextPageToken())
+			assert.Equal(t, &store.ListOptions{
+				Role:       "support",
+				Active:     proto.Bool(true),
+				Sort:       store.SortEmail,
+				Descending: true,
+				First:      10,
+			}, st.ListedWith)
+		})
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		permissions  []string
+		user         *store.User
+		expectedCode codes.Code
+	}{
+		{
+			name:         "forbidden",
+			user:         &store.User{ID: "user-2"},
+			expectedCode: codes.PermissionDenied,
+		},
+		{
+			name:         "not found",
+			permissions:  []string{authz.UsersDelete},
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:         "deleted",
+			permissions:  []string{authz.UsersDelete},
+			user:         &store.User{ID: "user-2"},
+			expectedCode: codes.OK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			st := &mocks.Store{
+				User:          sc.user,
+				Organizations: []*store.Organization{{ID: "org-1"}},
+				Permissions:   sc.permissions,
+			}
+			server, ctx := profileServer(t, st)
+
+			response, err := server.DeleteUser(ctx, &UserIDRequest{UserId: proto.String("user-2")})
+			assert.Equal(t, sc.expectedCode, status.Code(err))
+			if sc.expectedCode != codes.OK {
+				return
+			}
+			assert.True(t, response.GetDeleted())
+		})
+	}
+}
+
+func TestRestoreUser(t *testing.T) {
+	st := &mocks.Store{
+		User:          &store.User{ID: "user-2", Email: testEmail},
+		Organizations: []*store.Organization{{ID: "org-1"}},
+	}
+	server, ctx := profileServer(t, st)
+
+	_, err := server.RestoreUser(ctx, &UserIDRequest{UserId: proto.String("user-2")})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	st.Permissions = []string{authz.UsersDelete}
+	profile, err := server.RestoreUser(ctx, &UserIDRequest{UserId: proto.String("user-2")})
+	require.NoError(t, err)
+	assert.Equal(t, testEmail, profile.GetEmail())
+}
+
+func TestActorInterceptor(t *testing.T) {
+	server, ctx := profileServer(t, &mocks.Store{Organizations: []*store.Organization{{ID: "org-1"}}})
+	md, _ := metadata.FromIncomingContext(ctx)
+	md = metadata.Join(md, metadata.Pairs("user-agent", "grpc-go/1.0", "x-request-id", "req-1"))
+	ctx = peer.NewContext(metadata.NewIncomingContext(context.Background(), md),
+		&peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 5123}})
+
+	var actor store.Actor
+	_, err := actorInterceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, _ any) (any, error) {
+		ctx, _, err := server.tenant(ctx)
+		actor = store.ActorFromContext(ctx)
+		return nil, err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, store.Actor{UserID: testUserID, IP: "10.0.0.1", UserAgent: "grpc-go/1.0", RequestID: "req-1"}, actor)
+}
+
+func TestRegister(t *testing.T) {
+	request := func() *RegisterRequest {
+		return &RegisterRequest{
+			FirstName: proto.String("John"),
+			LastName:  proto.String("Doe"),
+			Email:     proto.String("John.Doe@Test.com"),
+			Password:  proto.String("secret123"),
+			Terms:     proto.Bool(true),
+		}
+	}
+	scenarios := []struct {
+		name         string
+		request      *RegisterRequest
+		store        *mocks.Store
+		expectedCode codes.Code
+	}{
+		{
+			name:         "invalid email",
+			request:      &RegisterRequest{FirstName: proto.String("John"), LastName: proto.String("Doe"), Email: proto.String("john")},
+			store:        &mocks.Store{},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "database error",
+			request:      request(),
+			store:        &mocks.Store{Error: errors.New("db error")},
+			expectedCode: codes.Internal,
+		},
+		{
+			name:         "duplicate email",
+			request:      request(),
+			store:        &mocks.Store{Error: store.ErrDuplicateEmail},
+			expectedCode: codes.AlreadyExists,
+		},
+		{
+			name:         "registered",
+			request:      request(),
+			store:        &mocks.Store{User: &store.User{ID: testUserID, Email: "john.doe@test.com"}},
+			expectedCode: codes.OK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			server := NewServer(logrus.New(), &store.TokenConfig{}, sc.store, &mocks.Authenticator{})
+
+			profile, err := server.Register(context.Background(), sc.request)
+			assert.Equal(t, sc.expectedCode, status.Code(err))
+			if sc.expectedCode == codes.AlreadyExists {
+				details := status.Convert(err).Details()
+				require.Len(t, details, 1)
+				info, ok := details[0].(*errdetails.ErrorInfo)
+				require.True(t, ok)
+				assert.Equal(t, foundation.EmailAlreadyExists, info.GetReason())
+			}
+			if sc.expectedCode != codes.OK {
+				return
+			}
+			assert.Equal(t, testUserID, profile.GetID())
+		})
+	}
+}
+
 func TestMustEmbedUnimplementedIdentityServer(t *testing.T) {
 	server := &Server{}
 
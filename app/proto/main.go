@@ -1,10 +1,11 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/signal"
-	"sync"
 	"syscall"
+	"time"
 
 	// initialise migration settings.
 	_ "github.com/go-sql-driver/mysql"
@@ -12,9 +13,14 @@ import (
 	"github.com/riyadennis/identity-server/app/proto/identity"
 
 	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/business/validation"
 	"github.com/riyadennis/identity-server/foundation"
 )
 
+// shutdownTimeout is how long the calls in flight and the notices being sent
+// have to finish when the server is stopped.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	logger := foundation.NewLogger()
 	err := foundation.ValidatePort(os.Getenv("GRPC_PORT"))
@@ -22,21 +28,25 @@ func main() {
 		logger.Fatalf("invalid port: %v", os.Getenv("GRPC_PORT"))
 	}
 	cfg := store.NewENVConfig()
+	err = validation.LoadDisposableDomains(os.Getenv("DISPOSABLE_EMAIL_DOMAINS"))
+	if err != nil {
+		logger.Fatalf("failed to load disposable email domains: %v", err)
+	}
 	st, auth, err := store.SetUpMYSQL(logger)
 	if err != nil {
 		logger.Fatalf("database setUp failed %v", err)
 	}
 	server := identity.NewServer(logger, cfg.Token, st, auth)
 	signal.Notify(server.ShutDown, os.Interrupt, syscall.SIGTERM)
-	var wt sync.WaitGroup
-	wt.Add(1)
 	go func() {
 		err = server.Run(os.Getenv("GRPC_PORT"))
 		if err != nil {
 			logger.Fatalf("failed to run gRPC server: %v", err)
-			wt.Done()
 		}
 	}()
 	<-server.ShutDown
-	wt.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	server.Stop(ctx)
 }
@@ -0,0 +1,65 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+var errMissingActive = errors.New("missing active in request")
+
+// ActivationRequest says whether a user is to be active.
+type ActivationRequest struct {
+	Active *bool `json:"active"`
+}
+
+// ActivationResponse says whether a user is active.
+type ActivationResponse struct {
+	UserID string `json:"user_id"`
+	Active bool   `json:"active"`
+}
+
+// Activation godoc
+//
+//	@Summary		Activate or deactivate a user
+//	@Description	Set whether a user of the caller's organization is active, users already in that state are left as they are. Requires the users:write permission
+//	@Tags			Admin
+//	@Security		ApiKeyAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID		path		string				true	"User ID"
+//	@Param			activation	body		ActivationRequest	true	"Whether the user is active"
+//	@Success		200			{object}	ActivationResponse
+//	@Failure		400			{object}	foundation.Response
+//	@Failure		401			{object}	foundation.Response
+//	@Failure		403			{object}	foundation.Response
+//	@Failure		404			{object}	foundation.Response
+//	@Failure		500			{object}	foundation.Response
+//	@Router			/v1/users/{userID}/activation [put]
+func (h *Handler) Activation(w http.ResponseWriter, r *http.Request) {
+	req := &ActivationRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		h.Logger.Errorf("invalid data in request: %v", err)
+
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+		return
+	}
+	if req.Active == nil {
+		foundation.ErrorResponse(w, r, http.StatusBadRequest,
+			&foundation.FieldError{Field: "active", Err: errMissingActive}, foundation.ValidationFailed)
+		return
+	}
+
+	userID := chi.URLParam(r, "userID")
+	h.Logger.Infof("setting active status of user %s to %t", userID, *req.Active)
+	if _, err := h.Users.SetActive(r.Context(), userID, *req.Active); err != nil {
+		h.classifiedError(w, r, err)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, &ActivationResponse{UserID: userID, Active: *req.Active})
+}
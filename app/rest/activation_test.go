@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+func TestHandlerActivation(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		body           string
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+		expectedBody   string
+	}{
+		{
+			name:           "invalid json",
+			body:           "{",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "missing active",
+			body:           `{}`,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.ValidationFailed,
+		},
+		{
+			name:           "not found",
+			body:           `{"active":true}`,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.UserDoNotExist,
+		},
+		{
+			name:           "already active",
+			body:           `{"active":true}`,
+			store:          &mocks.Store{User: &store.User{ID: "user-1", Active: true}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"user_id":"user-1","active":true}`,
+		},
+		{
+			name:           "deactivated",
+			body:           `{"active":false}`,
+			store:          &mocks.Store{User: &store.User{ID: "user-1", Active: true}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"user_id":"user-1","active":false}`,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			w := httptest.NewRecorder()
+			handler.Activation(w, webhookRequest(http.MethodPut, "/v1/users/user-1/activation", sc.body, "userID", "user-1", nil))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.JSONEq(t, sc.expectedBody, w.Body.String())
+		})
+	}
+}
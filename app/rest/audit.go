@@ -0,0 +1,81 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+// AuditEvents godoc
+//
+//	@Summary			List audit events
+//	@Description		Page through the audit log of the caller's organization, newest first, pass next_cursor as page_token to read the next page, requires the audit:read permission
+//	@Tags				Admin
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Param				page_size	query		int		false	"Events in a page, at most 100"	default(20)
+//	@Param				page_token	query		string	false	"next_cursor of the previous page"
+//	@Param				actor_id	query		string	false	"ID of the user that made the changes"
+//	@Param				target_id	query		string	false	"ID of the user or role that was changed"
+//	@Param				action		query		string	false	"Action such as role.assigned or user.deleted"
+//	@Param				since		query		string	false	"RFC 3339 time the events were recorded at or after"
+//	@Param				until		query		string	false	"RFC 3339 time the events were recorded before"
+//	@Success			200			{object}	store.AuditPage
+//	@Failure			400			{object}	foundation.Response
+//	@Failure			401			{object}	foundation.Response
+//	@Failure			403			{object}	foundation.Response
+//	@Failure			500			{object}	foundation.Response
+//	@Router				/v1/audit [get]
+//	@DeprecatedRouter	/admin/audit [get]
+func (h *Handler) AuditEvents(w http.ResponseWriter, r *http.Request) {
+	opts, err := auditOptions(r.URL.Query())
+	if err != nil {
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+		return
+	}
+
+	page, err := h.Store.ListAuditEvents(r.Context(), opts)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidCursor) {
+			foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+			return
+		}
+		h.Logger.Errorf("failed to list audit events: %v", err)
+		foundation.ErrorResponse(w, r, http.StatusInternalServerError, err, foundation.DatabaseError)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, page)
+}
+
+// auditOptions reads the filters and page of an audit log listing from the query string.
+func auditOptions(q url.Values) (store.AuditOptions, error) {
+	opts := store.AuditOptions{
+		ActorID:  q.Get("actor_id"),
+		TargetID: q.Get("target_id"),
+		Action:   q.Get("action"),
+		After:    q.Get("page_token"),
+	}
+
+	var err error
+	if opts.First, err = pageSize(q); err != nil {
+		return opts, err
+	}
+	if since := q.Get("since"); since != "" {
+		if opts.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			return opts, fmt.Errorf("invalid since: %w", err)
+		}
+	}
+	if until := q.Get("until"); until != "" {
+		if opts.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			return opts, fmt.Errorf("invalid until: %w", err)
+		}
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,79 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+func TestHandlerAuditEvents(t *testing.T) {
+	event := &store.AuditEvent{ID: "event-1", Action: store.AuditUserDeleted, TargetType: store.AuditTargetUser, TargetID: "user-1"}
+	scenarios := []struct {
+		name           string
+		query          string
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+		expectedOpts   *store.AuditOptions
+	}{
+		{
+			name:           "invalid since",
+			query:          "since=yesterday",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "invalid cursor",
+			store:          &mocks.Store{Error: store.ErrInvalidCursor},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "database error",
+			store:          &mocks.Store{Error: errors.New("db error")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   foundation.DatabaseError,
+		},
+		{
+			name:           "listed",
+			query:          "actor_id=admin-1&target_id=user-1&action=user.deleted&until=2024-02-01T00:00:00Z&page_size=5&page_token=abc",
+			store:          &mocks.Store{AuditEvents: []*store.AuditEvent{event}},
+			expectedStatus: http.StatusOK,
+			expectedOpts: &store.AuditOptions{
+				ActorID:  "admin-1",
+				TargetID: "user-1",
+				Action:   store.AuditUserDeleted,
+				Until:    time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+				First:    5,
+				After:    "abc",
+			},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			w := httptest.NewRecorder()
+			handler.AuditEvents(w, httptest.NewRequest(http.MethodGet, "/admin/audit?"+sc.query, nil))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Equal(t, sc.expectedOpts, sc.store.AuditListedWith)
+			assert.Contains(t, w.Body.String(), `"action":"user.deleted"`)
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/riyadennis/identity-server/business/store"
 	"github.com/riyadennis/identity-server/foundation"
 )
 
@@ -13,38 +14,84 @@ var (
 	errDeleteFailed = errors.New("failed to remove user")
 )
 
-// Delete @Summary      Delete Endpoint
+// Delete godoc
 //
-//	@Description	Permanently remove a user by ID
-//	@Tags			User
-//	@Security		ApiKeyAuth
-//	@Produce		json
-//	@Param			id	path		string	true	"User ID"
-//	@Success		204	{string}	string	"No Content"
-//	@Failure		400	{object}	foundation.Response
-//	@Failure		404	{object}	foundation.Response
-//	@Router			/admin/delete/{userID} [delete]
+//	@Summary			Delete a user
+//	@Description		Delete a user of the caller's organization by ID, the user can be restored until the retention has passed and is then permanently removed, requires the users:delete permission
+//	@Tags				User
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Param				userID	path		string	true	"User ID"
+//	@Success			204		{string}	string	"No Content"
+//	@Failure			400		{object}	foundation.Response
+//	@Failure			403		{object}	foundation.Response
+//	@Failure			404		{object}	foundation.Response
+//	@Router				/v1/users/{userID} [delete]
+//	@DeprecatedRouter	/admin/delete/{userID} [delete]
 func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "userID")
 	if id == "" {
-		foundation.ErrorResponse(w, http.StatusBadRequest,
+		foundation.ErrorResponse(w, r, http.StatusBadRequest,
 			errInvalidID, foundation.InvalidRequest)
 		return
 	}
 
-	deleted, err := h.Store.Delete(id)
+	err := h.Deleter.Delete(r.Context(), id)
 	if err != nil {
-		h.Logger.Printf("user deletion failed: %v", err)
+		if errors.Is(err, store.ErrUserNotFound) {
+			foundation.ErrorResponse(w, r, http.StatusBadRequest,
+				errDeleteFailed, foundation.UserDoNotExist)
+			return
+		}
+		if errors.Is(err, store.ErrSharedUser) {
+			foundation.ErrorResponse(w, r, http.StatusForbidden, err, foundation.Forbidden)
+			return
+		}
 
-		foundation.ErrorResponse(w, http.StatusBadRequest,
+		foundation.ErrorResponse(w, r, http.StatusBadRequest,
 			errDeleteFailed, foundation.DatabaseError)
 		return
 	}
 
-	if deleted == 0 {
-		foundation.ErrorResponse(w, http.StatusBadRequest,
-			errDeleteFailed, foundation.UserDoNotExist)
+	_ = foundation.JSONResponse(w, http.StatusNoContent, "", "")
+}
+
+// Restore godoc
+//
+//	@Summary			Restore a user
+//	@Description		Bring back a deleted user of the caller's organization by ID, only users deleted within the retention can be restored, requires the users:delete permission
+//	@Tags				User
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Param				userID	path		string	true	"User ID"
+//	@Success			200		{object}	store.User
+//	@Failure			400		{object}	foundation.Response
+//	@Failure			403		{object}	foundation.Response
+//	@Failure			404		{object}	foundation.Response
+//	@Failure			500		{object}	foundation.Response
+//	@Router				/v1/users/{userID}/restore [post]
+//	@DeprecatedRouter	/admin/restore/{userID} [post]
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "userID")
+	if id == "" {
+		foundation.ErrorResponse(w, r, http.StatusBadRequest,
+			errInvalidID, foundation.InvalidRequest)
 		return
 	}
-	_ = foundation.JSONResponse(w, http.StatusNoContent, "", "")
+
+	user, err := h.Deleter.Restore(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			foundation.ErrorResponse(w, r, http.StatusNotFound, err, foundation.UserDoNotExist)
+			return
+		}
+		if errors.Is(err, store.ErrSharedUser) {
+			foundation.ErrorResponse(w, r, http.StatusForbidden, err, foundation.Forbidden)
+			return
+		}
+		foundation.ErrorResponse(w, r, http.StatusInternalServerError, err, foundation.DatabaseError)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, user)
 }
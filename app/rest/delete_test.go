@@ -13,6 +13,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 
+	"github.com/riyadennis/identity-server/app/mocks"
 	"github.com/riyadennis/identity-server/business/store"
 	"github.com/riyadennis/identity-server/foundation"
 )
@@ -40,10 +41,11 @@ func TestHandlerDelete(t *testing.T) {
 			conn: func() *sql.DB {
 				conn, mock, err := sqlmock.New()
 				assert.NoError(t, err)
-				mock.ExpectPrepare("DELETE FROM identity_users WHERE id = ?").
-					ExpectExec().
-					WithArgs("INVALID").
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE identity_users SET deleted_at = \? WHERE id = \?`).
+					WithArgs(sqlmock.AnyArg(), "INVALID").
 					WillReturnError(errors.New("error"))
+				mock.ExpectRollback()
 				return conn
 			}(),
 			expectedStatus: http.StatusBadRequest,
@@ -59,10 +61,11 @@ func TestHandlerDelete(t *testing.T) {
 			conn: func() *sql.DB {
 				conn, mock, err := sqlmock.New()
 				assert.NoError(t, err)
-				mock.ExpectPrepare("DELETE FROM identity_users WHERE id = ?").
-					ExpectExec().
-					WithArgs("INVALID").
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE identity_users SET deleted_at = \? WHERE id = \?`).
+					WithArgs(sqlmock.AnyArg(), "INVALID").
 					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
 				return conn
 			}(),
 			expectedStatus: http.StatusBadRequest,
@@ -78,10 +81,15 @@ func TestHandlerDelete(t *testing.T) {
 			conn: func() *sql.DB {
 				conn, mock, err := sqlmock.New()
 				assert.NoError(t, err)
-				mock.ExpectPrepare("DELETE FROM identity_users WHERE id = ?").
-					ExpectExec().
-					WithArgs("123").
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE identity_users SET deleted_at = \? WHERE id = \?`).
+					WithArgs(sqlmock.AnyArg(), "123").
 					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(`INSERT INTO audit_events`).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`INSERT INTO outbox_events`).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
 				return conn
 			}(),
 			expectedStatus: http.StatusNoContent,
@@ -118,3 +126,60 @@ func TestHandlerDelete(t *testing.T) {
 		})
 	}
 }
+
+func TestHandlerRestore(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		userID         string
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "no id",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "not deleted or past the retention",
+			userID:         "123",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.UserDoNotExist,
+		},
+		{
+			name:           "database error",
+			userID:         "123",
+			store:          &mocks.Store{User: &store.User{ID: "123"}, Error: errors.New("db error")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   foundation.DatabaseError,
+		},
+		{
+			name:           "restored",
+			userID:         "123",
+			store:          &mocks.Store{User: &store.User{ID: "123", Email: "jane@example.com"}},
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			r := httptest.NewRequest(http.MethodPost, "/admin/restore/{userID}", nil)
+			routeContext := chi.NewRouteContext()
+			routeContext.URLParams.Add("userID", sc.userID)
+			r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext))
+			w := httptest.NewRecorder()
+			handler.Restore(w, r)
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Contains(t, w.Body.String(), "jane@example.com")
+		})
+	}
+}
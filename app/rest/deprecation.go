@@ -0,0 +1,37 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// deprecated is the middleware for the routes kept from before the API was
+// versioned. Responses say the route is deprecated and link to successor,
+// the pattern of the route that replaces it, filled in with the parameters
+// of the request.
+func deprecated(successor string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successorPath(r, successor)))
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// successorPath replaces the parameters in pattern with the values they have in r.
+func successorPath(r *http.Request, pattern string) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return pattern
+	}
+	for i, key := range rctx.URLParams.Keys {
+		pattern = strings.ReplaceAll(pattern, "{"+key+"}", url.PathEscape(rctx.URLParams.Values[i]))
+	}
+	return pattern
+}
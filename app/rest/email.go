@@ -0,0 +1,114 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/riyadennis/identity-server/business"
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+	"github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+// ChangeEmailRequest has the current password of the user and the address
+// they want to log in with.
+type ChangeEmailRequest struct {
+	Password string `json:"password"`
+	NewEmail string `json:"new_email"`
+}
+
+// ConfirmEmailRequest has the token from the email confirmation link.
+type ConfirmEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// ChangeEmail godoc
+//
+//	@Summary			Change email
+//	@Description		Email a confirmation link to the new address and a notice to the current one, the email is only changed once the link is used
+//	@Tags				User
+//	@Security			ApiKeyAuth
+//	@Accept				json
+//	@Produce			json
+//	@Param				email	body		ChangeEmailRequest	true	"Current password and new email"
+//	@Success			202		{object}	foundation.Response
+//	@Failure			400		{object}	foundation.Response
+//	@Failure			401		{object}	foundation.Response
+//	@Failure			403		{object}	foundation.Response
+//	@Failure			409		{object}	foundation.Response
+//	@Failure			500		{object}	foundation.Response
+//	@Router				/v1/me/email [post]
+//	@DeprecatedRouter	/user/email [post]
+func (h *Handler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	req := &ChangeEmailRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		h.Logger.Errorf("invalid data in request: %v", err)
+
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(middleware.UserClaimsKey).(*store.Claims)
+	if !ok || claims == nil {
+		foundation.ErrorResponse(w, r, http.StatusUnauthorized, authz.ErrUnauthenticated, foundation.UnAuthorised)
+		return
+	}
+
+	if err := h.EmailChanger.Request(r.Context(), claims.Subject, req.Password, req.NewEmail); err != nil {
+		h.emailChangeError(w, r, err)
+		return
+	}
+
+	_ = foundation.JSONResponse(w, http.StatusAccepted, "confirmation sent to the new email address", "")
+}
+
+// ConfirmEmail godoc
+//
+//	@Summary			Confirm email change
+//	@Description		Change the email of the user to the address the confirmation link was sent to, a link can only be used once
+//	@Tags				User
+//	@Accept				json
+//	@Produce			json
+//	@Param				confirmation	body		ConfirmEmailRequest	true	"Token from the confirmation link"
+//	@Success			200				{object}	store.User
+//	@Failure			400				{object}	foundation.Response
+//	@Failure			404				{object}	foundation.Response
+//	@Failure			409				{object}	foundation.Response
+//	@Failure			500				{object}	foundation.Response
+//	@Router				/v1/email/confirm [post]
+//	@DeprecatedRouter	/email/confirm [post]
+func (h *Handler) ConfirmEmail(w http.ResponseWriter, r *http.Request) {
+	req := &ConfirmEmailRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		h.Logger.Errorf("invalid data in request: %v", err)
+
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+		return
+	}
+
+	user, err := h.EmailChanger.Confirm(r.Context(), req.Token)
+	if err != nil {
+		h.emailChangeError(w, r, err)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, user)
+}
+
+// emailChangeError writes the response for an error from the email change flow.
+func (h *Handler) emailChangeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrEmailChangeNotFound):
+		foundation.ErrorResponse(w, r, http.StatusNotFound, err, foundation.EmailChangeNotFound)
+	case errors.Is(err, business.ErrInvalidPassword):
+		foundation.ErrorResponse(w, r, http.StatusForbidden, err, foundation.InvalidPassword)
+	case errors.Is(err, business.ErrInvalidDetails):
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.ValidationFailed)
+	case errors.Is(err, store.ErrUserNotFound):
+		foundation.ErrorResponse(w, r, http.StatusNotFound, err, foundation.UserDoNotExist)
+	default:
+		h.classifiedError(w, r, err)
+	}
+}
@@ -0,0 +1,156 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+	"github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+func TestHandlerChangeEmail(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		body           string
+		noClaims       bool
+		store          *mocks.Store
+		auth           *mocks.Authenticator
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "invalid body",
+			body:           "{",
+			store:          &mocks.Store{},
+			auth:           &mocks.Authenticator{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "no claims",
+			body:           `{"password":"secret123","new_email":"jane@new.example.com"}`,
+			noClaims:       true,
+			store:          &mocks.Store{},
+			auth:           &mocks.Authenticator{},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   foundation.UnAuthorised,
+		},
+		{
+			name:           "invalid email",
+			body:           `{"password":"secret123","new_email":"jane"}`,
+			store:          &mocks.Store{User: &store.User{ID: "user-123", Email: "jane@example.com"}},
+			auth:           &mocks.Authenticator{ReturnVal: true},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.ValidationFailed,
+		},
+		{
+			name:           "wrong password",
+			body:           `{"password":"wrong","new_email":"jane@new.example.com"}`,
+			store:          &mocks.Store{User: &store.User{ID: "user-123", Email: "jane@example.com"}},
+			auth:           &mocks.Authenticator{Error: errors.New("mismatch")},
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   foundation.InvalidPassword,
+		},
+		{
+			name: "reserved email",
+			body: `{"password":"secret123","new_email":"jane@new.example.com"}`,
+			store: &mocks.Store{
+				User:           &store.User{ID: "user-123", Email: "jane@example.com"},
+				ReservedEmails: []string{"jane@new.example.com"},
+			},
+			auth:           &mocks.Authenticator{ReturnVal: true},
+			expectedStatus: http.StatusConflict,
+			expectedCode:   foundation.EmailAlreadyExists,
+		},
+		{
+			name:           "requested",
+			body:           `{"password":"secret123","new_email":"jane@new.example.com"}`,
+			store:          &mocks.Store{User: &store.User{ID: "user-123", Email: "jane@example.com"}},
+			auth:           &mocks.Authenticator{ReturnVal: true},
+			expectedStatus: http.StatusAccepted,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			mailer := &mocks.Mailer{}
+			handler := NewHandler(sc.store, sc.auth, &store.TokenConfig{}, logrus.New())
+			handler.EmailChanger.Mailer = mailer
+
+			r := httptest.NewRequest(http.MethodPost, "/user/email", strings.NewReader(sc.body))
+			if !sc.noClaims {
+				r = r.WithContext(context.WithValue(r.Context(), middleware.UserClaimsKey, profileClaims))
+			}
+			w := httptest.NewRecorder()
+			handler.ChangeEmail(w, r)
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			require.Len(t, mailer.Sent, 2)
+			assert.Equal(t, "jane@new.example.com", mailer.Sent[0].To)
+			assert.Equal(t, "jane@example.com", mailer.Sent[1].To)
+		})
+	}
+}
+
+func TestConfirmEmailRoute(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "invalid body",
+			body:           "{",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "unknown token",
+			body:           `{"token":"unknown"}`,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.EmailChangeNotFound,
+		},
+		{
+			name:           "confirmed",
+			body:           `{"token":"token"}`,
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			st := &mocks.Store{EmailChanges: []*store.EmailChange{{
+				UserID:   "user-123",
+				NewEmail: "jane@new.example.com",
+				// sha256 of "token".
+				TokenHash: "3c469e9d6c5875d37a43f353d4f88e61fcf812c66eee3457465a40b0da4153e0",
+			}}}
+			router := setupTestRouter(st, &mocks.Authenticator{})
+			req := httptest.NewRequest(http.MethodPost, ConfirmEmailEndPoint, strings.NewReader(sc.body))
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, sc.expectedStatus, rec.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, rec.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Contains(t, rec.Body.String(), "jane@new.example.com")
+		})
+	}
+}
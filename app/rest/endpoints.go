@@ -9,23 +9,130 @@ import (
 	"github.com/go-chi/cors"
 	"github.com/sirupsen/logrus"
 
+	"github.com/riyadennis/identity-server/business/authz"
 	"github.com/riyadennis/identity-server/business/store"
 	customMiddleware "github.com/riyadennis/identity-server/foundation/middleware"
 )
 
 const (
+	// V1 is the prefix of the versioned API, the routes without it are
+	// deprecated aliases kept for existing clients.
+	V1 = "/v1"
+
 	// RegisterEndpoint is to create a new user.
 	RegisterEndpoint = "/register"
 
 	// DeleteEndpoint is to delete a user.
 	DeleteEndpoint = "/delete/{userID}"
 
+	// RestoreEndpoint brings back a deleted user.
+	RestoreEndpoint = "/restore/{userID}"
+
 	// LoginEndPoint creates a token for the  user of credentials are valid.
 	LoginEndPoint = "/login"
 
 	// logged-in user with a valid token can access.
 	HomeEndPoint = "/home"
 
+	// ProfileEndPoint reads and updates the profile of the logged-in user.
+	ProfileEndPoint = "/profile"
+
+	// ChangeEmailEndPoint emails a link to confirm a new email address.
+	ChangeEmailEndPoint = "/email"
+
+	// ConfirmEmailEndPoint changes the email of a user once the new address is confirmed.
+	ConfirmEmailEndPoint = "/email/confirm"
+
+	// SwitchOrganizationEndPoint issues a token for another organization of the user.
+	SwitchOrganizationEndPoint = "/organizations/{organizationID}/switch"
+
+	// UsersEndPoint pages through the users of the organization.
+	UsersEndPoint = "/users"
+
+	// SearchUsersEndPoint finds users by words of their name, email or company.
+	SearchUsersEndPoint = "/users/search"
+
+	// ExportEndPoint downloads everything stored about a user.
+	ExportEndPoint = "/export"
+
+	// ExportUserEndPoint downloads everything stored about a user of the organization.
+	ExportUserEndPoint = "/users/{userID}/export"
+
+	// EraseUserEndPoint erases the personal data of a user.
+	EraseUserEndPoint = "/users/{userID}/erase"
+
+	// LoginsEndPoint pages through the login history of the logged-in user.
+	LoginsEndPoint = "/logins"
+
+	// UserLoginsEndPoint pages through the login history of a user of the organization.
+	UserLoginsEndPoint = "/users/{userID}/logins"
+
+	// AuditEndPoint pages through the audit log of the organization.
+	AuditEndPoint = "/audit"
+
+	// WebhooksEndPoint lists and registers webhooks.
+	WebhooksEndPoint = "/webhooks"
+
+	// WebhookEndPoint deletes a webhook.
+	WebhookEndPoint = "/webhooks/{webhookID}"
+
+	// WebhookDeliveriesEndPoint pages through the deliveries of a webhook.
+	WebhookDeliveriesEndPoint = "/webhooks/{webhookID}/deliveries"
+
+	// ReplayDeliveryEndPoint posts a webhook delivery again.
+	ReplayDeliveryEndPoint = "/webhooks/deliveries/{deliveryID}/replay"
+
+	// InvitationsEndPoint lists and creates invitations.
+	InvitationsEndPoint = "/invitations"
+
+	// InvitationEndPoint revokes an invitation.
+	InvitationEndPoint = "/invitations/{invitationID}"
+
+	// ResendInvitationEndPoint emails a new link for an invitation.
+	ResendInvitationEndPoint = "/invitations/{invitationID}/resend"
+
+	// AcceptInvitationEndPoint creates the invited user.
+	AcceptInvitationEndPoint = "/invitations/accept"
+
+	// MeEndPoint reads and updates the logged-in user, its own resources are under it.
+	MeEndPoint = "/me"
+
+	// UserEndPoint deletes a user of the organization.
+	UserEndPoint = "/users/{userID}"
+
+	// RestoreUserEndPoint brings back a deleted user of the organization.
+	RestoreUserEndPoint = "/users/{userID}/restore"
+
+	// UserRoleEndPoint reads and assigns the built-in role of a user.
+	UserRoleEndPoint = "/users/{userID}/role"
+
+	// UserRolesEndPoint lists every role of a user.
+	UserRolesEndPoint = "/users/{userID}/roles"
+
+	// UserRoleGrantEndPoint grants and revokes a role of a user.
+	UserRoleGrantEndPoint = "/users/{userID}/roles/{role}"
+
+	// ActivationEndPoint activates and deactivates a user.
+	ActivationEndPoint = "/users/{userID}/activation"
+
+	// RolesEndPoint lists and creates roles.
+	RolesEndPoint = "/roles"
+
+	// RoleEndPoint deletes a role.
+	RoleEndPoint = "/roles/{role}"
+
+	// RolePermissionsEndPoint replaces the permissions of a role.
+	RolePermissionsEndPoint = "/roles/{role}/permissions"
+
+	// PermissionsEndPoint lists the permissions roles can grant.
+	PermissionsEndPoint = "/permissions"
+
+	// OrganizationsEndPoint lists the organizations of the user and creates new ones.
+	OrganizationsEndPoint = "/organizations"
+
+	// MemberEndPoint removes a user from the organization.
+	MemberEndPoint = "/members/{userID}"
+
 	// LivenessEndPoint is for kubernetes to check when to restart the container.
 	LivenessEndPoint = "/liveness"
 
@@ -35,11 +142,17 @@ const (
 
 // LoadRESTEndpoints adds REST endpoints to the router.
 func LoadRESTEndpoints(tc *store.TokenConfig, logger *logrus.Logger, st store.Store, auth store.Authenticator) http.Handler {
+	return NewRouter(NewHandler(st, auth, tc, logger))
+}
+
+// NewRouter serves the REST endpoints with h.
+func NewRouter(h *Handler) http.Handler {
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(customMiddleware.RealIP)
+	r.Use(customMiddleware.Actor)
 
 	// Set a timeout value on the request context (ctx) that will signal
 	// through ctx.Done() that the request has timed out and further
@@ -50,31 +163,123 @@ func LoadRESTEndpoints(tc *store.TokenConfig, logger *logrus.Logger, st store.St
 		// AllowedOrigins:   []string{"https://foo.com"}, // Use this to allow specific origin hosts
 		AllowedOrigins: []string{"https://*", "http://*"},
 		// AllowOriginFunc: func(r *http.Request, origin string) bool { return true },
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value isn't ignored by any of the major browsers
 	}))
 	r.Get(LivenessEndPoint, Liveness)
-	r.Get(ReadinessEndPoint, Ready(st))
+	r.Get(ReadinessEndPoint, Ready(h.Store))
 
-	h := NewHandler(st, auth, tc, logger)
+	ac := &customMiddleware.AuthConfig{
+		TokenConfig: h.TokenConfig,
+		Authorizer:  h.Authorizer,
+		Logger:      h.Logger,
+	}
+	r.Route(V1, func(r chi.Router) {
+		h.v1(r, ac)
+	})
+	h.unversioned(r, ac)
+
+	return r
+}
+
+// v1 adds the routes of the versioned API.
+func (h *Handler) v1(r chi.Router, ac *customMiddleware.AuthConfig) {
 	r.Post(RegisterEndpoint, h.Register)
 	r.Post(LoginEndPoint, h.Login)
-	ac := customMiddleware.AuthConfig{
-		TokenConfig: tc,
-		Logger:      logger,
-	}
-	// register routes here
+	r.Post(AcceptInvitationEndPoint, h.AcceptInvitation)
+	r.Post(ConfirmEmailEndPoint, h.ConfirmEmail)
+
+	r.Group(func(r chi.Router) {
+		r.Use(ac.Auth)
+		r.Get(MeEndPoint, h.Profile)
+		r.Patch(MeEndPoint, h.UpdateProfile)
+		r.Post(MeEndPoint+ChangeEmailEndPoint, h.ChangeEmail)
+		r.Get(MeEndPoint+ExportEndPoint, h.Export)
+		r.Get(MeEndPoint+LoginsEndPoint, h.Logins)
+		r.Get(OrganizationsEndPoint, h.ListOrganizations)
+		r.Post(OrganizationsEndPoint, h.CreateOrganization)
+		r.Post(SwitchOrganizationEndPoint, h.SwitchOrganization)
+
+		r.With(ac.RequirePermission(authz.UsersRead)).Get(UsersEndPoint, h.ListUsers)
+		r.With(ac.RequirePermission(authz.UsersWrite)).Post(UsersEndPoint, h.CreateUser)
+		r.With(ac.RequirePermission(authz.UsersRead)).Get(SearchUsersEndPoint, h.SearchUsers)
+		r.With(ac.RequirePermission(authz.UsersDelete)).Delete(UserEndPoint, h.Delete)
+		r.With(ac.RequirePermission(authz.UsersDelete)).Post(RestoreUserEndPoint, h.Restore)
+		r.With(ac.RequirePermission(authz.UsersRead)).Get(ExportUserEndPoint, h.ExportUser)
+		r.With(ac.RequirePermission(authz.UsersDelete)).Post(EraseUserEndPoint, h.EraseUser)
+		r.With(ac.RequirePermission(authz.UsersRead)).Get(UserLoginsEndPoint, h.UserLogins)
+		r.With(ac.RequirePermission(authz.UsersWrite)).Put(ActivationEndPoint, h.Activation)
+		r.With(ac.RequirePermission(authz.UsersWrite)).Delete(MemberEndPoint, h.RemoveMember)
+
+		r.With(ac.RequirePermission(authz.RolesRead)).Get(UserRoleEndPoint, h.UserRole)
+		r.With(ac.RequirePermission(authz.RolesWrite)).Put(UserRoleEndPoint, h.AssignRole)
+		r.With(ac.RequirePermission(authz.RolesRead)).Get(UserRolesEndPoint, h.UserRoles)
+		r.With(ac.RequirePermission(authz.RolesWrite)).Put(UserRoleGrantEndPoint, h.GrantRole)
+		r.With(ac.RequirePermission(authz.RolesWrite)).Delete(UserRoleGrantEndPoint, h.RevokeRole)
+		r.With(ac.RequirePermission(authz.RolesRead)).Get(RolesEndPoint, h.ListRoles)
+		r.With(ac.RequirePermission(authz.RolesWrite)).Post(RolesEndPoint, h.CreateRole)
+		r.With(ac.RequirePermission(authz.RolesWrite)).Delete(RoleEndPoint, h.DeleteRole)
+		r.With(ac.RequirePermission(authz.RolesWrite)).Put(RolePermissionsEndPoint, h.UpdateRolePermissions)
+		r.With(ac.RequirePermission(authz.RolesRead)).Get(PermissionsEndPoint, h.ListPermissions)
+
+		r.With(ac.RequirePermission(authz.AuditRead)).Get(AuditEndPoint, h.AuditEvents)
+		r.With(ac.RequirePermission(authz.WebhooksManage)).Get(WebhooksEndPoint, h.ListWebhooks)
+		r.With(ac.RequirePermission(authz.WebhooksManage)).Post(WebhooksEndPoint, h.CreateWebhook)
+		r.With(ac.RequirePermission(authz.WebhooksManage)).Delete(WebhookEndPoint, h.DeleteWebhook)
+		r.With(ac.RequirePermission(authz.WebhooksManage)).Get(WebhookDeliveriesEndPoint, h.WebhookDeliveries)
+		r.With(ac.RequirePermission(authz.WebhooksManage)).Post(ReplayDeliveryEndPoint, h.ReplayWebhookDelivery)
+		r.With(ac.RequirePermission(authz.UsersRead)).Get(InvitationsEndPoint, h.ListInvitations)
+		r.With(ac.RequirePermission(authz.UsersWrite)).Post(InvitationsEndPoint, h.Invite)
+		r.With(ac.RequirePermission(authz.UsersWrite)).Post(ResendInvitationEndPoint, h.ResendInvitation)
+		r.With(ac.RequirePermission(authz.UsersWrite)).Delete(InvitationEndPoint, h.RevokeInvitation)
+	})
+}
+
+// unversioned adds the routes from before the API was versioned, each of
+// them links to the route of the versioned API that replaces it.
+func (h *Handler) unversioned(r chi.Router, ac *customMiddleware.AuthConfig) {
+	r.With(deprecated(V1+RegisterEndpoint)).Post(RegisterEndpoint, h.Register)
+	r.With(deprecated(V1+LoginEndPoint)).Post(LoginEndPoint, h.Login)
+	r.With(deprecated(V1+AcceptInvitationEndPoint)).Post(AcceptInvitationEndPoint, h.AcceptInvitation)
+	r.With(deprecated(V1+ConfirmEmailEndPoint)).Post(ConfirmEmailEndPoint, h.ConfirmEmail)
+
 	r.Route("/user", func(r chi.Router) {
 		r.Use(ac.Auth)
-		r.Get(HomeEndPoint, Home)
+		alias := func(successor string) chi.Router {
+			return r.With(deprecated(V1 + successor))
+		}
+		alias(MeEndPoint).Get(HomeEndPoint, Home)
+		alias(MeEndPoint).Get(ProfileEndPoint, h.Profile)
+		alias(MeEndPoint).Patch(ProfileEndPoint, h.UpdateProfile)
+		alias(MeEndPoint+ChangeEmailEndPoint).Post(ChangeEmailEndPoint, h.ChangeEmail)
+		alias(MeEndPoint+ExportEndPoint).Get(ExportEndPoint, h.Export)
+		alias(MeEndPoint+LoginsEndPoint).Get(LoginsEndPoint, h.Logins)
+		alias(SwitchOrganizationEndPoint).Post(SwitchOrganizationEndPoint, h.SwitchOrganization)
 	})
 	r.Route("/admin", func(r chi.Router) {
 		r.Use(ac.Auth)
-		r.Delete(DeleteEndpoint, h.Delete)
+		alias := func(successor, permission string) chi.Router {
+			return r.With(deprecated(V1+successor), ac.RequirePermission(permission))
+		}
+		alias(UserEndPoint, authz.UsersDelete).Delete(DeleteEndpoint, h.Delete)
+		alias(RestoreUserEndPoint, authz.UsersDelete).Post(RestoreEndpoint, h.Restore)
+		alias(UsersEndPoint, authz.UsersRead).Get(UsersEndPoint, h.ListUsers)
+		alias(SearchUsersEndPoint, authz.UsersRead).Get(SearchUsersEndPoint, h.SearchUsers)
+		alias(ExportUserEndPoint, authz.UsersRead).Get(ExportUserEndPoint, h.ExportUser)
+		alias(EraseUserEndPoint, authz.UsersDelete).Post(EraseUserEndPoint, h.EraseUser)
+		alias(UserLoginsEndPoint, authz.UsersRead).Get(UserLoginsEndPoint, h.UserLogins)
+		alias(AuditEndPoint, authz.AuditRead).Get(AuditEndPoint, h.AuditEvents)
+		alias(WebhooksEndPoint, authz.WebhooksManage).Get(WebhooksEndPoint, h.ListWebhooks)
+		alias(WebhooksEndPoint, authz.WebhooksManage).Post(WebhooksEndPoint, h.CreateWebhook)
+		alias(WebhookEndPoint, authz.WebhooksManage).Delete(WebhookEndPoint, h.DeleteWebhook)
+		alias(WebhookDeliveriesEndPoint, authz.WebhooksManage).Get(WebhookDeliveriesEndPoint, h.WebhookDeliveries)
+		alias(ReplayDeliveryEndPoint, authz.WebhooksManage).Post(ReplayDeliveryEndPoint, h.ReplayWebhookDelivery)
+		alias(InvitationsEndPoint, authz.UsersRead).Get(InvitationsEndPoint, h.ListInvitations)
+		alias(InvitationsEndPoint, authz.UsersWrite).Post(InvitationsEndPoint, h.Invite)
+		alias(ResendInvitationEndPoint, authz.UsersWrite).Post(ResendInvitationEndPoint, h.ResendInvitation)
+		alias(InvitationEndPoint, authz.UsersWrite).Delete(InvitationEndPoint, h.RevokeInvitation)
 	})
-
-	return r
 }
@@ -2,6 +2,7 @@ package rest
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
@@ -10,10 +11,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/riyadennis/identity-server/app/mocks"
 	"github.com/riyadennis/identity-server/foundation"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/riyadennis/identity-server/business/store"
 )
@@ -116,6 +119,23 @@ func TestRegisterRoute_ValidRequest(t *testing.T) {
 	assert.Equal(t, http.StatusCreated, rec.Code)
 }
 
+func TestRegisterRoute_DuplicateEmail(t *testing.T) {
+	for _, path := range []string{V1 + RegisterEndpoint, RegisterEndpoint} {
+		t.Run(path, func(t *testing.T) {
+			router := setupTestRouter(&mocks.Store{Error: store.ErrDuplicateEmail}, &mocks.Authenticator{})
+
+			var buf bytes.Buffer
+			require.NoError(t, json.NewEncoder(&buf).Encode(user(t)))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, &buf))
+
+			assert.Equal(t, http.StatusConflict, rec.Code)
+			assert.Equal(t, foundation.NewResponse(http.StatusConflict, "email already exists", foundation.EmailAlreadyExists),
+				responseFromHTTP(t, rec.Body))
+		})
+	}
+}
+
 func TestDeleteRoute_ValidToken(t *testing.T) {
 	auth := &mocks.Authenticator{
 		ReturnVal: true,
@@ -150,3 +170,90 @@ func TestHomeRoute_ValidToken(t *testing.T) {
 	// In a real scenario, you'd need to setup proper token validation or mock the auth middleware
 	assert.Equal(t, http.StatusUnauthorized, rec.Code)
 }
+
+func TestV1Routes(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		method       string
+		path         string
+		expectedCode int
+	}{
+		{name: "me", method: http.MethodGet, path: V1 + MeEndPoint, expectedCode: http.StatusUnauthorized},
+		{name: "users", method: http.MethodPost, path: V1 + UsersEndPoint, expectedCode: http.StatusUnauthorized},
+		{name: "role", method: http.MethodPut, path: "/v1/users/123/role", expectedCode: http.StatusUnauthorized},
+		{name: "activation", method: http.MethodPut, path: "/v1/users/123/activation", expectedCode: http.StatusUnauthorized},
+		{name: "wrong method", method: http.MethodPost, path: "/v1/users/123/activation", expectedCode: http.StatusMethodNotAllowed},
+		{name: "register", method: http.MethodGet, path: V1 + RegisterEndpoint, expectedCode: http.StatusMethodNotAllowed},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			router := setupTestRouter(&mocks.Store{}, &mocks.Authenticator{})
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, httptest.NewRequest(sc.method, sc.path, nil))
+
+			assert.Equal(t, sc.expectedCode, rec.Code)
+			assert.Empty(t, rec.Header().Get("Deprecation"))
+		})
+	}
+}
+
+func TestDeprecatedRoutes(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		method       string
+		path         string
+		expectedLink string
+		expectedCode int
+	}{
+		{
+			name:         "register",
+			method:       http.MethodPost,
+			path:         RegisterEndpoint,
+			expectedLink: `</v1/register>; rel="successor-version"`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "login",
+			method:       http.MethodPost,
+			path:         LoginEndPoint,
+			expectedLink: `</v1/login>; rel="successor-version"`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "confirm email",
+			method:       http.MethodPost,
+			path:         ConfirmEmailEndPoint,
+			expectedLink: `</v1/email/confirm>; rel="successor-version"`,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			router := setupTestRouter(&mocks.Store{}, &mocks.Authenticator{})
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, httptest.NewRequest(sc.method, sc.path, nil))
+
+			assert.Equal(t, sc.expectedCode, rec.Code)
+			assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+			assert.Equal(t, sc.expectedLink, rec.Header().Get("Link"))
+		})
+	}
+}
+
+func TestDeprecated(t *testing.T) {
+	r := httptest.NewRequest(http.MethodDelete, "/admin/delete/a%20b", nil)
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("userID", "a b")
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext))
+	rec := httptest.NewRecorder()
+
+	deprecated(V1+UserEndPoint)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})).ServeHTTP(rec, r)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+	assert.Equal(t, `</v1/users/a%20b>; rel="successor-version"`, rec.Header().Get("Link"))
+}
@@ -0,0 +1,27 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/riyadennis/identity-server/business"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+// errInternal replaces the message of errors that are failures of the service.
+var errInternal = errors.New("internal error")
+
+// classifiedError writes err with the status and code it gets from every
+// API. Failures of the service are logged and sent without their details,
+// the id of the request finds them in the log.
+func (h *Handler) classifiedError(w http.ResponseWriter, r *http.Request, err error) {
+	ce := business.Classify(err)
+	if ce.Category == foundation.CategoryInternal {
+		id := business.CorrelationID(r.Context())
+		h.Logger.WithField("correlation_id", id).Errorf("%s %s failed: %v", r.Method, r.URL.Path, err)
+		foundation.ErrorResponse(w, r, http.StatusInternalServerError, errInternal, foundation.InternalError)
+		return
+	}
+
+	foundation.ErrorResponse(w, r, ce.Category.Status(), err, ce.Code)
+}
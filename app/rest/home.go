@@ -6,15 +6,16 @@ import (
 	"github.com/riyadennis/identity-server/foundation"
 )
 
-// Home @Summary      Get user dashboard
+// Home godoc
 //
-//	@Description	Returns dashboard info for authenticated user
-//	@Tags			User
-//	@Security		ApiKeyAuth
-//	@Produce		json
-//	@Success		200	{object}	foundation.Response
-//	@Failure		401	{object}	foundation.Response
-//	@Router			/user/home [get]
+//	@Summary			Get user dashboard
+//	@Description		Returns dashboard info for authenticated user
+//	@Tags				User
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Success			200	{object}	foundation.Response
+//	@Failure			401	{object}	foundation.Response
+//	@DeprecatedRouter	/user/home [get]
 func Home(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
@@ -0,0 +1,215 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/riyadennis/identity-server/business"
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+	"github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+var errInvalidInvitationID = errors.New("invalid invitationID in request")
+
+// InviteRequest has the email to invite and the role the user gets.
+type InviteRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// AcceptInvitationRequest has the token from the invitation link and the
+// details the invited user fills in.
+type AcceptInvitationRequest struct {
+	Token     string `json:"token"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Password  string `json:"password"`
+	Company   string `json:"company"`
+	PostCode  string `json:"post_code"`
+	Terms     bool   `json:"terms"`
+}
+
+// Invite godoc
+//
+//	@Summary			Invite a user
+//	@Description		Email an invitation to join the caller's organization with a preassigned role, requires the users:write permission
+//	@Tags				Invitation
+//	@Security			ApiKeyAuth
+//	@Accept				json
+//	@Produce			json
+//	@Param				invitation	body		InviteRequest	true	"Email to invite and the role to assign"
+//	@Success			201			{object}	store.Invitation
+//	@Failure			400			{object}	foundation.Response
+//	@Failure			403			{object}	foundation.Response
+//	@Failure			409			{object}	foundation.Response
+//	@Failure			500			{object}	foundation.Response
+//	@Router				/v1/invitations [post]
+//	@DeprecatedRouter	/admin/invitations [post]
+func (h *Handler) Invite(w http.ResponseWriter, r *http.Request) {
+	req := &InviteRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		h.Logger.Errorf("invalid data in request: %v", err)
+
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(middleware.UserClaimsKey).(*store.Claims)
+	if !ok || claims == nil {
+		foundation.ErrorResponse(w, r, http.StatusUnauthorized, authz.ErrUnauthenticated, foundation.UnAuthorised)
+		return
+	}
+
+	inv, err := h.Inviter.Invite(r.Context(), claims.Subject, req.Email, req.Role)
+	if err != nil {
+		h.invitationError(w, r, err)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusCreated, inv)
+}
+
+// ListInvitations godoc
+//
+//	@Summary			List invitations
+//	@Description		List the pending invitations of the caller's organization, requires the users:read permission
+//	@Tags				Invitation
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Success			200	{array}		store.Invitation
+//	@Failure			403	{object}	foundation.Response
+//	@Failure			500	{object}	foundation.Response
+//	@Router				/v1/invitations [get]
+//	@DeprecatedRouter	/admin/invitations [get]
+func (h *Handler) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	invitations, err := h.Store.ListInvitations(r.Context())
+	if err != nil {
+		h.invitationError(w, r, err)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, invitations)
+}
+
+// ResendInvitation godoc
+//
+//	@Summary			Resend an invitation
+//	@Description		Email a new link for a pending invitation, the previous link stops working, requires the users:write permission
+//	@Tags				Invitation
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Param				invitationID	path		string	true	"Invitation ID"
+//	@Success			200				{object}	store.Invitation
+//	@Failure			400				{object}	foundation.Response
+//	@Failure			403				{object}	foundation.Response
+//	@Failure			404				{object}	foundation.Response
+//	@Router				/v1/invitations/{invitationID}/resend [post]
+//	@DeprecatedRouter	/admin/invitations/{invitationID}/resend [post]
+func (h *Handler) ResendInvitation(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "invitationID")
+	if id == "" {
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, errInvalidInvitationID, foundation.InvalidRequest)
+		return
+	}
+
+	inv, err := h.Inviter.Resend(r.Context(), id)
+	if err != nil {
+		h.invitationError(w, r, err)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, inv)
+}
+
+// RevokeInvitation godoc
+//
+//	@Summary			Revoke an invitation
+//	@Description		Cancel a pending invitation so its link can no longer be used, requires the users:write permission
+//	@Tags				Invitation
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Param				invitationID	path		string	true	"Invitation ID"
+//	@Success			204				{string}	string	"No Content"
+//	@Failure			400				{object}	foundation.Response
+//	@Failure			403				{object}	foundation.Response
+//	@Failure			404				{object}	foundation.Response
+//	@Router				/v1/invitations/{invitationID} [delete]
+//	@DeprecatedRouter	/admin/invitations/{invitationID} [delete]
+func (h *Handler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "invitationID")
+	if id == "" {
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, errInvalidInvitationID, foundation.InvalidRequest)
+		return
+	}
+
+	if err := h.Store.RevokeInvitation(r.Context(), id); err != nil {
+		h.invitationError(w, r, err)
+		return
+	}
+
+	_ = foundation.JSONResponse(w, http.StatusNoContent, "", "")
+}
+
+// AcceptInvitation godoc
+//
+//	@Summary			Accept an invitation
+//	@Description		Create the invited user with the token from the invitation link, a link can only be used once
+//	@Tags				Invitation
+//	@Accept				json
+//	@Produce			json
+//	@Param				invitation	body		AcceptInvitationRequest	true	"Invitation token and user details"
+//	@Success			201			{object}	store.User
+//	@Failure			400			{object}	foundation.Response
+//	@Failure			404			{object}	foundation.Response
+//	@Failure			409			{object}	foundation.Response
+//	@Failure			500			{object}	foundation.Response
+//	@Router				/v1/invitations/accept [post]
+//	@DeprecatedRouter	/invitations/accept [post]
+func (h *Handler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	req := &AcceptInvitationRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		h.Logger.Errorf("invalid data in request: %v", err)
+
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+		return
+	}
+
+	user, err := h.Inviter.Accept(r.Context(), req.Token, &store.User{
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Password:  req.Password,
+		Company:   req.Company,
+		PostCode:  req.PostCode,
+		Terms:     req.Terms,
+	})
+	if err != nil {
+		h.invitationError(w, r, err)
+		return
+	}
+
+	user.Password = "********"
+	_ = foundation.Resource(w, http.StatusCreated, user)
+}
+
+// invitationError writes the response for an error from the invitation flow.
+func (h *Handler) invitationError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrInvitationNotFound):
+		foundation.ErrorResponse(w, r, http.StatusNotFound, err, foundation.InvitationNotFound)
+	case errors.Is(err, store.ErrRoleNotFound):
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+	case errors.Is(err, store.ErrPlatformRole):
+		foundation.ErrorResponse(w, r, http.StatusForbidden, err, foundation.Forbidden)
+	case errors.Is(err, store.ErrOrganizationNotFound):
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+	case errors.Is(err, business.ErrInvalidDetails):
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.ValidationFailed)
+	default:
+		h.classifiedError(w, r, err)
+	}
+}
@@ -0,0 +1,245 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+	"github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+func invitationStore() *mocks.Store {
+	return &mocks.Store{
+		Organizations: []*store.Organization{{ID: "org-1", Name: "Acme"}},
+		Invitations: []*store.Invitation{{
+			ID:             "invitation-1",
+			Email:          "jane@example.com",
+			OrganizationID: "org-1",
+			Role:           "user",
+			ExpiresAt:      time.Now().Add(time.Hour),
+		}},
+	}
+}
+
+func TestHandlerInvite(t *testing.T) {
+	claims := &store.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "admin-1"}, OrgID: "org-1"}
+	scenarios := []struct {
+		name           string
+		body           string
+		claims         *store.Claims
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "invalid body",
+			body:           "{",
+			claims:         claims,
+			store:          invitationStore(),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "no claims",
+			body:           `{"email":"jane@example.com"}`,
+			store:          invitationStore(),
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   foundation.UnAuthorised,
+		},
+		{
+			name:           "invalid email",
+			body:           `{"email":"jane"}`,
+			claims:         claims,
+			store:          invitationStore(),
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.ValidationFailed,
+		},
+		{
+			name:           "existing user",
+			body:           `{"email":"jane@example.com"}`,
+			claims:         claims,
+			store:          &mocks.Store{User: &store.User{Email: "jane@example.com"}},
+			expectedStatus: http.StatusConflict,
+			expectedCode:   foundation.EmailAlreadyExists,
+		},
+		{
+			name:           "invited",
+			body:           `{"email":"jane@example.com","role":"support"}`,
+			claims:         claims,
+			store:          invitationStore(),
+			expectedStatus: http.StatusCreated,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			mailer := &mocks.Mailer{}
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+			handler.Inviter.Mailer = mailer
+
+			r := httptest.NewRequest(http.MethodPost, "/admin/invitations", strings.NewReader(sc.body))
+			ctx := store.WithTenant(r.Context(), store.Tenant{OrganizationID: "org-1"})
+			if sc.claims != nil {
+				ctx = context.WithValue(ctx, middleware.UserClaimsKey, sc.claims)
+			}
+			w := httptest.NewRecorder()
+			handler.Invite(w, r.WithContext(ctx))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			require.Len(t, mailer.Sent, 1)
+			assert.Equal(t, "jane@example.com", mailer.Sent[0].To)
+			assert.Contains(t, w.Body.String(), `"role":"support"`)
+		})
+	}
+}
+
+func TestHandlerResendInvitation(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		id             string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "no id",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "unknown invitation",
+			id:             "invitation-2",
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.InvitationNotFound,
+		},
+		{
+			name:           "resent",
+			id:             "invitation-1",
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			mailer := &mocks.Mailer{}
+			handler := NewHandler(invitationStore(), &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+			handler.Inviter.Mailer = mailer
+
+			r := httptest.NewRequest(http.MethodPost, "/admin/invitations/{invitationID}/resend", nil)
+			routeContext := chi.NewRouteContext()
+			routeContext.URLParams.Add("invitationID", sc.id)
+			w := httptest.NewRecorder()
+			handler.ResendInvitation(w, r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext)))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Len(t, mailer.Sent, 1)
+		})
+	}
+}
+
+func TestHandlerRevokeInvitation(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		id             string
+		expectedStatus int
+	}{
+		{name: "no id", expectedStatus: http.StatusBadRequest},
+		{name: "unknown invitation", id: "invitation-2", expectedStatus: http.StatusNotFound},
+		{name: "revoked", id: "invitation-1", expectedStatus: http.StatusNoContent},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(invitationStore(), &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			r := httptest.NewRequest(http.MethodDelete, "/admin/invitations/{invitationID}", nil)
+			routeContext := chi.NewRouteContext()
+			routeContext.URLParams.Add("invitationID", sc.id)
+			w := httptest.NewRecorder()
+			handler.RevokeInvitation(w, r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, routeContext)))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+		})
+	}
+}
+
+func TestHandlerListInvitations(t *testing.T) {
+	handler := NewHandler(invitationStore(), &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+	w := httptest.NewRecorder()
+	handler.ListInvitations(w, httptest.NewRequest(http.MethodGet, "/admin/invitations", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "jane@example.com")
+}
+
+func TestAcceptInvitationRoute(t *testing.T) {
+	st := invitationStore()
+	// sha256 of "token".
+	st.Invitations[0].TokenHash = "3c469e9d6c5875d37a43f353d4f88e61fcf812c66eee3457465a40b0da4153e0"
+	scenarios := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "invalid body",
+			body:           "{",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "unknown token",
+			body:           `{"token":"unknown","first_name":"Jane","last_name":"Doe","password":"secret123","terms":true}`,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.InvitationNotFound,
+		},
+		{
+			name:           "missing password",
+			body:           `{"token":"token","first_name":"Jane","last_name":"Doe","terms":true}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.ValidationFailed,
+		},
+		{
+			name:           "accepted",
+			body:           `{"token":"token","first_name":"Jane","last_name":"Doe","password":"secret123","terms":true}`,
+			expectedStatus: http.StatusCreated,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			router := setupTestRouter(st, &mocks.Authenticator{})
+			req := httptest.NewRequest(http.MethodPost, AcceptInvitationEndPoint, strings.NewReader(sc.body))
+			rec := httptest.NewRecorder()
+
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, sc.expectedStatus, rec.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, rec.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Contains(t, rec.Body.String(), "jane@example.com")
+			assert.NotContains(t, rec.Body.String(), "secret123")
+		})
+	}
+}
@@ -5,8 +5,7 @@ import (
 	"errors"
 	"net/http"
 
-	"github.com/riyadennis/identity-server/business"
-	"github.com/riyadennis/identity-server/business/validation"
+	"github.com/riyadennis/identity-server/business/store"
 	"github.com/riyadennis/identity-server/foundation"
 )
 
@@ -18,48 +17,50 @@ type UserLogin struct {
 	Password string `json:"password"`
 }
 
-// Login @Summary      Login Endpoint
+// Login godoc
 //
-//	@Description	Authenticate a user and return a JWT token
-//	@Tags			Auth
-//	@Accept			json
-//	@Produce		json
-//	@Param			Authorization	header		string	true	"Basic base64(email:password)"
-//	@Success		200				{object}	store.Token
-//	@Failure		400				{object}	foundation.Response
-//	@Failure		401				{object}	foundation.Response
-//	@Failure		500				{object}	foundation.Response
-//	@Router			/login [post]
+//	@Summary			Log in
+//	@Description		Authenticate a user and return a JWT token
+//	@Tags				Auth
+//	@Accept				json
+//	@Produce			json
+//	@Param				Authorization	header		string	true	"Basic base64(email:password)"
+//	@Success			200				{object}	store.Token
+//	@Failure			400				{object}	foundation.Response
+//	@Failure			401				{object}	foundation.Response
+//	@Failure			500				{object}	foundation.Response
+//	@Router				/v1/login [post]
+//	@DeprecatedRouter	/login [post]
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	email, password, ok := r.BasicAuth()
 	if !ok {
 		h.Logger.Printf("invalid request, username or password is empty")
 
-		foundation.ErrorResponse(w, http.StatusBadRequest,
+		foundation.ErrorResponse(w, r, http.StatusBadRequest,
 			errors.New("empty login data"), foundation.InvalidRequest)
 		return
 	}
-	err := validation.ValidateEmail(email)
+	user, err := h.Helper.UserCredentialsInDB(r.Context(), store.NormalizeEmail(email), password)
 	if err != nil {
-		h.Logger.Printf("invalid request, username is invalid: %v", err)
-		foundation.ErrorResponse(w, http.StatusBadRequest,
+		foundation.ErrorResponse(w, r, http.StatusBadRequest,
 			err, foundation.InvalidRequest)
 		return
 	}
-	helper := business.NewHelper(h.Store, h.Authenticator, h.Logger)
-	user, err := helper.UserCredentialsInDB(r.Context(), email, password)
+
+	orgID, err := h.Helper.DefaultOrganization(r.Context(), user.ID)
 	if err != nil {
-		foundation.ErrorResponse(w, http.StatusBadRequest,
-			err, foundation.InvalidRequest)
+		foundation.ErrorResponse(w, r, http.StatusInternalServerError,
+			err, foundation.DatabaseError)
 		return
 	}
 
-	token, err := helper.ManageToken(r.Context(), h.TokenConfig, user.ID)
+	token, err := h.Helper.ManageToken(r.Context(), h.TokenConfig, user.ID, orgID)
 	if err != nil {
-		foundation.ErrorResponse(w, http.StatusInternalServerError,
+		foundation.ErrorResponse(w, r, http.StatusInternalServerError,
 			errTokenGeneration, foundation.TokenError)
 		return
 	}
+	h.Helper.Logins.Succeeded(r.Context(), user)
 
 	w.Header().Set("Content-Type", "application/json")
 	err = json.NewEncoder(w).Encode(token)
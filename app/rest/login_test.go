@@ -53,7 +53,8 @@ func TestLogin(t *testing.T) {
 		{
 			name:     "invalid email",
 			request:  loginRequest(t, "invalid", "pass"),
-			response: expectedResponse(t, "invalid email"),
+			response: expectedResponse(t, "email not found"),
+			store:    &mocks.Store{},
 		},
 		{
 			name:     "login DB error",
@@ -82,7 +83,7 @@ func TestLogin(t *testing.T) {
 			request: loginRequest(t, testEmail, testPassword),
 			response: &foundation.Response{
 				Status:    http.StatusInternalServerError,
-				Message:   errTokenGeneration.Error(),
+				Message:   "internal error",
 				ErrorCode: foundation.TokenError,
 			},
 			store: &mocks.Store{
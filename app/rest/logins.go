@@ -0,0 +1,90 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+	"github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+// Logins godoc
+//
+//	@Summary			My login history
+//	@Description		Page through the logins of the logged-in user, successful or not, newest first, pass next_cursor as page_token to read the next page
+//	@Tags				User
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Param				page_size	query		int		false	"Logins in a page, at most 100"	default(20)
+//	@Param				page_token	query		string	false	"next_cursor of the previous page"
+//	@Success			200			{object}	store.LoginAttemptPage
+//	@Failure			400			{object}	foundation.Response
+//	@Failure			401			{object}	foundation.Response
+//	@Failure			404			{object}	foundation.Response
+//	@Failure			500			{object}	foundation.Response
+//	@Router				/v1/me/logins [get]
+//	@DeprecatedRouter	/user/logins [get]
+func (h *Handler) Logins(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserClaimsKey).(*store.Claims)
+	if !ok || claims == nil {
+		foundation.ErrorResponse(w, r, http.StatusUnauthorized, authz.ErrUnauthenticated, foundation.UnAuthorised)
+		return
+	}
+
+	h.logins(w, r, claims.Subject)
+}
+
+// UserLogins godoc
+//
+//	@Summary			User login history
+//	@Description		Page through the logins of a user of the caller's organization, successful or not, newest first, pass next_cursor as page_token to read the next page, requires the users:read permission
+//	@Tags				Admin
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Param				userID		path		string	true	"User ID"
+//	@Param				page_size	query		int		false	"Logins in a page, at most 100"	default(20)
+//	@Param				page_token	query		string	false	"next_cursor of the previous page"
+//	@Success			200			{object}	store.LoginAttemptPage
+//	@Failure			400			{object}	foundation.Response
+//	@Failure			401			{object}	foundation.Response
+//	@Failure			403			{object}	foundation.Response
+//	@Failure			404			{object}	foundation.Response
+//	@Failure			500			{object}	foundation.Response
+//	@Router				/v1/users/{userID}/logins [get]
+//	@DeprecatedRouter	/admin/users/{userID}/logins [get]
+func (h *Handler) UserLogins(w http.ResponseWriter, r *http.Request) {
+	h.logins(w, r, chi.URLParam(r, "userID"))
+}
+
+// logins writes a page of the login history of userID.
+func (h *Handler) logins(w http.ResponseWriter, r *http.Request, userID string) {
+	q := r.URL.Query()
+	first, err := pageSize(q)
+	if err != nil {
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+		return
+	}
+
+	page, err := h.Store.ListLoginAttempts(r.Context(), userID, store.LoginHistoryOptions{
+		First: first,
+		After: q.Get("page_token"),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrUserNotFound):
+			foundation.ErrorResponse(w, r, http.StatusNotFound, err, foundation.UserDoNotExist)
+		case errors.Is(err, store.ErrInvalidCursor):
+			foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+		default:
+			h.Logger.Errorf("failed to list logins of %s: %v", userID, err)
+			foundation.ErrorResponse(w, r, http.StatusInternalServerError, err, foundation.DatabaseError)
+		}
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, page)
+}
@@ -0,0 +1,121 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+func TestHandlerLogins(t *testing.T) {
+	attempt := &store.LoginAttempt{ID: "attempt-1", UserID: "user-1", IP: "203.0.113.7", Success: true}
+	scenarios := []struct {
+		name           string
+		query          string
+		claims         *store.Claims
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+		expectedOpts   *store.LoginHistoryOptions
+	}{
+		{
+			name:           "no claims",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   foundation.UnAuthorised,
+		},
+		{
+			name:           "invalid page size",
+			query:          "page_size=many",
+			claims:         profileClaims,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "invalid cursor",
+			claims:         profileClaims,
+			store:          &mocks.Store{Error: store.ErrInvalidCursor},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "user not found",
+			claims:         profileClaims,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.UserDoNotExist,
+		},
+		{
+			name:           "database error",
+			claims:         profileClaims,
+			store:          &mocks.Store{Error: errors.New("db error")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   foundation.DatabaseError,
+		},
+		{
+			name:   "listed",
+			query:  "page_size=5&page_token=abc",
+			claims: profileClaims,
+			store: &mocks.Store{
+				User:          &store.User{ID: "user-1"},
+				LoginAttempts: []*store.LoginAttempt{attempt},
+			},
+			expectedStatus: http.StatusOK,
+			expectedOpts:   &store.LoginHistoryOptions{First: 5, After: "abc"},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			w := httptest.NewRecorder()
+			handler.Logins(w, privacyRequest(http.MethodGet, "/user/logins?"+sc.query, "", sc.claims))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Equal(t, sc.expectedOpts, sc.store.LoginHistoryWith)
+			assert.Contains(t, w.Body.String(), `"id":"attempt-1"`)
+		})
+	}
+}
+
+func TestHandlerUserLogins(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		store          *mocks.Store
+		expectedStatus int
+	}{
+		{
+			name:           "user not found",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "listed",
+			store:          &mocks.Store{User: &store.User{ID: "user-2"}},
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			w := httptest.NewRecorder()
+			handler.UserLogins(w, privacyRequest(http.MethodGet, "/admin/users/user-2/logins", "user-2", profileClaims))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+	"github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+var errInvalidOrganizationID = errors.New("invalid organizationID in request")
+
+// SwitchOrganization godoc
+//
+//	@Summary			Switch organization
+//	@Description		Issue a token for another organization the user is a member of
+//	@Tags				User
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Param				organizationID	path		string	true	"Organization ID"
+//	@Success			200				{object}	store.Token
+//	@Failure			400				{object}	foundation.Response
+//	@Failure			401				{object}	foundation.Response
+//	@Failure			403				{object}	foundation.Response
+//	@Failure			404				{object}	foundation.Response
+//	@Router				/v1/organizations/{organizationID}/switch [post]
+//	@DeprecatedRouter	/user/organizations/{organizationID}/switch [post]
+func (h *Handler) SwitchOrganization(w http.ResponseWriter, r *http.Request) {
+	orgID := chi.URLParam(r, "organizationID")
+	if orgID == "" {
+		foundation.ErrorResponse(w, r, http.StatusBadRequest,
+			errInvalidOrganizationID, foundation.InvalidRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(middleware.UserClaimsKey).(*store.Claims)
+	if !ok || claims == nil {
+		foundation.ErrorResponse(w, r, http.StatusUnauthorized, authz.ErrUnauthenticated, foundation.UnAuthorised)
+		return
+	}
+
+	token, err := h.Helper.SwitchOrganization(r.Context(), h.TokenConfig, claims.Subject, orgID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrOrganizationNotFound):
+			foundation.ErrorResponse(w, r, http.StatusNotFound, err, foundation.InvalidRequest)
+		case errors.Is(err, authz.ErrForbidden):
+			foundation.ErrorResponse(w, r, http.StatusForbidden, err, foundation.Forbidden)
+		default:
+			h.Logger.Errorf("failed to switch organization: %v", err)
+			foundation.ErrorResponse(w, r, http.StatusInternalServerError,
+				errTokenGeneration, foundation.TokenError)
+		}
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, token)
+}
+
+// OrganizationRequest has the name of a new organization.
+type OrganizationRequest struct {
+	Name string `json:"name"`
+}
+
+// ListOrganizations godoc
+//
+//	@Summary		List my organizations
+//	@Description	List the organizations the logged-in user is a member of
+//	@Tags			Organization
+//	@Security		ApiKeyAuth
+//	@Produce		json
+//	@Success		200	{array}		store.Organization
+//	@Failure		401	{object}	foundation.Response
+//	@Failure		500	{object}	foundation.Response
+//	@Router			/v1/organizations [get]
+func (h *Handler) ListOrganizations(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserClaimsKey).(*store.Claims)
+	if !ok || claims == nil {
+		foundation.ErrorResponse(w, r, http.StatusUnauthorized, authz.ErrUnauthenticated, foundation.UnAuthorised)
+		return
+	}
+
+	organizations, err := h.Store.ListOrganizations(r.Context(), claims.Subject)
+	if err != nil {
+		h.classifiedError(w, r, err)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, organizations)
+}
+
+// CreateOrganization godoc
+//
+//	@Summary		Create an organization
+//	@Description	Create an organization the logged-in user owns, switch to it for a token of the organization
+//	@Tags			Organization
+//	@Security		ApiKeyAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			organization	body		OrganizationRequest	true	"Name of the organization"
+//	@Success		201				{object}	store.Organization
+//	@Failure		400				{object}	foundation.Response
+//	@Failure		401				{object}	foundation.Response
+//	@Failure		500				{object}	foundation.Response
+//	@Router			/v1/organizations [post]
+func (h *Handler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	req := &OrganizationRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		h.Logger.Errorf("invalid data in request: %v", err)
+
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(middleware.UserClaimsKey).(*store.Claims)
+	if !ok || claims == nil {
+		foundation.ErrorResponse(w, r, http.StatusUnauthorized, authz.ErrUnauthenticated, foundation.UnAuthorised)
+		return
+	}
+
+	h.Logger.Infof("user %s creating organization %s", claims.Subject, req.Name)
+	org, err := h.Store.CreateOrganization(r.Context(), req.Name, claims.Subject)
+	if err != nil {
+		h.classifiedError(w, r, err)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusCreated, org)
+}
+
+// RemoveMember godoc
+//
+//	@Summary		Remove a member
+//	@Description	Remove a user from the caller's organization, requires the users:write permission
+//	@Tags			Organization
+//	@Security		ApiKeyAuth
+//	@Produce		json
+//	@Param			userID	path		string	true	"User ID"
+//	@Success		204		{string}	string	"No Content"
+//	@Failure		401		{object}	foundation.Response
+//	@Failure		403		{object}	foundation.Response
+//	@Failure		404		{object}	foundation.Response
+//	@Failure		500		{object}	foundation.Response
+//	@Router			/v1/members/{userID} [delete]
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := store.TenantFromContext(r.Context())
+	if !ok || tenant.OrganizationID == "" {
+		foundation.ErrorResponse(w, r, http.StatusForbidden, authz.ErrForbidden, foundation.Forbidden)
+		return
+	}
+
+	userID := chi.URLParam(r, "userID")
+	h.Logger.Infof("removing user %s from organization %s", userID, tenant.OrganizationID)
+	if err := h.Store.RemoveMember(r.Context(), tenant.OrganizationID, userID); err != nil {
+		h.classifiedError(w, r, err)
+		return
+	}
+
+	_ = foundation.JSONResponse(w, http.StatusNoContent, "", "")
+}
@@ -0,0 +1,198 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+	"github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+func TestHandlerSwitchOrganization(t *testing.T) {
+	claims := &store.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-123"}, OrgID: "org-1"}
+	member := &mocks.Store{
+		User:          &store.User{ID: "user-123", Roles: []string{"user"}},
+		Organizations: []*store.Organization{{ID: "org-1"}, {ID: "org-2"}},
+	}
+	scenarios := []struct {
+		name           string
+		orgID          string
+		claims         *store.Claims
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "no id",
+			claims:         claims,
+			store:          member,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "no claims",
+			orgID:          "org-2",
+			store:          member,
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   foundation.UnAuthorised,
+		},
+		{
+			name:           "unknown organization",
+			orgID:          "org-3",
+			claims:         claims,
+			store:          member,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "success",
+			orgID:          "org-2",
+			claims:         claims,
+			store:          member,
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler := NewHandler(sc.store, &mocks.Authenticator{Token: &store.TokenRecord{
+				Token:  "org-2-token",
+				Expiry: time.Now().Add(time.Hour),
+				TTL:    "3600",
+			}}, &store.TokenConfig{}, logrus.New())
+
+			r := httptest.NewRequest(http.MethodPost, "/user/organizations/{organizationID}/switch", nil)
+			routeContext := chi.NewRouteContext()
+			routeContext.URLParams.Add("organizationID", sc.orgID)
+			ctx := context.WithValue(r.Context(), chi.RouteCtxKey, routeContext)
+			if sc.claims != nil {
+				ctx = context.WithValue(ctx, middleware.UserClaimsKey, sc.claims)
+			}
+
+			handler.SwitchOrganization(w, r.WithContext(ctx))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+			} else {
+				assert.Contains(t, w.Body.String(), "org-2-token")
+			}
+		})
+	}
+}
+
+func TestHandlerListOrganizations(t *testing.T) {
+	handler := NewHandler(&mocks.Store{Organizations: []*store.Organization{{ID: "org-1", Name: "Acme"}}},
+		&mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+	w := httptest.NewRecorder()
+	handler.ListOrganizations(w, webhookRequest(http.MethodGet, "/v1/organizations", "", "", "", profileClaims))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"Acme"`)
+}
+
+func TestHandlerCreateOrganization(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		body           string
+		claims         *store.Claims
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "invalid json",
+			body:           "{",
+			claims:         profileClaims,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "no claims",
+			body:           `{"name":"Acme"}`,
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   foundation.UnAuthorised,
+		},
+		{
+			name:           "created",
+			body:           `{"name":"Acme"}`,
+			claims:         profileClaims,
+			expectedStatus: http.StatusCreated,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(&mocks.Store{}, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			w := httptest.NewRecorder()
+			handler.CreateOrganization(w, webhookRequest(http.MethodPost, "/v1/organizations", sc.body, "", "", sc.claims))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Contains(t, w.Body.String(), `"created_by":"user-123"`)
+		})
+	}
+}
+
+func TestHandlerRemoveMember(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		tenant         *store.Tenant
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "no organization",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   foundation.Forbidden,
+		},
+		{
+			name:           "not a member",
+			tenant:         &store.Tenant{OrganizationID: "org-1"},
+			store:          &mocks.Store{Error: store.ErrUserNotFound},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.UserDoNotExist,
+		},
+		{
+			name:           "removed",
+			tenant:         &store.Tenant{OrganizationID: "org-1"},
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusNoContent,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			r := webhookRequest(http.MethodDelete, "/v1/members/user-1", "", "userID", "user-1", nil)
+			if sc.tenant != nil {
+				r = r.WithContext(store.WithTenant(r.Context(), *sc.tenant))
+			}
+			w := httptest.NewRecorder()
+			handler.RemoveMember(w, r)
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+	"github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+// Export godoc
+//
+//	@Summary			Export my data
+//	@Description		Download everything stored about the logged-in user as JSON
+//	@Tags				User
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Success			200	{object}	store.UserExport
+//	@Failure			401	{object}	foundation.Response
+//	@Failure			404	{object}	foundation.Response
+//	@Failure			500	{object}	foundation.Response
+//	@Router				/v1/me/export [get]
+//	@DeprecatedRouter	/user/export [get]
+func (h *Handler) Export(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserClaimsKey).(*store.Claims)
+	if !ok || claims == nil {
+		foundation.ErrorResponse(w, r, http.StatusUnauthorized, authz.ErrUnauthenticated, foundation.UnAuthorised)
+		return
+	}
+
+	h.export(w, r, claims.Subject, claims.Subject)
+}
+
+// ExportUser godoc
+//
+//	@Summary			Export user data
+//	@Description		Download everything stored about a user of the caller's organization in it as JSON to answer a subject access request, requires the users:read permission. Email changes and login attempts of the account are only exported to platform administrators
+//	@Tags				Admin
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Param				userID	path		string	true	"User ID"
+//	@Success			200		{object}	store.UserExport
+//	@Failure			401		{object}	foundation.Response
+//	@Failure			403		{object}	foundation.Response
+//	@Failure			404		{object}	foundation.Response
+//	@Failure			500		{object}	foundation.Response
+//	@Router				/v1/users/{userID}/export [get]
+//	@DeprecatedRouter	/admin/users/{userID}/export [get]
+func (h *Handler) ExportUser(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserClaimsKey).(*store.Claims)
+	if !ok || claims == nil {
+		foundation.ErrorResponse(w, r, http.StatusUnauthorized, authz.ErrUnauthenticated, foundation.UnAuthorised)
+		return
+	}
+
+	h.export(w, r, chi.URLParam(r, "userID"), claims.Subject)
+}
+
+// export writes the export of userID as a JSON attachment.
+func (h *Handler) export(w http.ResponseWriter, r *http.Request, userID, exportedBy string) {
+	export, err := h.Store.ExportUser(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			foundation.ErrorResponse(w, r, http.StatusNotFound, err, foundation.UserDoNotExist)
+			return
+		}
+		h.Logger.Errorf("failed to export user %s: %v", userID, err)
+		foundation.ErrorResponse(w, r, http.StatusInternalServerError, err, foundation.DatabaseError)
+		return
+	}
+
+	h.Logger.Infof("data of user %s exported by %s", userID, exportedBy)
+	w.Header().Set("Content-Disposition", `attachment; filename="user-`+userID+`.json"`)
+	_ = foundation.Resource(w, http.StatusOK, export)
+}
+
+// EraseUser godoc
+//
+//	@Summary			Erase user data
+//	@Description		Erase the personal data of a user. The user is anonymized and deleted for good, its sessions and email changes are removed and the erasure is recorded. The account is shared by every organization of the user, so only platform administrators can erase it, organization admins remove the user from the organization instead. Requires the users:delete permission
+//	@Tags				Admin
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Param				userID	path		string	true	"User ID"
+//	@Success			200		{object}	store.Erasure
+//	@Failure			401		{object}	foundation.Response
+//	@Failure			403		{object}	foundation.Response
+//	@Failure			404		{object}	foundation.Response
+//	@Failure			500		{object}	foundation.Response
+//	@Router				/v1/users/{userID}/erase [post]
+//	@DeprecatedRouter	/admin/users/{userID}/erase [post]
+func (h *Handler) EraseUser(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserClaimsKey).(*store.Claims)
+	if !ok || claims == nil {
+		foundation.ErrorResponse(w, r, http.StatusUnauthorized, authz.ErrUnauthenticated, foundation.UnAuthorised)
+		return
+	}
+
+	userID := chi.URLParam(r, "userID")
+	erasure, err := h.Store.EraseUser(r.Context(), userID, claims.Subject)
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			foundation.ErrorResponse(w, r, http.StatusNotFound, err, foundation.UserDoNotExist)
+			return
+		}
+		if errors.Is(err, store.ErrPlatformOnly) {
+			foundation.ErrorResponse(w, r, http.StatusForbidden, err, foundation.Forbidden)
+			return
+		}
+		h.Logger.Errorf("failed to erase user %s: %v", userID, err)
+		foundation.ErrorResponse(w, r, http.StatusInternalServerError, err, foundation.DatabaseError)
+		return
+	}
+
+	h.Logger.Infof("user %s erased by %s", userID, claims.Subject)
+	_ = foundation.Resource(w, http.StatusOK, erasure)
+}
@@ -0,0 +1,161 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+	"github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+// privacyRequest returns a request for userID made with claims.
+func privacyRequest(method, path, userID string, claims *store.Claims) *http.Request {
+	r := httptest.NewRequest(method, path, nil)
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("userID", userID)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, routeContext)
+	if claims != nil {
+		ctx = context.WithValue(ctx, middleware.UserClaimsKey, claims)
+	}
+	return r.WithContext(ctx)
+}
+
+func TestHandlerExport(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		claims         *store.Claims
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "no claims",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   foundation.UnAuthorised,
+		},
+		{
+			name:           "user not found",
+			claims:         profileClaims,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.UserDoNotExist,
+		},
+		{
+			name:           "database error",
+			claims:         profileClaims,
+			store:          &mocks.Store{Error: errors.New("db error")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   foundation.DatabaseError,
+		},
+		{
+			name:           "exported",
+			claims:         profileClaims,
+			store:          &mocks.Store{User: &store.User{ID: "user-123", Email: "jane@example.com"}},
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			w := httptest.NewRecorder()
+			handler.Export(w, privacyRequest(http.MethodGet, "/user/export", "", sc.claims))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Equal(t, `attachment; filename="user-user-123.json"`, w.Header().Get("Content-Disposition"))
+			assert.Contains(t, w.Body.String(), `"profile"`)
+			assert.Contains(t, w.Body.String(), "jane@example.com")
+		})
+	}
+}
+
+func TestHandlerExportUser(t *testing.T) {
+	handler := NewHandler(&mocks.Store{User: &store.User{ID: "user-456", Email: "john@example.com"}},
+		&mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+	w := httptest.NewRecorder()
+	handler.ExportUser(w, privacyRequest(http.MethodGet, "/admin/users/user-456/export", "user-456", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ExportUser(w, privacyRequest(http.MethodGet, "/admin/users/user-456/export", "user-456", profileClaims))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename="user-user-456.json"`, w.Header().Get("Content-Disposition"))
+	assert.Contains(t, w.Body.String(), "john@example.com")
+}
+
+func TestHandlerEraseUser(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		claims         *store.Claims
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "no claims",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   foundation.UnAuthorised,
+		},
+		{
+			name:           "user not found",
+			claims:         profileClaims,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.UserDoNotExist,
+		},
+		{
+			name:           "database error",
+			claims:         profileClaims,
+			store:          &mocks.Store{Error: errors.New("db error")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   foundation.DatabaseError,
+		},
+		{
+			name:           "organization admin",
+			claims:         profileClaims,
+			store:          &mocks.Store{Error: store.ErrPlatformOnly},
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   foundation.Forbidden,
+		},
+		{
+			name:           "erased",
+			claims:         profileClaims,
+			store:          &mocks.Store{User: &store.User{ID: "user-456"}},
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			w := httptest.NewRecorder()
+			handler.EraseUser(w, privacyRequest(http.MethodPost, "/admin/users/user-456/erase", "user-456", sc.claims))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Contains(t, w.Body.String(), `"user_id":"user-456"`)
+			assert.Contains(t, w.Body.String(), `"erased_by":"user-123"`)
+		})
+	}
+}
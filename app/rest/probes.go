@@ -9,7 +9,14 @@ import (
 	"github.com/riyadennis/identity-server/foundation"
 )
 
-// @Router			/liveness [get].
+// Liveness godoc
+//
+//	@Summary		Liveness probe
+//	@Description	Returns liveness and k8s deployment info
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	map[string]interface{}
+//	@Router			/liveness [get]
 func Liveness(w http.ResponseWriter, _ *http.Request) {
 	hostName, err := os.Hostname()
 	if err != nil {
@@ -36,11 +43,19 @@ func Liveness(w http.ResponseWriter, _ *http.Request) {
 	_ = json.NewEncoder(w).Encode(data)
 }
 
-// @Router			/readiness [get].
+// Ready godoc
+//
+//	@Summary		Readiness probe
+//	@Description	Checks if API is ready for traffic (DB available)
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	foundation.Response
+//	@Failure		500	{object}	foundation.Response
+//	@Router			/readiness [get]
 func Ready(store store.Store) http.HandlerFunc {
-	return func(w http.ResponseWriter, _ *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
 		if err := store.Ping(); err != nil {
-			foundation.ErrorResponse(w, http.StatusInternalServerError, err, foundation.DatabaseError)
+			foundation.ErrorResponse(w, r, http.StatusInternalServerError, err, foundation.DatabaseError)
 			return
 		}
 
@@ -0,0 +1,97 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/business/validation"
+	"github.com/riyadennis/identity-server/foundation"
+	"github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+// Profile godoc
+//
+//	@Summary			Get profile
+//	@Description		Get the profile of the logged-in user
+//	@Tags				User
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Success			200	{object}	store.User
+//	@Failure			401	{object}	foundation.Response
+//	@Failure			404	{object}	foundation.Response
+//	@Failure			500	{object}	foundation.Response
+//	@Router				/v1/me [get]
+//	@DeprecatedRouter	/user/profile [get]
+func (h *Handler) Profile(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.UserClaimsKey).(*store.Claims)
+	if !ok || claims == nil {
+		foundation.ErrorResponse(w, r, http.StatusUnauthorized, authz.ErrUnauthenticated, foundation.UnAuthorised)
+		return
+	}
+
+	user, err := h.Store.Retrieve(r.Context(), claims.Subject)
+	if err != nil {
+		h.Logger.Errorf("failed to retrieve profile: %v", err)
+		foundation.ErrorResponse(w, r, http.StatusInternalServerError, err, foundation.DatabaseError)
+		return
+	}
+	if user == nil {
+		foundation.ErrorResponse(w, r, http.StatusNotFound, store.ErrUserNotFound, foundation.UserDoNotExist)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, user)
+}
+
+// UpdateProfile godoc
+//
+//	@Summary			Update profile
+//	@Description		Update the profile of the logged-in user, only the fields sent are changed and an empty value clears locale, timezone and picture_url
+//	@Tags				User
+//	@Security			ApiKeyAuth
+//	@Accept				json
+//	@Produce			json
+//	@Param				profile	body		store.ProfileUpdate	true	"Profile details to change"
+//	@Success			200		{object}	store.User
+//	@Failure			400		{object}	foundation.Response
+//	@Failure			401		{object}	foundation.Response
+//	@Failure			404		{object}	foundation.Response
+//	@Failure			500		{object}	foundation.Response
+//	@Router				/v1/me [patch]
+//	@DeprecatedRouter	/user/profile [patch]
+func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	update := &store.ProfileUpdate{}
+	if err := json.NewDecoder(r.Body).Decode(update); err != nil {
+		h.Logger.Errorf("invalid data in request: %v", err)
+
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(middleware.UserClaimsKey).(*store.Claims)
+	if !ok || claims == nil {
+		foundation.ErrorResponse(w, r, http.StatusUnauthorized, authz.ErrUnauthenticated, foundation.UnAuthorised)
+		return
+	}
+
+	if err := validation.ValidateProfile(update); err != nil {
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.ValidationFailed)
+		return
+	}
+
+	user, err := h.Store.UpdateProfile(r.Context(), claims.Subject, update)
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			foundation.ErrorResponse(w, r, http.StatusNotFound, err, foundation.UserDoNotExist)
+			return
+		}
+		h.Logger.Errorf("failed to update profile: %v", err)
+		foundation.ErrorResponse(w, r, http.StatusInternalServerError, err, foundation.DatabaseError)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, user)
+}
@@ -0,0 +1,149 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+	"github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+var profileClaims = &store.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-123"}, OrgID: "org-1"}
+
+func TestHandlerProfile(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		claims         *store.Claims
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "no claims",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   foundation.UnAuthorised,
+		},
+		{
+			name:           "user not found",
+			claims:         profileClaims,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.UserDoNotExist,
+		},
+		{
+			name:           "store error",
+			claims:         profileClaims,
+			store:          &mocks.Store{Error: errors.New("db error")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   foundation.DatabaseError,
+		},
+		{
+			name:           "success",
+			claims:         profileClaims,
+			store:          &mocks.Store{User: &store.User{ID: "user-123", Locale: "en-GB"}},
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			r := httptest.NewRequest(http.MethodGet, "/user/profile", nil)
+			if sc.claims != nil {
+				r = r.WithContext(context.WithValue(r.Context(), middleware.UserClaimsKey, sc.claims))
+			}
+			w := httptest.NewRecorder()
+			handler.Profile(w, r)
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Contains(t, w.Body.String(), `"locale":"en-GB"`)
+		})
+	}
+}
+
+func TestHandlerUpdateProfile(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		body           string
+		claims         *store.Claims
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "invalid body",
+			body:           "{",
+			claims:         profileClaims,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "no claims",
+			body:           `{"locale":"en-GB"}`,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   foundation.UnAuthorised,
+		},
+		{
+			name:           "invalid timezone",
+			body:           `{"timezone":"Mars/Olympus"}`,
+			claims:         profileClaims,
+			store:          &mocks.Store{User: &store.User{ID: "user-123"}},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.ValidationFailed,
+		},
+		{
+			name:           "user not found",
+			body:           `{"locale":"en-GB"}`,
+			claims:         profileClaims,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.UserDoNotExist,
+		},
+		{
+			name:           "updated",
+			body:           `{"locale":"en-GB","timezone":"Europe/London"}`,
+			claims:         profileClaims,
+			store:          &mocks.Store{User: &store.User{ID: "user-123", FirstName: "Jane"}},
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			r := httptest.NewRequest(http.MethodPatch, "/user/profile", strings.NewReader(sc.body))
+			if sc.claims != nil {
+				r = r.WithContext(context.WithValue(r.Context(), middleware.UserClaimsKey, sc.claims))
+			}
+			w := httptest.NewRecorder()
+			handler.UpdateProfile(w, r)
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Contains(t, w.Body.String(), `"first_name":"Jane"`)
+			assert.Contains(t, w.Body.String(), `"timezone":"Europe/London"`)
+		})
+	}
+}
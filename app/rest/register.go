@@ -2,12 +2,12 @@ package rest
 
 import (
 	"encoding/json"
-	"errors"
 	"net/http"
+	"os"
 
 	"github.com/riyadennis/identity-server/business"
+	"github.com/riyadennis/identity-server/business/authz"
 	"github.com/riyadennis/identity-server/business/store"
-	"github.com/riyadennis/identity-server/business/validation"
 	"github.com/riyadennis/identity-server/foundation"
 	"github.com/sirupsen/logrus"
 )
@@ -16,6 +16,13 @@ import (
 type Handler struct {
 	Store         store.Store
 	Authenticator store.Authenticator
+	Authorizer    *authz.Authorizer
+	Inviter       *business.Inviter
+	EmailChanger  *business.EmailChanger
+	Deleter       *business.Deleter
+	Webhooks      *business.Webhooks
+	Users         *business.Users
+	Helper        *business.Helper
 	Logger        *logrus.Logger
 	TokenConfig   *store.TokenConfig
 }
@@ -23,25 +30,37 @@ type Handler struct {
 func NewHandler(store store.Store, authenticator store.Authenticator,
 	tc *store.TokenConfig, logger *logrus.Logger,
 ) *Handler {
+	mailer := foundation.NewENVMailer(logger)
 	return &Handler{
 		Store:         store,
 		Authenticator: authenticator,
-		Logger:        logger,
-		TokenConfig:   tc,
+		Authorizer:    authz.NewAuthorizer(store, logger),
+		Inviter:       business.NewInviter(store, mailer, logger, os.Getenv("INVITATION_URL")),
+		EmailChanger: business.NewEmailChanger(store, authenticator, mailer, logger,
+			os.Getenv("EMAIL_CHANGE_URL")),
+		Deleter:     business.NewDeleter(store, logger, os.Getenv("DELETED_USER_RETENTION")),
+		Webhooks:    business.NewWebhooks(store, logger),
+		Users:       business.NewUsers(store, logger),
+		Helper:      business.NewHelper(store, authenticator, mailer, logger),
+		Logger:      logger,
+		TokenConfig: tc,
 	}
 }
 
-// Register @Summary     Endpoint to  Register a new user
+// Register godoc
 //
-//	@Description	Create a user with email and password
-//	@Tags			Auth
-//	@Accept			json
-//	@Produce		json
-//	@Param			user	body		store.User	true	"User registration data"
-//	@Success		201		{object}	store.User
-//	@Failure		400		{object}	foundation.Response
-//	@Failure		500		{object}	foundation.Response
-//	@Router			/register [post]
+//	@Summary			Register a new user
+//	@Description		Create a user with email and password
+//	@Tags				Auth
+//	@Accept				json
+//	@Produce			json
+//	@Param				user	body		store.User	true	"User registration data"
+//	@Success			201		{object}	store.User
+//	@Failure			400		{object}	foundation.Response
+//	@Failure			409		{object}	foundation.Response
+//	@Failure			500		{object}	foundation.Response
+//	@Router				/v1/register [post]
+//	@DeprecatedRouter	/register [post]
 func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	u := &store.User{}
 	decoder := json.NewDecoder(r.Body)
@@ -50,48 +69,19 @@ func (h *Handler) Register(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		h.Logger.Errorf("invalid data in request: %v", err)
 
-		foundation.ErrorResponse(w, http.StatusBadRequest, err, foundation.InvalidRequest)
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
 		return
 	}
 
-	err = validation.ValidateUser(u)
-	if err != nil {
-		h.Logger.Errorf("validation failed: %v", err)
-
-		foundation.ErrorResponse(w, http.StatusBadRequest, err, foundation.ValidationFailed)
-		return
-	}
-
-	userExists, err := h.Store.Read(r.Context(), u.Email)
-	if err != nil {
-		h.Logger.Errorf("failed to read from database: %v", err)
-
-		foundation.ErrorResponse(w, http.StatusBadRequest, err, foundation.ValidationFailed)
-		return
-	}
+	// roles are granted by administrators, never by the user registering.
+	u.Roles = nil
 
-	if userExists.Email == u.Email {
-		h.Logger.Errorf("email already exists: %#v", userExists.Email)
-
-		foundation.ErrorResponse(w, http.StatusBadRequest, errors.New("email already exists"), foundation.EmailAlreadyExists)
-		return
-	}
-
-	u.Password, err = business.EncryptPassword(u.Password)
+	resource, err := h.Users.Create(r.Context(), u, "")
 	if err != nil {
-		h.Logger.Errorf("password encryption failed: %v", err)
-
-		foundation.ErrorResponse(w, http.StatusInternalServerError, err, foundation.PassWordError)
+		h.classifiedError(w, r, err)
 		return
 	}
 
-	resource, err := h.Store.Insert(r.Context(), u)
-	if err != nil {
-		h.Logger.Errorf("failed to save user: %v", err)
-
-		foundation.ErrorResponse(w, http.StatusInternalServerError, err, foundation.DatabaseError)
-	}
-
 	resource.Password = "********"
 	_ = foundation.Resource(w, http.StatusCreated, resource)
 }
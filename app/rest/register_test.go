@@ -2,6 +2,7 @@ package rest
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -11,9 +12,11 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/riyadennis/identity-server/app/mocks"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/riyadennis/identity-server/business"
 	"github.com/riyadennis/identity-server/business/store"
@@ -35,7 +38,7 @@ func TestRegister(t *testing.T) {
 				Email:     "",
 			}),
 			expectedResponse: foundation.NewResponse(http.StatusBadRequest,
-				"missing email",
+				"missing email\nplease select terms",
 				foundation.ValidationFailed),
 		},
 		{
@@ -46,7 +49,7 @@ func TestRegister(t *testing.T) {
 				Email:     testEmail,
 			}),
 			expectedResponse: foundation.NewResponse(http.StatusBadRequest,
-				"missing first name",
+				"missing first name\nplease select terms",
 				foundation.ValidationFailed),
 		},
 		{
@@ -57,7 +60,7 @@ func TestRegister(t *testing.T) {
 				Email:     testEmail,
 			}),
 			expectedResponse: foundation.NewResponse(http.StatusBadRequest,
-				"missing last name",
+				"missing last name\nplease select terms",
 				foundation.ValidationFailed),
 		},
 		{
@@ -68,32 +71,31 @@ func TestRegister(t *testing.T) {
 				return registerPayLoad(t, u)
 			}(),
 			expectedResponse: foundation.NewResponse(http.StatusBadRequest,
-				"invalid email",
+				"invalid email: domain needs a top level domain",
 				foundation.ValidationFailed),
 		},
-		{
-			name: "error reading db",
-			req: func() *http.Request {
-				u := user(t)
-				u.Email = "joh@doe.com"
-				return registerPayLoad(t, u)
-			}(),
-			store:            &mocks.Store{Error: errors.New("error")},
-			expectedResponse: foundation.NewResponse(http.StatusBadRequest, "error", foundation.ValidationFailed),
-		},
 		{
 			name: "duplicate email",
 			req: func() *http.Request {
 				u := user(t)
-				u.Email = "joh@doe.com"
+				u.Email = "Joh@Doe.com"
 				return registerPayLoad(t, u)
 			}(),
-			store: &mocks.Store{User: &store.User{Email: "joh@doe.com"}},
+			store: &mocks.Store{Error: store.ErrDuplicateEmail},
 			expectedResponse: foundation.NewResponse(
-				http.StatusBadRequest,
+				http.StatusConflict,
 				"email already exists",
 				foundation.EmailAlreadyExists),
 		},
+		{
+			name:  "database error",
+			req:   registerPayLoad(t, user(t)),
+			store: &mocks.Store{Error: errors.New("connection refused")},
+			expectedResponse: foundation.NewResponse(
+				http.StatusInternalServerError,
+				"internal error",
+				foundation.InternalError),
+		},
 	}
 	logger := logrus.New()
 	for _, sc := range scenarios {
@@ -112,6 +114,31 @@ func TestRegister(t *testing.T) {
 	}
 }
 
+func TestRegister_Problem(t *testing.T) {
+	u := user(t)
+	u.Email = "joh@dom"
+	req := registerPayLoad(t, u)
+	req.Header.Set("Accept", foundation.ProblemContentType)
+	req = req.WithContext(context.WithValue(req.Context(), middleware.RequestIDKey, "req-1"))
+	w := httptest.NewRecorder()
+
+	NewHandler(&mocks.Store{}, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New()).Register(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, foundation.ProblemContentType, w.Header().Get("Content-Type"))
+	var problem foundation.Problem
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&problem))
+	assert.Equal(t, foundation.Problem{
+		Type:     "urn:identity-server:problem:validation-failure",
+		Title:    "Bad Request",
+		Status:   http.StatusBadRequest,
+		Detail:   "invalid email: domain needs a top level domain",
+		Instance: "req-1",
+		Code:     foundation.ValidationFailed,
+		Errors:   []foundation.FieldProblem{{Field: "email", Detail: "invalid email: domain needs a top level domain"}},
+	}, problem)
+}
+
 func registerPayLoad(t *testing.T, u *store.User) *http.Request {
 	var buff bytes.Buffer
 	err := json.NewEncoder(&buff).Encode(u)
@@ -0,0 +1,333 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+// RoleRequest has the built-in role to give a user, admin or user.
+type RoleRequest struct {
+	Role string `json:"role"`
+}
+
+// RoleResponse is the most privileged built-in role of a user.
+type RoleResponse struct {
+	UserID string `json:"user_id"`
+	Role   string `json:"role"`
+}
+
+// UserRolesResponse has every role assigned to a user.
+type UserRolesResponse struct {
+	UserID string   `json:"user_id"`
+	Roles  []string `json:"roles"`
+}
+
+// CreateRoleRequest has the name, description and permissions of a custom role.
+type CreateRoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// RolePermissionsRequest has the permissions that replace those of a role.
+type RolePermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+// UserRole godoc
+//
+//	@Summary		Get the role of a user
+//	@Description	Get the most privileged built-in role, admin or user, of a user of the caller's organization, requires the roles:read permission
+//	@Tags			Role
+//	@Security		ApiKeyAuth
+//	@Produce		json
+//	@Param			userID	path		string	true	"User ID"
+//	@Success		200		{object}	RoleResponse
+//	@Failure		401		{object}	foundation.Response
+//	@Failure		403		{object}	foundation.Response
+//	@Failure		404		{object}	foundation.Response
+//	@Failure		500		{object}	foundation.Response
+//	@Router			/v1/users/{userID}/role [get]
+func (h *Handler) UserRole(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+	role, err := h.Users.Role(r.Context(), userID)
+	if err != nil {
+		h.classifiedError(w, r, err)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, &RoleResponse{UserID: userID, Role: role})
+}
+
+// AssignRole godoc
+//
+//	@Summary		Assign a built-in role
+//	@Description	Make a user of the caller's organization an admin or a user, the built-in roles replace each other. Requires the roles:write permission
+//	@Tags			Role
+//	@Security		ApiKeyAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		string		true	"User ID"
+//	@Param			role	body		RoleRequest	true	"Built-in role, admin or user"
+//	@Success		200		{object}	RoleResponse
+//	@Failure		400		{object}	foundation.Response
+//	@Failure		401		{object}	foundation.Response
+//	@Failure		403		{object}	foundation.Response
+//	@Failure		404		{object}	foundation.Response
+//	@Failure		500		{object}	foundation.Response
+//	@Router			/v1/users/{userID}/role [put]
+func (h *Handler) AssignRole(w http.ResponseWriter, r *http.Request) {
+	req := &RoleRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		h.Logger.Errorf("invalid data in request: %v", err)
+
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+		return
+	}
+
+	userID := chi.URLParam(r, "userID")
+	h.Logger.Infof("assigning role %s to user %s", req.Role, userID)
+	if err := h.Users.AssignRole(r.Context(), userID, req.Role); err != nil {
+		h.classifiedError(w, r, err)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, &RoleResponse{UserID: userID, Role: req.Role})
+}
+
+// UserRoles godoc
+//
+//	@Summary		List the roles of a user
+//	@Description	List every role assigned to a user of the caller's organization, requires the roles:read permission
+//	@Tags			Role
+//	@Security		ApiKeyAuth
+//	@Produce		json
+//	@Param			userID	path		string	true	"User ID"
+//	@Success		200		{object}	UserRolesResponse
+//	@Failure		401		{object}	foundation.Response
+//	@Failure		403		{object}	foundation.Response
+//	@Failure		404		{object}	foundation.Response
+//	@Failure		500		{object}	foundation.Response
+//	@Router			/v1/users/{userID}/roles [get]
+func (h *Handler) UserRoles(w http.ResponseWriter, r *http.Request) {
+	h.userRoles(w, r, chi.URLParam(r, "userID"))
+}
+
+// GrantRole godoc
+//
+//	@Summary		Grant a role
+//	@Description	Assign a role to a user of the caller's organization alongside the roles they already have, requires the roles:write permission
+//	@Tags			Role
+//	@Security		ApiKeyAuth
+//	@Produce		json
+//	@Param			userID	path		string	true	"User ID"
+//	@Param			role	path		string	true	"Role name"
+//	@Success		200		{object}	UserRolesResponse
+//	@Failure		401		{object}	foundation.Response
+//	@Failure		403		{object}	foundation.Response
+//	@Failure		404		{object}	foundation.Response
+//	@Failure		500		{object}	foundation.Response
+//	@Router			/v1/users/{userID}/roles/{role} [put]
+func (h *Handler) GrantRole(w http.ResponseWriter, r *http.Request) {
+	userID, role := chi.URLParam(r, "userID"), chi.URLParam(r, "role")
+	h.Logger.Infof("granting role %s to user %s", role, userID)
+	if err := h.Store.AssignRole(r.Context(), userID, role); err != nil {
+		h.classifiedError(w, r, err)
+		return
+	}
+
+	h.userRoles(w, r, userID)
+}
+
+// RevokeRole godoc
+//
+//	@Summary		Revoke a role
+//	@Description	Take a role away from a user of the caller's organization, requires the roles:write permission
+//	@Tags			Role
+//	@Security		ApiKeyAuth
+//	@Produce		json
+//	@Param			userID	path		string	true	"User ID"
+//	@Param			role	path		string	true	"Role name"
+//	@Success		200		{object}	UserRolesResponse
+//	@Failure		401		{object}	foundation.Response
+//	@Failure		403		{object}	foundation.Response
+//	@Failure		404		{object}	foundation.Response
+//	@Failure		500		{object}	foundation.Response
+//	@Router			/v1/users/{userID}/roles/{role} [delete]
+func (h *Handler) RevokeRole(w http.ResponseWriter, r *http.Request) {
+	userID, role := chi.URLParam(r, "userID"), chi.URLParam(r, "role")
+	h.Logger.Infof("revoking role %s from user %s", role, userID)
+	if err := h.Store.RevokeRole(r.Context(), userID, role); err != nil {
+		h.classifiedError(w, r, err)
+		return
+	}
+
+	h.userRoles(w, r, userID)
+}
+
+// userRoles writes every role assigned to userID.
+func (h *Handler) userRoles(w http.ResponseWriter, r *http.Request, userID string) {
+	roles, err := h.Store.UserRoles(r.Context(), userID)
+	if err != nil {
+		h.classifiedError(w, r, err)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, &UserRolesResponse{UserID: userID, Roles: roles})
+}
+
+// ListRoles godoc
+//
+//	@Summary		List roles
+//	@Description	List the built-in roles and the custom roles of the caller's organization with their permissions, requires the roles:read permission
+//	@Tags			Role
+//	@Security		ApiKeyAuth
+//	@Produce		json
+//	@Success		200	{array}		store.Role
+//	@Failure		401	{object}	foundation.Response
+//	@Failure		403	{object}	foundation.Response
+//	@Failure		500	{object}	foundation.Response
+//	@Router			/v1/roles [get]
+func (h *Handler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.Store.ListRoles(r.Context())
+	if err != nil {
+		h.classifiedError(w, r, err)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, roles)
+}
+
+// CreateRole godoc
+//
+//	@Summary		Create a role
+//	@Description	Create a custom role of the caller's organization granting the permissions, requires the roles:write permission
+//	@Tags			Role
+//	@Security		ApiKeyAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			role	body		CreateRoleRequest	true	"Name, description and permissions of the role"
+//	@Success		201		{object}	store.Role
+//	@Failure		400		{object}	foundation.Response
+//	@Failure		401		{object}	foundation.Response
+//	@Failure		403		{object}	foundation.Response
+//	@Failure		404		{object}	foundation.Response
+//	@Failure		500		{object}	foundation.Response
+//	@Router			/v1/roles [post]
+func (h *Handler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	req := &CreateRoleRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		h.Logger.Errorf("invalid data in request: %v", err)
+
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+		return
+	}
+
+	h.Logger.Infof("creating role %s", req.Name)
+	role, err := h.Store.CreateRole(r.Context(), &store.Role{
+		Name:        req.Name,
+		Description: req.Description,
+		Permissions: req.Permissions,
+	})
+	if err != nil {
+		h.classifiedError(w, r, err)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusCreated, role)
+}
+
+// UpdateRolePermissions godoc
+//
+//	@Summary		Replace the permissions of a role
+//	@Description	Replace the permissions a custom role of the caller's organization grants, built-in roles cannot be changed. Requires the roles:write permission
+//	@Tags			Role
+//	@Security		ApiKeyAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			role		path		string					true	"Role name"
+//	@Param			permissions	body		RolePermissionsRequest	true	"Permissions of the role"
+//	@Success		200			{object}	store.Role
+//	@Failure		400			{object}	foundation.Response
+//	@Failure		401			{object}	foundation.Response
+//	@Failure		403			{object}	foundation.Response
+//	@Failure		404			{object}	foundation.Response
+//	@Failure		500			{object}	foundation.Response
+//	@Router			/v1/roles/{role}/permissions [put]
+func (h *Handler) UpdateRolePermissions(w http.ResponseWriter, r *http.Request) {
+	req := &RolePermissionsRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		h.Logger.Errorf("invalid data in request: %v", err)
+
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+		return
+	}
+
+	name := chi.URLParam(r, "role")
+	h.Logger.Infof("updating permissions of role %s", name)
+	if err := h.Store.SetRolePermissions(r.Context(), name, req.Permissions); err != nil {
+		h.classifiedError(w, r, err)
+		return
+	}
+
+	role, err := h.Store.RetrieveRole(r.Context(), name)
+	if err != nil {
+		h.classifiedError(w, r, err)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, role)
+}
+
+// DeleteRole godoc
+//
+//	@Summary		Delete a role
+//	@Description	Delete a custom role of the caller's organization, it is taken away from every user. Built-in roles cannot be deleted. Requires the roles:write permission
+//	@Tags			Role
+//	@Security		ApiKeyAuth
+//	@Produce		json
+//	@Param			role	path		string	true	"Role name"
+//	@Success		204		{string}	string	"No Content"
+//	@Failure		400		{object}	foundation.Response
+//	@Failure		401		{object}	foundation.Response
+//	@Failure		403		{object}	foundation.Response
+//	@Failure		404		{object}	foundation.Response
+//	@Failure		500		{object}	foundation.Response
+//	@Router			/v1/roles/{role} [delete]
+func (h *Handler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "role")
+	h.Logger.Infof("deleting role %s", name)
+	if err := h.Store.DeleteRole(r.Context(), name); err != nil {
+		h.classifiedError(w, r, err)
+		return
+	}
+
+	_ = foundation.JSONResponse(w, http.StatusNoContent, "", "")
+}
+
+// ListPermissions godoc
+//
+//	@Summary		List permissions
+//	@Description	List the permissions roles can grant, requires the roles:read permission
+//	@Tags			Role
+//	@Security		ApiKeyAuth
+//	@Produce		json
+//	@Success		200	{array}		store.Permission
+//	@Failure		401	{object}	foundation.Response
+//	@Failure		403	{object}	foundation.Response
+//	@Failure		500	{object}	foundation.Response
+//	@Router			/v1/permissions [get]
+func (h *Handler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	permissions, err := h.Store.ListPermissions(r.Context())
+	if err != nil {
+		h.classifiedError(w, r, err)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, permissions)
+}
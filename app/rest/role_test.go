@@ -0,0 +1,295 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+func TestHandlerUserRole(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+		expectedBody   string
+	}{
+		{
+			name:           "not found",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.UserDoNotExist,
+		},
+		{
+			name:           "database error",
+			store:          &mocks.Store{Error: errors.New("db error")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   foundation.InternalError,
+		},
+		{
+			name:           "admin",
+			store:          &mocks.Store{User: &store.User{ID: "user-1", Roles: []string{"user", "admin"}}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"user_id":"user-1","role":"admin"}`,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			w := httptest.NewRecorder()
+			handler.UserRole(w, webhookRequest(http.MethodGet, "/v1/users/user-1/role", "", "userID", "user-1", nil))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.JSONEq(t, sc.expectedBody, w.Body.String())
+		})
+	}
+}
+
+func TestHandlerAssignRole(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		body           string
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "invalid json",
+			body:           "{",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "not built-in",
+			body:           `{"role":"support"}`,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "not found",
+			body:           `{"role":"admin"}`,
+			store:          &mocks.Store{Error: store.ErrUserNotFound},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.UserDoNotExist,
+		},
+		{
+			name:           "assigned",
+			body:           `{"role":"user"}`,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			w := httptest.NewRecorder()
+			handler.AssignRole(w, webhookRequest(http.MethodPut, "/v1/users/user-1/role", sc.body, "userID", "user-1", nil))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.JSONEq(t, `{"user_id":"user-1","role":"user"}`, w.Body.String())
+		})
+	}
+}
+
+func TestHandlerGrantRole(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "unknown role",
+			store:          &mocks.Store{Error: store.ErrRoleNotFound},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.RoleNotFound,
+		},
+		{
+			name:           "platform role",
+			store:          &mocks.Store{Error: store.ErrPlatformRole},
+			expectedStatus: http.StatusForbidden,
+			expectedCode:   foundation.Forbidden,
+		},
+		{
+			name:           "granted",
+			store:          &mocks.Store{User: &store.User{Roles: []string{"user", "support"}}},
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			r := webhookRequest(http.MethodPut, "/v1/users/user-1/roles/support", "", "userID", "user-1", nil)
+			chi.RouteContext(r.Context()).URLParams.Add("role", "support")
+			w := httptest.NewRecorder()
+			handler.GrantRole(w, r)
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.JSONEq(t, `{"user_id":"user-1","roles":["user","support"]}`, w.Body.String())
+		})
+	}
+}
+
+func TestHandlerRevokeRole(t *testing.T) {
+	handler := NewHandler(&mocks.Store{User: &store.User{Roles: []string{"user"}}},
+		&mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+	r := webhookRequest(http.MethodDelete, "/v1/users/user-1/roles/support", "", "userID", "user-1", nil)
+	chi.RouteContext(r.Context()).URLParams.Add("role", "support")
+	w := httptest.NewRecorder()
+	handler.RevokeRole(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"user_id":"user-1","roles":["user"]}`, w.Body.String())
+}
+
+func TestHandlerListRoles(t *testing.T) {
+	handler := NewHandler(&mocks.Store{RoleList: []*store.Role{{Name: "support", Permissions: []string{"users:read"}}}},
+		&mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+	w := httptest.NewRecorder()
+	handler.ListRoles(w, httptest.NewRequest(http.MethodGet, "/v1/roles", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"support"`)
+}
+
+func TestHandlerCreateRole(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		body           string
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "invalid json",
+			body:           "{",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "unknown permission",
+			body:           `{"name":"support","permissions":["users:fly"]}`,
+			store:          &mocks.Store{Error: store.ErrPermissionNotFound},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.PermissionNotFound,
+		},
+		{
+			name:           "created",
+			body:           `{"name":"support","description":"Helps users","permissions":["users:read"]}`,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusCreated,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			w := httptest.NewRecorder()
+			handler.CreateRole(w, webhookRequest(http.MethodPost, "/v1/roles", sc.body, "", "", nil))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Contains(t, w.Body.String(), `"description":"Helps users"`)
+		})
+	}
+}
+
+func TestHandlerUpdateRolePermissions(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "built-in role",
+			store:          &mocks.Store{Error: store.ErrBuiltInRole},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "unknown role",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.RoleNotFound,
+		},
+		{
+			name:           "updated",
+			store:          &mocks.Store{RoleList: []*store.Role{{Name: "support", Permissions: []string{"users:read"}}}},
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			w := httptest.NewRecorder()
+			handler.UpdateRolePermissions(w, webhookRequest(http.MethodPut, "/v1/roles/support/permissions",
+				`{"permissions":["users:read"]}`, "role", "support", nil))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Contains(t, w.Body.String(), `"name":"support"`)
+		})
+	}
+}
+
+func TestHandlerDeleteRole(t *testing.T) {
+	handler := NewHandler(&mocks.Store{}, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+	w := httptest.NewRecorder()
+	handler.DeleteRole(w, webhookRequest(http.MethodDelete, "/v1/roles/support", "", "role", "support", nil))
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestHandlerListPermissions(t *testing.T) {
+	handler := NewHandler(&mocks.Store{Permissions: []string{"users:read"}},
+		&mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+	w := httptest.NewRecorder()
+	handler.ListPermissions(w, httptest.NewRequest(http.MethodGet, "/v1/permissions", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"users:read"`)
+}
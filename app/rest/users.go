@@ -0,0 +1,213 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+	"github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+var errInvalidOrder = errors.New("order has to be asc or desc")
+
+// ListUsers godoc
+//
+//	@Summary			List users
+//	@Description		Page through the users of the caller's organization, pass next_cursor as page_token to read the next page, requires the users:read permission
+//	@Tags				Admin
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Param				page_size		query		int		false	"Users in a page, at most 100"	default(20)
+//	@Param				page_token		query		string	false	"next_cursor of the previous page"
+//	@Param				role			query		string	false	"Name of a role the users have been assigned"
+//	@Param				active			query		bool	false	"Only active or inactive users"
+//	@Param				company			query		string	false	"Company of the users"
+//	@Param				created_after	query		string	false	"RFC 3339 time the users registered at or after"
+//	@Param				created_before	query		string	false	"RFC 3339 time the users registered before"
+//	@Param				email_prefix	query		string	false	"Start of the email of the users"
+//	@Param				sort			query		string	false	"Field to order by"		Enums(created_at, email, last_name)
+//	@Param				order			query		string	false	"Direction to order in"	Enums(asc, desc)
+//	@Success			200				{object}	store.UserPage
+//	@Failure			400				{object}	foundation.Response
+//	@Failure			401				{object}	foundation.Response
+//	@Failure			403				{object}	foundation.Response
+//	@Failure			500				{object}	foundation.Response
+//	@Router				/v1/users [get]
+//	@DeprecatedRouter	/admin/users [get]
+func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r.URL.Query())
+	if err != nil {
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+		return
+	}
+
+	page, err := h.Store.ListUsers(r.Context(), opts)
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidCursor) || errors.Is(err, store.ErrInvalidSort) {
+			foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+			return
+		}
+		h.Logger.Errorf("failed to list users: %v", err)
+		foundation.ErrorResponse(w, r, http.StatusInternalServerError, err, foundation.DatabaseError)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, page)
+}
+
+// CreateUser godoc
+//
+//	@Summary		Create a user
+//	@Description	Create an active user in the caller's organization with email and password, requires the users:write permission
+//	@Tags			Admin
+//	@Security		ApiKeyAuth
+//	@Accept			json
+//	@Produce		json
+//	@Param			user	body		store.User	true	"User details"
+//	@Success		201		{object}	store.User
+//	@Failure		400		{object}	foundation.Response
+//	@Failure		401		{object}	foundation.Response
+//	@Failure		403		{object}	foundation.Response
+//	@Failure		409		{object}	foundation.Response
+//	@Failure		500		{object}	foundation.Response
+//	@Router			/v1/users [post]
+func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	u := &store.User{}
+	if err := json.NewDecoder(r.Body).Decode(u); err != nil {
+		h.Logger.Errorf("invalid data in request: %v", err)
+
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(middleware.UserClaimsKey).(*store.Claims)
+	if !ok || claims == nil {
+		foundation.ErrorResponse(w, r, http.StatusUnauthorized, authz.ErrUnauthenticated, foundation.UnAuthorised)
+		return
+	}
+
+	// roles are assigned once the user exists, like for users who register.
+	u.Roles = nil
+	u.Active = true
+
+	h.Logger.Infof("admin %s creating user %s", claims.Subject, u.Email)
+	created, err := h.Users.Create(r.Context(), u, claims.Subject)
+	if err != nil {
+		h.classifiedError(w, r, err)
+		return
+	}
+
+	created.Password = "********"
+	_ = foundation.Resource(w, http.StatusCreated, created)
+}
+
+// listOptions reads the filters, order and page of a user listing from the query string.
+func listOptions(q url.Values) (store.ListOptions, error) {
+	opts := store.ListOptions{
+		Role:        q.Get("role"),
+		Company:     q.Get("company"),
+		EmailPrefix: q.Get("email_prefix"),
+		After:       q.Get("page_token"),
+	}
+
+	var err error
+	if opts.First, err = pageSize(q); err != nil {
+		return opts, err
+	}
+	if active := q.Get("active"); active != "" {
+		value, err := strconv.ParseBool(active)
+		if err != nil {
+			return opts, fmt.Errorf("invalid active: %q", active)
+		}
+		opts.Active = &value
+	}
+	if after := q.Get("created_after"); after != "" {
+		if opts.CreatedAfter, err = time.Parse(time.RFC3339, after); err != nil {
+			return opts, fmt.Errorf("invalid created_after: %w", err)
+		}
+	}
+	if before := q.Get("created_before"); before != "" {
+		if opts.CreatedBefore, err = time.Parse(time.RFC3339, before); err != nil {
+			return opts, fmt.Errorf("invalid created_before: %w", err)
+		}
+	}
+
+	sort, ok := store.ParseUserSort(q.Get("sort"))
+	if !ok {
+		return opts, store.ErrInvalidSort
+	}
+	opts.Sort = sort
+
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		opts.Descending = true
+	default:
+		return opts, errInvalidOrder
+	}
+
+	return opts, nil
+}
+
+// SearchUsers godoc
+//
+//	@Summary			Search users
+//	@Description		Find the users of the caller's organization with words in their name, email or company starting with each word of q, best matches first, requires the users:read permission
+//	@Tags				Admin
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Param				q			query		string	true	"Words to search for"
+//	@Param				page_size	query		int		false	"Users in a page, at most 100"	default(20)
+//	@Param				page_token	query		string	false	"next_cursor of the previous page"
+//	@Success			200			{object}	store.UserPage
+//	@Failure			400			{object}	foundation.Response
+//	@Failure			401			{object}	foundation.Response
+//	@Failure			403			{object}	foundation.Response
+//	@Failure			500			{object}	foundation.Response
+//	@Router				/v1/users/search [get]
+//	@DeprecatedRouter	/admin/users/search [get]
+func (h *Handler) SearchUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	first, err := pageSize(q)
+	if err != nil {
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+		return
+	}
+
+	page, err := h.Store.SearchUsers(r.Context(), store.SearchOptions{
+		Query: q.Get("q"),
+		First: first,
+		After: q.Get("page_token"),
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrEmptySearch) || errors.Is(err, store.ErrInvalidCursor) {
+			foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+			return
+		}
+		h.Logger.Errorf("failed to search users: %v", err)
+		foundation.ErrorResponse(w, r, http.StatusInternalServerError, err, foundation.DatabaseError)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, page)
+}
+
+// pageSize reads the page_size of a listing from the query string, it is 0 when not set.
+func pageSize(q url.Values) (int, error) {
+	size := q.Get("page_size")
+	if size == "" {
+		return 0, nil
+	}
+	first, err := strconv.Atoi(size)
+	if err != nil || first < 0 {
+		return 0, fmt.Errorf("invalid page_size: %q", size)
+	}
+	return first, nil
+}
@@ -0,0 +1,230 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+func TestHandlerListUsers(t *testing.T) {
+	active := false
+	scenarios := []struct {
+		name           string
+		query          string
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+		expectedOpts   *store.ListOptions
+	}{
+		{
+			name:           "invalid page size",
+			query:          "page_size=ten",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "invalid created after",
+			query:          "created_after=yesterday",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "invalid sort",
+			query:          "sort=password",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "invalid order",
+			query:          "order=up",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "invalid page token",
+			query:          "page_token=abc",
+			store:          &mocks.Store{Error: store.ErrInvalidCursor},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "database error",
+			store:          &mocks.Store{Error: errors.New("db error")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   foundation.DatabaseError,
+		},
+		{
+			name: "listed",
+			query: "page_size=5&page_token=abc&role=support&active=false&company=Acme" +
+				"&created_after=2024-01-01T00:00:00Z&created_before=2025-01-01T00:00:00Z" +
+				"&email_prefix=jane&sort=last_name&order=desc",
+			store:          &mocks.Store{User: &store.User{ID: "user-1", Email: "jane@example.com"}},
+			expectedStatus: http.StatusOK,
+			expectedOpts: &store.ListOptions{
+				Role:          "support",
+				Active:        &active,
+				Company:       "Acme",
+				CreatedAfter:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				CreatedBefore: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+				EmailPrefix:   "jane",
+				Sort:          store.SortLastName,
+				Descending:    true,
+				First:         5,
+				After:         "abc",
+			},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			w := httptest.NewRecorder()
+			handler.ListUsers(w, httptest.NewRequest(http.MethodGet, "/admin/users?"+sc.query, nil))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Equal(t, sc.expectedOpts, sc.store.ListedWith)
+			assert.Contains(t, w.Body.String(), "jane@example.com")
+		})
+	}
+}
+
+func TestHandlerSearchUsers(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		query          string
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+		expectedOpts   *store.SearchOptions
+	}{
+		{
+			name:           "invalid page size",
+			query:          "q=jane&page_size=-1",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "no words",
+			store:          &mocks.Store{Error: store.ErrEmptySearch},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "database error",
+			query:          "q=jane",
+			store:          &mocks.Store{Error: errors.New("db error")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   foundation.DatabaseError,
+		},
+		{
+			name:           "found",
+			query:          "q=jane+acme&page_size=5&page_token=abc",
+			store:          &mocks.Store{User: &store.User{ID: "user-1", Email: "jane@example.com"}},
+			expectedStatus: http.StatusOK,
+			expectedOpts:   &store.SearchOptions{Query: "jane acme", First: 5, After: "abc"},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			w := httptest.NewRecorder()
+			handler.SearchUsers(w, httptest.NewRequest(http.MethodGet, "/admin/users/search?"+sc.query, nil))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Equal(t, sc.expectedOpts, sc.store.SearchedWith)
+			assert.Contains(t, w.Body.String(), "jane@example.com")
+		})
+	}
+}
+
+func TestHandlerCreateUser(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		body           string
+		claims         *store.Claims
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "invalid json",
+			body:           "{",
+			claims:         profileClaims,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "no claims",
+			body:           `{"first_name":"John","last_name":"Doe","email":"john@example.com","password":"secret","terms":true}`,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   foundation.UnAuthorised,
+		},
+		{
+			name:           "invalid details",
+			body:           `{"first_name":"John","email":"john@example.com","password":"secret","terms":true}`,
+			claims:         profileClaims,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.ValidationFailed,
+		},
+		{
+			name:           "email taken",
+			body:           `{"first_name":"John","last_name":"Doe","email":"john@example.com","password":"secret","terms":true}`,
+			claims:         profileClaims,
+			store:          &mocks.Store{Error: store.ErrDuplicateEmail},
+			expectedStatus: http.StatusConflict,
+			expectedCode:   foundation.EmailAlreadyExists,
+		},
+		{
+			name:           "created",
+			body:           `{"first_name":"John","last_name":"Doe","email":"john@example.com","password":"secret","terms":true,"roles":["admin"]}`,
+			claims:         profileClaims,
+			store:          &mocks.Store{User: &store.User{ID: "user-1", Password: "hash"}},
+			expectedStatus: http.StatusCreated,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			w := httptest.NewRecorder()
+			handler.CreateUser(w, webhookRequest(http.MethodPost, "/v1/users", sc.body, "", "", sc.claims))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Contains(t, w.Body.String(), `"id":"user-1"`)
+			assert.Contains(t, w.Body.String(), `"password":"********"`)
+		})
+	}
+}
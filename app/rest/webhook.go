@@ -0,0 +1,196 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/riyadennis/identity-server/business"
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+	"github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+// WebhookRequest has the endpoint events are posted to, the events it gets
+// and the secret deliveries are signed with, one is generated when it is empty.
+type WebhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret"`
+}
+
+// CreateWebhook godoc
+//
+//	@Summary			Register a webhook
+//	@Description		Register an endpoint the subscribed events of the caller's organization are posted to, signed with the secret, which is only returned here. Requires the webhooks:manage permission
+//	@Tags				Webhook
+//	@Security			ApiKeyAuth
+//	@Accept				json
+//	@Produce			json
+//	@Param				webhook	body		WebhookRequest	true	"URL, event types and secret of the webhook"
+//	@Success			201		{object}	store.Webhook
+//	@Failure			400		{object}	foundation.Response
+//	@Failure			401		{object}	foundation.Response
+//	@Failure			403		{object}	foundation.Response
+//	@Failure			500		{object}	foundation.Response
+//	@Router				/v1/webhooks [post]
+//	@DeprecatedRouter	/admin/webhooks [post]
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	req := &WebhookRequest{}
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		h.Logger.Errorf("invalid data in request: %v", err)
+
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value(middleware.UserClaimsKey).(*store.Claims)
+	if !ok || claims == nil {
+		foundation.ErrorResponse(w, r, http.StatusUnauthorized, authz.ErrUnauthenticated, foundation.UnAuthorised)
+		return
+	}
+
+	webhook, err := h.Webhooks.Create(r.Context(), claims.Subject, &store.Webhook{
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     req.Secret,
+	})
+	if err != nil {
+		h.webhookError(w, r, err)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusCreated, webhook)
+}
+
+// ListWebhooks godoc
+//
+//	@Summary			List webhooks
+//	@Description		List the webhooks of the caller's organization without their secrets, requires the webhooks:manage permission
+//	@Tags				Webhook
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Success			200	{array}		store.Webhook
+//	@Failure			401	{object}	foundation.Response
+//	@Failure			403	{object}	foundation.Response
+//	@Failure			500	{object}	foundation.Response
+//	@Router				/v1/webhooks [get]
+//	@DeprecatedRouter	/admin/webhooks [get]
+func (h *Handler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, err := h.Store.ListWebhooks(r.Context())
+	if err != nil {
+		h.webhookError(w, r, err)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, webhooks)
+}
+
+// DeleteWebhook godoc
+//
+//	@Summary			Delete a webhook
+//	@Description		Stop posting events to a webhook and remove its deliveries, requires the webhooks:manage permission
+//	@Tags				Webhook
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Param				webhookID	path		string	true	"Webhook ID"
+//	@Success			204			{string}	string	"No Content"
+//	@Failure			401			{object}	foundation.Response
+//	@Failure			403			{object}	foundation.Response
+//	@Failure			404			{object}	foundation.Response
+//	@Failure			500			{object}	foundation.Response
+//	@Router				/v1/webhooks/{webhookID} [delete]
+//	@DeprecatedRouter	/admin/webhooks/{webhookID} [delete]
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := h.Store.DeleteWebhook(r.Context(), chi.URLParam(r, "webhookID")); err != nil {
+		h.webhookError(w, r, err)
+		return
+	}
+
+	_ = foundation.JSONResponse(w, http.StatusNoContent, "", "")
+}
+
+// WebhookDeliveries godoc
+//
+//	@Summary			List webhook deliveries
+//	@Description		Page through the deliveries of a webhook with their status and the response code of the last attempt, newest first, pass next_cursor as page_token to read the next page. Requires the webhooks:manage permission
+//	@Tags				Webhook
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Param				webhookID	path		string	true	"Webhook ID"
+//	@Param				page_size	query		int		false	"Deliveries in a page, at most 100"	default(20)
+//	@Param				page_token	query		string	false	"next_cursor of the previous page"
+//	@Success			200			{object}	store.WebhookDeliveryPage
+//	@Failure			400			{object}	foundation.Response
+//	@Failure			401			{object}	foundation.Response
+//	@Failure			403			{object}	foundation.Response
+//	@Failure			404			{object}	foundation.Response
+//	@Failure			500			{object}	foundation.Response
+//	@Router				/v1/webhooks/{webhookID}/deliveries [get]
+//	@DeprecatedRouter	/admin/webhooks/{webhookID}/deliveries [get]
+func (h *Handler) WebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	first, err := pageSize(q)
+	if err != nil {
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+		return
+	}
+
+	page, err := h.Store.ListWebhookDeliveries(r.Context(), chi.URLParam(r, "webhookID"), store.WebhookDeliveryOptions{
+		First: first,
+		After: q.Get("page_token"),
+	})
+	if err != nil {
+		h.webhookError(w, r, err)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, page)
+}
+
+// ReplayWebhookDelivery godoc
+//
+//	@Summary			Replay a webhook delivery
+//	@Description		Post a delivery again straight away, with all its retries, whether it failed or not. Requires the webhooks:manage permission
+//	@Tags				Webhook
+//	@Security			ApiKeyAuth
+//	@Produce			json
+//	@Param				deliveryID	path		string	true	"Delivery ID"
+//	@Success			200			{object}	store.WebhookDelivery
+//	@Failure			401			{object}	foundation.Response
+//	@Failure			403			{object}	foundation.Response
+//	@Failure			404			{object}	foundation.Response
+//	@Failure			500			{object}	foundation.Response
+//	@Router				/v1/webhooks/deliveries/{deliveryID}/replay [post]
+//	@DeprecatedRouter	/admin/webhooks/deliveries/{deliveryID}/replay [post]
+func (h *Handler) ReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	d, err := h.Webhooks.Replay(r.Context(), chi.URLParam(r, "deliveryID"))
+	if err != nil {
+		h.webhookError(w, r, err)
+		return
+	}
+
+	_ = foundation.Resource(w, http.StatusOK, d)
+}
+
+// webhookError writes the response for an error from managing webhooks.
+func (h *Handler) webhookError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, store.ErrWebhookNotFound):
+		foundation.ErrorResponse(w, r, http.StatusNotFound, err, foundation.WebhookNotFound)
+	case errors.Is(err, store.ErrDeliveryNotFound):
+		foundation.ErrorResponse(w, r, http.StatusNotFound, err, foundation.DeliveryNotFound)
+	case errors.Is(err, store.ErrInvalidCursor):
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+	case errors.Is(err, store.ErrOrganizationNotFound):
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.InvalidRequest)
+	case errors.Is(err, business.ErrInvalidDetails):
+		foundation.ErrorResponse(w, r, http.StatusBadRequest, err, foundation.ValidationFailed)
+	default:
+		h.Logger.Errorf("webhook request failed: %v", err)
+		foundation.ErrorResponse(w, r, http.StatusInternalServerError, err, foundation.DatabaseError)
+	}
+}
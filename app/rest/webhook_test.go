@@ -0,0 +1,235 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+	"github.com/riyadennis/identity-server/foundation/middleware"
+)
+
+func webhookRequest(method, path, body, param, value string, claims *store.Claims) *http.Request {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add(param, value)
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, routeContext)
+	if claims != nil {
+		ctx = context.WithValue(ctx, middleware.UserClaimsKey, claims)
+	}
+	return r.WithContext(ctx)
+}
+
+func TestHandlerCreateWebhook(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		body           string
+		claims         *store.Claims
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "invalid json",
+			body:           "{",
+			claims:         profileClaims,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "no claims",
+			body:           `{"url":"https://example.com/hook","event_types":["user.registered"]}`,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusUnauthorized,
+			expectedCode:   foundation.UnAuthorised,
+		},
+		{
+			name:           "unknown event type",
+			body:           `{"url":"https://example.com/hook","event_types":["user.renamed"]}`,
+			claims:         profileClaims,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.ValidationFailed,
+		},
+		{
+			name:           "database error",
+			body:           `{"url":"https://example.com/hook","event_types":["user.registered"]}`,
+			claims:         profileClaims,
+			store:          &mocks.Store{Error: errors.New("db error")},
+			expectedStatus: http.StatusInternalServerError,
+			expectedCode:   foundation.DatabaseError,
+		},
+		{
+			name:           "created",
+			body:           `{"url":"https://example.com/hook","event_types":["user.registered"],"secret":"0123456789abcdef"}`,
+			claims:         profileClaims,
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusCreated,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			w := httptest.NewRecorder()
+			handler.CreateWebhook(w, webhookRequest(http.MethodPost, "/admin/webhooks", sc.body, "", "", sc.claims))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Contains(t, w.Body.String(), `"secret":"0123456789abcdef"`)
+			assert.Len(t, sc.store.Webhooks, 1)
+			assert.Equal(t, "user-123", sc.store.Webhooks[0].CreatedBy)
+		})
+	}
+}
+
+func TestHandlerListWebhooks(t *testing.T) {
+	handler := NewHandler(&mocks.Store{Webhooks: []*store.Webhook{{ID: "webhook-1", URL: "https://example.com/hook"}}},
+		&mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+	w := httptest.NewRecorder()
+	handler.ListWebhooks(w, httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"webhook-1"`)
+	assert.NotContains(t, w.Body.String(), `"secret"`)
+}
+
+func TestHandlerDeleteWebhook(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "not found",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.WebhookNotFound,
+		},
+		{
+			name:           "deleted",
+			store:          &mocks.Store{Webhooks: []*store.Webhook{{ID: "webhook-1"}}},
+			expectedStatus: http.StatusNoContent,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			w := httptest.NewRecorder()
+			handler.DeleteWebhook(w, webhookRequest(http.MethodDelete, "/admin/webhooks/webhook-1", "", "webhookID", "webhook-1", nil))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Empty(t, sc.store.Webhooks)
+		})
+	}
+}
+
+func TestHandlerWebhookDeliveries(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		query          string
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "invalid page size",
+			query:          "page_size=lots",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   foundation.InvalidRequest,
+		},
+		{
+			name:           "webhook not found",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.WebhookNotFound,
+		},
+		{
+			name: "listed",
+			store: &mocks.Store{
+				Webhooks:   []*store.Webhook{{ID: "webhook-1"}},
+				Deliveries: []*store.WebhookDelivery{{ID: "delivery-1", WebhookID: "webhook-1", Status: store.DeliveryFailed}},
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			w := httptest.NewRecorder()
+			handler.WebhookDeliveries(w, webhookRequest(http.MethodGet, "/admin/webhooks/webhook-1/deliveries?"+sc.query, "",
+				"webhookID", "webhook-1", nil))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Contains(t, w.Body.String(), `"id":"delivery-1"`)
+			assert.Contains(t, w.Body.String(), `"status":"failed"`)
+		})
+	}
+}
+
+func TestHandlerReplayWebhookDelivery(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		store          *mocks.Store
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			name:           "not found",
+			store:          &mocks.Store{},
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   foundation.DeliveryNotFound,
+		},
+		{
+			name:           "replayed",
+			store:          &mocks.Store{Deliveries: []*store.WebhookDelivery{{ID: "delivery-1", Status: store.DeliveryFailed, Attempts: 10}}},
+			expectedStatus: http.StatusOK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			handler := NewHandler(sc.store, &mocks.Authenticator{}, &store.TokenConfig{}, logrus.New())
+
+			w := httptest.NewRecorder()
+			handler.ReplayWebhookDelivery(w, webhookRequest(http.MethodPost, "/admin/webhooks/deliveries/delivery-1/replay", "",
+				"deliveryID", "delivery-1", nil))
+
+			assert.Equal(t, sc.expectedStatus, w.Code)
+			if sc.expectedCode != "" {
+				resp := responseFromHTTP(t, w.Body)
+				assert.Equal(t, sc.expectedCode, resp.ErrorCode)
+				return
+			}
+			assert.Contains(t, w.Body.String(), `"status":"pending"`)
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/riyadennis/identity-server/app/rest"
+	"github.com/riyadennis/identity-server/business"
 	"github.com/riyadennis/identity-server/business/store"
 	"github.com/riyadennis/identity-server/foundation"
 	"github.com/sirupsen/logrus"
@@ -31,6 +32,7 @@ type ProtoServer struct {
 type Server struct {
 	Logger      *logrus.Logger
 	restServer  http.Server
+	logins      *business.LoginRecorder
 	ServerError chan error
 	ShutDown    chan os.Signal
 }
@@ -65,7 +67,9 @@ func NewServer(logger *logrus.Logger, restPort string) (*Server, error) {
 }
 
 func (s *Server) RESTHandler(tc *store.TokenConfig, st store.Store, auth store.Authenticator) {
-	s.restServer.Handler = rest.LoadRESTEndpoints(tc, s.Logger, st, auth)
+	h := rest.NewHandler(st, auth, tc, s.Logger)
+	s.restServer.Handler = rest.NewRouter(h)
+	s.logins = h.Helper.Logins
 }
 
 // and waits to receive from shutdown and error channels.
@@ -87,6 +91,11 @@ func (s *Server) Run() error {
 		defer cancel()
 
 		err := s.restServer.Shutdown(ctx)
+		if s.logins != nil {
+			if err := s.logins.Wait(ctx); err != nil {
+				s.Logger.Errorf("login notices not sent before shutdown: %v", err)
+			}
+		}
 		if err != nil {
 			return err
 		}
@@ -88,4 +88,5 @@ func TestRESTHandler(t *testing.T) {
 		&mocks.Store{},
 		&mocks.Authenticator{},
 	)
+	assert.NotNil(t, s.logins)
 }
@@ -0,0 +1,167 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/riyadennis/identity-server/business/store"
+)
+
+// Built-in roles created by the migrations.
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+	// RoleSuperAdmin is a platform role, it is not tied to an organization
+	// and gives access to the users of every organization.
+	RoleSuperAdmin = "superadmin"
+)
+
+// Permissions that can be granted through roles.
+const (
+	UsersRead      = "users:read"
+	UsersWrite     = "users:write"
+	UsersDelete    = "users:delete"
+	RolesRead      = "roles:read"
+	RolesWrite     = "roles:write"
+	AuditRead      = "audit:read"
+	WebhooksManage = "webhooks:manage"
+	QueriesManage  = "queries:manage"
+)
+
+// Resource types that permissions can be checked against.
+const (
+	ResourceUser = "user"
+	ResourceRole = "role"
+)
+
+var (
+	// ErrUnauthenticated is returned when there is no subject to authorise.
+	ErrUnauthenticated = errors.New("unauthorized: missing or invalid token claims")
+	// ErrForbidden is returned when the subject lacks the required permission.
+	ErrForbidden = errors.New("forbidden: missing permission")
+)
+
+// selfPermissions are granted to every user on their own user resource.
+var selfPermissions = map[string]bool{
+	UsersRead:  true,
+	UsersWrite: true,
+}
+
+// Subject is the authenticated caller asking for access.
+type Subject struct {
+	UserID string
+}
+
+// Resource is the object a permission is checked against, an empty
+// resource means the permission is checked globally.
+type Resource struct {
+	Type string
+	ID   string
+}
+
+// User returns the resource for the user with the given ID.
+func User(id string) Resource {
+	return Resource{Type: ResourceUser, ID: id}
+}
+
+// Store is what the Authorizer needs to know about roles and organizations.
+type Store interface {
+	store.RoleStore
+	store.OrganizationStore
+}
+
+// Authorizer decides whether a subject holds a permission on a resource.
+type Authorizer struct {
+	Store  Store
+	Logger *logrus.Logger
+}
+
+// NewAuthorizer creates an Authorizer backed by the role store.
+func NewAuthorizer(st Store, logger *logrus.Logger) *Authorizer {
+	return &Authorizer{
+		Store:  st,
+		Logger: logger,
+	}
+}
+
+// Can reports whether subject has permission on resource. Users can always
+// read and update their own account, everything else needs to be granted
+// through one of the subject's roles.
+func (a *Authorizer) Can(ctx context.Context, subject Subject, permission string, resource Resource) (bool, error) {
+	if subject.UserID == "" {
+		return false, ErrUnauthenticated
+	}
+
+	if resource.Type == ResourceUser && resource.ID == subject.UserID && selfPermissions[permission] {
+		return true, nil
+	}
+
+	permissions, err := a.Store.UserPermissions(ctx, subject.UserID)
+	if err != nil {
+		a.Logger.Errorf("failed to fetch permissions for %s: %v", subject.UserID, err)
+		return false, err
+	}
+
+	return slices.Contains(permissions, permission), nil
+}
+
+// HasRole reports whether subject has been assigned the role.
+func (a *Authorizer) HasRole(ctx context.Context, subject Subject, role string) (bool, error) {
+	if subject.UserID == "" {
+		return false, ErrUnauthenticated
+	}
+
+	roles, err := a.Store.UserRoles(ctx, subject.UserID)
+	if err != nil {
+		a.Logger.Errorf("failed to fetch roles for %s: %v", subject.UserID, err)
+		return false, err
+	}
+
+	return slices.Contains(roles, role), nil
+}
+
+// Authorize is like Can but returns ErrForbidden when access is denied.
+func (a *Authorizer) Authorize(ctx context.Context, subject Subject, permission string, resource Resource) error {
+	allowed, err := a.Can(ctx, subject, permission, resource)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		a.Logger.Infof("user %s denied %s on %s %s", subject.UserID, permission, resource.Type, resource.ID)
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// Tenant resolves the tenant a subject acts within when using a token issued
+// for orgID. Superadmins act as platform tenants, everyone else has to be a
+// member of the organization.
+func (a *Authorizer) Tenant(ctx context.Context, subject Subject, orgID string) (store.Tenant, error) {
+	superAdmin, err := a.HasRole(ctx, subject, RoleSuperAdmin)
+	if err != nil {
+		return store.Tenant{}, err
+	}
+	if superAdmin {
+		return store.Tenant{OrganizationID: orgID, Platform: true}, nil
+	}
+
+	if orgID == "" {
+		a.Logger.Infof("user %s has no organization", subject.UserID)
+		return store.Tenant{}, ErrForbidden
+	}
+	member, err := a.Store.IsMember(ctx, orgID, subject.UserID)
+	if err != nil {
+		a.Logger.Errorf("failed to check membership of %s: %v", subject.UserID, err)
+		return store.Tenant{}, err
+	}
+	if !member {
+		a.Logger.Infof("user %s is not a member of organization %s", subject.UserID, orgID)
+		return store.Tenant{}, ErrForbidden
+	}
+
+	return store.Tenant{OrganizationID: orgID}, nil
+}
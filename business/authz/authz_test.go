@@ -0,0 +1,156 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+)
+
+func TestAuthorizer_Can(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		store       *mocks.Store
+		subject     Subject
+		permission  string
+		resource    Resource
+		expected    bool
+		expectedErr error
+	}{
+		{
+			name:        "no subject",
+			store:       &mocks.Store{},
+			permission:  UsersRead,
+			expectedErr: ErrUnauthenticated,
+		},
+		{
+			name:       "own account",
+			store:      &mocks.Store{Error: errors.New("store should not be called")},
+			subject:    Subject{UserID: "user-1"},
+			permission: UsersRead,
+			resource:   User("user-1"),
+			expected:   true,
+		},
+		{
+			name:       "own account needs role to delete",
+			store:      &mocks.Store{},
+			subject:    Subject{UserID: "user-1"},
+			permission: UsersDelete,
+			resource:   User("user-1"),
+			expected:   false,
+		},
+		{
+			name:       "other account without permission",
+			store:      &mocks.Store{Permissions: []string{RolesRead}},
+			subject:    Subject{UserID: "user-1"},
+			permission: UsersRead,
+			resource:   User("user-2"),
+			expected:   false,
+		},
+		{
+			name:       "granted through role",
+			store:      &mocks.Store{Permissions: []string{UsersRead, UsersWrite}},
+			subject:    Subject{UserID: "user-1"},
+			permission: UsersWrite,
+			resource:   User("user-2"),
+			expected:   true,
+		},
+		{
+			name:        "store error",
+			store:       &mocks.Store{Error: errors.New("db down")},
+			subject:     Subject{UserID: "user-1"},
+			permission:  RolesWrite,
+			expectedErr: errors.New("db down"),
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			a := NewAuthorizer(sc.store, logrus.New())
+			allowed, err := a.Can(context.Background(), sc.subject, sc.permission, sc.resource)
+			assert.Equal(t, sc.expectedErr, err)
+			assert.Equal(t, sc.expected, allowed)
+		})
+	}
+}
+
+func TestAuthorizer_Authorize(t *testing.T) {
+	a := NewAuthorizer(&mocks.Store{Permissions: []string{UsersRead}}, logrus.New())
+
+	err := a.Authorize(context.Background(), Subject{UserID: "user-1"}, UsersDelete, User("user-2"))
+	assert.Equal(t, ErrForbidden, err)
+
+	err = a.Authorize(context.Background(), Subject{UserID: "user-1"}, UsersRead, User("user-2"))
+	assert.NoError(t, err)
+}
+
+func TestAuthorizer_HasRole(t *testing.T) {
+	st := &mocks.Store{User: &store.User{ID: "user-1", Roles: []string{RoleAdmin, RoleUser}}}
+	a := NewAuthorizer(st, logrus.New())
+
+	ok, err := a.HasRole(context.Background(), Subject{UserID: "user-1"}, RoleAdmin)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = a.HasRole(context.Background(), Subject{UserID: "user-1"}, "support")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = a.HasRole(context.Background(), Subject{}, RoleAdmin)
+	assert.Equal(t, ErrUnauthenticated, err)
+}
+
+func TestAuthorizer_Tenant(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		store       *mocks.Store
+		orgID       string
+		expected    store.Tenant
+		expectedErr error
+	}{
+		{
+			name:        "no organization",
+			store:       &mocks.Store{User: &store.User{Roles: []string{RoleUser}}},
+			expectedErr: ErrForbidden,
+		},
+		{
+			name:        "not a member",
+			store:       &mocks.Store{User: &store.User{Roles: []string{RoleAdmin}}},
+			orgID:       "org-2",
+			expectedErr: ErrForbidden,
+		},
+		{
+			name: "member",
+			store: &mocks.Store{
+				User:          &store.User{Roles: []string{RoleAdmin}},
+				Organizations: []*store.Organization{{ID: "org-1"}},
+			},
+			orgID:    "org-1",
+			expected: store.Tenant{OrganizationID: "org-1"},
+		},
+		{
+			name:     "superadmin",
+			store:    &mocks.Store{User: &store.User{Roles: []string{RoleSuperAdmin}}},
+			orgID:    "org-2",
+			expected: store.Tenant{OrganizationID: "org-2", Platform: true},
+		},
+		{
+			name:        "store error",
+			store:       &mocks.Store{Error: errors.New("db down")},
+			orgID:       "org-1",
+			expectedErr: errors.New("db down"),
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			a := NewAuthorizer(sc.store, logrus.New())
+			tenant, err := a.Tenant(context.Background(), Subject{UserID: "user-1"}, sc.orgID)
+			assert.Equal(t, sc.expectedErr, err)
+			assert.Equal(t, sc.expected, tenant)
+		})
+	}
+}
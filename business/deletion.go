@@ -0,0 +1,122 @@
+package business
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/riyadennis/identity-server/business/store"
+)
+
+const (
+	// DeletionRetention is how long deleted users can be restored for before they are purged.
+	DeletionRetention = 30 * 24 * time.Hour
+
+	// PurgeInterval is how often the purger looks for users to purge.
+	PurgeInterval = time.Hour
+
+	// purgeBatchSize is the most users purged in a single transaction.
+	purgeBatchSize = 100
+)
+
+// Deleter deletes users, they can be restored until the retention has passed
+// and are then permanently removed by the purger.
+type Deleter struct {
+	Store     store.Store
+	Logger    *logrus.Logger
+	Retention time.Duration
+}
+
+// NewDeleter creates a Deleter keeping deleted users for retention, a
+// duration such as 720h. DeletionRetention is used when it is empty or invalid.
+func NewDeleter(st store.Store, logger *logrus.Logger, retention string) *Deleter {
+	d := &Deleter{
+		Store:     st,
+		Logger:    logger,
+		Retention: DeletionRetention,
+	}
+	if retention == "" {
+		return d
+	}
+
+	r, err := time.ParseDuration(retention)
+	if err != nil || r <= 0 {
+		logger.Errorf("invalid deleted user retention %q, keeping deleted users for %s", retention, DeletionRetention)
+		return d
+	}
+	d.Retention = r
+	return d
+}
+
+// Delete hides a user of the tenant from every query until it is restored or purged.
+func (d *Deleter) Delete(ctx context.Context, userID string) error {
+	deleted, err := d.Store.Delete(ctx, userID)
+	if err != nil {
+		d.Logger.Errorf("failed to delete user %s: %v", userID, err)
+		return err
+	}
+	if deleted == 0 {
+		return store.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// Restore brings back a user of the tenant deleted within the retention.
+func (d *Deleter) Restore(ctx context.Context, userID string) (*store.User, error) {
+	restored, err := d.Store.Restore(ctx, userID, time.Now().Add(-d.Retention))
+	if err != nil {
+		d.Logger.Errorf("failed to restore user %s: %v", userID, err)
+		return nil, err
+	}
+	if restored == 0 {
+		return nil, store.ErrUserNotFound
+	}
+
+	user, err := d.Store.Retrieve(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, store.ErrUserNotFound
+	}
+	return user, nil
+}
+
+// Purge permanently removes the users deleted before the retention, it
+// returns the number of users removed.
+func (d *Deleter) Purge(ctx context.Context) (int64, error) {
+	before := time.Now().Add(-d.Retention)
+	var total int64
+	for {
+		purged, err := d.Store.PurgeDeleted(ctx, before, purgeBatchSize)
+		if err != nil {
+			return total, err
+		}
+		total += purged
+		if purged < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// RunPurger purges deleted users every interval until ctx is done.
+func (d *Deleter) RunPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		purged, err := d.Purge(ctx)
+		if err != nil {
+			d.Logger.Errorf("failed to purge deleted users: %v", err)
+		} else if purged > 0 {
+			d.Logger.Infof("purged %d deleted users", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
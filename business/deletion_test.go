@@ -0,0 +1,106 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+)
+
+func TestNewDeleter(t *testing.T) {
+	scenarios := []struct {
+		name              string
+		retention         string
+		expectedRetention time.Duration
+	}{
+		{name: "default", expectedRetention: DeletionRetention},
+		{name: "invalid", retention: "a month", expectedRetention: DeletionRetention},
+		{name: "negative", retention: "-1h", expectedRetention: DeletionRetention},
+		{name: "configured", retention: "168h", expectedRetention: 7 * 24 * time.Hour},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			d := NewDeleter(&mocks.Store{}, logrus.New(), sc.retention)
+			assert.Equal(t, sc.expectedRetention, d.Retention)
+		})
+	}
+}
+
+func TestDeleter_Delete(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		store       *mocks.Store
+		expectedErr error
+	}{
+		{
+			name:        "store error",
+			store:       &mocks.Store{User: &store.User{ID: "user-1"}, Error: errors.New("db down")},
+			expectedErr: errors.New("db down"),
+		},
+		{
+			name:        "not found",
+			store:       &mocks.Store{},
+			expectedErr: store.ErrUserNotFound,
+		},
+		{
+			name:  "deleted",
+			store: &mocks.Store{User: &store.User{ID: "user-1"}},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			err := NewDeleter(sc.store, logrus.New(), "").Delete(context.Background(), "user-1")
+			assert.Equal(t, sc.expectedErr, err)
+		})
+	}
+}
+
+func TestDeleter_Restore(t *testing.T) {
+	_, err := NewDeleter(&mocks.Store{}, logrus.New(), "").Restore(context.Background(), "user-1")
+	assert.Equal(t, store.ErrUserNotFound, err)
+
+	st := &mocks.Store{User: &store.User{ID: "user-1"}}
+	user, err := NewDeleter(st, logrus.New(), "24h").Restore(context.Background(), "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", user.ID)
+	assert.WithinDuration(t, time.Now().Add(-24*time.Hour), st.RestoredSince, time.Minute)
+}
+
+func TestDeleter_Purge(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		store          *mocks.Store
+		expectedPurged int64
+		expectedErr    error
+	}{
+		{
+			name:        "store error",
+			store:       &mocks.Store{Error: errors.New("db down")},
+			expectedErr: errors.New("db down"),
+		},
+		{
+			name:           "single batch",
+			store:          &mocks.Store{PurgeBatches: []int64{3}},
+			expectedPurged: 3,
+		},
+		{
+			name:           "until a batch is not full",
+			store:          &mocks.Store{PurgeBatches: []int64{purgeBatchSize, purgeBatchSize, 1, 5}},
+			expectedPurged: 2*purgeBatchSize + 1,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			purged, err := NewDeleter(sc.store, logrus.New(), "").Purge(context.Background())
+			assert.Equal(t, sc.expectedErr, err)
+			assert.Equal(t, sc.expectedPurged, purged)
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/business/validation"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+const (
+	// EmailChangeTTL is how long the link confirming a new email address can be used for.
+	EmailChangeTTL = 24 * time.Hour
+
+	// EmailReservation is how long an address can not be used by anyone else
+	// after its owner changed to another one.
+	EmailReservation = 30 * 24 * time.Hour
+)
+
+var errSameEmail = errors.New("new email is the current email")
+
+// EmailChanger changes the email users log in with. The new address has to be
+// confirmed from a link sent to it and the old address is told about the change.
+type EmailChanger struct {
+	Store         store.Store
+	Authenticator store.Authenticator
+	Mailer        foundation.Mailer
+	Logger        *logrus.Logger
+	// ConfirmURL is the page the confirmation link points to, the token is added as a query parameter.
+	ConfirmURL  string
+	TTL         time.Duration
+	Reservation time.Duration
+}
+
+func NewEmailChanger(st store.Store, auth store.Authenticator, mailer foundation.Mailer,
+	logger *logrus.Logger, confirmURL string,
+) *EmailChanger {
+	return &EmailChanger{
+		Store:         st,
+		Authenticator: auth,
+		Mailer:        mailer,
+		Logger:        logger,
+		ConfirmURL:    confirmURL,
+		TTL:           EmailChangeTTL,
+		Reservation:   EmailReservation,
+	}
+}
+
+// Request checks the current password of the user and emails a confirmation
+// link to the new address, the email of the user is only changed once the link is used.
+func (e *EmailChanger) Request(ctx context.Context, userID, password, newEmail string) error {
+	newEmail, err := validation.ParseUserEmail(newEmail)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidDetails, err)
+	}
+
+	user, err := e.Store.Retrieve(ctx, userID)
+	if err != nil {
+		e.Logger.Errorf("failed to read from database: %v", err)
+		return err
+	}
+	if user == nil {
+		return store.ErrUserNotFound
+	}
+	if user.Email == newEmail {
+		return fmt.Errorf("%w: %w", ErrInvalidDetails, errSameEmail)
+	}
+
+	valid, err := e.Authenticator.Authenticate(user.Email, password)
+	if err != nil || !valid {
+		e.Logger.Infof("invalid password to change email of %s", userID)
+		return ErrInvalidPassword
+	}
+
+	if err := e.available(ctx, userID, newEmail); err != nil {
+		return err
+	}
+
+	token, err := GeneratePassword()
+	if err != nil {
+		return err
+	}
+
+	c, err := e.Store.CreateEmailChange(ctx, &store.EmailChange{
+		UserID:    userID,
+		OldEmail:  user.Email,
+		NewEmail:  newEmail,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(e.TTL),
+	})
+	if err != nil {
+		e.Logger.Errorf("failed to save email change: %v", err)
+		return err
+	}
+
+	return e.send(ctx, c, token)
+}
+
+// Confirm swaps the email of the user for the address the link was sent to,
+// the previous address stays reserved for the user during the cooling-off period.
+func (e *EmailChanger) Confirm(ctx context.Context, token string) (*store.User, error) {
+	user, err := e.Store.ConfirmEmailChange(ctx, hashToken(token), time.Now().Add(e.Reservation))
+	if err != nil {
+		if errors.Is(err, store.ErrDuplicateEmail) {
+			return nil, ErrEmailAlreadyExists
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// available returns ErrEmailAlreadyExists if email belongs to another user or is reserved.
+func (e *EmailChanger) available(ctx context.Context, userID, email string) error {
+	existing, err := e.Store.Read(ctx, email)
+	if err != nil {
+		e.Logger.Errorf("failed to read from database: %v", err)
+		return err
+	}
+	if existing != nil && existing.Email == email {
+		return ErrEmailAlreadyExists
+	}
+
+	reserved, err := e.Store.EmailReserved(ctx, email, userID)
+	if err != nil {
+		e.Logger.Errorf("failed to check email reservation: %v", err)
+		return err
+	}
+	if reserved {
+		return ErrEmailAlreadyExists
+	}
+
+	return nil
+}
+
+func (e *EmailChanger) send(ctx context.Context, c *store.EmailChange, token string) error {
+	link, err := tokenLink(e.ConfirmURL, token)
+	if err != nil {
+		return err
+	}
+
+	err = e.Mailer.Send(ctx, foundation.Mail{
+		To:      c.NewEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Follow the link below to log in with this address from now on, it expires on %s.\n\n%s\n",
+			c.ExpiresAt.UTC().Format(time.RFC1123), link),
+	})
+	if err != nil {
+		e.Logger.Errorf("failed to send email change confirmation %s: %v", c.ID, err)
+		return err
+	}
+
+	err = e.Mailer.Send(ctx, foundation.Mail{
+		To:      c.OldEmail,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("A change of the email address of your account to %s was requested, "+
+			"it takes effect once the new address is confirmed.\n\n"+
+			"If you did not ask for it, change your password straight away. "+
+			"This address stays reserved for your account for %d days after the change.\n",
+			c.NewEmail, int(e.Reservation.Hours()/24)),
+	})
+	if err != nil {
+		e.Logger.Errorf("failed to send email change notice %s: %v", c.ID, err)
+		return err
+	}
+
+	return nil
+}
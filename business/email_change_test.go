@@ -0,0 +1,154 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+)
+
+const confirmURL = "https://app.example.com/email/confirm"
+
+func TestEmailChanger_Request(t *testing.T) {
+	user := func() *store.User { return &store.User{ID: "user-1", Email: "jane@example.com"} }
+	scenarios := []struct {
+		name        string
+		store       *mocks.Store
+		auth        *mocks.Authenticator
+		email       string
+		expectedErr error
+	}{
+		{
+			name:        "invalid email",
+			store:       &mocks.Store{User: user()},
+			auth:        &mocks.Authenticator{ReturnVal: true},
+			email:       "not-an-email",
+			expectedErr: fmt.Errorf("%w: %w", ErrInvalidDetails, fmt.Errorf("%w: missing @", errors.New("invalid email"))),
+		},
+		{
+			name:        "email longer than the column",
+			store:       &mocks.Store{User: user()},
+			auth:        &mocks.Authenticator{ReturnVal: true},
+			email:       strings.Repeat("j", 60) + "@" + strings.Repeat("n", 60) + ".example.com",
+			expectedErr: fmt.Errorf("%w: %w", ErrInvalidDetails, fmt.Errorf("%w, the most is %d characters", errors.New("too long"), 120)),
+		},
+		{
+			name:        "unknown user",
+			store:       &mocks.Store{},
+			auth:        &mocks.Authenticator{ReturnVal: true},
+			email:       "jane@new.example.com",
+			expectedErr: store.ErrUserNotFound,
+		},
+		{
+			name:        "same email",
+			store:       &mocks.Store{User: user()},
+			auth:        &mocks.Authenticator{ReturnVal: true},
+			email:       "jane@example.com",
+			expectedErr: fmt.Errorf("%w: %w", ErrInvalidDetails, errSameEmail),
+		},
+		{
+			name:        "wrong password",
+			store:       &mocks.Store{User: user()},
+			auth:        &mocks.Authenticator{Error: errors.New("mismatch")},
+			email:       "jane@new.example.com",
+			expectedErr: ErrInvalidPassword,
+		},
+		{
+			name:        "reserved email",
+			store:       &mocks.Store{User: user(), ReservedEmails: []string{"jane@new.example.com"}},
+			auth:        &mocks.Authenticator{ReturnVal: true},
+			email:       "jane@new.example.com",
+			expectedErr: ErrEmailAlreadyExists,
+		},
+		{
+			name:  "requested",
+			store: &mocks.Store{User: user()},
+			auth:  &mocks.Authenticator{ReturnVal: true},
+			email: "jane@new.example.com",
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			mailer := &mocks.Mailer{}
+			e := NewEmailChanger(sc.store, sc.auth, mailer, logrus.New(), confirmURL)
+
+			err := e.Request(context.Background(), "user-1", "secret123", sc.email)
+			assert.Equal(t, sc.expectedErr, err)
+			if sc.expectedErr != nil {
+				assert.Empty(t, mailer.Sent)
+				return
+			}
+
+			require.Len(t, sc.store.EmailChanges, 1)
+			c := sc.store.EmailChanges[0]
+			assert.Equal(t, "jane@example.com", c.OldEmail)
+			assert.Equal(t, "jane@new.example.com", c.NewEmail)
+
+			require.Len(t, mailer.Sent, 2)
+			assert.Equal(t, "jane@new.example.com", mailer.Sent[0].To)
+			i := strings.Index(mailer.Sent[0].Body, confirmURL)
+			require.GreaterOrEqual(t, i, 0)
+			link, err := url.Parse(strings.TrimSpace(mailer.Sent[0].Body[i:]))
+			require.NoError(t, err)
+			assert.Equal(t, hashToken(link.Query().Get("token")), c.TokenHash)
+
+			assert.Equal(t, "jane@example.com", mailer.Sent[1].To)
+			assert.Contains(t, mailer.Sent[1].Body, "jane@new.example.com")
+			assert.NotContains(t, mailer.Sent[1].Body, confirmURL)
+		})
+	}
+}
+
+func TestEmailChanger_Confirm(t *testing.T) {
+	change := func() *store.EmailChange {
+		return &store.EmailChange{UserID: "user-1", NewEmail: "jane@new.example.com", TokenHash: hashToken("token")}
+	}
+	scenarios := []struct {
+		name        string
+		store       *mocks.Store
+		token       string
+		expectedErr error
+	}{
+		{
+			name:        "unknown token",
+			store:       &mocks.Store{EmailChanges: []*store.EmailChange{change()}},
+			token:       "unknown",
+			expectedErr: store.ErrEmailChangeNotFound,
+		},
+		{
+			name: "taken since requested",
+			store: &mocks.Store{
+				EmailChanges:   []*store.EmailChange{change()},
+				ReservedEmails: []string{"jane@new.example.com"},
+			},
+			token:       "token",
+			expectedErr: ErrEmailAlreadyExists,
+		},
+		{
+			name:  "confirmed",
+			store: &mocks.Store{EmailChanges: []*store.EmailChange{change()}},
+			token: "token",
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			e := NewEmailChanger(sc.store, &mocks.Authenticator{}, &mocks.Mailer{}, logrus.New(), confirmURL)
+
+			user, err := e.Confirm(context.Background(), sc.token)
+			assert.Equal(t, sc.expectedErr, err)
+			if sc.expectedErr != nil {
+				return
+			}
+			assert.Equal(t, "jane@new.example.com", user.Email)
+		})
+	}
+}
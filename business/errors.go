@@ -0,0 +1,77 @@
+package business
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/business/validation"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+// classification is the category and code clients get for an error.
+type classification struct {
+	target   error
+	category foundation.Category
+	code     string
+}
+
+// classifications are the errors of the business and store packages that are
+// about the request rather than a failure of the service.
+var classifications = []classification{
+	{authz.ErrUnauthenticated, foundation.CategoryUnauthenticated, foundation.UnAuthorised},
+	{authz.ErrForbidden, foundation.CategoryForbidden, foundation.Forbidden},
+	{store.ErrPlatformOnly, foundation.CategoryForbidden, foundation.Forbidden},
+	{store.ErrPlatformRole, foundation.CategoryForbidden, foundation.Forbidden},
+	{store.ErrSharedUser, foundation.CategoryForbidden, foundation.Forbidden},
+	{ErrInvalidPassword, foundation.CategoryForbidden, foundation.InvalidPassword},
+	{ErrEmailAlreadyExists, foundation.CategoryConflict, foundation.EmailAlreadyExists},
+	{store.ErrDuplicateEmail, foundation.CategoryConflict, foundation.EmailAlreadyExists},
+	{ErrInvalidDetails, foundation.CategoryInvalid, foundation.ValidationFailed},
+	{errEmailNotFound, foundation.CategoryInvalid, foundation.InvalidRequest},
+	{store.ErrInvalidCursor, foundation.CategoryInvalid, foundation.InvalidRequest},
+	{store.ErrInvalidSort, foundation.CategoryInvalid, foundation.InvalidRequest},
+	{store.ErrEmptySearch, foundation.CategoryInvalid, foundation.InvalidRequest},
+	{store.ErrBuiltInRole, foundation.CategoryInvalid, foundation.InvalidRequest},
+	{ErrInvalidRole, foundation.CategoryInvalid, foundation.InvalidRequest},
+	{store.ErrUserNotFound, foundation.CategoryNotFound, foundation.UserDoNotExist},
+	{store.ErrOrganizationNotFound, foundation.CategoryNotFound, foundation.OrganizationNotFound},
+	{store.ErrRoleNotFound, foundation.CategoryNotFound, foundation.RoleNotFound},
+	{store.ErrPermissionNotFound, foundation.CategoryNotFound, foundation.PermissionNotFound},
+	{store.ErrInvitationNotFound, foundation.CategoryNotFound, foundation.InvitationNotFound},
+	{store.ErrEmailChangeNotFound, foundation.CategoryNotFound, foundation.EmailChangeNotFound},
+	{store.ErrWebhookNotFound, foundation.CategoryNotFound, foundation.WebhookNotFound},
+	{store.ErrDeliveryNotFound, foundation.CategoryNotFound, foundation.DeliveryNotFound},
+}
+
+// Classify returns err with the category and code it is reported to clients
+// with. Errors that already have them keep theirs, errors nothing is known
+// about are internal ones.
+func Classify(err error) *foundation.CustomError {
+	var ce *foundation.CustomError
+	if errors.As(err, &ce) {
+		return foundation.NewCustomError(ce.Category, ce.Code, err)
+	}
+	for _, c := range classifications {
+		if errors.Is(err, c.target) {
+			return foundation.NewCustomError(c.category, c.code, err)
+		}
+	}
+	if validation.IsInvalid(err) {
+		return foundation.NewCustomError(foundation.CategoryInvalid, foundation.ValidationFailed, err)
+	}
+	return foundation.NewCustomError(foundation.CategoryInternal, foundation.InternalError, err)
+}
+
+// CorrelationID is the id the details of a masked error are logged with, it
+// is the id of the request when there is one so the other logs of the
+// request can be found too.
+func CorrelationID(ctx context.Context) string {
+	if id := store.ActorFromContext(ctx).RequestID; id != "" {
+		return id
+	}
+	return uuid.NewString()
+}
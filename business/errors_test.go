@@ -0,0 +1,90 @@
+package business
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/business/validation"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+func TestClassify(t *testing.T) {
+	scenarios := []struct {
+		name             string
+		err              error
+		expectedCategory foundation.Category
+		expectedCode     string
+	}{
+		{
+			name:             "unauthenticated",
+			err:              authz.ErrUnauthenticated,
+			expectedCategory: foundation.CategoryUnauthenticated,
+			expectedCode:     foundation.UnAuthorised,
+		},
+		{
+			name:             "forbidden",
+			err:              fmt.Errorf("failed to create role: %w", authz.ErrForbidden),
+			expectedCategory: foundation.CategoryForbidden,
+			expectedCode:     foundation.Forbidden,
+		},
+		{
+			name:             "duplicate email",
+			err:              fmt.Errorf("failed to register: %w", store.ErrDuplicateEmail),
+			expectedCategory: foundation.CategoryConflict,
+			expectedCode:     foundation.EmailAlreadyExists,
+		},
+		{
+			name:             "not found",
+			err:              fmt.Errorf("failed to delete webhook: %w", store.ErrWebhookNotFound),
+			expectedCategory: foundation.CategoryNotFound,
+			expectedCode:     foundation.WebhookNotFound,
+		},
+		{
+			name:             "invalid cursor",
+			err:              store.ErrInvalidCursor,
+			expectedCategory: foundation.CategoryInvalid,
+			expectedCode:     foundation.InvalidRequest,
+		},
+		{
+			name:             "validation",
+			err:              validation.ValidateUser(&store.User{}),
+			expectedCategory: foundation.CategoryInvalid,
+			expectedCode:     foundation.ValidationFailed,
+		},
+		{
+			name:             "already classified",
+			err:              fmt.Errorf("wrapped: %w", foundation.NewCustomError(foundation.CategoryNotFound, foundation.UserDoNotExist, errors.New("user 1 not found"))),
+			expectedCategory: foundation.CategoryNotFound,
+			expectedCode:     foundation.UserDoNotExist,
+		},
+		{
+			name:             "unknown",
+			err:              errors.New("connection refused"),
+			expectedCategory: foundation.CategoryInternal,
+			expectedCode:     foundation.InternalError,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			ce := Classify(sc.err)
+			assert.Equal(t, sc.expectedCategory, ce.Category)
+			assert.Equal(t, sc.expectedCode, ce.Code)
+			assert.ErrorIs(t, ce, sc.err)
+			assert.Equal(t, sc.err.Error(), ce.Error())
+		})
+	}
+}
+
+func TestCorrelationID(t *testing.T) {
+	ctx := store.WithActor(t.Context(), store.Actor{RequestID: "req-1"})
+	assert.Equal(t, "req-1", CorrelationID(ctx))
+
+	id := CorrelationID(t.Context())
+	assert.NotEmpty(t, id)
+	assert.NotEqual(t, id, CorrelationID(t.Context()), "every error without a request gets its own id")
+}
@@ -0,0 +1,65 @@
+package events
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// subscriberBuffer is how many events a subscriber can fall behind before
+// the bus drops the events it does not read.
+const subscriberBuffer = 64
+
+// Bus hands the events published in this process to the subscribers of
+// their type straight away. Unlike the outbox it keeps nothing: a
+// subscriber only gets the events published while it is subscribed and a
+// subscriber that falls behind misses events rather than slowing down the
+// publisher.
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[*subscriber]struct{}
+}
+
+type subscriber struct {
+	types  []string
+	events chan Event
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[*subscriber]struct{})}
+}
+
+// Publish hands e to every subscriber of its type.
+func (b *Bus) Publish(e Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for s := range b.subscribers {
+		if len(s.types) > 0 && !slices.Contains(s.types, e.EventType()) {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe returns the events of types published from now on, or of every
+// type when there are none. The channel is closed once ctx is done.
+func (b *Bus) Subscribe(ctx context.Context, types ...string) <-chan Event {
+	s := &subscriber{types: types, events: make(chan Event, subscriberBuffer)}
+
+	b.mu.Lock()
+	b.subscribers[s] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subscribers, s)
+		close(s.events)
+		b.mu.Unlock()
+	}()
+
+	return s.events
+}
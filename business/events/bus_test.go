@@ -0,0 +1,65 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return nil
+	}
+}
+
+func TestBus_Subscribe(t *testing.T) {
+	bus := NewBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	roles := bus.Subscribe(ctx, TypeRoleAssigned)
+	all := bus.Subscribe(ctx)
+
+	bus.Publish(UserRegistered{UserID: "user-1"})
+	bus.Publish(RoleAssigned{UserID: "user-1", Role: "support"})
+
+	assert.Equal(t, RoleAssigned{UserID: "user-1", Role: "support"}, receive(t, roles))
+	assert.Equal(t, UserRegistered{UserID: "user-1"}, receive(t, all))
+	assert.Equal(t, RoleAssigned{UserID: "user-1", Role: "support"}, receive(t, all))
+	assert.Empty(t, roles)
+}
+
+func TestBus_Unsubscribe(t *testing.T) {
+	bus := NewBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := bus.Subscribe(ctx)
+	cancel()
+
+	select {
+	case _, ok := <-ch:
+		require.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed")
+	}
+	bus.Publish(UserDeleted{UserID: "user-1"})
+}
+
+func TestBus_SlowSubscriber(t *testing.T) {
+	bus := NewBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := bus.Subscribe(ctx)
+
+	for range subscriberBuffer + 10 {
+		bus.Publish(UserActivated{UserID: "user-1"})
+	}
+	assert.Len(t, ch, subscriberBuffer)
+}
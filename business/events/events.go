@@ -0,0 +1,94 @@
+// Package events has the domain events other services react to. They are
+// written to the outbox in the same transaction as the change they describe
+// and published from there.
+package events
+
+// Types of the events.
+const (
+	TypeUserRegistered  = "user.registered"
+	TypeUserDeleted     = "user.deleted"
+	TypeUserActivated   = "user.activated"
+	TypeUserDeactivated = "user.deactivated"
+	TypeRoleAssigned    = "role.assigned"
+	TypePasswordChanged = "user.password_changed"
+)
+
+// Event is a change to a user.
+type Event interface {
+	// EventType names the event, such as user.registered.
+	EventType() string
+	// AggregateID is the user the event is about, the events of a user are
+	// published in the order they happened.
+	AggregateID() string
+}
+
+// UserRegistered is published when a user is created, by registering or accepting an invitation.
+type UserRegistered struct {
+	UserID         string   `json:"user_id"`
+	OrganizationID string   `json:"organization_id"`
+	Email          string   `json:"email"`
+	Roles          []string `json:"roles"`
+}
+
+func (e UserRegistered) EventType() string   { return TypeUserRegistered }
+func (e UserRegistered) AggregateID() string { return e.UserID }
+
+// UserDeleted is published when a user is deleted, it can still be restored
+// until it is purged. The organization is the one the user was deleted in,
+// it is empty when a platform administrator deleted them.
+type UserDeleted struct {
+	UserID         string `json:"user_id"`
+	OrganizationID string `json:"organization_id,omitempty"`
+}
+
+func (e UserDeleted) EventType() string   { return TypeUserDeleted }
+func (e UserDeleted) AggregateID() string { return e.UserID }
+
+// UserActivated is published when a user that was deactivated is activated,
+// in the organization as UserDeleted.
+type UserActivated struct {
+	UserID         string `json:"user_id"`
+	OrganizationID string `json:"organization_id,omitempty"`
+}
+
+func (e UserActivated) EventType() string   { return TypeUserActivated }
+func (e UserActivated) AggregateID() string { return e.UserID }
+
+// UserDeactivated is published when a user is deactivated and can no longer
+// log in, in the organization as UserDeleted.
+type UserDeactivated struct {
+	UserID         string `json:"user_id"`
+	OrganizationID string `json:"organization_id,omitempty"`
+}
+
+func (e UserDeactivated) EventType() string   { return TypeUserDeactivated }
+func (e UserDeactivated) AggregateID() string { return e.UserID }
+
+// RoleAssigned is published when a user is given a role it did not have,
+// the organization is empty for platform roles.
+type RoleAssigned struct {
+	UserID         string `json:"user_id"`
+	Role           string `json:"role"`
+	OrganizationID string `json:"organization_id"`
+}
+
+func (e RoleAssigned) EventType() string   { return TypeRoleAssigned }
+func (e RoleAssigned) AggregateID() string { return e.UserID }
+
+// PasswordChanged is published when a user sets a new password.
+type PasswordChanged struct {
+	UserID string `json:"user_id"`
+}
+
+func (e PasswordChanged) EventType() string   { return TypePasswordChanged }
+func (e PasswordChanged) AggregateID() string { return e.UserID }
+
+// Types are the types of every event.
+var Types = []string{
+	TypeUserRegistered,
+	TypeUserDeleted,
+	TypeUserActivated,
+	TypeUserDeactivated,
+	TypeRoleAssigned,
+	TypePasswordChanged,
+}
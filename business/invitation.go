@@ -0,0 +1,199 @@
+package business
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/business/validation"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+// InvitationTTL is how long an invitation link can be used for.
+const InvitationTTL = 72 * time.Hour
+
+var (
+	// ErrEmailAlreadyExists is returned when an email already belongs to a user or is reserved.
+	ErrEmailAlreadyExists = errors.New("email already exists")
+	// ErrInvalidDetails wraps the validation errors of invitation requests.
+	ErrInvalidDetails = errors.New("invalid details")
+
+	errEmptyUser       = errors.New("empty user details")
+	errMissingPassword = errors.New("missing password")
+)
+
+// Inviter invites new users to an organization by email, the invitee
+// sets their own password when accepting the invitation.
+type Inviter struct {
+	Store  store.Store
+	Mailer foundation.Mailer
+	Logger *logrus.Logger
+	// AcceptURL is the page the invitation link points to, the token is added as a query parameter.
+	AcceptURL string
+	TTL       time.Duration
+}
+
+func NewInviter(st store.Store, mailer foundation.Mailer, logger *logrus.Logger, acceptURL string) *Inviter {
+	return &Inviter{
+		Store:     st,
+		Mailer:    mailer,
+		Logger:    logger,
+		AcceptURL: acceptURL,
+		TTL:       InvitationTTL,
+	}
+}
+
+// Invite creates an invitation to the tenant's organization and emails the link to it.
+func (i *Inviter) Invite(ctx context.Context, invitedBy, email, role string) (*store.Invitation, error) {
+	email, err := validation.ParseUserEmail(email)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDetails, err)
+	}
+
+	existing, err := i.Store.Read(ctx, email)
+	if err != nil {
+		i.Logger.Errorf("failed to read from database: %v", err)
+		return nil, err
+	}
+	if existing != nil && existing.Email == email {
+		return nil, ErrEmailAlreadyExists
+	}
+
+	token, err := GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	inv, err := i.Store.CreateInvitation(ctx, &store.Invitation{
+		Email:     email,
+		Role:      role,
+		InvitedBy: invitedBy,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(i.TTL),
+	})
+	if err != nil {
+		i.Logger.Errorf("failed to save invitation: %v", err)
+		return nil, err
+	}
+
+	if err := i.send(ctx, inv, token); err != nil {
+		return nil, err
+	}
+
+	return inv, nil
+}
+
+// Resend emails a new link for a pending invitation, the previous link stops working.
+func (i *Inviter) Resend(ctx context.Context, id string) (*store.Invitation, error) {
+	inv, err := i.Store.RetrieveInvitation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := GeneratePassword()
+	if err != nil {
+		return nil, err
+	}
+
+	inv.ExpiresAt = time.Now().Add(i.TTL)
+	if err := i.Store.RenewInvitation(ctx, id, hashToken(token), inv.ExpiresAt); err != nil {
+		i.Logger.Errorf("failed to renew invitation: %v", err)
+		return nil, err
+	}
+
+	if err := i.send(ctx, inv, token); err != nil {
+		return nil, err
+	}
+
+	return inv, nil
+}
+
+// Accept creates the invited user with the password and profile they chose,
+// the invitation can only be accepted once.
+func (i *Inviter) Accept(ctx context.Context, token string, u *store.User) (*store.User, error) {
+	if u == nil {
+		return nil, errEmptyUser
+	}
+
+	inv, err := i.Store.PendingInvitation(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	u.Email = inv.Email
+	if err := validation.ValidateUser(u); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDetails, err)
+	}
+	if u.Password == "" {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDetails, errMissingPassword)
+	}
+
+	u.Password, err = EncryptPassword(u.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := i.Store.AcceptInvitation(ctx, hashToken(token), u)
+	if err != nil {
+		if errors.Is(err, store.ErrDuplicateEmail) {
+			return nil, ErrEmailAlreadyExists
+		}
+		i.Logger.Errorf("failed to accept invitation: %v", err)
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (i *Inviter) send(ctx context.Context, inv *store.Invitation, token string) error {
+	org, err := i.Store.RetrieveOrganization(ctx, inv.OrganizationID)
+	if err != nil {
+		i.Logger.Errorf("failed to find organization %s: %v", inv.OrganizationID, err)
+		return err
+	}
+
+	link, err := tokenLink(i.AcceptURL, token)
+	if err != nil {
+		return err
+	}
+
+	err = i.Mailer.Send(ctx, foundation.Mail{
+		To:      inv.Email,
+		Subject: fmt.Sprintf("You have been invited to join %s", org.Name),
+		Body: fmt.Sprintf("You have been invited to join %s as %s.\n\n"+
+			"Follow the link below to set your password, it expires on %s.\n\n%s\n",
+			org.Name, inv.Role, inv.ExpiresAt.UTC().Format(time.RFC1123), link),
+	})
+	if err != nil {
+		i.Logger.Errorf("failed to send invitation %s: %v", inv.ID, err)
+		return err
+	}
+
+	return nil
+}
+
+// tokenLink adds a token to the query of the page a link points to.
+func tokenLink(page, token string) (string, error) {
+	link, err := url.Parse(page)
+	if err != nil {
+		return "", err
+	}
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
+
+// hashToken returns the form of a link token that is stored.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
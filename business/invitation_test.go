@@ -0,0 +1,193 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+)
+
+const acceptURL = "https://app.example.com/invitations/accept"
+
+// invitationToken returns the token of the link in an invitation mail.
+func invitationToken(t *testing.T, body string) string {
+	t.Helper()
+	i := strings.Index(body, acceptURL)
+	require.GreaterOrEqual(t, i, 0)
+	link, err := url.Parse(strings.TrimSpace(body[i:]))
+	require.NoError(t, err)
+	return link.Query().Get("token")
+}
+
+func TestInviter_Invite(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		store       *mocks.Store
+		mailer      *mocks.Mailer
+		email       string
+		expectedErr error
+	}{
+		{
+			name:        "invalid email",
+			store:       &mocks.Store{},
+			mailer:      &mocks.Mailer{},
+			email:       "not-an-email",
+			expectedErr: fmt.Errorf("%w: %w", ErrInvalidDetails, fmt.Errorf("%w: missing @", errors.New("invalid email"))),
+		},
+		{
+			name:        "email longer than the column",
+			store:       &mocks.Store{},
+			mailer:      &mocks.Mailer{},
+			email:       strings.Repeat("j", 60) + "@" + strings.Repeat("e", 60) + ".example.com",
+			expectedErr: fmt.Errorf("%w: %w", ErrInvalidDetails, fmt.Errorf("%w, the most is %d characters", errors.New("too long"), 120)),
+		},
+		{
+			name:        "existing user",
+			store:       &mocks.Store{User: &store.User{Email: "jane@example.com"}},
+			mailer:      &mocks.Mailer{},
+			email:       "jane@example.com",
+			expectedErr: ErrEmailAlreadyExists,
+		},
+		{
+			name:        "mail error",
+			store:       &mocks.Store{Organizations: []*store.Organization{{ID: "org-1", Name: "Acme"}}},
+			mailer:      &mocks.Mailer{Error: errors.New("smtp down")},
+			email:       "jane@example.com",
+			expectedErr: errors.New("smtp down"),
+		},
+		{
+			name:   "sent",
+			store:  &mocks.Store{Organizations: []*store.Organization{{ID: "org-1", Name: "Acme"}}},
+			mailer: &mocks.Mailer{},
+			email:  "jane@example.com",
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			i := NewInviter(sc.store, sc.mailer, logrus.New(), acceptURL)
+			ctx := store.WithTenant(context.Background(), store.Tenant{OrganizationID: "org-1"})
+
+			inv, err := i.Invite(ctx, "admin-1", sc.email, "support")
+			assert.Equal(t, sc.expectedErr, err)
+			if sc.expectedErr != nil {
+				return
+			}
+
+			assert.Equal(t, "org-1", inv.OrganizationID)
+			assert.Equal(t, "support", inv.Role)
+			require.Len(t, sc.mailer.Sent, 1)
+			assert.Equal(t, "jane@example.com", sc.mailer.Sent[0].To)
+			assert.Contains(t, sc.mailer.Sent[0].Subject, "Acme")
+
+			token := invitationToken(t, sc.mailer.Sent[0].Body)
+			assert.NotEmpty(t, token)
+			assert.Equal(t, hashToken(token), inv.TokenHash)
+		})
+	}
+}
+
+func TestInviter_Resend(t *testing.T) {
+	inv := &store.Invitation{
+		ID:             "invitation-1",
+		Email:          "jane@example.com",
+		OrganizationID: "org-1",
+		TokenHash:      hashToken("old-token"),
+		ExpiresAt:      time.Now().Add(-time.Hour),
+	}
+	st := &mocks.Store{
+		Organizations: []*store.Organization{{ID: "org-1", Name: "Acme"}},
+		Invitations:   []*store.Invitation{inv},
+	}
+	mailer := &mocks.Mailer{}
+	i := NewInviter(st, mailer, logrus.New(), acceptURL)
+
+	_, err := i.Resend(context.Background(), "unknown")
+	assert.Equal(t, store.ErrInvitationNotFound, err)
+
+	resent, err := i.Resend(context.Background(), "invitation-1")
+	require.NoError(t, err)
+	assert.False(t, resent.Expired())
+	require.Len(t, mailer.Sent, 1)
+	assert.Equal(t, hashToken(invitationToken(t, mailer.Sent[0].Body)), inv.TokenHash)
+}
+
+func TestInviter_Accept(t *testing.T) {
+	invitation := func() *store.Invitation {
+		return &store.Invitation{
+			Email:     "jane@example.com",
+			Role:      "support",
+			TokenHash: hashToken("token"),
+		}
+	}
+	invitee := func() *store.User {
+		return &store.User{FirstName: "Jane", LastName: "Doe", Password: "secret123", Terms: true}
+	}
+	scenarios := []struct {
+		name        string
+		store       *mocks.Store
+		token       string
+		user        *store.User
+		expectedErr error
+	}{
+		{
+			name:        "empty user",
+			store:       &mocks.Store{},
+			token:       "token",
+			expectedErr: errEmptyUser,
+		},
+		{
+			name:        "unknown token",
+			store:       &mocks.Store{Invitations: []*store.Invitation{invitation()}},
+			token:       "other",
+			user:        invitee(),
+			expectedErr: store.ErrInvitationNotFound,
+		},
+		{
+			name:        "missing password",
+			store:       &mocks.Store{Invitations: []*store.Invitation{invitation()}},
+			token:       "token",
+			user:        &store.User{FirstName: "Jane", LastName: "Doe", Terms: true},
+			expectedErr: fmt.Errorf("%w: %w", ErrInvalidDetails, errMissingPassword),
+		},
+		{
+			name: "email registered meanwhile",
+			store: &mocks.Store{
+				User:        &store.User{Email: "jane@example.com"},
+				Invitations: []*store.Invitation{invitation()},
+			},
+			token:       "token",
+			user:        invitee(),
+			expectedErr: ErrEmailAlreadyExists,
+		},
+		{
+			name:  "accepted",
+			store: &mocks.Store{Invitations: []*store.Invitation{invitation()}},
+			token: "token",
+			user:  invitee(),
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			i := NewInviter(sc.store, &mocks.Mailer{}, logrus.New(), acceptURL)
+			user, err := i.Accept(context.Background(), sc.token, sc.user)
+			assert.Equal(t, sc.expectedErr, err)
+			if sc.expectedErr != nil {
+				return
+			}
+
+			assert.Equal(t, "jane@example.com", user.Email)
+			assert.Equal(t, []string{"support"}, user.Roles)
+			assert.NotEqual(t, "secret123", user.Password)
+		})
+	}
+}
@@ -9,7 +9,7 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/riyadennis/identity-server/business/validation"
+	"github.com/riyadennis/identity-server/business/authz"
 	"github.com/riyadennis/identity-server/foundation"
 	"github.com/sirupsen/logrus"
 
@@ -19,41 +19,80 @@ import (
 type Helper struct {
 	Store         store.Store
 	Authenticator store.Authenticator
+	Logins        *LoginRecorder
 	Logger        *logrus.Logger
 }
 
 var (
-	errEmailNotFound   = errors.New("email not found")
-	errInvalidPassword = errors.New("invalid password")
+	// ErrInvalidPassword is returned when the password does not match the one of the user.
+	ErrInvalidPassword = errors.New("invalid password")
+
+	errEmailNotFound = errors.New("email not found")
 )
 
-func NewHelper(s store.Store, a store.Authenticator, l *logrus.Logger) *Helper {
+// NewHelper returns a Helper that sends the notices of logins from new devices with mailer.
+func NewHelper(s store.Store, a store.Authenticator, mailer foundation.Mailer, l *logrus.Logger) *Helper {
 	return &Helper{
 		Store:         s,
 		Authenticator: a,
+		Logins:        NewLoginRecorder(s, mailer, l),
 		Logger:        l,
 	}
 }
 
 func (h *Helper) Login(ctx context.Context, tc *store.TokenConfig, email, password string) (*store.Token, error) {
-	err := validation.ValidateEmail(email)
+	user, err := h.UserCredentialsInDB(ctx, store.NormalizeEmail(email), password)
 	if err != nil {
+		// already logged
 		return nil, err
 	}
-	user, err := h.UserCredentialsInDB(ctx, email, password)
+	orgID, err := h.DefaultOrganization(ctx, user.ID)
 	if err != nil {
-		// already logged
 		return nil, err
 	}
-	token, err := h.ManageToken(ctx, tc, user.ID)
+	token, err := h.ManageToken(ctx, tc, user.ID, orgID)
 	if err != nil {
 		// already logged
 		return nil, err
 	}
+	h.Logins.Succeeded(ctx, user)
 
 	return token, nil
 }
 
+// DefaultOrganization returns the organization a user logs in to, that is
+// the one they joined first. Superadmins might not belong to any.
+func (h *Helper) DefaultOrganization(ctx context.Context, userID string) (string, error) {
+	organizations, err := h.Store.ListOrganizations(ctx, userID)
+	if err != nil {
+		h.Logger.Errorf("failed to fetch organizations of user: %v", err)
+		return "", err
+	}
+	if len(organizations) == 0 {
+		return "", nil
+	}
+
+	return organizations[0].ID, nil
+}
+
+// SwitchOrganization issues a token for another organization the user is a member of.
+func (h *Helper) SwitchOrganization(ctx context.Context, tc *store.TokenConfig, userID, orgID string) (*store.Token, error) {
+	if _, err := h.Store.RetrieveOrganization(ctx, orgID); err != nil {
+		h.Logger.Errorf("failed to find organization %s: %v", orgID, err)
+		return nil, err
+	}
+
+	_, err := authz.NewAuthorizer(h.Store, h.Logger).Tenant(ctx, authz.Subject{UserID: userID}, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.issueToken(ctx, tc, userID, orgID, store.AuditOrganizationSwitched)
+}
+
+// UserCredentialsInDB returns the user with email when password is theirs.
+// Failed attempts are added to the login history of the user, successful ones
+// are added with Logins.Succeeded once the token is issued.
 func (h *Helper) UserCredentialsInDB(ctx context.Context, email, password string) (*store.User, error) {
 	user, err := h.Store.Read(ctx, email)
 	if err != nil {
@@ -62,22 +101,54 @@ func (h *Helper) UserCredentialsInDB(ctx context.Context, email, password string
 	}
 	if user == nil {
 		h.Logger.Printf("user not found in DB")
+		h.Logins.Failed(ctx, "", store.LoginFailureUnknownEmail)
 		return nil, errEmailNotFound
 	}
 	valid, err := h.Authenticator.Authenticate(email, password)
 	if err != nil {
 		h.Logger.Errorf("failed to authenticate provided password %v", err)
-		return nil, errInvalidPassword
+		h.Logins.Failed(ctx, user.ID, store.LoginFailureInvalidPassword)
+		return nil, ErrInvalidPassword
 	}
 	if !valid {
 		h.Logger.Errorf("failed to authenticate user: %v", err)
-		return nil, errInvalidPassword
+		h.Logins.Failed(ctx, user.ID, store.LoginFailureInvalidPassword)
+		return nil, ErrInvalidPassword
 	}
 	return user, nil
 }
 
-func (h *Helper) ManageToken(ctx context.Context, config *store.TokenConfig, userID string) (*store.Token, error) {
-	tr, err := h.Authenticator.FetchLoginToken(userID)
+// ManageToken returns the token of the user for an organization, a new one is
+// issued when there is none or it has expired. The login is recorded in the audit log.
+func (h *Helper) ManageToken(ctx context.Context, config *store.TokenConfig, userID, orgID string) (*store.Token, error) {
+	return h.issueToken(ctx, config, userID, orgID, store.AuditUserLoggedIn)
+}
+
+// issueToken returns a token of the user for the organization and records
+// action in the audit log. The token is already stored when the event is
+// written, so an event that can not be written is logged and the token returned.
+func (h *Helper) issueToken(ctx context.Context, config *store.TokenConfig, userID, orgID, action string) (*store.Token, error) {
+	token, err := h.token(ctx, config, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = h.Store.RecordAudit(ctx, &store.AuditEvent{
+		OrganizationID: orgID,
+		ActorID:        userID,
+		Action:         action,
+		TargetType:     store.AuditTargetUser,
+		TargetID:       userID,
+	})
+	if err != nil {
+		h.Logger.Errorf("failed to record %s of user %s: %v", action, userID, err)
+	}
+
+	return token, nil
+}
+
+func (h *Helper) token(ctx context.Context, config *store.TokenConfig, userID, orgID string) (*store.Token, error) {
+	tr, err := h.Authenticator.FetchLoginToken(userID, orgID)
 	if err != nil {
 		h.Logger.Errorf("failed to fetch token from DB: %v", err)
 		return nil, err
@@ -101,22 +172,26 @@ func (h *Helper) ManageToken(ctx context.Context, config *store.TokenConfig, use
 	}
 	expiryTime := time.Now().UTC().Add(120 * time.Hour)
 
-	token, err := store.GenerateToken(h.Logger, key, &jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(expiryTime),
-		Issuer:    config.Issuer,
-		Subject:   userID,
-		// need to change this later
-		Audience: jwt.ClaimStrings{"local"},
+	token, err := store.GenerateToken(h.Logger, key, &store.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiryTime),
+			Issuer:    config.Issuer,
+			Subject:   userID,
+			// need to change this later
+			Audience: jwt.ClaimStrings{"local"},
+		},
+		OrgID: orgID,
 	})
 	if err != nil {
 		h.Logger.Errorf("failed to generate token: %v", err)
 		return nil, err
 	}
 	err = h.Authenticator.SaveLoginToken(ctx, &store.TokenRecord{
-		UserID: userID,
-		Token:  token.AccessToken,
-		Expiry: expiryTime,
-		TTL:    fmt.Sprintf("%d", expiryTime.Unix()),
+		UserID:         userID,
+		OrganizationID: orgID,
+		Token:          token.AccessToken,
+		Expiry:         expiryTime,
+		TTL:            fmt.Sprintf("%d", expiryTime.Unix()),
 	})
 	if err != nil {
 		h.Logger.Printf("token saving failed: %v", err)
@@ -0,0 +1,159 @@
+package business
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+const (
+	// ipv4RangeBits and ipv6RangeBits are the prefixes of the networks logins
+	// are compared by, roughly the network of a home or an office.
+	ipv4RangeBits = 24
+	ipv6RangeBits = 48
+
+	// noticeTimeout is how long sending the notice of a login from a new
+	// device can take.
+	noticeTimeout = 30 * time.Second
+)
+
+// LoginRecorder keeps the history of logins and tells users when their
+// account is logged in to from a device or network it was not used from before.
+type LoginRecorder struct {
+	Store  store.Store
+	Mailer foundation.Mailer
+	Logger *logrus.Logger
+	// notices are the notices being sent.
+	notices sync.WaitGroup
+}
+
+func NewLoginRecorder(st store.Store, mailer foundation.Mailer, logger *logrus.Logger) *LoginRecorder {
+	return &LoginRecorder{
+		Store:  st,
+		Mailer: mailer,
+		Logger: logger,
+	}
+}
+
+// Failed records a login that failed for reason, userID is empty when
+// nobody has the email that was used.
+func (l *LoginRecorder) Failed(ctx context.Context, userID, reason string) {
+	a := newLoginAttempt(ctx, userID)
+	a.FailureReason = reason
+	l.record(ctx, a)
+}
+
+// Succeeded records a successful login of user and sends a notice when it
+// comes from a new device or network. The first login of a user is never new.
+// The notice is sent in the background so a slow mail server does not hold
+// the login up.
+func (l *LoginRecorder) Succeeded(ctx context.Context, user *store.User) {
+	a := newLoginAttempt(ctx, user.ID)
+	a.Success = true
+
+	known, err := l.Store.KnownLogins(ctx, user.ID, a.Device, a.IPRange)
+	if err != nil {
+		l.Logger.Errorf("failed to read the login history of %s: %v", user.ID, err)
+	}
+	l.record(ctx, a)
+
+	if known == nil || known.Logins == 0 || (known.Device && known.IPRange) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), noticeTimeout)
+	l.notices.Add(1)
+	go func() {
+		defer l.notices.Done()
+		defer cancel()
+		l.notify(ctx, user, a)
+	}()
+}
+
+// Wait blocks until the notices being sent are done or ctx is, servers call it
+// when they shut down so notices of the last logins are not dropped.
+func (l *LoginRecorder) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		l.notices.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// notify tells user about the login attempt a.
+func (l *LoginRecorder) notify(ctx context.Context, user *store.User, a *store.LoginAttempt) {
+	err := l.Mailer.Send(ctx, foundation.Mail{
+		To:      user.Email,
+		Subject: "New login to your account",
+		Body: fmt.Sprintf("Your account was logged in to from a device or network it was not used from before.\n\n"+
+			"Time: %s\nIP address: %s\nDevice: %s\n\n"+
+			"If this was you there is nothing to do, otherwise change your password straight away.\n",
+			a.CreatedAt.Format(time.RFC1123), a.IP, a.UserAgent),
+	})
+	if err != nil {
+		l.Logger.Errorf("failed to send new login notice to %s: %v", user.ID, err)
+	}
+}
+
+// record saves the attempt, logins go ahead when it can not be saved.
+func (l *LoginRecorder) record(ctx context.Context, a *store.LoginAttempt) {
+	if err := l.Store.RecordLoginAttempt(ctx, a); err != nil {
+		l.Logger.Errorf("failed to record login attempt of %q: %v", a.UserID, err)
+	}
+}
+
+// newLoginAttempt returns an attempt by userID from the client of the request in ctx.
+func newLoginAttempt(ctx context.Context, userID string) *store.LoginAttempt {
+	actor := store.ActorFromContext(ctx)
+	return &store.LoginAttempt{
+		UserID:    userID,
+		IP:        actor.IP,
+		IPRange:   ipRange(actor.IP),
+		UserAgent: actor.UserAgent,
+		Device:    deviceFingerprint(actor.UserAgent),
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// deviceFingerprint identifies the device a request comes from by its user agent.
+func deviceFingerprint(userAgent string) string {
+	userAgent = strings.ToLower(strings.TrimSpace(userAgent))
+	if userAgent == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(userAgent))
+	return hex.EncodeToString(sum[:])
+}
+
+// ipRange returns the network ip is in, it is empty when ip is not an address.
+func ipRange(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+	bits := ipv6RangeBits
+	if addr.Is4() {
+		bits = ipv4RangeBits
+	}
+	prefix, err := addr.WithZone("").Prefix(bits)
+	if err != nil {
+		return ""
+	}
+	return prefix.String()
+}
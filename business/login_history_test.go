@@ -0,0 +1,188 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+func TestLoginRecorder_Succeeded(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		store          *mocks.Store
+		mailer         *mocks.Mailer
+		expectedNotice bool
+	}{
+		{
+			name:   "first login",
+			store:  &mocks.Store{},
+			mailer: &mocks.Mailer{},
+		},
+		{
+			name:   "known device and network",
+			store:  &mocks.Store{Known: &store.KnownLogins{Logins: 4, Device: true, IPRange: true}},
+			mailer: &mocks.Mailer{},
+		},
+		{
+			name:           "new device",
+			store:          &mocks.Store{Known: &store.KnownLogins{Logins: 4, IPRange: true}},
+			mailer:         &mocks.Mailer{},
+			expectedNotice: true,
+		},
+		{
+			name:           "new network",
+			store:          &mocks.Store{Known: &store.KnownLogins{Logins: 4, Device: true}},
+			mailer:         &mocks.Mailer{},
+			expectedNotice: true,
+		},
+		{
+			name:   "notice can not be sent",
+			store:  &mocks.Store{Known: &store.KnownLogins{Logins: 1}},
+			mailer: &mocks.Mailer{Error: errors.New("smtp error")},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			ctx := store.WithActor(context.Background(), store.Actor{IP: "203.0.113.7", UserAgent: "Mozilla/5.0"})
+			recorder := NewLoginRecorder(sc.store, sc.mailer, logrus.New())
+			recorder.Succeeded(ctx, &store.User{ID: "user-1", Email: "jane@example.com"})
+			require.NoError(t, recorder.Wait(context.Background()))
+
+			require.Len(t, sc.store.LoginAttempts, 1)
+			a := sc.store.LoginAttempts[0]
+			assert.Equal(t, "user-1", a.UserID)
+			assert.True(t, a.Success)
+			assert.Equal(t, "203.0.113.7", a.IP)
+			assert.Equal(t, "203.0.113.0/24", a.IPRange)
+			assert.Equal(t, deviceFingerprint("Mozilla/5.0"), a.Device)
+
+			if !sc.expectedNotice {
+				assert.Empty(t, sc.mailer.Sent)
+				return
+			}
+			require.Len(t, sc.mailer.Sent, 1)
+			assert.Equal(t, "jane@example.com", sc.mailer.Sent[0].To)
+			assert.Contains(t, sc.mailer.Sent[0].Body, "203.0.113.7")
+			assert.Contains(t, sc.mailer.Sent[0].Body, "Mozilla/5.0")
+		})
+	}
+}
+
+// blockingMailer sends mail once release is closed.
+type blockingMailer struct {
+	release chan struct{}
+}
+
+func (m *blockingMailer) Send(ctx context.Context, _ foundation.Mail) error {
+	select {
+	case <-m.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestLoginRecorder_Wait(t *testing.T) {
+	mailer := &blockingMailer{release: make(chan struct{})}
+	recorder := NewLoginRecorder(&mocks.Store{Known: &store.KnownLogins{Logins: 4}}, mailer, logrus.New())
+	recorder.Succeeded(context.Background(), &store.User{ID: "user-1", Email: "jane@example.com"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, recorder.Wait(ctx), context.DeadlineExceeded)
+
+	close(mailer.release)
+	assert.NoError(t, recorder.Wait(context.Background()))
+}
+
+func TestLoginRecordsAttempts(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		user           *store.User
+		valid          bool
+		expectedUserID string
+		expectedReason string
+	}{
+		{
+			name:           "unknown email",
+			expectedReason: store.LoginFailureUnknownEmail,
+		},
+		{
+			name:           "invalid password",
+			user:           &store.User{ID: "user-1", Email: "jane@example.com"},
+			expectedUserID: "user-1",
+			expectedReason: store.LoginFailureInvalidPassword,
+		},
+		{
+			name:           "logged in",
+			user:           &store.User{ID: "user-1", Email: "jane@example.com"},
+			valid:          true,
+			expectedUserID: "user-1",
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			st := &mocks.Store{User: sc.user}
+			auth := &mocks.Authenticator{
+				ReturnVal: sc.valid,
+				Token:     &store.TokenRecord{Token: "existing-jwt-token", Expiry: time.Now().Add(time.Hour)},
+			}
+			helper := NewHelper(st, auth, &mocks.Mailer{}, logrus.New())
+			_, _ = helper.Login(context.Background(), &store.TokenConfig{}, "jane@example.com", "password")
+
+			require.Len(t, st.LoginAttempts, 1)
+			assert.Equal(t, sc.expectedUserID, st.LoginAttempts[0].UserID)
+			assert.Equal(t, sc.valid, st.LoginAttempts[0].Success)
+			assert.Equal(t, sc.expectedReason, st.LoginAttempts[0].FailureReason)
+		})
+	}
+}
+
+func TestLoginRecordsAttempts_UnknownEmail(t *testing.T) {
+	// the store finds no row for the email, the attempt is recorded without a user.
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	mock.ExpectQuery(regexp.QuoteMeta(store.ReadQuery)).
+		WithArgs("jane@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "first_name", "last_name", "email", "company", "post_code",
+			"created_by", "active", "created_at", "updated_at"}))
+	mock.ExpectExec(`INSERT INTO login_attempts`).
+		WithArgs(sqlmock.AnyArg(), nil, "", "", "", "", false, store.LoginFailureUnknownEmail, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	auth := &mocks.Authenticator{ReturnVal: true}
+	helper := NewHelper(store.NewDB(conn), auth, &mocks.Mailer{}, logrus.New())
+	user, err := helper.UserCredentialsInDB(context.Background(), "jane@example.com", "password")
+	assert.ErrorIs(t, err, errEmailNotFound)
+	assert.Nil(t, user)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIPRange(t *testing.T) {
+	scenarios := []struct {
+		ip       string
+		expected string
+	}{
+		{ip: "203.0.113.7", expected: "203.0.113.0/24"},
+		{ip: "::ffff:203.0.113.7", expected: "203.0.113.0/24"},
+		{ip: "2001:db8:1234:5678::1", expected: "2001:db8:1234::/48"},
+		{ip: "", expected: ""},
+		{ip: "not an ip", expected: ""},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.ip, func(t *testing.T) {
+			assert.Equal(t, sc.expected, ipRange(sc.ip))
+		})
+	}
+}
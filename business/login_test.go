@@ -3,6 +3,7 @@ package business
 import (
 	"context"
 	"errors"
+	"net/http"
 	"os"
 	"path/filepath"
 	"testing"
@@ -53,7 +54,7 @@ func TestUserCredentialsInDB(t *testing.T) {
 			},
 			mockAuth:      &mocks.Authenticator{Error: errors.New("err")},
 			expectedUser:  nil,
-			expectedError: errInvalidPassword,
+			expectedError: ErrInvalidPassword,
 		},
 		{
 			name:     "authentication returns false",
@@ -68,7 +69,7 @@ func TestUserCredentialsInDB(t *testing.T) {
 				},
 			},
 			mockAuth:      &mocks.Authenticator{ReturnVal: false},
-			expectedError: errInvalidPassword,
+			expectedError: ErrInvalidPassword,
 		},
 		{
 			name:     "successful authentication",
@@ -98,7 +99,7 @@ func TestUserCredentialsInDB(t *testing.T) {
 			logger := logrus.New()
 			logger.SetOutput(os.Stderr)
 
-			helper := NewHelper(tc.mockStore, tc.mockAuth, logger)
+			helper := NewHelper(tc.mockStore, tc.mockAuth, &mocks.Mailer{}, logger)
 			user, err := helper.UserCredentialsInDB(context.Background(), tc.email, tc.password)
 
 			assert.Equal(t, tc.expectedError, err)
@@ -133,6 +134,23 @@ func TestLogin(t *testing.T) {
 			mockAuth:      &mocks.Authenticator{},
 			expectedError: true,
 		},
+		{
+			name:     "email registered before the syntax rules",
+			email:    "Legacy..Jane@Example.com",
+			password: "correctpassword",
+			mockStore: &mocks.Store{
+				User: &store.User{ID: "user123", Email: "legacy..jane@example.com"},
+			},
+			mockAuth: &mocks.Authenticator{
+				ReturnVal: true,
+				Token: &store.TokenRecord{
+					ID:     "token123",
+					Token:  "existing-jwt-token",
+					Expiry: futureExpiry,
+					TTL:    "3600",
+				},
+			},
+		},
 		{
 			name:     "successful login with existing token",
 			email:    "test@example.com",
@@ -161,7 +179,7 @@ func TestLogin(t *testing.T) {
 			logger := logrus.New()
 			logger.SetOutput(os.Stderr)
 
-			helper := NewHelper(tc.mockStore, tc.mockAuth, logger)
+			helper := NewHelper(tc.mockStore, tc.mockAuth, &mocks.Mailer{}, logger)
 			token, err := helper.Login(context.Background(), &store.TokenConfig{}, tc.email, tc.password)
 			if tc.expectedError {
 				assert.Error(t, err)
@@ -174,6 +192,33 @@ func TestLogin(t *testing.T) {
 	}
 }
 
+func TestLoginRecordsAudit(t *testing.T) {
+	st := &mocks.Store{
+		User:          &store.User{ID: "user123", Email: "test@example.com"},
+		Organizations: []*store.Organization{{ID: "org-1"}},
+	}
+	auth := &mocks.Authenticator{
+		ReturnVal: true,
+		Token: &store.TokenRecord{
+			ID:     "token123",
+			Token:  "existing-jwt-token",
+			Expiry: time.Now().Add(time.Hour),
+			TTL:    "3600",
+		},
+	}
+
+	helper := NewHelper(st, auth, &mocks.Mailer{}, logrus.New())
+	_, err := helper.Login(context.Background(), &store.TokenConfig{}, "test@example.com", "correctpassword")
+	assert.NoError(t, err)
+	assert.Equal(t, []*store.AuditEvent{{
+		OrganizationID: "org-1",
+		ActorID:        "user123",
+		Action:         store.AuditUserLoggedIn,
+		TargetType:     store.AuditTargetUser,
+		TargetID:       "user123",
+	}}, st.AuditEvents)
+}
+
 func TestManageToken(t *testing.T) {
 	// Create a temporary directory for test keys
 	tempDir, err := os.MkdirTemp("", "test-keys")
@@ -204,6 +249,30 @@ func TestManageToken(t *testing.T) {
 			mockAuth:      &mocks.Authenticator{},
 			expectedError: errors.New("database error"),
 		},
+		{
+			name: "login can not be recorded - token is still returned",
+			config: &store.TokenConfig{
+				Issuer:         "test-issuer",
+				KeyPath:        tempDir + "/",
+				PrivateKeyName: "private.pem",
+				PublicKeyName:  "public.pem",
+			},
+			userID:    "user123",
+			mockStore: &mocks.Store{Error: errors.New("error")},
+			mockAuth: &mocks.Authenticator{Token: &store.TokenRecord{
+				ID:     "token123",
+				Token:  "existing-jwt-token",
+				Expiry: futureExpiry,
+				TTL:    "3600",
+			}},
+			expectedToken: &store.Token{
+				Status:      http.StatusOK,
+				AccessToken: "existing-jwt-token",
+				TokenType:   "Bearer",
+				Expiry:      futureExpiry.String(),
+				TokenTTL:    "3600",
+			},
+		},
 		{
 			name: "token exists and not expired - reuse existing token",
 			config: &store.TokenConfig{
@@ -301,12 +370,15 @@ func TestManageToken(t *testing.T) {
 			logger := logrus.New()
 			logger.SetOutput(os.Stderr)
 
-			helper := NewHelper(tc.mockStore, tc.mockAuth, logger)
+			helper := NewHelper(tc.mockStore, tc.mockAuth, &mocks.Mailer{}, logger)
 
-			_, err := helper.ManageToken(context.Background(), tc.config, tc.userID)
+			token, err := helper.ManageToken(context.Background(), tc.config, tc.userID, "org-1")
 			if err != nil {
 				assert.EqualError(t, tc.expectedError, err.Error())
 			}
+			if tc.expectedToken != nil {
+				assert.Equal(t, tc.expectedToken, token)
+			}
 			// Clean up generated keys if any
 			privateKeyPath := filepath.Join(tc.config.KeyPath, tc.config.PrivateKeyName)
 			publicKeyPath := filepath.Join(tc.config.KeyPath, tc.config.PublicKeyName)
@@ -315,3 +387,53 @@ func TestManageToken(t *testing.T) {
 		})
 	}
 }
+
+func TestSwitchOrganization(t *testing.T) {
+	futureExpiry := time.Now().Add(time.Hour)
+	existing := &mocks.Authenticator{Token: &store.TokenRecord{
+		ID:     "token123",
+		Token:  "org-2-token",
+		Expiry: futureExpiry,
+		TTL:    "3600",
+	}}
+	testCases := []struct {
+		name          string
+		mockStore     *mocks.Store
+		orgID         string
+		expectedToken string
+		expectedError error
+	}{
+		{
+			name:          "unknown organization",
+			mockStore:     &mocks.Store{},
+			orgID:         "org-3",
+			expectedError: store.ErrOrganizationNotFound,
+		},
+		{
+			name: "member",
+			mockStore: &mocks.Store{
+				User:          &store.User{ID: "user123", Roles: []string{"user"}},
+				Organizations: []*store.Organization{{ID: "org-1"}, {ID: "org-2"}},
+			},
+			orgID:         "org-2",
+			expectedToken: "org-2-token",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			helper := NewHelper(tc.mockStore, existing, &mocks.Mailer{}, logrus.New())
+			token, err := helper.SwitchOrganization(context.Background(), &store.TokenConfig{}, "user123", tc.orgID)
+			assert.Equal(t, tc.expectedError, err)
+			if tc.expectedToken != "" {
+				assert.Equal(t, tc.expectedToken, token.AccessToken)
+				assert.Equal(t, []*store.AuditEvent{{
+					OrganizationID: tc.orgID,
+					ActorID:        "user123",
+					Action:         store.AuditOrganizationSwitched,
+					TargetType:     store.AuditTargetUser,
+					TargetID:       "user123",
+				}}, tc.mockStore.AuditEvents)
+			}
+		})
+	}
+}
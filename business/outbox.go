@@ -0,0 +1,81 @@
+package business
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+const (
+	// RelayInterval is how often the relay looks for events to publish.
+	RelayInterval = time.Second
+
+	// relayBatchSize is the most events published in a single transaction.
+	relayBatchSize = 100
+)
+
+// Relay publishes the domain events written to the outbox by the store.
+// Every event is published at least once and the events of a user are
+// published in the order they happened.
+type Relay struct {
+	Store     store.Store
+	Publisher foundation.Publisher
+	Logger    *logrus.Logger
+}
+
+func NewRelay(st store.Store, publisher foundation.Publisher, logger *logrus.Logger) *Relay {
+	return &Relay{
+		Store:     st,
+		Publisher: publisher,
+		Logger:    logger,
+	}
+}
+
+// Relay publishes the events waiting in the outbox, it returns the number of
+// events published. Events that can not be published are retried by the next call.
+func (r *Relay) Relay(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		published, err := r.Store.RelayEvents(ctx, relayBatchSize, func(e *store.OutboxEvent) error {
+			err := r.Publisher.Publish(ctx, foundation.Message{
+				ID:          e.ID,
+				Type:        e.Type,
+				AggregateID: e.AggregateID,
+				Payload:     e.Payload,
+				OccurredAt:  e.OccurredAt,
+			})
+			if err != nil {
+				r.Logger.Errorf("failed to publish event %s after %d attempts: %v", e.ID, e.Attempts+1, err)
+			}
+			return err
+		})
+		if err != nil {
+			return total, err
+		}
+		total += published
+		if published < relayBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// Run publishes the events in the outbox every interval until ctx is done.
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Relay(ctx); err != nil {
+			r.Logger.Errorf("failed to relay events: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
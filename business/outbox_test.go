@@ -0,0 +1,85 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+)
+
+func outboxEvents(n int, aggregateID string) []*store.OutboxEvent {
+	events := make([]*store.OutboxEvent, 0, n)
+	for i := range n {
+		events = append(events, &store.OutboxEvent{ID: fmt.Sprintf("%s-%d", aggregateID, i), AggregateID: aggregateID})
+	}
+	return events
+}
+
+func TestRelay_Relay(t *testing.T) {
+	scenarios := []struct {
+		name              string
+		store             *mocks.Store
+		publisher         *mocks.Publisher
+		expectedPublished []string
+		expectedPending   int
+		expectedErr       error
+	}{
+		{
+			name:        "store error",
+			store:       &mocks.Store{Error: errors.New("db down")},
+			publisher:   &mocks.Publisher{},
+			expectedErr: errors.New("db down"),
+		},
+		{
+			name: "in order",
+			store: &mocks.Store{Outbox: []*store.OutboxEvent{
+				{ID: "1", AggregateID: "user-1"}, {ID: "2", AggregateID: "user-2"}, {ID: "3", AggregateID: "user-1"},
+			}},
+			publisher:         &mocks.Publisher{},
+			expectedPublished: []string{"1", "2", "3"},
+		},
+		{
+			name: "failed event is kept",
+			store: &mocks.Store{Outbox: []*store.OutboxEvent{
+				{ID: "1", AggregateID: "user-1"}, {ID: "2", AggregateID: "user-2"},
+			}},
+			publisher:         &mocks.Publisher{Fail: []string{"1"}},
+			expectedPublished: []string{"2"},
+			expectedPending:   1,
+		},
+		{
+			name:              "more than a batch",
+			store:             &mocks.Store{Outbox: outboxEvents(relayBatchSize+1, "user-1")},
+			publisher:         &mocks.Publisher{},
+			expectedPublished: ids(outboxEvents(relayBatchSize+1, "user-1")),
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			published, err := NewRelay(sc.store, sc.publisher, logrus.New()).Relay(context.Background())
+			assert.Equal(t, sc.expectedErr, err)
+			assert.Equal(t, len(sc.expectedPublished), published)
+
+			var publishedIDs []string
+			for _, m := range sc.publisher.Published {
+				publishedIDs = append(publishedIDs, m.ID)
+			}
+			assert.Equal(t, sc.expectedPublished, publishedIDs)
+			assert.Len(t, sc.store.Outbox, sc.expectedPending)
+		})
+	}
+}
+
+func ids(events []*store.OutboxEvent) []string {
+	ids := make([]string, 0, len(events))
+	for _, e := range events {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
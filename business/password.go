@@ -2,31 +2,30 @@ package business
 
 import (
 	"crypto/rand"
-	"fmt"
 	"math/big"
 	"strings"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
-const passwordSeed = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+const (
+	passwordSeed   = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	passwordLength = 24
+)
 
-// GeneratePassword will create a password for user login.
+// GeneratePassword will create a random password for user login, it is
+// also used for the single use tokens sent to users by email.
 func GeneratePassword() (string, error) {
-	result := ""
-	for {
-		if len(result) >= 15 {
-			return result, nil
-		}
-		num, err := rand.Int(rand.Reader, big.NewInt(int64(127)))
+	var result strings.Builder
+	for result.Len() < passwordLength {
+		num, err := rand.Int(rand.Reader, big.NewInt(int64(len(passwordSeed))))
 		if err != nil {
 			return "", err
 		}
-		s := fmt.Sprintf("%d", num.Int64())
-		if strings.Contains(passwordSeed, s) {
-			result += s
-		}
+		result.WriteByte(passwordSeed[num.Int64()])
 	}
+
+	return result.String(), nil
 }
 
 // EncryptPassword will encrypt that for security.
@@ -13,9 +13,11 @@ func TestGeneratePassword(t *testing.T) {
 		name      string
 		minLength int
 		unique    bool
+		seedOnly  bool
 	}{
 		{name: "meets minimum length", minLength: 15},
 		{name: "produces unique values", unique: true},
+		{name: "uses letters and digits", seedOnly: true},
 	}
 
 	for _, sc := range scenarios {
@@ -27,6 +29,12 @@ func TestGeneratePassword(t *testing.T) {
 				assert.GreaterOrEqual(t, len(p1), sc.minLength)
 			}
 
+			if sc.seedOnly {
+				for _, c := range p1 {
+					assert.Contains(t, passwordSeed, string(c))
+				}
+			}
+
 			if sc.unique {
 				p2, err := GeneratePassword()
 				require.NoError(t, err)
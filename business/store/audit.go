@@ -0,0 +1,317 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Actions recorded in the audit log.
+const (
+	AuditUserCreated           = "user.created"
+	AuditUserActivationToggled = "user.activation_toggled"
+	AuditUserDeleted           = "user.deleted"
+	AuditUserRestored          = "user.restored"
+	AuditUserPurged            = "user.purged"
+	AuditUserErased            = "user.erased"
+	AuditUserLoggedIn          = "user.logged_in"
+	AuditOrganizationSwitched  = "user.organization_switched"
+	AuditRoleAssigned          = "role.assigned"
+	AuditRoleRevoked           = "role.revoked"
+	AuditRoleCreated           = "role.created"
+	AuditRolePermissionsSet    = "role.permissions_changed"
+	AuditRoleDeleted           = "role.deleted"
+	AuditWebhookCreated        = "webhook.created"
+	AuditWebhookDeleted        = "webhook.deleted"
+)
+
+// Types of the targets of audit events.
+const (
+	AuditTargetUser    = "user"
+	AuditTargetRole    = "role"
+	AuditTargetWebhook = "webhook"
+)
+
+// AuditStore records security relevant events and reads them back.
+type AuditStore interface {
+	RecordAudit(ctx context.Context, e *AuditEvent) error
+	ListAuditEvents(ctx context.Context, opts AuditOptions) (*AuditPage, error)
+}
+
+// Actor is who makes a change and the request it is made in, it is recorded
+// with the audit events of the change.
+type Actor struct {
+	// UserID is empty for changes made by the service itself or by users
+	// that have not logged in, such as registrations.
+	UserID    string
+	IP        string
+	UserAgent string
+	RequestID string
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx with the actor that audit events are recorded for.
+func WithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, a)
+}
+
+// ActorFromContext returns the actor set by WithActor.
+func ActorFromContext(ctx context.Context) Actor {
+	a, _ := ctx.Value(actorKey{}).(Actor)
+	return a
+}
+
+// AuditEvent is an entry of the audit log. Before and After are JSON
+// objects with the values that changed, either can be empty.
+type AuditEvent struct {
+	ID             string          `json:"id"`
+	OrganizationID string          `json:"organization_id"`
+	ActorID        string          `json:"actor_id"`
+	Action         string          `json:"action"`
+	TargetType     string          `json:"target_type"`
+	TargetID       string          `json:"target_id"`
+	Before         json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After          json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+	IP             string          `json:"ip"`
+	UserAgent      string          `json:"user_agent"`
+	RequestID      string          `json:"request_id"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// AuditOptions narrows down the events returned by ListAuditEvents, filters
+// that are not set match every event.
+type AuditOptions struct {
+	ActorID  string
+	TargetID string
+	Action   string
+	// Since and Until limit events to those recorded from Since and before Until.
+	Since time.Time
+	Until time.Time
+
+	// First is the size of the page, DefaultPageSize when 0 and never more than MaxPageSize.
+	First int
+	// After is the cursor of the event the page starts after.
+	After string
+}
+
+// AuditPage is a page of audit events, newest first, and where the next one starts.
+type AuditPage struct {
+	Events []*AuditEvent `json:"events"`
+	// NextCursor is the cursor of the last event, it is empty on the last page.
+	NextCursor  string `json:"next_cursor,omitempty"`
+	HasNextPage bool   `json:"has_next_page"`
+}
+
+// timeCursor identifies the position of a row in a listing ordered by creation time.
+type timeCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+}
+
+func encodeTimeCursor(createdAt time.Time, id string) string {
+	b, _ := json.Marshal(timeCursor{CreatedAt: createdAt, ID: id})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeTimeCursor(s string) (*timeCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	c := &timeCursor{}
+	if err := json.Unmarshal(b, c); err != nil || c.ID == "" || c.CreatedAt.IsZero() {
+		return nil, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// AuditCursor returns the cursor of e, it is passed as AuditOptions.After to
+// list the events recorded before e.
+func AuditCursor(e *AuditEvent) string {
+	return encodeTimeCursor(e.CreatedAt, e.ID)
+}
+
+// auditValue encodes the before or after value of an event, nil stays empty.
+func auditValue(v any) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return b
+}
+
+const auditColumns = `id, organization_id, actor_id, action, target_type, target_id,
+before_value, after_value, ip, user_agent, request_id, created_at`
+
+// recordAudit appends e to the audit log with db, which is the transaction
+// of the change e describes. The actor and request come from ctx unless e
+// has them, the organization defaults to the tenant's.
+func recordAudit(ctx context.Context, db execer, e *AuditEvent) error {
+	a := ActorFromContext(ctx)
+	if e.ActorID == "" {
+		e.ActorID = a.UserID
+	}
+	if e.OrganizationID == "" {
+		t, _ := TenantFromContext(ctx)
+		e.OrganizationID = t.OrganizationID
+	}
+	e.ID = uuid.New().String()
+	e.IP = a.IP
+	e.UserAgent = truncate(a.UserAgent, 255)
+	e.RequestID = a.RequestID
+	e.CreatedAt = time.Now().UTC()
+
+	_, err := db.ExecContext(ctx, `INSERT INTO audit_events (`+auditColumns+`)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.ID, e.OrganizationID, e.ActorID, e.Action, e.TargetType, e.TargetID,
+		nullJSON(e.Before), nullJSON(e.After), e.IP, e.UserAgent, e.RequestID, e.CreatedAt)
+	return err
+}
+
+// nullJSON stores an empty value as NULL.
+func nullJSON(v json.RawMessage) any {
+	if len(v) == 0 {
+		return nil
+	}
+	return string(v)
+}
+
+func truncate(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// RecordAudit appends an event that is not part of a change to the data,
+// such as a login, to the audit log.
+func (m *MYSQL) RecordAudit(ctx context.Context, e *AuditEvent) error {
+	if m.Conn == nil {
+		return errEmptyDBConnection
+	}
+
+	return recordAudit(ctx, m.Conn, e)
+}
+
+// tenantAudit restricts audit_events to the events of the tenant's
+// organization, platform administrators see every event.
+func tenantAudit(ctx context.Context) (string, []any) {
+	t, ok := TenantFromContext(ctx)
+	if !ok || t.Platform {
+		return "TRUE", nil
+	}
+
+	return `organization_id = ?`, []any{t.OrganizationID}
+}
+
+// ListAuditEvents returns a page of the events of the tenant that match the
+// filters of opts, newest first.
+func (m *MYSQL) ListAuditEvents(ctx context.Context, opts AuditOptions) (*AuditPage, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	first := opts.First
+	if first <= 0 {
+		first = DefaultPageSize
+	}
+	first = min(first, MaxPageSize)
+
+	scope, args := tenantAudit(ctx)
+	conditions := []string{scope}
+	if opts.ActorID != "" {
+		conditions = append(conditions, `actor_id = ?`)
+		args = append(args, opts.ActorID)
+	}
+	if opts.TargetID != "" {
+		conditions = append(conditions, `target_id = ?`)
+		args = append(args, opts.TargetID)
+	}
+	if opts.Action != "" {
+		conditions = append(conditions, `action = ?`)
+		args = append(args, opts.Action)
+	}
+	if !opts.Since.IsZero() {
+		conditions = append(conditions, `created_at >= ?`)
+		args = append(args, opts.Since.UTC())
+	}
+	if !opts.Until.IsZero() {
+		conditions = append(conditions, `created_at < ?`)
+		args = append(args, opts.Until.UTC())
+	}
+	if opts.After != "" {
+		c, err := decodeTimeCursor(opts.After)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, `(created_at < ? OR (created_at = ? AND id < ?))`)
+		args = append(args, c.CreatedAt.UTC(), c.CreatedAt.UTC(), c.ID)
+	}
+
+	// one more event than asked for is read to know if there is a next page.
+	args = append(args, first+1)
+	events, err := m.auditEvents(ctx, `SELECT `+auditColumns+` FROM audit_events WHERE `+
+		strings.Join(conditions, ` AND `)+` ORDER BY created_at DESC, id DESC LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &AuditPage{Events: events}
+	if len(events) > first {
+		page.Events = events[:first]
+		page.HasNextPage = true
+		page.NextCursor = AuditCursor(page.Events[first-1])
+	}
+	if page.Events == nil {
+		page.Events = []*AuditEvent{}
+	}
+
+	return page, nil
+}
+
+// userAuditEvents returns the events a user made or was the target of, oldest first.
+func (m *MYSQL) userAuditEvents(ctx context.Context, userID, orgID string) ([]*AuditEvent, error) {
+	return m.auditEvents(ctx, `SELECT `+auditColumns+` FROM audit_events
+	WHERE (actor_id = ? OR (target_type = ? AND target_id = ?)) AND `+inOrganization+` ORDER BY created_at, id`,
+		userID, AuditTargetUser, userID, orgID, orgID)
+}
+
+func (m *MYSQL) auditEvents(ctx context.Context, query string, args ...any) ([]*AuditEvent, error) {
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*AuditEvent
+	for rows.Next() {
+		e := &AuditEvent{}
+		var before, after []byte
+		err := rows.Scan(&e.ID, &e.OrganizationID, &e.ActorID, &e.Action, &e.TargetType, &e.TargetID,
+			&before, &after, &e.IP, &e.UserAgent, &e.RequestID, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if len(before) > 0 {
+			e.Before = before
+		}
+		if len(after) > 0 {
+			e.After = after
+		}
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
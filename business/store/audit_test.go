@@ -0,0 +1,353 @@
+package store
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riyadennis/identity-server/business/events"
+)
+
+var auditEventColumns = []string{
+	"id", "organization_id", "actor_id", "action", "target_type", "target_id",
+	"before_value", "after_value", "ip", "user_agent", "request_id", "created_at",
+}
+
+// expectAudit expects an event with action to be recorded for targetID.
+func expectAudit(mock sqlmock.Sqlmock, action string, targetID driver.Value) {
+	mock.ExpectExec(`INSERT INTO audit_events`).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), action, sqlmock.AnyArg(), targetID,
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func auditRows(ids ...string) *sqlmock.Rows {
+	rows := sqlmock.NewRows(auditEventColumns)
+	for _, id := range ids {
+		rows.AddRow(id, "org-1", "admin-1", AuditUserActivationToggled, AuditTargetUser, "user-1",
+			[]byte(`{"active":true}`), []byte(`{"active":false}`), "10.0.0.1", "curl/8.0", "req-1",
+			time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	}
+	return rows
+}
+
+func TestDB_RecordAudit(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	mock.ExpectExec(`INSERT INTO audit_events`).
+		WithArgs(sqlmock.AnyArg(), "org-1", "admin-1", AuditUserLoggedIn, AuditTargetUser, "user-1",
+			nil, `{"organization_id":"org-1"}`, "10.0.0.1", "curl/8.0", "req-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ctx := WithTenant(context.Background(), testTenant)
+	ctx = WithActor(ctx, Actor{UserID: "admin-1", IP: "10.0.0.1", UserAgent: "curl/8.0", RequestID: "req-1"})
+	e := &AuditEvent{
+		Action:     AuditUserLoggedIn,
+		TargetType: AuditTargetUser,
+		TargetID:   "user-1",
+		After:      auditValue(map[string]any{"organization_id": "org-1"}),
+	}
+	require.NoError(t, NewDB(conn).RecordAudit(ctx, e))
+	assert.NotEmpty(t, e.ID)
+	assert.False(t, e.CreatedAt.IsZero())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTruncate(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{name: "short", value: "curl", expected: "curl"},
+		{name: "exact", value: "curl/", expected: "curl/"},
+		{name: "long", value: "curl/8.0", expected: "curl/"},
+		{name: "multibyte", value: "ééééééé", expected: "ééééé"},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			assert.Equal(t, sc.expected, truncate(sc.value, 5))
+		})
+	}
+}
+
+func TestDB_ListAuditEvents(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cursorTime := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	scenarios := []struct {
+		name        string
+		db          func() *MYSQL
+		tenant      *Tenant
+		opts        AuditOptions
+		expectedIDs []string
+		hasNext     bool
+		expectedErr error
+	}{
+		{
+			name:        "empty connection",
+			db:          func() *MYSQL { return &MYSQL{} },
+			expectedErr: errEmptyDBConnection,
+		},
+		{
+			name: "malformed cursor",
+			db: func() *MYSQL {
+				conn, _, err := sqlmock.New()
+				assert.NoError(t, err)
+				return NewDB(conn)
+			},
+			opts:        AuditOptions{After: "not a cursor"},
+			expectedErr: ErrInvalidCursor,
+		},
+		{
+			name: "query failed",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`FROM audit_events`).WillReturnError(errors.New("query error"))
+				return NewDB(conn)
+			},
+			expectedErr: errors.New("query error"),
+		},
+		{
+			name: "empty result",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`FROM audit_events WHERE TRUE ORDER BY created_at DESC, id DESC LIMIT \?`).
+					WithArgs(DefaultPageSize + 1).
+					WillReturnRows(sqlmock.NewRows(auditEventColumns))
+				return NewDB(conn)
+			},
+			expectedIDs: []string{},
+		},
+		{
+			name: "more pages",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`LIMIT \?`).
+					WithArgs(3).
+					WillReturnRows(auditRows("3", "2", "1"))
+				return NewDB(conn)
+			},
+			opts:        AuditOptions{First: 2},
+			expectedIDs: []string{"3", "2"},
+			hasNext:     true,
+		},
+		{
+			name: "filtered after a cursor",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`WHERE organization_id = \? AND actor_id = \? AND target_id = \? AND action = \?`+
+					` AND created_at >= \? AND created_at < \? AND \(created_at < \? OR \(created_at = \? AND id < \?\)\)`+
+					` ORDER BY created_at DESC, id DESC LIMIT \?`).
+					WithArgs("org-1", "admin-1", "user-1", AuditUserDeleted, since, cursorTime.Add(time.Hour),
+						cursorTime, cursorTime, "5", 11).
+					WillReturnRows(auditRows("4"))
+				return NewDB(conn)
+			},
+			tenant: &testTenant,
+			opts: AuditOptions{
+				ActorID:  "admin-1",
+				TargetID: "user-1",
+				Action:   AuditUserDeleted,
+				Since:    since,
+				Until:    cursorTime.Add(time.Hour),
+				First:    10,
+				After:    AuditCursor(&AuditEvent{ID: "5", CreatedAt: cursorTime}),
+			},
+			expectedIDs: []string{"4"},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if sc.tenant != nil {
+				ctx = WithTenant(ctx, *sc.tenant)
+			}
+			page, err := sc.db().ListAuditEvents(ctx, sc.opts)
+			assert.Equal(t, sc.expectedErr, err)
+			if sc.expectedErr != nil {
+				return
+			}
+
+			ids := make([]string, 0, len(page.Events))
+			for _, e := range page.Events {
+				ids = append(ids, e.ID)
+			}
+			assert.Equal(t, sc.expectedIDs, ids)
+			assert.Equal(t, sc.hasNext, page.HasNextPage)
+			if !sc.hasNext {
+				assert.Empty(t, page.NextCursor)
+				return
+			}
+			last := page.Events[len(page.Events)-1]
+			require.Equal(t, AuditCursor(last), page.NextCursor)
+			assert.JSONEq(t, `{"active":true}`, string(last.Before))
+			assert.Equal(t, json.RawMessage(`{"active":false}`), last.After)
+		})
+	}
+}
+
+func TestDB_ToggleActive(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		db             func() *MYSQL
+		expectedActive bool
+		expectedErr    error
+	}{
+		{
+			name:        "empty connection",
+			db:          func() *MYSQL { return &MYSQL{} },
+			expectedErr: errEmptyDBConnection,
+		},
+		{
+			name: "not found",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT active FROM identity_users WHERE id = \? AND identity_users.deleted_at IS NULL AND TRUE FOR UPDATE`).
+					WithArgs("user-1").
+					WillReturnRows(sqlmock.NewRows([]string{"active"}))
+				mock.ExpectRollback()
+				return NewDB(conn)
+			},
+			expectedErr: ErrUserNotFound,
+		},
+		{
+			name: "audit failed",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT active FROM identity_users`).
+					WillReturnRows(sqlmock.NewRows([]string{"active"}).AddRow(true))
+				mock.ExpectExec(`UPDATE identity_users SET active = \? WHERE id = \?`).
+					WithArgs(false, "user-1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`INSERT INTO audit_events`).WillReturnError(errors.New("audit error"))
+				mock.ExpectRollback()
+				return NewDB(conn)
+			},
+			expectedErr: errors.New("audit error"),
+		},
+		{
+			name: "deactivated",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT active FROM identity_users`).
+					WillReturnRows(sqlmock.NewRows([]string{"active"}).AddRow(true))
+				mock.ExpectExec(`UPDATE identity_users SET active = \? WHERE id = \?`).
+					WithArgs(false, "user-1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`INSERT INTO audit_events`).
+					WithArgs(sqlmock.AnyArg(), "", "", AuditUserActivationToggled, AuditTargetUser, "user-1",
+						`{"active":true}`, `{"active":false}`, "", "", "", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectEvent(mock, events.TypeUserDeactivated, "user-1")
+				mock.ExpectCommit()
+				return NewDB(conn)
+			},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			active, err := sc.db().ToggleActive(context.Background(), "user-1")
+			assert.Equal(t, sc.expectedErr, err)
+			assert.Equal(t, sc.expectedActive, active)
+		})
+	}
+}
+
+func TestDB_ToggleActive_SharedUser(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT active FROM identity_users WHERE id = \? AND identity_users.deleted_at IS NULL AND identity_users.id IN`).
+		WithArgs("user-1", "org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).AddRow(true))
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM organization_members`).
+		WithArgs("user-1", "org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"shared"}).AddRow(true))
+	mock.ExpectRollback()
+
+	ctx := WithTenant(context.Background(), Tenant{OrganizationID: "org-1"})
+	_, err = NewDB(conn).ToggleActive(ctx, "user-1")
+	assert.ErrorIs(t, err, ErrSharedUser)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDB_SetActive(t *testing.T) {
+	scenarios := []struct {
+		name            string
+		active          bool
+		expect          func(mock sqlmock.Sqlmock)
+		expectedChanged bool
+		expectedErr     error
+	}{
+		{
+			name:   "not found",
+			active: true,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT active FROM identity_users WHERE id = \? AND identity_users.deleted_at IS NULL AND TRUE FOR UPDATE`).
+					WithArgs("user-1").
+					WillReturnRows(sqlmock.NewRows([]string{"active"}))
+				mock.ExpectRollback()
+			},
+			expectedErr: ErrUserNotFound,
+		},
+		{
+			name:   "already active",
+			active: true,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT active FROM identity_users`).
+					WillReturnRows(sqlmock.NewRows([]string{"active"}).AddRow(true))
+				mock.ExpectRollback()
+			},
+		},
+		{
+			name:   "activated",
+			active: true,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT active FROM identity_users`).
+					WillReturnRows(sqlmock.NewRows([]string{"active"}).AddRow(false))
+				mock.ExpectExec(`UPDATE identity_users SET active = \? WHERE id = \?`).
+					WithArgs(true, "user-1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`INSERT INTO audit_events`).
+					WithArgs(sqlmock.AnyArg(), "", "", AuditUserActivationToggled, AuditTargetUser, "user-1",
+						`{"active":false}`, `{"active":true}`, "", "", "", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectEvent(mock, events.TypeUserActivated, "user-1")
+				mock.ExpectCommit()
+			},
+			expectedChanged: true,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			sc.expect(mock)
+
+			changed, err := NewDB(conn).SetActive(context.Background(), "user-1", sc.active)
+			assert.Equal(t, sc.expectedErr, err)
+			assert.Equal(t, sc.expectedChanged, changed)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
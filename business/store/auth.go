@@ -13,7 +13,7 @@ import (
 
 type Authenticator interface {
 	Authenticate(email, password string) (bool, error)
-	FetchLoginToken(userID string) (*TokenRecord, error)
+	FetchLoginToken(userID, orgID string) (*TokenRecord, error)
 	SaveLoginToken(ctx context.Context, t *TokenRecord) error
 }
 
@@ -24,7 +24,7 @@ type Auth struct {
 
 var authQuery = `SELECT password FROM
 identity_users
-where email = ?`
+where email = ? AND ` + notDeleted
 
 // Authenticate checks the validity of a given password for an email.
 func (a *Auth) Authenticate(email, inputPassword string) (bool, error) {
@@ -33,7 +33,7 @@ func (a *Auth) Authenticate(email, inputPassword string) (bool, error) {
 		return false, err
 	}
 
-	row := login.QueryRow(email)
+	row := login.QueryRow(NormalizeEmail(email))
 	var storedHash string
 
 	err = row.Scan(&storedHash)
@@ -51,27 +51,29 @@ func (a *Auth) Authenticate(email, inputPassword string) (bool, error) {
 }
 
 type TokenRecord struct {
-	ID        string
-	UserID    string
-	Token     string
-	TTL       string
-	Expiry    time.Time
-	LastUsed  sql.NullString
-	CreatedAt string
-	UpdatedAt string
+	ID             string
+	UserID         string
+	OrganizationID string
+	Token          string
+	TTL            string
+	Expiry         time.Time
+	LastUsed       sql.NullString
+	CreatedAt      string
+	UpdatedAt      string
 }
 
 var tokenQuery = `SELECT id,token,ttl,expiry,last_used FROM
 login_tokens
-where user_id = ?`
+where user_id = ? AND organization_id = ?`
 
-func (a *Auth) FetchLoginToken(userID string) (*TokenRecord, error) {
+// FetchLoginToken returns the token issued to the user for an organization.
+func (a *Auth) FetchLoginToken(userID, orgID string) (*TokenRecord, error) {
 	query, err := a.Conn.Prepare(tokenQuery)
 	if err != nil {
 		return nil, err
 	}
 	token := &TokenRecord{}
-	tokenRow := query.QueryRow(userID)
+	tokenRow := query.QueryRow(userID, orgID)
 	err = tokenRow.Scan(&token.ID, &token.Token, &token.TTL, &token.Expiry, &token.LastUsed)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return nil, err
@@ -83,7 +85,7 @@ func (a *Auth) FetchLoginToken(userID string) (*TokenRecord, error) {
 	return token, nil
 }
 
-var saveTokenQuery = `INSERT INTO login_tokens (id, user_id, token, ttl, expiry) VALUES (?, ?, ?, ?, ?)`
+var saveTokenQuery = `INSERT INTO login_tokens (id, user_id, organization_id, token, ttl, expiry) VALUES (?, ?, ?, ?, ?, ?)`
 
 func (a *Auth) SaveLoginToken(ctx context.Context, t *TokenRecord) error {
 	saveStmt, err := a.Conn.Prepare(saveTokenQuery)
@@ -92,7 +94,7 @@ func (a *Auth) SaveLoginToken(ctx context.Context, t *TokenRecord) error {
 		return err
 	}
 	id := uuid.New().String()
-	result, err := saveStmt.ExecContext(ctx, id, t.UserID, t.Token, t.TTL, t.Expiry)
+	result, err := saveStmt.ExecContext(ctx, id, t.UserID, t.OrganizationID, t.Token, t.TTL, t.Expiry)
 	if err != nil {
 		a.Logger.Errorf("failed to save token: %v", err)
 		return err
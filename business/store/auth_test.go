@@ -107,7 +107,7 @@ func TestAuth_FetchLoginToken(t *testing.T) {
 				conn, mock, err := sqlmock.New()
 				assert.NoError(t, err)
 				mock.ExpectPrepare(regexp.QuoteMeta(tokenQuery)).
-					ExpectQuery().WithArgs(sqlmock.AnyArg()).
+					ExpectQuery().WithArgs(sqlmock.AnyArg(), "org-1").
 					WillReturnError(errors.New("error"))
 				return &Auth{
 					Conn: conn,
@@ -121,7 +121,7 @@ func TestAuth_FetchLoginToken(t *testing.T) {
 				conn, mock, err := sqlmock.New()
 				assert.NoError(t, err)
 				mock.ExpectPrepare(regexp.QuoteMeta(tokenQuery)).
-					ExpectQuery().WithArgs(sqlmock.AnyArg()).
+					ExpectQuery().WithArgs(sqlmock.AnyArg(), "org-1").
 					WillReturnRows(sqlmock.NewRows(
 						[]string{"id", "token", "ttl", "expiry", "last_used"}).
 						AddRow("", "", "", time.Now(), ""))
@@ -136,7 +136,7 @@ func TestAuth_FetchLoginToken(t *testing.T) {
 				conn, mock, err := sqlmock.New()
 				assert.NoError(t, err)
 				mock.ExpectPrepare(regexp.QuoteMeta(tokenQuery)).
-					ExpectQuery().WithArgs(sqlmock.AnyArg()).
+					ExpectQuery().WithArgs(sqlmock.AnyArg(), "org-1").
 					WillReturnRows(sqlmock.NewRows(
 						[]string{"id", "token", "ttl", "expiry", "last_used"}).
 						AddRow("123", "token", "123", testExpiry, "2024-01-01"))
@@ -151,7 +151,7 @@ func TestAuth_FetchLoginToken(t *testing.T) {
 	for _, testCase := range testcases {
 		t.Run(testCase.name, func(t *testing.T) {
 			testCase.auth.Logger = logger
-			token, err := testCase.auth.FetchLoginToken("token")
+			token, err := testCase.auth.FetchLoginToken("token", "org-1")
 			assert.Equal(t, testCase.expectedError, err)
 			assert.Equal(t, testCase.expectedResult, token)
 		})
@@ -42,6 +42,10 @@ type DBConnection struct {
 	Port          string
 	ParseTime     bool
 	MigrationPath string
+
+	// MultiStatements allows several statements in one query, only the
+	// connection running the migrations needs it.
+	MultiStatements bool
 }
 
 // Token has credentials present in a token.
@@ -107,6 +111,9 @@ func ConnectMYSQL(dbCfg *DBConnection) (*sql.DB, error) {
 		dbCfg.Database,
 		dbCfg.ParseTime,
 	)
+	if dbCfg.MultiStatements {
+		dsn += "&multiStatements=true"
+	}
 
 	conn, err := sql.Open("mysql", dsn)
 	if err != nil {
@@ -116,7 +123,7 @@ func ConnectMYSQL(dbCfg *DBConnection) (*sql.DB, error) {
 	return conn, nil
 }
 
-func GenerateToken(logger *logrus.Logger, key []byte, claims *jwt.RegisteredClaims) (*Token, error) {
+func GenerateToken(logger *logrus.Logger, key []byte, claims *Claims) (*Token, error) {
 	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(key)
 	if err != nil {
 		logger.Printf("failed to parser private key: %v", err)
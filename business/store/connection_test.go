@@ -97,7 +97,7 @@ func TestGenerateToken(t *testing.T) {
 	logger.SetOutput(os.Stderr)
 
 	t.Run("invalid key bytes", func(t *testing.T) {
-		_, err := GenerateToken(logger, []byte("not valid pem"), &jwt.RegisteredClaims{})
+		_, err := GenerateToken(logger, []byte("not valid pem"), &Claims{})
 		assert.Error(t, err)
 	})
 
@@ -111,10 +111,13 @@ func TestGenerateToken(t *testing.T) {
 		})
 
 		expiry := time.Now().Add(1 * time.Hour)
-		token, err := GenerateToken(logger, privPEM, &jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expiry),
-			Issuer:    "test-issuer",
-			Subject:   "user-123",
+		token, err := GenerateToken(logger, privPEM, &Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(expiry),
+				Issuer:    "test-issuer",
+				Subject:   "user-123",
+			},
+			OrgID: "org-1",
 		})
 		assert.NoError(t, err)
 		assert.NotNil(t, token)
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/riyadennis/identity-server/business/events"
+)
+
+// notDeleted keeps users that have been deleted out of a query, they can
+// only be restored or purged.
+const notDeleted = `identity_users.deleted_at IS NULL`
+
+// purgedTables are the tables with rows that belong to a user, they are
+// emptied before the user is purged.
+var purgedTables = []string{"login_tokens", "email_changes", "login_attempts", "webhook_deliveries", "user_roles", "organization_members"}
+
+// Delete marks a user of the tenant as deleted, the user is hidden from every
+// other query until it is restored or purged. A tenant can only delete users
+// that are not members of other organizations. It returns the number of users deleted.
+func (m *MYSQL) Delete(ctx context.Context, id string) (int64, error) {
+	if m.Conn == nil {
+		return 0, errEmptyDBConnection
+	}
+
+	scope, scopeArgs := tenantUsers(ctx)
+	return m.changeDeleted(ctx, id, AuditUserDeleted, events.UserDeleted{UserID: id, OrganizationID: eventOrganization(ctx)},
+		`UPDATE identity_users SET deleted_at = ? WHERE id = ? AND `+notDeleted+` AND `+scope,
+		append([]any{time.Now().UTC(), id}, scopeArgs...)...)
+}
+
+// Restore brings back a user of the tenant that was deleted at or after
+// deletedSince, erased users can not be restored. A tenant can only restore
+// users that are not members of other organizations. It returns the number of users restored.
+func (m *MYSQL) Restore(ctx context.Context, id string, deletedSince time.Time) (int64, error) {
+	if m.Conn == nil {
+		return 0, errEmptyDBConnection
+	}
+
+	scope, scopeArgs := tenantUsers(ctx)
+	return m.changeDeleted(ctx, id, AuditUserRestored, nil,
+		`UPDATE identity_users SET deleted_at = NULL WHERE id = ? AND deleted_at >= ? AND erased_at IS NULL AND `+scope,
+		append([]any{id, deletedSince.UTC()}, scopeArgs...)...)
+}
+
+// changeDeleted runs the update that deletes or restores user id and records
+// action in the audit log when the user was changed, event is published
+// unless it is nil.
+func (m *MYSQL) changeDeleted(ctx context.Context, id, action string, event events.Event, query string, args ...any) (int64, error) {
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := soleMember(ctx, tx, id); err != nil {
+		return 0, err
+	}
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		logrus.Errorf("failed to change deletion of user: %v", err)
+		return 0, err
+	}
+	changed, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+
+	err = recordAudit(ctx, tx, &AuditEvent{Action: action, TargetType: AuditTargetUser, TargetID: id})
+	if err != nil {
+		return 0, err
+	}
+	if event != nil {
+		if err := enqueueEvent(ctx, tx, event); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return changed, nil
+}
+
+// PurgeDeleted permanently removes up to limit users deleted before
+// deletedBefore along with the rows that belong to them, in one transaction.
+// Erased users are kept.
+// It returns the number of users purged.
+func (m *MYSQL) PurgeDeleted(ctx context.Context, deletedBefore time.Time, limit int) (int64, error) {
+	if m.Conn == nil {
+		return 0, errEmptyDBConnection
+	}
+
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	rows, err := tx.QueryContext(ctx, `SELECT id FROM identity_users
+	WHERE deleted_at < ? AND erased_at IS NULL ORDER BY deleted_at LIMIT ? FOR UPDATE`, deletedBefore.UTC(), limit)
+	if err != nil {
+		return 0, err
+	}
+	var ids []any
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	in := `(?` + strings.Repeat(`, ?`, len(ids)-1) + `)`
+	for _, table := range purgedTables {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE user_id IN `+in, ids...); err != nil {
+			return 0, err
+		}
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM identity_users WHERE id IN `+in, ids...)
+	if err != nil {
+		return 0, err
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		err := recordAudit(ctx, tx, &AuditEvent{Action: AuditUserPurged, TargetType: AuditTargetUser, TargetID: id.(string)})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,242 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadennis/identity-server/business/events"
+)
+
+func TestDB_Delete(t *testing.T) {
+	scenarios := []struct {
+		name                 string
+		db                   *MYSQL
+		id                   string
+		expectedErr          error
+		expectedRowsAffected int64
+	}{
+		{
+			name:        "empty connection",
+			db:          &MYSQL{},
+			expectedErr: errEmptyDBConnection,
+		},
+		{
+			name: "deletion failed",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE identity_users SET deleted_at = \?`).
+					WillReturnError(errors.New("error"))
+				mock.ExpectRollback()
+				return NewDB(conn)
+			}(),
+			expectedErr: errors.New("error"),
+		},
+		{
+			name: "not found",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE identity_users SET deleted_at = \?`).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+				return NewDB(conn)
+			}(),
+			id: "123",
+		},
+		{
+			name: "success",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE identity_users SET deleted_at = \? WHERE id = \? AND identity_users.deleted_at IS NULL`).
+					WithArgs(sqlmock.AnyArg(), "123").WillReturnResult(sqlmock.NewResult(1, 1))
+				expectAudit(mock, AuditUserDeleted, "123")
+				expectEvent(mock, events.TypeUserDeleted, "123")
+				mock.ExpectCommit()
+				return NewDB(conn)
+			}(),
+			id:                   "123",
+			expectedRowsAffected: 1,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			rowsAffected, err := sc.db.Delete(context.Background(), sc.id)
+			assert.Equal(t, sc.expectedErr, err)
+			assert.Equal(t, sc.expectedRowsAffected, rowsAffected)
+		})
+	}
+}
+
+func TestDB_Delete_Tenant(t *testing.T) {
+	ctx := WithTenant(context.Background(), Tenant{OrganizationID: "org-1"})
+
+	t.Run("member of other organizations", func(t *testing.T) {
+		conn, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM organization_members WHERE user_id = \? AND organization_id <> \?\)`).
+			WithArgs("123", "org-1").
+			WillReturnRows(sqlmock.NewRows([]string{"shared"}).AddRow(true))
+		mock.ExpectRollback()
+
+		deleted, err := NewDB(conn).Delete(ctx, "123")
+		assert.ErrorIs(t, err, ErrSharedUser)
+		assert.Zero(t, deleted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("only member of the tenant", func(t *testing.T) {
+		conn, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT EXISTS`).
+			WithArgs("123", "org-1").
+			WillReturnRows(sqlmock.NewRows([]string{"shared"}).AddRow(false))
+		mock.ExpectExec(`UPDATE identity_users SET deleted_at = \? WHERE id = \? AND identity_users.deleted_at IS NULL AND identity_users.id IN`).
+			WithArgs(sqlmock.AnyArg(), "123", "org-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectAudit(mock, AuditUserDeleted, "123")
+		expectEvent(mock, events.TypeUserDeleted, "123")
+		mock.ExpectCommit()
+
+		deleted, err := NewDB(conn).Delete(ctx, "123")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), deleted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDB_Restore(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	scenarios := []struct {
+		name                 string
+		db                   *MYSQL
+		expectedErr          error
+		expectedRowsAffected int64
+	}{
+		{
+			name:        "empty connection",
+			db:          &MYSQL{},
+			expectedErr: errEmptyDBConnection,
+		},
+		{
+			name: "update failed",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE identity_users SET deleted_at = NULL`).WillReturnError(errors.New("error"))
+				mock.ExpectRollback()
+				return NewDB(conn)
+			}(),
+			expectedErr: errors.New("error"),
+		},
+		{
+			name: "restored",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE identity_users SET deleted_at = NULL WHERE id = \? AND deleted_at >= \? AND erased_at IS NULL AND TRUE`).
+					WithArgs("123", since).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectAudit(mock, AuditUserRestored, "123")
+				mock.ExpectCommit()
+				return NewDB(conn)
+			}(),
+			expectedRowsAffected: 1,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			rowsAffected, err := sc.db.Restore(context.Background(), "123", since)
+			assert.Equal(t, sc.expectedErr, err)
+			assert.Equal(t, sc.expectedRowsAffected, rowsAffected)
+		})
+	}
+}
+
+func TestDB_PurgeDeleted(t *testing.T) {
+	before := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	scenarios := []struct {
+		name           string
+		db             func() *MYSQL
+		expectedErr    error
+		expectedPurged int64
+	}{
+		{
+			name:        "empty connection",
+			db:          func() *MYSQL { return &MYSQL{} },
+			expectedErr: errEmptyDBConnection,
+		},
+		{
+			name: "nothing to purge",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT id FROM identity_users WHERE deleted_at < \? AND erased_at IS NULL ORDER BY deleted_at LIMIT \? FOR UPDATE`).
+					WithArgs(before, 10).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectRollback()
+				return NewDB(conn)
+			},
+		},
+		{
+			name: "dependents failed",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT id FROM identity_users`).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
+				mock.ExpectExec(`DELETE FROM login_tokens WHERE user_id IN \(\?\)`).
+					WithArgs("1").
+					WillReturnError(errors.New("error"))
+				mock.ExpectRollback()
+				return NewDB(conn)
+			},
+			expectedErr: errors.New("error"),
+		},
+		{
+			name: "purged",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT id FROM identity_users`).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1").AddRow("2"))
+				for _, table := range purgedTables {
+					mock.ExpectExec(`DELETE FROM `+table+` WHERE user_id IN \(\?, \?\)`).
+						WithArgs("1", "2").
+						WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+				mock.ExpectExec(`DELETE FROM identity_users WHERE id IN \(\?, \?\)`).
+					WithArgs("1", "2").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				expectAudit(mock, AuditUserPurged, "1")
+				expectAudit(mock, AuditUserPurged, "2")
+				mock.ExpectCommit()
+				return NewDB(conn)
+			},
+			expectedPurged: 2,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			purged, err := sc.db().PurgeDeleted(context.Background(), before, 10)
+			assert.Equal(t, sc.expectedErr, err)
+			assert.Equal(t, sc.expectedPurged, purged)
+		})
+	}
+}
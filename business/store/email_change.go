@@ -0,0 +1,180 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrEmailChangeNotFound is returned when confirming an email change that
+	// does not exist, expired or was already confirmed.
+	ErrEmailChangeNotFound = errors.New("email change not found")
+
+	errEmptyEmailChange = errors.New("empty email change")
+)
+
+// EmailChangeStore manages the changes of email address waiting for the user
+// to confirm the new address.
+type EmailChangeStore interface {
+	CreateEmailChange(ctx context.Context, c *EmailChange) (*EmailChange, error)
+	ConfirmEmailChange(ctx context.Context, tokenHash string, reservedUntil time.Time) (*User, error)
+	EmailReserved(ctx context.Context, email, userID string) (bool, error)
+}
+
+// EmailChange is a request to change the email of a user to NewEmail.
+// Only the SHA-256 hash of the confirmation token is stored.
+type EmailChange struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	OldEmail  string    `json:"old_email"`
+	NewEmail  string    `json:"new_email"`
+	TokenHash string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// reservedEmail finds addresses given up less than the cooling-off period
+// ago by a user other than the second argument, it takes the current time last.
+const reservedEmail = `SELECT EXISTS(SELECT 1 FROM email_changes
+	WHERE old_email = ? AND user_id <> ? AND reserved_until > ?)`
+
+// CreateEmailChange saves a pending change of email, it replaces the
+// changes of the user that were not confirmed yet.
+func (m *MYSQL) CreateEmailChange(ctx context.Context, c *EmailChange) (*EmailChange, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+	if c == nil {
+		return nil, errEmptyEmailChange
+	}
+
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM email_changes WHERE user_id = ? AND confirmed_at IS NULL`, c.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.OldEmail = NormalizeEmail(c.OldEmail)
+	c.NewEmail = NormalizeEmail(c.NewEmail)
+	c.ID = uuid.New().String()
+	_, err = tx.ExecContext(ctx, `INSERT INTO email_changes
+(id, user_id, old_email, new_email, token_hash, expires_at)
+ VALUES (?, ?, ?, ?, ?, ?)`,
+		c.ID, c.UserID, c.OldEmail, c.NewEmail, c.TokenHash, c.ExpiresAt.UTC())
+	if err != nil {
+		logrus.Errorf("failed to insert email change: %v", err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// ConfirmEmailChange swaps the email of the user for the new address and
+// reserves the old address until reservedUntil, in a single transaction so
+// a link can only be used once. It will return ErrDuplicateEmail if the new
+// address was taken since the change was requested.
+func (m *MYSQL) ConfirmEmailChange(ctx context.Context, tokenHash string, reservedUntil time.Time) (*User, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC()
+	c := &EmailChange{}
+	err = tx.QueryRowContext(ctx, `SELECT id, user_id, old_email, new_email, expires_at FROM email_changes
+	WHERE token_hash = ? AND confirmed_at IS NULL AND expires_at > ? FOR UPDATE`, tokenHash, now).
+		Scan(&c.ID, &c.UserID, &c.OldEmail, &c.NewEmail, &c.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrEmailChangeNotFound
+		}
+		return nil, err
+	}
+
+	if err := availableEmail(ctx, tx, c.NewEmail, c.UserID); err != nil {
+		return nil, err
+	}
+
+	result, err := tx.ExecContext(ctx, `UPDATE identity_users SET email = ? WHERE id = ? AND `+notDeleted,
+		c.NewEmail, c.UserID)
+	if err != nil {
+		return nil, duplicateEmail(err)
+	}
+	// the user was deleted after asking for the change.
+	if rows, err := result.RowsAffected(); err != nil || rows == 0 {
+		return nil, ErrEmailChangeNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE email_changes SET confirmed_at = ?, reserved_until = ? WHERE id = ?`,
+		now, reservedUntil.UTC(), c.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return m.Retrieve(ctx, c.UserID)
+}
+
+// EmailReserved reports whether an address was given up by another user than
+// userID and can not be used yet.
+func (m *MYSQL) EmailReserved(ctx context.Context, email, userID string) (bool, error) {
+	if m.Conn == nil {
+		return false, errEmptyDBConnection
+	}
+
+	var reserved bool
+	err := m.Conn.QueryRowContext(ctx, reservedEmail, NormalizeEmail(email), userID, time.Now().UTC()).Scan(&reserved)
+	return reserved, err
+}
+
+// availableEmail returns ErrDuplicateEmail if email belongs to a user other than
+// userID or is reserved, the user row is locked until the transaction ends.
+func availableEmail(ctx context.Context, db execer, email, userID string) error {
+	var owner string
+	err := db.QueryRowContext(ctx, `SELECT id FROM identity_users WHERE email = ? FOR UPDATE`, email).Scan(&owner)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return err
+	case owner != userID:
+		return ErrDuplicateEmail
+	}
+
+	return unreservedEmail(ctx, db, email, userID)
+}
+
+// unreservedEmail returns ErrDuplicateEmail if email was given up by a user
+// other than userID during the cooling-off period.
+func unreservedEmail(ctx context.Context, db execer, email, userID string) error {
+	var reserved bool
+	err := db.QueryRowContext(ctx, reservedEmail, email, userID, time.Now().UTC()).Scan(&reserved)
+	if err != nil {
+		return err
+	}
+	if reserved {
+		return ErrDuplicateEmail
+	}
+
+	return nil
+}
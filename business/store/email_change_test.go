@@ -0,0 +1,199 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+var reservationExpiry = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestDB_CreateEmailChange(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM email_changes WHERE user_id = \? AND confirmed_at IS NULL`).
+		WithArgs("user-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO email_changes`).
+		WithArgs(sqlmock.AnyArg(), "user-1", "jane@example.com", "jane@new.example.com", "hash", reservationExpiry).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	c, err := NewDB(conn).CreateEmailChange(context.Background(), &EmailChange{
+		UserID: "user-1", OldEmail: "jane@example.com", NewEmail: "jane@new.example.com",
+		TokenHash: "hash", ExpiresAt: reservationExpiry,
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, c.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDB_ConfirmEmailChange(t *testing.T) {
+	changeRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "user_id", "old_email", "new_email", "expires_at"}).
+			AddRow("change-1", "user-1", "jane@example.com", "jane@new.example.com", reservationExpiry)
+	}
+	reserved := func(reserved bool) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"reserved"}).AddRow(reserved)
+	}
+	scenarios := []struct {
+		name        string
+		db          func() (*MYSQL, sqlmock.Sqlmock)
+		expectedErr error
+	}{
+		{
+			name: "used or expired",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`FROM email_changes`).
+					WithArgs("hash", sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "old_email", "new_email", "expires_at"}))
+				mock.ExpectRollback()
+				return NewDB(conn), mock
+			},
+			expectedErr: ErrEmailChangeNotFound,
+		},
+		{
+			name: "taken by another user",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`FROM email_changes`).WillReturnRows(changeRows())
+				mock.ExpectQuery(`SELECT id FROM identity_users WHERE email = \? FOR UPDATE`).
+					WithArgs("jane@new.example.com").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("user-2"))
+				mock.ExpectRollback()
+				return NewDB(conn), mock
+			},
+			expectedErr: ErrDuplicateEmail,
+		},
+		{
+			name: "reserved by another user",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`FROM email_changes`).WillReturnRows(changeRows())
+				mock.ExpectQuery(`SELECT id FROM identity_users`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery(`SELECT EXISTS`).
+					WithArgs("jane@new.example.com", "user-1", sqlmock.AnyArg()).
+					WillReturnRows(reserved(true))
+				mock.ExpectRollback()
+				return NewDB(conn), mock
+			},
+			expectedErr: ErrDuplicateEmail,
+		},
+		{
+			name: "taken concurrently",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`FROM email_changes`).WillReturnRows(changeRows())
+				mock.ExpectQuery(`SELECT id FROM identity_users`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(reserved(false))
+				mock.ExpectExec(`UPDATE identity_users SET email = \?`).
+					WillReturnError(&mysql.MySQLError{
+						Number:  mysqlErrDuplicateEntry,
+						Message: "Duplicate entry 'jane@new.example.com' for key 'identity_users.identity_users_email_unique'",
+					})
+				mock.ExpectRollback()
+				return NewDB(conn), mock
+			},
+			expectedErr: ErrDuplicateEmail,
+		},
+		{
+			name: "user deleted",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`FROM email_changes`).WillReturnRows(changeRows())
+				mock.ExpectQuery(`SELECT id FROM identity_users`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(reserved(false))
+				mock.ExpectExec(`UPDATE identity_users SET email = \? WHERE id = \? AND identity_users.deleted_at IS NULL`).
+					WithArgs("jane@new.example.com", "user-1").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+				return NewDB(conn), mock
+			},
+			expectedErr: ErrEmailChangeNotFound,
+		},
+		{
+			name: "update failed",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`FROM email_changes`).WillReturnRows(changeRows())
+				mock.ExpectQuery(`SELECT id FROM identity_users`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(reserved(false))
+				mock.ExpectExec(`UPDATE identity_users SET email = \?`).
+					WillReturnError(errors.New("update error"))
+				mock.ExpectRollback()
+				return NewDB(conn), mock
+			},
+			expectedErr: errors.New("update error"),
+		},
+		{
+			name: "confirmed",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`FROM email_changes`).WillReturnRows(changeRows())
+				mock.ExpectQuery(`SELECT id FROM identity_users`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(reserved(false))
+				mock.ExpectExec(`UPDATE identity_users SET email = \? WHERE id = \?`).
+					WithArgs("jane@new.example.com", "user-1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`UPDATE email_changes SET confirmed_at = \?, reserved_until = \? WHERE id = \?`).
+					WithArgs(sqlmock.AnyArg(), reservationExpiry, "change-1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectPrepare(`SELECT first_name`).
+					ExpectQuery().
+					WillReturnRows(sqlmock.NewRows([]string{
+						"first_name", "last_name", "email", "company", "post_code", "created_by", "active",
+						"created_at", "updated_at", "roles", "locale", "timezone", "picture_url",
+					}).AddRow("Jane", "Doe", "jane@new.example.com", "", "", "", true,
+						"2024-01-01", "2024-01-01", "user", "", "", ""))
+				return NewDB(conn), mock
+			},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			db, mock := sc.db()
+			user, err := db.ConfirmEmailChange(context.Background(), "hash", reservationExpiry)
+			assert.Equal(t, sc.expectedErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+			if sc.expectedErr != nil {
+				return
+			}
+			assert.Equal(t, "user-1", user.ID)
+			assert.Equal(t, "jane@new.example.com", user.Email)
+		})
+	}
+}
+
+func TestDB_EmailReserved(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectQuery(`WHERE old_email = \? AND user_id <> \? AND reserved_until > \?`).
+		WithArgs("jane@example.com", "user-2", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"reserved"}).AddRow(true))
+
+	reserved, err := NewDB(conn).EmailReserved(context.Background(), "Jane@Example.com", "user-2")
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+}
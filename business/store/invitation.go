@@ -0,0 +1,274 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrInvitationNotFound is returned when an invitation does not exist, or
+	// when accepting one that expired, was revoked or was already used.
+	ErrInvitationNotFound = errors.New("invitation not found")
+
+	errEmptyInvitation = errors.New("empty invitation")
+)
+
+// InvitationStore manages the invitations admins send to new users.
+type InvitationStore interface {
+	CreateInvitation(ctx context.Context, inv *Invitation) (*Invitation, error)
+	RetrieveInvitation(ctx context.Context, id string) (*Invitation, error)
+	PendingInvitation(ctx context.Context, tokenHash string) (*Invitation, error)
+	ListInvitations(ctx context.Context) ([]*Invitation, error)
+	RenewInvitation(ctx context.Context, id, tokenHash string, expiresAt time.Time) error
+	RevokeInvitation(ctx context.Context, id string) error
+	AcceptInvitation(ctx context.Context, tokenHash string, u *User) (*User, error)
+}
+
+// Invitation lets the owner of Email join an organization with a preassigned role.
+// Only the SHA-256 hash of the link token is stored.
+type Invitation struct {
+	ID             string    `json:"id"`
+	Email          string    `json:"email"`
+	OrganizationID string    `json:"organization_id"`
+	Role           string    `json:"role"`
+	TokenHash      string    `json:"-"`
+	InvitedBy      string    `json:"invited_by"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	CreatedAt      string    `json:"created_at"`
+	UpdatedAt      string    `json:"updated_at"`
+}
+
+// Expired reports whether the invitation link can no longer be used.
+func (i *Invitation) Expired() bool {
+	return !i.ExpiresAt.After(time.Now())
+}
+
+var invitationQuery = `SELECT id, email, organization_id, role, invited_by, expires_at, created_at, updated_at
+	FROM invitations`
+
+// pendingInvitations restricts invitations to the ones neither accepted nor revoked.
+const pendingInvitations = `accepted_at IS NULL AND revoked_at IS NULL`
+
+// tenantInvitations returns the condition that restricts invitations to the
+// tenant's organization.
+func tenantInvitations(ctx context.Context) (string, []any) {
+	if platformTenant(ctx) {
+		return "TRUE", nil
+	}
+
+	t, _ := TenantFromContext(ctx)
+	return `organization_id = ?`, []any{t.OrganizationID}
+}
+
+// CreateInvitation saves an invitation, tenants can only invite users to their own organization.
+// The role must exist and platform roles can only be handed out by platform administrators.
+func (m *MYSQL) CreateInvitation(ctx context.Context, inv *Invitation) (*Invitation, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+	if inv == nil {
+		return nil, errEmptyInvitation
+	}
+
+	if t, ok := TenantFromContext(ctx); ok && (inv.OrganizationID == "" || !t.Platform) {
+		inv.OrganizationID = t.OrganizationID
+	}
+	if inv.OrganizationID == "" {
+		return nil, ErrOrganizationNotFound
+	}
+	if inv.Role == "" {
+		inv.Role = DefaultRole
+	}
+	inv.Email = NormalizeEmail(inv.Email)
+
+	_, platform, err := lookupRole(ctx, m.Conn, inv.Role)
+	if err != nil {
+		return nil, err
+	}
+	if platform && !platformTenant(ctx) {
+		return nil, ErrPlatformRole
+	}
+
+	id := uuid.New().String()
+	_, err = m.Conn.ExecContext(ctx, `INSERT INTO invitations
+(id, email, organization_id, role, token_hash, invited_by, expires_at)
+ VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		id, inv.Email, inv.OrganizationID, inv.Role, inv.TokenHash, inv.InvitedBy, inv.ExpiresAt.UTC())
+	if err != nil {
+		logrus.Errorf("failed to insert invitation: %v", err)
+		return nil, err
+	}
+
+	return m.RetrieveInvitation(ctx, id)
+}
+
+// RetrieveInvitation fetches a pending invitation of the tenant's organization,
+// it will return ErrInvitationNotFound if there is none.
+func (m *MYSQL) RetrieveInvitation(ctx context.Context, id string) (*Invitation, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	scope, scopeArgs := tenantInvitations(ctx)
+	row := m.Conn.QueryRowContext(ctx,
+		invitationQuery+` WHERE id = ? AND `+pendingInvitations+` AND `+scope,
+		append([]any{id}, scopeArgs...)...)
+
+	return scanInvitation(row)
+}
+
+// PendingInvitation fetches the invitation a link token belongs to, it will
+// return ErrInvitationNotFound unless the invitation can still be accepted.
+func (m *MYSQL) PendingInvitation(ctx context.Context, tokenHash string) (*Invitation, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	row := m.Conn.QueryRowContext(ctx, invitationQuery+` WHERE token_hash = ? AND `+
+		pendingInvitations+` AND expires_at > ?`, tokenHash, time.Now().UTC())
+
+	return scanInvitation(row)
+}
+
+// ListInvitations returns the pending invitations of the tenant's
+// organization, newest first. Expired invitations are included so they can be resent.
+func (m *MYSQL) ListInvitations(ctx context.Context) ([]*Invitation, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	scope, scopeArgs := tenantInvitations(ctx)
+	rows, err := m.Conn.QueryContext(ctx, invitationQuery+` WHERE `+pendingInvitations+` AND `+scope+
+		` ORDER BY created_at DESC`, scopeArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []*Invitation{}
+	for rows.Next() {
+		inv, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+
+	return invitations, rows.Err()
+}
+
+// RenewInvitation replaces the link token of a pending invitation, the
+// previous link stops working.
+func (m *MYSQL) RenewInvitation(ctx context.Context, id, tokenHash string, expiresAt time.Time) error {
+	if m.Conn == nil {
+		return errEmptyDBConnection
+	}
+
+	scope, scopeArgs := tenantInvitations(ctx)
+	args := append([]any{tokenHash, expiresAt.UTC(), id}, scopeArgs...)
+
+	return execInvitation(ctx, m.Conn, `UPDATE invitations SET token_hash = ?, expires_at = ?
+		WHERE id = ? AND `+pendingInvitations+` AND `+scope, args...)
+}
+
+// RevokeInvitation cancels a pending invitation.
+func (m *MYSQL) RevokeInvitation(ctx context.Context, id string) error {
+	if m.Conn == nil {
+		return errEmptyDBConnection
+	}
+
+	scope, scopeArgs := tenantInvitations(ctx)
+	args := append([]any{time.Now().UTC(), id}, scopeArgs...)
+
+	return execInvitation(ctx, m.Conn, `UPDATE invitations SET revoked_at = ?
+		WHERE id = ? AND `+pendingInvitations+` AND `+scope, args...)
+}
+
+// AcceptInvitation creates the invited user and marks the invitation as used,
+// in a single transaction so a link can only be used once. The email,
+// organization and role of the user come from the invitation.
+func (m *MYSQL) AcceptInvitation(ctx context.Context, tokenHash string, u *User) (*User, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+	if u == nil {
+		return nil, errEmptyUser
+	}
+
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	inv, err := scanInvitation(tx.QueryRowContext(ctx, invitationQuery+` WHERE token_hash = ? AND `+
+		pendingInvitations+` AND expires_at > ? FOR UPDATE`, tokenHash, time.Now().UTC()))
+	if err != nil {
+		return nil, err
+	}
+
+	u.Email = inv.Email
+	u.OrganizationID = inv.OrganizationID
+	u.Roles = []string{inv.Role}
+	u.CreatedBy = inv.InvitedBy
+	u.Active = true
+
+	id, err := insertUser(ctx, tx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	err = execInvitation(ctx, tx, `UPDATE invitations SET accepted_at = ? WHERE id = ?`,
+		time.Now().UTC(), inv.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return m.Retrieve(ctx, id)
+}
+
+// execInvitation runs a statement that changes one invitation, it will
+// return ErrInvitationNotFound when no invitation was changed.
+func execInvitation(ctx context.Context, db execer, query string, args ...any) error {
+	result, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrInvitationNotFound
+	}
+
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanInvitation(row scanner) (*Invitation, error) {
+	inv := &Invitation{}
+	err := row.Scan(&inv.ID, &inv.Email, &inv.OrganizationID, &inv.Role, &inv.InvitedBy,
+		&inv.ExpiresAt, &inv.CreatedAt, &inv.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, err
+	}
+
+	return inv, nil
+}
@@ -0,0 +1,264 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadennis/identity-server/business/events"
+)
+
+var invitationColumns = []string{
+	"id", "email", "organization_id", "role", "invited_by", "expires_at", "created_at", "updated_at",
+}
+
+var invitationExpiry = time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func invitationRows() *sqlmock.Rows {
+	return sqlmock.NewRows(invitationColumns).
+		AddRow("inv-1", "jane@example.com", "org-1", "support", "admin-1", invitationExpiry, "2024-01-01", "2024-01-01")
+}
+
+func testInvitation() *Invitation {
+	return &Invitation{
+		ID: "inv-1", Email: "jane@example.com", OrganizationID: "org-1", Role: "support",
+		InvitedBy: "admin-1", ExpiresAt: invitationExpiry, CreatedAt: "2024-01-01", UpdatedAt: "2024-01-01",
+	}
+}
+
+func TestDB_CreateInvitation(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		db          *MYSQL
+		ctx         context.Context
+		invitation  *Invitation
+		expected    *Invitation
+		expectedErr error
+	}{
+		{
+			name:        "empty connection",
+			db:          &MYSQL{},
+			ctx:         context.Background(),
+			invitation:  &Invitation{},
+			expectedErr: errEmptyDBConnection,
+		},
+		{
+			name: "no organization",
+			db: func() *MYSQL {
+				conn, _, err := sqlmock.New()
+				assert.NoError(t, err)
+				return NewDB(conn)
+			}(),
+			ctx:         context.Background(),
+			invitation:  &Invitation{Email: "jane@example.com"},
+			expectedErr: ErrOrganizationNotFound,
+		},
+		{
+			name: "platform role from a tenant",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`SELECT id, platform FROM roles WHERE name = ?`).
+					WithArgs("superadmin").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "platform"}).AddRow("role-super", true))
+				return NewDB(conn)
+			}(),
+			ctx:         WithTenant(context.Background(), testTenant),
+			invitation:  &Invitation{Email: "jane@example.com", Role: "superadmin"},
+			expectedErr: ErrPlatformRole,
+		},
+		{
+			name: "success",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`SELECT id, platform FROM roles WHERE name = ?`).
+					WithArgs("support").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "platform"}).AddRow("role-support", false))
+				mock.ExpectExec(`INSERT INTO invitations`).
+					WithArgs(sqlmock.AnyArg(), "jane@example.com", "org-1", "support", "hash", "admin-1", invitationExpiry).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(`SELECT id, email, organization_id`).
+					WithArgs(sqlmock.AnyArg(), "org-1").
+					WillReturnRows(invitationRows())
+				return NewDB(conn)
+			}(),
+			// a tenant can not pick another organization.
+			ctx: WithTenant(context.Background(), testTenant),
+			invitation: &Invitation{
+				Email: "jane@example.com", OrganizationID: "org-2", Role: "support",
+				TokenHash: "hash", InvitedBy: "admin-1", ExpiresAt: invitationExpiry,
+			},
+			expected: testInvitation(),
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			inv, err := sc.db.CreateInvitation(sc.ctx, sc.invitation)
+			assert.Equal(t, sc.expectedErr, err)
+			assert.Equal(t, sc.expected, inv)
+		})
+	}
+}
+
+func TestDB_ListInvitations(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectQuery(`accepted_at IS NULL AND revoked_at IS NULL AND organization_id = \? ORDER BY created_at DESC`).
+		WithArgs("org-1").
+		WillReturnRows(invitationRows())
+
+	invitations, err := NewDB(conn).ListInvitations(WithTenant(context.Background(), testTenant))
+	assert.NoError(t, err)
+	assert.Equal(t, []*Invitation{testInvitation()}, invitations)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDB_PendingInvitation(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectQuery(`WHERE token_hash = \? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > \?`).
+		WithArgs("unknown", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(invitationColumns))
+
+	_, err = NewDB(conn).PendingInvitation(context.Background(), "unknown")
+	assert.Equal(t, ErrInvitationNotFound, err)
+}
+
+func TestDB_RevokeInvitation(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		affected    int64
+		expectedErr error
+	}{
+		{name: "not pending", affected: 0, expectedErr: ErrInvitationNotFound},
+		{name: "revoked", affected: 1},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			mock.ExpectExec(`UPDATE invitations SET revoked_at = \?`).
+				WithArgs(sqlmock.AnyArg(), "inv-1", "org-1").
+				WillReturnResult(sqlmock.NewResult(0, sc.affected))
+
+			err = NewDB(conn).RevokeInvitation(WithTenant(context.Background(), testTenant), "inv-1")
+			assert.Equal(t, sc.expectedErr, err)
+		})
+	}
+}
+
+func TestDB_RenewInvitation(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectExec(`UPDATE invitations SET token_hash = \?, expires_at = \?`).
+		WithArgs("new-hash", invitationExpiry, "inv-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = NewDB(conn).RenewInvitation(context.Background(), "inv-1", "new-hash", invitationExpiry)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDB_AcceptInvitation(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		db          func() (*MYSQL, sqlmock.Sqlmock)
+		expectedErr error
+	}{
+		{
+			name: "used or expired",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`FOR UPDATE`).
+					WithArgs("hash", sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(invitationColumns))
+				mock.ExpectRollback()
+				return NewDB(conn), mock
+			},
+			expectedErr: ErrInvitationNotFound,
+		},
+		{
+			name: "insert failed",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`FOR UPDATE`).
+					WithArgs("hash", sqlmock.AnyArg()).
+					WillReturnRows(invitationRows())
+				mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM email_changes`).
+					WithArgs("jane@example.com", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"reserved"}).AddRow(false))
+				mock.ExpectPrepare(`INSERT INTO identity_users`).
+					ExpectExec().
+					WillReturnError(errors.New("insert error"))
+				mock.ExpectRollback()
+				return NewDB(conn), mock
+			},
+			expectedErr: errors.New("insert error"),
+		},
+		{
+			name: "accepted",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`FOR UPDATE`).
+					WithArgs("hash", sqlmock.AnyArg()).
+					WillReturnRows(invitationRows())
+				mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM email_changes`).
+					WithArgs("jane@example.com", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"reserved"}).AddRow(false))
+				mock.ExpectPrepare(`INSERT INTO identity_users`).
+					ExpectExec().
+					WithArgs(sqlmock.AnyArg(), "Jane", "Doe", "hashed", "jane@example.com",
+						"", "", true, "admin-1", true).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(`INSERT IGNORE INTO organization_members`).
+					WithArgs("org-1", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(`SELECT id, platform FROM roles WHERE name = ?`).
+					WithArgs("support").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "platform"}).AddRow("role-support", false))
+				mock.ExpectExec(`INSERT IGNORE INTO user_roles`).
+					WithArgs(sqlmock.AnyArg(), "role-support", "org-1").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectAudit(mock, AuditUserCreated, sqlmock.AnyArg())
+				expectEvent(mock, events.TypeUserRegistered, sqlmock.AnyArg())
+				mock.ExpectExec(`UPDATE invitations SET accepted_at = \? WHERE id = \?`).
+					WithArgs(sqlmock.AnyArg(), "inv-1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectPrepare(`SELECT first_name`).
+					ExpectQuery().
+					WillReturnRows(sqlmock.NewRows([]string{
+						"first_name", "last_name", "email", "company", "post_code",
+						"created_by", "active", "created_at", "updated_at", "roles", "locale", "timezone", "picture_url",
+					}).AddRow("Jane", "Doe", "jane@example.com", "", "", "admin-1", true,
+						"2024-01-01", "2024-01-01", "support", "", "", ""))
+				return NewDB(conn), mock
+			},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			db, mock := sc.db()
+			u := &User{FirstName: "Jane", LastName: "Doe", Password: "hashed", Terms: true}
+			user, err := db.AcceptInvitation(context.Background(), "hash", u)
+			assert.Equal(t, sc.expectedErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+			if sc.expectedErr != nil {
+				return
+			}
+			assert.Equal(t, "jane@example.com", user.Email)
+			assert.Equal(t, []string{"support"}, user.Roles)
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPageSize is the number of users listed when no page size is asked for.
+	DefaultPageSize = 20
+	// MaxPageSize is the most users listed in a single page.
+	MaxPageSize = 100
+)
+
+var (
+	// ErrInvalidCursor is returned when a cursor was not issued for the sort order of a listing.
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidSort is returned when users are listed in order of an unknown field.
+	ErrInvalidSort = errors.New("invalid sort")
+)
+
+// UserSort is a field users can be listed in order of.
+type UserSort string
+
+const (
+	SortCreatedAt UserSort = "created_at"
+	SortEmail     UserSort = "email"
+	SortLastName  UserSort = "last_name"
+)
+
+// userSortColumns are the columns each UserSort orders by, the id breaks ties.
+var userSortColumns = map[UserSort]string{
+	SortCreatedAt: "created_at",
+	SortEmail:     "email",
+	SortLastName:  "last_name",
+}
+
+// ListOptions narrows down and orders the users returned by ListUsers.
+// Filters that are not set match every user.
+type ListOptions struct {
+	// Role only lists users that have been assigned the role.
+	Role   string
+	Active *bool
+	// Company only lists users of the company, it has to match exactly.
+	Company string
+	// CreatedAfter and CreatedBefore limit users to those that registered
+	// from CreatedAfter and before CreatedBefore.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// EmailPrefix only lists users whose email starts with it.
+	EmailPrefix string
+
+	// Sort defaults to SortCreatedAt.
+	Sort       UserSort
+	Descending bool
+
+	// First is the size of the page, DefaultPageSize when 0 and never more than MaxPageSize.
+	First int
+	// After is the cursor of the user the page starts after.
+	After string
+}
+
+// UserPage is a page of users and where the next one starts.
+type UserPage struct {
+	Users []*User `json:"users"`
+	// NextCursor is the cursor of the last user, it is empty on the last page.
+	NextCursor  string `json:"next_cursor,omitempty"`
+	HasNextPage bool   `json:"has_next_page"`
+}
+
+// cursor identifies the position of a user in a listing ordered by Sort.
+type cursor struct {
+	Sort  UserSort `json:"s"`
+	Value string   `json:"v"`
+	ID    string   `json:"id"`
+}
+
+// ParseUserSort returns the UserSort named sort, an empty sort is SortCreatedAt.
+func ParseUserSort(sort string) (UserSort, bool) {
+	if sort == "" {
+		return SortCreatedAt, true
+	}
+	s := UserSort(strings.ToLower(sort))
+	_, ok := userSortColumns[s]
+	return s, ok
+}
+
+// UserCursor returns the cursor of u in a listing ordered by sort, it is
+// passed as ListOptions.After to list the users that come after u.
+func UserCursor(u *User, sort UserSort) string {
+	if sort == "" {
+		sort = SortCreatedAt
+	}
+	c := cursor{Sort: sort, ID: u.ID}
+	switch sort {
+	case SortEmail:
+		c.Value = u.Email
+	case SortLastName:
+		c.Value = u.LastName
+	default:
+		c.Value = u.CreatedAt
+	}
+
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string, sort UserSort) (*cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	c := &cursor{}
+	if err := json.Unmarshal(b, c); err != nil || c.Sort != sort || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// ListUsers returns a page of the users of the tenant that match the filters
+// of opts. Pages are read with keyset pagination so the cost of a page does
+// not grow with the number of users before it.
+func (m *MYSQL) ListUsers(ctx context.Context, opts ListOptions) (*UserPage, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	sort, ok := ParseUserSort(string(opts.Sort))
+	if !ok {
+		return nil, ErrInvalidSort
+	}
+	column := userSortColumns[sort]
+	first := opts.First
+	if first <= 0 {
+		first = DefaultPageSize
+	}
+	first = min(first, MaxPageSize)
+
+	scope, scopeArgs := tenantUsers(ctx)
+	conditions := []string{scope, notDeleted}
+	args := append(tenantRoleArgs(ctx), scopeArgs...)
+
+	if opts.Role != "" {
+		conditions = append(conditions, `identity_users.id IN (
+		     SELECT ur.user_id FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+		     WHERE r.name = ? AND `+tenantRoles+`)`)
+		args = append(args, opts.Role)
+		args = append(args, tenantRoleArgs(ctx)...)
+	}
+	if opts.Active != nil {
+		conditions = append(conditions, `active = ?`)
+		args = append(args, *opts.Active)
+	}
+	if opts.Company != "" {
+		conditions = append(conditions, `company = ?`)
+		args = append(args, opts.Company)
+	}
+	if !opts.CreatedAfter.IsZero() {
+		conditions = append(conditions, `created_at >= ?`)
+		args = append(args, opts.CreatedAfter.UTC())
+	}
+	if !opts.CreatedBefore.IsZero() {
+		conditions = append(conditions, `created_at < ?`)
+		args = append(args, opts.CreatedBefore.UTC())
+	}
+	if opts.EmailPrefix != "" {
+		conditions = append(conditions, `email LIKE ?`)
+		args = append(args, likePrefix(NormalizeEmail(opts.EmailPrefix)))
+	}
+
+	direction, comparison := "ASC", ">"
+	if opts.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	if opts.After != "" {
+		c, err := decodeCursor(opts.After, sort)
+		if err != nil {
+			return nil, err
+		}
+		var value any = c.Value
+		if sort == SortCreatedAt {
+			createdAt, err := time.Parse(time.RFC3339, c.Value)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			value = createdAt.UTC()
+		}
+		conditions = append(conditions,
+			`(`+column+` `+comparison+` ? OR (`+column+` = ? AND id `+comparison+` ?))`)
+		args = append(args, value, value, c.ID)
+	}
+
+	// one more user than asked for is read to know if there is a next page.
+	args = append(args, first+1)
+	rows, err := m.Conn.QueryContext(ctx,
+		`SELECT id, first_name, last_name, email, company, post_code, created_by, active, `+rolesColumn+`, created_at, updated_at, `+
+			profileColumns+` FROM identity_users WHERE `+strings.Join(conditions, ` AND `)+
+			` ORDER BY `+column+` `+direction+`, id `+direction+` LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users, err := scanUsers(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &UserPage{Users: users}
+	if len(users) > first {
+		page.Users = users[:first]
+		page.HasNextPage = true
+		page.NextCursor = UserCursor(page.Users[first-1], sort)
+	}
+	if page.Users == nil {
+		page.Users = []*User{}
+	}
+
+	return page, nil
+}
+
+// likePrefix returns the LIKE pattern matching values that start with prefix.
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+}
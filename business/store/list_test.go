@@ -0,0 +1,193 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var listColumns = []string{
+	"id", "first_name", "last_name", "email", "company", "post_code", "created_by", "active", "roles",
+	"created_at", "updated_at", "locale", "timezone", "picture_url",
+}
+
+func listRows(ids ...string) *sqlmock.Rows {
+	rows := sqlmock.NewRows(listColumns)
+	for _, id := range ids {
+		rows.AddRow(id, "John", "Doe", id+"@test.com", "Acme", "12345", "", true, "user",
+			"2024-01-0"+id+"T10:00:00Z", "2024-01-01T10:00:00Z", "", "", "")
+	}
+	return rows
+}
+
+func TestDB_ListUsers(t *testing.T) {
+	active := true
+	createdAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	scenarios := []struct {
+		name        string
+		db          func() *MYSQL
+		tenant      *Tenant
+		opts        ListOptions
+		expectedIDs []string
+		hasNext     bool
+		expectedErr error
+	}{
+		{
+			name:        "empty connection",
+			db:          func() *MYSQL { return &MYSQL{} },
+			expectedErr: errEmptyDBConnection,
+		},
+		{
+			name: "invalid sort",
+			db: func() *MYSQL {
+				conn, _, err := sqlmock.New()
+				assert.NoError(t, err)
+				return NewDB(conn)
+			},
+			opts:        ListOptions{Sort: "password"},
+			expectedErr: ErrInvalidSort,
+		},
+		{
+			name: "cursor of another sort",
+			db: func() *MYSQL {
+				conn, _, err := sqlmock.New()
+				assert.NoError(t, err)
+				return NewDB(conn)
+			},
+			opts:        ListOptions{Sort: SortEmail, After: UserCursor(&User{ID: "1"}, SortLastName)},
+			expectedErr: ErrInvalidCursor,
+		},
+		{
+			name: "malformed cursor",
+			db: func() *MYSQL {
+				conn, _, err := sqlmock.New()
+				assert.NoError(t, err)
+				return NewDB(conn)
+			},
+			opts:        ListOptions{After: "not a cursor"},
+			expectedErr: ErrInvalidCursor,
+		},
+		{
+			name: "query failed",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`SELECT id, first_name`).WillReturnError(errors.New("query error"))
+				return NewDB(conn)
+			},
+			expectedErr: errors.New("query error"),
+		},
+		{
+			name: "empty result",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`ORDER BY created_at ASC, id ASC LIMIT \?`).
+					WithArgs("", "", DefaultPageSize+1).
+					WillReturnRows(listRows())
+				return NewDB(conn)
+			},
+			expectedIDs: []string{},
+		},
+		{
+			name: "page size capped",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`LIMIT \?`).
+					WithArgs("", "", MaxPageSize+1).
+					WillReturnRows(listRows("1"))
+				return NewDB(conn)
+			},
+			opts:        ListOptions{First: 1000},
+			expectedIDs: []string{"1"},
+		},
+		{
+			name: "more pages",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`LIMIT \?`).
+					WithArgs("", "", 3).
+					WillReturnRows(listRows("1", "2", "3"))
+				return NewDB(conn)
+			},
+			opts:        ListOptions{First: 2},
+			expectedIDs: []string{"1", "2"},
+			hasNext:     true,
+		},
+		{
+			name: "filtered after a cursor",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`WHERE identity_users.id IN \(SELECT om.user_id FROM organization_members om WHERE om.organization_id = \?\) AND identity_users.deleted_at IS NULL`+
+					` AND identity_users.id IN \(\s+SELECT ur.user_id FROM user_roles ur JOIN roles r ON r.id = ur.role_id\s+WHERE r.name = \?.*\)`+
+					` AND active = \? AND company = \? AND created_at >= \? AND email LIKE \?`+
+					` AND \(email < \? OR \(email = \? AND id < \?\)\) ORDER BY email DESC, id DESC LIMIT \?`).
+					WithArgs("org-1", "org-1", "org-1", "support", "org-1", "org-1", true, "Acme", createdAfter,
+						`jo\_hn%`, "3@test.com", "3@test.com", "3", 11).
+					WillReturnRows(listRows("2"))
+				return NewDB(conn)
+			},
+			tenant: &testTenant,
+			opts: ListOptions{
+				Role:         "support",
+				Active:       &active,
+				Company:      "Acme",
+				CreatedAfter: createdAfter,
+				EmailPrefix:  "Jo_hn",
+				Sort:         SortEmail,
+				Descending:   true,
+				First:        10,
+				After:        UserCursor(&User{ID: "3", Email: "3@test.com"}, SortEmail),
+			},
+			expectedIDs: []string{"2"},
+		},
+		{
+			name: "after a creation time",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`\(created_at > \? OR \(created_at = \? AND id > \?\)\)`).
+					WithArgs("", "", time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+						time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), "1", DefaultPageSize+1).
+					WillReturnRows(listRows("2"))
+				return NewDB(conn)
+			},
+			opts:        ListOptions{After: UserCursor(&User{ID: "1", CreatedAt: "2024-01-01T10:00:00Z"}, SortCreatedAt)},
+			expectedIDs: []string{"2"},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if sc.tenant != nil {
+				ctx = WithTenant(ctx, *sc.tenant)
+			}
+			page, err := sc.db().ListUsers(ctx, sc.opts)
+			assert.Equal(t, sc.expectedErr, err)
+			if sc.expectedErr != nil {
+				return
+			}
+
+			ids := make([]string, 0, len(page.Users))
+			for _, u := range page.Users {
+				ids = append(ids, u.ID)
+			}
+			assert.Equal(t, sc.expectedIDs, ids)
+			assert.Equal(t, sc.hasNext, page.HasNextPage)
+			if !sc.hasNext {
+				assert.Empty(t, page.NextCursor)
+				return
+			}
+			last := page.Users[len(page.Users)-1]
+			require.Equal(t, UserCursor(last, sc.opts.Sort), page.NextCursor)
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reasons a login fails for.
+const (
+	LoginFailureUnknownEmail    = "unknown_email"
+	LoginFailureInvalidPassword = "invalid_password"
+)
+
+// LoginStore records login attempts and reads them back.
+type LoginStore interface {
+	RecordLoginAttempt(ctx context.Context, a *LoginAttempt) error
+	KnownLogins(ctx context.Context, userID, device, ipRange string) (*KnownLogins, error)
+	ListLoginAttempts(ctx context.Context, userID string, opts LoginHistoryOptions) (*LoginAttemptPage, error)
+}
+
+// LoginAttempt is a login with a password, successful or not.
+type LoginAttempt struct {
+	ID string `json:"id"`
+	// UserID is empty when nobody has the email that was used.
+	UserID    string `json:"user_id"`
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+	// Device is the fingerprint of the device the login came from and IPRange
+	// the network its IP is in, they tell new devices and places from known ones.
+	Device        string    `json:"-"`
+	IPRange       string    `json:"-"`
+	Success       bool      `json:"success"`
+	FailureReason string    `json:"failure_reason,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// KnownLogins is what the successful logins of a user have in common with a new one.
+type KnownLogins struct {
+	// Logins is the number of successful logins.
+	Logins int
+	// Device and IPRange report whether one of them came from the device or the IP range.
+	Device  bool
+	IPRange bool
+}
+
+// LoginHistoryOptions is the page of login attempts ListLoginAttempts returns.
+type LoginHistoryOptions struct {
+	// First is the size of the page, DefaultPageSize when 0 and never more than MaxPageSize.
+	First int
+	// After is the cursor of the attempt the page starts after.
+	After string
+}
+
+// LoginAttemptPage is a page of login attempts, newest first, and where the next one starts.
+type LoginAttemptPage struct {
+	Attempts []*LoginAttempt `json:"attempts"`
+	// NextCursor is the cursor of the last attempt, it is empty on the last page.
+	NextCursor  string `json:"next_cursor,omitempty"`
+	HasNextPage bool   `json:"has_next_page"`
+}
+
+// LoginAttemptCursor returns the cursor of a, it is passed as
+// LoginHistoryOptions.After to list the attempts made before a.
+func LoginAttemptCursor(a *LoginAttempt) string {
+	return encodeTimeCursor(a.CreatedAt, a.ID)
+}
+
+const loginAttemptColumns = `id, user_id, ip, ip_range, user_agent, device, success, failure_reason, created_at`
+
+// RecordLoginAttempt saves a login attempt, the time defaults to now.
+func (m *MYSQL) RecordLoginAttempt(ctx context.Context, a *LoginAttempt) error {
+	if m.Conn == nil {
+		return errEmptyDBConnection
+	}
+
+	a.ID = uuid.New().String()
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now().UTC()
+	}
+	var userID any
+	if a.UserID != "" {
+		userID = a.UserID
+	}
+
+	_, err := m.Conn.ExecContext(ctx, `INSERT INTO login_attempts (`+loginAttemptColumns+`)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.ID, userID, a.IP, a.IPRange, truncate(a.UserAgent, 255), a.Device, a.Success, a.FailureReason, a.CreatedAt)
+	return err
+}
+
+// KnownLogins compares a login from device and ipRange with the successful logins of a user.
+func (m *MYSQL) KnownLogins(ctx context.Context, userID, device, ipRange string) (*KnownLogins, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	k := &KnownLogins{}
+	err := m.Conn.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(SUM(device = ?), 0) > 0, COALESCE(SUM(ip_range = ?), 0) > 0
+	FROM login_attempts WHERE user_id = ? AND success = TRUE`, device, ipRange, userID).Scan(&k.Logins, &k.Device, &k.IPRange)
+	if err != nil {
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// ListLoginAttempts returns a page of the login attempts of a user of the
+// tenant, newest first. It returns ErrUserNotFound when there is no such user.
+func (m *MYSQL) ListLoginAttempts(ctx context.Context, userID string, opts LoginHistoryOptions) (*LoginAttemptPage, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	first := opts.First
+	if first <= 0 {
+		first = DefaultPageSize
+	}
+	first = min(first, MaxPageSize)
+
+	condition := `user_id = ?`
+	args := []any{userID}
+	if opts.After != "" {
+		c, err := decodeTimeCursor(opts.After)
+		if err != nil {
+			return nil, err
+		}
+		condition += ` AND (created_at < ? OR (created_at = ? AND id < ?))`
+		args = append(args, c.CreatedAt.UTC(), c.CreatedAt.UTC(), c.ID)
+	}
+
+	user, err := m.Retrieve(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	// one more attempt than asked for is read to know if there is a next page.
+	args = append(args, first+1)
+	attempts, err := m.loginAttempts(ctx, `SELECT `+loginAttemptColumns+` FROM login_attempts WHERE `+
+		condition+` ORDER BY created_at DESC, id DESC LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &LoginAttemptPage{Attempts: attempts}
+	if len(attempts) > first {
+		page.Attempts = attempts[:first]
+		page.HasNextPage = true
+		page.NextCursor = LoginAttemptCursor(page.Attempts[first-1])
+	}
+
+	return page, nil
+}
+
+// userLoginAttempts returns every login attempt of a user, oldest first.
+func (m *MYSQL) userLoginAttempts(ctx context.Context, userID string) ([]*LoginAttempt, error) {
+	return m.loginAttempts(ctx, `SELECT `+loginAttemptColumns+` FROM login_attempts
+	WHERE user_id = ? ORDER BY created_at, id`, userID)
+}
+
+func (m *MYSQL) loginAttempts(ctx context.Context, query string, args ...any) ([]*LoginAttempt, error) {
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []*LoginAttempt{}
+	for rows.Next() {
+		a := &LoginAttempt{}
+		var userID sql.NullString
+		err := rows.Scan(&a.ID, &userID, &a.IP, &a.IPRange, &a.UserAgent, &a.Device, &a.Success,
+			&a.FailureReason, &a.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		a.UserID = userID.String
+		attempts = append(attempts, a)
+	}
+
+	return attempts, rows.Err()
+}
@@ -0,0 +1,200 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var loginAttemptColumnNames = []string{
+	"id", "user_id", "ip", "ip_range", "user_agent", "device", "success", "failure_reason", "created_at",
+}
+
+func loginAttemptRows(ids ...string) *sqlmock.Rows {
+	rows := sqlmock.NewRows(loginAttemptColumnNames)
+	for _, id := range ids {
+		rows.AddRow(id, "user-1", "10.0.0.1", "10.0.0.0/24", "curl/8.0", "device-1", true, "",
+			time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC))
+	}
+	return rows
+}
+
+func TestDB_RecordLoginAttempt(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		attempt        *LoginAttempt
+		expectedUserID any
+	}{
+		{
+			name: "unknown email",
+			attempt: &LoginAttempt{
+				IP: "10.0.0.1", IPRange: "10.0.0.0/24", UserAgent: "curl/8.0",
+				FailureReason: LoginFailureUnknownEmail,
+			},
+			expectedUserID: nil,
+		},
+		{
+			name: "successful login",
+			attempt: &LoginAttempt{
+				UserID: "user-1", IP: "10.0.0.1", IPRange: "10.0.0.0/24", UserAgent: "curl/8.0",
+				Device: "device-1", Success: true,
+			},
+			expectedUserID: "user-1",
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			a := sc.attempt
+			mock.ExpectExec(`INSERT INTO login_attempts`).
+				WithArgs(sqlmock.AnyArg(), sc.expectedUserID, a.IP, a.IPRange, a.UserAgent, a.Device, a.Success,
+					a.FailureReason, sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			require.NoError(t, NewDB(conn).RecordLoginAttempt(context.Background(), a))
+			assert.NotEmpty(t, a.ID)
+			assert.False(t, a.CreatedAt.IsZero())
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDB_KnownLogins(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		db            func() *MYSQL
+		expectedKnown *KnownLogins
+		expectedErr   error
+	}{
+		{
+			name:        "empty connection",
+			db:          func() *MYSQL { return &MYSQL{} },
+			expectedErr: errEmptyDBConnection,
+		},
+		{
+			name: "query failed",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`FROM login_attempts`).WillReturnError(errors.New("query error"))
+				return NewDB(conn)
+			},
+			expectedErr: errors.New("query error"),
+		},
+		{
+			name: "known device from a new network",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`SELECT COUNT\(\*\), COALESCE\(SUM\(device = \?\), 0\) > 0, COALESCE\(SUM\(ip_range = \?\), 0\) > 0\s+`+
+					`FROM login_attempts WHERE user_id = \? AND success = TRUE`).
+					WithArgs("device-1", "10.0.0.0/24", "user-1").
+					WillReturnRows(sqlmock.NewRows([]string{"count", "device", "ip_range"}).AddRow(3, true, false))
+				return NewDB(conn)
+			},
+			expectedKnown: &KnownLogins{Logins: 3, Device: true},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			known, err := sc.db().KnownLogins(context.Background(), "user-1", "device-1", "10.0.0.0/24")
+			assert.Equal(t, sc.expectedErr, err)
+			assert.Equal(t, sc.expectedKnown, known)
+		})
+	}
+}
+
+func TestDB_ListLoginAttempts(t *testing.T) {
+	cursor := LoginAttemptCursor(&LoginAttempt{ID: "3", CreatedAt: time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)})
+	scenarios := []struct {
+		name        string
+		db          func() *MYSQL
+		opts        LoginHistoryOptions
+		expectedIDs []string
+		hasNext     bool
+		expectedErr error
+	}{
+		{
+			name:        "empty connection",
+			db:          func() *MYSQL { return &MYSQL{} },
+			expectedErr: errEmptyDBConnection,
+		},
+		{
+			name: "malformed cursor",
+			db: func() *MYSQL {
+				conn, _, err := sqlmock.New()
+				assert.NoError(t, err)
+				return NewDB(conn)
+			},
+			opts:        LoginHistoryOptions{After: "not a cursor"},
+			expectedErr: ErrInvalidCursor,
+		},
+		{
+			name: "user not found",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectPrepare(regexp.QuoteMeta(RetrieveQuery)).ExpectQuery().WillReturnError(sql.ErrNoRows)
+				return NewDB(conn)
+			},
+			expectedErr: ErrUserNotFound,
+		},
+		{
+			name: "more pages",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectPrepare(regexp.QuoteMeta(RetrieveQuery)).ExpectQuery().WillReturnRows(profileRows(""))
+				mock.ExpectQuery(`FROM login_attempts WHERE user_id = \? ORDER BY created_at DESC, id DESC LIMIT \?`).
+					WithArgs("user-1", 3).
+					WillReturnRows(loginAttemptRows("1", "2", "3"))
+				return NewDB(conn)
+			},
+			opts:        LoginHistoryOptions{First: 2},
+			expectedIDs: []string{"1", "2"},
+			hasNext:     true,
+		},
+		{
+			name: "after a cursor",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				created := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+				mock.ExpectPrepare(regexp.QuoteMeta(RetrieveQuery)).ExpectQuery().WillReturnRows(profileRows(""))
+				mock.ExpectQuery(`WHERE user_id = \? AND \(created_at < \? OR \(created_at = \? AND id < \?\)\)`).
+					WithArgs("user-1", created, created, "3", DefaultPageSize+1).
+					WillReturnRows(loginAttemptRows("4"))
+				return NewDB(conn)
+			},
+			opts:        LoginHistoryOptions{After: cursor},
+			expectedIDs: []string{"4"},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			page, err := sc.db().ListLoginAttempts(context.Background(), "user-1", sc.opts)
+			assert.Equal(t, sc.expectedErr, err)
+			if sc.expectedErr != nil {
+				return
+			}
+
+			ids := make([]string, 0, len(page.Attempts))
+			for _, a := range page.Attempts {
+				ids = append(ids, a.ID)
+			}
+			assert.Equal(t, sc.expectedIDs, ids)
+			assert.Equal(t, sc.hasNext, page.HasNextPage)
+			if sc.hasNext {
+				assert.Equal(t, LoginAttemptCursor(page.Attempts[len(page.Attempts)-1]), page.NextCursor)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// ErrOrganizationNotFound is returned when an operation targets an organization that does not exist.
+	ErrOrganizationNotFound = errors.New("organization not found")
+	// ErrPlatformOnly is returned when a tenant tries an operation reserved for platform superadmins.
+	ErrPlatformOnly = errors.New("operation is restricted to platform administrators")
+	// ErrSharedUser is returned when a tenant tries to change the account of a user that
+	// is also a member of other organizations, the tenant can only remove the membership.
+	ErrSharedUser = errors.New("user is a member of other organizations")
+
+	errEmptyOrganization = errors.New("empty organization name")
+)
+
+// OrganizationStore manages the organizations users belong to.
+type OrganizationStore interface {
+	CreateOrganization(ctx context.Context, name, ownerID string) (*Organization, error)
+	RetrieveOrganization(ctx context.Context, id string) (*Organization, error)
+	ListOrganizations(ctx context.Context, userID string) ([]*Organization, error)
+	IsMember(ctx context.Context, orgID, userID string) (bool, error)
+	AddMember(ctx context.Context, orgID, userID string, roles []string) error
+	RemoveMember(ctx context.Context, orgID, userID string) error
+}
+
+// Organization is a tenant, users only see and manage the members of their organization.
+type Organization struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	CreatedBy string `json:"created_by"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
+}
+
+var organizationQuery = `SELECT o.id, o.name, o.created_by, o.created_at, o.updated_at FROM organizations o`
+
+// CreateOrganization adds an organization with ownerID as its admin.
+func (m *MYSQL) CreateOrganization(ctx context.Context, name, ownerID string) (*Organization, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+	if strings.TrimSpace(name) == "" {
+		return nil, errEmptyOrganization
+	}
+
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	id, err := createOrganization(ctx, tx, name, ownerID, []string{OwnerRole})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return m.RetrieveOrganization(ctx, id)
+}
+
+func createOrganization(ctx context.Context, db execer, name, ownerID string, roles []string) (string, error) {
+	id := uuid.New().String()
+	_, err := db.ExecContext(ctx,
+		`INSERT INTO organizations (id, name, created_by) VALUES (?, ?, ?)`, id, name, ownerID)
+	if err != nil {
+		logrus.Errorf("failed to insert organization: %v", err)
+		return "", err
+	}
+
+	if err := addMember(ctx, db, id, ownerID, roles); err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// RetrieveOrganization fetches an organization, it will return ErrOrganizationNotFound if there is none.
+func (m *MYSQL) RetrieveOrganization(ctx context.Context, id string) (*Organization, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	o := &Organization{}
+	err := m.Conn.QueryRowContext(ctx, organizationQuery+` WHERE o.id = ?`, id).
+		Scan(&o.ID, &o.Name, &o.CreatedBy, &o.CreatedAt, &o.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// ListOrganizations returns the organizations a user is a member of, oldest membership first.
+func (m *MYSQL) ListOrganizations(ctx context.Context, userID string) ([]*Organization, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	rows, err := m.Conn.QueryContext(ctx, organizationQuery+`
+		JOIN organization_members om ON om.organization_id = o.id
+		JOIN identity_users ON identity_users.id = om.user_id
+		WHERE om.user_id = ? AND `+notDeleted+` ORDER BY om.created_at, o.name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	organizations := []*Organization{}
+	for rows.Next() {
+		o := &Organization{}
+		if err := rows.Scan(&o.ID, &o.Name, &o.CreatedBy, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			return nil, err
+		}
+		organizations = append(organizations, o)
+	}
+
+	return organizations, rows.Err()
+}
+
+// IsMember reports whether the user belongs to the organization, deleted
+// users belong to none.
+func (m *MYSQL) IsMember(ctx context.Context, orgID, userID string) (bool, error) {
+	if m.Conn == nil {
+		return false, errEmptyDBConnection
+	}
+
+	return isMember(ctx, m.Conn, orgID, userID)
+}
+
+func isMember(ctx context.Context, db execer, orgID, userID string) (bool, error) {
+	var count int
+	err := db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM organization_members om
+		JOIN identity_users ON identity_users.id = om.user_id
+		WHERE om.organization_id = ? AND om.user_id = ? AND `+notDeleted,
+		orgID, userID).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// AddMember adds an existing user to an organization with the given roles,
+// the user gets DefaultRole when no roles are given. Deleted users can not be added.
+func (m *MYSQL) AddMember(ctx context.Context, orgID, userID string, roles []string) error {
+	if m.Conn == nil {
+		return errEmptyDBConnection
+	}
+
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := addMember(ctx, tx, orgID, userID, roles); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func addMember(ctx context.Context, db execer, orgID, userID string, roles []string) error {
+	result, err := db.ExecContext(ctx,
+		`INSERT IGNORE INTO organization_members (organization_id, user_id)
+		SELECT ?, id FROM identity_users WHERE id = ? AND `+notDeleted, orgID, userID)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrNoReferencedRow {
+			return ErrOrganizationNotFound
+		}
+		return err
+	}
+	added, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if added == 0 {
+		// nothing is added for users that are already members as well.
+		member, err := isMember(ctx, db, orgID, userID)
+		if err != nil {
+			return err
+		}
+		if !member {
+			return ErrUserNotFound
+		}
+	}
+
+	if len(roles) == 0 {
+		roles = []string{DefaultRole}
+	}
+	for _, role := range roles {
+		if _, _, err := assignRole(ctx, db, userID, role, orgID); err != nil {
+			logrus.Errorf("failed to assign role %s in organization %s: %v", role, orgID, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RemoveMember takes a user out of an organization along with the roles they had in it.
+func (m *MYSQL) RemoveMember(ctx context.Context, orgID, userID string) error {
+	if m.Conn == nil {
+		return errEmptyDBConnection
+	}
+
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx,
+		`DELETE FROM user_roles WHERE organization_id = ? AND user_id = ?`, orgID, userID)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx,
+		`DELETE FROM organization_members WHERE organization_id = ? AND user_id = ?`, orgID, userID)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return tx.Commit()
+}
+
+// soleMember returns ErrSharedUser when ctx has an organization as tenant and
+// user id is a member of another organization too. Changes to the account of
+// such a user are left to platform administrators.
+func soleMember(ctx context.Context, tx *sql.Tx, id string) error {
+	t, ok := TenantFromContext(ctx)
+	if !ok || t.Platform {
+		return nil
+	}
+
+	var shared bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM organization_members WHERE user_id = ? AND organization_id <> ?)`,
+		id, t.OrganizationID).Scan(&shared)
+	if err != nil {
+		return err
+	}
+	if shared {
+		return ErrSharedUser
+	}
+
+	return nil
+}
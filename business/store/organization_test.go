@@ -0,0 +1,210 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var organizationColumns = []string{"id", "name", "created_by", "created_at", "updated_at"}
+
+func TestDB_CreateOrganization(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		db          *MYSQL
+		orgName     string
+		expected    *Organization
+		expectedErr error
+	}{
+		{
+			name:        "empty connection",
+			db:          &MYSQL{},
+			orgName:     "Acme",
+			expectedErr: errEmptyDBConnection,
+		},
+		{
+			name:        "empty name",
+			db:          &MYSQL{Conn: &sql.DB{}},
+			orgName:     " ",
+			expectedErr: errEmptyOrganization,
+		},
+		{
+			name: "insert failed",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO organizations`).
+					WithArgs(sqlmock.AnyArg(), "Acme", "user-123").
+					WillReturnError(errors.New("insert error"))
+				mock.ExpectRollback()
+				return NewDB(conn)
+			}(),
+			orgName:     "Acme",
+			expectedErr: errors.New("insert error"),
+		},
+		{
+			name: "success",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO organizations`).
+					WithArgs(sqlmock.AnyArg(), "Acme", "user-123").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(`INSERT IGNORE INTO organization_members`).
+					WithArgs(sqlmock.AnyArg(), "user-123").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(`SELECT id, platform FROM roles WHERE name = ?`).
+					WithArgs(OwnerRole).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "platform"}).AddRow("role-admin", false))
+				mock.ExpectExec(`INSERT IGNORE INTO user_roles`).
+					WithArgs("user-123", "role-admin", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+				mock.ExpectQuery(`SELECT o.id, o.name`).
+					WithArgs(sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows(organizationColumns).
+						AddRow("org-1", "Acme", "user-123", "2024-01-01", "2024-01-01"))
+				return NewDB(conn)
+			}(),
+			orgName: "Acme",
+			expected: &Organization{
+				ID: "org-1", Name: "Acme", CreatedBy: "user-123", CreatedAt: "2024-01-01", UpdatedAt: "2024-01-01",
+			},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			org, err := sc.db.CreateOrganization(context.Background(), sc.orgName, "user-123")
+			assert.Equal(t, sc.expectedErr, err)
+			assert.Equal(t, sc.expected, org)
+		})
+	}
+}
+
+func TestDB_RetrieveOrganization(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectQuery(`SELECT o.id, o.name`).
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = NewDB(conn).RetrieveOrganization(context.Background(), "missing")
+	assert.Equal(t, ErrOrganizationNotFound, err)
+}
+
+func TestDB_ListOrganizations(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectQuery(`JOIN organization_members om .* WHERE om.user_id = \? AND identity_users.deleted_at IS NULL`).
+		WithArgs("user-123").
+		WillReturnRows(sqlmock.NewRows(organizationColumns).
+			AddRow("org-1", "Acme", "user-123", "2024-01-01", "2024-01-01").
+			AddRow("org-2", "Globex", "user-456", "2024-01-02", "2024-01-02"))
+
+	organizations, err := NewDB(conn).ListOrganizations(context.Background(), "user-123")
+	assert.NoError(t, err)
+	assert.Equal(t, []*Organization{
+		{ID: "org-1", Name: "Acme", CreatedBy: "user-123", CreatedAt: "2024-01-01", UpdatedAt: "2024-01-01"},
+		{ID: "org-2", Name: "Globex", CreatedBy: "user-456", CreatedAt: "2024-01-02", UpdatedAt: "2024-01-02"},
+	}, organizations)
+}
+
+func TestDB_IsMember(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		count    int
+		expected bool
+	}{
+		{name: "member", count: 1, expected: true},
+		{name: "not a member", count: 0, expected: false},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			mock.ExpectQuery(`SELECT COUNT\(\*\) FROM organization_members om .* AND identity_users.deleted_at IS NULL`).
+				WithArgs("org-1", "user-123").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(sc.count))
+
+			member, err := NewDB(conn).IsMember(context.Background(), "org-1", "user-123")
+			assert.NoError(t, err)
+			assert.Equal(t, sc.expected, member)
+		})
+	}
+}
+
+func TestDB_AddMember(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		added       int64
+		member      int
+		expectedErr error
+	}{
+		{name: "deleted or unknown user", expectedErr: ErrUserNotFound},
+		{name: "already a member", member: 1},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			mock.ExpectBegin()
+			mock.ExpectExec(`INSERT IGNORE INTO organization_members \(organization_id, user_id\)\s+SELECT \?, id FROM identity_users WHERE id = \? AND identity_users.deleted_at IS NULL`).
+				WithArgs("org-1", "user-123").
+				WillReturnResult(sqlmock.NewResult(0, sc.added))
+			mock.ExpectQuery(`SELECT COUNT\(\*\) FROM organization_members om`).
+				WithArgs("org-1", "user-123").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(sc.member))
+			if sc.expectedErr == nil {
+				mock.ExpectQuery(`SELECT id, platform FROM roles WHERE name = ?`).WithArgs(DefaultRole).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "platform"}).AddRow("role-user", false))
+				mock.ExpectExec(`INSERT IGNORE INTO user_roles`).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			err = NewDB(conn).AddMember(context.Background(), "org-1", "user-123", nil)
+			assert.Equal(t, sc.expectedErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDB_RemoveMember(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		removed     int64
+		expectedErr error
+	}{
+		{name: "not a member", removed: 0, expectedErr: ErrUserNotFound},
+		{name: "success", removed: 1},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			mock.ExpectBegin()
+			mock.ExpectExec(`DELETE FROM user_roles WHERE organization_id = \? AND user_id = \?`).
+				WithArgs("org-1", "user-123").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(`DELETE FROM organization_members`).
+				WithArgs("org-1", "user-123").
+				WillReturnResult(sqlmock.NewResult(0, sc.removed))
+			if sc.expectedErr == nil {
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			err = NewDB(conn).RemoveMember(context.Background(), "org-1", "user-123")
+			assert.Equal(t, sc.expectedErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/riyadennis/identity-server/business/events"
+)
+
+// OutboxStore reads the domain events waiting in the outbox to be published.
+type OutboxStore interface {
+	RelayEvents(ctx context.Context, limit int, publish func(*OutboxEvent) error) (int, error)
+}
+
+// OutboxEvent is a domain event saved in the outbox.
+type OutboxEvent struct {
+	ID   string
+	Type string
+	// AggregateID is the user the event is about.
+	AggregateID string
+	Payload     json.RawMessage
+	OccurredAt  time.Time
+	// Attempts is the number of times publishing the event failed.
+	Attempts int
+}
+
+// eventOrganization returns the organization the events of changes made
+// with ctx happen in, changes of platform administrators have none.
+func eventOrganization(ctx context.Context) string {
+	if platformTenant(ctx) {
+		return ""
+	}
+	t, _ := TenantFromContext(ctx)
+	return t.OrganizationID
+}
+
+// enqueueEvent writes e to the outbox with db, which is the transaction of
+// the change e describes, so the event is published if and only if the change is committed.
+func enqueueEvent(ctx context.Context, db execer, e events.Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, `INSERT INTO outbox_events (id, type, aggregate_id, payload, occurred_at)
+	VALUES (?, ?, ?, ?, ?)`, uuid.New().String(), e.EventType(), e.AggregateID(), string(payload), time.Now().UTC())
+	return err
+}
+
+// outboxLease is how long a relay has to publish the events it claimed,
+// once it is over other relays can claim them again.
+const outboxLease = time.Minute
+
+// RelayEvents passes up to limit events waiting in the outbox to publish,
+// oldest first, and marks the ones it published. The events are claimed in a
+// short transaction and published outside of it, so relays running side by
+// side publish different events and a slow broker holds no locks. When an
+// event of a user can not be published the later events of that user are
+// left for the next call so they are published in order. Events can be
+// published more than once when marking them fails or the lease runs out.
+// It returns the number of events published.
+func (m *MYSQL) RelayEvents(ctx context.Context, limit int, publish func(*OutboxEvent) error) (int, error) {
+	if m.Conn == nil {
+		return 0, errEmptyDBConnection
+	}
+
+	sequences, pending, err := m.claimEvents(ctx, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	failed := map[string]bool{}
+	for i, e := range pending {
+		if failed[e.AggregateID] {
+			// released so the next call publishes it after the failed one
+			_, err := m.Conn.ExecContext(ctx, `UPDATE outbox_events SET claimed_until = NULL WHERE sequence = ?`,
+				sequences[i])
+			if err != nil {
+				return published, err
+			}
+			continue
+		}
+		if err := publish(e); err != nil {
+			failed[e.AggregateID] = true
+			_, err = m.Conn.ExecContext(ctx, `UPDATE outbox_events SET attempts = attempts + 1, last_error = ?,
+			claimed_until = NULL WHERE sequence = ?`, truncate(err.Error(), 255), sequences[i])
+			if err != nil {
+				return published, err
+			}
+			continue
+		}
+		_, err := m.Conn.ExecContext(ctx, `UPDATE outbox_events SET published_at = ?, claimed_until = NULL WHERE sequence = ?`,
+			time.Now().UTC(), sequences[i])
+		if err != nil {
+			return published, err
+		}
+		published++
+	}
+
+	return published, nil
+}
+
+// claimEvents claims up to limit unpublished events for outboxLease, oldest
+// first. Events claimed by another relay are skipped and so are the events of
+// users with an earlier event claimed by another relay, which has to be
+// published first. It returns the sequences of the events with the events.
+func (m *MYSQL) claimEvents(ctx context.Context, limit int) ([]int64, []*OutboxEvent, error) {
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().UTC()
+	rows, err := tx.QueryContext(ctx, `SELECT sequence, id, type, aggregate_id, payload, occurred_at, attempts
+	FROM outbox_events o WHERE published_at IS NULL AND (claimed_until IS NULL OR claimed_until < ?)
+	AND NOT EXISTS (SELECT 1 FROM outbox_events earlier WHERE earlier.aggregate_id = o.aggregate_id
+	AND earlier.published_at IS NULL AND earlier.sequence < o.sequence AND earlier.claimed_until >= ?)
+	ORDER BY sequence LIMIT ? FOR UPDATE`, now, now, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	var sequences []int64
+	var pending []*OutboxEvent
+	for rows.Next() {
+		var sequence int64
+		var payload string
+		e := &OutboxEvent{}
+		if err := rows.Scan(&sequence, &e.ID, &e.Type, &e.AggregateID, &payload, &e.OccurredAt, &e.Attempts); err != nil {
+			_ = rows.Close()
+			return nil, nil, err
+		}
+		e.Payload = json.RawMessage(payload)
+		sequences = append(sequences, sequence)
+		pending = append(pending, e)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, nil, err
+	}
+	if len(sequences) == 0 {
+		return nil, nil, nil
+	}
+
+	args := []any{now.Add(outboxLease)}
+	for _, sequence := range sequences {
+		args = append(args, sequence)
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE outbox_events SET claimed_until = ? WHERE sequence IN (?`+
+		strings.Repeat(", ?", len(sequences)-1)+`)`, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	return sequences, pending, nil
+}
@@ -0,0 +1,199 @@
+package store
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riyadennis/identity-server/business/events"
+)
+
+// expectEvent expects an event of eventType to be written to the outbox for aggregateID.
+func expectEvent(mock sqlmock.Sqlmock, eventType string, aggregateID driver.Value) {
+	mock.ExpectExec(`INSERT INTO outbox_events`).
+		WithArgs(sqlmock.AnyArg(), eventType, aggregateID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+var outboxEventColumns = []string{"sequence", "id", "type", "aggregate_id", "payload", "occurred_at", "attempts"}
+
+func outboxRows(events ...[2]string) *sqlmock.Rows {
+	rows := sqlmock.NewRows(outboxEventColumns)
+	for i, e := range events {
+		rows.AddRow(int64(i+1), e[0], "user.activated", e[1], `{"user_id":"`+e[1]+`"}`,
+			time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), 0)
+	}
+	return rows
+}
+
+func TestDB_RelayEvents(t *testing.T) {
+	scenarios := []struct {
+		name              string
+		db                func() (*MYSQL, sqlmock.Sqlmock)
+		failing           string
+		expectedPublished []string
+		expectedCount     int
+		expectedErr       error
+	}{
+		{
+			name:        "empty connection",
+			db:          func() (*MYSQL, sqlmock.Sqlmock) { return &MYSQL{}, nil },
+			expectedErr: errEmptyDBConnection,
+		},
+		{
+			name: "query failed",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`FROM outbox_events`).WillReturnError(errors.New("query error"))
+				mock.ExpectRollback()
+				return NewDB(conn), mock
+			},
+			expectedErr: errors.New("query error"),
+		},
+		{
+			name: "nothing to publish",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`FROM outbox_events`).WillReturnRows(outboxRows())
+				mock.ExpectRollback()
+				return NewDB(conn), mock
+			},
+		},
+		{
+			name: "published",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT sequence, id, type, aggregate_id, payload, occurred_at, attempts\s+`+
+					`FROM outbox_events o WHERE published_at IS NULL AND \(claimed_until IS NULL OR claimed_until < \?\)\s+`+
+					`AND NOT EXISTS \(SELECT 1 FROM outbox_events earlier WHERE earlier.aggregate_id = o.aggregate_id\s+`+
+					`AND earlier.published_at IS NULL AND earlier.sequence < o.sequence AND earlier.claimed_until >= \?\)\s+`+
+					`ORDER BY sequence LIMIT \? FOR UPDATE`).
+					WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 10).
+					WillReturnRows(outboxRows([2]string{"e1", "user-1"}, [2]string{"e2", "user-2"}))
+				mock.ExpectExec(`UPDATE outbox_events SET claimed_until = \? WHERE sequence IN \(\?, \?\)`).
+					WithArgs(sqlmock.AnyArg(), int64(1), int64(2)).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+				// published after the claim is committed
+				mock.ExpectExec(`UPDATE outbox_events SET published_at = \?, claimed_until = NULL WHERE sequence = \?`).
+					WithArgs(sqlmock.AnyArg(), int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`UPDATE outbox_events SET published_at = \?, claimed_until = NULL WHERE sequence = \?`).
+					WithArgs(sqlmock.AnyArg(), int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+				return NewDB(conn), mock
+			},
+			expectedPublished: []string{"e1", "e2"},
+			expectedCount:     2,
+		},
+		{
+			name: "claim failed",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`FROM outbox_events`).WillReturnRows(outboxRows([2]string{"e1", "user-1"}))
+				mock.ExpectExec(`UPDATE outbox_events SET claimed_until`).WillReturnError(errors.New("lock wait timeout"))
+				mock.ExpectRollback()
+				return NewDB(conn), mock
+			},
+			expectedErr: errors.New("lock wait timeout"),
+		},
+		{
+			name: "later events of a failed user are kept",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`FROM outbox_events`).
+					WillReturnRows(outboxRows([2]string{"e1", "user-1"}, [2]string{"e2", "user-2"}, [2]string{"e3", "user-1"}))
+				mock.ExpectExec(`UPDATE outbox_events SET claimed_until = \? WHERE sequence IN \(\?, \?, \?\)`).
+					WithArgs(sqlmock.AnyArg(), int64(1), int64(2), int64(3)).WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectCommit()
+				mock.ExpectExec(`UPDATE outbox_events SET attempts = attempts \+ 1, last_error = \?,\s+claimed_until = NULL WHERE sequence = \?`).
+					WithArgs("broker down", int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`UPDATE outbox_events SET published_at = \?, claimed_until = NULL WHERE sequence = \?`).
+					WithArgs(sqlmock.AnyArg(), int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`UPDATE outbox_events SET claimed_until = NULL WHERE sequence = \?`).
+					WithArgs(int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+				return NewDB(conn), mock
+			},
+			failing:           "e1",
+			expectedPublished: []string{"e2"},
+			expectedCount:     1,
+		},
+		{
+			name: "marking failed",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`FROM outbox_events`).
+					WillReturnRows(outboxRows([2]string{"e1", "user-1"}, [2]string{"e2", "user-2"}))
+				mock.ExpectExec(`UPDATE outbox_events SET claimed_until`).WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectCommit()
+				mock.ExpectExec(`UPDATE outbox_events SET published_at`).WillReturnError(errors.New("connection reset"))
+				return NewDB(conn), mock
+			},
+			expectedPublished: []string{"e1"},
+			expectedErr:       errors.New("connection reset"),
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			db, mock := sc.db()
+			var published []string
+			count, err := db.RelayEvents(context.Background(), 10, func(e *OutboxEvent) error {
+				if e.ID == sc.failing {
+					return errors.New("broker down")
+				}
+				published = append(published, e.ID)
+				return nil
+			})
+			assert.Equal(t, sc.expectedErr, err)
+			assert.Equal(t, sc.expectedCount, count)
+			assert.Equal(t, sc.expectedPublished, published)
+			if mock != nil {
+				assert.NoError(t, mock.ExpectationsWereMet())
+			}
+		})
+	}
+}
+
+func TestEnqueueEventPayload(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	mock.ExpectExec(`INSERT INTO outbox_events \(id, type, aggregate_id, payload, occurred_at\)`).
+		WithArgs(sqlmock.AnyArg(), "role.assigned", "user-1", `{"user_id":"user-1","role":"admin","organization_id":"org-1"}`, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = enqueueEvent(context.Background(), conn, events.RoleAssigned{UserID: "user-1", Role: "admin", OrganizationID: "org-1"})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEventOrganization(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		ctx      context.Context
+		expected string
+	}{
+		{name: "no tenant", ctx: context.Background()},
+		{name: "organization", ctx: WithTenant(context.Background(), testTenant), expected: "org-1"},
+		{name: "platform", ctx: WithTenant(context.Background(), Tenant{OrganizationID: "org-1", Platform: true})},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			assert.Equal(t, sc.expected, eventOrganization(sc.ctx))
+		})
+	}
+}
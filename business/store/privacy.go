@@ -0,0 +1,258 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// erasedEmailDomain is the domain of the addresses erased users are given,
+// .invalid can never be delivered to.
+const erasedEmailDomain = "erased.invalid"
+
+// PrivacyStore answers subject access and erasure requests.
+type PrivacyStore interface {
+	ExportUser(ctx context.Context, id string) (*UserExport, error)
+	EraseUser(ctx context.Context, id, erasedBy string) (*Erasure, error)
+}
+
+// UserExport is everything stored about a user.
+type UserExport struct {
+	ExportedAt    time.Time       `json:"exported_at"`
+	Profile       *User           `json:"profile"`
+	Organizations []*Organization `json:"organizations"`
+	Roles         []*RoleGrant    `json:"roles"`
+	Sessions      []*Session      `json:"sessions"`
+	EmailChanges  []*EmailChange  `json:"email_changes"`
+	// AuditEvents are the events the user made or was the target of.
+	AuditEvents   []*AuditEvent   `json:"audit_events"`
+	LoginAttempts []*LoginAttempt `json:"login_attempts"`
+}
+
+// RoleGrant is a role a user has in an organization, platform roles have no organization.
+type RoleGrant struct {
+	Role           string `json:"role"`
+	OrganizationID string `json:"organization_id"`
+}
+
+// Session is the metadata of a login token, the token itself is never exported.
+type Session struct {
+	ID             string     `json:"id"`
+	OrganizationID string     `json:"organization_id"`
+	TTL            string     `json:"ttl"`
+	Expiry         *time.Time `json:"expiry,omitempty"`
+	LastUsed       *time.Time `json:"last_used,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// Erasure records that the personal data of a user was erased.
+type Erasure struct {
+	ID       string    `json:"id"`
+	UserID   string    `json:"user_id"`
+	ErasedBy string    `json:"erased_by"`
+	ErasedAt time.Time `json:"erased_at"`
+}
+
+// exportOrganization returns the organization an export of user id is
+// limited to. Exports made by platform administrators and by the user
+// themselves have everything, others only what belongs to the tenant's organization.
+func exportOrganization(ctx context.Context, id string) string {
+	if platformTenant(ctx) || ActorFromContext(ctx).UserID == id {
+		return ""
+	}
+	t, _ := TenantFromContext(ctx)
+	return t.OrganizationID
+}
+
+// ExportUser reads everything stored about a user of the tenant, it returns
+// ErrUserNotFound when there is no such user. When an organization exports one
+// of its members it gets the member's data in the organization, the email
+// changes and login attempts of the account are left out.
+func (m *MYSQL) ExportUser(ctx context.Context, id string) (*UserExport, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	user, err := m.Retrieve(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	orgID := exportOrganization(ctx, id)
+	export := &UserExport{
+		ExportedAt:    time.Now().UTC(),
+		Profile:       user,
+		Organizations: []*Organization{},
+		EmailChanges:  []*EmailChange{},
+		LoginAttempts: []*LoginAttempt{},
+	}
+	organizations, err := m.ListOrganizations(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range organizations {
+		if orgID == "" || o.ID == orgID {
+			export.Organizations = append(export.Organizations, o)
+		}
+	}
+	if export.Roles, err = m.roleGrants(ctx, id, orgID); err != nil {
+		return nil, err
+	}
+	if export.Sessions, err = m.sessions(ctx, id, orgID); err != nil {
+		return nil, err
+	}
+	if orgID == "" {
+		if export.EmailChanges, err = m.emailChanges(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	if export.AuditEvents, err = m.userAuditEvents(ctx, id, orgID); err != nil {
+		return nil, err
+	}
+	if orgID == "" {
+		if export.LoginAttempts, err = m.userLoginAttempts(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return export, nil
+}
+
+// inOrganization restricts rows to the organization passed twice as
+// arguments, an empty organization keeps every row.
+const inOrganization = `(? = '' OR organization_id = ?)`
+
+func (m *MYSQL) roleGrants(ctx context.Context, userID, orgID string) ([]*RoleGrant, error) {
+	rows, err := m.Conn.QueryContext(ctx, `SELECT r.name, ur.organization_id FROM user_roles ur
+	JOIN roles r ON r.id = ur.role_id WHERE ur.user_id = ? AND `+inOrganization+`
+	ORDER BY ur.organization_id, r.name`, userID, orgID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := []*RoleGrant{}
+	for rows.Next() {
+		g := &RoleGrant{}
+		if err := rows.Scan(&g.Role, &g.OrganizationID); err != nil {
+			return nil, err
+		}
+		grants = append(grants, g)
+	}
+	return grants, rows.Err()
+}
+
+func (m *MYSQL) sessions(ctx context.Context, userID, orgID string) ([]*Session, error) {
+	rows, err := m.Conn.QueryContext(ctx, `SELECT id, organization_id, ttl, expiry, last_used, created_at
+	FROM login_tokens WHERE user_id = ? AND `+inOrganization+` ORDER BY created_at`, userID, orgID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		s := &Session{}
+		var ttl sql.NullString
+		if err := rows.Scan(&s.ID, &s.OrganizationID, &ttl, &s.Expiry, &s.LastUsed, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		s.TTL = ttl.String
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+func (m *MYSQL) emailChanges(ctx context.Context, userID string) ([]*EmailChange, error) {
+	rows, err := m.Conn.QueryContext(ctx, `SELECT id, user_id, old_email, new_email, expires_at
+	FROM email_changes WHERE user_id = ? ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []*EmailChange{}
+	for rows.Next() {
+		c := &EmailChange{}
+		if err := rows.Scan(&c.ID, &c.UserID, &c.OldEmail, &c.NewEmail, &c.ExpiresAt); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// EraseUser removes the personal data of a user in one transaction. The user
+// is kept, anonymized and deleted, so rows referring to it stay valid, its
+// sessions, email changes, login attempts, webhook deliveries and outbox
+// events are removed and invitations sent to its email are anonymized. The erasure is recorded and returned.
+// The account is shared by every organization of the user, so only platform
+// administrators and the user themselves can erase it.
+func (m *MYSQL) EraseUser(ctx context.Context, id, erasedBy string) (*Erasure, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+	if !platformTenant(ctx) && ActorFromContext(ctx).UserID != id {
+		return nil, ErrPlatformOnly
+	}
+
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	scope, scopeArgs := tenantUsers(ctx)
+	var email string
+	err = tx.QueryRowContext(ctx, `SELECT email FROM identity_users WHERE id = ? AND erased_at IS NULL AND `+scope+` FOR UPDATE`,
+		append([]any{id}, scopeArgs...)...).Scan(&email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	e := &Erasure{ID: uuid.New().String(), UserID: id, ErasedBy: erasedBy, ErasedAt: time.Now().UTC()}
+	anonymous := "erased+" + id + "@" + erasedEmailDomain
+	_, err = tx.ExecContext(ctx, `UPDATE identity_users SET first_name = '', last_name = '', email = ?, password = '',
+	company = '', post_code = '', locale = '', timezone = '', picture_url = '', active = FALSE,
+	deleted_at = COALESCE(deleted_at, ?), erased_at = ? WHERE id = ?`, anonymous, e.ErasedAt, e.ErasedAt, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, table := range []string{"login_tokens", "email_changes", "login_attempts", "webhook_deliveries"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE user_id = ?`, id); err != nil {
+			return nil, err
+		}
+	}
+	// the events about the user carry its details, those not published yet are dropped too.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM outbox_events WHERE aggregate_id = ?`, id); err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE invitations SET email = ? WHERE email = ?`, anonymous, email)
+	if err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO erasures (id, user_id, erased_by, erased_at) VALUES (?, ?, ?, ?)`,
+		e.ID, e.UserID, e.ErasedBy, e.ErasedAt)
+	if err != nil {
+		return nil, err
+	}
+	err = recordAudit(ctx, tx, &AuditEvent{ActorID: erasedBy, Action: AuditUserErased, TargetType: AuditTargetUser, TargetID: id})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
@@ -0,0 +1,259 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB_ExportUser(t *testing.T) {
+	created := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	scenarios := []struct {
+		name        string
+		db          func() *MYSQL
+		expectedErr error
+	}{
+		{
+			name:        "empty connection",
+			db:          func() *MYSQL { return &MYSQL{} },
+			expectedErr: errEmptyDBConnection,
+		},
+		{
+			name: "not found",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectPrepare(regexp.QuoteMeta(RetrieveQuery)).ExpectQuery().WillReturnError(sql.ErrNoRows)
+				return NewDB(conn)
+			},
+			expectedErr: ErrUserNotFound,
+		},
+		{
+			name: "sessions failed",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectPrepare(regexp.QuoteMeta(RetrieveQuery)).ExpectQuery().WillReturnRows(profileRows(""))
+				mock.ExpectQuery(`FROM organizations o`).WillReturnRows(sqlmock.NewRows(
+					[]string{"id", "name", "created_by", "created_at", "updated_at"}))
+				mock.ExpectQuery(`FROM user_roles ur`).WillReturnRows(sqlmock.NewRows([]string{"name", "organization_id"}))
+				mock.ExpectQuery(`FROM login_tokens`).WillReturnError(errors.New("query error"))
+				return NewDB(conn)
+			},
+			expectedErr: errors.New("query error"),
+		},
+		{
+			name: "exported",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectPrepare(regexp.QuoteMeta(RetrieveQuery)).ExpectQuery().WillReturnRows(profileRows("en-GB"))
+				mock.ExpectQuery(`FROM organizations o JOIN organization_members om`).
+					WithArgs("user-1").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_by", "created_at", "updated_at"}).
+						AddRow("org-1", "Acme", "user-1", "2024-01-01", "2024-01-01"))
+				mock.ExpectQuery(`SELECT r.name, ur.organization_id FROM user_roles ur`).
+					WithArgs("user-1", "", "").
+					WillReturnRows(sqlmock.NewRows([]string{"name", "organization_id"}).AddRow("admin", "org-1"))
+				mock.ExpectQuery(`SELECT id, organization_id, ttl, expiry, last_used, created_at\s+FROM login_tokens WHERE user_id = \?`).
+					WithArgs("user-1", "", "").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "ttl", "expiry", "last_used", "created_at"}).
+						AddRow("token-1", "org-1", "15m", created, nil, created))
+				mock.ExpectQuery(`FROM email_changes WHERE user_id = \?`).
+					WithArgs("user-1").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "old_email", "new_email", "expires_at"}).
+						AddRow("change-1", "user-1", "old@example.com", "jane@example.com", created))
+				mock.ExpectQuery(`FROM audit_events\s+WHERE \(actor_id = \? OR \(target_type = \? AND target_id = \?\)\) AND \(\? = '' OR organization_id = \?\) ORDER BY created_at, id`).
+					WithArgs("user-1", AuditTargetUser, "user-1", "", "").
+					WillReturnRows(auditRows("event-1"))
+				mock.ExpectQuery(`FROM login_attempts\s+WHERE user_id = \? ORDER BY created_at, id`).
+					WithArgs("user-1").
+					WillReturnRows(loginAttemptRows("attempt-1"))
+				return NewDB(conn)
+			},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			export, err := sc.db().ExportUser(context.Background(), "user-1")
+			assert.Equal(t, sc.expectedErr, err)
+			if sc.expectedErr != nil {
+				return
+			}
+
+			assert.Equal(t, "jane@example.com", export.Profile.Email)
+			require.Len(t, export.Organizations, 1)
+			assert.Equal(t, []*RoleGrant{{Role: "admin", OrganizationID: "org-1"}}, export.Roles)
+			assert.Equal(t, []*Session{{
+				ID: "token-1", OrganizationID: "org-1", TTL: "15m", Expiry: &created, CreatedAt: created,
+			}}, export.Sessions)
+			require.Len(t, export.EmailChanges, 1)
+			assert.Equal(t, "old@example.com", export.EmailChanges[0].OldEmail)
+			require.Len(t, export.AuditEvents, 1)
+			assert.Equal(t, AuditUserActivationToggled, export.AuditEvents[0].Action)
+			require.Len(t, export.LoginAttempts, 1)
+			assert.Equal(t, "attempt-1", export.LoginAttempts[0].ID)
+		})
+	}
+}
+
+func TestDB_ExportUser_Organization(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	mock.ExpectPrepare(regexp.QuoteMeta(RetrieveQuery)).ExpectQuery().WillReturnRows(profileRows(""))
+	mock.ExpectQuery(`FROM organizations o JOIN organization_members om`).
+		WithArgs("user-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_by", "created_at", "updated_at"}).
+			AddRow("org-1", "Acme", "user-1", "2024-01-01", "2024-01-01").
+			AddRow("org-2", "Globex", "user-2", "2024-01-01", "2024-01-01"))
+	mock.ExpectQuery(`FROM user_roles ur`).
+		WithArgs("user-1", "org-1", "org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "organization_id"}).AddRow("user", "org-1"))
+	mock.ExpectQuery(`FROM login_tokens`).
+		WithArgs("user-1", "org-1", "org-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "ttl", "expiry", "last_used", "created_at"}))
+	mock.ExpectQuery(`FROM audit_events`).
+		WithArgs("user-1", AuditTargetUser, "user-1", "org-1", "org-1").
+		WillReturnRows(auditRows("event-1"))
+
+	ctx := WithTenant(context.Background(), Tenant{OrganizationID: "org-1"})
+	ctx = WithActor(ctx, Actor{UserID: "admin-1"})
+	export, err := NewDB(conn).ExportUser(ctx, "user-1")
+	require.NoError(t, err)
+	require.Len(t, export.Organizations, 1)
+	assert.Equal(t, "org-1", export.Organizations[0].ID)
+	assert.Empty(t, export.EmailChanges)
+	assert.Empty(t, export.LoginAttempts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDB_EraseUser_Organization(t *testing.T) {
+	ctx := WithTenant(context.Background(), Tenant{OrganizationID: "org-1"})
+	ctx = WithActor(ctx, Actor{UserID: "admin-1"})
+
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	_, err = NewDB(conn).EraseUser(ctx, "user-1", "admin-1")
+	assert.Equal(t, ErrPlatformOnly, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDB_EraseUser(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		db          func() *MYSQL
+		expectedErr error
+	}{
+		{
+			name:        "empty connection",
+			db:          func() *MYSQL { return &MYSQL{} },
+			expectedErr: errEmptyDBConnection,
+		},
+		{
+			name: "not found or already erased",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT email FROM identity_users WHERE id = \? AND erased_at IS NULL AND TRUE FOR UPDATE`).
+					WithArgs("user-1").
+					WillReturnRows(sqlmock.NewRows([]string{"email"}))
+				mock.ExpectRollback()
+				return NewDB(conn)
+			},
+			expectedErr: ErrUserNotFound,
+		},
+		{
+			name: "anonymize failed",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT email FROM identity_users`).
+					WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("jane@example.com"))
+				mock.ExpectExec(`UPDATE identity_users SET first_name = ''`).WillReturnError(errors.New("update error"))
+				mock.ExpectRollback()
+				return NewDB(conn)
+			},
+			expectedErr: errors.New("update error"),
+		},
+		{
+			name: "outbox events not removed",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT email FROM identity_users`).
+					WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("jane@example.com"))
+				mock.ExpectExec(`UPDATE identity_users SET first_name = ''`).WillReturnResult(sqlmock.NewResult(0, 1))
+				for _, table := range []string{"login_tokens", "email_changes", "login_attempts", "webhook_deliveries"} {
+					mock.ExpectExec(`DELETE FROM ` + table).WillReturnResult(sqlmock.NewResult(0, 0))
+				}
+				mock.ExpectExec(`DELETE FROM outbox_events WHERE aggregate_id = \?`).
+					WithArgs("user-1").
+					WillReturnError(errors.New("delete error"))
+				mock.ExpectRollback()
+				return NewDB(conn)
+			},
+			expectedErr: errors.New("delete error"),
+		},
+		{
+			name: "erased",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				anonymous := "erased+user-1@erased.invalid"
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT email FROM identity_users`).
+					WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("jane@example.com"))
+				mock.ExpectExec(`UPDATE identity_users SET first_name = '', last_name = '', email = \?, password = ''.*`+
+					`deleted_at = COALESCE\(deleted_at, \?\), erased_at = \? WHERE id = \?`).
+					WithArgs(anonymous, sqlmock.AnyArg(), sqlmock.AnyArg(), "user-1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`DELETE FROM login_tokens WHERE user_id = \?`).
+					WithArgs("user-1").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(`DELETE FROM email_changes WHERE user_id = \?`).
+					WithArgs("user-1").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(`DELETE FROM login_attempts WHERE user_id = \?`).
+					WithArgs("user-1").
+					WillReturnResult(sqlmock.NewResult(0, 3))
+				mock.ExpectExec(`DELETE FROM webhook_deliveries WHERE user_id = \?`).
+					WithArgs("user-1").
+					WillReturnResult(sqlmock.NewResult(0, 2))
+				mock.ExpectExec(`DELETE FROM outbox_events WHERE aggregate_id = \?`).
+					WithArgs("user-1").
+					WillReturnResult(sqlmock.NewResult(0, 4))
+				mock.ExpectExec(`UPDATE invitations SET email = \? WHERE email = \?`).
+					WithArgs(anonymous, "jane@example.com").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(`INSERT INTO erasures \(id, user_id, erased_by, erased_at\)`).
+					WithArgs(sqlmock.AnyArg(), "user-1", "admin-1", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectAudit(mock, AuditUserErased, "user-1")
+				mock.ExpectCommit()
+				return NewDB(conn)
+			},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			erasure, err := sc.db().EraseUser(context.Background(), "user-1", "admin-1")
+			assert.Equal(t, sc.expectedErr, err)
+			if sc.expectedErr != nil {
+				return
+			}
+			assert.NotEmpty(t, erasure.ID)
+			assert.Equal(t, "user-1", erasure.UserID)
+			assert.Equal(t, "admin-1", erasure.ErasedBy)
+		})
+	}
+}
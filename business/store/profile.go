@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+var errEmptyProfile = errors.New("empty profile update")
+
+// ProfileUpdate has the profile details a user can change, fields left nil
+// are not updated.
+type ProfileUpdate struct {
+	FirstName  *string `json:"first_name,omitempty"`
+	LastName   *string `json:"last_name,omitempty"`
+	Company    *string `json:"company,omitempty"`
+	PostCode   *string `json:"post_code,omitempty"`
+	Locale     *string `json:"locale,omitempty"`
+	Timezone   *string `json:"timezone,omitempty"`
+	PictureURL *string `json:"picture_url,omitempty"`
+}
+
+// columns returns the assignments for the fields that are set and their values.
+func (p *ProfileUpdate) columns() ([]string, []any) {
+	fields := []struct {
+		column string
+		value  *string
+	}{
+		{"first_name", p.FirstName},
+		{"last_name", p.LastName},
+		{"company", p.Company},
+		{"post_code", p.PostCode},
+		{"locale", p.Locale},
+		{"timezone", p.Timezone},
+		{"picture_url", p.PictureURL},
+	}
+
+	var (
+		set  []string
+		args []any
+	)
+	for _, f := range fields {
+		if f.value == nil {
+			continue
+		}
+		set = append(set, f.column+" = ?")
+		args = append(args, *f.value)
+	}
+	return set, args
+}
+
+// UpdateProfile changes the profile details of a user and returns the updated user.
+func (m *MYSQL) UpdateProfile(ctx context.Context, id string, p *ProfileUpdate) (*User, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+	if p == nil {
+		return nil, errEmptyProfile
+	}
+
+	set, args := p.columns()
+	if len(set) == 0 {
+		return nil, errEmptyProfile
+	}
+
+	// the user is looked up first as MySQL reports no affected rows when
+	// the values are unchanged.
+	user, err := m.Retrieve(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	scope, scopeArgs := tenantUsers(ctx)
+	args = append(append(args, id), scopeArgs...)
+	_, err = m.Conn.ExecContext(ctx,
+		`UPDATE identity_users SET `+strings.Join(set, ", ")+` WHERE id = ? AND `+notDeleted+` AND `+scope, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.Retrieve(ctx, id)
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func profileRows(locale string) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{
+		"first_name", "last_name", "email", "company", "post_code", "created_by", "active",
+		"created_at", "updated_at", "roles", "locale", "timezone", "picture_url",
+	}).AddRow("Jane", "Doe", "jane@example.com", "", "", "", true,
+		"2024-01-01", "2024-01-01", "user", locale, "Europe/London", "")
+}
+
+func TestDB_UpdateProfile(t *testing.T) {
+	locale := "en-GB"
+	scenarios := []struct {
+		name        string
+		db          func() (*MYSQL, sqlmock.Sqlmock)
+		update      *ProfileUpdate
+		expected    string
+		expectedErr error
+	}{
+		{
+			name: "empty connection",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				_, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				return &MYSQL{}, mock
+			},
+			update:      &ProfileUpdate{Locale: &locale},
+			expectedErr: errEmptyDBConnection,
+		},
+		{
+			name: "nothing to update",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				return NewDB(conn), mock
+			},
+			update:      &ProfileUpdate{},
+			expectedErr: errEmptyProfile,
+		},
+		{
+			name: "user outside the tenant",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectPrepare(regexp.QuoteMeta(RetrieveQuery)).
+					ExpectQuery().
+					WithArgs("org-1", "org-1", "user-1", "org-1").
+					WillReturnError(sql.ErrNoRows)
+				return NewDB(conn), mock
+			},
+			update:      &ProfileUpdate{Locale: &locale},
+			expectedErr: ErrUserNotFound,
+		},
+		{
+			name: "update failed",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectPrepare(regexp.QuoteMeta(RetrieveQuery)).
+					ExpectQuery().
+					WillReturnRows(profileRows(""))
+				mock.ExpectExec(`UPDATE identity_users SET locale = \? WHERE id = \?`).
+					WillReturnError(errors.New("update error"))
+				return NewDB(conn), mock
+			},
+			update:      &ProfileUpdate{Locale: &locale},
+			expectedErr: errors.New("update error"),
+		},
+		{
+			name: "success",
+			db: func() (*MYSQL, sqlmock.Sqlmock) {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectPrepare(regexp.QuoteMeta(RetrieveQuery)).
+					ExpectQuery().
+					WillReturnRows(profileRows(""))
+				mock.ExpectExec(`UPDATE identity_users SET locale = \? WHERE id = \? AND identity_users.deleted_at IS NULL AND identity_users.id IN`).
+					WithArgs("en-GB", "user-1", "org-1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectPrepare(regexp.QuoteMeta(RetrieveQuery)).
+					ExpectQuery().
+					WillReturnRows(profileRows("en-GB"))
+				return NewDB(conn), mock
+			},
+			update:   &ProfileUpdate{Locale: &locale},
+			expected: "en-GB",
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			db, mock := sc.db()
+			ctx := WithTenant(context.Background(), testTenant)
+			user, err := db.UpdateProfile(ctx, "user-1", sc.update)
+			assert.Equal(t, sc.expectedErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+			if sc.expectedErr != nil {
+				return
+			}
+			assert.Equal(t, sc.expected, user.Locale)
+			assert.Equal(t, "Europe/London", user.Timezone)
+		})
+	}
+}
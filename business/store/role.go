@@ -0,0 +1,622 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/riyadennis/identity-server/business/events"
+)
+
+// DefaultRole is assigned to every user that is inserted without roles.
+const DefaultRole = "user"
+
+// OwnerRole is assigned to the user that creates an organization.
+const OwnerRole = "admin"
+
+// mysqlErrNoReferencedRow is returned by mysql when a foreign key points to a missing row.
+const mysqlErrNoReferencedRow = 1452
+
+// mysqlErrDuplicateEntry is returned by mysql when a row breaks a unique index.
+const mysqlErrDuplicateEntry = 1062
+
+var (
+	// ErrUserNotFound is returned when an operation targets a user that does not exist.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrRoleNotFound is returned when an operation targets a role that does not exist.
+	ErrRoleNotFound = errors.New("role not found")
+	// ErrPermissionNotFound is returned when a role refers to an unknown permission.
+	ErrPermissionNotFound = errors.New("permission not found")
+	// ErrBuiltInRole is returned when trying to change or remove a built-in role.
+	ErrBuiltInRole = errors.New("built-in roles cannot be modified")
+	// ErrPlatformRole is returned when a tenant tries to grant or revoke a platform role.
+	ErrPlatformRole = errors.New("platform roles can only be managed by platform administrators")
+
+	errEmptyRole = errors.New("empty role")
+)
+
+// RoleStore manages roles, the permissions they grant and their assignment to users.
+type RoleStore interface {
+	UserRoles(ctx context.Context, userID string) ([]string, error)
+	UserPermissions(ctx context.Context, userID string) ([]string, error)
+	AssignRole(ctx context.Context, userID, role string) error
+	RevokeRole(ctx context.Context, userID, role string) error
+	ReplaceRole(ctx context.Context, userID, role, replaced string) error
+	ListRoles(ctx context.Context) ([]*Role, error)
+	RetrieveRole(ctx context.Context, name string) (*Role, error)
+	CreateRole(ctx context.Context, r *Role) (*Role, error)
+	SetRolePermissions(ctx context.Context, role string, permissions []string) error
+	DeleteRole(ctx context.Context, name string) error
+	ListPermissions(ctx context.Context) ([]*Permission, error)
+}
+
+// Role is a named set of permissions that can be assigned to users.
+type Role struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	BuiltIn     bool     `json:"built_in"`
+	Platform    bool     `json:"platform"`
+	Permissions []string `json:"permissions"`
+	CreatedAt   string   `json:"created_at"`
+	UpdatedAt   string   `json:"updated_at"`
+}
+
+// Permission is a single action that can be granted through a role.
+type Permission struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+var userRolesQuery = `SELECT DISTINCT r.name FROM user_roles ur
+JOIN roles r ON r.id = ur.role_id
+JOIN identity_users ON identity_users.id = ur.user_id
+WHERE ur.user_id = ? AND ` + notDeleted + ` AND ` + tenantRoles + ` ORDER BY r.name`
+
+// UserRoles returns the names of the roles a user has in the tenant's
+// organization along with their platform roles, deleted users have none.
+func (m *MYSQL) UserRoles(ctx context.Context, userID string) ([]string, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	return m.names(ctx, userRolesQuery, append([]any{userID}, tenantRoleArgs(ctx)...)...)
+}
+
+var userPermissionsQuery = `SELECT DISTINCT p.name FROM user_roles ur
+JOIN role_permissions rp ON rp.role_id = ur.role_id
+JOIN permissions p ON p.id = rp.permission_id
+JOIN identity_users ON identity_users.id = ur.user_id
+WHERE ur.user_id = ? AND ` + notDeleted + ` AND ` + tenantRoles + ` ORDER BY p.name`
+
+// UserPermissions returns every permission granted to a user through the
+// roles returned by UserRoles.
+func (m *MYSQL) UserPermissions(ctx context.Context, userID string) ([]string, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	return m.names(ctx, userPermissionsQuery, append([]any{userID}, tenantRoleArgs(ctx)...)...)
+}
+
+// AssignRole grants a role to a member of the tenant's organization, assigning
+// a role the user already has is a no-op. Platform roles apply to every
+// organization and can only be granted by platform administrators.
+func (m *MYSQL) AssignRole(ctx context.Context, userID, role string) error {
+	if m.Conn == nil {
+		return errEmptyDBConnection
+	}
+	if role == "" {
+		return errEmptyRole
+	}
+
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := grantRole(ctx, tx, userID, role); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ReplaceRole grants role to a member of the tenant's organization and takes
+// replaced away from them in the same transaction, so the user never has
+// both or neither. The user not having replaced is not an error.
+func (m *MYSQL) ReplaceRole(ctx context.Context, userID, role, replaced string) error {
+	if m.Conn == nil {
+		return errEmptyDBConnection
+	}
+	if role == "" || replaced == "" {
+		return errEmptyRole
+	}
+
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := grantRole(ctx, tx, userID, role); err != nil {
+		return err
+	}
+	if _, err := revokeRole(ctx, tx, userID, replaced); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// grantRole is AssignRole within the transaction of db.
+func grantRole(ctx context.Context, db execer, userID, role string) error {
+	t, _ := TenantFromContext(ctx)
+	if !t.Platform {
+		member, err := isMember(ctx, db, t.OrganizationID, userID)
+		if err != nil {
+			return err
+		}
+		if !member {
+			return ErrUserNotFound
+		}
+	}
+
+	orgID, assigned, err := assignRole(ctx, db, userID, role, t.OrganizationID)
+	if err != nil {
+		return err
+	}
+	if !assigned {
+		return nil
+	}
+
+	err = recordAudit(ctx, db, &AuditEvent{
+		Action:     AuditRoleAssigned,
+		TargetType: AuditTargetUser,
+		TargetID:   userID,
+		After:      auditValue(map[string]any{"role": role}),
+	})
+	if err != nil {
+		return err
+	}
+
+	return enqueueEvent(ctx, db, events.RoleAssigned{UserID: userID, Role: role, OrganizationID: orgID})
+}
+
+// assignRole grants role to the user within orgID, platform roles are
+// granted outside any organization. Platform roles are refused when the
+// context carries a tenant that is not a platform administrator. It returns
+// the organization the role was granted in and reports whether the user did
+// not have the role before.
+func assignRole(ctx context.Context, db execer, userID, role, orgID string) (string, bool, error) {
+	if role == "" {
+		return "", false, errEmptyRole
+	}
+
+	roleID, platform, err := lookupRole(ctx, db, role)
+	if err != nil {
+		return "", false, err
+	}
+	if platform {
+		if t, ok := TenantFromContext(ctx); ok && !t.Platform {
+			return "", false, ErrPlatformRole
+		}
+		orgID = ""
+	}
+
+	result, err := db.ExecContext(ctx,
+		`INSERT IGNORE INTO user_roles (user_id, role_id, organization_id) VALUES (?, ?, ?)`,
+		userID, roleID, orgID)
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrNoReferencedRow {
+			return "", false, ErrUserNotFound
+		}
+		return "", false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return "", false, err
+	}
+
+	return orgID, rows > 0, nil
+}
+
+// RevokeRole removes a role the user has in the tenant's organization.
+func (m *MYSQL) RevokeRole(ctx context.Context, userID, role string) error {
+	if m.Conn == nil {
+		return errEmptyDBConnection
+	}
+
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	revoked, err := revokeRole(ctx, tx, userID, role)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrRoleNotFound
+	}
+
+	return tx.Commit()
+}
+
+// revokeRole removes role from the user within the tenant's organization, or
+// outside any organization for platform roles, and reports whether the user
+// had it.
+func revokeRole(ctx context.Context, db execer, userID, role string) (bool, error) {
+	roleID, platform, err := lookupRole(ctx, db, role)
+	if err != nil {
+		return false, err
+	}
+	t, _ := TenantFromContext(ctx)
+	orgID := t.OrganizationID
+	if platform {
+		if !t.Platform {
+			return false, ErrPlatformRole
+		}
+		orgID = ""
+	}
+
+	result, err := db.ExecContext(ctx,
+		`DELETE FROM user_roles WHERE user_id = ? AND role_id = ? AND organization_id = ?`,
+		userID, roleID, orgID)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	err = recordAudit(ctx, db, &AuditEvent{
+		Action:     AuditRoleRevoked,
+		TargetType: AuditTargetUser,
+		TargetID:   userID,
+		Before:     auditValue(map[string]any{"role": role}),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+var listRolesQuery = `SELECT r.id, r.name, r.description, r.built_in, r.platform, r.created_at, r.updated_at,
+       COALESCE(GROUP_CONCAT(p.name ORDER BY p.name), '') AS permissions
+FROM roles r
+LEFT JOIN role_permissions rp ON rp.role_id = r.id
+LEFT JOIN permissions p ON p.id = rp.permission_id`
+
+// ListRoles returns all roles along with the permissions they grant.
+func (m *MYSQL) ListRoles(ctx context.Context) ([]*Role, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	rows, err := m.Conn.QueryContext(ctx, listRolesQuery+` GROUP BY r.id ORDER BY r.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []*Role
+	for rows.Next() {
+		r, err := scanRole(rows)
+		if err != nil {
+			return nil, err
+		}
+		roles = append(roles, r)
+	}
+
+	return roles, rows.Err()
+}
+
+// RetrieveRole fetches a role by name, it will return ErrRoleNotFound if there is none.
+func (m *MYSQL) RetrieveRole(ctx context.Context, name string) (*Role, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	rows, err := m.Conn.QueryContext(ctx, listRolesQuery+` WHERE r.name = ? GROUP BY r.id`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, ErrRoleNotFound
+	}
+
+	return scanRole(rows)
+}
+
+func scanRole(rows *sql.Rows) (*Role, error) {
+	r := &Role{}
+	var permissions string
+	err := rows.Scan(&r.ID, &r.Name, &r.Description, &r.BuiltIn, &r.Platform,
+		&r.CreatedAt, &r.UpdatedAt, &permissions)
+	if err != nil {
+		return nil, err
+	}
+	r.Permissions = splitNames(permissions)
+
+	return r, nil
+}
+
+// CreateRole adds a custom role with its permissions. Roles are shared by
+// every organization so only platform administrators can define them.
+func (m *MYSQL) CreateRole(ctx context.Context, r *Role) (*Role, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+	if !platformTenant(ctx) {
+		return nil, ErrPlatformOnly
+	}
+	if r == nil || r.Name == "" {
+		return nil, errEmptyRole
+	}
+
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	id := uuid.New().String()
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO roles (id, name, description, built_in) VALUES (?, ?, ?, FALSE)`,
+		id, r.Name, r.Description)
+	if err != nil {
+		logrus.Errorf("failed to insert role: %v", err)
+		return nil, err
+	}
+
+	if err := grantPermissions(ctx, tx, id, r.Permissions); err != nil {
+		return nil, err
+	}
+	err = recordAudit(ctx, tx, &AuditEvent{
+		Action:     AuditRoleCreated,
+		TargetType: AuditTargetRole,
+		TargetID:   id,
+		After:      auditValue(map[string]any{"name": r.Name, "permissions": sortedNames(r.Permissions)}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return m.RetrieveRole(ctx, r.Name)
+}
+
+// SetRolePermissions replaces the permissions granted by a custom role.
+func (m *MYSQL) SetRolePermissions(ctx context.Context, role string, permissions []string) error {
+	if m.Conn == nil {
+		return errEmptyDBConnection
+	}
+	if !platformTenant(ctx) {
+		return ErrPlatformOnly
+	}
+
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	id, builtIn, err := customRole(ctx, tx, role)
+	if err != nil {
+		return err
+	}
+	if builtIn {
+		return ErrBuiltInRole
+	}
+
+	var before string
+	err = tx.QueryRowContext(ctx, `SELECT COALESCE(GROUP_CONCAT(p.name ORDER BY p.name), '')
+	FROM role_permissions rp JOIN permissions p ON p.id = rp.permission_id WHERE rp.role_id = ?`, id).Scan(&before)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM role_permissions WHERE role_id = ?`, id)
+	if err != nil {
+		return err
+	}
+
+	if err := grantPermissions(ctx, tx, id, permissions); err != nil {
+		return err
+	}
+	err = recordAudit(ctx, tx, &AuditEvent{
+		Action:     AuditRolePermissionsSet,
+		TargetType: AuditTargetRole,
+		TargetID:   id,
+		Before:     auditValue(map[string]any{"name": role, "permissions": splitNames(before)}),
+		After:      auditValue(map[string]any{"name": role, "permissions": sortedNames(permissions)}),
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteRole removes a custom role, users lose the role along with it.
+func (m *MYSQL) DeleteRole(ctx context.Context, name string) error {
+	if m.Conn == nil {
+		return errEmptyDBConnection
+	}
+	if !platformTenant(ctx) {
+		return ErrPlatformOnly
+	}
+
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	id, builtIn, err := customRole(ctx, tx, name)
+	if err != nil {
+		return err
+	}
+	if builtIn {
+		return ErrBuiltInRole
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM roles WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	err = recordAudit(ctx, tx, &AuditEvent{
+		Action:     AuditRoleDeleted,
+		TargetType: AuditTargetRole,
+		TargetID:   id,
+		Before:     auditValue(map[string]any{"name": name}),
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ListPermissions returns every permission that can be granted by a role.
+func (m *MYSQL) ListPermissions(ctx context.Context) ([]*Permission, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	rows, err := m.Conn.QueryContext(ctx,
+		`SELECT id, name, description FROM permissions ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var permissions []*Permission
+	for rows.Next() {
+		p := &Permission{}
+		if err := rows.Scan(&p.ID, &p.Name, &p.Description); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, p)
+	}
+
+	return permissions, rows.Err()
+}
+
+func (m *MYSQL) names(ctx context.Context, query string, args ...any) ([]string, error) {
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+func lookupRole(ctx context.Context, db execer, name string) (string, bool, error) {
+	var (
+		id       string
+		platform bool
+	)
+	err := db.QueryRowContext(ctx,
+		`SELECT id, platform FROM roles WHERE name = ?`, name).Scan(&id, &platform)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, ErrRoleNotFound
+		}
+		return "", false, err
+	}
+
+	return id, platform, nil
+}
+
+func customRole(ctx context.Context, db execer, name string) (string, bool, error) {
+	var (
+		id      string
+		builtIn bool
+	)
+	err := db.QueryRowContext(ctx,
+		`SELECT id, built_in FROM roles WHERE name = ?`, name).Scan(&id, &builtIn)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, ErrRoleNotFound
+		}
+		return "", false, err
+	}
+
+	return id, builtIn, nil
+}
+
+// sortedNames returns names in order without duplicates.
+func sortedNames(names []string) []string {
+	sorted := slices.Clone(names)
+	slices.Sort(sorted)
+	return slices.Compact(sorted)
+}
+
+func grantPermissions(ctx context.Context, db execer, roleID string, permissions []string) error {
+	seen := make(map[string]bool, len(permissions))
+	for _, name := range permissions {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		result, err := db.ExecContext(ctx,
+			`INSERT IGNORE INTO role_permissions (role_id, permission_id)
+			 SELECT ?, id FROM permissions WHERE name = ?`, roleID, name)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			logrus.Errorf("unknown permission %s", name)
+			return ErrPermissionNotFound
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,580 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadennis/identity-server/business/events"
+)
+
+var testTenant = Tenant{OrganizationID: "org-1"}
+
+func TestDB_UserPermissions(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		db            *MYSQL
+		tenant        *Tenant
+		expectedPerms []string
+		expectedErr   error
+	}{
+		{
+			name:        "empty connection",
+			db:          &MYSQL{},
+			expectedErr: errEmptyDBConnection,
+		},
+		{
+			name: "query failed",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(regexp.QuoteMeta(userPermissionsQuery)).
+					WillReturnError(errors.New("query error"))
+				return NewDB(conn)
+			}(),
+			expectedErr: errors.New("query error"),
+		},
+		{
+			name: "no roles",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(regexp.QuoteMeta(userPermissionsQuery)).
+					WithArgs("user-123", "", "").
+					WillReturnRows(sqlmock.NewRows([]string{"name"}))
+				return NewDB(conn)
+			}(),
+			expectedPerms: []string{},
+		},
+		{
+			name: "scoped to tenant",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(regexp.QuoteMeta(userPermissionsQuery)).
+					WithArgs("user-123", "org-1", "org-1").
+					WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("users:read"))
+				return NewDB(conn)
+			}(),
+			tenant:        &testTenant,
+			expectedPerms: []string{"users:read"},
+		},
+		{
+			name: "success",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(regexp.QuoteMeta(userPermissionsQuery)).
+					WithArgs("user-123", "", "").
+					WillReturnRows(sqlmock.NewRows([]string{"name"}).
+						AddRow("users:delete").AddRow("users:read"))
+				return NewDB(conn)
+			}(),
+			expectedPerms: []string{"users:delete", "users:read"},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if sc.tenant != nil {
+				ctx = WithTenant(ctx, *sc.tenant)
+			}
+			perms, err := sc.db.UserPermissions(ctx, "user-123")
+			assert.Equal(t, sc.expectedErr, err)
+			assert.Equal(t, sc.expectedPerms, perms)
+		})
+	}
+}
+
+func TestRoleQueriesSkipDeletedUsers(t *testing.T) {
+	assert.Contains(t, userRolesQuery, notDeleted)
+	assert.Contains(t, userPermissionsQuery, notDeleted)
+}
+
+func TestDB_AssignRole(t *testing.T) {
+	membership := func(mock sqlmock.Sqlmock, count int) {
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM organization_members`).
+			WithArgs("org-1", "user-123").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+	}
+	scenarios := []struct {
+		name        string
+		db          *MYSQL
+		role        string
+		expectedErr error
+	}{
+		{
+			name:        "empty connection",
+			db:          &MYSQL{},
+			role:        "admin",
+			expectedErr: errEmptyDBConnection,
+		},
+		{
+			name:        "empty role",
+			db:          &MYSQL{Conn: &sql.DB{}},
+			expectedErr: errEmptyRole,
+		},
+		{
+			name: "user outside the organization",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				membership(mock, 0)
+				return NewDB(conn)
+			}(),
+			role:        "admin",
+			expectedErr: ErrUserNotFound,
+		},
+		{
+			name: "role not found",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				membership(mock, 1)
+				mock.ExpectQuery(`SELECT id, platform FROM roles WHERE name = ?`).
+					WithArgs("missing").
+					WillReturnError(sql.ErrNoRows)
+				return NewDB(conn)
+			}(),
+			role:        "missing",
+			expectedErr: ErrRoleNotFound,
+		},
+		{
+			name: "platform role",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				membership(mock, 1)
+				mock.ExpectQuery(`SELECT id, platform FROM roles WHERE name = ?`).
+					WithArgs("superadmin").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "platform"}).AddRow("role-super", true))
+				return NewDB(conn)
+			}(),
+			role:        "superadmin",
+			expectedErr: ErrPlatformRole,
+		},
+		{
+			name: "user not found",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				membership(mock, 1)
+				mock.ExpectQuery(`SELECT id, platform FROM roles WHERE name = ?`).
+					WithArgs("admin").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "platform"}).AddRow("role-admin", false))
+				mock.ExpectExec(`INSERT IGNORE INTO user_roles`).
+					WithArgs("user-123", "role-admin", "org-1").
+					WillReturnError(&mysql.MySQLError{Number: mysqlErrNoReferencedRow})
+				return NewDB(conn)
+			}(),
+			role:        "admin",
+			expectedErr: ErrUserNotFound,
+		},
+		{
+			name: "already assigned",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				membership(mock, 1)
+				mock.ExpectQuery(`SELECT id, platform FROM roles WHERE name = ?`).
+					WithArgs("admin").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "platform"}).AddRow("role-admin", false))
+				mock.ExpectExec(`INSERT IGNORE INTO user_roles`).
+					WithArgs("user-123", "role-admin", "org-1").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+				return NewDB(conn)
+			}(),
+			role: "admin",
+		},
+		{
+			name: "success",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				membership(mock, 1)
+				mock.ExpectQuery(`SELECT id, platform FROM roles WHERE name = ?`).
+					WithArgs("admin").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "platform"}).AddRow("role-admin", false))
+				mock.ExpectExec(`INSERT IGNORE INTO user_roles`).
+					WithArgs("user-123", "role-admin", "org-1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectAudit(mock, AuditRoleAssigned, "user-123")
+				expectEvent(mock, events.TypeRoleAssigned, "user-123")
+				mock.ExpectCommit()
+				return NewDB(conn)
+			}(),
+			role: "admin",
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			ctx := WithTenant(context.Background(), testTenant)
+			err := sc.db.AssignRole(ctx, "user-123", sc.role)
+			assert.Equal(t, sc.expectedErr, err)
+		})
+	}
+}
+
+func TestDB_RevokeRole(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		db          *MYSQL
+		tenant      Tenant
+		expectedErr error
+	}{
+		{
+			name: "role not assigned",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT id, platform FROM roles WHERE name = ?`).
+					WithArgs("admin").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "platform"}).AddRow("role-admin", false))
+				mock.ExpectExec(`DELETE FROM user_roles`).
+					WithArgs("user-123", "role-admin", "org-1").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				return NewDB(conn)
+			}(),
+			tenant:      testTenant,
+			expectedErr: ErrRoleNotFound,
+		},
+		{
+			name: "platform role revoked by a tenant",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT id, platform FROM roles WHERE name = ?`).
+					WithArgs("admin").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "platform"}).AddRow("role-admin", true))
+				return NewDB(conn)
+			}(),
+			tenant:      testTenant,
+			expectedErr: ErrPlatformRole,
+		},
+		{
+			name: "platform role revoked by a superadmin",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT id, platform FROM roles WHERE name = ?`).
+					WithArgs("admin").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "platform"}).AddRow("role-admin", true))
+				mock.ExpectExec(`DELETE FROM user_roles`).
+					WithArgs("user-123", "role-admin", "").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectAudit(mock, AuditRoleRevoked, "user-123")
+				mock.ExpectCommit()
+				return NewDB(conn)
+			}(),
+			tenant: Tenant{OrganizationID: "org-1", Platform: true},
+		},
+		{
+			name: "success",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT id, platform FROM roles WHERE name = ?`).
+					WithArgs("admin").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "platform"}).AddRow("role-admin", false))
+				mock.ExpectExec(`DELETE FROM user_roles`).
+					WithArgs("user-123", "role-admin", "org-1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectAudit(mock, AuditRoleRevoked, "user-123")
+				mock.ExpectCommit()
+				return NewDB(conn)
+			}(),
+			tenant: testTenant,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			err := sc.db.RevokeRole(WithTenant(context.Background(), sc.tenant), "user-123", "admin")
+			assert.Equal(t, sc.expectedErr, err)
+		})
+	}
+}
+
+func TestDB_ReplaceRole(t *testing.T) {
+	grant := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM organization_members`).
+			WithArgs("org-1", "user-123").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(`SELECT id, platform FROM roles WHERE name = ?`).
+			WithArgs("user").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "platform"}).AddRow("role-user", false))
+		mock.ExpectExec(`INSERT IGNORE INTO user_roles`).
+			WithArgs("user-123", "role-user", "org-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectAudit(mock, AuditRoleAssigned, "user-123")
+		expectEvent(mock, events.TypeRoleAssigned, "user-123")
+		mock.ExpectQuery(`SELECT id, platform FROM roles WHERE name = ?`).
+			WithArgs("admin").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "platform"}).AddRow("role-admin", false))
+	}
+	scenarios := []struct {
+		name        string
+		expect      func(mock sqlmock.Sqlmock)
+		expectedErr error
+	}{
+		{
+			name: "revoke fails",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				grant(mock)
+				mock.ExpectExec(`DELETE FROM user_roles`).
+					WithArgs("user-123", "role-admin", "org-1").
+					WillReturnError(errors.New("delete error"))
+				mock.ExpectRollback()
+			},
+			expectedErr: errors.New("delete error"),
+		},
+		{
+			name: "replaced role not assigned",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				grant(mock)
+				mock.ExpectExec(`DELETE FROM user_roles`).
+					WithArgs("user-123", "role-admin", "org-1").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "replaced",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				grant(mock)
+				mock.ExpectExec(`DELETE FROM user_roles`).
+					WithArgs("user-123", "role-admin", "org-1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectAudit(mock, AuditRoleRevoked, "user-123")
+				mock.ExpectCommit()
+			},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			assert.NoError(t, err)
+			sc.expect(mock)
+
+			err = NewDB(conn).ReplaceRole(WithTenant(context.Background(), testTenant), "user-123", "user", "admin")
+			assert.Equal(t, sc.expectedErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDB_ListRoles(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectQuery(`SELECT r.id, r.name`).
+		WillReturnRows(sqlmock.NewRows(
+			[]string{"id", "name", "description", "built_in", "platform", "created_at", "updated_at", "permissions"}).
+			AddRow("1", "admin", "all access", true, false, "2024-01-01", "2024-01-01", "roles:read,users:read").
+			AddRow("2", "support", "", false, false, "2024-01-01", "2024-01-01", ""))
+
+	roles, err := NewDB(conn).ListRoles(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []*Role{
+		{
+			ID: "1", Name: "admin", Description: "all access", BuiltIn: true,
+			Permissions: []string{"roles:read", "users:read"}, CreatedAt: "2024-01-01", UpdatedAt: "2024-01-01",
+		},
+		{
+			ID: "2", Name: "support", Permissions: []string{},
+			CreatedAt: "2024-01-01", UpdatedAt: "2024-01-01",
+		},
+	}, roles)
+}
+
+func TestDB_CreateRole(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		db          *MYSQL
+		tenant      *Tenant
+		role        *Role
+		expectedErr error
+	}{
+		{
+			name:        "tenant",
+			db:          &MYSQL{Conn: &sql.DB{}},
+			tenant:      &testTenant,
+			role:        &Role{Name: "support"},
+			expectedErr: ErrPlatformOnly,
+		},
+		{
+			name:        "empty role",
+			db:          &MYSQL{Conn: &sql.DB{}},
+			role:        &Role{},
+			expectedErr: errEmptyRole,
+		},
+		{
+			name: "unknown permission",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO roles`).
+					WithArgs(sqlmock.AnyArg(), "support", "helpdesk").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(`INSERT IGNORE INTO role_permissions`).
+					WithArgs(sqlmock.AnyArg(), "users:fly").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+				return NewDB(conn)
+			}(),
+			role:        &Role{Name: "support", Description: "helpdesk", Permissions: []string{"users:fly"}},
+			expectedErr: ErrPermissionNotFound,
+		},
+		{
+			name: "success",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO roles`).
+					WithArgs(sqlmock.AnyArg(), "support", "helpdesk").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(`INSERT IGNORE INTO role_permissions`).
+					WithArgs(sqlmock.AnyArg(), "users:read").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(`INSERT INTO audit_events`).
+					WithArgs(sqlmock.AnyArg(), "", "", AuditRoleCreated, AuditTargetRole, sqlmock.AnyArg(),
+						nil, `{"name":"support","permissions":["users:read"]}`, "", "", "", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectQuery(`SELECT r.id, r.name`).
+					WithArgs("support").
+					WillReturnRows(sqlmock.NewRows(
+						[]string{"id", "name", "description", "built_in", "platform", "created_at", "updated_at", "permissions"}).
+						AddRow("2", "support", "helpdesk", false, false, "2024-01-01", "2024-01-01", "users:read"))
+				return NewDB(conn)
+			}(),
+			role: &Role{Name: "support", Description: "helpdesk", Permissions: []string{"users:read", "users:read"}},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if sc.tenant != nil {
+				ctx = WithTenant(ctx, *sc.tenant)
+			}
+			created, err := sc.db.CreateRole(ctx, sc.role)
+			assert.Equal(t, sc.expectedErr, err)
+			if err == nil {
+				assert.Equal(t, sc.role.Name, created.Name)
+				assert.Equal(t, []string{"users:read"}, created.Permissions)
+			}
+		})
+	}
+}
+
+func TestDB_DeleteRole(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		db          *MYSQL
+		expectedErr error
+	}{
+		{
+			name: "role not found",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT id, built_in FROM roles`).
+					WithArgs("support").
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+				return NewDB(conn)
+			}(),
+			expectedErr: ErrRoleNotFound,
+		},
+		{
+			name: "built-in role",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT id, built_in FROM roles`).
+					WithArgs("support").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "built_in"}).AddRow("1", true))
+				mock.ExpectRollback()
+				return NewDB(conn)
+			}(),
+			expectedErr: ErrBuiltInRole,
+		},
+		{
+			name: "success",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT id, built_in FROM roles`).
+					WithArgs("support").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "built_in"}).AddRow("2", false))
+				mock.ExpectExec(`DELETE FROM roles WHERE id = ?`).
+					WithArgs("2").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectAudit(mock, AuditRoleDeleted, "2")
+				mock.ExpectCommit()
+				return NewDB(conn)
+			}(),
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			err := sc.db.DeleteRole(context.Background(), "support")
+			assert.Equal(t, sc.expectedErr, err)
+		})
+	}
+}
+
+func TestDB_SetRolePermissions(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, built_in FROM roles`).
+		WithArgs("support").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "built_in"}).AddRow("2", false))
+	mock.ExpectQuery(`SELECT COALESCE\(GROUP_CONCAT\(p.name ORDER BY p.name\), ''\)\s+FROM role_permissions rp`).
+		WithArgs("2").
+		WillReturnRows(sqlmock.NewRows([]string{"permissions"}).AddRow("users:read"))
+	mock.ExpectExec(`DELETE FROM role_permissions WHERE role_id = \?`).
+		WithArgs("2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT IGNORE INTO role_permissions`).
+		WithArgs("2", "users:write").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT IGNORE INTO role_permissions`).
+		WithArgs("2", "users:read").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO audit_events`).
+		WithArgs(sqlmock.AnyArg(), "", "admin-1", AuditRolePermissionsSet, AuditTargetRole, "2",
+			`{"name":"support","permissions":["users:read"]}`,
+			`{"name":"support","permissions":["users:read","users:write"]}`, "", "", "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	ctx := WithActor(context.Background(), Actor{UserID: "admin-1"})
+	err = NewDB(conn).SetRolePermissions(ctx, "support", []string{"users:write", "users:read"})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
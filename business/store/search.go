@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// maxSearchTerms is the most words of a search that are matched.
+const maxSearchTerms = 8
+
+// searchColumns are the columns of the identity_users_search FULLTEXT index.
+const searchColumns = `first_name, last_name, email, company`
+
+// ErrEmptySearch is returned when a search has no words to match.
+var ErrEmptySearch = errors.New("search needs at least one word")
+
+// stopwords are the default InnoDB full-text stopwords, a required stopword
+// would never match so they are left out of searches.
+var stopwords = map[string]struct{}{
+	"a": {}, "about": {}, "an": {}, "are": {}, "as": {}, "at": {}, "be": {}, "by": {}, "com": {},
+	"de": {}, "en": {}, "for": {}, "from": {}, "how": {}, "i": {}, "in": {}, "is": {}, "it": {},
+	"la": {}, "of": {}, "on": {}, "or": {}, "that": {}, "the": {}, "this": {}, "to": {}, "was": {},
+	"what": {}, "when": {}, "where": {}, "who": {}, "will": {}, "with": {}, "und": {}, "www": {},
+}
+
+// SearchOptions is the text and page of a SearchUsers call.
+type SearchOptions struct {
+	// Query is matched against the start of the words in the name, email
+	// and company of users, every word has to match.
+	Query string
+	// First is the size of the page, DefaultPageSize when 0 and never more than MaxPageSize.
+	First int
+	// After is the cursor of the result the page starts after.
+	After string
+}
+
+// searchCursor is the position of a result in a search, results are ranked
+// by relevance so they are paged through by offset.
+type searchCursor struct {
+	Offset int `json:"o"`
+}
+
+// SearchCursor returns the cursor of the nth result of a search counting
+// from 1, the results that come after it are read by passing it as
+// SearchOptions.After.
+func SearchCursor(n int) string {
+	b, _ := json.Marshal(searchCursor{Offset: n})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// SearchOffset returns the number of results up to and including the one
+// the cursor after points to.
+func SearchOffset(after string) (int, error) {
+	if after == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(after)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	c := &searchCursor{}
+	if err := json.Unmarshal(b, c); err != nil || c.Offset <= 0 {
+		return 0, ErrInvalidCursor
+	}
+
+	return c.Offset, nil
+}
+
+// SearchUsers returns a page of the users of the tenant whose name, email or
+// company has words starting with each word of the query, ranked by relevance.
+func (m *MYSQL) SearchUsers(ctx context.Context, opts SearchOptions) (*UserPage, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	terms := searchTerms(opts.Query)
+	if terms == "" {
+		return nil, ErrEmptySearch
+	}
+	offset, err := SearchOffset(opts.After)
+	if err != nil {
+		return nil, err
+	}
+	first := opts.First
+	if first <= 0 {
+		first = DefaultPageSize
+	}
+	first = min(first, MaxPageSize)
+
+	scope, scopeArgs := tenantUsers(ctx)
+	args := append(tenantRoleArgs(ctx), terms)
+	args = append(args, scopeArgs...)
+	// one more user than asked for is read to know if there is a next page.
+	args = append(args, terms, first+1, offset)
+	rows, err := m.Conn.QueryContext(ctx,
+		`SELECT id, first_name, last_name, email, company, post_code, created_by, active, `+rolesColumn+`, created_at, updated_at, `+
+			profileColumns+` FROM identity_users
+		 WHERE MATCH(`+searchColumns+`) AGAINST(? IN BOOLEAN MODE) AND `+notDeleted+` AND `+scope+`
+		 ORDER BY MATCH(`+searchColumns+`) AGAINST(? IN BOOLEAN MODE) DESC, id ASC LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users, err := scanUsers(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &UserPage{Users: users}
+	if len(users) > first {
+		page.Users = users[:first]
+		page.HasNextPage = true
+		page.NextCursor = SearchCursor(offset + first)
+	}
+	if page.Users == nil {
+		page.Users = []*User{}
+	}
+
+	return page, nil
+}
+
+// searchTerms converts a query into a boolean mode search that requires
+// every word as a prefix. Anything but letters and digits separates words so
+// users can not use the operators of the boolean mode.
+func searchTerms(query string) string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		if _, ok := stopwords[word]; ok {
+			continue
+		}
+		terms = append(terms, "+"+word+"*")
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+
+	return strings.Join(terms, " ")
+}
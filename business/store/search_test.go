@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDB_SearchUsers(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		db           func() *MYSQL
+		opts         SearchOptions
+		expectedIDs  []string
+		expectedNext string
+		expectedErr  error
+	}{
+		{
+			name:        "empty connection",
+			db:          func() *MYSQL { return &MYSQL{} },
+			expectedErr: errEmptyDBConnection,
+		},
+		{
+			name: "no words",
+			db: func() *MYSQL {
+				conn, _, err := sqlmock.New()
+				assert.NoError(t, err)
+				return NewDB(conn)
+			},
+			opts:        SearchOptions{Query: " +-*@ the "},
+			expectedErr: ErrEmptySearch,
+		},
+		{
+			name: "malformed cursor",
+			db: func() *MYSQL {
+				conn, _, err := sqlmock.New()
+				assert.NoError(t, err)
+				return NewDB(conn)
+			},
+			opts:        SearchOptions{Query: "john", After: "not a cursor"},
+			expectedErr: ErrInvalidCursor,
+		},
+		{
+			name: "query failed",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`SELECT id, first_name`).WillReturnError(errors.New("query error"))
+				return NewDB(conn)
+			},
+			opts:        SearchOptions{Query: "john"},
+			expectedErr: errors.New("query error"),
+		},
+		{
+			name: "ranked",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`WHERE MATCH\(first_name, last_name, email, company\) AGAINST\(\? IN BOOLEAN MODE\) AND identity_users.deleted_at IS NULL AND TRUE\s+`+
+					`ORDER BY MATCH\(first_name, last_name, email, company\) AGAINST\(\? IN BOOLEAN MODE\) DESC, id ASC LIMIT \? OFFSET \?`).
+					WithArgs("", "", "+john* +doe* +example*", "+john* +doe* +example*", DefaultPageSize+1, 0).
+					WillReturnRows(listRows("1", "2"))
+				return NewDB(conn)
+			},
+			opts:        SearchOptions{Query: "John.Doe@example.com"},
+			expectedIDs: []string{"1", "2"},
+		},
+		{
+			name: "more pages",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`LIMIT \? OFFSET \?`).
+					WithArgs("", "", "+acme*", "+acme*", 3, 4).
+					WillReturnRows(listRows("5", "6", "7"))
+				return NewDB(conn)
+			},
+			opts:         SearchOptions{Query: "acme", First: 2, After: SearchCursor(4)},
+			expectedIDs:  []string{"5", "6"},
+			expectedNext: SearchCursor(6),
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			page, err := sc.db().SearchUsers(context.Background(), sc.opts)
+			assert.Equal(t, sc.expectedErr, err)
+			if sc.expectedErr != nil {
+				return
+			}
+
+			ids := make([]string, 0, len(page.Users))
+			for _, u := range page.Users {
+				ids = append(ids, u.ID)
+			}
+			assert.Equal(t, sc.expectedIDs, ids)
+			assert.Equal(t, sc.expectedNext != "", page.HasNextPage)
+			assert.Equal(t, sc.expectedNext, page.NextCursor)
+		})
+	}
+}
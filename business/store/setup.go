@@ -12,7 +12,7 @@ func SetUpMYSQL(logger *logrus.Logger) (Store, Authenticator, error) {
 	if err != nil {
 		return nil, nil, err
 	}
-	err = Migrate(db, cfg.DB.Database, cfg.DB.MigrationPath)
+	err = migrateMYSQL(cfg.DB)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -22,3 +22,19 @@ func SetUpMYSQL(logger *logrus.Logger) (Store, Authenticator, error) {
 		Logger: logger,
 	}, nil
 }
+
+// migrateMYSQL runs the migrations on a connection of their own, the files
+// have several statements each and the connection of the application must
+// not allow that.
+func migrateMYSQL(dbCfg *DBConnection) error {
+	migrationCfg := *dbCfg
+	migrationCfg.MultiStatements = true
+
+	db, err := ConnectMYSQL(&migrationCfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return Migrate(db, dbCfg.Database, dbCfg.MigrationPath)
+}
@@ -4,41 +4,104 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/net/idna"
+
+	"github.com/riyadennis/identity-server/business/events"
 )
 
-var errEmptyUser = errors.New("empty user")
+var (
+	// ErrDuplicateEmail is returned when an email address belongs to another
+	// user or is reserved after being changed.
+	ErrDuplicateEmail = errors.New("email already exists")
+
+	errEmptyUser = errors.New("empty user")
+)
+
+// emailIndex is the unique index that keeps emails from being used twice.
+const emailIndex = "identity_users_email_unique"
+
+// NormalizeEmail returns the form emails are stored and looked up in, lower
+// case with internationalized domains in punycode. It does not validate the
+// email, so addresses registered before a rule was added can still log in.
+func NormalizeEmail(email string) string {
+	email = strings.TrimSpace(email)
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		if domain, err := idna.Lookup.ToASCII(email[at+1:]); err == nil {
+			email = email[:at+1] + domain
+		}
+	}
+	return strings.ToLower(email)
+}
+
+// duplicateEmail converts a violation of emailIndex into ErrDuplicateEmail.
+func duplicateEmail(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDuplicateEntry &&
+		strings.Contains(mysqlErr.Message, emailIndex) {
+		return ErrDuplicateEmail
+	}
+	return err
+}
 
-// Store have CRUD functions for user management.
+// Store have CRUD functions for user management. Queries are scoped to the
+// tenant set with WithTenant, users outside the tenant's organization are
+// treated as if they did not exist.
 type Store interface {
 	Insert(ctx context.Context, u *User) (*User, error)
 	Read(ctx context.Context, email string) (*User, error)
 	Retrieve(ctx context.Context, id string) (*User, error)
-	Delete(id string) (int64, error)
+	RetrieveMany(ctx context.Context, ids []string) ([]*User, error)
+	Delete(ctx context.Context, id string) (int64, error)
+	Restore(ctx context.Context, id string, deletedSince time.Time) (int64, error)
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time, limit int) (int64, error)
 	Ping() error
-	UpdateRole(ctx context.Context, userID string, role string) error
-	ListByRole(ctx context.Context, role string) ([]*User, error)
-	ListAll(ctx context.Context) ([]*User, error)
+	ListUsers(ctx context.Context, opts ListOptions) (*UserPage, error)
+	SearchUsers(ctx context.Context, opts SearchOptions) (*UserPage, error)
 	ToggleActive(ctx context.Context, userID string) (bool, error)
+	SetActive(ctx context.Context, userID string, active bool) (bool, error)
+	UpdateProfile(ctx context.Context, id string, p *ProfileUpdate) (*User, error)
+	RoleStore
+	OrganizationStore
+	InvitationStore
+	EmailChangeStore
+	PrivacyStore
+	AuditStore
+	LoginStore
+	OutboxStore
+	WebhookStore
 }
 
 // User holds data from the registration request body.
 type User struct {
-	ID        string `json:"id"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	Email     string `json:"email"`
-	Password  string `json:"password"`
-	Company   string `json:"company"`
-	PostCode  string `json:"post_code"`
-	Terms     bool   `json:"terms"`
-	CreatedBy string `json:"created_by"`
-	Active    bool   `json:"active"`
-	Role      string `json:"role"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	ID        string   `json:"id"`
+	FirstName string   `json:"first_name"`
+	LastName  string   `json:"last_name"`
+	Email     string   `json:"email"`
+	Password  string   `json:"password"`
+	Company   string   `json:"company"`
+	PostCode  string   `json:"post_code"`
+	Terms     bool     `json:"terms"`
+	CreatedBy string   `json:"created_by"`
+	Active    bool     `json:"active"`
+	Roles     []string `json:"roles"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+	// Locale is a BCP 47 language tag such as en-GB.
+	Locale string `json:"locale"`
+	// Timezone is an IANA time zone name such as Europe/London.
+	Timezone   string `json:"timezone"`
+	PictureURL string `json:"picture_url"`
+	// OrganizationID is the organization a new user joins, a personal
+	// organization is created for the user when there is neither an
+	// OrganizationID nor a tenant.
+	OrganizationID string `json:"-"`
 }
 
 // MYSQL implements store interface.
@@ -63,25 +126,95 @@ func (m *MYSQL) Insert(ctx context.Context, u *User) (*User, error) {
 		return nil, errEmptyUser
 	}
 
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	id, err := insertUser(ctx, tx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return m.Retrieve(ctx, id)
+}
+
+// insertUser saves the user and adds it to an organization with its roles,
+// it returns the ID of the new user.
+func insertUser(ctx context.Context, tx *sql.Tx, u *User) (string, error) {
 	id := uuid.New().String()
+	u.Email = NormalizeEmail(u.Email)
+
+	roles := u.Roles
+	if len(roles) == 0 {
+		roles = []string{DefaultRole}
+	}
 
-	insert, err := m.Conn.Prepare(`INSERT INTO identity_users
+	if err := unreservedEmail(ctx, tx, u.Email, id); err != nil {
+		return "", err
+	}
+
+	insert, err := tx.PrepareContext(ctx, `INSERT INTO identity_users
 (id, first_name, last_name, password,
  email, company, post_code, terms, created_by, active)
  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
 	if err != nil {
 		logrus.Errorf("failed to prepare user insert: %v", err)
-		return nil, err
+		return "", err
 	}
 
 	_, err = insert.ExecContext(ctx, id, u.FirstName, u.LastName,
 		u.Password, u.Email, u.Company, u.PostCode, u.Terms, u.CreatedBy, u.Active)
 	if err != nil {
 		logrus.Errorf("failed to insert user data: %v", err)
-		return nil, err
+		return "", duplicateEmail(err)
 	}
 
-	return m.Retrieve(ctx, id)
+	// users created by a tenant join the tenant's organization, only platform
+	// administrators can pick another one.
+	orgID := u.OrganizationID
+	if t, ok := TenantFromContext(ctx); ok && (orgID == "" || !t.Platform) {
+		orgID = t.OrganizationID
+	}
+	if orgID == "" {
+		orgID, err = createOrganization(ctx, tx, organizationName(u), id, roles)
+	} else {
+		err = addMember(ctx, tx, orgID, id, roles)
+	}
+	if err != nil {
+		logrus.Errorf("failed to add new user to an organization: %v", err)
+		return "", err
+	}
+
+	err = recordAudit(ctx, tx, &AuditEvent{
+		OrganizationID: orgID,
+		Action:         AuditUserCreated,
+		TargetType:     AuditTargetUser,
+		TargetID:       id,
+		After:          auditValue(map[string]any{"active": u.Active, "roles": roles}),
+	})
+	if err != nil {
+		return "", err
+	}
+	err = enqueueEvent(ctx, tx, events.UserRegistered{UserID: id, OrganizationID: orgID, Email: u.Email, Roles: roles})
+	if err != nil {
+		return "", err
+	}
+
+	return id, nil
+}
+
+// organizationName names the personal organization created for a user.
+func organizationName(u *User) string {
+	if u.Company != "" {
+		return u.Company
+	}
+	return u.FirstName + " " + u.LastName
 }
 
 // will return nil if the user is not found.
@@ -90,13 +223,16 @@ func (m *MYSQL) Retrieve(ctx context.Context, id string) (*User, error) {
 		return nil, errEmptyDBConnection
 	}
 
-	fetch, err := m.Conn.Prepare(RetrieveQuery)
+	scope, scopeArgs := tenantUsers(ctx)
+	fetch, err := m.Conn.Prepare(RetrieveQuery + ` AND ` + scope + ` limit 1`)
 	if err != nil {
 		return nil, err
 	}
 
-	row := fetch.QueryRowContext(ctx, id)
+	args := append(tenantRoleArgs(ctx), id)
+	row := fetch.QueryRowContext(ctx, append(args, scopeArgs...)...)
 	user := &User{}
+	var roles string
 	err = row.Scan(
 		&user.FirstName,
 		&user.LastName,
@@ -107,7 +243,10 @@ func (m *MYSQL) Retrieve(ctx context.Context, id string) (*User, error) {
 		&user.Active,
 		&user.CreatedAt,
 		&user.UpdatedAt,
-		&user.Role,
+		&roles,
+		&user.Locale,
+		&user.Timezone,
+		&user.PictureURL,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -118,10 +257,86 @@ func (m *MYSQL) Retrieve(ctx context.Context, id string) (*User, error) {
 		return nil, err
 	}
 	user.ID = id
+	user.Roles = splitNames(roles)
 	return user, nil
 }
 
-var RetrieveQuery = `SELECT first_name, last_name, email, company, post_code, created_by, active, created_at, updated_at, role FROM identity_users where id = ? limit 1`
+// rolesColumn aggregates the names of the roles assigned to a user into a
+// single comma separated column, use splitNames to read it back. Only the
+// roles visible to the tenant are included, it takes tenantRoleArgs.
+const rolesColumn = `COALESCE((SELECT GROUP_CONCAT(DISTINCT r.name ORDER BY r.name)
+	FROM user_roles ur JOIN roles r ON r.id = ur.role_id
+	WHERE ur.user_id = identity_users.id AND ` + tenantRoles + `), '') AS roles`
+
+// RetrieveMany fetches the users with ids in a single query, users that are
+// not found are left out and the rest are returned in no particular order.
+func (m *MYSQL) RetrieveMany(ctx context.Context, ids []string) ([]*User, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	scope, scopeArgs := tenantUsers(ctx)
+	query := RetrieveManyQuery + `(` + strings.TrimSuffix(strings.Repeat(`?, `, len(ids)), `, `) + `) AND ` + scope
+	args := tenantRoleArgs(ctx)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	rows, err := m.Conn.QueryContext(ctx, query, append(args, scopeArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		user := &User{}
+		var roles string
+		err := rows.Scan(
+			&user.ID,
+			&user.FirstName,
+			&user.LastName,
+			&user.Email,
+			&user.Company,
+			&user.PostCode,
+			&user.CreatedBy,
+			&user.Active,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+			&roles,
+			&user.Locale,
+			&user.Timezone,
+			&user.PictureURL,
+		)
+		if err != nil {
+			return nil, err
+		}
+		user.Roles = splitNames(roles)
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+var RetrieveQuery = `SELECT first_name, last_name, email, company, post_code, created_by, active, created_at, updated_at, ` +
+	rolesColumn + `, ` + profileColumns + ` FROM identity_users where id = ? AND ` + notDeleted
+
+// RetrieveManyQuery is completed with the list of ids and the tenant scope by RetrieveMany.
+var RetrieveManyQuery = `SELECT id, first_name, last_name, email, company, post_code, created_by, active, created_at, updated_at, ` +
+	rolesColumn + `, ` + profileColumns + ` FROM identity_users WHERE ` + notDeleted + ` AND id IN `
+
+// profileColumns are the optional profile details of a user.
+const profileColumns = `locale, timezone, picture_url`
+
+func splitNames(names string) []string {
+	if names == "" {
+		return []string{}
+	}
+	return strings.Split(names, ",")
+}
 
 var ReadQuery = `SELECT id,
        first_name,
@@ -134,22 +349,25 @@ var ReadQuery = `SELECT id,
        created_at,
        updated_at
 		FROM identity_users
-		where email = ?`
+		where email = ? AND ` + notDeleted
 
+// Read looks a user up by email across every organization, it is used to
+// log users in before their tenant is known.
 // will return nil if the user is not found.
 func (m *MYSQL) Read(ctx context.Context, email string) (*User, error) {
 	if m.Conn == nil {
 		return nil, errEmptyDBConnection
 	}
 
-	rows, err := m.Conn.QueryContext(ctx, ReadQuery, email)
+	rows, err := m.Conn.QueryContext(ctx, ReadQuery, NormalizeEmail(email))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	user := &User{}
+	var user *User
 	for rows.Next() {
+		user = &User{}
 		err := rows.Scan(
 			&user.ID,
 			&user.FirstName,
@@ -163,124 +381,120 @@ func (m *MYSQL) Read(ctx context.Context, email string) (*User, error) {
 			&user.UpdatedAt,
 		)
 		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				logrus.Infof("user not found :: %s", email)
-				return nil, nil
-			}
 			logrus.Errorf("failed to read user data: %v", err)
 			return nil, errInvalidDataInDB
 		}
 	}
-
-	return user, nil
-}
-
-// Delete removes a user from auth as per the ID.
-func (m *MYSQL) Delete(id string) (int64, error) {
-	remove, err := m.Conn.Prepare(`DELETE  FROM identity_users WHERE id = ?`)
-	if err != nil {
-		logrus.Errorf("user deletion failed with error: %v", err)
-		return 0, err
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-
-	result, err := remove.Exec(id)
-	if err != nil {
-		return 0, err
+	if user == nil {
+		logrus.Infof("user not found :: %s", email)
 	}
 
-	return result.RowsAffected()
+	return user, nil
 }
 
 func (m *MYSQL) Ping() error {
 	return m.Conn.Ping()
 }
 
-// UpdateRole sets the role for a user identified by userID.
-func (m *MYSQL) UpdateRole(ctx context.Context, userID string, role string) error {
-	stmt, err := m.Conn.Prepare(`UPDATE identity_users SET role = ? WHERE id = ?`)
-	if err != nil {
-		return err
-	}
-	result, err := stmt.ExecContext(ctx, role, userID)
-	if err != nil {
-		return err
-	}
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return errors.New("user not found")
-	}
-	return nil
-}
-
-// ListAll returns all registered users.
-func (m *MYSQL) ListAll(ctx context.Context) ([]*User, error) {
-	rows, err := m.Conn.QueryContext(ctx,
-		`SELECT id, first_name, last_name, email, company, post_code, created_by, active, role, created_at, updated_at
-		 FROM identity_users`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
+// scanUsers reads the user listing columns selected by ListUsers and SearchUsers.
+func scanUsers(rows *sql.Rows) ([]*User, error) {
 	var users []*User
 	for rows.Next() {
 		u := &User{}
+		var roles string
 		if err := rows.Scan(
 			&u.ID, &u.FirstName, &u.LastName, &u.Email,
-			&u.Company, &u.PostCode, &u.CreatedBy, &u.Active, &u.Role, &u.CreatedAt, &u.UpdatedAt,
+			&u.Company, &u.PostCode, &u.CreatedBy, &u.Active, &roles, &u.CreatedAt, &u.UpdatedAt,
+			&u.Locale, &u.Timezone, &u.PictureURL,
 		); err != nil {
 			return nil, err
 		}
+		u.Roles = splitNames(roles)
 		users = append(users, u)
 	}
 	return users, nil
 }
 
-// ListByRole returns all users with the given role.
-func (m *MYSQL) ListByRole(ctx context.Context, role string) ([]*User, error) {
-	rows, err := m.Conn.QueryContext(ctx,
-		`SELECT id, first_name, last_name, email, company, post_code, created_by, active, role, created_at, updated_at
-		 FROM identity_users WHERE role = ?`, role)
+// ToggleActive flips the active flag for a user and returns the new value. A
+// tenant can only change users that are not members of other organizations.
+func (m *MYSQL) ToggleActive(ctx context.Context, userID string) (bool, error) {
+	var active bool
+	_, err := m.updateActive(ctx, userID, func(current bool) bool {
+		active = !current
+		return active
+	})
 	if err != nil {
-		return nil, err
+		return false, err
 	}
-	defer rows.Close()
+	return active, nil
+}
 
-	var users []*User
-	for rows.Next() {
-		u := &User{}
-		if err := rows.Scan(
-			&u.ID, &u.FirstName, &u.LastName, &u.Email,
-			&u.Company, &u.PostCode, &u.CreatedBy, &u.Active, &u.Role, &u.CreatedAt, &u.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		users = append(users, u)
-	}
-	return users, nil
+// SetActive activates or deactivates a user of the tenant, it reports whether
+// the user was changed, users already in that state are left alone. A tenant
+// can only change users that are not members of other organizations.
+func (m *MYSQL) SetActive(ctx context.Context, userID string, active bool) (bool, error) {
+	return m.updateActive(ctx, userID, func(bool) bool { return active })
 }
 
-// ToggleActive flips the active flag for a user and returns the new value.
-func (m *MYSQL) ToggleActive(ctx context.Context, userID string) (bool, error) {
-	result, err := m.Conn.ExecContext(ctx,
-		`UPDATE identity_users SET active = NOT active WHERE id = ?`, userID)
+// updateActive sets the active flag of a user to the value next returns for
+// the current one while the user is locked, it reports whether it changed.
+func (m *MYSQL) updateActive(ctx context.Context, userID string, next func(current bool) bool) (bool, error) {
+	if m.Conn == nil {
+		return false, errEmptyDBConnection
+	}
+
+	tx, err := m.Conn.BeginTx(ctx, nil)
 	if err != nil {
 		return false, err
 	}
-	rows, err := result.RowsAffected()
+	defer func() { _ = tx.Rollback() }()
+
+	scope, scopeArgs := tenantUsers(ctx)
+	var active bool
+	err = tx.QueryRowContext(ctx,
+		`SELECT active FROM identity_users WHERE id = ? AND `+notDeleted+` AND `+scope+` FOR UPDATE`,
+		append([]any{userID}, scopeArgs...)...).Scan(&active)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, ErrUserNotFound
+		}
 		return false, err
 	}
-	if rows == 0 {
-		return false, errors.New("user not found")
+	updated := next(active)
+	if updated == active {
+		return false, nil
+	}
+	if err := soleMember(ctx, tx, userID); err != nil {
+		return false, err
 	}
 
-	user, err := m.Retrieve(ctx, userID)
+	if _, err := tx.ExecContext(ctx, `UPDATE identity_users SET active = ? WHERE id = ?`, updated, userID); err != nil {
+		return false, err
+	}
+	err = recordAudit(ctx, tx, &AuditEvent{
+		Action:     AuditUserActivationToggled,
+		TargetType: AuditTargetUser,
+		TargetID:   userID,
+		Before:     auditValue(map[string]any{"active": active}),
+		After:      auditValue(map[string]any{"active": updated}),
+	})
 	if err != nil {
 		return false, err
 	}
-	return user.Active, nil
+	var event events.Event = events.UserActivated{UserID: userID, OrganizationID: eventOrganization(ctx)}
+	if !updated {
+		event = events.UserDeactivated{UserID: userID, OrganizationID: eventOrganization(ctx)}
+	}
+	if err := enqueueEvent(ctx, tx, event); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
 }
@@ -9,9 +9,12 @@ import (
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	driver "github.com/go-sql-driver/mysql"
 	"github.com/golang-migrate/migrate/database/mysql"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadennis/identity-server/business/events"
 )
 
 func TestPing(t *testing.T) {
@@ -38,6 +41,7 @@ func TestDBInsertSuccess(t *testing.T) {
 	scenarios := []struct {
 		name        string
 		db          *MYSQL
+		tenant      *Tenant
 		user        *User
 		uid         string
 		expectedErr error
@@ -58,14 +62,28 @@ func TestDBInsertSuccess(t *testing.T) {
 			db: func() *MYSQL {
 				conn, mock, err := sqlmock.New()
 				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM email_changes`).WithArgs("john.doe@test.com", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"reserved"}).AddRow(false))
 				mock.ExpectPrepare("INSERT INTO identity_users").ExpectExec().
 					WithArgs(sqlmock.AnyArg(), "John", "Doe", "check", "john.doe@test.com", "Arctura", "12345", true, "", false).
 					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(`INSERT INTO organizations`).WithArgs(sqlmock.AnyArg(), "Arctura", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(`INSERT IGNORE INTO organization_members`).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(`SELECT id, platform FROM roles WHERE name = ?`).WithArgs(DefaultRole).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "platform"}).AddRow("role-user", false))
+				mock.ExpectExec(`INSERT IGNORE INTO user_roles`).WithArgs(sqlmock.AnyArg(), "role-user", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				expectAudit(mock, AuditUserCreated, sqlmock.AnyArg())
+				expectEvent(mock, events.TypeUserRegistered, sqlmock.AnyArg())
+				mock.ExpectCommit()
 				mock.ExpectPrepare(regexp.QuoteMeta(RetrieveQuery)).
 					ExpectQuery().
-					WithArgs(sqlmock.AnyArg()).
-					WillReturnRows(sqlmock.NewRows([]string{"first_name", "last_name", "email", "company", "post_code", "created_by", "active", "created_at", "updated_at", "role"}).
-						AddRow("John", "Doe", "john.doe@test.com", "Arctura", "12345", "", true, time.Now(), time.Now(), "user"))
+					WithArgs("", "", sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"first_name", "last_name", "email", "company", "post_code", "created_by", "active", "created_at", "updated_at", "roles", "locale", "timezone", "picture_url"}).
+						AddRow("John", "Doe", "john.doe@test.com", "Arctura", "12345", "", true, time.Now(), time.Now(), "user", "", "", ""))
 				return NewDB(conn)
 			}(),
 			user: &User{
@@ -80,11 +98,71 @@ func TestDBInsertSuccess(t *testing.T) {
 			uid:         uuid.NewString(),
 			expectedErr: nil,
 		},
+		{
+			name: "reserved email",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM email_changes`).WithArgs("john.doe@test.com", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"reserved"}).AddRow(true))
+				mock.ExpectRollback()
+				return NewDB(conn)
+			}(),
+			user:        &User{FirstName: "John", LastName: "Doe", Email: "john.doe@test.com"},
+			expectedErr: ErrDuplicateEmail,
+		},
+		{
+			name: "joins the tenant's organization",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM email_changes`).WithArgs("john.doe@test.com", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"reserved"}).AddRow(false))
+				mock.ExpectPrepare("INSERT INTO identity_users").ExpectExec().
+					WithArgs(sqlmock.AnyArg(), "John", "Doe", "check", "john.doe@test.com", "Arctura", "12345", true, "", false).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(`INSERT IGNORE INTO organization_members`).WithArgs("org-1", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectQuery(`SELECT id, platform FROM roles WHERE name = ?`).WithArgs(DefaultRole).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "platform"}).AddRow("role-user", false))
+				mock.ExpectExec(`INSERT IGNORE INTO user_roles`).WithArgs(sqlmock.AnyArg(), "role-user", "org-1").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectExec(`INSERT INTO audit_events`).
+					WithArgs(sqlmock.AnyArg(), "org-1", "", AuditUserCreated, AuditTargetUser, sqlmock.AnyArg(),
+						nil, `{"active":false,"roles":["user"]}`, "", "", "", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectEvent(mock, events.TypeUserRegistered, sqlmock.AnyArg())
+				mock.ExpectCommit()
+				mock.ExpectPrepare(regexp.QuoteMeta(RetrieveQuery)).
+					ExpectQuery().
+					WithArgs("org-1", "org-1", sqlmock.AnyArg(), "org-1").
+					WillReturnRows(sqlmock.NewRows([]string{"first_name", "last_name", "email", "company", "post_code", "created_by", "active", "created_at", "updated_at", "roles", "locale", "timezone", "picture_url"}).
+						AddRow("John", "Doe", "john.doe@test.com", "Arctura", "12345", "", true, time.Now(), time.Now(), "user", "", "", ""))
+				return NewDB(conn)
+			}(),
+			tenant: &testTenant,
+			user: &User{
+				FirstName:      "John",
+				LastName:       "Doe",
+				Email:          "john.doe@test.com",
+				Password:       "check",
+				Company:        "Arctura",
+				PostCode:       "12345",
+				Terms:          true,
+				OrganizationID: "org-2",
+			},
+		},
 	}
 
 	for _, sc := range scenarios {
 		t.Run(sc.name, func(t *testing.T) {
-			re, err := sc.db.Insert(context.Background(), sc.user)
+			ctx := context.Background()
+			if sc.tenant != nil {
+				ctx = WithTenant(ctx, *sc.tenant)
+			}
+			re, err := sc.db.Insert(ctx, sc.user)
 			if !errors.Is(err, sc.expectedErr) {
 				t.Fatalf("unexpected error, wanted %v, got %v", sc.expectedErr, err)
 			}
@@ -108,6 +186,9 @@ func TestDBInsertExecuteFail(t *testing.T) {
 		Password:  "check",
 		Terms:     true,
 	}
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM email_changes`).WithArgs("john.doe@test.com", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"reserved"}).AddRow(false))
 	mock.ExpectPrepare("INSERT INTO identity_users").ExpectExec().
 		WithArgs(uid, user.FirstName, user.LastName, user.Password,
 			user.Email, user.Company, user.PostCode, user.Terms).
@@ -118,6 +199,32 @@ func TestDBInsertExecuteFail(t *testing.T) {
 	}
 }
 
+func TestDBInsertDuplicateEmail(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	user := &User{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     " John.Doe@Test.com ",
+		Password:  "check",
+		Terms:     true,
+	}
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM email_changes`).WithArgs("john.doe@test.com", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"reserved"}).AddRow(false))
+	mock.ExpectPrepare("INSERT INTO identity_users").ExpectExec().
+		WithArgs(sqlmock.AnyArg(), "John", "Doe", "check", "john.doe@test.com", "", "", true, "", false).
+		WillReturnError(&driver.MySQLError{
+			Number:  mysqlErrDuplicateEntry,
+			Message: "Duplicate entry 'john.doe@test.com' for key 'identity_users.identity_users_email_unique'",
+		})
+	mock.ExpectRollback()
+
+	_, err = NewDB(conn).Insert(context.Background(), user)
+	assert.Equal(t, ErrDuplicateEmail, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestDBInsertPrepareFail(t *testing.T) {
 	conn, mock, err := sqlmock.New()
 	if err != nil {
@@ -131,6 +238,9 @@ func TestDBInsertPrepareFail(t *testing.T) {
 		Password:  "check",
 		Terms:     true,
 	}
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM email_changes`).WithArgs("john.doe@test.com", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"reserved"}).AddRow(false))
 	mock.ExpectPrepare("INSERT INTO identity_users").WillReturnError(mysql.ErrNoDatabaseName)
 	_, err = NewDB(conn).Insert(context.Background(), user)
 	if err == nil {
@@ -142,6 +252,7 @@ func TestDBRetrieve(t *testing.T) {
 	scenarios := []struct {
 		name        string
 		db          *MYSQL
+		tenant      *Tenant
 		user        *User
 		uid         string
 		expectedErr error
@@ -169,7 +280,7 @@ func TestDBRetrieve(t *testing.T) {
 				assert.NoError(t, err)
 				mock.ExpectPrepare(regexp.QuoteMeta(RetrieveQuery)).
 					ExpectQuery().
-					WithArgs(sqlmock.AnyArg()).
+					WithArgs("", "", sqlmock.AnyArg()).
 					WillReturnError(errors.New("error"))
 				return NewDB(conn)
 			}(),
@@ -182,11 +293,24 @@ func TestDBRetrieve(t *testing.T) {
 				assert.NoError(t, err)
 				mock.ExpectPrepare(regexp.QuoteMeta(RetrieveQuery)).
 					ExpectQuery().
-					WithArgs(sqlmock.AnyArg()).
+					WithArgs("", "", sqlmock.AnyArg()).
 					WillReturnError(sql.ErrNoRows)
 				return NewDB(conn)
 			}(),
 		},
+		{
+			name: "user outside the tenant",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectPrepare(regexp.QuoteMeta(RetrieveQuery+` AND identity_users.id IN (`)).
+					ExpectQuery().
+					WithArgs("org-1", "org-1", sqlmock.AnyArg(), "org-1").
+					WillReturnError(sql.ErrNoRows)
+				return NewDB(conn)
+			}(),
+			tenant: &testTenant,
+		},
 		{
 			name: "success",
 			db: func() *MYSQL {
@@ -194,11 +318,11 @@ func TestDBRetrieve(t *testing.T) {
 				assert.NoError(t, err)
 				mock.ExpectPrepare(regexp.QuoteMeta(RetrieveQuery)).
 					ExpectQuery().
-					WithArgs(sqlmock.AnyArg()).
+					WithArgs("", "", sqlmock.AnyArg()).
 					WillReturnRows(
 						sqlmock.NewRows(
-							[]string{"first_name", "last_name", "email", "company", "post_code", "created_by", "active", "created_at", "updated_at", "role"}).
-							AddRow("john", "doe", "john.doe@gmail.com", "Arctura", "12345", "", true, "2024-01-01", "2024-01-01", "user"))
+							[]string{"first_name", "last_name", "email", "company", "post_code", "created_by", "active", "created_at", "updated_at", "roles", "locale", "timezone", "picture_url"}).
+							AddRow("john", "doe", "john.doe@gmail.com", "Arctura", "12345", "", true, "2024-01-01", "2024-01-01", "user", "", "", ""))
 				return NewDB(conn)
 			}(),
 			user: &User{
@@ -210,358 +334,185 @@ func TestDBRetrieve(t *testing.T) {
 				Active:    true,
 				CreatedAt: "2024-01-01",
 				UpdatedAt: "2024-01-01",
-				Role:      "user",
+				Roles:     []string{"user"},
 			},
 		},
 	}
 	for _, sc := range scenarios {
 		t.Run(sc.name, func(t *testing.T) {
-			user, err := sc.db.Retrieve(context.Background(), sc.uid)
+			ctx := context.Background()
+			if sc.tenant != nil {
+				ctx = WithTenant(ctx, *sc.tenant)
+			}
+			user, err := sc.db.Retrieve(ctx, sc.uid)
 			assert.Equal(t, sc.expectedErr, err)
 			assert.Equal(t, sc.user, user)
 		})
 	}
 }
 
-func TestDB_Read(t *testing.T) {
+func TestDBRetrieveMany(t *testing.T) {
+	columns := []string{"id", "first_name", "last_name", "email", "company", "post_code", "created_by", "active", "created_at", "updated_at", "roles", "locale", "timezone", "picture_url"}
 	scenarios := []struct {
 		name        string
 		db          *MYSQL
-		user        *User
-		email       string
+		tenant      *Tenant
+		ids         []string
+		users       []*User
 		expectedErr error
 	}{
 		{
 			name:        "empty connection",
 			db:          &MYSQL{},
+			ids:         []string{"1"},
 			expectedErr: errEmptyDBConnection,
 		},
 		{
-			name: "query failed",
-			db: func() *MYSQL {
-				conn, mock, err := sqlmock.New()
-				assert.NoError(t, err)
-				mock.ExpectQuery(ReadQuery).
-					WillReturnError(errors.New("error"))
-				return NewDB(conn)
-			}(),
-			expectedErr: errors.New("error"),
-		},
-		{
-			name: "invalid data in MYSQL",
+			name: "no ids",
 			db: func() *MYSQL {
-				conn, mock, err := sqlmock.New()
+				conn, _, err := sqlmock.New()
 				assert.NoError(t, err)
-				mock.ExpectQuery(ReadQuery).
-					WithArgs(sqlmock.AnyArg()).
-					WillReturnRows(sqlmock.NewRows(
-						[]string{"id", "first_name", "last_name", "email", "company", "post_code", "created_by", "active", "created_at", "updated_at"}).
-						AddRow(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil))
 				return NewDB(conn)
 			}(),
-			expectedErr: errInvalidDataInDB,
 		},
 		{
-			name: "success",
-			db: func() *MYSQL {
-				conn, mock, err := sqlmock.New()
-				assert.NoError(t, err)
-				mock.ExpectQuery(ReadQuery).
-					WithArgs(sqlmock.AnyArg()).
-					WillReturnRows(sqlmock.NewRows(
-						[]string{"id", "first_name", "last_name", "email", "company", "post_code", "created_by", "active", "created_at", "updated_at"}).
-						AddRow(123, "john", "doe", "john.doe@gmail.com", "Arctura", "12345", "", true, "2024-01-01", "2024-01-01"))
-				return NewDB(conn)
-			}(),
-			user: &User{
-				ID:        "123",
-				FirstName: "john",
-				LastName:  "doe",
-				Email:     "john.doe@gmail.com",
-				Company:   "Arctura",
-				PostCode:  "12345",
-				Active:    true,
-				CreatedAt: "2024-01-01",
-				UpdatedAt: "2024-01-01",
-			},
-		},
-	}
-	for _, sc := range scenarios {
-		t.Run(sc.name, func(t *testing.T) {
-			user, err := sc.db.Read(context.Background(), sc.email)
-			assert.Equal(t, sc.expectedErr, err)
-			assert.Equal(t, sc.user, user)
-		})
-	}
-}
-
-func TestDB_UpdateRole(t *testing.T) {
-	scenarios := []struct {
-		name        string
-		db          *MYSQL
-		userID      string
-		role        string
-		expectedErr error
-	}{
-		{
-			name: "prepare failed",
+			name: "query failed",
 			db: func() *MYSQL {
 				conn, mock, err := sqlmock.New()
 				assert.NoError(t, err)
-				mock.ExpectPrepare(`UPDATE identity_users SET role`).
+				mock.ExpectQuery(regexp.QuoteMeta(RetrieveManyQuery+`(?) AND TRUE`)).
+					WithArgs("", "", "1").
 					WillReturnError(errors.New("error"))
 				return NewDB(conn)
 			}(),
+			ids:         []string{"1"},
 			expectedErr: errors.New("error"),
 		},
 		{
-			name: "exec failed",
+			name: "duplicate ids",
 			db: func() *MYSQL {
 				conn, mock, err := sqlmock.New()
 				assert.NoError(t, err)
-				mock.ExpectPrepare(`UPDATE identity_users SET role`).
-					ExpectExec().WillReturnError(errors.New("exec error"))
+				mock.ExpectQuery(regexp.QuoteMeta(RetrieveManyQuery+`(?, ?) AND TRUE`)).
+					WithArgs("", "", "1", "2").
+					WillReturnRows(sqlmock.NewRows(columns).
+						AddRow("2", "jane", "doe", "jane.doe@gmail.com", "Arctura", "12345", "1", true, "2024-01-01", "2024-01-01", "", "", "", "").
+						AddRow("1", "john", "doe", "john.doe@gmail.com", "Arctura", "12345", "", true, "2024-01-01", "2024-01-01", "admin,user", "en-GB", "", ""))
 				return NewDB(conn)
 			}(),
-			userID:      "user-123",
-			role:        "admin",
-			expectedErr: errors.New("exec error"),
-		},
-		{
-			name: "user not found",
-			db: func() *MYSQL {
-				conn, mock, err := sqlmock.New()
-				assert.NoError(t, err)
-				mock.ExpectPrepare(`UPDATE identity_users SET role`).
-					ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
-				return NewDB(conn)
-			}(),
-			userID:      "nonexistent",
-			role:        "admin",
-			expectedErr: errors.New("user not found"),
+			ids: []string{"2", "1", "2"},
+			users: []*User{
+				{ID: "2", FirstName: "jane", LastName: "doe", Email: "jane.doe@gmail.com", Company: "Arctura", PostCode: "12345", CreatedBy: "1", Active: true, CreatedAt: "2024-01-01", UpdatedAt: "2024-01-01", Roles: []string{}},
+				{ID: "1", FirstName: "john", LastName: "doe", Email: "john.doe@gmail.com", Company: "Arctura", PostCode: "12345", Active: true, CreatedAt: "2024-01-01", UpdatedAt: "2024-01-01", Roles: []string{"admin", "user"}, Locale: "en-GB"},
+			},
 		},
 		{
-			name: "success",
+			name: "users outside the tenant",
 			db: func() *MYSQL {
 				conn, mock, err := sqlmock.New()
 				assert.NoError(t, err)
-				mock.ExpectPrepare(`UPDATE identity_users SET role`).
-					ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectQuery(regexp.QuoteMeta(RetrieveManyQuery+`(?, ?) AND identity_users.id IN (`)).
+					WithArgs("org-1", "org-1", "1", "2", "org-1").
+					WillReturnRows(sqlmock.NewRows(columns))
 				return NewDB(conn)
 			}(),
-			userID: "user-123",
-			role:   "admin",
+			tenant: &testTenant,
+			ids:    []string{"1", "2"},
 		},
 	}
 	for _, sc := range scenarios {
 		t.Run(sc.name, func(t *testing.T) {
-			err := sc.db.UpdateRole(context.Background(), sc.userID, sc.role)
+			ctx := context.Background()
+			if sc.tenant != nil {
+				ctx = WithTenant(ctx, *sc.tenant)
+			}
+			users, err := sc.db.RetrieveMany(ctx, sc.ids)
 			assert.Equal(t, sc.expectedErr, err)
+			assert.Equal(t, sc.users, users)
 		})
 	}
 }
 
-func TestDB_ListAll(t *testing.T) {
+func TestDB_Read(t *testing.T) {
 	scenarios := []struct {
-		name           string
-		db             *MYSQL
-		expectedUsers  []*User
-		expectedErr    error
-		expectedErrMsg string
+		name        string
+		db          *MYSQL
+		user        *User
+		email       string
+		expectedErr error
 	}{
 		{
-			name: "query failed",
-			db: func() *MYSQL {
-				conn, mock, err := sqlmock.New()
-				assert.NoError(t, err)
-				mock.ExpectQuery(`SELECT id, first_name`).
-					WillReturnError(errors.New("query error"))
-				return NewDB(conn)
-			}(),
-			expectedErr: errors.New("query error"),
-		},
-		{
-			name: "scan error",
-			db: func() *MYSQL {
-				conn, mock, err := sqlmock.New()
-				assert.NoError(t, err)
-				mock.ExpectQuery(`SELECT id, first_name`).
-					WillReturnRows(sqlmock.NewRows(
-						[]string{"id", "first_name", "last_name", "email", "company", "post_code", "created_by", "active", "role", "created_at", "updated_at"}).
-						AddRow(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil))
-				return NewDB(conn)
-			}(),
-			expectedErrMsg: `sql: Scan error on column index 0, name "id": converting NULL to string is unsupported`,
-		},
-		{
-			name: "empty result",
-			db: func() *MYSQL {
-				conn, mock, err := sqlmock.New()
-				assert.NoError(t, err)
-				mock.ExpectQuery(`SELECT id, first_name`).
-					WillReturnRows(sqlmock.NewRows(
-						[]string{"id", "first_name", "last_name", "email", "company", "post_code", "created_by", "active", "role", "created_at", "updated_at"}))
-				return NewDB(conn)
-			}(),
-			expectedUsers: nil,
-		},
-		{
-			name: "success",
-			db: func() *MYSQL {
-				conn, mock, err := sqlmock.New()
-				assert.NoError(t, err)
-				mock.ExpectQuery(`SELECT id, first_name`).
-					WillReturnRows(sqlmock.NewRows(
-						[]string{"id", "first_name", "last_name", "email", "company", "post_code", "created_by", "active", "role", "created_at", "updated_at"}).
-						AddRow("1", "John", "Doe", "john@test.com", "Acme", "12345", "", true, "user", "2024-01-01", "2024-01-01").
-						AddRow("2", "Jane", "Doe", "jane@test.com", "Acme", "12345", "admin-uuid", true, "admin", "2024-01-01", "2024-01-01"))
-				return NewDB(conn)
-			}(),
-			expectedUsers: []*User{
-				{ID: "1", FirstName: "John", LastName: "Doe", Email: "john@test.com", Company: "Acme", PostCode: "12345", Active: true, Role: "user", CreatedAt: "2024-01-01", UpdatedAt: "2024-01-01"},
-				{ID: "2", FirstName: "Jane", LastName: "Doe", Email: "jane@test.com", Company: "Acme", PostCode: "12345", CreatedBy: "admin-uuid", Active: true, Role: "admin", CreatedAt: "2024-01-01", UpdatedAt: "2024-01-01"},
-			},
+			name:        "empty connection",
+			db:          &MYSQL{},
+			expectedErr: errEmptyDBConnection,
 		},
-	}
-	for _, sc := range scenarios {
-		t.Run(sc.name, func(t *testing.T) {
-			users, err := sc.db.ListAll(context.Background())
-			if sc.expectedErrMsg != "" {
-				assert.EqualError(t, err, sc.expectedErrMsg)
-			} else {
-				assert.Equal(t, sc.expectedErr, err)
-			}
-			assert.Equal(t, sc.expectedUsers, users)
-		})
-	}
-}
-
-func TestDB_ListByRole(t *testing.T) {
-	scenarios := []struct {
-		name           string
-		db             *MYSQL
-		role           string
-		expectedUsers  []*User
-		expectedErr    error
-		expectedErrMsg string
-	}{
 		{
 			name: "query failed",
 			db: func() *MYSQL {
 				conn, mock, err := sqlmock.New()
 				assert.NoError(t, err)
-				mock.ExpectQuery(`SELECT id, first_name`).
-					WillReturnError(errors.New("query error"))
+				mock.ExpectQuery(regexp.QuoteMeta(ReadQuery)).
+					WillReturnError(errors.New("error"))
 				return NewDB(conn)
 			}(),
-			role:        "admin",
-			expectedErr: errors.New("query error"),
+			expectedErr: errors.New("error"),
 		},
 		{
-			name: "scan error",
+			name: "not found",
 			db: func() *MYSQL {
 				conn, mock, err := sqlmock.New()
 				assert.NoError(t, err)
-				mock.ExpectQuery(`SELECT id, first_name`).
+				mock.ExpectQuery(regexp.QuoteMeta(ReadQuery)).
+					WithArgs(sqlmock.AnyArg()).
 					WillReturnRows(sqlmock.NewRows(
-						[]string{"id", "first_name", "last_name", "email", "company", "post_code", "created_by", "active", "role", "created_at", "updated_at"}).
-						AddRow(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil))
+						[]string{"id", "first_name", "last_name", "email", "company", "post_code", "created_by", "active", "created_at", "updated_at"}))
 				return NewDB(conn)
 			}(),
-			role:           "admin",
-			expectedErrMsg: `sql: Scan error on column index 0, name "id": converting NULL to string is unsupported`,
 		},
 		{
-			name: "empty result",
+			name: "invalid data in MYSQL",
 			db: func() *MYSQL {
 				conn, mock, err := sqlmock.New()
 				assert.NoError(t, err)
-				mock.ExpectQuery(`SELECT id, first_name`).
+				mock.ExpectQuery(regexp.QuoteMeta(ReadQuery)).
+					WithArgs(sqlmock.AnyArg()).
 					WillReturnRows(sqlmock.NewRows(
-						[]string{"id", "first_name", "last_name", "email", "company", "post_code", "created_by", "active", "role", "created_at", "updated_at"}))
+						[]string{"id", "first_name", "last_name", "email", "company", "post_code", "created_by", "active", "created_at", "updated_at"}).
+						AddRow(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil))
 				return NewDB(conn)
 			}(),
-			role:          "admin",
-			expectedUsers: nil,
+			expectedErr: errInvalidDataInDB,
 		},
 		{
 			name: "success",
 			db: func() *MYSQL {
 				conn, mock, err := sqlmock.New()
 				assert.NoError(t, err)
-				mock.ExpectQuery(`SELECT id, first_name`).
-					WithArgs("admin").
+				mock.ExpectQuery(regexp.QuoteMeta(ReadQuery)).
+					WithArgs(sqlmock.AnyArg()).
 					WillReturnRows(sqlmock.NewRows(
-						[]string{"id", "first_name", "last_name", "email", "company", "post_code", "created_by", "active", "role", "created_at", "updated_at"}).
-						AddRow("1", "John", "Doe", "john@test.com", "Acme", "12345", "", true, "admin", "2024-01-01", "2024-01-01"))
+						[]string{"id", "first_name", "last_name", "email", "company", "post_code", "created_by", "active", "created_at", "updated_at"}).
+						AddRow(123, "john", "doe", "john.doe@gmail.com", "Arctura", "12345", "", true, "2024-01-01", "2024-01-01"))
 				return NewDB(conn)
 			}(),
-			role: "admin",
-			expectedUsers: []*User{
-				{ID: "1", FirstName: "John", LastName: "Doe", Email: "john@test.com", Company: "Acme", PostCode: "12345", Active: true, Role: "admin", CreatedAt: "2024-01-01", UpdatedAt: "2024-01-01"},
+			user: &User{
+				ID:        "123",
+				FirstName: "john",
+				LastName:  "doe",
+				Email:     "john.doe@gmail.com",
+				Company:   "Arctura",
+				PostCode:  "12345",
+				Active:    true,
+				CreatedAt: "2024-01-01",
+				UpdatedAt: "2024-01-01",
 			},
 		},
 	}
 	for _, sc := range scenarios {
 		t.Run(sc.name, func(t *testing.T) {
-			users, err := sc.db.ListByRole(context.Background(), sc.role)
-			if sc.expectedErrMsg != "" {
-				assert.EqualError(t, err, sc.expectedErrMsg)
-			} else {
-				assert.Equal(t, sc.expectedErr, err)
-			}
-			assert.Equal(t, sc.expectedUsers, users)
-		})
-	}
-}
-
-func TestDB_Delete(t *testing.T) {
-	scenarios := []struct {
-		name                 string
-		db                   *MYSQL
-		id                   string
-		expectedErr          error
-		expectedRowsAffected int64
-	}{
-		{
-			name: "deletion prepare failed",
-			db: func() *MYSQL {
-				conn, mock, err := sqlmock.New()
-				assert.NoError(t, err)
-				mock.ExpectPrepare(`DELETE  FROM identity_users WHERE id = ?`).
-					WillReturnError(errors.New("error"))
-				return NewDB(conn)
-			}(),
-			expectedErr: errors.New("error"),
-		},
-		{
-			name: "deletion execution failed",
-			db: func() *MYSQL {
-				conn, mock, err := sqlmock.New()
-				assert.NoError(t, err)
-				mock.ExpectPrepare(`DELETE  FROM identity_users WHERE id = ?`).
-					ExpectExec().WillReturnError(errors.New("error"))
-				return NewDB(conn)
-			}(),
-			expectedErr: errors.New("error"),
-		},
-		{
-			name: "success",
-			db: func() *MYSQL {
-				conn, mock, err := sqlmock.New()
-				assert.NoError(t, err)
-				mock.ExpectPrepare(`DELETE  FROM identity_users WHERE id = ?`).
-					ExpectExec().WillReturnResult(sqlmock.NewResult(1, 1))
-				return NewDB(conn)
-			}(),
-			expectedRowsAffected: 1,
-		},
-	}
-	for _, sc := range scenarios {
-		t.Run(sc.name, func(t *testing.T) {
-			rowsAffected, err := sc.db.Delete(sc.id)
+			user, err := sc.db.Read(context.Background(), sc.email)
 			assert.Equal(t, sc.expectedErr, err)
-			assert.Equal(t, sc.expectedRowsAffected, rowsAffected)
+			assert.Equal(t, sc.user, user)
 		})
 	}
 }
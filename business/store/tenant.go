@@ -0,0 +1,65 @@
+package store
+
+import (
+	"context"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims are the claims carried by the access tokens issued by the service.
+type Claims struct {
+	jwt.RegisteredClaims
+	// OrgID is the organization the token was issued for.
+	OrgID string `json:"org_id,omitempty"`
+}
+
+// Tenant is the organization a request acts within.
+type Tenant struct {
+	OrganizationID string
+	// Platform is set for platform superadmins, their queries are not
+	// restricted to the members of OrganizationID.
+	Platform bool
+}
+
+type tenantKey struct{}
+
+// WithTenant returns a copy of ctx that scopes store queries to the tenant.
+func WithTenant(ctx context.Context, t Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey{}, t)
+}
+
+// TenantFromContext returns the tenant set by WithTenant.
+func TenantFromContext(ctx context.Context) (Tenant, bool) {
+	t, ok := ctx.Value(tenantKey{}).(Tenant)
+	return t, ok
+}
+
+// platformTenant reports whether ctx may make changes that affect every
+// organization, that is when there is no tenant or the tenant is a platform administrator.
+func platformTenant(ctx context.Context) bool {
+	t, ok := TenantFromContext(ctx)
+	return !ok || t.Platform
+}
+
+// tenantUsers returns the condition that restricts identity_users to the
+// members of the tenant's organization. Queries made without a tenant, such
+// as login and registration, are not restricted.
+func tenantUsers(ctx context.Context) (string, []any) {
+	t, ok := TenantFromContext(ctx)
+	if !ok || t.Platform {
+		return "TRUE", nil
+	}
+
+	return `identity_users.id IN (SELECT om.user_id FROM organization_members om WHERE om.organization_id = ?)`,
+		[]any{t.OrganizationID}
+}
+
+// tenantRoles restricts user_roles, aliased ur, to platform roles and the roles
+// granted in the organization passed twice as arguments. An empty organization keeps every role.
+const tenantRoles = `(? = '' OR ur.organization_id IN ('', ?))`
+
+// tenantRoleArgs returns the arguments for tenantRoles.
+func tenantRoleArgs(ctx context.Context) []any {
+	t, _ := TenantFromContext(ctx)
+	return []any{t.OrganizationID, t.OrganizationID}
+}
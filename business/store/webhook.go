@@ -0,0 +1,450 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Statuses of webhook deliveries.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+var (
+	// ErrWebhookNotFound is returned when a webhook does not exist in the tenant's organization.
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrDeliveryNotFound is returned when a webhook delivery does not exist in the tenant's organization.
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+	errEmptyWebhook = errors.New("empty webhook")
+)
+
+// WebhookStore manages the webhooks of organizations and the deliveries of events to them.
+type WebhookStore interface {
+	CreateWebhook(ctx context.Context, w *Webhook) (*Webhook, error)
+	RetrieveWebhook(ctx context.Context, id string) (*Webhook, error)
+	ListWebhooks(ctx context.Context) ([]*Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	EnqueueWebhookDeliveries(ctx context.Context, e *OutboxEvent, organizationID string) (int64, error)
+	DueWebhookDeliveries(ctx context.Context, limit int) ([]*WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, d *WebhookDelivery) error
+	ListWebhookDeliveries(ctx context.Context, webhookID string, opts WebhookDeliveryOptions) (*WebhookDeliveryPage, error)
+	ReplayWebhookDelivery(ctx context.Context, id string) (*WebhookDelivery, error)
+}
+
+// Webhook is an endpoint of an organization that the events in EventTypes are posted to.
+type Webhook struct {
+	ID             string   `json:"id"`
+	OrganizationID string   `json:"organization_id"`
+	URL            string   `json:"url"`
+	EventTypes     []string `json:"event_types"`
+	// Secret signs the deliveries, it is only returned when the webhook is created.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is an event posted, or to be posted, to a webhook.
+type WebhookDelivery struct {
+	ID        string `json:"id"`
+	WebhookID string `json:"webhook_id"`
+	EventID   string `json:"event_id"`
+	EventType string `json:"event_type"`
+	// UserID is the user the event is about.
+	UserID     string          `json:"user_id"`
+	Payload    json.RawMessage `json:"payload" swaggertype:"object"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Status     string          `json:"status"`
+	Attempts   int             `json:"attempts"`
+	// ResponseCode is the HTTP status of the last attempt, 0 when it got no response.
+	ResponseCode int    `json:"response_code"`
+	LastError    string `json:"last_error,omitempty"`
+	// NextAttemptAt is when a pending delivery is attempted next.
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	// URL and Secret are the webhook's, they are only read by DueWebhookDeliveries.
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+// WebhookDeliveryOptions is the page of deliveries ListWebhookDeliveries returns.
+type WebhookDeliveryOptions struct {
+	// First is the size of the page, DefaultPageSize when 0 and never more than MaxPageSize.
+	First int
+	// After is the cursor of the delivery the page starts after.
+	After string
+}
+
+// WebhookDeliveryPage is a page of deliveries, newest first, and where the next one starts.
+type WebhookDeliveryPage struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+	// NextCursor is the cursor of the last delivery, it is empty on the last page.
+	NextCursor  string `json:"next_cursor,omitempty"`
+	HasNextPage bool   `json:"has_next_page"`
+}
+
+// WebhookDeliveryCursor returns the cursor of d, it is passed as
+// WebhookDeliveryOptions.After to list the deliveries created before d.
+func WebhookDeliveryCursor(d *WebhookDelivery) string {
+	return encodeTimeCursor(d.CreatedAt, d.ID)
+}
+
+const webhookColumns = `w.id, w.organization_id, w.url, w.event_types, w.created_by, w.created_at`
+
+const deliveryColumns = `d.id, d.webhook_id, d.event_id, d.event_type, d.user_id, d.payload, d.occurred_at,
+d.status, d.attempts, d.response_code, d.last_error, d.next_attempt_at, d.delivered_at, d.created_at`
+
+// tenantWebhooks returns the condition that restricts webhooks, aliased w, to
+// the tenant's organization.
+func tenantWebhooks(ctx context.Context) (string, []any) {
+	if platformTenant(ctx) {
+		return "TRUE", nil
+	}
+
+	t, _ := TenantFromContext(ctx)
+	return `w.organization_id = ?`, []any{t.OrganizationID}
+}
+
+// CreateWebhook saves a webhook of the tenant's organization, only platform
+// administrators can create one for another organization.
+func (m *MYSQL) CreateWebhook(ctx context.Context, w *Webhook) (*Webhook, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+	if w == nil {
+		return nil, errEmptyWebhook
+	}
+
+	if t, ok := TenantFromContext(ctx); ok && (w.OrganizationID == "" || !t.Platform) {
+		w.OrganizationID = t.OrganizationID
+	}
+	if w.OrganizationID == "" {
+		return nil, ErrOrganizationNotFound
+	}
+	eventTypes, err := json.Marshal(w.EventTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	w.ID = uuid.New().String()
+	w.CreatedAt = time.Now().UTC()
+	_, err = tx.ExecContext(ctx, `INSERT INTO webhooks (id, organization_id, url, secret, event_types, created_by, created_at)
+	VALUES (?, ?, ?, ?, ?, ?, ?)`, w.ID, w.OrganizationID, w.URL, w.Secret, string(eventTypes), w.CreatedBy, w.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	err = recordAudit(ctx, tx, &AuditEvent{
+		OrganizationID: w.OrganizationID,
+		Action:         AuditWebhookCreated,
+		TargetType:     AuditTargetWebhook,
+		TargetID:       w.ID,
+		After:          auditValue(map[string]any{"url": w.URL, "event_types": w.EventTypes}),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// RetrieveWebhook fetches a webhook of the tenant's organization without its
+// secret, it will return ErrWebhookNotFound if there is none.
+func (m *MYSQL) RetrieveWebhook(ctx context.Context, id string) (*Webhook, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	scope, scopeArgs := tenantWebhooks(ctx)
+	webhooks, err := m.webhooks(ctx, `SELECT `+webhookColumns+` FROM webhooks w WHERE w.id = ? AND `+scope,
+		append([]any{id}, scopeArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	if len(webhooks) == 0 {
+		return nil, ErrWebhookNotFound
+	}
+
+	return webhooks[0], nil
+}
+
+// ListWebhooks returns the webhooks of the tenant's organization without their secrets, newest first.
+func (m *MYSQL) ListWebhooks(ctx context.Context) ([]*Webhook, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	scope, scopeArgs := tenantWebhooks(ctx)
+	return m.webhooks(ctx, `SELECT `+webhookColumns+` FROM webhooks w WHERE `+scope+
+		` ORDER BY w.created_at DESC, w.id DESC`, scopeArgs...)
+}
+
+func (m *MYSQL) webhooks(ctx context.Context, query string, args ...any) ([]*Webhook, error) {
+	rows, err := m.Conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []*Webhook{}
+	for rows.Next() {
+		w := &Webhook{}
+		var eventTypes []byte
+		if err := rows.Scan(&w.ID, &w.OrganizationID, &w.URL, &eventTypes, &w.CreatedBy, &w.CreatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(eventTypes, &w.EventTypes); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+
+	return webhooks, rows.Err()
+}
+
+// DeleteWebhook removes a webhook of the tenant's organization along with its deliveries.
+func (m *MYSQL) DeleteWebhook(ctx context.Context, id string) error {
+	if m.Conn == nil {
+		return errEmptyDBConnection
+	}
+
+	tx, err := m.Conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	scope, scopeArgs := tenantWebhooks(ctx)
+	result, err := tx.ExecContext(ctx, `DELETE w FROM webhooks w WHERE w.id = ? AND `+scope,
+		append([]any{id}, scopeArgs...)...)
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrWebhookNotFound
+	}
+	err = recordAudit(ctx, tx, &AuditEvent{Action: AuditWebhookDeleted, TargetType: AuditTargetWebhook, TargetID: id})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// EnqueueWebhookDeliveries creates a pending delivery of e for every webhook
+// of organizationID subscribed to its type. An event is only delivered once
+// to a webhook however many times it is enqueued. It returns the number of
+// deliveries created.
+func (m *MYSQL) EnqueueWebhookDeliveries(ctx context.Context, e *OutboxEvent, organizationID string) (int64, error) {
+	if m.Conn == nil {
+		return 0, errEmptyDBConnection
+	}
+
+	now := time.Now().UTC()
+	result, err := m.Conn.ExecContext(ctx, `INSERT IGNORE INTO webhook_deliveries
+	(id, webhook_id, event_id, event_type, user_id, payload, occurred_at, status, next_attempt_at, created_at)
+	SELECT UUID(), w.id, ?, ?, ?, ?, ?, ?, ?, ? FROM webhooks w
+	WHERE JSON_CONTAINS(w.event_types, JSON_QUOTE(?)) AND w.organization_id = ?`,
+		e.ID, e.Type, e.AggregateID, string(e.Payload), e.OccurredAt.UTC(), DeliveryPending, now, now, e.Type, organizationID)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// DueWebhookDeliveries returns up to limit pending deliveries that are due,
+// with the URL and secret of their webhook, the ones due first first.
+func (m *MYSQL) DueWebhookDeliveries(ctx context.Context, limit int) ([]*WebhookDelivery, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	rows, err := m.Conn.QueryContext(ctx, `SELECT `+deliveryColumns+`, w.url, w.secret
+	FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+	WHERE d.status = ? AND d.next_attempt_at <= ? ORDER BY d.next_attempt_at LIMIT ?`,
+		DeliveryPending, time.Now().UTC(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows, true)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// scanDelivery reads the deliveryColumns of a row, followed by the URL and
+// secret of the webhook when withWebhook is set.
+func scanDelivery(rows *sql.Rows, withWebhook bool) (*WebhookDelivery, error) {
+	d := &WebhookDelivery{}
+	var payload []byte
+	var nextAttemptAt, deliveredAt sql.NullTime
+	dest := []any{&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.UserID, &payload, &d.OccurredAt,
+		&d.Status, &d.Attempts, &d.ResponseCode, &d.LastError, &nextAttemptAt, &deliveredAt, &d.CreatedAt}
+	if withWebhook {
+		dest = append(dest, &d.URL, &d.Secret)
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	d.Payload = json.RawMessage(payload)
+	if nextAttemptAt.Valid {
+		d.NextAttemptAt = &nextAttemptAt.Time
+	}
+	if deliveredAt.Valid {
+		d.DeliveredAt = &deliveredAt.Time
+	}
+	return d, nil
+}
+
+// UpdateWebhookDelivery saves the outcome of an attempt to post a delivery.
+func (m *MYSQL) UpdateWebhookDelivery(ctx context.Context, d *WebhookDelivery) error {
+	if m.Conn == nil {
+		return errEmptyDBConnection
+	}
+
+	_, err := m.Conn.ExecContext(ctx, `UPDATE webhook_deliveries SET status = ?, attempts = ?, response_code = ?,
+	last_error = ?, next_attempt_at = ?, delivered_at = ? WHERE id = ?`,
+		d.Status, d.Attempts, d.ResponseCode, truncate(d.LastError, 255), nullTime(d.NextAttemptAt), nullTime(d.DeliveredAt), d.ID)
+	return err
+}
+
+// nullTime stores a missing time as NULL.
+func nullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// ListWebhookDeliveries returns a page of the deliveries of a webhook of the
+// tenant's organization, newest first. It returns ErrWebhookNotFound when there is no such webhook.
+func (m *MYSQL) ListWebhookDeliveries(ctx context.Context, webhookID string, opts WebhookDeliveryOptions) (*WebhookDeliveryPage, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	first := opts.First
+	if first <= 0 {
+		first = DefaultPageSize
+	}
+	first = min(first, MaxPageSize)
+
+	condition := `d.webhook_id = ?`
+	args := []any{webhookID}
+	if opts.After != "" {
+		c, err := decodeTimeCursor(opts.After)
+		if err != nil {
+			return nil, err
+		}
+		condition += ` AND (d.created_at < ? OR (d.created_at = ? AND d.id < ?))`
+		args = append(args, c.CreatedAt.UTC(), c.CreatedAt.UTC(), c.ID)
+	}
+
+	if _, err := m.RetrieveWebhook(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	// one more delivery than asked for is read to know if there is a next page.
+	args = append(args, first+1)
+	rows, err := m.Conn.QueryContext(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries d WHERE `+
+		condition+` ORDER BY d.created_at DESC, d.id DESC LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows, false)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	page := &WebhookDeliveryPage{Deliveries: deliveries}
+	if len(deliveries) > first {
+		page.Deliveries = deliveries[:first]
+		page.HasNextPage = true
+		page.NextCursor = WebhookDeliveryCursor(page.Deliveries[first-1])
+	}
+
+	return page, nil
+}
+
+// ReplayWebhookDelivery makes a delivery of a webhook of the tenant's
+// organization pending again with no attempts, so it is posted straight
+// away and retried as if it were new. Deliveries that succeeded are posted again. It will return ErrDeliveryNotFound if there is none.
+func (m *MYSQL) ReplayWebhookDelivery(ctx context.Context, id string) (*WebhookDelivery, error) {
+	if m.Conn == nil {
+		return nil, errEmptyDBConnection
+	}
+
+	scope, scopeArgs := tenantWebhooks(ctx)
+	rows, err := m.Conn.QueryContext(ctx, `SELECT `+deliveryColumns+`
+	FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id WHERE d.id = ? AND `+scope,
+		append([]any{id}, scopeArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	var d *WebhookDelivery
+	if rows.Next() {
+		d, err = scanDelivery(rows, false)
+		if err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, ErrDeliveryNotFound
+	}
+
+	now := time.Now().UTC()
+	d.Status = DeliveryPending
+	d.Attempts = 0
+	d.LastError = ""
+	d.NextAttemptAt = &now
+	d.DeliveredAt = nil
+	if err := m.UpdateWebhookDelivery(ctx, d); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
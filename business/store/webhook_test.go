@@ -0,0 +1,317 @@
+package store
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var webhookColumnNames = []string{"id", "organization_id", "url", "event_types", "created_by", "created_at"}
+
+var deliveryColumnNames = []string{
+	"id", "webhook_id", "event_id", "event_type", "user_id", "payload", "occurred_at",
+	"status", "attempts", "response_code", "last_error", "next_attempt_at", "delivered_at", "created_at",
+}
+
+func deliveryRow(rows *sqlmock.Rows, id string, extra ...driver.Value) *sqlmock.Rows {
+	values := []driver.Value{id, "webhook-1", "event-1", "user.registered", "user-1", []byte(`{"user_id":"user-1"}`),
+		time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), DeliveryFailed, 10, 500, "unexpected status",
+		nil, nil, time.Date(2024, 1, 1, 10, 0, 1, 0, time.UTC)}
+	return rows.AddRow(append(values, extra...)...)
+}
+
+func TestDB_CreateWebhook(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		db          func() *MYSQL
+		tenant      *Tenant
+		expectedErr error
+	}{
+		{
+			name:        "empty connection",
+			db:          func() *MYSQL { return &MYSQL{} },
+			expectedErr: errEmptyDBConnection,
+		},
+		{
+			name: "no organization",
+			db: func() *MYSQL {
+				conn, _, err := sqlmock.New()
+				assert.NoError(t, err)
+				return NewDB(conn)
+			},
+			expectedErr: ErrOrganizationNotFound,
+		},
+		{
+			name: "created",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectBegin()
+				mock.ExpectExec(`INSERT INTO webhooks \(id, organization_id, url, secret, event_types, created_by, created_at\)`).
+					WithArgs(sqlmock.AnyArg(), "org-1", "https://example.com/hook", "0123456789abcdef",
+						`["user.registered","user.deleted"]`, "admin-1", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				expectAudit(mock, AuditWebhookCreated, sqlmock.AnyArg())
+				mock.ExpectCommit()
+				return NewDB(conn)
+			},
+			tenant: &testTenant,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if sc.tenant != nil {
+				ctx = WithTenant(ctx, *sc.tenant)
+			}
+			w, err := sc.db().CreateWebhook(ctx, &Webhook{
+				URL:        "https://example.com/hook",
+				EventTypes: []string{"user.registered", "user.deleted"},
+				Secret:     "0123456789abcdef",
+				CreatedBy:  "admin-1",
+			})
+			assert.Equal(t, sc.expectedErr, err)
+			if sc.expectedErr != nil {
+				return
+			}
+			assert.NotEmpty(t, w.ID)
+			assert.Equal(t, "org-1", w.OrganizationID)
+			assert.Equal(t, "0123456789abcdef", w.Secret)
+		})
+	}
+}
+
+func TestDB_ListWebhooks(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	mock.ExpectQuery(`SELECT w.id, w.organization_id, w.url, w.event_types, w.created_by, w.created_at FROM webhooks w\s+` +
+		`WHERE w.organization_id = \? ORDER BY w.created_at DESC, w.id DESC`).
+		WithArgs("org-1").
+		WillReturnRows(sqlmock.NewRows(webhookColumnNames).
+			AddRow("webhook-1", "org-1", "https://example.com/hook", []byte(`["user.registered"]`), "admin-1", time.Now()))
+
+	webhooks, err := NewDB(conn).ListWebhooks(WithTenant(context.Background(), testTenant))
+	require.NoError(t, err)
+	require.Len(t, webhooks, 1)
+	assert.Equal(t, []string{"user.registered"}, webhooks[0].EventTypes)
+	assert.Empty(t, webhooks[0].Secret)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDB_DeleteWebhook(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		affected    int64
+		expectedErr error
+	}{
+		{
+			name:        "not found",
+			expectedErr: ErrWebhookNotFound,
+		},
+		{
+			name:     "deleted",
+			affected: 1,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			mock.ExpectBegin()
+			mock.ExpectExec(`DELETE w FROM webhooks w WHERE w.id = \? AND w.organization_id = \?`).
+				WithArgs("webhook-1", "org-1").
+				WillReturnResult(sqlmock.NewResult(0, sc.affected))
+			if sc.affected > 0 {
+				expectAudit(mock, AuditWebhookDeleted, "webhook-1")
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			err = NewDB(conn).DeleteWebhook(WithTenant(context.Background(), testTenant), "webhook-1")
+			assert.Equal(t, sc.expectedErr, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDB_EnqueueWebhookDeliveries(t *testing.T) {
+	event := &OutboxEvent{
+		ID:          "event-1",
+		Type:        "role.assigned",
+		AggregateID: "user-1",
+		Payload:     json.RawMessage(`{"user_id":"user-1"}`),
+		OccurredAt:  time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+	}
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	mock.ExpectExec(`INSERT IGNORE INTO webhook_deliveries[\s\S]+`+
+		`WHERE JSON_CONTAINS\(w.event_types, JSON_QUOTE\(\?\)\) AND w.organization_id = \?`).
+		WithArgs("event-1", "role.assigned", "user-1", `{"user_id":"user-1"}`, event.OccurredAt,
+			DeliveryPending, sqlmock.AnyArg(), sqlmock.AnyArg(), "role.assigned", "org-1").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	created, err := NewDB(conn).EnqueueWebhookDeliveries(context.Background(), event, "org-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), created)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDB_DueWebhookDeliveries(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	mock.ExpectQuery(`FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id\s+`+
+		`WHERE d.status = \? AND d.next_attempt_at <= \? ORDER BY d.next_attempt_at LIMIT \?`).
+		WithArgs(DeliveryPending, sqlmock.AnyArg(), 50).
+		WillReturnRows(deliveryRow(sqlmock.NewRows(append(deliveryColumnNames, "url", "secret")), "delivery-1",
+			"https://example.com/hook", "0123456789abcdef"))
+
+	deliveries, err := NewDB(conn).DueWebhookDeliveries(context.Background(), 50)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, "https://example.com/hook", deliveries[0].URL)
+	assert.Equal(t, "0123456789abcdef", deliveries[0].Secret)
+	assert.JSONEq(t, `{"user_id":"user-1"}`, string(deliveries[0].Payload))
+	assert.Nil(t, deliveries[0].DeliveredAt)
+}
+
+func TestDB_ListWebhookDeliveries(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		db          func() *MYSQL
+		opts        WebhookDeliveryOptions
+		expectedIDs []string
+		hasNext     bool
+		expectedErr error
+	}{
+		{
+			name: "malformed cursor",
+			db: func() *MYSQL {
+				conn, _, err := sqlmock.New()
+				assert.NoError(t, err)
+				return NewDB(conn)
+			},
+			opts:        WebhookDeliveryOptions{After: "not a cursor"},
+			expectedErr: ErrInvalidCursor,
+		},
+		{
+			name: "webhook not found",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`FROM webhooks w WHERE w.id = \?`).WillReturnRows(sqlmock.NewRows(webhookColumnNames))
+				return NewDB(conn)
+			},
+			expectedErr: ErrWebhookNotFound,
+		},
+		{
+			name: "more pages",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`FROM webhooks w WHERE w.id = \?`).
+					WillReturnRows(sqlmock.NewRows(webhookColumnNames).
+						AddRow("webhook-1", "org-1", "https://example.com/hook", []byte(`["user.registered"]`), "admin-1", time.Now()))
+				rows := sqlmock.NewRows(deliveryColumnNames)
+				for _, id := range []string{"1", "2", "3"} {
+					deliveryRow(rows, id)
+				}
+				mock.ExpectQuery(`FROM webhook_deliveries d WHERE d.webhook_id = \? ORDER BY d.created_at DESC, d.id DESC LIMIT \?`).
+					WithArgs("webhook-1", 3).
+					WillReturnRows(rows)
+				return NewDB(conn)
+			},
+			opts:        WebhookDeliveryOptions{First: 2},
+			expectedIDs: []string{"1", "2"},
+			hasNext:     true,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			page, err := sc.db().ListWebhookDeliveries(context.Background(), "webhook-1", sc.opts)
+			assert.Equal(t, sc.expectedErr, err)
+			if sc.expectedErr != nil {
+				return
+			}
+
+			ids := make([]string, 0, len(page.Deliveries))
+			for _, d := range page.Deliveries {
+				ids = append(ids, d.ID)
+			}
+			assert.Equal(t, sc.expectedIDs, ids)
+			assert.Equal(t, sc.hasNext, page.HasNextPage)
+			assert.Equal(t, WebhookDeliveryCursor(page.Deliveries[len(page.Deliveries)-1]), page.NextCursor)
+		})
+	}
+}
+
+func TestDB_ReplayWebhookDelivery(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		db          func() *MYSQL
+		expectedErr error
+	}{
+		{
+			name:        "empty connection",
+			db:          func() *MYSQL { return &MYSQL{} },
+			expectedErr: errEmptyDBConnection,
+		},
+		{
+			name: "not found",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id WHERE d.id = \? AND w.organization_id = \?`).
+					WithArgs("delivery-1", "org-1").
+					WillReturnRows(sqlmock.NewRows(deliveryColumnNames))
+				return NewDB(conn)
+			},
+			expectedErr: ErrDeliveryNotFound,
+		},
+		{
+			name: "update failed",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`FROM webhook_deliveries d JOIN webhooks w`).
+					WillReturnRows(deliveryRow(sqlmock.NewRows(deliveryColumnNames), "delivery-1"))
+				mock.ExpectExec(`UPDATE webhook_deliveries`).WillReturnError(errors.New("update error"))
+				return NewDB(conn)
+			},
+			expectedErr: errors.New("update error"),
+		},
+		{
+			name: "replayed",
+			db: func() *MYSQL {
+				conn, mock, err := sqlmock.New()
+				assert.NoError(t, err)
+				mock.ExpectQuery(`FROM webhook_deliveries d JOIN webhooks w`).
+					WillReturnRows(deliveryRow(sqlmock.NewRows(deliveryColumnNames), "delivery-1"))
+				mock.ExpectExec(`UPDATE webhook_deliveries SET status = \?, attempts = \?, response_code = \?,\s+`+
+					`last_error = \?, next_attempt_at = \?, delivered_at = \? WHERE id = \?`).
+					WithArgs(DeliveryPending, 0, 500, "", sqlmock.AnyArg(), nil, "delivery-1").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				return NewDB(conn)
+			},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			d, err := sc.db().ReplayWebhookDelivery(WithTenant(context.Background(), testTenant), "delivery-1")
+			assert.Equal(t, sc.expectedErr, err)
+			if sc.expectedErr != nil {
+				return
+			}
+			assert.Equal(t, DeliveryPending, d.Status)
+			assert.Zero(t, d.Attempts)
+			assert.NotNil(t, d.NextAttemptAt)
+		})
+	}
+}
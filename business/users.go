@@ -0,0 +1,112 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/business/validation"
+)
+
+var (
+	// ErrEncryptPassword is returned when the password of a new user cannot be encrypted.
+	ErrEncryptPassword = errors.New("failed to encrypt password")
+
+	// ErrInvalidRole is returned when a user is given a role other than a built-in one.
+	ErrInvalidRole = errors.New("role must be admin or user")
+)
+
+// Users creates users and manages their built-in role and whether they are
+// active, every API goes through it so they all behave the same.
+type Users struct {
+	Store  store.Store
+	Logger *logrus.Logger
+}
+
+// NewUsers creates Users.
+func NewUsers(st store.Store, logger *logrus.Logger) *Users {
+	return &Users{
+		Store:  st,
+		Logger: logger,
+	}
+}
+
+// Create validates u, encrypts its password and saves it. createdBy is the
+// administrator creating the user, empty when users register themselves.
+func (us *Users) Create(ctx context.Context, u *store.User, createdBy string) (*store.User, error) {
+	if err := validation.ValidateUser(u); err != nil {
+		return nil, err
+	}
+	u.CreatedBy = createdBy
+
+	password, err := EncryptPassword(u.Password)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEncryptPassword, err)
+	}
+	u.Password = password
+
+	created, err := us.Store.Insert(ctx, u)
+	if err != nil {
+		if !errors.Is(err, store.ErrDuplicateEmail) {
+			us.Logger.Errorf("failed to save user: %v", err)
+		}
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// Role returns the most privileged built-in role assigned to a user of the tenant.
+func (us *Users) Role(ctx context.Context, userID string) (string, error) {
+	user, err := us.Store.Retrieve(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return "", store.ErrUserNotFound
+	}
+
+	return BuiltInRole(user.Roles), nil
+}
+
+// AssignRole gives a user one of the built-in roles. They replace each other,
+// so demoting a user to user takes admin away.
+func (us *Users) AssignRole(ctx context.Context, userID, role string) error {
+	if role != authz.RoleAdmin && role != authz.RoleUser {
+		return ErrInvalidRole
+	}
+
+	var err error
+	if role == authz.RoleUser {
+		err = us.Store.ReplaceRole(ctx, userID, role, authz.RoleAdmin)
+	} else {
+		err = us.Store.AssignRole(ctx, userID, role)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to assign role: %w", err)
+	}
+
+	return nil
+}
+
+// SetActive activates or deactivates a user of the tenant, it reports
+// whether the user was changed, users already in that state are left alone.
+func (us *Users) SetActive(ctx context.Context, userID string, active bool) (bool, error) {
+	return us.Store.SetActive(ctx, userID, active)
+}
+
+// BuiltInRole picks the most privileged built-in role out of the roles assigned to a user.
+func BuiltInRole(roles []string) string {
+	if slices.Contains(roles, authz.RoleSuperAdmin) {
+		return authz.RoleSuperAdmin
+	}
+	if slices.Contains(roles, authz.RoleAdmin) {
+		return authz.RoleAdmin
+	}
+	return authz.RoleUser
+}
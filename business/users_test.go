@@ -0,0 +1,219 @@
+package business
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/authz"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/business/validation"
+)
+
+func newUser() *store.User {
+	return &store.User{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "John@Example.com",
+		Password:  "secret",
+		Terms:     true,
+	}
+}
+
+func TestUsers_Create(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		user        *store.User
+		store       *mocks.Store
+		expectedErr error
+	}{
+		{
+			name:        "invalid",
+			user:        &store.User{FirstName: "John"},
+			store:       &mocks.Store{},
+			expectedErr: errors.New("missing last name\nmissing email\nplease select terms"),
+		},
+		{
+			name:        "duplicate email",
+			user:        newUser(),
+			store:       &mocks.Store{Error: store.ErrDuplicateEmail},
+			expectedErr: store.ErrDuplicateEmail,
+		},
+		{
+			name:        "store error",
+			user:        newUser(),
+			store:       &mocks.Store{Error: errors.New("db down")},
+			expectedErr: errors.New("db down"),
+		},
+		{
+			name:  "created",
+			user:  newUser(),
+			store: &mocks.Store{User: &store.User{ID: "user-1"}},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			created, err := NewUsers(sc.store, logrus.New()).Create(context.Background(), sc.user, "admin-1")
+			if sc.expectedErr != nil {
+				assert.EqualError(t, err, sc.expectedErr.Error())
+				assert.Nil(t, created)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "user-1", created.ID)
+			assert.Equal(t, "admin-1", sc.user.CreatedBy)
+			assert.Equal(t, "john@example.com", sc.user.Email)
+			assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(sc.user.Password), []byte("secret")))
+		})
+	}
+}
+
+func TestUsers_Create_Invalid(t *testing.T) {
+	_, err := NewUsers(&mocks.Store{}, logrus.New()).Create(context.Background(), &store.User{}, "")
+	assert.True(t, validation.IsInvalid(err))
+}
+
+func TestUsers_Role(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		store        *mocks.Store
+		expectedRole string
+		expectedErr  error
+	}{
+		{
+			name:        "store error",
+			store:       &mocks.Store{Error: errors.New("db down")},
+			expectedErr: errors.New("db down"),
+		},
+		{
+			name:        "not found",
+			store:       &mocks.Store{},
+			expectedErr: store.ErrUserNotFound,
+		},
+		{
+			name:         "admin",
+			store:        &mocks.Store{User: &store.User{Roles: []string{authz.RoleUser, authz.RoleAdmin}}},
+			expectedRole: authz.RoleAdmin,
+		},
+		{
+			name:         "superadmin",
+			store:        &mocks.Store{User: &store.User{Roles: []string{authz.RoleAdmin, authz.RoleSuperAdmin}}},
+			expectedRole: authz.RoleSuperAdmin,
+		},
+		{
+			name:         "user",
+			store:        &mocks.Store{User: &store.User{Roles: []string{"support"}}},
+			expectedRole: authz.RoleUser,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			role, err := NewUsers(sc.store, logrus.New()).Role(context.Background(), "user-1")
+			assert.Equal(t, sc.expectedErr, err)
+			assert.Equal(t, sc.expectedRole, role)
+		})
+	}
+}
+
+func TestUsers_AssignRole(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		role         string
+		store        *mocks.Store
+		expectedErr  error
+		expectedSwap string
+	}{
+		{
+			name:        "not built-in",
+			role:        "support",
+			store:       &mocks.Store{},
+			expectedErr: ErrInvalidRole,
+		},
+		{
+			name:        "superadmin",
+			role:        authz.RoleSuperAdmin,
+			store:       &mocks.Store{},
+			expectedErr: ErrInvalidRole,
+		},
+		{
+			name:        "not found",
+			role:        authz.RoleAdmin,
+			store:       &mocks.Store{Error: store.ErrUserNotFound},
+			expectedErr: store.ErrUserNotFound,
+		},
+		{
+			name:        "role not found",
+			role:        authz.RoleUser,
+			store:       &mocks.Store{Error: store.ErrRoleNotFound},
+			expectedErr: store.ErrRoleNotFound,
+		},
+		{
+			name:  "assigned",
+			role:  authz.RoleAdmin,
+			store: &mocks.Store{},
+		},
+		{
+			name:         "demoted",
+			role:         authz.RoleUser,
+			store:        &mocks.Store{},
+			expectedSwap: authz.RoleAdmin,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			err := NewUsers(sc.store, logrus.New()).AssignRole(context.Background(), "user-1", sc.role)
+			if sc.expectedErr == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, sc.expectedSwap, sc.store.Replaced)
+				return
+			}
+			assert.ErrorIs(t, err, sc.expectedErr)
+		})
+	}
+}
+
+func TestUsers_SetActive(t *testing.T) {
+	scenarios := []struct {
+		name            string
+		store           *mocks.Store
+		active          bool
+		expectedChanged bool
+		expectedErr     error
+	}{
+		{
+			name:        "not found",
+			store:       &mocks.Store{},
+			active:      true,
+			expectedErr: store.ErrUserNotFound,
+		},
+		{
+			name:   "already active",
+			store:  &mocks.Store{User: &store.User{Active: true}},
+			active: true,
+		},
+		{
+			name:            "deactivated",
+			store:           &mocks.Store{User: &store.User{Active: true}},
+			expectedChanged: true,
+		},
+		{
+			name:            "activated",
+			store:           &mocks.Store{User: &store.User{}},
+			active:          true,
+			expectedChanged: true,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			changed, err := NewUsers(sc.store, logrus.New()).SetActive(context.Background(), "user-1", sc.active)
+			assert.Equal(t, sc.expectedErr, err)
+			assert.Equal(t, sc.expectedChanged, changed)
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package validation
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/idna"
+
+	"github.com/riyadennis/identity-server/business/store"
+)
+
+const (
+	// maxEmailLength is the longest address that fits in an SMTP path.
+	maxEmailLength = 254
+	// maxLocalPartLength is the longest local part allowed by RFC 5321.
+	maxLocalPartLength = 64
+	// maxDomainLength is the longest domain name allowed in DNS.
+	maxDomainLength = 253
+	// maxLabelLength is the longest label of a domain name allowed in DNS.
+	maxLabelLength = 63
+)
+
+var (
+	errMissingAt          = fmt.Errorf("%w: missing @", errInvalidEmail)
+	errEmailTooLong       = fmt.Errorf("%w: longer than %d characters", errInvalidEmail, maxEmailLength)
+	errMissingLocalPart   = fmt.Errorf("%w: missing the part before @", errInvalidEmail)
+	errLocalPartTooLong   = fmt.Errorf("%w: part before @ is longer than %d characters", errInvalidEmail, maxLocalPartLength)
+	errInvalidLocalPart   = fmt.Errorf("%w: part before @ has invalid characters or dots", errInvalidEmail)
+	errMissingDomain      = fmt.Errorf("%w: missing domain", errInvalidEmail)
+	errInvalidDomain      = fmt.Errorf("%w: invalid domain", errInvalidEmail)
+	errDisposableDomain   = fmt.Errorf("%w: disposable email domains are not allowed", errInvalidEmail)
+	errDomainNotQualified = fmt.Errorf("%w: domain needs a top level domain", errInvalidEmail)
+)
+
+// disposable has the domains of throwaway email services that can not be used.
+var disposable = struct {
+	sync.RWMutex
+	domains map[string]struct{}
+}{}
+
+// ValidateEmail checks email is an address ParseEmail accepts.
+func ValidateEmail(email string) error {
+	_, err := ParseEmail(email)
+	return err
+}
+
+// ParseUserEmail is ParseEmail for an address that is stored on a user, so
+// it also has to fit in the email column of identity_users.
+func ParseUserEmail(email string) (string, error) {
+	email, err := ParseEmail(email)
+	if err != nil {
+		return "", err
+	}
+	if err := lengthError("email", email); err != nil {
+		return "", err
+	}
+	return email, nil
+}
+
+// ParseEmail checks an address users register or change to has an RFC 5321
+// local part and a domain that is not disposable, and returns it in the form
+// store.NormalizeEmail stores it in. Logins only normalize the email, so
+// addresses accepted before a rule was added keep working.
+func ParseEmail(email string) (string, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return "", errMissingEmail
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return "", errMissingAt
+	}
+	local, domain := email[:at], email[at+1:]
+
+	if err := validLocalPart(local); err != nil {
+		return "", err
+	}
+	domain, err := normalizeDomain(domain)
+	if err != nil {
+		return "", err
+	}
+	if disposableDomain(domain) {
+		return "", errDisposableDomain
+	}
+
+	normalized := store.NormalizeEmail(email)
+	if len(normalized) > maxEmailLength {
+		return "", errEmailTooLong
+	}
+
+	return normalized, nil
+}
+
+// LoadDisposableDomains reads the domains emails can not be registered with
+// from a file with one domain per line, lines starting with # are ignored.
+// Nothing is loaded for an empty path.
+func LoadDisposableDomains(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	domains := make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domain, err := normalizeDomain(line)
+		if err != nil {
+			return fmt.Errorf("disposable domain %q: %w", line, err)
+		}
+		domains[domain] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	disposable.Lock()
+	disposable.domains = domains
+	disposable.Unlock()
+	return nil
+}
+
+// validLocalPart checks local is a dot-string or a quoted-string.
+func validLocalPart(local string) error {
+	if local == "" {
+		return errMissingLocalPart
+	}
+	if len(local) > maxLocalPartLength {
+		return errLocalPartTooLong
+	}
+
+	if strings.HasPrefix(local, `"`) {
+		if !quotedString(local) {
+			return errInvalidLocalPart
+		}
+		return nil
+	}
+
+	for _, atom := range strings.Split(local, ".") {
+		if atom == "" {
+			return errInvalidLocalPart
+		}
+		for i := 0; i < len(atom); i++ {
+			if !atext(atom[i]) {
+				return errInvalidLocalPart
+			}
+		}
+	}
+	return nil
+}
+
+// quotedString reports whether s is enclosed in quotes with only printable
+// characters in between, quotes and backslashes have to be escaped.
+func quotedString(s string) bool {
+	if len(s) < 2 || s[len(s)-1] != '"' {
+		return false
+	}
+	content := s[1 : len(s)-1]
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '\\':
+			i++
+			if i == len(content) || content[i] < ' ' || content[i] > '~' {
+				return false
+			}
+		case c == '"' || c < ' ' || c > '~':
+			return false
+		}
+	}
+	return true
+}
+
+// atext reports whether c can be used in an atom of a dot-string.
+func atext(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$%&'*+-/=?^_`{|}~", c) >= 0
+}
+
+// normalizeDomain converts an internationalized domain to lower case
+// punycode and checks it can be looked up.
+func normalizeDomain(domain string) (string, error) {
+	if domain == "" {
+		return "", errMissingDomain
+	}
+
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil || len(ascii) > maxDomainLength {
+		return "", errInvalidDomain
+	}
+
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 {
+		return "", errDomainNotQualified
+	}
+	for _, label := range labels {
+		if !validLabel(label) {
+			return "", errInvalidDomain
+		}
+	}
+	if strings.Trim(labels[len(labels)-1], "0123456789") == "" {
+		return "", errDomainNotQualified
+	}
+
+	return ascii, nil
+}
+
+// validLabel reports whether label only has letters, digits and hyphens that
+// are not at either end.
+func validLabel(label string) bool {
+	if label == "" || len(label) > maxLabelLength || label[0] == '-' || label[len(label)-1] == '-' {
+		return false
+	}
+	for i := 0; i < len(label); i++ {
+		c := label[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// disposableDomain reports whether domain or one of its parents is in the
+// list of disposable domains.
+func disposableDomain(domain string) bool {
+	disposable.RLock()
+	defer disposable.RUnlock()
+	for {
+		if _, ok := disposable.domains[domain]; ok {
+			return true
+		}
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 {
+			return false
+		}
+		domain = domain[dot+1:]
+	}
+}
@@ -0,0 +1,163 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEmail(t *testing.T) {
+	scenarios := []struct {
+		name          string
+		email         string
+		expected      string
+		expectedError error
+	}{
+		{
+			name:          "empty",
+			email:         " ",
+			expectedError: errMissingEmail,
+		},
+		{
+			name:          "missing at",
+			email:         "john.doe",
+			expectedError: errMissingAt,
+		},
+		{
+			name:          "missing local part",
+			email:         "@example.com",
+			expectedError: errMissingLocalPart,
+		},
+		{
+			name:          "local part too long",
+			email:         strings.Repeat("a", 65) + "@example.com",
+			expectedError: errLocalPartTooLong,
+		},
+		{
+			name:          "consecutive dots",
+			email:         "john..doe@example.com",
+			expectedError: errInvalidLocalPart,
+		},
+		{
+			name:          "leading dot",
+			email:         ".john@example.com",
+			expectedError: errInvalidLocalPart,
+		},
+		{
+			name:          "invalid character",
+			email:         "john doe@example.com",
+			expectedError: errInvalidLocalPart,
+		},
+		{
+			name:          "unterminated quotes",
+			email:         `"john@example.com`,
+			expectedError: errInvalidLocalPart,
+		},
+		{
+			name:          "missing domain",
+			email:         "john@",
+			expectedError: errMissingDomain,
+		},
+		{
+			name:          "missing top level domain",
+			email:         "john@localhost",
+			expectedError: errDomainNotQualified,
+		},
+		{
+			name:          "numeric top level domain",
+			email:         "john@127.0.0.1",
+			expectedError: errDomainNotQualified,
+		},
+		{
+			name:          "invalid domain",
+			email:         "john@-example.com",
+			expectedError: errInvalidDomain,
+		},
+		{
+			name:          "underscore in domain",
+			email:         "john@my_domain.com",
+			expectedError: errInvalidDomain,
+		},
+		{
+			name:          "too long",
+			email:         strings.Repeat("a", 64) + "@" + strings.Repeat(strings.Repeat("a", 50)+".", 4) + "com",
+			expectedError: errEmailTooLong,
+		},
+		{
+			name:     "upper case",
+			email:    " John.Doe@Example.COM ",
+			expected: "john.doe@example.com",
+		},
+		{
+			name:     "long top level domain",
+			email:    "curator@example.museum",
+			expected: "curator@example.museum",
+		},
+		{
+			name:     "special characters",
+			email:    "o'neil+news/tag=1@example.com",
+			expected: "o'neil+news/tag=1@example.com",
+		},
+		{
+			name:     "quoted local part",
+			email:    `"john \"jd\" doe"@example.com`,
+			expected: `"john \"jd\" doe"@example.com`,
+		},
+		{
+			name:     "internationalized domain",
+			email:    "hans@Bücher.de",
+			expected: "hans@xn--bcher-kva.de",
+		},
+		{
+			name:     "internationalized domain in punycode",
+			email:    "hans@xn--bcher-kva.de",
+			expected: "hans@xn--bcher-kva.de",
+		},
+		{
+			name:          "non ascii local part",
+			email:         "jürgen@example.com",
+			expectedError: errInvalidLocalPart,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			email, err := ParseEmail(sc.email)
+			assert.Equal(t, sc.expectedError, err)
+			assert.Equal(t, sc.expected, email)
+		})
+	}
+}
+
+func TestParseUserEmail(t *testing.T) {
+	long := strings.Repeat("a", 60) + "@" + strings.Repeat("b", 55) + ".example"
+
+	_, err := ParseEmail(long)
+	require.NoError(t, err)
+	_, err = ParseUserEmail(long)
+	assert.ErrorIs(t, err, errTooLong)
+	assert.True(t, IsInvalid(err))
+
+	_, err = ParseUserEmail("john.doe")
+	assert.Equal(t, errMissingAt, err)
+
+	email, err := ParseUserEmail(" John@Example.com ")
+	require.NoError(t, err)
+	assert.Equal(t, "john@example.com", email)
+}
+
+func TestLoadDisposableDomains(t *testing.T) {
+	t.Cleanup(func() { disposable.domains = nil })
+
+	require.NoError(t, LoadDisposableDomains(""))
+	assert.NoError(t, ValidateEmail("john@mailinator.com"))
+
+	assert.Error(t, LoadDisposableDomains("testdata/missing.txt"))
+	require.NoError(t, LoadDisposableDomains("testdata/disposable_domains.txt"))
+
+	assert.Equal(t, errDisposableDomain, ValidateEmail("john@mailinator.com"))
+	assert.Equal(t, errDisposableDomain, ValidateEmail("john@eu.Mailinator.com"))
+	assert.Equal(t, errDisposableDomain, ValidateEmail("john@trash-mail.example"))
+	assert.NoError(t, ValidateEmail("john@example.com"))
+}
@@ -2,13 +2,19 @@ package validation
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
 	"os"
-	"regexp"
 	"time"
+	"unicode/utf8"
+	// embeds the time zone database so timezones validate without one installed.
+	_ "time/tzdata"
 
 	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/text/language"
 
 	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
 )
 
 var (
@@ -18,6 +24,12 @@ var (
 	errMissingEmail     = errors.New("missing email")
 	errInvalidEmail     = errors.New("invalid email")
 	errTermsMissing     = errors.New("please select terms")
+	errTooLong          = errors.New("too long")
+
+	errEmptyProfile      = errors.New("no profile details to update")
+	errInvalidLocale     = errors.New("invalid locale")
+	errInvalidTimezone   = errors.New("invalid timezone")
+	errInvalidPictureURL = errors.New("invalid picture url")
 
 	errMissingToken       = errors.New("missing token in header")
 	errMissingBearerToken = errors.New("missing bearer token in header")
@@ -26,57 +38,160 @@ var (
 	errInvalidTokenMethod = errors.New("invalid token method")
 )
 
+// invalid are the errors about the details of a request, the token errors
+// are left out as they are about who is making it.
+var invalid = []error{
+	errEmptyUser, errMissingFirstName, errMissingLastName, errMissingEmail, errInvalidEmail, errTermsMissing, errTooLong,
+	errEmptyProfile, errInvalidLocale, errInvalidTimezone, errInvalidPictureURL,
+}
+
+// IsInvalid reports whether err is from validating the details of a request,
+// so it can be shown to the client as it is.
+func IsInvalid(err error) bool {
+	for _, target := range invalid {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxLengths are the sizes in characters of the identity_users columns,
+// longer values are refused rather than left to fail in the database.
+var maxLengths = map[string]int{
+	"first_name":  100,
+	"last_name":   100,
+	"email":       120,
+	"company":     64,
+	"post_code":   64,
+	"locale":      35,
+	"timezone":    64,
+	"picture_url": 2048,
+}
+
+// checkLength returns an error when value is longer than the column of field.
+func checkLength(field, value string) error {
+	if err := lengthError(field, value); err != nil {
+		return fieldError(field, err)
+	}
+	return nil
+}
+
+// lengthError is the error of checkLength without the field it is about.
+func lengthError(field, value string) error {
+	if limit := maxLengths[field]; utf8.RuneCountInString(value) > limit {
+		return fmt.Errorf("%w, the most is %d characters", errTooLong, limit)
+	}
+	return nil
+}
+
 const (
 	// BearerSchema is expected prefix for token from authorisation header.
 	BearerSchema = "Bearer "
 )
 
-// ValidateUser checks registration request validity.
+// ValidateUser checks registration request validity and replaces the email
+// with its normalized form. Every field that failed is in the error.
 func ValidateUser(u *store.User) error {
 	if u == nil {
 		return errEmptyUser
 	}
+	var errs []error
 	if u.FirstName == "" {
-		return errMissingFirstName
+		errs = append(errs, fieldError("first_name", errMissingFirstName))
 	}
 	if u.LastName == "" {
-		return errMissingLastName
+		errs = append(errs, fieldError("last_name", errMissingLastName))
 	}
-	if u.Email == "" {
-		return errMissingEmail
-	}
-
-	if err := ValidateEmail(u.Email); err != nil {
-		return err
+	email, err := ParseUserEmail(u.Email)
+	if err != nil {
+		errs = append(errs, fieldError("email", err))
+	} else {
+		u.Email = email
+	}
+	for _, f := range []struct{ field, value string }{
+		{"first_name", u.FirstName}, {"last_name", u.LastName}, {"company", u.Company}, {"post_code", u.PostCode},
+	} {
+		if err := checkLength(f.field, f.value); err != nil {
+			errs = append(errs, err)
+		}
 	}
 	if !u.Terms {
-		return errTermsMissing
+		errs = append(errs, fieldError("terms", errTermsMissing))
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
-func ValidateEmail(email string) error {
-	re := regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,4}$`)
-	if !re.MatchString(email) {
-		return errInvalidEmail
+// ValidateProfile checks a profile update, names can not be removed while the
+// optional details are cleared with an empty value. Every field that failed
+// is in the error.
+func ValidateProfile(p *store.ProfileUpdate) error {
+	if p == nil || *p == (store.ProfileUpdate{}) {
+		return errEmptyProfile
+	}
+	var errs []error
+	if p.FirstName != nil && *p.FirstName == "" {
+		errs = append(errs, fieldError("first_name", errMissingFirstName))
+	}
+	if p.LastName != nil && *p.LastName == "" {
+		errs = append(errs, fieldError("last_name", errMissingLastName))
+	}
+	tooLong := map[string]bool{}
+	for _, f := range []struct {
+		field string
+		value *string
+	}{
+		{"first_name", p.FirstName}, {"last_name", p.LastName}, {"company", p.Company}, {"post_code", p.PostCode},
+		{"locale", p.Locale}, {"timezone", p.Timezone}, {"picture_url", p.PictureURL},
+	} {
+		if f.value == nil {
+			continue
+		}
+		if err := checkLength(f.field, *f.value); err != nil {
+			errs = append(errs, err)
+			tooLong[f.field] = true
+		}
+	}
+	if p.Locale != nil && *p.Locale != "" && !tooLong["locale"] {
+		if _, err := language.Parse(*p.Locale); err != nil {
+			errs = append(errs, fieldError("locale", errInvalidLocale))
+		}
+	}
+	if p.Timezone != nil && *p.Timezone != "" && !tooLong["timezone"] {
+		if _, err := time.LoadLocation(*p.Timezone); err != nil || *p.Timezone == "Local" {
+			errs = append(errs, fieldError("timezone", errInvalidTimezone))
+		}
+	}
+	if p.PictureURL != nil && *p.PictureURL != "" && !tooLong["picture_url"] {
+		u, err := url.Parse(*p.PictureURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fieldError("picture_url", errInvalidPictureURL))
+		}
 	}
 
-	return nil
+	return errors.Join(errs...)
+}
+
+// fieldError returns err as the failure of field, named as in the JSON of the request.
+func fieldError(field string, err error) error {
+	return &foundation.FieldError{Field: field, Err: err}
 }
 
-func ValidateToken(token string, tc *store.TokenConfig) (*jwt.RegisteredClaims, error) {
+func ValidateToken(token string, tc *store.TokenConfig) (*store.Claims, error) {
 	if token == "" {
 		return nil, errMissingToken
 	}
 
-	if token[len(BearerSchema):] == "" {
+	if len(token) <= len(BearerSchema) {
 		return nil, errMissingBearerToken
 	}
-	claims := &jwt.RegisteredClaims{
-		Issuer:    tc.Issuer,
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(tc.TokenTTL)),
+	claims := &store.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tc.Issuer,
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(tc.TokenTTL)),
+		},
 	}
 	t, err := jwt.ParseWithClaims(
 		token[len(BearerSchema):], claims, fetchKey(tc.KeyPath+tc.PublicKeyName),
@@ -87,7 +202,7 @@ func ValidateToken(token string, tc *store.TokenConfig) (*jwt.RegisteredClaims,
 	if !t.Valid {
 		return nil, errInvalidToken
 	}
-	claims, ok := t.Claims.(*jwt.RegisteredClaims)
+	claims, ok := t.Claims.(*store.Claims)
 	if !ok {
 		return nil, errInvalidToken
 	}
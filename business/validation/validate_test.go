@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
 )
 
 func TestValidateUser(t *testing.T) {
@@ -73,6 +75,27 @@ func TestValidateUser(t *testing.T) {
 			},
 			expectedError: nil,
 		},
+		{
+			name: "company too long",
+			user: &store.User{
+				FirstName: "John",
+				LastName:  "Doe",
+				Email:     "john.doe@test.com",
+				Company:   strings.Repeat("a", 65),
+				Terms:     true,
+			},
+			expectedError: errTooLong,
+		},
+		{
+			name: "uppercase email",
+			user: &store.User{
+				FirstName: "John",
+				LastName:  "Doe",
+				Email:     "John.Doe@Test.COM",
+				Terms:     true,
+			},
+			expectedError: nil,
+		},
 	}
 
 	for _, sc := range scenarios {
@@ -85,6 +108,114 @@ func TestValidateUser(t *testing.T) {
 	}
 }
 
+func TestValidateUser_EveryField(t *testing.T) {
+	err := ValidateUser(&store.User{LastName: "Doe", Email: "INVALID", Company: strings.Repeat("a", 65)})
+	for _, target := range []error{errMissingFirstName, errInvalidEmail, errTooLong, errTermsMissing} {
+		assert.ErrorIs(t, err, target)
+	}
+	assert.NotErrorIs(t, err, errMissingLastName)
+
+	var fields []string
+	for _, err := range err.(interface{ Unwrap() []error }).Unwrap() {
+		var fe *foundation.FieldError
+		if assert.ErrorAs(t, err, &fe) {
+			fields = append(fields, fe.Field)
+		}
+	}
+	assert.Equal(t, []string{"first_name", "email", "company", "terms"}, fields)
+}
+
+func TestValidateProfile(t *testing.T) {
+	value := func(s string) *string { return &s }
+	scenarios := []struct {
+		name          string
+		profile       *store.ProfileUpdate
+		expectedError error
+	}{
+		{
+			name:          "empty profile",
+			profile:       &store.ProfileUpdate{},
+			expectedError: errEmptyProfile,
+		},
+		{
+			name:          "removed first name",
+			profile:       &store.ProfileUpdate{FirstName: value("")},
+			expectedError: errMissingFirstName,
+		},
+		{
+			name:          "first name too long",
+			profile:       &store.ProfileUpdate{FirstName: value(strings.Repeat("é", 101))},
+			expectedError: errTooLong,
+		},
+		{
+			name:          "post code too long",
+			profile:       &store.ProfileUpdate{PostCode: value(strings.Repeat("1", 65))},
+			expectedError: errTooLong,
+		},
+		{
+			name:    "longest first name",
+			profile: &store.ProfileUpdate{FirstName: value(strings.Repeat("é", 100))},
+		},
+		{
+			name:          "invalid locale",
+			profile:       &store.ProfileUpdate{Locale: value("not a locale")},
+			expectedError: errInvalidLocale,
+		},
+		{
+			name:          "invalid timezone",
+			profile:       &store.ProfileUpdate{Timezone: value("Mars/Olympus")},
+			expectedError: errInvalidTimezone,
+		},
+		{
+			name:          "picture url without scheme",
+			profile:       &store.ProfileUpdate{PictureURL: value("example.com/jane.png")},
+			expectedError: errInvalidPictureURL,
+		},
+		{
+			name:          "picture url with unsupported scheme",
+			profile:       &store.ProfileUpdate{PictureURL: value("javascript://example.com/jane.png")},
+			expectedError: errInvalidPictureURL,
+		},
+		{
+			name: "valid profile",
+			profile: &store.ProfileUpdate{
+				FirstName:  value("Jane"),
+				Locale:     value("en-GB"),
+				Timezone:   value("Europe/London"),
+				PictureURL: value("https://example.com/jane.png"),
+			},
+		},
+		{
+			name:    "cleared details",
+			profile: &store.ProfileUpdate{Locale: value(""), Timezone: value(""), PictureURL: value("")},
+		},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			err := ValidateProfile(sc.profile)
+			if !errors.Is(err, sc.expectedError) {
+				t.Fatalf("expected err %v, got %v", sc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestValidateProfile_EveryField(t *testing.T) {
+	value := func(s string) *string { return &s }
+	err := ValidateProfile(&store.ProfileUpdate{
+		LastName:   value(""),
+		Locale:     value(strings.Repeat("a", 36)),
+		Timezone:   value("Mars/Olympus"),
+		PictureURL: value("example.com/jane.png"),
+	})
+	for _, target := range []error{errMissingLastName, errTooLong, errInvalidTimezone, errInvalidPictureURL} {
+		assert.ErrorIs(t, err, target)
+	}
+	// a locale that is too long is not parsed as well
+	assert.NotErrorIs(t, err, errInvalidLocale)
+}
+
 func TestValidateToken(t *testing.T) {
 	// privateKey, _ := loadTestKeys(t)
 	scenarios := []struct {
@@ -105,6 +236,12 @@ func TestValidateToken(t *testing.T) {
 			tokenConfig:   &store.TokenConfig{},
 			expectedError: errMissingBearerToken.Error(),
 		},
+		{
+			name:          "shorter than the scheme",
+			token:         "abc",
+			tokenConfig:   &store.TokenConfig{},
+			expectedError: errMissingBearerToken.Error(),
+		},
 		{
 			name:  "invalid token format",
 			token: "Bearer invalid.token.format",
@@ -192,8 +329,28 @@ func generateTestToken(t *testing.T, issuer string, ttl time.Time) string {
 
 	signedToken, err := store.GenerateToken(logrus.New(),
 		privateKeyData,
-		&jwt.RegisteredClaims{Issuer: issuer, ExpiresAt: jwt.NewNumericDate(ttl)})
+		&store.Claims{RegisteredClaims: jwt.RegisteredClaims{Issuer: issuer, ExpiresAt: jwt.NewNumericDate(ttl)}})
 	assert.NoError(t, err)
 
 	return "Bearer " + signedToken.AccessToken
 }
+
+func TestIsInvalid(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "missing name", err: errMissingFirstName, expected: true},
+		{name: "invalid email", err: errDisposableDomain, expected: true},
+		{name: "wrapped", err: fmt.Errorf("failed to register: %w", errInvalidTimezone), expected: true},
+		{name: "token", err: errInvalidToken},
+		{name: "other", err: errors.New("database down")},
+		{name: "nil"},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			assert.Equal(t, sc.expected, IsInvalid(sc.err))
+		})
+	}
+}
@@ -0,0 +1,394 @@
+package business
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/riyadennis/identity-server/business/events"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+const (
+	// WebhookInterval is how often the deliveries that are due are posted.
+	WebhookInterval = 10 * time.Second
+
+	// WebhookMaxAttempts is how many times a delivery is posted before it fails.
+	WebhookMaxAttempts = 10
+
+	// WebhookTolerance is how old the timestamp of a delivery can be for
+	// VerifyWebhookSignature to accept it.
+	WebhookTolerance = 5 * time.Minute
+
+	// webhookBaseDelay is the wait before the first retry of a delivery, it
+	// doubles with every attempt up to webhookMaxDelay.
+	webhookBaseDelay = time.Minute
+	webhookMaxDelay  = 6 * time.Hour
+
+	// webhookBatchSize is the most deliveries read at a time.
+	webhookBatchSize = 50
+
+	// webhookTimeout is how long a receiver has to answer a delivery.
+	webhookTimeout = 10 * time.Second
+
+	minWebhookSecretLength = 16
+)
+
+// Headers sent with every delivery.
+const (
+	// WebhookIDHeader is the ID of the event, receivers use it to drop events
+	// they already handled as a delivery can be posted more than once.
+	WebhookIDHeader        = "Webhook-Id"
+	WebhookEventHeader     = "Webhook-Event"
+	WebhookTimestampHeader = "Webhook-Timestamp"
+	// WebhookSignatureHeader is v1= followed by the hex HMAC-SHA256 of the
+	// timestamp, a dot and the body, keyed with the secret of the webhook.
+	WebhookSignatureHeader = "Webhook-Signature"
+)
+
+var (
+	// ErrInvalidSignature is returned by VerifyWebhookSignature when a delivery is not signed with the secret.
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	// ErrStaleDelivery is returned by VerifyWebhookSignature when the timestamp of a delivery is too old or in the future.
+	ErrStaleDelivery = errors.New("webhook timestamp is outside the tolerance")
+
+	errInvalidWebhookURL = errors.New("webhook url must be an absolute http or https url")
+	errPrivateWebhookURL = errors.New("webhook url must point to a public address")
+	errNoEventTypes      = errors.New("webhook needs at least one event type")
+	errShortSecret       = fmt.Errorf("webhook secret must be at least %d characters", minWebhookSecretLength)
+)
+
+// Webhooks registers the webhooks of organizations and posts the domain
+// events they subscribe to, retrying failed deliveries with exponential backoff.
+type Webhooks struct {
+	Store       store.Store
+	Client      *http.Client
+	Logger      *logrus.Logger
+	MaxAttempts int
+	BaseDelay   time.Duration
+}
+
+func NewWebhooks(st store.Store, logger *logrus.Logger) *Webhooks {
+	return &Webhooks{
+		Store:       st,
+		Client:      newWebhookClient(publicAddr),
+		Logger:      logger,
+		MaxAttempts: WebhookMaxAttempts,
+		BaseDelay:   webhookBaseDelay,
+	}
+}
+
+// Create registers a webhook of the tenant's organization, a secret is
+// generated when it has none. The secret is only returned here.
+func (wh *Webhooks) Create(ctx context.Context, createdBy string, w *store.Webhook) (*store.Webhook, error) {
+	if err := validateWebhook(w); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDetails, err)
+	}
+	if w.Secret == "" {
+		secret, err := webhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		w.Secret = secret
+	}
+	w.CreatedBy = createdBy
+
+	created, err := wh.Store.CreateWebhook(ctx, w)
+	if err != nil {
+		wh.Logger.Errorf("failed to save webhook: %v", err)
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// validateWebhook checks the URL, the event types and the secret of w, it
+// removes event types listed more than once.
+func validateWebhook(w *store.Webhook) error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errInvalidWebhookURL
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errPrivateWebhookURL
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !publicAddr(addr) {
+		return errPrivateWebhookURL
+	}
+	if len(w.EventTypes) == 0 {
+		return errNoEventTypes
+	}
+	var eventTypes []string
+	for _, t := range w.EventTypes {
+		if !slices.Contains(events.Types, t) {
+			return fmt.Errorf("unknown event type %q", t)
+		}
+		if !slices.Contains(eventTypes, t) {
+			eventTypes = append(eventTypes, t)
+		}
+	}
+	w.EventTypes = eventTypes
+	if w.Secret != "" && len(w.Secret) < minWebhookSecretLength {
+		return errShortSecret
+	}
+
+	return nil
+}
+
+// nonPublicPrefixes are the networks, besides the private, loopback,
+// link-local and multicast ones, that webhooks can not be posted to.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+}
+
+// publicAddr reports whether addr can be reached from the internet, webhooks
+// are only posted to those so they can not be used to reach internal services.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// newWebhookClient returns the client deliveries are posted with. It only
+// connects to the addresses allowed accepts, checked when connecting so a
+// host that resolves to another address after the webhook was created is
+// caught too, and it does not follow redirects.
+func newWebhookClient(allowed func(netip.Addr) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !allowed(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", errPrivateWebhookURL, address)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would connect to the webhook instead of the dialer
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func webhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Publish creates the deliveries of an event for the webhooks subscribed to
+// it in the organization the event happened in. Events without an
+// organization, made by platform administrators, are not delivered as they
+// can be about users of any organization. It lets the outbox relay feed webhooks.
+func (wh *Webhooks) Publish(ctx context.Context, m foundation.Message) error {
+	var payload struct {
+		OrganizationID string `json:"organization_id"`
+	}
+	_ = json.Unmarshal(m.Payload, &payload)
+	if payload.OrganizationID == "" {
+		return nil
+	}
+
+	_, err := wh.Store.EnqueueWebhookDeliveries(ctx, &store.OutboxEvent{
+		ID:          m.ID,
+		Type:        m.Type,
+		AggregateID: m.AggregateID,
+		Payload:     m.Payload,
+		OccurredAt:  m.OccurredAt,
+	}, payload.OrganizationID)
+	return err
+}
+
+// Deliver posts the deliveries that are due, it returns the number that succeeded.
+func (wh *Webhooks) Deliver(ctx context.Context) (int, error) {
+	delivered := 0
+	for {
+		due, err := wh.Store.DueWebhookDeliveries(ctx, webhookBatchSize)
+		if err != nil {
+			return delivered, err
+		}
+		for _, d := range due {
+			wh.attempt(ctx, d)
+			if err := wh.Store.UpdateWebhookDelivery(ctx, d); err != nil {
+				return delivered, err
+			}
+			if d.Status == store.DeliverySucceeded {
+				delivered++
+			}
+		}
+		if len(due) < webhookBatchSize {
+			return delivered, nil
+		}
+	}
+}
+
+// attempt posts d once and sets its status, a failed delivery is retried
+// after a delay that doubles with every attempt until it runs out of attempts.
+func (wh *Webhooks) attempt(ctx context.Context, d *store.WebhookDelivery) {
+	d.Attempts++
+	d.ResponseCode, d.LastError = wh.post(ctx, d)
+	now := time.Now().UTC()
+	switch {
+	case d.LastError == "":
+		d.Status = store.DeliverySucceeded
+		d.DeliveredAt = &now
+		d.NextAttemptAt = nil
+	case d.Attempts >= wh.MaxAttempts:
+		d.Status = store.DeliveryFailed
+		d.NextAttemptAt = nil
+		wh.Logger.Errorf("webhook delivery %s failed after %d attempts: %s", d.ID, d.Attempts, d.LastError)
+	default:
+		next := now.Add(webhookBackoff(wh.BaseDelay, d.Attempts))
+		d.NextAttemptAt = &next
+	}
+}
+
+// post sends d to its webhook, it returns the status of the response and
+// why the delivery failed, which is empty when it got a 2xx response.
+func (wh *Webhooks) post(ctx context.Context, d *store.WebhookDelivery) (int, string) {
+	body, err := json.Marshal(map[string]any{
+		"id":          d.EventID,
+		"type":        d.EventType,
+		"occurred_at": d.OccurredAt.UTC(),
+		"data":        d.Payload,
+	})
+	if err != nil {
+		return 0, err.Error()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err.Error()
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "identity-server-webhooks")
+	req.Header.Set(WebhookIDHeader, d.EventID)
+	req.Header.Set(WebhookEventHeader, d.EventType)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(d.Secret, timestamp, body))
+
+	resp, err := wh.Client.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, "unexpected status " + resp.Status
+	}
+	return resp.StatusCode, ""
+}
+
+// webhookBackoff is the wait after the failed attempt number attempts of a delivery.
+func webhookBackoff(base time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < webhookMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookMaxDelay)
+}
+
+// Replay posts a delivery of the tenant's organization again, straight away
+// and with all its attempts, whether it failed or not.
+func (wh *Webhooks) Replay(ctx context.Context, id string) (*store.WebhookDelivery, error) {
+	d, err := wh.Store.ReplayWebhookDelivery(ctx, id)
+	if err != nil {
+		if !errors.Is(err, store.ErrDeliveryNotFound) {
+			wh.Logger.Errorf("failed to replay webhook delivery %s: %v", id, err)
+		}
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Run posts the deliveries that are due every interval until ctx is done.
+func (wh *Webhooks) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := wh.Deliver(ctx); err != nil {
+			wh.Logger.Errorf("failed to deliver webhooks: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SignWebhook returns the Webhook-Signature of a delivery of body at timestamp, in Unix seconds.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks that a delivery with header and body was
+// signed with secret no more than tolerance ago, receivers reject replayed
+// deliveries by their timestamp and the ID of the event.
+func VerifyWebhookSignature(secret string, header http.Header, body []byte, tolerance time.Duration) error {
+	timestamp, err := strconv.ParseInt(header.Get(WebhookTimestampHeader), 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	age := time.Since(time.Unix(timestamp, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrStaleDelivery
+	}
+
+	expected := SignWebhook(secret, timestamp, body)
+	if !hmac.Equal([]byte(header.Get(WebhookSignatureHeader)), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
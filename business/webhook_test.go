@@ -0,0 +1,375 @@
+package business
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/store"
+	"github.com/riyadennis/identity-server/foundation"
+)
+
+const testWebhookSecret = "0123456789abcdef"
+
+func TestWebhooks_Create(t *testing.T) {
+	scenarios := []struct {
+		name               string
+		webhook            *store.Webhook
+		expectedEventTypes []string
+		expectedErr        error
+	}{
+		{
+			name:        "relative url",
+			webhook:     &store.Webhook{URL: "/hook", EventTypes: []string{"user.registered"}},
+			expectedErr: errInvalidWebhookURL,
+		},
+		{
+			name:        "not http",
+			webhook:     &store.Webhook{URL: "ftp://example.com/hook", EventTypes: []string{"user.registered"}},
+			expectedErr: errInvalidWebhookURL,
+		},
+		{
+			name:        "no event types",
+			webhook:     &store.Webhook{URL: "https://example.com/hook"},
+			expectedErr: errNoEventTypes,
+		},
+		{
+			name:        "unknown event type",
+			webhook:     &store.Webhook{URL: "https://example.com/hook", EventTypes: []string{"user.renamed"}},
+			expectedErr: ErrInvalidDetails,
+		},
+		{
+			name:        "loopback address",
+			webhook:     &store.Webhook{URL: "http://127.0.0.1:8080/hook", EventTypes: []string{"user.registered"}},
+			expectedErr: errPrivateWebhookURL,
+		},
+		{
+			name:        "metadata address",
+			webhook:     &store.Webhook{URL: "http://169.254.169.254/latest", EventTypes: []string{"user.registered"}},
+			expectedErr: errPrivateWebhookURL,
+		},
+		{
+			name:        "private ipv6 address",
+			webhook:     &store.Webhook{URL: "http://[fd00::1]/hook", EventTypes: []string{"user.registered"}},
+			expectedErr: errPrivateWebhookURL,
+		},
+		{
+			name:        "localhost",
+			webhook:     &store.Webhook{URL: "http://LOCALHOST./hook", EventTypes: []string{"user.registered"}},
+			expectedErr: errPrivateWebhookURL,
+		},
+		{
+			name:        "short secret",
+			webhook:     &store.Webhook{URL: "https://example.com/hook", EventTypes: []string{"user.registered"}, Secret: "short"},
+			expectedErr: errShortSecret,
+		},
+		{
+			name: "created",
+			webhook: &store.Webhook{
+				URL:        "https://example.com/hook",
+				EventTypes: []string{"user.registered", "role.assigned", "user.registered"},
+			},
+			expectedEventTypes: []string{"user.registered", "role.assigned"},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			st := &mocks.Store{}
+			w, err := NewWebhooks(st, logrus.New()).Create(context.Background(), "admin-1", sc.webhook)
+			if sc.expectedErr != nil {
+				assert.ErrorIs(t, err, sc.expectedErr)
+				assert.ErrorIs(t, err, ErrInvalidDetails)
+				assert.Empty(t, st.Webhooks)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, sc.expectedEventTypes, w.EventTypes)
+			assert.Equal(t, "admin-1", w.CreatedBy)
+			assert.Len(t, w.Secret, 64)
+		})
+	}
+}
+
+func TestWebhooks_Publish(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		payload      string
+		expectedOrgs []string
+	}{
+		{
+			name:         "organization of the event",
+			payload:      `{"user_id":"user-1","role":"admin","organization_id":"org-1"}`,
+			expectedOrgs: []string{"org-1"},
+		},
+		{
+			name:    "no organization",
+			payload: `{"user_id":"user-1"}`,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			st := &mocks.Store{}
+			err := NewWebhooks(st, logrus.New()).Publish(context.Background(), foundation.Message{
+				ID:          "event-1",
+				Type:        "role.assigned",
+				AggregateID: "user-1",
+				Payload:     json.RawMessage(sc.payload),
+			})
+			require.NoError(t, err)
+			assert.Equal(t, sc.expectedOrgs, st.EnqueuedFor)
+			if sc.expectedOrgs == nil {
+				assert.Empty(t, st.Enqueued)
+				return
+			}
+			require.Len(t, st.Enqueued, 1)
+			assert.Equal(t, "event-1", st.Enqueued[0].ID)
+		})
+	}
+}
+
+func TestWebhooks_Deliver(t *testing.T) {
+	scenarios := []struct {
+		name             string
+		status           int
+		attempts         int
+		expectedStatus   string
+		expectedRetry    time.Duration
+		expectedDelivers int
+	}{
+		{
+			name:             "delivered",
+			status:           http.StatusNoContent,
+			expectedStatus:   store.DeliverySucceeded,
+			expectedDelivers: 1,
+		},
+		{
+			name:           "retried",
+			status:         http.StatusInternalServerError,
+			attempts:       2,
+			expectedStatus: store.DeliveryPending,
+			expectedRetry:  4 * time.Minute,
+		},
+		{
+			name:           "out of attempts",
+			status:         http.StatusGone,
+			attempts:       WebhookMaxAttempts - 1,
+			expectedStatus: store.DeliveryFailed,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			var received http.Header
+			var verifyErr error
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				received = r.Header
+				verifyErr = VerifyWebhookSignature(testWebhookSecret, r.Header, body, WebhookTolerance)
+				w.WriteHeader(sc.status)
+			}))
+			defer srv.Close()
+
+			d := &store.WebhookDelivery{
+				ID:        "delivery-1",
+				EventID:   "event-1",
+				EventType: "user.registered",
+				Payload:   json.RawMessage(`{"user_id":"user-1"}`),
+				Status:    store.DeliveryPending,
+				Attempts:  sc.attempts,
+				URL:       srv.URL,
+				Secret:    testWebhookSecret,
+			}
+			before := time.Now()
+			wh := NewWebhooks(&mocks.Store{Deliveries: []*store.WebhookDelivery{d}}, logrus.New())
+			// the test server listens on a loopback address
+			wh.Client = newWebhookClient(func(netip.Addr) bool { return true })
+			delivered, err := wh.Deliver(context.Background())
+			require.NoError(t, err)
+
+			assert.Equal(t, sc.expectedDelivers, delivered)
+			assert.NoError(t, verifyErr)
+			assert.Equal(t, "event-1", received.Get(WebhookIDHeader))
+			assert.Equal(t, "user.registered", received.Get(WebhookEventHeader))
+			assert.Equal(t, sc.expectedStatus, d.Status)
+			assert.Equal(t, sc.attempts+1, d.Attempts)
+			assert.Equal(t, sc.status, d.ResponseCode)
+			if sc.expectedRetry > 0 {
+				require.NotNil(t, d.NextAttemptAt)
+				assert.WithinDuration(t, before.Add(sc.expectedRetry), *d.NextAttemptAt, time.Second)
+			} else {
+				assert.Nil(t, d.NextAttemptAt)
+			}
+			if sc.expectedStatus == store.DeliverySucceeded {
+				assert.NotNil(t, d.DeliveredAt)
+				assert.Empty(t, d.LastError)
+			} else {
+				assert.NotEmpty(t, d.LastError)
+			}
+		})
+	}
+}
+
+func TestWebhookClient(t *testing.T) {
+	redirected := false
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		redirected = true
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer target.Close()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	defer srv.Close()
+
+	t.Run("private address", func(t *testing.T) {
+		_, err := newWebhookClient(publicAddr).Post(srv.URL, "application/json", nil)
+		assert.ErrorIs(t, err, errPrivateWebhookURL)
+	})
+	t.Run("redirect", func(t *testing.T) {
+		resp, err := newWebhookClient(func(netip.Addr) bool { return true }).Post(srv.URL, "application/json", nil)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusTemporaryRedirect, resp.StatusCode)
+		assert.False(t, redirected)
+	})
+}
+
+func TestPublicAddr(t *testing.T) {
+	scenarios := []struct {
+		addr     string
+		expected bool
+	}{
+		{addr: "93.184.216.34", expected: true},
+		{addr: "2606:2800:220:1::1", expected: true},
+		{addr: "127.0.0.1"},
+		{addr: "10.1.2.3"},
+		{addr: "172.16.0.1"},
+		{addr: "192.168.1.1"},
+		{addr: "169.254.169.254"},
+		{addr: "100.64.0.1"},
+		{addr: "0.0.0.0"},
+		{addr: "::1"},
+		{addr: "::"},
+		{addr: "fe80::1"},
+		{addr: "fd00::1"},
+		{addr: "::ffff:127.0.0.1"},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.addr, func(t *testing.T) {
+			assert.Equal(t, sc.expected, publicAddr(netip.MustParseAddr(sc.addr)))
+		})
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	scenarios := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 1, expected: time.Minute},
+		{attempts: 2, expected: 2 * time.Minute},
+		{attempts: 5, expected: 16 * time.Minute},
+		{attempts: 9, expected: 256 * time.Minute},
+		{attempts: 10, expected: webhookMaxDelay},
+		{attempts: 100, expected: webhookMaxDelay},
+	}
+	for _, sc := range scenarios {
+		t.Run(strconv.Itoa(sc.attempts), func(t *testing.T) {
+			assert.Equal(t, sc.expected, webhookBackoff(webhookBaseDelay, sc.attempts))
+		})
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"id":"event-1"}`)
+	signed := func(timestamp int64, secret string) http.Header {
+		h := http.Header{}
+		h.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+		h.Set(WebhookSignatureHeader, SignWebhook(secret, timestamp, body))
+		return h
+	}
+	now := time.Now().Unix()
+
+	scenarios := []struct {
+		name        string
+		header      http.Header
+		expectedErr error
+	}{
+		{
+			name:   "valid",
+			header: signed(now, testWebhookSecret),
+		},
+		{
+			name:        "no timestamp",
+			header:      http.Header{},
+			expectedErr: ErrInvalidSignature,
+		},
+		{
+			name:        "other secret",
+			header:      signed(now, "fedcba9876543210"),
+			expectedErr: ErrInvalidSignature,
+		},
+		{
+			name:        "too old",
+			header:      signed(now-int64(time.Hour/time.Second), testWebhookSecret),
+			expectedErr: ErrStaleDelivery,
+		},
+		{
+			name:        "in the future",
+			header:      signed(now+int64(time.Hour/time.Second), testWebhookSecret),
+			expectedErr: ErrStaleDelivery,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			assert.Equal(t, sc.expectedErr, VerifyWebhookSignature(testWebhookSecret, sc.header, body, WebhookTolerance))
+		})
+	}
+}
+
+func TestWebhooks_Replay(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		store       *mocks.Store
+		expectedErr error
+	}{
+		{
+			name:        "not found",
+			store:       &mocks.Store{},
+			expectedErr: store.ErrDeliveryNotFound,
+		},
+		{
+			name:        "store error",
+			store:       &mocks.Store{Error: errors.New("db down")},
+			expectedErr: errors.New("db down"),
+		},
+		{
+			name: "replayed",
+			store: &mocks.Store{Deliveries: []*store.WebhookDelivery{
+				{ID: "delivery-1", Status: store.DeliveryFailed, Attempts: WebhookMaxAttempts},
+			}},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			d, err := NewWebhooks(sc.store, logrus.New()).Replay(context.Background(), "delivery-1")
+			assert.Equal(t, sc.expectedErr, err)
+			if sc.expectedErr != nil {
+				return
+			}
+			assert.Equal(t, store.DeliveryPending, d.Status)
+			assert.Zero(t, d.Attempts)
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package foundation
 
+import "net/http"
+
 const (
 	// validation failures in a POST request.
 	ValidationFailed = "validation-failure"
@@ -25,17 +27,99 @@ const (
 	// UnAuthorised is when a user have invalid or expired token.
 	UnAuthorised = "unauthorised"
 
+	// Forbidden is when a user is authenticated but lacks the permission needed.
+	Forbidden = "forbidden"
+
 	// UserDoNotExist is returned when search for an email in db fails.
 	UserDoNotExist = "user-do-not-exist"
+
+	// InvitationNotFound is returned when an invitation link is unknown, expired or already used.
+	InvitationNotFound = "invitation-not-found"
+
+	// InvalidPassword is returned when a change needs the current password and it does not match.
+	InvalidPassword = "invalid-password"
+
+	// EmailChangeNotFound is returned when an email confirmation link is unknown, expired or already used.
+	EmailChangeNotFound = "email-change-not-found"
+
+	// WebhookNotFound is returned when a webhook does not exist in the organization.
+	WebhookNotFound = "webhook-not-found"
+
+	// DeliveryNotFound is returned when a webhook delivery does not exist in the organization.
+	DeliveryNotFound = "webhook-delivery-not-found"
+
+	// InvalidManifest is returned when the persisted query manifest can not be loaded.
+	InvalidManifest = "invalid-manifest"
+
+	// OrganizationNotFound is returned when an organization does not exist or the user is not a member.
+	OrganizationNotFound = "organization-not-found"
+
+	// RoleNotFound is returned when a role does not exist in the organization.
+	RoleNotFound = "role-not-found"
+
+	// PermissionNotFound is returned when a role is given a permission that does not exist.
+	PermissionNotFound = "permission-not-found"
+
+	// InternalError is returned for failures of the service, the details are only logged.
+	InternalError = "internal-error"
+)
+
+// Category is the kind of failure of an error, it decides the HTTP status
+// and gRPC code it is reported with.
+type Category int
+
+const (
+	// CategoryInternal errors are failures of the service, their message is
+	// not shown to clients.
+	CategoryInternal Category = iota
+	// CategoryInvalid errors are requests that can not succeed as they are.
+	CategoryInvalid
+	// CategoryUnauthenticated errors are requests without a valid token.
+	CategoryUnauthenticated
+	// CategoryForbidden errors are requests the caller is not allowed to make.
+	CategoryForbidden
+	// CategoryNotFound errors are requests for something that does not exist.
+	CategoryNotFound
+	// CategoryConflict errors are requests that clash with the current state, such as a taken email.
+	CategoryConflict
 )
 
+// Status is the HTTP status of errors of the category.
+func (c Category) Status() int {
+	switch c {
+	case CategoryInvalid:
+		return http.StatusBadRequest
+	case CategoryUnauthenticated:
+		return http.StatusUnauthorized
+	case CategoryForbidden:
+		return http.StatusForbidden
+	case CategoryNotFound:
+		return http.StatusNotFound
+	case CategoryConflict:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 // CustomError holds error code and details about the error.
 type CustomError struct {
-	Code string
-	Err  error
+	Code     string
+	Category Category
+	Err      error
+}
+
+// NewCustomError returns err with the code and category clients get for it.
+func NewCustomError(category Category, code string, err error) *CustomError {
+	return &CustomError{Code: code, Category: category, Err: err}
 }
 
 // Error returns just the error message for a custom error.
 func (e *CustomError) Error() string {
 	return e.Err.Error()
 }
+
+// Unwrap returns the error the code was given to.
+func (e *CustomError) Unwrap() error {
+	return e.Err
+}
@@ -2,6 +2,8 @@ package foundation
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,3 +16,32 @@ func TestCustomError_Error(t *testing.T) {
 	}
 	assert.Equal(t, "field required", err.Error())
 }
+
+func TestCustomError_Unwrap(t *testing.T) {
+	sentinel := errors.New("not found")
+	err := error(NewCustomError(CategoryNotFound, UserDoNotExist, fmt.Errorf("failed to delete user: %w", sentinel)))
+
+	assert.ErrorIs(t, err, sentinel)
+	var ce *CustomError
+	assert.ErrorAs(t, fmt.Errorf("wrapped: %w", err), &ce)
+	assert.Equal(t, UserDoNotExist, ce.Code)
+	assert.Equal(t, "failed to delete user: not found", err.Error())
+}
+
+func TestCategory_Status(t *testing.T) {
+	scenarios := []struct {
+		category Category
+		expected int
+	}{
+		{category: CategoryInternal, expected: http.StatusInternalServerError},
+		{category: CategoryInvalid, expected: http.StatusBadRequest},
+		{category: CategoryUnauthenticated, expected: http.StatusUnauthorized},
+		{category: CategoryForbidden, expected: http.StatusForbidden},
+		{category: CategoryNotFound, expected: http.StatusNotFound},
+		{category: CategoryConflict, expected: http.StatusConflict},
+		{category: Category(42), expected: http.StatusInternalServerError},
+	}
+	for _, sc := range scenarios {
+		assert.Equal(t, sc.expected, sc.category.Status())
+	}
+}
@@ -0,0 +1,143 @@
+package foundation
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Mail is an email sent by the service.
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails to users.
+type Mailer interface {
+	Send(ctx context.Context, m Mail) error
+}
+
+// SMTPMailer sends emails through an SMTP relay.
+type SMTPMailer struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+}
+
+// Send delivers the mail as a plain text message. It works like
+// smtp.SendMail but gives up when ctx is done.
+func (s *SMTPMailer) Send(ctx context.Context, m Mail) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	host, _, _ := net.SplitHostPort(s.Addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer func() { _ = c.Close() }()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.Auth != nil {
+		if err := c.Auth(s.Auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(s.From); err != nil {
+		return err
+	}
+	if err := c.Rcpt(m.To); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message(s.From, m)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return c.Quit()
+}
+
+// headerBreaks removes the line breaks that would start a new header.
+var headerBreaks = strings.NewReplacer("\r", "", "\n", "")
+
+// message builds the mail. Subjects can contain names users chose, they are
+// encoded as RFC 2047 words so no line break in them ends up in the headers.
+func message(from string, m Mail) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", headerBreaks.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", headerBreaks.Replace(m.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n\r\n")
+	b.WriteString(m.Body)
+
+	return []byte(b.String())
+}
+
+// LogMailer writes emails to the log instead of sending them, it is used
+// when no SMTP server is configured.
+type LogMailer struct {
+	Logger *logrus.Logger
+}
+
+// Send logs the mail.
+func (l *LogMailer) Send(_ context.Context, m Mail) error {
+	l.Logger.WithFields(logrus.Fields{
+		"to":      m.To,
+		"subject": m.Subject,
+	}).Info(m.Body)
+
+	return nil
+}
+
+// NewENVMailer returns an SMTPMailer configured from SMTP_HOST, SMTP_PORT,
+// SMTP_USERNAME, SMTP_PASSWORD and MAIL_FROM, or a LogMailer when SMTP_HOST is not set.
+func NewENVMailer(logger *logrus.Logger) Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return &LogMailer{Logger: logger}
+	}
+
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	var auth smtp.Auth
+	if user := os.Getenv("SMTP_USERNAME"); user != "" {
+		auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+	}
+
+	return &SMTPMailer{
+		Addr: net.JoinHostPort(host, port),
+		From: os.Getenv("MAIL_FROM"),
+		Auth: auth,
+	}
+}
@@ -0,0 +1,108 @@
+package foundation
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewENVMailer(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		env      map[string]string
+		expected Mailer
+	}{
+		{
+			name:     "no smtp host",
+			env:      map[string]string{"SMTP_HOST": ""},
+			expected: &LogMailer{},
+		},
+		{
+			name: "smtp host",
+			env: map[string]string{
+				"SMTP_HOST": "smtp.example.com",
+				"SMTP_PORT": "2525",
+				"MAIL_FROM": "noreply@example.com",
+			},
+			expected: &SMTPMailer{Addr: "smtp.example.com:2525", From: "noreply@example.com"},
+		},
+	}
+
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			for k, v := range sc.env {
+				t.Setenv(k, v)
+			}
+			mailer := NewENVMailer(nil)
+			assert.IsType(t, sc.expected, mailer)
+			if s, ok := sc.expected.(*SMTPMailer); ok {
+				assert.Equal(t, s.Addr, mailer.(*SMTPMailer).Addr)
+				assert.Equal(t, s.From, mailer.(*SMTPMailer).From)
+			}
+		})
+	}
+}
+
+func TestLogMailer_Send(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &buf
+
+	m := &LogMailer{Logger: logger}
+	err := m.Send(context.Background(), Mail{To: "john@example.com", Subject: "Hello", Body: "welcome"})
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "john@example.com")
+	assert.Contains(t, buf.String(), "welcome")
+}
+
+func TestSMTPMailer_SendTimeout(t *testing.T) {
+	// the server accepts connections but never greets the client
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			_, _ = io.Copy(io.Discard, conn)
+			_ = conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	m := &SMTPMailer{Addr: listener.Addr().String(), From: "noreply@example.com"}
+	start := time.Now()
+	err = m.Send(ctx, Mail{To: "john@example.com", Subject: "Hello", Body: "welcome"})
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestMessage(t *testing.T) {
+	msg := string(message("noreply@example.com", Mail{To: "john@example.com", Subject: "Hello", Body: "welcome"}))
+	assert.Contains(t, msg, "From: noreply@example.com\r\n")
+	assert.Contains(t, msg, "To: john@example.com\r\n")
+	assert.Contains(t, msg, "Subject: Hello\r\n")
+	assert.Contains(t, msg, "\r\n\r\nwelcome")
+}
+
+func TestMessage_HeaderInjection(t *testing.T) {
+	msg := string(message("noreply@example.com", Mail{
+		To:      "john@example.com\r\nBcc: eve@example.com",
+		Subject: "You have been invited to join Acme\r\nBcc: eve@example.com",
+		Body:    "welcome",
+	}))
+	headers, _, found := strings.Cut(msg, "\r\n\r\n")
+	require.True(t, found)
+	for _, line := range strings.Split(headers, "\r\n") {
+		assert.NotRegexp(t, `(?i)^bcc:`, line)
+	}
+	assert.Contains(t, headers, "Subject: =?utf-8?q?")
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/riyadennis/identity-server/business/store"
+)
+
+// Actor adds the address, user agent and request ID of a request to the
+// context so the audit events of the changes it makes can be traced back to
+// it. It must be used after the chi RequestID and RealIP middlewares, Auth
+// adds the user making the request.
+func Actor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := store.WithActor(r.Context(), store.Actor{
+			IP:        remoteIP(r.RemoteAddr),
+			UserAgent: r.UserAgent(),
+			RequestID: middleware.GetReqID(r.Context()),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RealIP replaces the remote address of requests with the one in their
+// X-Forwarded-For or X-Real-IP headers when TRUST_PROXY_HEADERS is true.
+// Clients can set those headers to anything, so they are only trusted when
+// the service runs behind a proxy that overwrites them.
+func RealIP(next http.Handler) http.Handler {
+	if trust, _ := strconv.ParseBool(os.Getenv("TRUST_PROXY_HEADERS")); trust {
+		return middleware.RealIP(next)
+	}
+	return next
+}
+
+// remoteIP drops the port from a remote address, RealIP sets addresses without one.
+func remoteIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/riyadennis/identity-server/business/store"
+)
+
+func TestActor(t *testing.T) {
+	scenarios := []struct {
+		name       string
+		remoteAddr string
+		expectedIP string
+	}{
+		{name: "address with port", remoteAddr: "10.0.0.1:5123", expectedIP: "10.0.0.1"},
+		{name: "address set by RealIP", remoteAddr: "203.0.113.7", expectedIP: "203.0.113.7"},
+		{name: "ipv6 address", remoteAddr: "[2001:db8::1]:443", expectedIP: "2001:db8::1"},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			var actor store.Actor
+			handler := middleware.RequestID(Actor(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				actor = store.ActorFromContext(r.Context())
+			})))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = sc.remoteAddr
+			r.Header.Set("User-Agent", "curl/8.0")
+			r.Header.Set(middleware.RequestIDHeader, "req-1")
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			assert.Equal(t, store.Actor{IP: sc.expectedIP, UserAgent: "curl/8.0", RequestID: "req-1"}, actor)
+		})
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	scenarios := []struct {
+		name       string
+		trust      string
+		expectedIP string
+	}{
+		{name: "headers not trusted", trust: "", expectedIP: "10.0.0.1"},
+		{name: "headers trusted", trust: "true", expectedIP: "203.0.113.7"},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			t.Setenv("TRUST_PROXY_HEADERS", sc.trust)
+			var actor store.Actor
+			handler := RealIP(Actor(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				actor = store.ActorFromContext(r.Context())
+			})))
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = "10.0.0.1:5123"
+			r.Header.Set("X-Forwarded-For", "203.0.113.7")
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			assert.Equal(t, sc.expectedIP, actor.IP)
+		})
+	}
+}
@@ -2,8 +2,13 @@ package middleware
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
 
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/riyadennis/identity-server/business/authz"
 	"github.com/riyadennis/identity-server/business/store"
 	"github.com/riyadennis/identity-server/business/validation"
 	"github.com/riyadennis/identity-server/foundation"
@@ -19,23 +24,152 @@ const (
 
 type AuthConfig struct {
 	TokenConfig *store.TokenConfig
+	Authorizer  *authz.Authorizer
 	Logger      *logrus.Logger
 }
 
+// ErrInvalidToken is returned by Authenticate when the token is missing, malformed or expired.
+var ErrInvalidToken = errors.New("invalid token")
+
+// errAuthorise replaces the message of authorization checks that could not be made.
+var errAuthorise = errors.New("internal error")
+
 // Auth is the middleware that should be used for endpoints that needs jwt Token authentication.
 // If Token is not present or is invalid, then the user is denied access to the wrapped endpoint.
+// When an Authorizer is configured store queries are scoped to the organization of the token.
 func (ac *AuthConfig) Auth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		headerToken := r.Header.Get("Authorization")
-		claims, err := validation.ValidateToken(headerToken, ac.TokenConfig)
+		ctx, err := ac.Authenticate(r.Context(), r.Header.Get("Authorization"))
 		if err != nil {
-			ac.Logger.Errorf("invalid token: %v", err)
-			foundation.ErrorResponse(w, http.StatusUnauthorized, err, foundation.UnAuthorised)
+			if errors.Is(err, ErrInvalidToken) {
+				foundation.ErrorResponse(w, r, http.StatusUnauthorized, err, foundation.UnAuthorised)
+				return
+			}
+			ac.authzError(w, r, err)
 			return
 		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		ctx := context.WithValue(r.Context(), UserClaimsKey, claims)
-		ctx = context.WithValue(ctx, AccessTokenKey, headerToken)
+// Authenticate validates a bearer token and returns a copy of ctx with its
+// claims, the token and the actor, scoped to the organization of the token
+// when an Authorizer is configured. It lets transports that do not go through
+// Auth, such as websockets, authenticate the same way.
+func (ac *AuthConfig) Authenticate(ctx context.Context, token string) (context.Context, error) {
+	claims, err := validation.ValidateToken(token, ac.TokenConfig)
+	if err != nil {
+		ac.Logger.Errorf("invalid token: %v", err)
+		return ctx, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	ctx = context.WithValue(ctx, UserClaimsKey, claims)
+	ctx = context.WithValue(ctx, AccessTokenKey, token)
+	actor := store.ActorFromContext(ctx)
+	actor.UserID = claims.Subject
+	ctx = store.WithActor(ctx, actor)
+	if ac.Authorizer != nil {
+		tenant, err := ac.Authorizer.Tenant(ctx, authz.Subject{UserID: claims.Subject}, claims.OrgID)
+		if err != nil {
+			return ctx, err
+		}
+		ctx = store.WithTenant(ctx, tenant)
+	}
+
+	return ctx, nil
+}
+
+// OptionalAuth is the middleware for endpoints that serve callers with and
+// without a token, such as GraphQL where each field decides whether it needs
+// one. The claims and tenant of a valid token are put in the request context,
+// requests without a token or with an invalid one are let through without them.
+func (ac *AuthConfig) OptionalAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := ac.AuthenticateOptional(r.Context(), r.Header.Get("Authorization"))
+		if err != nil {
+			ac.authzError(w, r, err)
+			return
+		}
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// AuthenticateOptional is Authenticate for callers that may be anonymous, ctx
+// is returned unchanged when the token is empty or invalid. It only fails when
+// the organization of a valid token can not be used.
+func (ac *AuthConfig) AuthenticateOptional(ctx context.Context, token string) (context.Context, error) {
+	if token == "" {
+		return ctx, nil
+	}
+	authenticated, err := ac.Authenticate(ctx, token)
+	if errors.Is(err, ErrInvalidToken) {
+		return ctx, nil
+	}
+	if err != nil {
+		return ctx, err
+	}
+
+	return authenticated, nil
+}
+
+// RequirePermission is the middleware for endpoints that need a permission granted through the caller's roles.
+// It must be used after Auth so that the token claims are present in the request context.
+func (ac *AuthConfig) RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(UserClaimsKey).(*store.Claims)
+			if !ok || claims == nil {
+				foundation.ErrorResponse(w, r, http.StatusUnauthorized, authz.ErrUnauthenticated, foundation.UnAuthorised)
+				return
+			}
+
+			err := ac.Authorizer.Authorize(r.Context(), authz.Subject{UserID: claims.Subject}, permission, authz.Resource{})
+			if err != nil {
+				ac.authzError(w, r, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRole is the middleware for endpoints only callers assigned the role can use.
+// It must be used after Auth so that the token claims are present in the request context.
+func (ac *AuthConfig) RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := r.Context().Value(UserClaimsKey).(*store.Claims)
+			if !ok || claims == nil {
+				foundation.ErrorResponse(w, r, http.StatusUnauthorized, authz.ErrUnauthenticated, foundation.UnAuthorised)
+				return
+			}
+
+			ok, err := ac.Authorizer.HasRole(r.Context(), authz.Subject{UserID: claims.Subject}, role)
+			if err != nil {
+				ac.authzError(w, r, err)
+				return
+			}
+			if !ok {
+				ac.Logger.Infof("user %s denied %s, missing role %s", claims.Subject, r.URL.Path, role)
+				ac.authzError(w, r, authz.ErrForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authzError writes the response for a failed authorization check. Checks
+// that could not be made are logged and sent without their details, the id
+// of the request finds them in the log.
+func (ac *AuthConfig) authzError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, authz.ErrForbidden) {
+		foundation.ErrorResponse(w, r, http.StatusForbidden, err, foundation.Forbidden)
+		return
+	}
+	ac.Logger.WithField("correlation_id", middleware.GetReqID(r.Context())).
+		Errorf("failed to authorise %s %s: %v", r.Method, r.URL.Path, err)
+	foundation.ErrorResponse(w, r, http.StatusInternalServerError, errAuthorise, foundation.InternalError)
+}
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -8,6 +10,8 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/riyadennis/identity-server/app/mocks"
+	"github.com/riyadennis/identity-server/business/authz"
 	"github.com/riyadennis/identity-server/business/store"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -41,11 +45,14 @@ func validToken(t *testing.T) string {
 	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes)
 	require.NoError(t, err)
 
-	claims := &jwt.RegisteredClaims{
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
-		Issuer:    "test",
-		Subject:   "user-123",
-		Audience:  jwt.ClaimStrings{"local"},
+	claims := &store.Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			Issuer:    "test",
+			Subject:   "user-123",
+			Audience:  jwt.ClaimStrings{"local"},
+		},
+		OrgID: "org-1",
 	}
 	tok, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(privateKey)
 	require.NoError(t, err)
@@ -73,18 +80,257 @@ func TestAuth_ValidToken(t *testing.T) {
 	ac := newAuthConfig()
 	tok := "Bearer " + validToken(t)
 	reached := false
-	handler := ac.Auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := Actor(ac.Auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		reached = true
 		assert.NotNil(t, r.Context().Value(UserClaimsKey))
 		assert.Equal(t, tok, r.Context().Value(AccessTokenKey))
+		assert.Equal(t, store.Actor{UserID: "user-123", IP: "192.0.2.1", UserAgent: "curl/8.0"},
+			store.ActorFromContext(r.Context()))
 		w.WriteHeader(http.StatusOK)
-	}))
+	})))
 
 	req := httptest.NewRequest(http.MethodGet, "/", nil)
 	req.Header.Set("Authorization", tok)
+	req.Header.Set("User-Agent", "curl/8.0")
 	rr := httptest.NewRecorder()
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusOK, rr.Code)
 	assert.True(t, reached)
 }
+
+func TestAuth_Tenant(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		store          *mocks.Store
+		expectedCode   int
+		expectedTenant store.Tenant
+	}{
+		{
+			name:         "not a member of the organization",
+			store:        &mocks.Store{User: &store.User{Roles: []string{authz.RoleAdmin}}},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "store error",
+			store:        &mocks.Store{Error: errors.New("db down")},
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			name: "member",
+			store: &mocks.Store{
+				User:          &store.User{Roles: []string{authz.RoleUser}},
+				Organizations: []*store.Organization{{ID: "org-1"}},
+			},
+			expectedCode:   http.StatusOK,
+			expectedTenant: store.Tenant{OrganizationID: "org-1"},
+		},
+		{
+			name:           "superadmin",
+			store:          &mocks.Store{User: &store.User{Roles: []string{authz.RoleSuperAdmin}}},
+			expectedCode:   http.StatusOK,
+			expectedTenant: store.Tenant{OrganizationID: "org-1", Platform: true},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			ac := newAuthConfig()
+			ac.Authorizer = authz.NewAuthorizer(sc.store, ac.Logger)
+			handler := ac.Auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tenant, ok := store.TenantFromContext(r.Context())
+				assert.True(t, ok)
+				assert.Equal(t, sc.expectedTenant, tenant)
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+validToken(t))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, sc.expectedCode, rr.Code)
+		})
+	}
+}
+
+func TestRequirePermission(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		claims       *store.Claims
+		store        *mocks.Store
+		expectedCode int
+	}{
+		{
+			name:         "missing claims",
+			store:        &mocks.Store{},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "missing permission",
+			claims:       &store.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-123"}},
+			store:        &mocks.Store{Permissions: []string{authz.UsersRead}},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "store error",
+			claims:       &store.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-123"}},
+			store:        &mocks.Store{Error: errors.New("db down")},
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			name:         "granted",
+			claims:       &store.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-123"}},
+			store:        &mocks.Store{Permissions: []string{authz.UsersDelete}},
+			expectedCode: http.StatusOK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			ac := newAuthConfig()
+			ac.Authorizer = authz.NewAuthorizer(sc.store, ac.Logger)
+
+			req := httptest.NewRequest(http.MethodDelete, "/", nil)
+			if sc.claims != nil {
+				req = req.WithContext(context.WithValue(req.Context(), UserClaimsKey, sc.claims))
+			}
+			rr := httptest.NewRecorder()
+			ac.RequirePermission(authz.UsersDelete)(okHandler()).ServeHTTP(rr, req)
+
+			assert.Equal(t, sc.expectedCode, rr.Code)
+			assert.NotContains(t, rr.Body.String(), "db down")
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	scenarios := []struct {
+		name         string
+		claims       *store.Claims
+		store        *mocks.Store
+		expectedCode int
+	}{
+		{
+			name:         "missing claims",
+			store:        &mocks.Store{},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "missing role",
+			claims:       &store.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-123"}},
+			store:        &mocks.Store{User: &store.User{Roles: []string{authz.RoleUser}}},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "store error",
+			claims:       &store.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-123"}},
+			store:        &mocks.Store{User: &store.User{}, Error: errors.New("db down")},
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			name:         "granted",
+			claims:       &store.Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: "user-123"}},
+			store:        &mocks.Store{User: &store.User{Roles: []string{authz.RoleAdmin}}},
+			expectedCode: http.StatusOK,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			ac := newAuthConfig()
+			ac.Authorizer = authz.NewAuthorizer(sc.store, ac.Logger)
+
+			req := httptest.NewRequest(http.MethodPut, "/", nil)
+			if sc.claims != nil {
+				req = req.WithContext(context.WithValue(req.Context(), UserClaimsKey, sc.claims))
+			}
+			rr := httptest.NewRecorder()
+			ac.RequireRole(authz.RoleAdmin)(okHandler()).ServeHTTP(rr, req)
+
+			assert.Equal(t, sc.expectedCode, rr.Code)
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	ac := newAuthConfig()
+	ac.Authorizer = authz.NewAuthorizer(&mocks.Store{
+		User:          &store.User{Roles: []string{authz.RoleUser}},
+		Organizations: []*store.Organization{{ID: "org-1"}},
+	}, ac.Logger)
+
+	_, err := ac.Authenticate(context.Background(), "")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = ac.Authenticate(context.Background(), "abc")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	tok := "Bearer " + validToken(t)
+	ctx, err := ac.Authenticate(context.Background(), tok)
+	require.NoError(t, err)
+	claims, ok := ctx.Value(UserClaimsKey).(*store.Claims)
+	require.True(t, ok)
+	assert.Equal(t, "user-123", claims.Subject)
+	assert.Equal(t, tok, ctx.Value(AccessTokenKey))
+	tenant, ok := store.TenantFromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, store.Tenant{OrganizationID: "org-1"}, tenant)
+}
+
+func TestOptionalAuth(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		token          string
+		store          *mocks.Store
+		expectedCode   int
+		expectedClaims bool
+	}{
+		{
+			name:         "no token",
+			store:        &mocks.Store{},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid token",
+			token:        "Bearer not-a-real-token",
+			store:        &mocks.Store{},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "not a member of the organization",
+			token:        "valid",
+			store:        &mocks.Store{User: &store.User{Roles: []string{authz.RoleUser}}},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:  "valid token",
+			token: "valid",
+			store: &mocks.Store{
+				User:          &store.User{Roles: []string{authz.RoleUser}},
+				Organizations: []*store.Organization{{ID: "org-1"}},
+			},
+			expectedCode:   http.StatusOK,
+			expectedClaims: true,
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			ac := newAuthConfig()
+			ac.Authorizer = authz.NewAuthorizer(sc.store, ac.Logger)
+			claims := false
+			handler := ac.OptionalAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, claims = r.Context().Value(UserClaimsKey).(*store.Claims)
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+			if sc.token == "valid" {
+				req.Header.Set("Authorization", "Bearer "+validToken(t))
+			} else if sc.token != "" {
+				req.Header.Set("Authorization", sc.token)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, sc.expectedCode, rr.Code)
+			assert.Equal(t, sc.expectedClaims, claims)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package foundation
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details, clients
+// that accept it get errors in that format rather than as a Response.
+const ProblemContentType = "application/problem+json"
+
+// problemTypePrefix makes the error code of a problem into its type URI.
+const problemTypePrefix = "urn:identity-server:problem:"
+
+// Problem is an RFC 7807 problem details document. Code is the same error
+// code the Response of the error has.
+type Problem struct {
+	Type     string         `json:"type"`
+	Title    string         `json:"title"`
+	Status   int            `json:"status"`
+	Detail   string         `json:"detail,omitempty"`
+	Instance string         `json:"instance,omitempty"`
+	Code     string         `json:"code,omitempty"`
+	Errors   []FieldProblem `json:"errors,omitempty"`
+}
+
+// FieldProblem is a field of the request that failed validation.
+type FieldProblem struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+// FieldError is an error about one field of a request.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+// Error returns the error message without the field.
+func (e *FieldError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the field.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// NewProblem returns the problem details of err for the request r, the
+// instance is the id the request is logged with. Failures of the service
+// are described without err, only with the id that finds them in the log.
+func NewProblem(r *http.Request, status int, err error, code string) *Problem {
+	p := &Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   err.Error(),
+		Instance: middleware.GetReqID(r.Context()),
+		Code:     code,
+		Errors:   fieldProblems(err),
+	}
+	if status >= http.StatusInternalServerError {
+		p.Detail = internalDetail(p.Instance)
+		p.Errors = nil
+	}
+	if code != "" {
+		p.Type = problemTypePrefix + code
+	}
+	return p
+}
+
+// internalDetail is the detail of a failure of the service logged with correlationID.
+func internalDetail(correlationID string) string {
+	if correlationID == "" {
+		return "internal error"
+	}
+	return "internal error, correlation id " + correlationID
+}
+
+// fieldProblems returns the FieldErrors in the tree of err.
+func fieldProblems(err error) []FieldProblem {
+	switch e := err.(type) {
+	case *FieldError:
+		return []FieldProblem{{Field: e.Field, Detail: e.Error()}}
+	case interface{ Unwrap() []error }:
+		var problems []FieldProblem
+		for _, err := range e.Unwrap() {
+			problems = append(problems, fieldProblems(err)...)
+		}
+		return problems
+	case interface{ Unwrap() error }:
+		return fieldProblems(e.Unwrap())
+	}
+	return nil
+}
+
+// WantsProblem reports whether the Accept header of r asks for problem details.
+func WantsProblem(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, params, err := mime.ParseMediaType(mediaRange)
+			if err != nil || mediaType != ProblemContentType {
+				continue
+			}
+			if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+				continue
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// ProblemResponse writes the problem details of err.
+func ProblemResponse(w http.ResponseWriter, r *http.Request, status int, err error, code string) error {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(NewProblem(r, status, err, code))
+}
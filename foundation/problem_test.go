@@ -0,0 +1,136 @@
+package foundation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWantsProblem(t *testing.T) {
+	scenarios := []struct {
+		name     string
+		accept   []string
+		expected bool
+	}{
+		{name: "no accept header"},
+		{name: "json", accept: []string{"application/json"}},
+		{name: "problem", accept: []string{"application/problem+json"}, expected: true},
+		{name: "in a list", accept: []string{"application/json, application/problem+json;q=0.9"}, expected: true},
+		{name: "in a second header", accept: []string{"application/json", "application/problem+json"}, expected: true},
+		{name: "refused", accept: []string{"application/problem+json;q=0"}},
+		{name: "wildcard", accept: []string{"*/*"}},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, a := range sc.accept {
+				r.Header.Add("Accept", a)
+			}
+			assert.Equal(t, sc.expected, WantsProblem(r))
+		})
+	}
+}
+
+func TestNewProblem(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/register", nil)
+	r = r.WithContext(context.WithValue(r.Context(), middleware.RequestIDKey, "req-1"))
+
+	scenarios := []struct {
+		name     string
+		status   int
+		err      error
+		code     string
+		expected *Problem
+	}{
+		{
+			name:   "with a code",
+			status: http.StatusNotFound,
+			err:    errors.New("user not found"),
+			code:   UserDoNotExist,
+			expected: &Problem{
+				Type:     "urn:identity-server:problem:user-do-not-exist",
+				Title:    "Not Found",
+				Status:   http.StatusNotFound,
+				Detail:   "user not found",
+				Instance: "req-1",
+				Code:     UserDoNotExist,
+			},
+		},
+		{
+			name:   "without a code",
+			status: http.StatusInternalServerError,
+			err:    errors.New("database failure"),
+			expected: &Problem{
+				Type:     "about:blank",
+				Title:    "Internal Server Error",
+				Status:   http.StatusInternalServerError,
+				Detail:   "internal error, correlation id req-1",
+				Instance: "req-1",
+			},
+		},
+		{
+			name:   "internal error",
+			status: http.StatusServiceUnavailable,
+			err:    &FieldError{Field: "email", Err: errors.New("dial tcp 10.0.0.5:3306: connection refused")},
+			code:   InternalError,
+			expected: &Problem{
+				Type:     "urn:identity-server:problem:internal-error",
+				Title:    "Service Unavailable",
+				Status:   http.StatusServiceUnavailable,
+				Detail:   "internal error, correlation id req-1",
+				Instance: "req-1",
+				Code:     InternalError,
+			},
+		},
+		{
+			name:   "field errors",
+			status: http.StatusBadRequest,
+			err: fmt.Errorf("invalid details: %w", errors.Join(
+				&FieldError{Field: "first_name", Err: errors.New("missing first name")},
+				&FieldError{Field: "email", Err: errors.New("invalid email")},
+			)),
+			code: ValidationFailed,
+			expected: &Problem{
+				Type:     "urn:identity-server:problem:validation-failure",
+				Title:    "Bad Request",
+				Status:   http.StatusBadRequest,
+				Detail:   "invalid details: missing first name\ninvalid email",
+				Instance: "req-1",
+				Code:     ValidationFailed,
+				Errors: []FieldProblem{
+					{Field: "first_name", Detail: "missing first name"},
+					{Field: "email", Detail: "invalid email"},
+				},
+			},
+		},
+	}
+	for _, sc := range scenarios {
+		t.Run(sc.name, func(t *testing.T) {
+			assert.Equal(t, sc.expected, NewProblem(r, sc.status, sc.err, sc.code))
+		})
+	}
+}
+
+func TestErrorResponse_Problem(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/register", nil)
+	r.Header.Set("Accept", ProblemContentType)
+	rr := httptest.NewRecorder()
+
+	ErrorResponse(rr, r, http.StatusBadRequest, &FieldError{Field: "terms", Err: errors.New("please select terms")}, ValidationFailed)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, ProblemContentType, rr.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", rr.Header().Get("Vary"))
+	var got Problem
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&got))
+	assert.Equal(t, "please select terms", got.Detail)
+	assert.Equal(t, []FieldProblem{{Field: "terms", Detail: "please select terms"}}, got.Errors)
+}
@@ -0,0 +1,114 @@
+package foundation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/sirupsen/logrus"
+)
+
+// Message is a domain event published to other services.
+type Message struct {
+	// ID is unique to the event, subscribers use it to drop events they already
+	// handled as an event can be published more than once.
+	ID          string
+	Type        string
+	AggregateID string
+	Payload     json.RawMessage
+	OccurredAt  time.Time
+}
+
+// Publisher publishes domain events.
+type Publisher interface {
+	Publish(ctx context.Context, m Message) error
+}
+
+// Publishers publishes every event with each of its publishers.
+type Publishers []Publisher
+
+// Publish publishes m with every publisher, even when one of them fails.
+func (p Publishers) Publish(ctx context.Context, m Message) error {
+	var errs []error
+	for _, publisher := range p {
+		if err := publisher.Publish(ctx, m); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LogPublisher writes events to the log instead of publishing them, it is
+// used when no message broker is configured.
+type LogPublisher struct {
+	Logger *logrus.Logger
+}
+
+// Publish logs the event.
+func (l *LogPublisher) Publish(_ context.Context, m Message) error {
+	l.Logger.WithFields(logrus.Fields{
+		"event_id":     m.ID,
+		"type":         m.Type,
+		"aggregate_id": m.AggregateID,
+		"occurred_at":  m.OccurredAt,
+	}).Info(string(m.Payload))
+
+	return nil
+}
+
+// NATSPublisher publishes events to NATS on the subject SubjectPrefix.type,
+// such as identity.user.registered.
+type NATSPublisher struct {
+	Conn          *nats.Conn
+	SubjectPrefix string
+}
+
+// natsFlushTimeout is how long Publish waits for the server to receive an event.
+const natsFlushTimeout = 5 * time.Second
+
+// Publish sends the event with its ID in the Nats-Msg-Id header, so JetStream
+// streams drop the ones they already have, and waits for the server to receive it.
+func (n *NATSPublisher) Publish(ctx context.Context, m Message) error {
+	msg := nats.NewMsg(n.SubjectPrefix + "." + m.Type)
+	msg.Header.Set(nats.MsgIdHdr, m.ID)
+	msg.Header.Set("Aggregate-Id", m.AggregateID)
+	msg.Header.Set("Occurred-At", m.OccurredAt.UTC().Format(time.RFC3339Nano))
+	msg.Data = m.Payload
+	if err := n.Conn.PublishMsg(msg); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, natsFlushTimeout)
+	defer cancel()
+	return n.Conn.FlushWithContext(ctx)
+}
+
+// Close drains the connection to NATS.
+func (n *NATSPublisher) Close() error {
+	return n.Conn.Drain()
+}
+
+// NewENVPublisher returns a NATSPublisher connected to NATS_URL that publishes
+// on subjects starting with NATS_SUBJECT_PREFIX, identity by default, or a
+// LogPublisher when NATS_URL is not set.
+func NewENVPublisher(logger *logrus.Logger) (Publisher, error) {
+	url := os.Getenv("NATS_URL")
+	if url == "" {
+		return &LogPublisher{Logger: logger}, nil
+	}
+
+	prefix := os.Getenv("NATS_SUBJECT_PREFIX")
+	if prefix == "" {
+		prefix = "identity"
+	}
+
+	conn, err := nats.Connect(url, nats.Name("identity-server"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+
+	return &NATSPublisher{Conn: conn, SubjectPrefix: prefix}, nil
+}
@@ -0,0 +1,106 @@
+package foundation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runNATS starts an embedded NATS server on a random port.
+func runNATS(t *testing.T) *server.Server {
+	t.Helper()
+	ns, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: server.RANDOM_PORT, NoLog: true, NoSigs: true})
+	require.NoError(t, err)
+	go ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats server did not start")
+	}
+	t.Cleanup(ns.Shutdown)
+	return ns
+}
+
+func TestNATSPublisher_Publish(t *testing.T) {
+	ns := runNATS(t)
+	t.Setenv("NATS_URL", ns.ClientURL())
+	t.Setenv("NATS_SUBJECT_PREFIX", "")
+
+	publisher, err := NewENVPublisher(logrus.New())
+	require.NoError(t, err)
+	require.IsType(t, &NATSPublisher{}, publisher)
+	defer func() { _ = publisher.(*NATSPublisher).Close() }()
+
+	sub, err := nats.Connect(ns.ClientURL())
+	require.NoError(t, err)
+	defer sub.Close()
+	received, err := sub.SubscribeSync("identity.>")
+	require.NoError(t, err)
+	require.NoError(t, sub.Flush())
+
+	occurred := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	err = publisher.Publish(context.Background(), Message{
+		ID:          "event-1",
+		Type:        "user.registered",
+		AggregateID: "user-1",
+		Payload:     json.RawMessage(`{"user_id":"user-1"}`),
+		OccurredAt:  occurred,
+	})
+	require.NoError(t, err)
+
+	msg, err := received.NextMsg(5 * time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "identity.user.registered", msg.Subject)
+	assert.Equal(t, "event-1", msg.Header.Get(nats.MsgIdHdr))
+	assert.Equal(t, "user-1", msg.Header.Get("Aggregate-Id"))
+	assert.Equal(t, occurred.Format(time.RFC3339Nano), msg.Header.Get("Occurred-At"))
+	assert.JSONEq(t, `{"user_id":"user-1"}`, string(msg.Data))
+}
+
+func TestNewENVPublisher(t *testing.T) {
+	t.Setenv("NATS_URL", "")
+	publisher, err := NewENVPublisher(logrus.New())
+	require.NoError(t, err)
+	assert.IsType(t, &LogPublisher{}, publisher)
+
+	t.Setenv("NATS_URL", "nats://127.0.0.1:1")
+	_, err = NewENVPublisher(logrus.New())
+	assert.Error(t, err)
+}
+
+func TestLogPublisher_Publish(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &buf
+
+	p := &LogPublisher{Logger: logger}
+	err := p.Publish(context.Background(), Message{ID: "event-1", Type: "user.deleted", Payload: json.RawMessage(`{"user_id":"user-1"}`)})
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "user.deleted")
+	assert.Contains(t, buf.String(), "event-1")
+}
+
+// failingPublisher fails to publish every event.
+type failingPublisher struct{}
+
+func (failingPublisher) Publish(context.Context, Message) error {
+	return errors.New("broker down")
+}
+
+func TestPublishers_Publish(t *testing.T) {
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &buf
+
+	p := Publishers{failingPublisher{}, &LogPublisher{Logger: logger}}
+	err := p.Publish(context.Background(), Message{ID: "event-1", Type: "user.deleted"})
+	require.EqualError(t, err, "broker down")
+	assert.Contains(t, buf.String(), "event-1")
+}
@@ -3,6 +3,8 @@ package foundation
 import (
 	"encoding/json"
 	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
 )
 
 // and success messages.
@@ -12,10 +14,22 @@ type Response struct {
 	ErrorCode string `json:"error-code"`
 }
 
-// ErrorResponse give details to the user about the error that occurred.
-func ErrorResponse(w http.ResponseWriter, code int, errr error, customCode string) {
+// ErrorResponse give details to the user about the error that occurred, as
+// problem details when r accepts them and as a Response otherwise. The
+// details of failures of the service are left out either way, they have to
+// be logged by the caller.
+func ErrorResponse(w http.ResponseWriter, r *http.Request, code int, errr error, customCode string) {
+	w.Header().Add("Vary", "Accept")
+	if WantsProblem(r) {
+		_ = ProblemResponse(w, r, code, errr, customCode)
+		return
+	}
+	message := errr.Error()
+	if code >= http.StatusInternalServerError {
+		message = internalDetail(middleware.GetReqID(r.Context()))
+	}
 	w.Header().Set("Content-Type", "application/json")
-	_ = JSONResponse(w, code, errr.Error(), customCode)
+	_ = JSONResponse(w, code, message, customCode)
 }
 
 // JSONResponse converts response into a json.
@@ -106,15 +106,16 @@ func TestErrorResponse(t *testing.T) {
 			status:      http.StatusInternalServerError,
 			err:         errors.New("database failure"),
 			errorCode:   DatabaseError,
-			wantMessage: "database failure",
+			wantMessage: "internal error",
 		},
 	}
 
 	for _, sc := range scenarios {
 		t.Run(sc.name, func(t *testing.T) {
 			rr := httptest.NewRecorder()
-			ErrorResponse(rr, sc.status, sc.err, sc.errorCode)
+			ErrorResponse(rr, httptest.NewRequest(http.MethodGet, "/", nil), sc.status, sc.err, sc.errorCode)
 			assert.Equal(t, sc.status, rr.Code)
+			assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
 
 			var got Response
 			require.NoError(t, json.NewDecoder(rr.Body).Decode(&got))
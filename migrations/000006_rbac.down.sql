@@ -0,0 +1,11 @@
+ALTER TABLE identity_users ADD COLUMN role VARCHAR(50) NOT NULL DEFAULT 'user';
+
+UPDATE identity_users u
+    JOIN user_roles ur ON ur.user_id = u.id
+    JOIN roles r ON r.id = ur.role_id AND r.name = 'admin'
+SET u.role = 'ADMIN';
+
+DROP TABLE user_roles;
+DROP TABLE role_permissions;
+DROP TABLE permissions;
+DROP TABLE roles;
@@ -0,0 +1,57 @@
+CREATE TABLE IF NOT EXISTS
+    roles (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    built_in BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY roles_name_unique (name))
+    ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS
+    permissions (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(255) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE KEY permissions_name_unique (name))
+    ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS
+    role_permissions (
+    role_id VARCHAR(64) NOT NULL,
+    permission_id VARCHAR(64) NOT NULL,
+    PRIMARY KEY (role_id, permission_id),
+    CONSTRAINT role_permissions_role_fk FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE,
+    CONSTRAINT role_permissions_permission_fk FOREIGN KEY (permission_id) REFERENCES permissions (id) ON DELETE CASCADE)
+    ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS
+    user_roles (
+    user_id VARCHAR(64) NOT NULL,
+    role_id VARCHAR(64) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, role_id),
+    CONSTRAINT user_roles_user_fk FOREIGN KEY (user_id) REFERENCES identity_users (id) ON DELETE CASCADE,
+    CONSTRAINT user_roles_role_fk FOREIGN KEY (role_id) REFERENCES roles (id) ON DELETE CASCADE)
+    ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+INSERT INTO roles (id, name, description, built_in) VALUES
+    (UUID(), 'admin', 'Full access to user and role management', TRUE),
+    (UUID(), 'user', 'Default role for registered users', TRUE);
+
+INSERT INTO permissions (id, name, description) VALUES
+    (UUID(), 'users:read', 'Read any user account'),
+    (UUID(), 'users:write', 'Create users and change their state'),
+    (UUID(), 'users:delete', 'Delete user accounts'),
+    (UUID(), 'roles:read', 'Read roles and role assignments'),
+    (UUID(), 'roles:write', 'Manage roles and assign them to users');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p WHERE r.name = 'admin';
+
+INSERT INTO user_roles (user_id, role_id)
+SELECT u.id, r.id FROM identity_users u JOIN roles r ON r.name = LOWER(u.role);
+
+ALTER TABLE identity_users DROP COLUMN role;
@@ -0,0 +1,20 @@
+ALTER TABLE login_tokens DROP COLUMN organization_id;
+
+DELETE ur FROM user_roles ur JOIN roles r ON r.id = ur.role_id WHERE r.platform = TRUE;
+
+DELETE ur FROM user_roles ur
+    LEFT JOIN user_roles keep ON keep.user_id = ur.user_id AND keep.role_id = ur.role_id
+        AND keep.organization_id < ur.organization_id
+WHERE keep.user_id IS NOT NULL;
+
+ALTER TABLE user_roles
+    DROP PRIMARY KEY,
+    DROP COLUMN organization_id,
+    ADD PRIMARY KEY (user_id, role_id);
+
+DELETE FROM roles WHERE name = 'superadmin';
+
+ALTER TABLE roles DROP COLUMN platform;
+
+DROP TABLE organization_members;
+DROP TABLE organizations;
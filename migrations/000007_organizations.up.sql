@@ -0,0 +1,47 @@
+CREATE TABLE IF NOT EXISTS
+    organizations (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(120) NOT NULL,
+    created_by VARCHAR(64) NOT NULL DEFAULT '',
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP)
+    ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+CREATE TABLE IF NOT EXISTS
+    organization_members (
+    organization_id VARCHAR(64) NOT NULL,
+    user_id VARCHAR(64) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (organization_id, user_id),
+    KEY organization_members_user (user_id),
+    CONSTRAINT organization_members_organization_fk FOREIGN KEY (organization_id) REFERENCES organizations (id) ON DELETE CASCADE,
+    CONSTRAINT organization_members_user_fk FOREIGN KEY (user_id) REFERENCES identity_users (id) ON DELETE CASCADE)
+    ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+ALTER TABLE roles ADD COLUMN platform BOOLEAN NOT NULL DEFAULT FALSE AFTER built_in;
+
+INSERT INTO roles (id, name, description, built_in, platform) VALUES
+    (UUID(), 'superadmin', 'Platform administrator, not restricted to an organization', TRUE, TRUE);
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p WHERE r.name = 'superadmin';
+
+-- roles are granted within an organization, platform roles use an empty organization_id.
+ALTER TABLE user_roles
+    ADD COLUMN organization_id VARCHAR(64) NOT NULL DEFAULT '' AFTER role_id,
+    DROP PRIMARY KEY,
+    ADD PRIMARY KEY (user_id, role_id, organization_id);
+
+SET @default_organization = UUID();
+
+INSERT INTO organizations (id, name) VALUES (@default_organization, 'Default');
+
+INSERT INTO organization_members (organization_id, user_id)
+SELECT @default_organization, id FROM identity_users;
+
+UPDATE user_roles SET organization_id = @default_organization;
+
+-- tokens issued before this migration carry no organization, users have to login again.
+ALTER TABLE login_tokens ADD COLUMN organization_id VARCHAR(64) NOT NULL DEFAULT '' AFTER user_id;
+
+DELETE FROM login_tokens;
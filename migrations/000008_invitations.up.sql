@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS
+    invitations (
+    id VARCHAR(64) PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    organization_id VARCHAR(64) NOT NULL,
+    role VARCHAR(64) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    invited_by VARCHAR(64) NOT NULL DEFAULT '',
+    expires_at DATETIME NOT NULL,
+    accepted_at DATETIME NULL,
+    revoked_at DATETIME NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY invitations_token_hash_unique (token_hash),
+    KEY invitations_organization (organization_id),
+    CONSTRAINT invitations_organization_fk FOREIGN KEY (organization_id) REFERENCES organizations (id) ON DELETE CASCADE)
+    ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
@@ -0,0 +1,4 @@
+ALTER TABLE identity_users
+    DROP COLUMN picture_url,
+    DROP COLUMN timezone,
+    DROP COLUMN locale;
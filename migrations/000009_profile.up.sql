@@ -0,0 +1,4 @@
+ALTER TABLE identity_users
+    ADD COLUMN locale VARCHAR(35) NOT NULL DEFAULT '',
+    ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN picture_url VARCHAR(2048) NOT NULL DEFAULT '';
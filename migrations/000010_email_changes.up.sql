@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS
+    email_changes (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(64) NOT NULL,
+    old_email VARCHAR(255) NOT NULL,
+    new_email VARCHAR(255) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    confirmed_at DATETIME NULL,
+    reserved_until DATETIME NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY email_changes_token_hash_unique (token_hash),
+    KEY email_changes_user (user_id),
+    KEY email_changes_old_email (old_email, reserved_until),
+    CONSTRAINT email_changes_user_fk FOREIGN KEY (user_id) REFERENCES identity_users (id) ON DELETE CASCADE)
+    ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
@@ -0,0 +1,13 @@
+-- users sharing an email once normalized have to be merged before the index
+-- can be added. The first statement fails on them before anything is changed,
+-- after merging them the version can be forced back to 10 and run again.
+-- They are listed by:
+-- SELECT LOWER(TRIM(email)), COUNT(*) FROM identity_users GROUP BY LOWER(TRIM(email)) HAVING COUNT(*) > 1;
+ALTER TABLE identity_users ADD UNIQUE KEY identity_users_email_normalized ((LOWER(TRIM(email))));
+ALTER TABLE identity_users DROP INDEX identity_users_email_normalized;
+
+UPDATE identity_users SET email = LOWER(TRIM(email));
+UPDATE invitations SET email = LOWER(TRIM(email));
+UPDATE email_changes SET old_email = LOWER(TRIM(old_email)), new_email = LOWER(TRIM(new_email));
+
+ALTER TABLE identity_users ADD UNIQUE KEY identity_users_email_unique (email);
@@ -0,0 +1,4 @@
+ALTER TABLE identity_users
+    DROP INDEX identity_users_created_at,
+    DROP INDEX identity_users_last_name,
+    DROP INDEX identity_users_company;
@@ -0,0 +1,6 @@
+-- listings page through users ordered by one of these columns with the id
+-- breaking ties, the email is covered by its unique index.
+ALTER TABLE identity_users
+    ADD INDEX identity_users_created_at (created_at, id),
+    ADD INDEX identity_users_last_name (last_name, id),
+    ADD INDEX identity_users_company (company);
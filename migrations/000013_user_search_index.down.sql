@@ -0,0 +1 @@
+ALTER TABLE identity_users DROP INDEX identity_users_search;
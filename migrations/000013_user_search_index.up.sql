@@ -0,0 +1,4 @@
+-- admins search users by any word of their name, email or company, the
+-- columns have to match the MATCH clause of SearchUsers.
+ALTER TABLE identity_users
+    ADD FULLTEXT INDEX identity_users_search (first_name, last_name, email, company);
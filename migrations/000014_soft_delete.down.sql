@@ -0,0 +1,13 @@
+-- deleted users are removed as the purger would, together with the rows that
+-- belong to them. Login tokens have no foreign key to cascade from.
+DELETE FROM login_tokens WHERE user_id IN (SELECT id FROM identity_users WHERE deleted_at IS NOT NULL);
+DELETE FROM email_changes WHERE user_id IN (SELECT id FROM identity_users WHERE deleted_at IS NOT NULL);
+DELETE FROM user_roles WHERE user_id IN (SELECT id FROM identity_users WHERE deleted_at IS NOT NULL);
+DELETE FROM organization_members WHERE user_id IN (SELECT id FROM identity_users WHERE deleted_at IS NOT NULL);
+DELETE FROM identity_users WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE login_tokens DROP INDEX login_tokens_user;
+
+ALTER TABLE identity_users
+    DROP INDEX identity_users_deleted_at,
+    DROP COLUMN deleted_at;
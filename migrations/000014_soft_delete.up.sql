@@ -0,0 +1,8 @@
+-- deleted users are kept until the purger removes them so admins can restore
+-- them, their email stays taken until then.
+ALTER TABLE identity_users
+    ADD COLUMN deleted_at DATETIME NULL,
+    ADD INDEX identity_users_deleted_at (deleted_at);
+
+-- login tokens have no foreign key, the purger deletes them by user.
+ALTER TABLE login_tokens ADD INDEX login_tokens_user (user_id);
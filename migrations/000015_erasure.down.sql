@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS erasures;
+
+ALTER TABLE identity_users DROP COLUMN erased_at;
@@ -0,0 +1,14 @@
+-- erased users keep their row, without any personal data, so the rows that
+-- refer to them stay valid. The purger leaves them alone.
+ALTER TABLE identity_users ADD COLUMN erased_at DATETIME NULL;
+
+-- erasures has no foreign key to identity_users, the record of an erasure
+-- outlives the user.
+CREATE TABLE IF NOT EXISTS
+    erasures (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(64) NOT NULL,
+    erased_by VARCHAR(64) NOT NULL DEFAULT '',
+    erased_at DATETIME NOT NULL,
+    KEY erasures_user (user_id))
+    ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
@@ -0,0 +1,26 @@
+-- audit_events is append-only, the service never updates or deletes a row.
+-- It has no foreign keys so events outlive the users and roles they describe.
+CREATE TABLE IF NOT EXISTS
+    audit_events (
+    id VARCHAR(64) PRIMARY KEY,
+    organization_id VARCHAR(64) NOT NULL DEFAULT '',
+    actor_id VARCHAR(64) NOT NULL DEFAULT '',
+    action VARCHAR(64) NOT NULL,
+    target_type VARCHAR(32) NOT NULL,
+    target_id VARCHAR(64) NOT NULL,
+    before_value JSON NULL,
+    after_value JSON NULL,
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    created_at DATETIME(6) NOT NULL,
+    KEY audit_events_organization (organization_id, created_at, id),
+    KEY audit_events_actor (actor_id, created_at),
+    KEY audit_events_target (target_id, created_at))
+    ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+INSERT INTO permissions (id, name, description) VALUES
+    (UUID(), 'audit:read', 'Read the audit log');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.name = 'audit:read' WHERE r.name IN ('admin', 'superadmin');
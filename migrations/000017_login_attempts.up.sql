@@ -0,0 +1,18 @@
+-- login_attempts has a row for every login with a password. user_id is NULL
+-- when nobody has the email that was used.
+CREATE TABLE IF NOT EXISTS
+    login_attempts (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(64) NULL,
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    ip_range VARCHAR(64) NOT NULL DEFAULT '',
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    device CHAR(64) NOT NULL DEFAULT '',
+    success BOOLEAN NOT NULL,
+    failure_reason VARCHAR(32) NOT NULL DEFAULT '',
+    created_at DATETIME(6) NOT NULL,
+    KEY login_attempts_user (user_id, created_at, id),
+    KEY login_attempts_user_device (user_id, success, device),
+    KEY login_attempts_user_range (user_id, success, ip_range),
+    CONSTRAINT login_attempts_user_fk FOREIGN KEY (user_id) REFERENCES identity_users (id) ON DELETE CASCADE)
+    ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
@@ -0,0 +1,17 @@
+-- outbox_events has the domain events waiting to be published, they are
+-- written in the transaction of the change they describe. sequence is the
+-- order they are published in.
+CREATE TABLE IF NOT EXISTS
+    outbox_events (
+    sequence BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    id VARCHAR(64) NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    aggregate_id VARCHAR(64) NOT NULL,
+    payload JSON NOT NULL,
+    occurred_at DATETIME(6) NOT NULL,
+    published_at DATETIME(6) NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error VARCHAR(255) NOT NULL DEFAULT '',
+    UNIQUE KEY outbox_events_id (id),
+    KEY outbox_events_pending (published_at, sequence))
+    ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
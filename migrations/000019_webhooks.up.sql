@@ -0,0 +1,46 @@
+-- webhooks are the endpoints of an organization that domain events are
+-- posted to. event_types is a JSON array of the events the endpoint gets.
+CREATE TABLE IF NOT EXISTS
+    webhooks (
+    id VARCHAR(64) PRIMARY KEY,
+    organization_id VARCHAR(64) NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    event_types JSON NOT NULL,
+    created_by VARCHAR(64) NOT NULL DEFAULT '',
+    created_at DATETIME(6) NOT NULL,
+    KEY webhooks_organization (organization_id, created_at),
+    CONSTRAINT webhooks_organization_fk FOREIGN KEY (organization_id) REFERENCES organizations (id) ON DELETE CASCADE)
+    ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+-- webhook_deliveries has an event to post to a webhook and how posting it
+-- went, a delivery is pending until it succeeds or runs out of attempts.
+-- user_id is the user the event is about.
+CREATE TABLE IF NOT EXISTS
+    webhook_deliveries (
+    id VARCHAR(64) PRIMARY KEY,
+    webhook_id VARCHAR(64) NOT NULL,
+    event_id VARCHAR(64) NOT NULL,
+    event_type VARCHAR(64) NOT NULL,
+    user_id VARCHAR(64) NOT NULL,
+    payload JSON NOT NULL,
+    occurred_at DATETIME(6) NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    response_code INT NOT NULL DEFAULT 0,
+    last_error VARCHAR(255) NOT NULL DEFAULT '',
+    next_attempt_at DATETIME(6) NULL,
+    delivered_at DATETIME(6) NULL,
+    created_at DATETIME(6) NOT NULL,
+    UNIQUE KEY webhook_deliveries_event (webhook_id, event_id),
+    KEY webhook_deliveries_due (status, next_attempt_at),
+    KEY webhook_deliveries_webhook (webhook_id, created_at, id),
+    KEY webhook_deliveries_user (user_id),
+    CONSTRAINT webhook_deliveries_webhook_fk FOREIGN KEY (webhook_id) REFERENCES webhooks (id) ON DELETE CASCADE)
+    ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_unicode_ci;
+
+INSERT INTO permissions (id, name, description) VALUES
+    (UUID(), 'webhooks:manage', 'Register webhooks and replay their deliveries');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.name = 'webhooks:manage' WHERE r.name IN ('admin', 'superadmin');
@@ -0,0 +1,5 @@
+INSERT INTO permissions (id, name, description) VALUES
+    (UUID(), 'queries:manage', 'Reload the persisted query manifest of the GraphQL server');
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r JOIN permissions p ON p.name = 'queries:manage' WHERE r.name = 'superadmin';
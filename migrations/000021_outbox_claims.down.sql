@@ -0,0 +1,3 @@
+ALTER TABLE outbox_events
+    DROP KEY outbox_events_aggregate,
+    DROP COLUMN claimed_until;
@@ -0,0 +1,5 @@
+-- relays claim the events they publish until claimed_until, so they publish
+-- outside of a transaction without two of them publishing the same events.
+ALTER TABLE outbox_events
+    ADD COLUMN claimed_until DATETIME(6) NULL AFTER published_at,
+    ADD KEY outbox_events_aggregate (aggregate_id, published_at, sequence);